@@ -3,6 +3,7 @@ package quic
 import (
 	"sync"
 
+	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -13,6 +14,14 @@ type packetBuffer struct {
 	// It doesn't support concurrent use.
 	// It is > 1 when used for coalesced packet.
 	refCount int
+
+	// If set, the header protection of the packet still needs to be applied before it is sent.
+	// The send queue applies it together with that of the other queued packets, see Config.BatchHeaderProtection.
+	headerSealer     handshake.ShortHeaderSealer
+	headerProtection handshake.HeaderProtection
+
+	// If set, the packet carries partially reliable data, and is marked using Config.PRPacketMarking.
+	partiallyReliable bool
 }
 
 // Split increases the refCount.
@@ -68,6 +77,9 @@ func getPacketBuffer() *packetBuffer {
 	buf := bufferPool.Get().(*packetBuffer)
 	buf.refCount = 1
 	buf.Data = buf.Data[:0]
+	buf.headerSealer = nil
+	buf.headerProtection = handshake.HeaderProtection{}
+	buf.partiallyReliable = false
 	return buf
 }
 
@@ -0,0 +1,117 @@
+package quic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// A CertificateReloader serves a certificate loaded from a PEM encoded certificate and key file,
+// and allows replacing it while the listener is running.
+// Its GetCertificate method is meant to be used as tls.Config.GetCertificate.
+// For GetCertificate to be called, tls.Config.Certificates must be empty.
+//
+// Optionally, an OCSP response is stapled to the certificate (see SetOCSPStapleFile).
+// Clients then don't need to contact the OCSP responder of the CA during the handshake.
+//
+// Certificate compression (RFC 8879) is not supported, since neither crypto/tls nor qtls implement it.
+// Since the server's first flight is subject to the anti-amplification limit (RFC 9000, Section 8.1),
+// short certificate chains (e.g. using ECDSA keys) keep the handshake from taking an additional round trip.
+//
+// Reloading the certificate only affects new handshakes.
+// Connections that were established using the old certificate are not affected.
+type CertificateReloader struct {
+	certFile, keyFile string
+
+	mutex    sync.RWMutex
+	ocspFile string
+	cert     *tls.Certificate
+}
+
+// NewCertificateReloader loads the certificate and key from the given files.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetOCSPStapleFile loads a DER encoded OCSP response from the given file, and staples it to the certificate.
+// The file is loaded again on every Reload, so it must be updated together with the certificate.
+// The response must have been issued for the certificate, and it must not have expired.
+// If the certificate file contains the issuer certificate, the signature of the response is verified.
+func (r *CertificateReloader) SetOCSPStapleFile(file string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cert := *r.cert
+	if err := loadOCSPStaple(&cert, file); err != nil {
+		return err
+	}
+	r.ocspFile = file
+	r.cert = &cert
+	return nil
+}
+
+// Reload loads the certificate and key from the files again, as well as the OCSP response, if used.
+// If loading fails, the previous certificate continues to be used.
+func (r *CertificateReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.ocspFile != "" {
+		if err := loadOCSPStaple(&cert, r.ocspFile); err != nil {
+			return err
+		}
+	}
+	r.cert = &cert
+	return nil
+}
+
+// GetCertificate returns the most recently loaded certificate.
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
+
+func loadOCSPStaple(cert *tls.Certificate, file string) error {
+	staple, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	var issuer *x509.Certificate
+	if len(cert.Certificate) > 1 {
+		if issuer, err = x509.ParseCertificate(cert.Certificate[1]); err != nil {
+			return err
+		}
+	}
+	resp, err := ocsp.ParseResponseForCert(staple, leaf, issuer)
+	if err != nil {
+		return fmt.Errorf("invalid OCSP response: %w", err)
+	}
+	if resp.Status != ocsp.Good {
+		return fmt.Errorf("OCSP response doesn't have the good status (status %d)", resp.Status)
+	}
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return fmt.Errorf("OCSP response expired at %s", resp.NextUpdate)
+	}
+	cert.OCSPStaple = staple
+	return nil
+}
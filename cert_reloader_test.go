@@ -0,0 +1,224 @@
+package quic
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Certificate Reloader", func() {
+	var dir, certFile, keyFile string
+
+	writeCertificate := func(commonName string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: commonName},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		Expect(err).ToNot(HaveOccurred())
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)).To(Succeed())
+		Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)).To(Succeed())
+	}
+
+	commonName := func(r *CertificateReloader) string {
+		cert, err := r.GetCertificate(nil)
+		Expect(err).ToNot(HaveOccurred())
+		c, err := x509.ParseCertificate(cert.Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+		return c.Subject.CommonName
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "quic-go-cert")
+		Expect(err).ToNot(HaveOccurred())
+		certFile = filepath.Join(dir, "cert.pem")
+		keyFile = filepath.Join(dir, "priv.key")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("errors if the certificate can't be loaded", func() {
+		_, err := NewCertificateReloader(certFile, keyFile)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reloads the certificate", func() {
+		writeCertificate("first")
+		r, err := NewCertificateReloader(certFile, keyFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(commonName(r)).To(Equal("first"))
+		writeCertificate("second")
+		Expect(commonName(r)).To(Equal("first"))
+		Expect(r.Reload()).To(Succeed())
+		Expect(commonName(r)).To(Equal("second"))
+	})
+
+	It("keeps the old certificate if reloading fails", func() {
+		writeCertificate("first")
+		r, err := NewCertificateReloader(certFile, keyFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(keyFile, []byte("foobar"), 0o600)).To(Succeed())
+		Expect(r.Reload()).ToNot(Succeed())
+		Expect(commonName(r)).To(Equal("first"))
+	})
+
+	Context("OCSP stapling", func() {
+		var (
+			caCert   *x509.Certificate
+			caKey    *ecdsa.PrivateKey
+			serial   int64
+			ocspFile string
+		)
+
+		// writeSignedCertificate writes a certificate issued by the CA, followed by the CA certificate.
+		writeSignedCertificate := func(commonName string) *x509.Certificate {
+			serial++
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(serial),
+				Subject:      pkix.Name{CommonName: commonName},
+				NotBefore:    time.Now(),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+			Expect(err).ToNot(HaveOccurred())
+			cert, err := x509.ParseCertificate(der)
+			Expect(err).ToNot(HaveOccurred())
+			keyDER, err := x509.MarshalECPrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+			chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+			chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})...)
+			Expect(os.WriteFile(certFile, chain, 0o600)).To(Succeed())
+			Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)).To(Succeed())
+			return cert
+		}
+
+		writeOCSPResponse := func(cert *x509.Certificate, status int, nextUpdate time.Time) []byte {
+			resp, err := ocsp.CreateResponse(caCert, caCert, ocsp.Response{
+				Status:       status,
+				SerialNumber: cert.SerialNumber,
+				ThisUpdate:   time.Now().Add(-time.Hour),
+				NextUpdate:   nextUpdate,
+			}, caKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.WriteFile(ocspFile, resp, 0o600)).To(Succeed())
+			return resp
+		}
+
+		staple := func(r *CertificateReloader) []byte {
+			cert, err := r.GetCertificate(nil)
+			Expect(err).ToNot(HaveOccurred())
+			return cert.OCSPStaple
+		}
+
+		BeforeEach(func() {
+			ocspFile = filepath.Join(dir, "ocsp.der")
+			var err error
+			caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			template := &x509.Certificate{
+				SerialNumber:          big.NewInt(1),
+				Subject:               pkix.Name{CommonName: "CA"},
+				NotBefore:             time.Now().Add(-time.Hour),
+				NotAfter:              time.Now().Add(time.Hour),
+				IsCA:                  true,
+				BasicConstraintsValid: true,
+				KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+			Expect(err).ToNot(HaveOccurred())
+			caCert, err = x509.ParseCertificate(der)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("staples the OCSP response", func() {
+			cert := writeSignedCertificate("first")
+			resp := writeOCSPResponse(cert, ocsp.Good, time.Now().Add(time.Hour))
+			r, err := NewCertificateReloader(certFile, keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staple(r)).To(BeNil())
+			Expect(r.SetOCSPStapleFile(ocspFile)).To(Succeed())
+			Expect(staple(r)).To(Equal(resp))
+		})
+
+		It("reloads the OCSP response together with the certificate", func() {
+			cert := writeSignedCertificate("first")
+			writeOCSPResponse(cert, ocsp.Good, time.Now().Add(time.Hour))
+			r, err := NewCertificateReloader(certFile, keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.SetOCSPStapleFile(ocspFile)).To(Succeed())
+			cert = writeSignedCertificate("second")
+			resp := writeOCSPResponse(cert, ocsp.Good, time.Now().Add(time.Hour))
+			Expect(r.Reload()).To(Succeed())
+			Expect(commonName(r)).To(Equal("second"))
+			Expect(staple(r)).To(Equal(resp))
+		})
+
+		It("keeps the old certificate if the OCSP response doesn't match the new certificate", func() {
+			cert := writeSignedCertificate("first")
+			resp := writeOCSPResponse(cert, ocsp.Good, time.Now().Add(time.Hour))
+			r, err := NewCertificateReloader(certFile, keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.SetOCSPStapleFile(ocspFile)).To(Succeed())
+			writeSignedCertificate("second")
+			Expect(r.Reload()).To(MatchError(ContainSubstring("no response matching the supplied certificate")))
+			Expect(commonName(r)).To(Equal("first"))
+			Expect(staple(r)).To(Equal(resp))
+		})
+
+		It("rejects OCSP responses for revoked certificates", func() {
+			cert := writeSignedCertificate("first")
+			writeOCSPResponse(cert, ocsp.Revoked, time.Now().Add(time.Hour))
+			r, err := NewCertificateReloader(certFile, keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.SetOCSPStapleFile(ocspFile)).To(MatchError("OCSP response doesn't have the good status (status 1)"))
+			Expect(staple(r)).To(BeNil())
+		})
+
+		It("rejects expired OCSP responses", func() {
+			cert := writeSignedCertificate("first")
+			writeOCSPResponse(cert, ocsp.Good, time.Now().Add(-time.Minute))
+			r, err := NewCertificateReloader(certFile, keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.SetOCSPStapleFile(ocspFile)).To(MatchError(ContainSubstring("OCSP response expired")))
+		})
+
+		It("rejects OCSP responses signed by a different CA", func() {
+			cert := writeSignedCertificate("first")
+			otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			resp, err := ocsp.CreateResponse(caCert, caCert, ocsp.Response{
+				Status:       ocsp.Good,
+				SerialNumber: cert.SerialNumber,
+				ThisUpdate:   time.Now(),
+			}, otherKey)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.WriteFile(ocspFile, resp, 0o600)).To(Succeed())
+			r, err := NewCertificateReloader(certFile, keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.SetOCSPStapleFile(ocspFile)).To(MatchError(ContainSubstring("invalid OCSP response")))
+		})
+	})
+})
@@ -0,0 +1,176 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrChunkAbandoned is returned by ChunkWriter.WriteChunk when the chunk was abandoned,
+// before or while WriteChunk was waiting for the chunks reserved before it.
+var ErrChunkAbandoned = errors.New("chunk abandoned")
+
+// A ChunkWriter allows multiple goroutines to write chunks of data to the same stream,
+// e.g. the tiles of a video frame that are encoded in parallel.
+//
+// The order of the chunks on the stream is determined when a chunk is reserved using Reserve,
+// not when it is written: WriteChunk blocks until all chunks reserved before were written or abandoned.
+// A chunk is never interleaved with data of other chunks, and every chunk ends a message (see SendStream.EndMessage),
+// such that the receiver can skip to the next chunk using ReceiveStream.ReadMessageBoundary.
+//
+// Every reserved chunk must be either written or abandoned, otherwise all chunks reserved later block forever.
+// Once writing a chunk fails, all following calls to WriteChunk return that error.
+// Instead of passing the policy of every chunk, the application can tag the chunks with their metadata,
+// and let a PRPolicyPreset choose the policy, see SetPreset.
+// The stream must not be written to directly while it is used by a ChunkWriter.
+type ChunkWriter struct {
+	str SendStream
+
+	mutex        sync.Mutex
+	cond         sync.Cond
+	nextReserved uint64
+	nextWrite    uint64
+	abandoned    map[uint64]struct{}
+	writing      bool // the chunk nextWrite is being written
+	preset       PRPolicyPreset
+	err          error
+}
+
+// NewChunkWriter creates a new ChunkWriter.
+func NewChunkWriter(str SendStream) *ChunkWriter {
+	w := &ChunkWriter{
+		str:       str,
+		abandoned: make(map[uint64]struct{}),
+	}
+	w.cond.L = &w.mutex
+	return w
+}
+
+// Reserve reserves the position of the next chunk on the stream, and returns its sequence number.
+func (w *ChunkWriter) Reserve() uint64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	seq := w.nextReserved
+	w.nextReserved++
+	return seq
+}
+
+// WriteChunk writes the chunk with the sequence number seq, using the policy.
+// It blocks until all chunks reserved before were written or abandoned, and the chunk was written.
+func (w *ChunkWriter) WriteChunk(seq uint64, p []byte, policy PRPolicy) error {
+	w.mutex.Lock()
+	if err := w.checkReserved(seq); err != nil {
+		w.mutex.Unlock()
+		return err
+	}
+	for w.err == nil && w.nextWrite < seq && !w.isAbandoned(seq) {
+		w.cond.Wait()
+	}
+	if w.err != nil {
+		w.mutex.Unlock()
+		return w.err
+	}
+	if w.nextWrite > seq || w.isAbandoned(seq) {
+		w.mutex.Unlock()
+		return ErrChunkAbandoned
+	}
+	if w.writing {
+		w.mutex.Unlock()
+		return fmt.Errorf("chunk %d is already being written", seq)
+	}
+	w.writing = true
+	w.mutex.Unlock()
+
+	// No other chunk can be written until nextWrite is incremented, so the mutex doesn't need to be held.
+	_, err := w.str.WriteWithPolicy(p, policy)
+	if err == nil {
+		err = w.str.EndMessage()
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.writing = false
+	if err != nil {
+		w.err = err
+	} else {
+		w.nextWrite++
+		w.skipAbandoned()
+	}
+	w.cond.Broadcast()
+	return err
+}
+
+// SetPreset sets the preset used by WriteChunkWithMetadata, e.g. the preset returned by ProtectKeyframes.
+func (w *ChunkWriter) SetPreset(preset PRPolicyPreset) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.preset = preset
+}
+
+// WriteChunkWithMetadata writes the chunk with the sequence number seq, using the policy the preset chooses for the metadata.
+// It fails if no preset was set using SetPreset.
+func (w *ChunkWriter) WriteChunkWithMetadata(seq uint64, p []byte, m ChunkMetadata) error {
+	w.mutex.Lock()
+	preset := w.preset
+	w.mutex.Unlock()
+
+	if preset == nil {
+		return errors.New("no PR policy preset set")
+	}
+	return w.WriteChunk(seq, p, preset.Policy(m))
+}
+
+// Abandon gives up a reserved chunk, e.g. because encoding it failed.
+// Chunks reserved after it are not blocked by it any more.
+// If WriteChunk is waiting to write the chunk, it returns ErrChunkAbandoned.
+// A chunk that is already being written can't be abandoned.
+func (w *ChunkWriter) Abandon(seq uint64) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.checkReserved(seq); err != nil {
+		return err
+	}
+	if seq == w.nextWrite && w.writing {
+		return fmt.Errorf("chunk %d is being written", seq)
+	}
+	w.abandoned[seq] = struct{}{}
+	if seq == w.nextWrite {
+		w.skipAbandoned()
+	}
+	w.cond.Broadcast()
+	return nil
+}
+
+// isAbandoned says if the chunk seq was abandoned, but not skipped yet.
+// It must be called with the mutex held.
+func (w *ChunkWriter) isAbandoned(seq uint64) bool {
+	_, ok := w.abandoned[seq]
+	return ok
+}
+
+// checkReserved checks that seq was reserved, and was neither written nor skipped yet.
+// It must be called with the mutex held.
+func (w *ChunkWriter) checkReserved(seq uint64) error {
+	if seq >= w.nextReserved {
+		return fmt.Errorf("chunk %d was not reserved", seq)
+	}
+	if seq < w.nextWrite {
+		return fmt.Errorf("chunk %d was already written or abandoned", seq)
+	}
+	return nil
+}
+
+// skipAbandoned advances nextWrite past abandoned chunks.
+// It must be called with the mutex held.
+func (w *ChunkWriter) skipAbandoned() {
+	for {
+		if _, ok := w.abandoned[w.nextWrite]; !ok {
+			return
+		}
+		delete(w.abandoned, w.nextWrite)
+		w.nextWrite++
+	}
+}
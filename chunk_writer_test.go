@@ -0,0 +1,208 @@
+package quic
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Chunk Writer", func() {
+	var (
+		str    *MockSendStreamI
+		mutex  sync.Mutex
+		writes []string
+		writer *ChunkWriter
+	)
+
+	getWrites := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string{}, writes...)
+	}
+
+	BeforeEach(func() {
+		writes = nil
+		str = NewMockSendStreamI(mockCtrl)
+		str.EXPECT().WriteWithPolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(p []byte, _ PRPolicy) (int, error) {
+			mutex.Lock()
+			writes = append(writes, string(p))
+			mutex.Unlock()
+			return len(p), nil
+		}).AnyTimes()
+		str.EXPECT().EndMessage().DoAndReturn(func() error {
+			mutex.Lock()
+			writes = append(writes, "|")
+			mutex.Unlock()
+			return nil
+		}).AnyTimes()
+		writer = NewChunkWriter(str)
+	})
+
+	It("writes chunks in the order they were reserved", func() {
+		first := writer.Reserve()
+		second := writer.Reserve()
+		third := writer.Reserve()
+		Expect([]uint64{first, second, third}).To(Equal([]uint64{0, 1, 2}))
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			Expect(writer.WriteChunk(third, []byte("baz"), PRPolicy{})).To(Succeed())
+		}()
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+			Expect(writer.WriteChunk(second, []byte("bar"), PRPolicy{})).To(Succeed())
+		}()
+		Consistently(getWrites).Should(BeEmpty())
+		Expect(writer.WriteChunk(first, []byte("foo"), PRPolicy{})).To(Succeed())
+		wg.Wait()
+		Expect(getWrites()).To(Equal([]string{"foo", "|", "bar", "|", "baz", "|"}))
+	})
+
+	It("uses the policy of the chunk", func() {
+		str := NewMockSendStreamI(mockCtrl)
+		writer := NewChunkWriter(str)
+		policy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+		str.EXPECT().WriteWithPolicy([]byte("foobar"), policy).Return(6, nil)
+		str.EXPECT().EndMessage()
+		Expect(writer.WriteChunk(writer.Reserve(), []byte("foobar"), policy)).To(Succeed())
+	})
+
+	It("uses the policy chosen by the preset", func() {
+		str := NewMockSendStreamI(mockCtrl)
+		writer := NewChunkWriter(str)
+		policy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+		preset, err := ProtectKeyframes(policy)
+		Expect(err).ToNot(HaveOccurred())
+		writer.SetPreset(preset)
+		gomock.InOrder(
+			str.EXPECT().WriteWithPolicy([]byte("key"), PRPolicy{}).Return(3, nil),
+			str.EXPECT().EndMessage(),
+			str.EXPECT().WriteWithPolicy([]byte("delta"), policy).Return(5, nil),
+			str.EXPECT().EndMessage(),
+		)
+		Expect(writer.WriteChunkWithMetadata(writer.Reserve(), []byte("key"), ChunkMetadata{Keyframe: true})).To(Succeed())
+		Expect(writer.WriteChunkWithMetadata(writer.Reserve(), []byte("delta"), ChunkMetadata{})).To(Succeed())
+	})
+
+	It("refuses to write chunks with metadata without a preset", func() {
+		Expect(writer.WriteChunkWithMetadata(writer.Reserve(), []byte("foo"), ChunkMetadata{})).To(MatchError("no PR policy preset set"))
+		Expect(getWrites()).To(BeEmpty())
+	})
+
+	It("skips abandoned chunks", func() {
+		first := writer.Reserve()
+		second := writer.Reserve()
+		third := writer.Reserve()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(writer.WriteChunk(third, []byte("baz"), PRPolicy{})).To(Succeed())
+		}()
+		Expect(writer.Abandon(second)).To(Succeed())
+		Expect(writer.WriteChunk(second, []byte("bar"), PRPolicy{})).To(MatchError(ErrChunkAbandoned))
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(writer.Abandon(first)).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(getWrites()).To(Equal([]string{"baz", "|"}))
+		Expect(writer.WriteChunk(first, []byte("foo"), PRPolicy{})).To(MatchError("chunk 0 was already written or abandoned"))
+	})
+
+	It("stops waiting when the chunk is abandoned", func() {
+		first := writer.Reserve()
+		second := writer.Reserve()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(writer.WriteChunk(second, []byte("bar"), PRPolicy{})).To(MatchError(ErrChunkAbandoned))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(writer.Abandon(second)).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(writer.WriteChunk(first, []byte("foo"), PRPolicy{})).To(Succeed())
+		Expect(getWrites()).To(Equal([]string{"foo", "|"}))
+		Expect(writer.WriteChunk(writer.Reserve(), []byte("baz"), PRPolicy{})).To(Succeed())
+		Expect(getWrites()).To(Equal([]string{"foo", "|", "baz", "|"}))
+	})
+
+	It("stops waiting when the chunk is skipped", func() {
+		first := writer.Reserve()
+		second := writer.Reserve()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(writer.WriteChunk(second, []byte("bar"), PRPolicy{})).To(MatchError(ErrChunkAbandoned))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(writer.Abandon(second)).To(Succeed())
+		Expect(writer.Abandon(first)).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(getWrites()).To(BeEmpty())
+	})
+
+	It("doesn't abandon a chunk that is being written", func() {
+		str := NewMockSendStreamI(mockCtrl)
+		writer := NewChunkWriter(str)
+		first := writer.Reserve()
+		second := writer.Reserve()
+		writing := make(chan struct{})
+		unblock := make(chan struct{})
+		str.EXPECT().WriteWithPolicy([]byte("foo"), PRPolicy{}).DoAndReturn(func([]byte, PRPolicy) (int, error) {
+			close(writing)
+			<-unblock
+			return 3, nil
+		})
+		str.EXPECT().EndMessage()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(writer.WriteChunk(first, []byte("foo"), PRPolicy{})).To(Succeed())
+		}()
+		Eventually(writing).Should(BeClosed())
+		Expect(writer.Abandon(first)).To(MatchError("chunk 0 is being written"))
+		Expect(writer.WriteChunk(first, []byte("foo"), PRPolicy{})).To(MatchError("chunk 0 is already being written"))
+		close(unblock)
+		Eventually(done).Should(BeClosed())
+		// the next chunk is written after the first one
+		str.EXPECT().WriteWithPolicy([]byte("bar"), PRPolicy{}).Return(3, nil)
+		str.EXPECT().EndMessage()
+		Expect(writer.WriteChunk(second, []byte("bar"), PRPolicy{})).To(Succeed())
+	})
+
+	It("refuses to write chunks that weren't reserved", func() {
+		Expect(writer.WriteChunk(0, []byte("foo"), PRPolicy{})).To(MatchError("chunk 0 was not reserved"))
+		Expect(writer.Abandon(0)).To(MatchError("chunk 0 was not reserved"))
+	})
+
+	It("returns write errors for all following chunks", func() {
+		testErr := errors.New("test err")
+		str := NewMockSendStreamI(mockCtrl)
+		writer := NewChunkWriter(str)
+		first := writer.Reserve()
+		second := writer.Reserve()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(writer.WriteChunk(second, []byte("bar"), PRPolicy{})).To(MatchError(testErr))
+		}()
+		// make sure the second chunk is waiting
+		time.Sleep(scaleDuration(10 * time.Millisecond))
+		str.EXPECT().WriteWithPolicy([]byte("foo"), PRPolicy{}).Return(0, testErr)
+		Expect(writer.WriteChunk(first, []byte("foo"), PRPolicy{})).To(MatchError(testErr))
+		Eventually(done).Should(BeClosed())
+		Expect(writer.WriteChunk(writer.Reserve(), []byte("baz"), PRPolicy{})).To(MatchError(testErr))
+	})
+})
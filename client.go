@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
-	"fmt"
 	"net"
 	"strings"
 
@@ -186,14 +185,27 @@ func dialContext(
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
-	if err := validateConfig(config); err != nil {
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
+	userConfig, userPconn := config, pconn
 	config = populateClientConfig(config, createdPacketConn)
+	// The server rejected a frame used for partial reliability on an earlier connection.
+	prDisabledByCache := !config.PR.Disabled && prIncapableHosts.Contains(remoteAddr.String())
+	if prDisabledByCache {
+		config.PR.Disabled = true
+	}
+	if config.DisableOOB {
+		pconn = newPortableConn(pconn)
+	}
 	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDGenerator.ConnectionIDLen(), config.StatelessResetKey, config.Tracer)
 	if err != nil {
 		return nil, err
 	}
+	// Path MTU Discovery only works if the DF bit is set.
+	if !packetHandlers.Capabilities().DF {
+		config.DisablePathMTUDiscovery = true
+	}
 	c, err := newClient(pconn, remoteAddr, config, tlsConf, host, use0RTT, createdPacketConn)
 	if err != nil {
 		return nil, err
@@ -210,9 +222,25 @@ func dialContext(
 	}
 	if c.tracer != nil {
 		c.tracer.StartedConnection(c.sconn.LocalAddr(), c.sconn.RemoteAddr(), c.srcConnID, c.destConnID)
+		if prDisabledByCache {
+			c.tracer.DisabledPR(logging.PRDisabledHostCache)
+		}
 	}
 	//该方法即给c创建了quicConn
 	if err := c.dial(ctx); err != nil {
+		// The server rejected a frame used for partial reliability before the handshake completed, e.g. a frame sent in 0-RTT.
+		// Redial without partial reliability.
+		if !config.PR.Disabled && isPRFrameRejection(err) {
+			prIncapableHosts.Add(remoteAddr.String())
+			if createdPacketConn { // the packet conn was closed together with the connection
+				udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+				if err != nil {
+					return nil, err
+				}
+				userPconn = udpConn
+			}
+			return dialContext(ctx, userPconn, remoteAddr, host, tlsConf, userConfig, use0RTT, createdPacketConn)
+		}
 		return nil, err
 	}
 	return c.conn, nil
@@ -245,15 +273,6 @@ func newClient(
 		tlsConf.ServerName = sni
 	}
 
-	// check that all versions are actually supported
-	if config != nil {
-		for _, v := range config.Versions {
-			if !protocol.IsValidVersion(v) {
-				return nil, fmt.Errorf("%s is not a valid QUIC version", v)
-			}
-		}
-	}
-
 	srcConnID, err := config.ConnectionIDGenerator.GenerateConnectionID()
 	if err != nil {
 		return nil, err
@@ -265,7 +284,7 @@ func newClient(
 	c := &client{
 		srcConnID:         srcConnID,
 		destConnID:        destConnID,
-		sconn:             newSendPconn(pconn, remoteAddr),
+		sconn:             newSendPconn(pconn, remoteAddr, config.PacketMarking, config.PRPacketMarking),
 		createdPacketConn: createdPacketConn,
 		use0RTT:           use0RTT,
 		tlsConf:           tlsConf,
@@ -10,6 +10,7 @@ import (
 
 	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/logging"
 
@@ -64,7 +65,7 @@ var _ = Describe("Client", func() {
 			srcConnID:  connID,
 			destConnID: connID,
 			version:    protocol.VersionTLS,
-			sconn:      newSendPconn(packetConn, addr),
+			sconn:      newSendPconn(packetConn, addr, PacketMarking{}, PacketMarking{}),
 			tracer:     tracer,
 			logger:     utils.DefaultLogger,
 		}
@@ -107,6 +108,7 @@ var _ = Describe("Client", func() {
 			}
 
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			manager.EXPECT().Destroy()
 			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
@@ -140,6 +142,7 @@ var _ = Describe("Client", func() {
 
 		It("uses the tls.Config.ServerName as the hostname, if present", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			manager.EXPECT().Destroy()
 			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
@@ -174,6 +177,7 @@ var _ = Describe("Client", func() {
 
 		It("allows passing host without port as server name", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
@@ -211,8 +215,46 @@ var _ = Describe("Client", func() {
 			Eventually(hostnameChan).Should(Receive(Equal("test.com")))
 		})
 
+		It("disables Path MTU Discovery if the DF bit can't be set", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: false}).AnyTimes()
+			manager.EXPECT().Add(gomock.Any(), gomock.Any())
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
+
+			configChan := make(chan *Config, 1)
+			newClientConnection = func(
+				_ sendConn,
+				_ connRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				conf *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ bool,
+				_ bool,
+				_ logging.ConnectionTracer,
+				_ uint64,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) quicConn {
+				configChan <- conf
+				conn := NewMockQuicConn(mockCtrl)
+				conn.EXPECT().HandshakeComplete().Return(context.Background())
+				conn.EXPECT().run()
+				return conn
+			}
+			tracer.EXPECT().StartedConnection(packetConn.LocalAddr(), addr, gomock.Any(), gomock.Any())
+			_, err := Dial(packetConn, addr, "localhost:1337", tlsConf, config)
+			Expect(err).ToNot(HaveOccurred())
+			var conf *Config
+			Eventually(configChan).Should(Receive(&conf))
+			Expect(conf.DisablePathMTUDiscovery).To(BeTrue())
+			Expect(config.DisablePathMTUDiscovery).To(BeFalse())
+		})
+
 		It("returns after the handshake is complete", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
@@ -255,6 +297,7 @@ var _ = Describe("Client", func() {
 
 		It("returns early connections", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
@@ -304,6 +347,7 @@ var _ = Describe("Client", func() {
 
 		It("returns an error that occurs while waiting for the handshake to complete", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
@@ -341,6 +385,7 @@ var _ = Describe("Client", func() {
 
 		It("closes the connection when the context is canceled", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
@@ -396,6 +441,7 @@ var _ = Describe("Client", func() {
 			}
 
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
 
@@ -472,9 +518,6 @@ var _ = Describe("Client", func() {
 			})
 
 			It("errors when the Config contains an invalid version", func() {
-				manager := NewMockPacketHandlerManager(mockCtrl)
-				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
-
 				version := protocol.VersionNumber(0x1234)
 				_, err := Dial(packetConn, nil, "localhost:1234", tlsConf, &Config{Versions: []protocol.VersionNumber{version}})
 				Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
@@ -515,6 +558,7 @@ var _ = Describe("Client", func() {
 
 		It("creates new connections with the right parameters", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(connID, gomock.Any())
 			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
 
@@ -558,6 +602,7 @@ var _ = Describe("Client", func() {
 
 		It("creates a new connections after version negotiation", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
 			manager.EXPECT().Add(connID, gomock.Any()).Times(2)
 			manager.EXPECT().Destroy()
 			mockMultiplexer.EXPECT().AddConn(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil)
@@ -602,6 +647,61 @@ var _ = Describe("Client", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(counter).To(Equal(2))
 		})
+
+		It("redials without partial reliability, if the server rejects a PR frame during the handshake", func() {
+			origHostCache := prIncapableHosts
+			prIncapableHosts = newPRHostCache()
+			defer func() { prIncapableHosts = origHostCache }()
+
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Capabilities().Return(connCapabilities{DF: true}).AnyTimes()
+			manager.EXPECT().Add(connID, gomock.Any()).Times(2)
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any(), gomock.Any()).Return(manager, nil).Times(2)
+
+			var counter int
+			newClientConnection = func(
+				_ sendConn,
+				_ connRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				configP *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ bool,
+				_ bool,
+				_ logging.ConnectionTracer,
+				_ uint64,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) quicConn {
+				conn := NewMockQuicConn(mockCtrl)
+				conn.EXPECT().HandshakeComplete().Return(context.Background())
+				if counter == 0 {
+					Expect(configP.PR.Disabled).To(BeFalse())
+					conn.EXPECT().run().Return(&qerr.TransportError{
+						Remote:    true,
+						ErrorCode: qerr.FrameEncodingError,
+						FrameType: 0x48,
+					})
+				} else {
+					Expect(configP.PR.Disabled).To(BeTrue())
+					conn.EXPECT().run()
+				}
+				counter++
+				return conn
+			}
+
+			tr := mocklogging.NewMockTracer(mockCtrl)
+			tr.EXPECT().TracerForConnection(gomock.Any(), protocol.PerspectiveClient, gomock.Any()).Return(tracer).Times(2)
+			config := &Config{Tracer: tr, Versions: []protocol.VersionNumber{protocol.VersionTLS}, ConnectionIDGenerator: &mockConnIDGenerator{ConnID: connID}}
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			tracer.EXPECT().DisabledPR(logging.PRDisabledHostCache)
+			_, err := Dial(packetConn, addr, "localhost:1337", tlsConf, config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(counter).To(Equal(2))
+			Expect(prIncapableHosts.Contains(addr.String())).To(BeTrue())
+			Expect(config.PR.Disabled).To(BeFalse()) // the config passed to Dial is not modified
+		})
 	})
 })
 
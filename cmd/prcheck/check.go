@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+)
+
+const alpn = "prcheck"
+
+// errDeadlock is returned when a transfer doesn't complete before the timeout.
+var errDeadlock = errors.New("transfer didn't complete, flow control might be deadlocked")
+
+type checker struct {
+	loss         float64
+	delay        time.Duration
+	size         int
+	chunkSize    int
+	maxSkipRatio float64
+	window       uint64
+	timeout      time.Duration
+	seed         int64
+}
+
+// A result is the result of all checks for one PR policy.
+type result struct {
+	skipRatio   float64 // skip ratio of the transfer with partial reliability enabled, -1 if it failed
+	skipRatioOK bool
+	disabledOK  bool
+	noDeadlock  bool
+	errors      []error
+}
+
+func (r *result) passed() bool { return r.skipRatioOK && r.disabledOK && r.noDeadlock }
+
+func (r *result) skipped() string {
+	if r.skipRatio < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", r.skipRatio*100)
+}
+
+// check runs two transfers using the policy: one with partial reliability enabled,
+// and one with partial reliability disabled on the receiver.
+func (c *checker) check(policy quic.PRPolicy) *result {
+	r := &result{skipRatio: -1, noDeadlock: true}
+
+	skipped, err := c.transfer(policy, false)
+	if err != nil {
+		r.errors = append(r.errors, fmt.Errorf("PR enabled: %w", err))
+		r.noDeadlock = !errors.Is(err, errDeadlock)
+	} else {
+		r.skipRatio = float64(skipped) / float64(c.size)
+		if policy.IsReliable() {
+			r.skipRatioOK = skipped == 0
+		} else {
+			r.skipRatioOK = r.skipRatio <= c.maxSkipRatio
+		}
+		if !r.skipRatioOK {
+			r.errors = append(r.errors, fmt.Errorf("PR enabled: skipped %d of %d bytes", skipped, c.size))
+		}
+	}
+
+	skipped, err = c.transfer(policy, true)
+	if err != nil {
+		r.errors = append(r.errors, fmt.Errorf("PR disabled: %w", err))
+		if errors.Is(err, errDeadlock) {
+			r.noDeadlock = false
+		}
+	} else {
+		r.disabledOK = skipped == 0
+		if !r.disabledOK {
+			r.errors = append(r.errors, fmt.Errorf("PR disabled: skipped %d of %d bytes", skipped, c.size))
+		}
+	}
+	return r
+}
+
+// transfer sends c.size bytes from the client to the server, through a proxy that drops and delays packets.
+// It returns the number of bytes skipped. The data consists of 0xff bytes, and skipped data is read as zeros.
+func (c *checker) transfer(policy quic.PRPolicy, disablePROnServer bool) (skipped int, _ error) {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{alpn}
+	ln, err := quic.ListenAddr("localhost:0", tlsConf, &quic.Config{
+		InitialStreamReceiveWindow:     c.window,
+		MaxStreamReceiveWindow:         c.window,
+		InitialConnectionReceiveWindow: c.window,
+		MaxConnectionReceiveWindow:     c.window,
+		PR: quic.PRConfig{
+			Disabled:     disablePROnServer,
+			MaxSkipRatio: c.maxSkipRatio,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+
+	rng := rand.New(rand.NewSource(c.seed))
+	var rngMutex sync.Mutex
+	proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+		RemoteAddr: ln.Addr().String(),
+		// Only 1-RTT packets are dropped, such that the handshake doesn't take too long.
+		DropPacket: func(_ quicproxy.Direction, packet []byte) bool {
+			if len(packet) == 0 || packet[0]&0x80 > 0 {
+				return false
+			}
+			rngMutex.Lock()
+			defer rngMutex.Unlock()
+			return rng.Float64() < c.loss
+		},
+		DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return c.delay },
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer proxy.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	type serverResult struct {
+		skipped int
+		err     error
+	}
+	serverChan := make(chan serverResult, 1)
+	go func() {
+		skipped, err := receive(ctx, ln, c.size)
+		serverChan <- serverResult{skipped: skipped, err: err}
+	}()
+
+	clientErr := c.send(ctx, proxy.LocalAddr().String(), policy)
+	var res serverResult
+	select {
+	case res = <-serverChan:
+	case <-ctx.Done():
+		res.err = ctx.Err()
+	}
+	for _, err := range []error{clientErr, res.err} {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return 0, errDeadlock
+		}
+	}
+	if clientErr != nil {
+		return 0, clientErr
+	}
+	return res.skipped, res.err
+}
+
+func (c *checker) send(ctx context.Context, addr string, policy quic.PRPolicy) error {
+	conn, err := quic.DialAddrContext(ctx, addr, &tls.Config{
+		// The server uses a test certificate.
+		InsecureSkipVerify: true,
+		NextProtos:         []string{alpn},
+	}, &quic.Config{PR: quic.PRConfig{RandSeed: c.seed}})
+	if err != nil {
+		return err
+	}
+	defer conn.CloseWithError(0, "")
+
+	str, err := conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		str.SetWriteDeadline(deadline)
+	}
+	chunk := make([]byte, c.chunkSize)
+	for i := range chunk {
+		chunk[i] = 0xff
+	}
+	for n := 0; n < c.size; n += len(chunk) {
+		if c.size-n < len(chunk) {
+			chunk = chunk[:c.size-n]
+		}
+		if _, err := str.WriteWithPolicy(chunk, policy); err != nil {
+			return translateTimeout(ctx, err)
+		}
+	}
+	if err := str.Close(); err != nil {
+		return err
+	}
+	// Keep the connection open until the server received all data.
+	select {
+	case <-conn.Context().Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// receive accepts a single connection, reads the data sent on the first unidirectional stream,
+// and returns the number of bytes that were skipped.
+func receive(ctx context.Context, ln quic.Listener, size int) (skipped int, _ error) {
+	conn, err := ln.Accept(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.CloseWithError(0, "")
+
+	str, err := conn.AcceptUniStream(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		str.SetReadDeadline(deadline)
+	}
+	b := make([]byte, 4096)
+	var n int
+	for {
+		m, err := str.Read(b)
+		for _, c := range b[:m] {
+			if c == 0 {
+				skipped++
+			}
+		}
+		n += m
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, translateTimeout(ctx, err)
+		}
+	}
+	if n != size {
+		return 0, fmt.Errorf("received %d bytes, expected %d", n, size)
+	}
+	return skipped, nil
+}
+
+// translateTimeout returns context.DeadlineExceeded if err was caused by the deadline of ctx.
+func translateTimeout(ctx context.Context, err error) error {
+	var nerr interface{ Timeout() bool }
+	if errors.As(err, &nerr) && nerr.Timeout() && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+// prcheck checks that the partial reliability policy engine upholds its invariants.
+// For every PR policy of the sweep, it transfers data between two local endpoints through a proxy that
+// drops and delays packets, and verifies that
+//   - the receiver's maximum skip ratio (PRConfig.MaxSkipRatio) is never exceeded,
+//     and that no data is skipped at all if the reliable policy is used,
+//   - no data is skipped if partial reliability is disabled on the receiver (PRConfig.Disabled),
+//   - flow control never deadlocks: every transfer completes, even with small receive windows.
+//
+// It prints a pass / fail matrix, and exits with a non-zero status code if any check failed:
+//
+//	prcheck -loss 0.05 -delay 10ms -policies deadline:20,deadline:200,times:0,probability:5000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const defaultPolicies = "reliable,abandon,deadline:10,deadline:50,deadline:200,times:0,times:1,times:3,probability:0,probability:5000,priority:0"
+
+func main() {
+	policiesFlag := flag.String("policies", defaultPolicies, "comma-separated list of the PR policies to check: reliable, abandon, probability:N, times:N, deadline:MS or priority:N")
+	loss := flag.Float64("loss", 0.05, "probability that the proxy drops a 1-RTT packet, in each direction")
+	delay := flag.Duration("delay", 10*time.Millisecond, "one-way delay added by the proxy")
+	size := flag.Int("size", 1<<20, "number of bytes transferred per check")
+	chunkSize := flag.Int("chunk", 1000, "number of bytes passed to every WriteWithPolicy call")
+	maxSkipRatio := flag.Float64("max-skip-ratio", 0.2, "maximum skip ratio configured on the receiver")
+	window := flag.Uint64("window", 64<<10, "receive window of the receiver, for the stream and the connection")
+	timeout := flag.Duration("timeout", 30*time.Second, "time after which a transfer is considered deadlocked")
+	seed := flag.Int64("seed", 1, "seed of the packet loss, and of the retransmission decisions of the probability policy")
+	verbose := flag.Bool("v", false, "verbose")
+	flag.Parse()
+
+	logger := utils.DefaultLogger
+	if *verbose {
+		logger.SetLogLevel(utils.LogLevelDebug)
+	} else {
+		logger.SetLogLevel(utils.LogLevelError)
+	}
+	logger.SetLogTimeFormat("")
+
+	policies, err := parsePolicies(*policiesFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *maxSkipRatio <= 0 || *maxSkipRatio >= 1 {
+		fmt.Fprintln(os.Stderr, "the maximum skip ratio must be between 0 and 1")
+		os.Exit(2)
+	}
+	if *size <= 0 || *chunkSize <= 0 {
+		fmt.Fprintln(os.Stderr, "size and chunk must be positive")
+		os.Exit(2)
+	}
+
+	c := &checker{
+		loss:         *loss,
+		delay:        *delay,
+		size:         *size,
+		chunkSize:    *chunkSize,
+		maxSkipRatio: *maxSkipRatio,
+		window:       *window,
+		timeout:      *timeout,
+		seed:         *seed,
+	}
+	fmt.Printf("%d bytes per transfer, %.1f%% loss, %s one-way delay, max skip ratio %.2f, %d byte receive window\n\n",
+		*size, *loss*100, *delay, *maxSkipRatio, *window)
+	fmt.Printf("%-20s %-10s %-16s %-24s %s\n", "policy", "skipped", "skip ratio", "no skips when disabled", "no deadlock")
+	failed := false
+	for _, p := range policies {
+		r := c.check(p)
+		fmt.Printf("%-20s %-10s %-16s %-24s %s\n", p, r.skipped(), passFail(r.skipRatioOK), passFail(r.disabledOK), passFail(r.noDeadlock))
+		if !r.passed() {
+			failed = true
+		}
+		for _, err := range r.errors {
+			fmt.Printf("  %s\n", err)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func passFail(ok bool) string {
+	if ok {
+		return "PASS"
+	}
+	return "FAIL"
+}
+
+func parsePolicies(s string) ([]quic.PRPolicy, error) {
+	var policies []quic.PRPolicy
+	for _, p := range strings.Split(s, ",") {
+		policy, err := parsePolicy(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// parsePolicy parses a PR policy, in the form "reliable", "abandon", or "<kind>:<value>".
+func parsePolicy(s string) (quic.PRPolicy, error) {
+	switch s {
+	case "reliable":
+		return quic.PRPolicy{}, nil
+	case "abandon":
+		return quic.PRPolicy{PTDA: quic.PTDAAbandon}, nil
+	}
+	kind, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return quic.PRPolicy{}, fmt.Errorf("invalid policy: %s", s)
+	}
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return quic.PRPolicy{}, fmt.Errorf("invalid policy value: %s", value)
+	}
+	switch kind {
+	case "probability":
+		return quic.PRPolicy{PTDA: quic.PTDAProbability, Value: v}, nil
+	case "times":
+		return quic.PRPolicy{PTDA: quic.PTDATimes, Value: v}, nil
+	case "deadline":
+		return quic.PRPolicy{PTDA: quic.PTDADeadline, Value: v}, nil
+	case "priority":
+		return quic.PRPolicy{PTDA: quic.PTDAPriority, Value: v}, nil
+	default:
+		return quic.PRPolicy{}, fmt.Errorf("invalid policy: %s", s)
+	}
+}
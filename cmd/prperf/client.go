@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+func runClient(addr string, m mode, policy quic.PRPolicy, size int, rate uint64, duration time.Duration) error {
+	tlsConf := &tls.Config{
+		// The server uses a test certificate.
+		InsecureSkipVerify: true,
+		NextProtos:         []string{alpn},
+	}
+	conn, err := quic.DialAddr(addr, tlsConf, config())
+	if err != nil {
+		return err
+	}
+	defer conn.CloseWithError(0, "")
+
+	str, err := conn.OpenStreamSync(context.Background())
+	if err != nil {
+		return err
+	}
+	if _, err := str.Write(encodeRequest(m, size)); err != nil {
+		return err
+	}
+
+	msg := newMessage(size)
+	start := time.Now()
+	var sent uint64
+	for time.Since(start) < duration {
+		if rate > 0 {
+			next := start.Add(time.Duration(float64(sent) * float64(size) / float64(rate) * float64(time.Second)))
+			if d := time.Until(next); d > 0 {
+				time.Sleep(d)
+			}
+		}
+		sent++
+		setHeader(msg, sent, time.Now())
+		switch m {
+		case modeReliable:
+			_, err = str.Write(msg)
+		case modePR:
+			_, err = str.WriteWithPolicy(msg, policy)
+		case modeDatagram:
+			err = conn.SendMessageWithPolicy(msg, policy)
+			// the message counts as lost
+			if errors.Is(err, quic.ErrDatagramDropped) {
+				err = nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if m == modeDatagram {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, sent)
+		if _, err := str.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := str.Close(); err != nil {
+		return err
+	}
+
+	var r report
+	if err := json.NewDecoder(str).Decode(&r); err != nil {
+		return err
+	}
+	printReport(m, policy, size, &r)
+	return nil
+}
+
+func printReport(m mode, policy quic.PRPolicy, size int, r *report) {
+	if m == modeReliable {
+		fmt.Printf("mode:       %s\n", m)
+	} else {
+		fmt.Printf("mode:       %s, policy %s\n", m, policy)
+	}
+	fmt.Printf("sent:       %d messages of %d bytes\n", r.Messages, size)
+	var loss, skipRatio, throughput float64
+	if r.Messages > 0 {
+		loss = 100 * float64(r.Messages-r.Received) / float64(r.Messages)
+		skipRatio = 100 * float64(r.Skipped) / float64(r.Messages*uint64(size))
+	}
+	if r.Duration > 0 {
+		throughput = float64(r.Bytes) * 8 / r.Duration.Seconds() / 1e6
+	}
+	fmt.Printf("received:   %d messages, %.2f%% lost or incomplete\n", r.Received, loss)
+	if m == modePR {
+		fmt.Printf("skipped:    %d bytes, %.2f%% of the data\n", r.Skipped, skipRatio)
+	}
+	fmt.Printf("throughput: %.2f Mbit/s\n", throughput)
+	fmt.Printf("latency:    p50 %s, p90 %s, p99 %s, max %s\n", r.P50, r.P90, r.P99, r.Max)
+}
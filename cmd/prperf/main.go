@@ -0,0 +1,102 @@
+// prperf measures the throughput, loss, skip ratio and latency of a network path,
+// for data sent on a reliable stream, on a partially reliable stream, or in datagrams.
+//
+// Start the server:
+//
+//	prperf -server -addr 0.0.0.0:4433
+//
+// and run the client against it, once for every mode to compare:
+//
+//	prperf -addr server:4433 -mode reliable -rate 10000000
+//	prperf -addr server:4433 -mode pr -policy deadline:100 -rate 10000000
+//	prperf -addr server:4433 -mode datagram -policy deadline:100 -rate 10000000
+//
+// The client sends fixed-size messages for the configured duration, and prints the statistics collected by the server.
+// Latencies are measured from the time the message was passed to quic-go on the client
+// to the time it was read on the server. They are only meaningful if the clocks of both hosts are synchronized.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+func main() {
+	server := flag.Bool("server", false, "run the server")
+	addr := flag.String("addr", "localhost:4433", "address to listen on (server) or connect to (client)")
+	modeFlag := flag.String("mode", "pr", "how to send the data: reliable, pr or datagram")
+	policyFlag := flag.String("policy", "deadline:100", "PR policy used by the pr and datagram modes: reliable, abandon, probability:N, times:N, deadline:MS or priority:N")
+	size := flag.Int("size", 1000, "message size in bytes")
+	rate := flag.Uint64("rate", 0, "sending rate in bytes per second, 0 sends as fast as possible")
+	duration := flag.Duration("time", 10*time.Second, "how long to send data")
+	verbose := flag.Bool("v", false, "verbose")
+	flag.Parse()
+
+	logger := utils.DefaultLogger
+	if *verbose {
+		logger.SetLogLevel(utils.LogLevelDebug)
+	} else {
+		logger.SetLogLevel(utils.LogLevelInfo)
+	}
+	logger.SetLogTimeFormat("")
+
+	if *server {
+		log.Fatal(runServer(*addr))
+	}
+
+	m, err := parseMode(*modeFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	policy, err := parsePolicy(*policyFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *size < headerLen {
+		fmt.Fprintf(os.Stderr, "message size must be at least %d bytes\n", headerLen)
+		os.Exit(2)
+	}
+	if err := runClient(*addr, m, policy, *size, *rate, *duration); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parsePolicy parses a PR policy, in the form "reliable", "abandon", or "<kind>:<value>".
+func parsePolicy(s string) (quic.PRPolicy, error) {
+	switch s {
+	case "reliable":
+		return quic.PRPolicy{}, nil
+	case "abandon":
+		return quic.PRPolicy{PTDA: quic.PTDAAbandon}, nil
+	}
+	kind, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return quic.PRPolicy{}, fmt.Errorf("invalid policy: %s", s)
+	}
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return quic.PRPolicy{}, fmt.Errorf("invalid policy value: %s", value)
+	}
+	switch kind {
+	case "probability":
+		return quic.PRPolicy{PTDA: quic.PTDAProbability, Value: v}, nil
+	case "times":
+		return quic.PRPolicy{PTDA: quic.PTDATimes, Value: v}, nil
+	case "deadline":
+		return quic.PRPolicy{PTDA: quic.PTDADeadline, Value: v}, nil
+	case "priority":
+		return quic.PRPolicy{PTDA: quic.PTDAPriority, Value: v}, nil
+	default:
+		return quic.PRPolicy{}, fmt.Errorf("invalid policy: %s", s)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const alpn = "prperf"
+
+// mode says how the client sends the data.
+type mode uint8
+
+const (
+	modeReliable mode = iota + 1
+	modePR
+	modeDatagram
+)
+
+func parseMode(s string) (mode, error) {
+	switch s {
+	case "reliable":
+		return modeReliable, nil
+	case "pr":
+		return modePR, nil
+	case "datagram":
+		return modeDatagram, nil
+	default:
+		return 0, fmt.Errorf("invalid mode: %s", s)
+	}
+}
+
+func (m mode) String() string {
+	switch m {
+	case modeReliable:
+		return "reliable"
+	case modePR:
+		return "pr"
+	case modeDatagram:
+		return "datagram"
+	default:
+		return fmt.Sprintf("unknown mode %d", m)
+	}
+}
+
+// The client opens a bidirectional stream, and reliably sends the mode and the message size (a uint32).
+// For the reliable and pr modes, the messages follow on the same stream, and the client closes it when done.
+// For the datagram mode, the messages are sent in datagrams. When done, the client reliably sends the number
+// of messages sent (a uint64) on the stream, and closes it.
+// The server then sends a JSON encoded report and closes the stream.
+const requestLen = 5
+
+// Every message starts with a header containing the sequence number and the send time (in Unix nanoseconds).
+// Both are never 0, such that a header that was skipped by the sender (and is read as zeros) can be detected.
+// The rest of the message is filled with 0xff.
+const headerLen = 16
+
+func encodeRequest(m mode, size int) []byte {
+	b := make([]byte, requestLen)
+	b[0] = byte(m)
+	binary.BigEndian.PutUint32(b[1:], uint32(size))
+	return b
+}
+
+func decodeRequest(b []byte) (mode, int) {
+	return mode(b[0]), int(binary.BigEndian.Uint32(b[1:]))
+}
+
+func newMessage(size int) []byte {
+	msg := make([]byte, size)
+	for i := headerLen; i < size; i++ {
+		msg[i] = 0xff
+	}
+	return msg
+}
+
+func setHeader(msg []byte, seq uint64, t time.Time) {
+	binary.BigEndian.PutUint64(msg, seq)
+	binary.BigEndian.PutUint64(msg[8:], uint64(t.UnixNano()))
+}
+
+// A report contains the statistics collected by the server.
+type report struct {
+	Messages uint64        // number of messages sent by the client
+	Received uint64        // number of messages received completely
+	Bytes    uint64        // number of bytes received, not counting skipped data
+	Skipped  uint64        // number of bytes skipped by the client
+	Duration time.Duration // time between the first and the last message received
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+type stats struct {
+	mutex sync.Mutex
+
+	done        bool
+	first, last time.Time
+	messages    uint64
+	received    uint64
+	bytes       uint64
+	skipped     uint64
+	latencies   []time.Duration
+}
+
+// addMessage records a message. Skipped data is read as zeros.
+func (s *stats) addMessage(msg []byte, now time.Time) {
+	seq := binary.BigEndian.Uint64(msg)
+	sent := int64(binary.BigEndian.Uint64(msg[8:]))
+	var skipped int
+	if seq == 0 || sent == 0 {
+		skipped += headerLen
+	}
+	for _, b := range msg[headerLen:] {
+		if b == 0 {
+			skipped++
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.done {
+		return
+	}
+	if s.first.IsZero() {
+		s.first = now
+	}
+	s.last = now
+	s.bytes += uint64(len(msg) - skipped)
+	s.skipped += uint64(skipped)
+	if skipped == 0 {
+		s.received++
+		s.latencies = append(s.latencies, now.Sub(time.Unix(0, sent)))
+	}
+}
+
+// finish stops recording messages, and returns the report.
+// messages is the number of messages sent by the client.
+func (s *stats) finish(messages uint64) *report {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.done = true
+	r := &report{
+		Messages: messages,
+		Received: s.received,
+		Bytes:    s.bytes,
+		Skipped:  s.skipped,
+		Duration: s.last.Sub(s.first),
+	}
+	if len(s.latencies) > 0 {
+		sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+		percentile := func(p float64) time.Duration {
+			return s.latencies[int(p*float64(len(s.latencies)-1))]
+		}
+		r.P50 = percentile(0.5)
+		r.P90 = percentile(0.9)
+		r.P99 = percentile(0.99)
+		r.Max = s.latencies[len(s.latencies)-1]
+	}
+	return r
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// drainPeriod is the time the server keeps receiving datagrams after the client is done sending,
+// since datagrams might arrive after the end of the control stream.
+const drainPeriod = 500 * time.Millisecond
+
+func config() *quic.Config {
+	return &quic.Config{
+		EnableDatagrams: true,
+		// Data written using Write is sent reliably. The pr mode explicitly uses WriteWithPolicy.
+		PR: quic.PRConfig{DefaultPolicy: &quic.PRPolicy{}},
+	}
+}
+
+func runServer(addr string) error {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{alpn}
+	ln, err := quic.ListenAddr(addr, tlsConf, config())
+	if err != nil {
+		return err
+	}
+	utils.DefaultLogger.Infof("Listening on %s", ln.Addr())
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := handleConn(conn); err != nil {
+				utils.DefaultLogger.Errorf("Test with %s failed: %s", conn.RemoteAddr(), err)
+				conn.CloseWithError(1, err.Error())
+				return
+			}
+			conn.CloseWithError(0, "")
+		}()
+	}
+}
+
+func handleConn(conn quic.Connection) error {
+	var s stats
+	// Datagrams might arrive before the stream.
+	go func() {
+		for {
+			msg, err := conn.ReceiveMessage()
+			if err != nil {
+				return
+			}
+			if len(msg) >= headerLen {
+				s.addMessage(msg, time.Now())
+			}
+		}
+	}()
+
+	str, err := conn.AcceptStream(context.Background())
+	if err != nil {
+		return err
+	}
+	req := make([]byte, requestLen)
+	if _, err := io.ReadFull(str, req); err != nil {
+		return err
+	}
+	m, size := decodeRequest(req)
+	utils.DefaultLogger.Infof("Starting %s test with %s, using %d byte messages", m, conn.RemoteAddr(), size)
+
+	var messages uint64
+	switch m {
+	case modeReliable, modePR:
+		msg := make([]byte, size)
+		for {
+			if _, err := io.ReadFull(str, msg); err != nil {
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					break
+				}
+				return err
+			}
+			s.addMessage(msg, time.Now())
+			messages++
+		}
+	case modeDatagram:
+		b := make([]byte, 8)
+		if _, err := io.ReadFull(str, b); err != nil {
+			return err
+		}
+		messages = binary.BigEndian.Uint64(b)
+		if _, err := io.Copy(io.Discard, str); err != nil {
+			return err
+		}
+		time.Sleep(drainPeriod)
+	}
+
+	r := s.finish(messages)
+	utils.DefaultLogger.Infof("Finished %s test with %s: received %d of %d messages", m, conn.RemoteAddr(), r.Received, r.Messages)
+	if err := json.NewEncoder(str).Encode(r); err != nil {
+		return err
+	}
+	if err := str.Close(); err != nil {
+		return err
+	}
+	// wait for the client to close the connection, such that the report is delivered
+	<-conn.Context().Done()
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// An indexEntry holds the reports of a qlog file.
+// The reports are only valid as long as the size and the modification time of the file don't change.
+type indexEntry struct {
+	Size    int64              `json:"size"`
+	ModTime time.Time          `json:"mod_time"`
+	Reports map[string]*report `json:"reports"` // indexed by the direction
+}
+
+// The index stores the reports of qlog files, so that unchanged files don't need to be parsed again.
+type index struct {
+	Entries map[string]*indexEntry `json:"entries"` // indexed by the absolute path of the qlog file
+}
+
+func newIndex() *index {
+	return &index{Entries: make(map[string]*indexEntry)}
+}
+
+// loadIndex loads the index from a file. If the file doesn't exist, an empty index is returned.
+func loadIndex(file string) (*index, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return newIndex(), nil
+		}
+		return nil, err
+	}
+	idx := newIndex()
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, err
+	}
+	if idx.Entries == nil {
+		idx.Entries = make(map[string]*indexEntry)
+	}
+	return idx, nil
+}
+
+func (idx *index) Save(file string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// Report returns the report of a qlog file, parsing it only if it isn't indexed yet or it changed.
+func (idx *index) Report(file string, dir direction) (*report, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := idx.Entries[path]
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		entry = &indexEntry{
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			Reports: make(map[string]*report),
+		}
+		idx.Entries[path] = entry
+	}
+	if r, ok := entry.Reports[dir.String()]; ok {
+		r.File = file
+		return r, nil
+	}
+	r, err := parseQlog(file, dir)
+	if err != nil {
+		return nil, err
+	}
+	entry.Reports[dir.String()] = r
+	return r, nil
+}
@@ -0,0 +1,98 @@
+// prqlog reads qlogs recorded by quic-go and reports, for every stream carrying partially reliable data,
+// how much of the data was delivered, which ranges were skipped and how often deadlines were met.
+// It is meant to help choosing the PR policies (PTDA values) of an application.
+//
+//	prqlog client_1234.qlog server_1234.qlog
+//	prqlog -index qlogs.index ./qlogs
+//
+// Directories are searched for .qlog files. By default, the report covers the data sent by the endpoint
+// that recorded the qlog. Use -received to report the data it received instead.
+//
+// For every stream, the report contains:
+//   - the number of bytes of the stream seen in the qlog, sent or skipped,
+//   - the number of skipped bytes, i.e. the bytes covered by PR_ACK_NOTIFY frames,
+//   - the delivery ratio, the share of the bytes that was not skipped,
+//   - the deadline hit rate, the share of the bytes sent with a deadline policy that was not skipped,
+//   - the skipped ranges.
+//
+// Parsing large qlogs is slow. With -index, the reports are stored in an index file,
+// and qlogs that didn't change since the last run are not parsed again.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	indexFile := flag.String("index", "", "file used to store the reports, to avoid parsing unchanged qlogs again")
+	received := flag.Bool("received", false, "report the data received by the endpoint that recorded the qlog, instead of the data sent")
+	maxRanges := flag.Int("ranges", 10, "maximum number of skipped ranges printed per stream, 0 prints all ranges")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: prqlog [flags] <qlog file or directory>...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	files, err := findQlogs(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	idx := newIndex()
+	if *indexFile != "" {
+		if idx, err = loadIndex(*indexFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	dir := directionSent
+	if *received {
+		dir = directionReceived
+	}
+	for _, file := range files {
+		r, err := idx.Report(file, dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r.Print(os.Stdout, *maxRanges)
+	}
+	if *indexFile != "" {
+		if err := idx.Save(*indexFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// findQlogs returns the qlog files at the given paths.
+// Directories are searched recursively for files with the .qlog extension.
+func findQlogs(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		if err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(path, ".qlog") {
+				files = append(files, path)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
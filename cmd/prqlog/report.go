@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// direction says if the report covers the data sent or received by the endpoint that recorded the qlog.
+type direction uint8
+
+const (
+	directionSent direction = iota
+	directionReceived
+)
+
+// eventName is the name of the qlog event carrying the frames of the direction.
+func (d direction) eventName() string {
+	if d == directionReceived {
+		return "transport:packet_received"
+	}
+	return "transport:packet_sent"
+}
+
+func (d direction) String() string {
+	if d == directionReceived {
+		return "received"
+	}
+	return "sent"
+}
+
+// A byteRange is the range [Start, End) of stream data.
+type byteRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+func (r byteRange) String() string { return fmt.Sprintf("[%d, %d)", r.Start, r.End) }
+
+// A rangeSet is a sorted list of non-overlapping byte ranges.
+type rangeSet []byteRange
+
+func (s *rangeSet) Add(start, end uint64) {
+	if end <= start {
+		return
+	}
+	ranges := *s
+	// find the first range that ends at or after start, i.e. that can be merged with the new range
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].End >= start })
+	j := i
+	for j < len(ranges) && ranges[j].Start <= end {
+		if ranges[j].Start < start {
+			start = ranges[j].Start
+		}
+		if ranges[j].End > end {
+			end = ranges[j].End
+		}
+		j++
+	}
+	merged := append(ranges[:i:i], byteRange{Start: start, End: end})
+	*s = append(merged, ranges[j:]...)
+}
+
+func (s rangeSet) Len() uint64 {
+	var n uint64
+	for _, r := range s {
+		n += r.End - r.Start
+	}
+	return n
+}
+
+// A streamReport describes the partially reliable data of a stream.
+type streamReport struct {
+	StreamID quic.StreamID `json:"stream_id"`
+	// Bytes is the number of bytes of the stream that were sent or skipped.
+	Bytes        uint64 `json:"bytes"`
+	SkippedBytes uint64 `json:"skipped_bytes"`
+	// DeadlineBytes is the number of bytes sent with a deadline policy,
+	// and DeadlineMissedBytes the number of those bytes that were skipped.
+	DeadlineBytes       uint64      `json:"deadline_bytes"`
+	DeadlineMissedBytes uint64      `json:"deadline_missed_bytes"`
+	Skipped             []byteRange `json:"skipped"`
+}
+
+// DeliveryRatio returns the share of the bytes that was not skipped.
+func (r *streamReport) DeliveryRatio() float64 {
+	if r.Bytes == 0 {
+		return 1
+	}
+	return float64(r.Bytes-r.SkippedBytes) / float64(r.Bytes)
+}
+
+// DeadlineHitRate returns the share of the bytes sent with a deadline policy that was not skipped.
+// It returns false if no data was sent with a deadline policy.
+func (r *streamReport) DeadlineHitRate() (float64, bool) {
+	if r.DeadlineBytes == 0 {
+		return 0, false
+	}
+	return float64(r.DeadlineBytes-r.DeadlineMissedBytes) / float64(r.DeadlineBytes), true
+}
+
+// A report describes the partially reliable streams of a qlog.
+type report struct {
+	File         string         `json:"file"`
+	VantagePoint string         `json:"vantage_point"`
+	ODCID        string         `json:"odcid"`
+	Direction    direction      `json:"direction"`
+	Streams      []streamReport `json:"streams"`
+}
+
+func (r *report) Print(w io.Writer, maxRanges int) {
+	fmt.Fprintf(w, "%s (%s, ODCID %s), data %s\n", r.File, r.VantagePoint, r.ODCID, r.Direction)
+	if len(r.Streams) == 0 {
+		fmt.Fprintf(w, "no partially reliable streams\n\n")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "stream\tbytes\tskipped\tdelivered\tdeadline hit rate\t")
+	for i := range r.Streams {
+		s := &r.Streams[i]
+		hitRate := "-"
+		if rate, ok := s.DeadlineHitRate(); ok {
+			hitRate = fmt.Sprintf("%.2f%%", 100*rate)
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.2f%%\t%s\t\n", s.StreamID, s.Bytes, s.SkippedBytes, 100*s.DeliveryRatio(), hitRate)
+	}
+	tw.Flush()
+	for _, s := range r.Streams {
+		if len(s.Skipped) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "stream %d skipped:", s.StreamID)
+		for i, rng := range s.Skipped {
+			if maxRanges > 0 && i == maxRanges {
+				fmt.Fprintf(w, " and %d more", len(s.Skipped)-maxRanges)
+				break
+			}
+			fmt.Fprintf(w, " %s", rng)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+}
+
+// The qlog structures, as written by the qlog package. Only the fields needed for the report are decoded.
+type (
+	qlogHeader struct {
+		Trace struct {
+			VantagePoint struct {
+				Type string `json:"type"`
+			} `json:"vantage_point"`
+			CommonFields struct {
+				ODCID string `json:"ODCID"`
+			} `json:"common_fields"`
+		} `json:"trace"`
+	}
+	qlogEvent struct {
+		Name string `json:"name"`
+		Data struct {
+			Frames []qlogFrame `json:"frames"`
+		} `json:"data"`
+	}
+	qlogFrame struct {
+		FrameType string `json:"frame_type"`
+		StreamID  int64  `json:"stream_id"`
+		Offset    uint64 `json:"offset"`
+		Length    uint64 `json:"length"`
+		PTDA      uint64 `json:"ptda"`
+	}
+)
+
+// streamStats collects the ranges of a stream while parsing a qlog.
+type streamStats struct {
+	pr       bool
+	all      rangeSet
+	skipped  rangeSet
+	deadline rangeSet
+	missed   rangeSet
+}
+
+func parseQlog(file string, dir direction) (*report, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	var hdr qlogHeader
+	if err := dec.Decode(&hdr); err != nil {
+		return nil, fmt.Errorf("%s: reading qlog header failed: %w", file, err)
+	}
+	streams := make(map[int64]*streamStats)
+	eventName := dir.eventName()
+	for {
+		var ev qlogEvent
+		if err := dec.Decode(&ev); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if ev.Name != eventName {
+			continue
+		}
+		for _, frame := range ev.Data.Frames {
+			var isPR, skipped bool
+			switch frame.FrameType {
+			case "stream":
+			case "pr_stream":
+				isPR = true
+			case "pr_ack_notify":
+				isPR = true
+				skipped = true
+			default:
+				continue
+			}
+			s, ok := streams[frame.StreamID]
+			if !ok {
+				s = &streamStats{}
+				streams[frame.StreamID] = s
+			}
+			start, end := frame.Offset, frame.Offset+frame.Length
+			s.pr = s.pr || isPR
+			s.all.Add(start, end)
+			if skipped {
+				s.skipped.Add(start, end)
+			}
+			if isPR && byte(frame.PTDA)&quic.PTDADeadline != 0 {
+				s.deadline.Add(start, end)
+				if skipped {
+					s.missed.Add(start, end)
+				}
+			}
+		}
+	}
+
+	r := &report{
+		File:         file,
+		VantagePoint: hdr.Trace.VantagePoint.Type,
+		ODCID:        hdr.Trace.CommonFields.ODCID,
+		Direction:    dir,
+	}
+	for id, s := range streams {
+		if !s.pr {
+			continue
+		}
+		r.Streams = append(r.Streams, streamReport{
+			StreamID:            quic.StreamID(id),
+			Bytes:               s.all.Len(),
+			SkippedBytes:        s.skipped.Len(),
+			DeadlineBytes:       s.deadline.Len(),
+			DeadlineMissedBytes: s.missed.Len(),
+			Skipped:             s.skipped,
+		})
+	}
+	sort.Slice(r.Streams, func(i, j int) bool { return r.Streams[i].StreamID < r.Streams[j].StreamID })
+	return r, nil
+}
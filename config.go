@@ -2,6 +2,7 @@ package quic
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
@@ -19,16 +20,90 @@ func (c *Config) handshakeTimeout() time.Duration {
 	return utils.Max(protocol.DefaultHandshakeTimeout, 2*c.HandshakeIdleTimeout)
 }
 
-func validateConfig(config *Config) error {
-	if config == nil {
+// Validate checks the Config for invalid values, and for settings that conflict with each other.
+// Dial and Listen call Validate, so calling it is optional.
+// It allows detecting configuration errors early, e.g. when loading the configuration of an application.
+// A nil Config is valid.
+func (c *Config) Validate() error {
+	if c == nil {
 		return nil
 	}
-	if config.MaxIncomingStreams > 1<<60 {
+	for _, v := range c.Versions {
+		if !protocol.IsValidVersion(v) {
+			return fmt.Errorf("%s is not a valid QUIC version", v)
+		}
+	}
+	if c.MaxIncomingStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingStreams")
 	}
-	if config.MaxIncomingUniStreams > 1<<60 {
+	if c.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if c.InitialStreamReceiveWindow > 0 && c.MaxStreamReceiveWindow > 0 && c.InitialStreamReceiveWindow > c.MaxStreamReceiveWindow {
+		return fmt.Errorf("Config.InitialStreamReceiveWindow (%d) is larger than Config.MaxStreamReceiveWindow (%d)", c.InitialStreamReceiveWindow, c.MaxStreamReceiveWindow)
+	}
+	if c.InitialConnectionReceiveWindow > 0 && c.MaxConnectionReceiveWindow > 0 && c.InitialConnectionReceiveWindow > c.MaxConnectionReceiveWindow {
+		return fmt.Errorf("Config.InitialConnectionReceiveWindow (%d) is larger than Config.MaxConnectionReceiveWindow (%d)", c.InitialConnectionReceiveWindow, c.MaxConnectionReceiveWindow)
+	}
+	if c.TokenIPv4PrefixLen < 0 || c.TokenIPv4PrefixLen > 32 {
+		return errors.New("invalid value for Config.TokenIPv4PrefixLen")
+	}
+	if c.TokenIPv6PrefixLen < 0 || c.TokenIPv6PrefixLen > 128 {
+		return errors.New("invalid value for Config.TokenIPv6PrefixLen")
+	}
+	if c.ClientHelloFragmentSize < 0 || (c.ClientHelloFragmentSize > 0 && c.ClientHelloFragmentSize < protocol.MinClientHelloFragmentSize) {
+		return errors.New("invalid value for Config.ClientHelloFragmentSize")
+	}
+	if c.ZeroRTTReplayFilter != nil && c.ZeroRTTReplayFilter.window <= 0 {
+		return errors.New("invalid value for Config.ZeroRTTReplayFilter")
+	}
+	if c.ReusePortSockets < 0 || c.ReusePortSockets > maxReusePortSockets {
+		return errors.New("invalid value for Config.ReusePortSockets")
+	}
+	if c.ReusePortSockets > 1 && c.ConnectionIDGenerator != nil && c.ConnectionIDGenerator.ConnectionIDLen() == 0 {
+		return errors.New("Config.ReusePortSockets requires connection IDs")
+	}
+	if c.PacketMarking.DSCP > maxDSCP {
+		return errors.New("invalid value for Config.PacketMarking.DSCP")
+	}
+	if c.PacketMarking.IPv6FlowLabel > maxIPv6FlowLabel {
+		return errors.New("invalid value for Config.PacketMarking.IPv6FlowLabel")
+	}
+	if c.PRPacketMarking.DSCP > maxDSCP {
+		return errors.New("invalid value for Config.PRPacketMarking.DSCP")
+	}
+	if c.PRPacketMarking.IPv6FlowLabel > maxIPv6FlowLabel {
+		return errors.New("invalid value for Config.PRPacketMarking.IPv6FlowLabel")
+	}
+	if c.DatagramOverflowPolicy > DatagramOverflowDropLowestPriority {
+		return errors.New("invalid value for Config.DatagramOverflowPolicy")
+	}
+	if c.DatagramOverflowPolicy != DatagramOverflowBlock && !c.EnableDatagrams {
+		return errors.New("Config.DatagramOverflowPolicy requires Config.EnableDatagrams")
+	}
+	if c.PR.IdleStreamTimeout < 0 {
+		return errors.New("invalid value for Config.PR.IdleStreamTimeout")
+	}
+	if c.PR.ScavengerCongestionControl && !c.PR.SeparatePRPackets {
+		return errors.New("Config.PR.ScavengerCongestionControl requires Config.PR.SeparatePRPackets")
+	}
+	if c.PR.ScavengerCongestionControl && c.Scheduler != nil {
+		return errors.New("Config.PR.ScavengerCongestionControl can't be used with a Config.Scheduler")
+	}
+	if c.PR.MaxPRStreams < 0 {
+		return errors.New("invalid value for Config.PR.MaxPRStreams")
+	}
+	if c.PR.StreamLimiter != nil && c.PR.StreamLimiter.max <= 0 {
+		return errors.New("invalid value for Config.PR.StreamLimiter")
+	}
+	if !(c.PR.MaxSkipRatio >= 0 && c.PR.MaxSkipRatio <= 1) {
+		return errors.New("invalid value for Config.PR.MaxSkipRatio")
+	}
+	if c.PR.DefaultPolicy != nil {
+		if err := c.PR.DefaultPolicy.validate(); err != nil {
+			return fmt.Errorf("invalid value for Config.PR.DefaultPolicy: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -108,6 +183,33 @@ func populateConfig(config *Config, defaultConnIDLen int) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	if config.MaxReceiveBufferSize > 0 {
+		initialConnectionReceiveWindow = utils.Min(initialConnectionReceiveWindow, config.MaxReceiveBufferSize)
+		maxConnectionReceiveWindow = utils.Min(maxConnectionReceiveWindow, config.MaxReceiveBufferSize)
+	}
+	maxDatagramFrameSize := config.MaxDatagramFrameSize
+	if maxDatagramFrameSize == 0 {
+		maxDatagramFrameSize = uint64(protocol.MaxDatagramFrameSize)
+	}
+	maxDatagramFrameSize = utils.Min(maxDatagramFrameSize, uint64(protocol.MaxPacketBufferSize))
+	amplificationFactor := config.AmplificationFactor
+	if amplificationFactor <= 0 || amplificationFactor > protocol.MaxAmplificationFactor {
+		amplificationFactor = protocol.MaxAmplificationFactor
+	}
+	packetThreshold := config.LossDetectionPacketThreshold
+	if packetThreshold <= 0 {
+		packetThreshold = protocol.DefaultPacketThreshold
+	}
+	timeThreshold := config.LossDetectionTimeThreshold
+	if timeThreshold <= 0 {
+		timeThreshold = protocol.DefaultTimeThreshold
+	} else if timeThreshold < 1 {
+		timeThreshold = 1
+	}
+	datagramSendQueueLen := config.DatagramSendQueueLen
+	if datagramSendQueueLen <= 0 {
+		datagramSendQueueLen = protocol.DefaultDatagramSendQueueLen
+	}
 	connIDGenerator := config.ConnectionIDGenerator
 	if connIDGenerator == nil {
 		connIDGenerator = &protocol.DefaultConnectionIDGenerator{ConnLen: conIDLen}
@@ -119,13 +221,25 @@ func populateConfig(config *Config, defaultConnIDLen int) *Config {
 		MaxIdleTimeout:                   idleTimeout,
 		MaxTokenAge:                      config.MaxTokenAge,
 		MaxRetryTokenAge:                 config.MaxRetryTokenAge,
+		TokenIPv4PrefixLen:               config.TokenIPv4PrefixLen,
+		TokenIPv6PrefixLen:               config.TokenIPv6PrefixLen,
+		Allow0RTT:                        config.Allow0RTT,
+		ZeroRTTReplayFilter:              config.ZeroRTTReplayFilter,
 		RequireAddressValidation:         config.RequireAddressValidation,
+		MaxUnvalidatedHandshakes:         config.MaxUnvalidatedHandshakes,
+		AmplificationFactor:              amplificationFactor,
+		LossDetectionPacketThreshold:     packetThreshold,
+		LossDetectionTimeThreshold:       timeThreshold,
+		PathValidationPacketThreshold:    config.PathValidationPacketThreshold,
 		KeepAlivePeriod:                  config.KeepAlivePeriod,
 		InitialStreamReceiveWindow:       initialStreamReceiveWindow,
 		MaxStreamReceiveWindow:           maxStreamReceiveWindow,
 		InitialConnectionReceiveWindow:   initialConnectionReceiveWindow,
 		MaxConnectionReceiveWindow:       maxConnectionReceiveWindow,
+		MaxReceiveBufferSize:             config.MaxReceiveBufferSize,
 		AllowConnectionWindowIncrease:    config.AllowConnectionWindowIncrease,
+		WindowUpdateStrategy:             config.WindowUpdateStrategy,
+		AcceptIncomingStream:             config.AcceptIncomingStream,
 		MaxIncomingStreams:               maxIncomingStreams,
 		MaxIncomingUniStreams:            maxIncomingUniStreams,
 		ConnectionIDLength:               conIDLen,
@@ -133,8 +247,22 @@ func populateConfig(config *Config, defaultConnIDLen int) *Config {
 		StatelessResetKey:                config.StatelessResetKey,
 		TokenStore:                       config.TokenStore,
 		EnableDatagrams:                  config.EnableDatagrams,
+		MaxDatagramFrameSize:             maxDatagramFrameSize,
+		DatagramSendQueueLen:             datagramSendQueueLen,
+		DatagramOverflowPolicy:           config.DatagramOverflowPolicy,
+		FramerQuotas:                     config.FramerQuotas,
+		Scheduler:                        config.Scheduler,
+		TestingLossInjector:              config.TestingLossInjector,
 		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
+		ClientHelloFragmentSize:          config.ClientHelloFragmentSize,
 		DisableVersionNegotiationPackets: config.DisableVersionNegotiationPackets,
+		DisableOOB:                       config.DisableOOB,
+		BatchHeaderProtection:            config.BatchHeaderProtection,
+		ReusePortSockets:                 config.ReusePortSockets,
+		PacketMarking:                    config.PacketMarking,
+		PRPacketMarking:                  config.PRPacketMarking,
 		Tracer:                           config.Tracer,
+		Logger:                           config.Logger,
+		PR:                               config.PR,
 	}
 }
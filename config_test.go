@@ -2,12 +2,14 @@ package quic
 
 import (
 	"fmt"
+	"math"
 	"net"
 	"reflect"
 	"time"
 
 	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,19 +18,116 @@ import (
 var _ = Describe("Config", func() {
 	Context("validating", func() {
 		It("validates a nil config", func() {
-			Expect(validateConfig(nil)).To(Succeed())
+			var c *Config
+			Expect(c.Validate()).To(Succeed())
 		})
 
 		It("validates a config with normal values", func() {
-			Expect(validateConfig(populateServerConfig(&Config{}))).To(Succeed())
+			Expect(populateServerConfig(&Config{}).Validate()).To(Succeed())
+		})
+
+		It("errors on invalid versions", func() {
+			conf := &Config{Versions: []VersionNumber{protocol.Version1, 0x1234}}
+			Expect(conf.Validate()).To(MatchError("0x1234 is not a valid QUIC version"))
 		})
 
 		It("errors on too large values for MaxIncomingStreams", func() {
-			Expect(validateConfig(&Config{MaxIncomingStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingStreams"))
+			Expect((&Config{MaxIncomingStreams: 1<<60 + 1}).Validate()).To(MatchError("invalid value for Config.MaxIncomingStreams"))
 		})
 
 		It("errors on too large values for MaxIncomingUniStreams", func() {
-			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
+			Expect((&Config{MaxIncomingUniStreams: 1<<60 + 1}).Validate()).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
+		})
+
+		It("errors when the initial stream receive window is larger than the maximum", func() {
+			Expect((&Config{InitialStreamReceiveWindow: 1000}).Validate()).To(Succeed())
+			conf := &Config{InitialStreamReceiveWindow: 1000, MaxStreamReceiveWindow: 999}
+			Expect(conf.Validate()).To(MatchError("Config.InitialStreamReceiveWindow (1000) is larger than Config.MaxStreamReceiveWindow (999)"))
+		})
+
+		It("errors when the initial connection receive window is larger than the maximum", func() {
+			Expect((&Config{MaxConnectionReceiveWindow: 1000}).Validate()).To(Succeed())
+			conf := &Config{InitialConnectionReceiveWindow: 1000, MaxConnectionReceiveWindow: 999}
+			Expect(conf.Validate()).To(MatchError("Config.InitialConnectionReceiveWindow (1000) is larger than Config.MaxConnectionReceiveWindow (999)"))
+		})
+
+		It("errors on invalid token prefix lengths", func() {
+			Expect((&Config{TokenIPv4PrefixLen: 32, TokenIPv6PrefixLen: 128}).Validate()).To(Succeed())
+			Expect((&Config{TokenIPv4PrefixLen: 33}).Validate()).To(MatchError("invalid value for Config.TokenIPv4PrefixLen"))
+			Expect((&Config{TokenIPv4PrefixLen: -1}).Validate()).To(MatchError("invalid value for Config.TokenIPv4PrefixLen"))
+			Expect((&Config{TokenIPv6PrefixLen: 129}).Validate()).To(MatchError("invalid value for Config.TokenIPv6PrefixLen"))
+		})
+
+		It("errors on invalid numbers of SO_REUSEPORT sockets", func() {
+			Expect((&Config{ReusePortSockets: -1}).Validate()).To(MatchError("invalid value for Config.ReusePortSockets"))
+			Expect((&Config{ReusePortSockets: 257}).Validate()).To(MatchError("invalid value for Config.ReusePortSockets"))
+			conf := &Config{ReusePortSockets: 2, ConnectionIDGenerator: &protocol.DefaultConnectionIDGenerator{}}
+			Expect(conf.Validate()).To(MatchError("Config.ReusePortSockets requires connection IDs"))
+		})
+
+		It("errors on invalid packet markings", func() {
+			Expect((&Config{PacketMarking: PacketMarking{DSCP: 63, IPv6FlowLabel: 0xfffff}}).Validate()).To(Succeed())
+			Expect((&Config{PacketMarking: PacketMarking{DSCP: 64}}).Validate()).To(MatchError("invalid value for Config.PacketMarking.DSCP"))
+			Expect((&Config{PacketMarking: PacketMarking{IPv6FlowLabel: 0x100000}}).Validate()).To(MatchError("invalid value for Config.PacketMarking.IPv6FlowLabel"))
+			Expect((&Config{PRPacketMarking: PacketMarking{DSCP: 64}}).Validate()).To(MatchError("invalid value for Config.PRPacketMarking.DSCP"))
+			Expect((&Config{PRPacketMarking: PacketMarking{IPv6FlowLabel: 0x100000}}).Validate()).To(MatchError("invalid value for Config.PRPacketMarking.IPv6FlowLabel"))
+		})
+
+		It("errors on invalid datagram overflow policies", func() {
+			conf := &Config{EnableDatagrams: true, DatagramOverflowPolicy: DatagramOverflowDropLowestPriority + 1}
+			Expect(conf.Validate()).To(MatchError("invalid value for Config.DatagramOverflowPolicy"))
+		})
+
+		It("errors when a datagram overflow policy is set without enabling datagrams", func() {
+			conf := &Config{DatagramOverflowPolicy: DatagramOverflowDropLowestPriority}
+			Expect(conf.Validate()).To(MatchError("Config.DatagramOverflowPolicy requires Config.EnableDatagrams"))
+			conf.EnableDatagrams = true
+			Expect(conf.Validate()).To(Succeed())
+		})
+
+		It("errors on negative idle stream timeouts", func() {
+			conf := &Config{PR: PRConfig{IdleStreamTimeout: -time.Second}}
+			Expect(conf.Validate()).To(MatchError("invalid value for Config.PR.IdleStreamTimeout"))
+		})
+
+		It("errors on an invalid 0-RTT replay filter", func() {
+			Expect((&Config{ZeroRTTReplayFilter: NewZeroRTTReplayFilter(0)}).Validate()).To(MatchError("invalid value for Config.ZeroRTTReplayFilter"))
+			Expect((&Config{ZeroRTTReplayFilter: NewZeroRTTReplayFilter(time.Minute)}).Validate()).To(Succeed())
+		})
+
+		It("errors on an invalid ClientHello fragment size", func() {
+			Expect((&Config{ClientHelloFragmentSize: -1}).Validate()).To(MatchError("invalid value for Config.ClientHelloFragmentSize"))
+			Expect((&Config{ClientHelloFragmentSize: 31}).Validate()).To(MatchError("invalid value for Config.ClientHelloFragmentSize"))
+			Expect((&Config{ClientHelloFragmentSize: 32}).Validate()).To(Succeed())
+		})
+
+		It("errors on invalid PR stream limits", func() {
+			Expect((&Config{PR: PRConfig{MaxPRStreams: -1}}).Validate()).To(MatchError("invalid value for Config.PR.MaxPRStreams"))
+			Expect((&Config{PR: PRConfig{StreamLimiter: NewPRStreamLimiter(0)}}).Validate()).To(MatchError("invalid value for Config.PR.StreamLimiter"))
+			Expect((&Config{PR: PRConfig{MaxPRStreams: 10, StreamLimiter: NewPRStreamLimiter(100)}}).Validate()).To(Succeed())
+		})
+
+		It("errors if the scavenger congestion controller is used without separate PR packets", func() {
+			conf := &Config{PR: PRConfig{ScavengerCongestionControl: true}}
+			Expect(conf.Validate()).To(MatchError("Config.PR.ScavengerCongestionControl requires Config.PR.SeparatePRPackets"))
+			conf.PR.SeparatePRPackets = true
+			Expect(conf.Validate()).To(Succeed())
+			conf.Scheduler = func() Scheduler { return nil }
+			Expect(conf.Validate()).To(MatchError("Config.PR.ScavengerCongestionControl can't be used with a Config.Scheduler"))
+		})
+
+		It("errors on an invalid max skip ratio", func() {
+			Expect((&Config{PR: PRConfig{MaxSkipRatio: -0.1}}).Validate()).To(MatchError("invalid value for Config.PR.MaxSkipRatio"))
+			Expect((&Config{PR: PRConfig{MaxSkipRatio: 1.1}}).Validate()).To(MatchError("invalid value for Config.PR.MaxSkipRatio"))
+			Expect((&Config{PR: PRConfig{MaxSkipRatio: math.NaN()}}).Validate()).To(MatchError("invalid value for Config.PR.MaxSkipRatio"))
+			Expect((&Config{PR: PRConfig{MaxSkipRatio: 0.5}}).Validate()).To(Succeed())
+		})
+
+		It("errors on invalid default PR policies", func() {
+			conf := &Config{PR: PRConfig{DefaultPolicy: &PRPolicy{PTDA: PTDADeadline, Value: quicvarint.Max + 1}}}
+			Expect(conf.Validate()).To(MatchError("invalid value for Config.PR.DefaultPolicy: invalid PR policy: value 0x4000000000000000 larger than 0x3fffffffffffffff"))
+			conf = &Config{PR: PRConfig{DefaultPolicy: &PRPolicy{PTDA: PTDADeadline}}}
+			Expect(conf.Validate()).To(MatchError("invalid value for Config.PR.DefaultPolicy: invalid PR policy: deadline of 0ms"))
 		})
 	})
 
@@ -45,7 +144,7 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease":
+			case "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "AcceptIncomingStream", "Scheduler", "Allow0RTT", "TestingLossInjector":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
@@ -61,6 +160,12 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(2 * time.Hour))
 			case "MaxRetryTokenAge":
 				f.Set(reflect.ValueOf(2 * time.Minute))
+			case "TokenIPv4PrefixLen":
+				f.Set(reflect.ValueOf(24))
+			case "TokenIPv6PrefixLen":
+				f.Set(reflect.ValueOf(64))
+			case "ZeroRTTReplayFilter":
+				f.Set(reflect.ValueOf(NewZeroRTTReplayFilter(time.Minute)))
 			case "TokenStore":
 				f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
 			case "InitialStreamReceiveWindow":
@@ -71,6 +176,20 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(uint64(4321)))
 			case "MaxConnectionReceiveWindow":
 				f.Set(reflect.ValueOf(uint64(10)))
+			case "WindowUpdateStrategy":
+				f.Set(reflect.ValueOf(&ThresholdWindowUpdateStrategy{Threshold: 1000, Increment: 2000}))
+			case "MaxReceiveBufferSize":
+				f.Set(reflect.ValueOf(uint64(1 << 20)))
+			case "MaxUnvalidatedHandshakes":
+				f.Set(reflect.ValueOf(64))
+			case "AmplificationFactor":
+				f.Set(reflect.ValueOf(2))
+			case "LossDetectionPacketThreshold":
+				f.Set(reflect.ValueOf(5))
+			case "LossDetectionTimeThreshold":
+				f.Set(reflect.ValueOf(1.5))
+			case "PathValidationPacketThreshold":
+				f.Set(reflect.ValueOf(4))
 			case "MaxIncomingStreams":
 				f.Set(reflect.ValueOf(int64(11)))
 			case "MaxIncomingUniStreams":
@@ -81,12 +200,48 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(time.Second))
 			case "EnableDatagrams":
 				f.Set(reflect.ValueOf(true))
+			case "MaxDatagramFrameSize":
+				f.Set(reflect.ValueOf(uint64(1300)))
+			case "DatagramSendQueueLen":
+				f.Set(reflect.ValueOf(16))
+			case "DatagramOverflowPolicy":
+				f.Set(reflect.ValueOf(DatagramOverflowDropLowestPriority))
+			case "FramerQuotas":
+				f.Set(reflect.ValueOf(FramerQuotas{Control: 1, Reliable: 2, PR: 3}))
 			case "DisableVersionNegotiationPackets":
 				f.Set(reflect.ValueOf(true))
+			case "DisableOOB":
+				f.Set(reflect.ValueOf(true))
+			case "BatchHeaderProtection":
+				f.Set(reflect.ValueOf(true))
+			case "ReusePortSockets":
+				f.Set(reflect.ValueOf(4))
+			case "PacketMarking":
+				f.Set(reflect.ValueOf(PacketMarking{DSCP: 46, IPv6FlowLabel: 1234}))
+			case "PRPacketMarking":
+				f.Set(reflect.ValueOf(PacketMarking{DSCP: 34}))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
+			case "ClientHelloFragmentSize":
+				f.Set(reflect.ValueOf(100))
 			case "Tracer":
 				f.Set(reflect.ValueOf(mocklogging.NewMockTracer(mockCtrl)))
+			case "Logger":
+				f.Set(reflect.ValueOf(&recordingLogger{}))
+			case "PR":
+				f.Set(reflect.ValueOf(PRConfig{
+					Disabled:                   true,
+					IdleStreamTimeout:          time.Minute,
+					IdleStreamErrorCode:        13,
+					DefaultPolicy:              &PRPolicy{PTDA: PTDADeadline, Value: 100},
+					MaxPRStreams:               10,
+					StreamLimiter:              NewPRStreamLimiter(100),
+					RejectExcessPRStreams:      true,
+					SeparatePRPackets:          true,
+					ScavengerCongestionControl: true,
+					MaxSkipRatio:               0.1,
+					RandSeed:                   42,
+				}))
 			default:
 				Fail(fmt.Sprintf("all fields must be accounted for, but saw unknown field %q", fn))
 			}
@@ -147,6 +302,15 @@ var _ = Describe("Config", func() {
 			Expect(calledAddrValidation).To(BeTrue())
 		})
 
+		It("populates the loss injector", func() {
+			var dropped []protocol.PacketNumber
+			c := populateConfig(&Config{
+				TestingLossInjector: func(pn protocol.PacketNumber) bool { dropped = append(dropped, pn); return true },
+			}, protocol.DefaultConnectionIDLength)
+			Expect(c.TestingLossInjector(42)).To(BeTrue())
+			Expect(dropped).To(Equal([]protocol.PacketNumber{42}))
+		})
+
 		It("copies non-function fields", func() {
 			c := configWithNonZeroNonFunctionFields()
 			Expect(populateConfig(c, protocol.DefaultConnectionIDLength)).To(Equal(c))
@@ -164,6 +328,47 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
 			Expect(c.DisableVersionNegotiationPackets).To(BeFalse())
 			Expect(c.DisablePathMTUDiscovery).To(BeFalse())
+			Expect(c.MaxDatagramFrameSize).To(BeEquivalentTo(protocol.MaxDatagramFrameSize))
+			Expect(c.DatagramSendQueueLen).To(Equal(protocol.DefaultDatagramSendQueueLen))
+			Expect(c.DatagramOverflowPolicy).To(Equal(DatagramOverflowBlock))
+			Expect(c.AmplificationFactor).To(Equal(protocol.MaxAmplificationFactor))
+			Expect(c.MaxUnvalidatedHandshakes).To(BeZero())
+			Expect(c.LossDetectionPacketThreshold).To(Equal(protocol.DefaultPacketThreshold))
+			Expect(c.LossDetectionTimeThreshold).To(Equal(protocol.DefaultTimeThreshold))
+			Expect(c.PathValidationPacketThreshold).To(BeZero())
+		})
+
+		It("limits the connection receive window to the maximum receive buffer size", func() {
+			c := populateConfig(&Config{MaxReceiveBufferSize: 1 << 20}, protocol.DefaultConnectionIDLength)
+			Expect(c.InitialConnectionReceiveWindow).To(BeEquivalentTo(protocol.DefaultInitialMaxData))
+			Expect(c.MaxConnectionReceiveWindow).To(BeEquivalentTo(1 << 20))
+			c = populateConfig(&Config{MaxReceiveBufferSize: 1000}, protocol.DefaultConnectionIDLength)
+			Expect(c.InitialConnectionReceiveWindow).To(BeEquivalentTo(1000))
+			Expect(c.MaxConnectionReceiveWindow).To(BeEquivalentTo(1000))
+		})
+
+		It("limits the amplification factor to the maximum allowed by RFC 9000", func() {
+			c := populateConfig(&Config{AmplificationFactor: 2}, protocol.DefaultConnectionIDLength)
+			Expect(c.AmplificationFactor).To(Equal(2))
+			c = populateConfig(&Config{AmplificationFactor: 10}, protocol.DefaultConnectionIDLength)
+			Expect(c.AmplificationFactor).To(Equal(protocol.MaxAmplificationFactor))
+		})
+
+		It("doesn't allow time thresholds smaller than 1 RTT", func() {
+			c := populateConfig(&Config{LossDetectionTimeThreshold: 0.5}, protocol.DefaultConnectionIDLength)
+			Expect(c.LossDetectionTimeThreshold).To(Equal(1.0))
+			c = populateConfig(&Config{LossDetectionTimeThreshold: 1.25}, protocol.DefaultConnectionIDLength)
+			Expect(c.LossDetectionTimeThreshold).To(Equal(1.25))
+		})
+
+		It("keeps a negative path validation packet threshold, which disables migration", func() {
+			c := populateConfig(&Config{PathValidationPacketThreshold: -1}, protocol.DefaultConnectionIDLength)
+			Expect(c.PathValidationPacketThreshold).To(Equal(-1))
+		})
+
+		It("limits the max_datagram_frame_size to the maximum packet size", func() {
+			c := populateConfig(&Config{MaxDatagramFrameSize: 1 << 16}, protocol.DefaultConnectionIDLength)
+			Expect(c.MaxDatagramFrameSize).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -141,3 +142,36 @@ func (m *connIDGenerator) ReplaceWithClosed(pers protocol.Perspective, connClose
 	}
 	m.replaceWithClosed(connIDs, pers, connClose)
 }
+
+// HandoffState returns the active connection IDs, see Connection.Handoff.
+func (m *connIDGenerator) HandoffState() ([]HandoffConnectionID, uint64 /* highest sequence number */) {
+	connIDs := make([]HandoffConnectionID, 0, len(m.activeSrcConnIDs))
+	for seq, connID := range m.activeSrcConnIDs {
+		connIDs = append(connIDs, HandoffConnectionID{SequenceNumber: seq, ConnectionID: connID.Bytes()})
+	}
+	sort.Slice(connIDs, func(i, j int) bool { return connIDs[i].SequenceNumber < connIDs[j].SequenceNumber })
+	return connIDs, m.highestSeq
+}
+
+// Resume restores the connection IDs of a connection that was handed off by another process,
+// and adds them to the packet handler map.
+func (m *connIDGenerator) Resume(connIDs []HandoffConnectionID, highestSeq uint64) error {
+	active := make(map[uint64]protocol.ConnectionID, len(connIDs))
+	for _, c := range connIDs {
+		if c.SequenceNumber > highestSeq {
+			return fmt.Errorf("connection ID %d above the highest sequence number (%d)", c.SequenceNumber, highestSeq)
+		}
+		connID, err := parseHandoffConnectionID(c.ConnectionID)
+		if err != nil {
+			return err
+		}
+		active[c.SequenceNumber] = connID
+	}
+	m.activeSrcConnIDs = active
+	m.highestSeq = highestSeq
+	m.initialClientDestConnID = nil
+	for _, connID := range m.activeSrcConnIDs {
+		m.addConnectionID(connID)
+	}
+	return nil
+}
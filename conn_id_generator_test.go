@@ -187,4 +187,30 @@ var _ = Describe("Connection ID Generator", func() {
 			Expect(replacedWithClosed).To(ContainElement(nf.ConnectionID))
 		}
 	})
+
+	It("hands off and resumes the active connection IDs", func() {
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(g.Retire(1, protocol.ParseConnectionID([]byte{0xff}))).To(Succeed())
+		connIDs, highestSeq := g.HandoffState()
+		Expect(highestSeq).To(BeEquivalentTo(4))
+		Expect(connIDs).To(HaveLen(4))
+		Expect(connIDs[0]).To(Equal(HandoffConnectionID{SequenceNumber: 0, ConnectionID: initialConnID.Bytes()}))
+		Expect(connIDs[1].SequenceNumber).To(BeEquivalentTo(2))
+		addedConnIDs = nil
+		queuedFrames = nil
+		Expect(g.Resume(connIDs, highestSeq)).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(4))
+		Expect(addedConnIDs).To(ContainElement(initialConnID))
+		// The initial client destination connection ID was retired by the other process.
+		g.SetHandshakeComplete()
+		Expect(retiredConnIDs).ToNot(ContainElement(initialClientDestConnID))
+		// only the connection ID that was retired is replaced
+		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(1))
+		Expect(queuedFrames[0].(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(5))
+	})
+
+	It("refuses to resume connection IDs above the highest sequence number", func() {
+		Expect(g.Resume([]HandoffConnectionID{{SequenceNumber: 3, ConnectionID: []byte{1, 2, 3, 4}}}, 2)).To(MatchError("connection ID 3 above the highest sequence number (2)"))
+	})
 })
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -212,3 +213,59 @@ func (h *connIDManager) Get() protocol.ConnectionID {
 func (h *connIDManager) SetHandshakeComplete() {
 	h.handshakeComplete = true
 }
+
+// HandoffState returns the connection IDs issued by the peer, starting with the active one, see Connection.Handoff.
+func (h *connIDManager) HandoffState() []HandoffConnectionID {
+	connIDs := make([]HandoffConnectionID, 0, 1+h.queue.Len())
+	active := HandoffConnectionID{SequenceNumber: h.activeSequenceNumber, ConnectionID: h.activeConnectionID.Bytes()}
+	if h.activeStatelessResetToken != nil {
+		active.StatelessResetToken = append([]byte{}, h.activeStatelessResetToken[:]...)
+	}
+	connIDs = append(connIDs, active)
+	for el := h.queue.Front(); el != nil; el = el.Next() {
+		connIDs = append(connIDs, HandoffConnectionID{
+			SequenceNumber:      el.Value.SequenceNumber,
+			ConnectionID:        el.Value.ConnectionID.Bytes(),
+			StatelessResetToken: append([]byte{}, el.Value.StatelessResetToken[:]...),
+		})
+	}
+	return connIDs
+}
+
+// Resume restores the connection IDs of a connection that was handed off by another process.
+// The first connection ID is the active one.
+func (h *connIDManager) Resume(connIDs []HandoffConnectionID) error {
+	if len(connIDs) == 0 {
+		return errors.New("no connection ID")
+	}
+	for i, c := range connIDs {
+		connID, err := parseHandoffConnectionID(c.ConnectionID)
+		if err != nil {
+			return err
+		}
+		var token *protocol.StatelessResetToken
+		if c.StatelessResetToken != nil {
+			if len(c.StatelessResetToken) != len(protocol.StatelessResetToken{}) {
+				return fmt.Errorf("invalid stateless reset token length: %d", len(c.StatelessResetToken))
+			}
+			token = new(protocol.StatelessResetToken)
+			copy(token[:], c.StatelessResetToken)
+		}
+		if i == 0 {
+			h.activeSequenceNumber = c.SequenceNumber
+			h.activeConnectionID = connID
+			h.activeStatelessResetToken = token
+			if token != nil {
+				h.addStatelessResetToken(*token)
+			}
+			continue
+		}
+		if token == nil {
+			return fmt.Errorf("missing stateless reset token for connection ID %d", c.SequenceNumber)
+		}
+		if err := h.addConnectionID(c.SequenceNumber, connID, *token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -361,4 +361,35 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens).To(HaveLen(1))
 		Expect(removedTokens[0]).To(Equal(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
 	})
+
+	It("hands off and resumes the connection IDs", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			StatelessResetToken: protocol.StatelessResetToken{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+		})).To(Succeed())
+		connIDs := m.HandoffState()
+		Expect(connIDs).To(Equal([]HandoffConnectionID{
+			{SequenceNumber: 0, ConnectionID: initialConnID.Bytes()},
+			{SequenceNumber: 1, ConnectionID: []byte{1, 2, 3, 4}, StatelessResetToken: []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		}))
+
+		resumed := newConnIDManager(
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			func(token protocol.StatelessResetToken) { tokenAdded = &token },
+			func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
+			func(f wire.Frame) { frameQueue = append(frameQueue, f) },
+		)
+		Expect(resumed.Resume(connIDs)).To(Succeed())
+		Expect(resumed.Get()).To(Equal(initialConnID))
+		Expect(tokenAdded).To(BeNil())
+		Expect(resumed.HandoffState()).To(Equal(connIDs))
+	})
+
+	It("refuses to resume connection IDs without a stateless reset token", func() {
+		Expect(m.Resume([]HandoffConnectionID{
+			{SequenceNumber: 0, ConnectionID: []byte{1, 2, 3, 4}},
+			{SequenceNumber: 1, ConnectionID: []byte{5, 6, 7, 8}},
+		})).To(MatchError("missing stateless reset token for connection ID 1"))
+	})
 })
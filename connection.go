@@ -46,6 +46,12 @@ type streamManager interface {
 	DeleteStream(protocol.StreamID) error
 	UpdateLimits(*wire.TransportParameters)
 	HandleMaxStreamsFrame(*wire.MaxStreamsFrame)
+	SetMaxIncomingStreams(uint64)
+	SetMaxIncomingUniStreams(uint64)
+	RejectIncomingStream(protocol.StreamID) error
+	EvictExpiredData(now time.Time) time.Time
+	HandoffState(*ConnectionHandoff)
+	Resume(*ConnectionHandoff)
 	CloseWithError(error)
 	ResetFor0RTT()
 	UseResetMaps()
@@ -59,6 +65,7 @@ type cryptoStreamHandler interface {
 	GetSessionTicket() ([]byte, error)
 	io.Closer
 	ConnectionState() handshake.ConnectionState
+	Export1RTTKeys() (*handshake.OneRTTKeys, error)
 }
 
 type packetInfo struct {
@@ -133,6 +140,11 @@ func nextConnTracingID() uint64 { return atomic.AddUint64(&connTracingID, 1) }
 
 // A Connection is a QUIC connection
 type connection struct {
+	// the current maximum packet size, updated by Path MTU discovery
+	// Accessed atomically, since it's used when sending DATAGRAM frames.
+	// It's the first field, so that it is 64-bit aligned on 32-bit platforms.
+	maxPacketSize int64
+
 	// Destination connection ID used during the handshake.
 	// Used to check source connection ID on incoming packets.
 	handshakeDestConnID protocol.ConnectionID
@@ -150,9 +162,14 @@ type connection struct {
 	sendQueue sender
 
 	streamsMap      streamManager
+	prPolicies      *prPolicyChain
 	connIDManager   *connIDManager
 	connIDGenerator *connIDGenerator
 
+	// the next incoming streams that Config.AcceptIncomingStream will be called for
+	nextIncomingBidiStreamNum protocol.StreamNum
+	nextIncomingUniStreamNum  protocol.StreamNum
+
 	rttStats *utils.RTTStats
 
 	cryptoStreamManager   *cryptoStreamManager
@@ -169,6 +186,7 @@ type connection struct {
 	frameParser   wire.FrameParser
 	packer        packer
 	mtuDiscoverer mtuDiscoverer // initialized when the handshake completes
+	pathManager   *pathManager  // only set for the server, if connection migration is enabled
 
 	oneRTTStream        cryptoStream // only set for the server
 	cryptoStreamHandler cryptoStreamHandler
@@ -184,6 +202,12 @@ type connection struct {
 	ctxCancel          context.CancelFunc
 	handshakeCtx       context.Context
 	handshakeCtxCancel context.CancelFunc
+	// handshakeConfirmedCtx is canceled when the handshake is confirmed
+	handshakeConfirmedCtx       context.Context
+	handshakeConfirmedCtxCancel context.CancelFunc
+
+	handshakeStateMutex sync.Mutex
+	handshakeState      HandshakeState
 
 	undecryptablePackets          []*receivedPacket // undecryptable packets, waiting for a change in encryption level
 	undecryptablePacketsToProcess []*receivedPacket
@@ -198,6 +222,7 @@ type connection struct {
 	receivedRetry       bool
 	versionNegotiated   bool
 	receivedFirstPacket bool
+	received1RTTPacket  bool
 
 	idleTimeout  time.Duration
 	creationTime time.Time
@@ -207,9 +232,15 @@ type connection struct {
 	firstAckElicitingPacketAfterIdleSentTime time.Time
 	// pacingDeadline is the time when the next packet should be sent
 	pacingDeadline time.Time
+	// the time when buffered data expires, such that it can be evicted
+	nextEvictionTime time.Time
 
 	peerParams *wire.TransportParameters
 
+	// set by the run loop when the connection is handed off, see Handoff
+	handoff    *ConnectionHandoff
+	handoffErr error
+
 	timer *utils.Timer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
 	// It is reset as soon as we receive a packet from the peer.
@@ -217,6 +248,7 @@ type connection struct {
 	keepAliveInterval time.Duration
 
 	datagramQueue *datagramQueue
+	events        *eventBus
 
 	logID  string
 	tracer logging.ConnectionTracer
@@ -267,6 +299,7 @@ var newConnection = func(
 	} else {
 		s.logID = destConnID.String()
 	}
+	s.logger = newConnectionLogger(conf, logger, s.perspective, s.logID)
 	s.connIDManager = newConnIDManager(
 		destConnID,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
@@ -292,6 +325,10 @@ var newConnection = func(
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		clientAddressValidated,
+		s.config.AmplificationFactor,
+		s.config.LossDetectionPacketThreshold,
+		s.config.LossDetectionTimeThreshold,
+		s.config.PR.ScavengerCongestionControl,
 		s.perspective,
 		s.tracer,
 		s.logger,
@@ -315,14 +352,17 @@ var newConnection = func(
 		ActiveConnectionIDLimit:         protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:       srcConnID,
 		RetrySourceConnectionID:         retrySrcConnID,
+		PartialReliability:              !s.config.PR.Disabled,
+		MaxSkipRatio:                    maxSkipRatioParam(s.config.PR),
 	}
 	if s.config.EnableDatagrams {
-		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
+		params.MaxDatagramFrameSize = protocol.ByteCount(s.config.MaxDatagramFrameSize)
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
+		s.tracer.SetPRRandSeed(s.prPolicies.RandSeed())
 	}
 	cs := handshake.NewCryptoSetupServer( //发生了tls握手，cs.conn是tls连接
 		initialStream,
@@ -342,8 +382,9 @@ var newConnection = func(
 		},
 		tlsConf,
 		enable0RTT,
+		newZeroRTTPolicy(s.config, conn.RemoteAddr()),
 		s.rttStats,
-		tracer,
+		tracerWithEvents(tracer, s.events),
 		logger,
 		s.version,
 	)
@@ -360,6 +401,9 @@ var newConnection = func(
 		s.framer,
 		s.receivedPacketHandler,
 		s.datagramQueue,
+		s.prPolicies.ackNotifies,
+		s.config.BatchHeaderProtection,
+		protocol.ByteCount(s.config.ClientHelloFragmentSize),
 		s.perspective,
 		s.version,
 	)
@@ -393,7 +437,7 @@ var newClientConnection = func(
 		perspective:           protocol.PerspectiveClient,
 		handshakeCompleteChan: make(chan struct{}),
 		logID:                 destConnID.String(),
-		logger:                logger,
+		logger:                newConnectionLogger(conf, logger, protocol.PerspectiveClient, destConnID.String()),
 		tracer:                tracer,
 		versionNegotiated:     hasNegotiatedVersion,
 		version:               v,
@@ -423,6 +467,10 @@ var newClientConnection = func(
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		false, /* has no effect */
+		s.config.AmplificationFactor,
+		s.config.LossDetectionPacketThreshold,
+		s.config.LossDetectionTimeThreshold,
+		s.config.PR.ScavengerCongestionControl,
 		s.perspective,
 		s.tracer,
 		s.logger,
@@ -443,14 +491,17 @@ var newClientConnection = func(
 		DisableActiveMigration:         true,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID:      srcConnID,
+		PartialReliability:             !s.config.PR.Disabled,
+		MaxSkipRatio:                   maxSkipRatioParam(s.config.PR),
 	}
 	if s.config.EnableDatagrams {
-		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
+		params.MaxDatagramFrameSize = protocol.ByteCount(s.config.MaxDatagramFrameSize)
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
+		s.tracer.SetPRRandSeed(s.prPolicies.RandSeed())
 	}
 	//handshake.NewCryptoSetupClient，执行这一步发生了tls握手
 	cs, clientHelloWritten := handshake.NewCryptoSetupClient(
@@ -469,7 +520,7 @@ var newClientConnection = func(
 		tlsConf,
 		enable0RTT,
 		s.rttStats,
-		tracer,
+		tracerWithEvents(tracer, s.events),
 		logger,
 		s.version,
 	)
@@ -489,6 +540,9 @@ var newClientConnection = func(
 		s.framer,
 		s.receivedPacketHandler,
 		s.datagramQueue,
+		s.prPolicies.ackNotifies,
+		s.config.BatchHeaderProtection,
+		protocol.ByteCount(s.config.ClientHelloFragmentSize),
 		s.perspective,
 		s.version,
 	)
@@ -505,10 +559,26 @@ var newClientConnection = func(
 	return s
 }
 
+// newConnectionLogger returns the logger used by a connection.
+// If a Logger is configured, it replaces the logger of the server or the client,
+// and receives the messages of the log level of that logger.
+func newConnectionLogger(conf *Config, logger utils.Logger, pers protocol.Perspective, logID string) utils.Logger {
+	if conf.Logger == nil {
+		return logger
+	}
+	prefix := "client"
+	if pers == protocol.PerspectiveServer {
+		prefix = "server"
+	}
+	return utils.NewStructuredLogger(conf.Logger, logger.LogLevel(), "conn_id", logID).WithPrefix(prefix)
+}
+
 func (s *connection) preSetup() {
+	s.events = newEventBus()
 	s.sendQueue = newSendQueue(s.conn)
+	s.maxPacketSize = int64(getMaxPacketSize(s.conn.RemoteAddr()))
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
-	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.version)
+	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, !s.config.PR.Disabled, s.version)
 	s.rttStats = &utils.RTTStats{}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
@@ -520,31 +590,43 @@ func (s *connection) preSetup() {
 			}
 			return s.config.AllowConnectionWindowIncrease(s, uint64(size))
 		},
+		s.connectionWindowUpdateFunc(),
 		s.rttStats,
 		s.logger,
 	)
 	s.earlyConnReadyChan = make(chan struct{})
+	s.prPolicies = newPRPolicyChain(s.config.PR)
+	if s.perspective == protocol.PerspectiveServer && s.config.PathValidationPacketThreshold > 0 {
+		s.pathManager = newPathManager(s.config.PathValidationPacketThreshold)
+	}
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		s.prPolicies,
 		s.perspective,
+		s.logger,
 		s.version,
 	)
-	s.framer = newFramer(s.streamsMap, s.version)
-	pr_version = s.version // for PR Policy
+	var scheduler Scheduler
+	if s.config.Scheduler != nil {
+		scheduler = s.config.Scheduler()
+	}
+	s.framer = newFramer(s.streamsMap, s.config.FramerQuotas, s.config.PR.SeparatePRPackets, scheduler, s.prPolicies.ackNotifies, s.version)
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxConnUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
+	s.handshakeConfirmedCtx, s.handshakeConfirmedCtxCancel = context.WithCancel(context.Background())
 
 	now := time.Now()
 	s.lastPacketReceivedTime = now
 	s.creationTime = now
+	s.handshakeState.Start = now
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
-	s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger, s.version)
+	s.datagramQueue = newDatagramQueue(s.scheduleSending, s.onDroppedDatagram, s.config.DatagramSendQueueLen, s.config.DatagramOverflowPolicy, s.logger, s.version)
 }
 
 // run the connection main loop
@@ -677,6 +759,16 @@ runLoop:
 			}
 		}
 
+		if !s.nextEvictionTime.IsZero() && !now.Before(s.nextEvictionTime) {
+			s.nextEvictionTime = s.streamsMap.EvictExpiredData(now)
+		}
+
+		if s.pathManager != nil {
+			if deadline := s.pathManager.ValidationDeadline(); !deadline.IsZero() && !now.Before(deadline) {
+				s.onPathValidationTimeout()
+			}
+		}
+
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
 			// send a PING frame since there is no activity in the connection
 			s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
@@ -710,6 +802,9 @@ runLoop:
 		}
 	}
 
+	if errors.Is(closeErr.err, ErrHandedOff) {
+		s.handoff, s.handoffErr = s.handoffState()
+	}
 	s.cryptoStreamHandler.Close()
 	<-handshaking
 	s.handleCloseError(&closeErr)
@@ -731,19 +826,45 @@ func (s *connection) HandshakeComplete() context.Context {
 	return s.handshakeCtx
 }
 
+func (s *connection) HandshakeConfirmed() context.Context {
+	return s.handshakeConfirmedCtx
+}
+
 func (s *connection) Context() context.Context {
 	return s.ctx
 }
 
+// updateHandshakeState records the time when the handshake reached a stage.
+func (s *connection) updateHandshakeState(update func(*HandshakeState)) {
+	s.handshakeStateMutex.Lock()
+	update(&s.handshakeState)
+	s.handshakeStateMutex.Unlock()
+}
+
 func (s *connection) supportsDatagrams() bool {
 	return s.peerParams.MaxDatagramFrameSize > 0
 }
 
+// maxDatagramFrameSize is the maximum size of a DATAGRAM frame we can send.
+// It is limited by the peer's max_datagram_frame_size and by the current packet size.
+func (s *connection) maxDatagramFrameSize() protocol.ByteCount {
+	packetSize := protocol.ByteCount(atomic.LoadInt64(&s.maxPacketSize))
+	return utils.Min(s.peerParams.MaxDatagramFrameSize, packetSize-protocol.DatagramFramePacketOverhead)
+}
+
 func (s *connection) ConnectionState() ConnectionState {
-	return ConnectionState{
+	state := ConnectionState{
 		TLS:               s.cryptoStreamHandler.ConnectionState(),
 		SupportsDatagrams: s.supportsDatagrams(),
 	}
+	s.handshakeStateMutex.Lock()
+	state.Handshake = s.handshakeState
+	s.handshakeStateMutex.Unlock()
+	if state.SupportsDatagrams {
+		f := &wire.DatagramFrame{DataLenPresent: true}
+		state.MaxDatagramSize = int(f.MaxDataLen(s.maxDatagramFrameSize(), s.version))
+	}
+	return state
 }
 
 // Time when the next keep-alive packet should be sent.
@@ -779,6 +900,14 @@ func (s *connection) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if !s.nextEvictionTime.IsZero() {
+		deadline = utils.MinTime(deadline, s.nextEvictionTime)
+	}
+	if s.pathManager != nil {
+		if validationDeadline := s.pathManager.ValidationDeadline(); !validationDeadline.IsZero() {
+			deadline = utils.MinTime(deadline, validationDeadline)
+		}
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -789,6 +918,7 @@ func (s *connection) idleTimeoutStartTime() time.Time {
 
 func (s *connection) handleHandshakeComplete() {
 	s.handshakeComplete = true
+	s.updateHandshakeState(func(state *HandshakeState) { state.TLSComplete = time.Now() })
 	s.handshakeCompleteChan = nil // prevent this case from ever being selected again
 	defer s.handshakeCtxCancel()
 	// Once the handshake completes, we have derived 1-RTT keys.
@@ -825,6 +955,8 @@ func (s *connection) handleHandshakeComplete() {
 
 func (s *connection) handleHandshakeConfirmed() {
 	s.handshakeConfirmed = true
+	s.updateHandshakeState(func(state *HandshakeState) { state.Confirmed = time.Now() })
+	defer s.handshakeConfirmedCtxCancel()
 	s.sentPacketHandler.SetHandshakeConfirmed()
 	s.cryptoStreamHandler.SetHandshakeConfirmed()
 
@@ -841,13 +973,17 @@ func (s *connection) handleHandshakeConfirmed() {
 			func(size protocol.ByteCount) {
 				s.sentPacketHandler.SetMaxDatagramSize(size)
 				s.packer.SetMaxPacketSize(size)
+				atomic.StoreInt64(&s.maxPacketSize, int64(size))
 			},
 		)
 	}
 }
 
 func (s *connection) handlePacketImpl(rp *receivedPacket) bool {
-	s.sentPacketHandler.ReceivedBytes(rp.Size()) //增加收到的字节数记录
+	// While a new client address is validated, the amplification limit only counts the bytes received on the new path.
+	if s.pathManager == nil || s.pathManager.ValidationDeadline().IsZero() || equalAddr(rp.remoteAddr, s.conn.RemoteAddr()) {
+		s.sentPacketHandler.ReceivedBytes(rp.Size()) //增加收到的字节数记录
+	}
 
 	if wire.IsVersionNegotiationPacket(rp.data) {
 		s.handleVersionNegotiationPacket(rp)
@@ -979,13 +1115,46 @@ func (s *connection) handleShortHeaderPacket(p *receivedPacket, destConnID proto
 			)
 		}
 	}
-	if err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log); err != nil {
+	isNonProbing, err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log)
+	if err != nil {
 		s.closeLocal(err)
 		return false
 	}
+	if s.pathManager != nil && s.handshakeConfirmed && p.remoteAddr != nil {
+		s.maybeMigrate(p.remoteAddr, p.Size(), pn, isNonProbing)
+	}
 	return true
 }
 
+// maybeMigrate migrates the connection to a new client address, see Config.PathValidationPacketThreshold.
+// The new address isn't validated yet, so the amplification limit applies until the PATH_RESPONSE is received.
+func (s *connection) maybeMigrate(addr net.Addr, size protocol.ByteCount, pn protocol.PacketNumber, isNonProbing bool) {
+	previous := s.conn.RemoteAddr()
+	if !s.pathManager.ReceivedPacket(previous, addr, pn, isNonProbing) {
+		return
+	}
+	s.logger.Infof("Client migrated from %s to %s. Validating the new address.", previous, addr)
+	s.switchPath(previous, addr)
+	s.sentPacketHandler.StartPathValidation(size)
+	s.queueControlFrame(s.pathManager.Migrated(previous, time.Now().Add(3*s.rttStats.PTO(true))))
+}
+
+func (s *connection) onPathValidationTimeout() {
+	current := s.conn.RemoteAddr()
+	addr := s.pathManager.OnValidationTimeout()
+	s.logger.Infof("Failed to validate %s. Returning to %s.", current, addr)
+	s.switchPath(current, addr)
+	s.sentPacketHandler.PathValidated()
+}
+
+func (s *connection) switchPath(from, to net.Addr) {
+	s.conn.SetRemoteAddr(to)
+	// The path most likely has the same characteristics if only the port changed.
+	if !isPortOnlyChange(from, to) {
+		s.sentPacketHandler.MigratedPath(protocol.ByteCount(atomic.LoadInt64(&s.maxPacketSize)))
+	}
+}
+
 func (s *connection) handleLongHeaderPacket(p *receivedPacket, hdr *wire.Header) bool /* was the packet successfully processed */ {
 	var wasQueued bool
 	defer func() {
@@ -1254,7 +1423,7 @@ func (s *connection) handleUnpackedPacket(
 			s.tracer.ReceivedLongHeaderPacket(packet.hdr, packetSize, frames)
 		}
 	}
-	isAckEliciting, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
+	isAckEliciting, _, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
 	if err != nil {
 		return err
 	}
@@ -1268,16 +1437,20 @@ func (s *connection) handleUnpackedShortHeaderPacket(
 	ecn protocol.ECN,
 	rcvTime time.Time,
 	log func([]logging.Frame),
-) error {
+) (isNonProbing bool, _ error) {
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
+	if !s.received1RTTPacket {
+		s.received1RTTPacket = true
+		s.updateHandshakeState(func(state *HandshakeState) { state.First1RTTPacketReceived = rcvTime })
+	}
 
-	isAckEliciting, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
+	isAckEliciting, isNonProbing, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
+	return isNonProbing, s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
 }
 
 func (s *connection) handleFrames(
@@ -1285,16 +1458,16 @@ func (s *connection) handleFrames(
 	destConnID protocol.ConnectionID,
 	encLevel protocol.EncryptionLevel,
 	log func([]logging.Frame),
-) (isAckEliciting bool, _ error) {
+) (isAckEliciting, isNonProbing bool, _ error) {
 	// Only used for tracing.
 	// If we're not tracing, this slice will always remain empty.
 	var frames []wire.Frame
 	for len(data) > 0 {
 		l, frame, err := s.frameParser.ParseNext(data, encLevel)
 		fmt.Printf("f: %T\n", frame)
-		Frames_recv_num++
+		atomic.AddInt64(&Frames_recv_num, 1)
 		if err != nil {
-			return false, err
+			return false, false, err
 		}
 		data = data[l:]
 		if frame == nil {
@@ -1303,11 +1476,14 @@ func (s *connection) handleFrames(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !isProbingFrame(frame) {
+			isNonProbing = true
+		}
 		// Only process frames now if we're not logging.
 		// If we're logging, we need to make sure that the packet_received event is logged first.
 		if log == nil {
 			if err := s.handleFrame(frame, encLevel, destConnID); err != nil {
-				return false, err
+				return false, false, err
 			}
 		} else {
 			frames = append(frames, frame)
@@ -1322,7 +1498,7 @@ func (s *connection) handleFrames(
 		log(fs)
 		for _, frame := range frames {
 			if err := s.handleFrame(frame, encLevel, destConnID); err != nil {
-				return false, err
+				return false, false, err
 			}
 		}
 	}
@@ -1337,6 +1513,10 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 		err = s.handlePRStreamFrame(frame)
 	case *wire.PRAckNotifyFrame:
 		err = s.handlePRAckNotifyFrame(frame)
+	case *wire.PRMessageBoundaryFrame:
+		err = s.handlePRMessageBoundaryFrame(frame)
+	case *wire.PRStreamPolicyFrame:
+		err = s.handlePRStreamPolicyFrame(frame)
 	case *wire.PRAckFrame:
 		// err = s.handlePRAckFrame(frame, encLevel)
 		// wire.PutPRAckFrame(frame)
@@ -1360,7 +1540,9 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 	case *wire.MaxStreamsFrame:
 		s.handleMaxStreamsFrame(frame)
 	case *wire.DataBlockedFrame:
+		s.handleDataBlockedFrame()
 	case *wire.StreamDataBlockedFrame:
+		err = s.handleStreamDataBlockedFrame(frame)
 	case *wire.StreamsBlockedFrame:
 	case *wire.StopSendingFrame:
 		err = s.handleStopSendingFrame(frame)
@@ -1368,8 +1550,7 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		err = s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1408,12 +1589,22 @@ func (s *connection) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame
 		})
 		return
 	}
-	s.closeRemote(&qerr.TransportError{
+	err := &qerr.TransportError{
 		Remote:       true,
 		ErrorCode:    qerr.TransportErrorCode(frame.ErrorCode),
 		FrameType:    frame.FrameType,
 		ErrorMessage: frame.ReasonPhrase,
-	})
+	}
+	// The server sent the partial_reliability transport parameter, but didn't understand a frame used for partial reliability.
+	// Don't use partial reliability for the following connections to this server.
+	if s.perspective == protocol.PerspectiveClient && !s.config.PR.Disabled && isPRFrameRejection(err) {
+		s.logger.Errorf("Server rejected frame type %#x. Disabling partial reliability for connections to %s.", frame.FrameType, s.conn.RemoteAddr())
+		prIncapableHosts.Add(s.conn.RemoteAddr().String())
+		if s.tracer != nil {
+			s.tracer.DisabledPR(logging.PRDisabledFrameRejected)
+		}
+	}
+	s.closeRemote(err)
 }
 
 func (s *connection) handleCryptoFrame(frame *wire.CryptoFrame, encLevel protocol.EncryptionLevel) error {
@@ -1430,6 +1621,8 @@ func (s *connection) handleCryptoFrame(frame *wire.CryptoFrame, encLevel protoco
 }
 
 // 接收方收到PRAckNotifyFrame，转换成StreamFrame，其data填0，实现强制确认
+// The data skipped by the sender is zero-filled.
+// If the sender declared the data lost spuriously, the data might still arrive. It then replaces the zeros.
 func (s *connection) handlePRAckNotifyFrame(frame *wire.PRAckNotifyFrame) error {
 	sf := wire.StreamFrame{
 		StreamID:       frame.StreamID,
@@ -1439,35 +1632,154 @@ func (s *connection) handlePRAckNotifyFrame(frame *wire.PRAckNotifyFrame) error
 		DataLenPresent: frame.DataLenPresent,
 		// fromPool: frame.fromPool,  // 不知道为啥报错：没有这个field
 	}
-	err := s.handleStreamFrame(&sf)
-	return err
+	str, err := s.receiveStreamForFrame(&sf)
+	if err != nil || str == nil {
+		return err
+	}
+	if s.logger.Debug() {
+		s.logger.With("stream_id", frame.StreamID, "pr_policy", PRPolicy{PTDA: frame.PTDA, Value: frame.PtdaC}).Debugf("Peer skipped data (offset %d, length %d)", frame.Offset, frame.DataLen())
+	}
+	return str.handleSkippedStreamFrame(&sf)
+}
+
+// A PR_MESSAGE_BOUNDARY frame can open a stream, just like a STREAM frame.
+func (s *connection) handlePRMessageBoundaryFrame(frame *wire.PRMessageBoundaryFrame) error {
+	str, err := s.receiveStreamForFrame(&wire.StreamFrame{StreamID: frame.StreamID, Offset: frame.Offset})
+	if err != nil || str == nil {
+		return err
+	}
+	return str.handleMessageBoundaryFrame(frame)
+}
+
+// handlePRStreamPolicyFrame handles the announcement of a policy change by the peer.
+// Every PR STREAM frame carries its policy, so the receive stream doesn't need to track it.
+func (s *connection) handlePRStreamPolicyFrame(frame *wire.PRStreamPolicyFrame) error {
+	policy := PRPolicy{PTDA: frame.PTDA, Value: frame.Value}
+	if err := policy.validate(); err != nil {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: err.Error(),
+		}
+	}
+	str, err := s.streamsMap.GetOrOpenReceiveStream(frame.StreamID)
+	if err != nil || str == nil {
+		return err
+	}
+	if s.logger.Debug() {
+		s.logger.With("stream_id", frame.StreamID, "pr_policy", policy).Debugf("Peer changed the PR policy at offset %d", frame.Offset)
+	}
+	if s.tracer != nil {
+		s.tracer.UpdatedPRPolicy(frame.StreamID, frame.Offset, frame.PTDA, frame.Value, true)
+	}
+	return nil
 }
 
 // 接收方收到PRStreamFrame，转换成StreamFrame，正常处理
+// Data sent with the deadline policy may be evicted from the receive buffer once the deadline has passed.
 func (s *connection) handlePRStreamFrame(frame *wire.PRStreamFrame) error {
-	sf := wire.StreamFrame{
-		StreamID:       frame.StreamID,
-		Offset:         frame.Offset,
-		Data:           frame.Data,
-		Fin:            frame.Fin,
-		DataLenPresent: frame.DataLenPresent,
-		// fromPool: frame.fromPool,  // 首字母小写在外部不能调用
+	var expiry time.Time
+	if frame.PTDA == PTDADeadline {
+		expiry = time.Now().Add(time.Duration(frame.PtdaC) * time.Millisecond)
 	}
-	err := s.handleStreamFrame(&sf)
-	return err
+	return s.handleExpiringStreamFrame(frame.ToStreamFrame(), expiry)
 }
 
 func (s *connection) handleStreamFrame(frame *wire.StreamFrame) error {
+	return s.handleExpiringStreamFrame(frame, time.Time{})
+}
+
+func (s *connection) handleExpiringStreamFrame(frame *wire.StreamFrame, expiry time.Time) error {
+	str, err := s.receiveStreamForFrame(frame)
+	if err != nil || str == nil {
+		return err
+	}
+	if expiry.IsZero() {
+		return str.handleStreamFrame(frame)
+	}
+	return str.handleExpiringStreamFrame(frame, expiry)
+}
+
+// receiveStreamForFrame returns the stream that a STREAM frame belongs to.
+// It returns nil if the stream is closed and already garbage collected, in that case the frame is ignored.
+func (s *connection) receiveStreamForFrame(frame *wire.StreamFrame) (receiveStreamI, error) {
 	str, err := s.streamsMap.GetOrOpenReceiveStream(frame.StreamID)
-	if err != nil {
+	if err != nil || str == nil {
+		return nil, err
+	}
+	if s.config.AcceptIncomingStream != nil && frame.StreamID.InitiatedBy() != s.perspective {
+		if err := s.handleNewIncomingStreams(frame); err != nil {
+			return nil, err
+		}
+	}
+	return str, nil
+}
+
+// The peer is blocked by connection-level flow control, because we buffered too much data.
+// Make room by evicting expired data.
+func (s *connection) handleDataBlockedFrame() {
+	s.nextEvictionTime = s.streamsMap.EvictExpiredData(time.Now())
+}
+
+func (s *connection) handleStreamDataBlockedFrame(frame *wire.StreamDataBlockedFrame) error {
+	str, err := s.streamsMap.GetOrOpenReceiveStream(frame.StreamID)
+	if err != nil || str == nil {
 		return err
 	}
-	if str == nil {
-		// Stream is closed and already garbage collected
-		// ignore this StreamFrame
+	s.nextEvictionTime = utils.MinNonZeroTime(s.nextEvictionTime, str.evictExpiredData(time.Now()))
+	return nil
+}
+
+// handleNewIncomingStreams calls the AcceptIncomingStream callback for every stream
+// opened by the peer up to the stream of this frame, that it wasn't called for yet.
+func (s *connection) handleNewIncomingStreams(frame *wire.StreamFrame) error {
+	stype := frame.StreamID.Type()
+	next := &s.nextIncomingBidiStreamNum
+	if stype == protocol.StreamTypeUni {
+		next = &s.nextIncomingUniStreamNum
+	}
+	for num := utils.Max(*next, 1); num <= frame.StreamID.StreamNum(); num++ {
+		id := num.StreamID(stype, s.perspective.Opposite())
+		info := IncomingStreamInfo{StreamID: id}
+		if id == frame.StreamID && frame.Offset == 0 {
+			info.Data = frame.Data
+		}
+		decision := s.config.AcceptIncomingStream(s, info)
+		if err := s.applyIncomingStreamDecision(id, decision); err != nil {
+			return err
+		}
+	}
+	*next = utils.Max(*next, frame.StreamID.StreamNum()+1)
+	return nil
+}
+
+func (s *connection) applyIncomingStreamDecision(id protocol.StreamID, decision IncomingStreamDecision) error {
+	if !decision.Reject && (decision.Policy == nil || id.Type() == protocol.StreamTypeUni) {
 		return nil
 	}
-	return str.handleStreamFrame(frame)
+	rstr, err := s.streamsMap.GetOrOpenReceiveStream(id)
+	if err != nil || rstr == nil {
+		return err
+	}
+	var sstr sendStreamI
+	if id.Type() == protocol.StreamTypeBidi {
+		if sstr, err = s.streamsMap.GetOrOpenSendStream(id); err != nil {
+			return err
+		}
+	}
+	if !decision.Reject {
+		if sstr != nil {
+			sstr.SetPRPolicy(decision.Policy)
+		}
+		return nil
+	}
+	if err := s.streamsMap.RejectIncomingStream(id); err != nil {
+		return err
+	}
+	rstr.CancelRead(decision.ErrorCode)
+	if sstr != nil {
+		sstr.CancelWrite(decision.ErrorCode)
+	}
+	return nil
 }
 
 func (s *connection) handleMaxDataFrame(frame *wire.MaxDataFrame) {
@@ -1500,7 +1812,11 @@ func (s *connection) handleResetStreamFrame(frame *wire.ResetStreamFrame) error
 		// stream is closed and already garbage collected
 		return nil
 	}
-	return str.handleResetStreamFrame(frame)
+	if err := str.handleResetStreamFrame(frame); err != nil {
+		return err
+	}
+	s.events.Publish(StreamResetEvent{StreamID: frame.StreamID, ErrorCode: frame.ErrorCode, FinalSize: uint64(frame.FinalSize)})
+	return nil
 }
 
 func (s *connection) handleStopSendingFrame(frame *wire.StopSendingFrame) error {
@@ -1520,6 +1836,22 @@ func (s *connection) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *connection) handlePathResponseFrame(frame *wire.PathResponseFrame) error {
+	// We only send PATH_CHALLENGEs when the client migrated to a new address.
+	if s.pathManager == nil {
+		return errors.New("unexpected PATH_RESPONSE frame")
+	}
+	expected, validated := s.pathManager.ReceivedPathResponse(frame)
+	if !expected {
+		return errors.New("unexpected PATH_RESPONSE frame")
+	}
+	if validated {
+		s.logger.Debugf("Validated the new client address %s.", s.conn.RemoteAddr())
+		s.sentPacketHandler.PathValidated()
+	}
+	return nil
+}
+
 func (s *connection) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return &qerr.TransportError{
@@ -1563,6 +1895,7 @@ func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encr
 	if !acked1RTTPacket {
 		return nil
 	}
+	s.prPolicies.SetAckMetrics(s.sentPacketHandler.AckMetrics(), s.rttStats.SmoothedRTT())
 	if s.perspective == protocol.PerspectiveClient && !s.handshakeConfirmed {
 		s.handleHandshakeConfirmed()
 	}
@@ -1570,7 +1903,7 @@ func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encr
 }
 
 func (s *connection) handleDatagramFrame(f *wire.DatagramFrame) error {
-	if f.Length(s.version) > protocol.MaxDatagramFrameSize {
+	if f.Length(s.version) > protocol.ByteCount(s.config.MaxDatagramFrameSize) {
 		return &qerr.TransportError{
 			ErrorCode:    qerr.ProtocolViolation,
 			ErrorMessage: "DATAGRAM frame too large",
@@ -1652,6 +1985,7 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	switch {
 	case errors.Is(e, qerr.ErrIdleTimeout),
 		errors.Is(e, qerr.ErrHandshakeTimeout),
+		errors.Is(e, ErrHandedOff),
 		errors.As(e, &statelessResetErr),
 		errors.As(e, &versionNegotiationErr),
 		errors.As(e, &recreateErr),
@@ -1669,6 +2003,7 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	if s.datagramQueue != nil {
 		s.datagramQueue.CloseWithError(e)
 	}
+	s.events.Close()
 
 	if s.tracer != nil && !errors.As(e, &recreateErr) {
 		s.tracer.ClosedConnection(e)
@@ -1723,6 +2058,7 @@ func (s *connection) restoreTransportParameters(params *wire.TransportParameters
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.streamsMap.UpdateLimits(params)
+	s.handlePRSupport(params)
 }
 
 func (s *connection) handleTransportParameters(params *wire.TransportParameters) {
@@ -1733,6 +2069,9 @@ func (s *connection) handleTransportParameters(params *wire.TransportParameters)
 		})
 	}
 	s.peerParams = params
+	s.publishPeerTransportParameters(params)
+	// This applies to 0-RTT packets as well: 0-RTT is rejected if the server stopped supporting partial reliability.
+	s.handlePRSupport(params)
 	// On the client side we have to wait for handshake completion.
 	// During a 0-RTT connection, we are only allowed to use the new transport parameters for 1-RTT packets.
 	if s.perspective == protocol.PerspectiveServer {
@@ -1743,6 +2082,22 @@ func (s *connection) handleTransportParameters(params *wire.TransportParameters)
 	}
 }
 
+// handlePRSupport disables partial reliability if the peer doesn't support it.
+func (s *connection) handlePRSupport(params *wire.TransportParameters) {
+	s.prPolicies.SetPeerSupportsPR(params.PartialReliability)
+	s.prPolicies.SetPeerMaxSkipRatio(params.MaxSkipRatio)
+	s.events.Publish(PRNegotiatedEvent{Enabled: params.PartialReliability && !s.config.PR.Disabled})
+	if params.PartialReliability || s.config.PR.Disabled {
+		return
+	}
+	if s.logger.Debug() {
+		s.logger.Debugf("Peer doesn't support partial reliability. Sending all data reliably.")
+	}
+	if s.tracer != nil {
+		s.tracer.DisabledPR(logging.PRDisabledNotNegotiated)
+	}
+}
+
 func (s *connection) checkTransportParameters(params *wire.TransportParameters) error {
 	if s.logger.Debug() {
 		s.logger.Debugf("Processed Transport Parameters: %s", params)
@@ -1776,6 +2131,24 @@ func (s *connection) checkTransportParameters(params *wire.TransportParameters)
 	return nil
 }
 
+func (s *connection) publishPeerTransportParameters(params *wire.TransportParameters) {
+	ev := PeerTransportParametersEvent{
+		MaxIdleTimeout:     params.MaxIdleTimeout,
+		InitialMaxData:     uint64(params.InitialMaxData),
+		MaxBidiStreams:     int64(params.MaxBidiStreamNum),
+		MaxUniStreams:      int64(params.MaxUniStreamNum),
+		PartialReliability: params.PartialReliability,
+	}
+	if params.MaxDatagramFrameSize != protocol.InvalidByteCount {
+		ev.MaxDatagramFrameSize = uint64(params.MaxDatagramFrameSize)
+	}
+	s.events.Publish(ev)
+}
+
+func (s *connection) onDroppedDatagram(length int, expired, received bool) {
+	s.events.Publish(DatagramDroppedEvent{Length: length, Expired: expired, Received: received})
+}
+
 func (s *connection) applyTransportParameters() {
 	params := s.peerParams
 	// Our local idle timeout will always be > 0.
@@ -1939,6 +2312,9 @@ func (s *connection) sendPacket() (bool, error) {
 		s.framer.QueueControlFrame(&wire.DataBlockedFrame{MaximumData: offset})
 	}
 	s.windowUpdateQueue.QueueAll()
+	if s.config.PR.ScavengerCongestionControl {
+		s.framer.SetPRBlocked(!s.sentPacketHandler.CanSendPR())
+	}
 
 	now := time.Now()
 	if !s.handshakeConfirmed {
@@ -1946,7 +2322,10 @@ func (s *connection) sendPacket() (bool, error) {
 		if err != nil || packet == nil {
 			return false, err
 		}
-		s.sentFirstPacket = true
+		if !s.sentFirstPacket {
+			s.sentFirstPacket = true
+			s.updateHandshakeState(func(state *HandshakeState) { state.FirstFlightSent = now })
+		}
 		s.logCoalescedPacket(packet)
 		for _, p := range packet.packets {
 			if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && p.IsAckEliciting() {
@@ -1979,11 +2358,30 @@ func (s *connection) sendPackedPacket(packet *packedPacket, now time.Time) {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
 	s.logPacket(packet)
+	if s.config.PRPacketMarking != (PacketMarking{}) {
+		packet.buffer.partiallyReliable = carriesPRData(packet.frames)
+	}
 	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
 	s.connIDManager.SentPacket()
+	if s.config.TestingLossInjector != nil && !packet.header.IsLongHeader && s.config.TestingLossInjector(packet.header.PacketNumber) {
+		s.logger.Debugf("Dropping packet %d (TestingLossInjector)", packet.header.PacketNumber)
+		packet.buffer.Release()
+		return
+	}
 	s.sendQueue.Send(packet.buffer)
 }
 
+// carriesPRData says if a packet carries partially reliable data, see Config.PRPacketMarking.
+func carriesPRData(frames []ackhandler.Frame) bool {
+	for _, f := range frames {
+		switch f.Frame.(type) {
+		case *wire.PRStreamFrame, *wire.PRDatagramFrame:
+			return true
+		}
+	}
+	return false
+}
+
 func (s *connection) sendConnectionClose(e error) ([]byte, error) {
 	var packet *coalescedPacket
 	var err error
@@ -2053,6 +2451,30 @@ func (s *connection) logPacket(packet *packedPacket) {
 	s.logPacketContents(packet.packetContents)
 }
 
+func (s *connection) OnStream(cb func(Stream)) {
+	go func() {
+		for {
+			str, err := s.streamsMap.AcceptStream(context.Background())
+			if err != nil {
+				return
+			}
+			go cb(str)
+		}
+	}()
+}
+
+func (s *connection) OnUniStream(cb func(ReceiveStream)) {
+	go func() {
+		for {
+			str, err := s.streamsMap.AcceptUniStream(context.Background())
+			if err != nil {
+				return
+			}
+			go cb(str)
+		}
+	}()
+}
+
 // AcceptStream returns the next stream openend by the peer
 func (s *connection) AcceptStream(ctx context.Context) (Stream, error) {
 	return s.streamsMap.AcceptStream(ctx)
@@ -2062,6 +2484,14 @@ func (s *connection) AcceptUniStream(ctx context.Context) (ReceiveStream, error)
 	return s.streamsMap.AcceptUniStream(ctx)
 }
 
+func (s *connection) SetMaxIncomingStreams(n int64) {
+	s.streamsMap.SetMaxIncomingStreams(uint64(utils.Max(n, 0)))
+}
+
+func (s *connection) SetMaxIncomingUniStreams(n int64) {
+	s.streamsMap.SetMaxIncomingUniStreams(uint64(utils.Max(n, 0)))
+}
+
 // OpenStream opens a stream
 func (s *connection) OpenStream() (Stream, error) {
 	return s.streamsMap.OpenStream()
@@ -2095,11 +2525,32 @@ func (s *connection) newFlowController(id protocol.StreamID) flowcontrol.StreamF
 		protocol.ByteCount(s.config.MaxStreamReceiveWindow),
 		initialSendWindow,
 		s.onHasStreamWindowUpdate,
+		s.streamWindowUpdateFunc(id),
 		s.rttStats,
 		s.logger,
 	)
 }
 
+func (s *connection) connectionWindowUpdateFunc() flowcontrol.WindowUpdateFunc {
+	strategy := s.config.WindowUpdateStrategy
+	if strategy == nil {
+		return nil
+	}
+	return func(bytesRead, receiveWindow, windowSize protocol.ByteCount) protocol.ByteCount {
+		return protocol.ByteCount(strategy.ConnectionWindowUpdate(newWindowState(bytesRead, receiveWindow, windowSize)))
+	}
+}
+
+func (s *connection) streamWindowUpdateFunc(id protocol.StreamID) flowcontrol.WindowUpdateFunc {
+	strategy := s.config.WindowUpdateStrategy
+	if strategy == nil {
+		return nil
+	}
+	return func(bytesRead, receiveWindow, windowSize protocol.ByteCount) protocol.ByteCount {
+		return protocol.ByteCount(strategy.StreamWindowUpdate(id, newWindowState(bytesRead, receiveWindow, windowSize)))
+	}
+}
+
 // scheduleSending signals that we have data for sending
 func (s *connection) scheduleSending() {
 	select {
@@ -2154,18 +2605,86 @@ func (s *connection) onStreamCompleted(id protocol.StreamID) {
 	}
 }
 
+func (s *connection) onIdleStreamCanceled(id protocol.StreamID) {
+	if s.logger.Debug() {
+		s.logger.With("stream_id", id).Debugf("Canceled stream, since no data was written for the idle timeout.")
+	}
+	if s.tracer != nil {
+		s.tracer.CanceledIdleStream(id)
+	}
+}
+
+func (s *connection) onSpuriousPRConversion(id protocol.StreamID, offset, length protocol.ByteCount, notifyCanceled bool) {
+	if s.logger.Debug() {
+		s.logger.With("stream_id", id).Debugf("Skipped data (offset %d, length %d) was acknowledged after all. PRAckNotify canceled: %t", offset, length, notifyCanceled)
+	}
+	if s.tracer != nil {
+		s.tracer.SpuriousPRConversion(id, offset, length, notifyCanceled)
+	}
+}
+
+func (s *connection) onPRPolicyChanged(id protocol.StreamID, offset protocol.ByteCount, policy PRPolicy) {
+	s.queueControlFrame(&wire.PRStreamPolicyFrame{
+		StreamID: id,
+		Offset:   offset,
+		PTDA:     policy.PTDA,
+		Value:    policy.Value,
+	})
+	if s.logger.Debug() {
+		s.logger.With("stream_id", id, "pr_policy", policy).Debugf("Changed the PR policy at offset %d", offset)
+	}
+	if s.tracer != nil {
+		s.tracer.UpdatedPRPolicy(id, offset, policy.PTDA, policy.Value, false)
+	}
+}
+
+func (s *connection) MaxStreamFrameDataSize(id StreamID) int {
+	packetSize := protocol.ByteCount(atomic.LoadInt64(&s.maxPacketSize))
+	// Assume the largest possible offset, such that the size doesn't shrink as more data is sent on the stream.
+	f := &wire.StreamFrame{StreamID: id, Offset: protocol.MaxByteCount, DataLenPresent: true}
+	maxSize := packetSize - protocol.DatagramFramePacketOverhead - wire.MaxPRStreamFrameOverhead
+	return int(f.MaxDataLen(maxSize, s.version))
+}
+
+func (s *connection) SetPRPolicy(policy *PRPolicy) {
+	s.prPolicies.SetConnectionPolicy(policy)
+}
+
+func (s *connection) PRStats() PRStats {
+	return s.prPolicies.Stats()
+}
+
 func (s *connection) SendMessage(p []byte) error {
+	return s.SendMessageWithPolicy(p, PRPolicy{})
+}
+
+func (s *connection) SendMessageWithPolicy(p []byte, policy PRPolicy) error {
+	return s.sendMessage(p, policy, nil)
+}
+
+func (s *connection) SendMessageTracked(p []byte, policy PRPolicy) (*DatagramHandle, error) {
+	handle := newDatagramHandle()
+	if err := s.sendMessage(p, policy, handle); err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
+
+func (s *connection) sendMessage(p []byte, policy PRPolicy, handle *DatagramHandle) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
 	if !s.supportsDatagrams() {
 		return errors.New("datagram support disabled")
 	}
 
 	f := &wire.DatagramFrame{DataLenPresent: true}
-	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
+	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.maxDatagramFrameSize(), s.version) {
 		return errors.New("message too large")
 	}
 	f.Data = make([]byte, len(p))
 	copy(f.Data, p)
-	return s.datagramQueue.AddAndWait(f)
+	return s.datagramQueue.Add(f, policy, handle)
 }
 
 func (s *connection) ReceiveMessage() ([]byte, error) {
@@ -2175,6 +2694,10 @@ func (s *connection) ReceiveMessage() ([]byte, error) {
 	return s.datagramQueue.Receive()
 }
 
+func (s *connection) Events() <-chan TransportEvent {
+	return s.events.Events()
+}
+
 func (s *connection) LocalAddr() net.Addr {
 	return s.conn.LocalAddr()
 }
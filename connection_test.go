@@ -24,6 +24,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/quicvarint"
 
 	"github.com/golang/mock/gomock"
 
@@ -37,6 +38,22 @@ func areConnsRunning() bool {
 	return strings.Contains(b.String(), "quic-go.(*connection).run")
 }
 
+type loggedMessage struct {
+	msg    string
+	fields []interface{}
+}
+
+// recordingLogger is a Logger that records the debug messages
+type recordingLogger struct{ messages []loggedMessage }
+
+var _ Logger = &recordingLogger{}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, loggedMessage{msg: msg, fields: keysAndValues})
+}
+func (l *recordingLogger) Info(string, ...interface{})  {}
+func (l *recordingLogger) Error(string, ...interface{}) {}
+
 var _ = Describe("Connection", func() {
 	var (
 		conn          *connection
@@ -86,6 +103,7 @@ var _ = Describe("Connection", func() {
 		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
 		tracer.EXPECT().NegotiatedVersion(gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
+		tracer.EXPECT().SetPRRandSeed(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
 		conn = newConnection(
@@ -146,6 +164,61 @@ var _ = Describe("Connection", func() {
 				Expect(conn.handleStreamFrame(f)).To(MatchError(testErr))
 			})
 
+			It("passes the expiry of PRSTREAM frames with a deadline to the stream", func() {
+				f := &wire.PRStreamFrame{
+					StreamID: 5,
+					Data:     []byte{0xde, 0xca, 0xfb, 0xad},
+					PTDA:     PTDADeadline,
+					D:        true,
+					PtdaC:    500,
+				}
+				str := NewMockReceiveStreamI(mockCtrl)
+				var expiry time.Time
+				str.EXPECT().handleExpiringStreamFrame(gomock.Any(), gomock.Any()).Do(func(_ *wire.StreamFrame, t time.Time) { expiry = t })
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+				Expect(conn.handlePRStreamFrame(f)).To(Succeed())
+				Expect(expiry).To(BeTemporally("~", time.Now().Add(500*time.Millisecond), scaleDuration(10*time.Millisecond)))
+			})
+
+			It("passes zero-filled data for PRAckNotify frames to the stream", func() {
+				str := NewMockReceiveStreamI(mockCtrl)
+				str.EXPECT().handleSkippedStreamFrame(&wire.StreamFrame{
+					StreamID:       5,
+					Offset:         10,
+					Data:           make([]byte, 4),
+					Fin:            true,
+					DataLenPresent: true,
+				})
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+				Expect(conn.handlePRAckNotifyFrame(&wire.PRAckNotifyFrame{
+					StreamID:       5,
+					Offset:         10,
+					PRDataLen:      4,
+					Fin:            true,
+					DataLenPresent: true,
+				})).To(Succeed())
+			})
+
+			It("passes PR_MESSAGE_BOUNDARY frames to the stream", func() {
+				f := &wire.PRMessageBoundaryFrame{StreamID: 5, Offset: 42}
+				str := NewMockReceiveStreamI(mockCtrl)
+				str.EXPECT().handleMessageBoundaryFrame(f)
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+				Expect(conn.handlePRMessageBoundaryFrame(f)).To(Succeed())
+			})
+
+			It("traces PR_STREAM_POLICY frames", func() {
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(NewMockReceiveStreamI(mockCtrl), nil)
+				tracer.EXPECT().UpdatedPRPolicy(protocol.StreamID(5), protocol.ByteCount(42), PTDADeadline, uint64(100), true)
+				Expect(conn.handlePRStreamPolicyFrame(&wire.PRStreamPolicyFrame{StreamID: 5, Offset: 42, PTDA: PTDADeadline, Value: 100})).To(Succeed())
+			})
+
+			It("rejects PR_STREAM_POLICY frames with invalid policies", func() {
+				err := conn.handlePRStreamPolicyFrame(&wire.PRStreamPolicyFrame{StreamID: 5, Offset: 42, PTDA: 0x3})
+				Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
+				Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+			})
+
 			It("ignores STREAM frames for closed streams", func() {
 				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(nil, nil) // for closed streams, the streamManager returns nil
 				Expect(conn.handleStreamFrame(&wire.StreamFrame{
@@ -153,6 +226,98 @@ var _ = Describe("Connection", func() {
 					Data:     []byte("foobar"),
 				})).To(Succeed())
 			})
+
+			Context("accepting incoming streams", func() {
+				var infos []IncomingStreamInfo
+				var decision IncomingStreamDecision
+
+				BeforeEach(func() {
+					infos = nil
+					decision = IncomingStreamDecision{}
+					conn.config.AcceptIncomingStream = func(c Connection, info IncomingStreamInfo) IncomingStreamDecision {
+						Expect(c).To(Equal(conn))
+						infos = append(infos, info)
+						return decision
+					}
+				})
+
+				It("calls the callback once for every new incoming stream", func() {
+					f := &wire.StreamFrame{StreamID: 4, Data: []byte("foobar")}
+					str := NewMockReceiveStreamI(mockCtrl)
+					str.EXPECT().handleStreamFrame(gomock.Any()).Times(2)
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(4)).Return(str, nil).Times(2)
+					Expect(conn.handleStreamFrame(f)).To(Succeed())
+					Expect(infos).To(Equal([]IncomingStreamInfo{
+						{StreamID: 0},
+						{StreamID: 4, Data: []byte("foobar")},
+					}))
+					Expect(conn.handleStreamFrame(&wire.StreamFrame{StreamID: 4, Offset: 6, Data: []byte("raboof")})).To(Succeed())
+					Expect(infos).To(HaveLen(2))
+				})
+
+				It("doesn't pass the data of frames that don't start at offset 0", func() {
+					str := NewMockReceiveStreamI(mockCtrl)
+					str.EXPECT().handleStreamFrame(gomock.Any())
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(2)).Return(str, nil)
+					Expect(conn.handleStreamFrame(&wire.StreamFrame{StreamID: 2, Offset: 10, Data: []byte("foobar")})).To(Succeed())
+					Expect(infos).To(Equal([]IncomingStreamInfo{{StreamID: 2}}))
+				})
+
+				It("calls the callback for streams opened by a PR_MESSAGE_BOUNDARY frame", func() {
+					decision = IncomingStreamDecision{Reject: true, ErrorCode: 42}
+					f := &wire.PRMessageBoundaryFrame{StreamID: 6, Offset: 42}
+					str := NewMockReceiveStreamI(mockCtrl)
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(2)).Return(str, nil)
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(6)).Return(str, nil).Times(2)
+					streamManager.EXPECT().RejectIncomingStream(protocol.StreamID(2))
+					streamManager.EXPECT().RejectIncomingStream(protocol.StreamID(6))
+					str.EXPECT().CancelRead(StreamErrorCode(42)).Times(2)
+					str.EXPECT().handleMessageBoundaryFrame(f)
+					Expect(conn.handlePRMessageBoundaryFrame(f)).To(Succeed())
+					Expect(infos).To(Equal([]IncomingStreamInfo{{StreamID: 2}, {StreamID: 6}}))
+				})
+
+				It("doesn't call the callback for outgoing streams", func() {
+					str := NewMockReceiveStreamI(mockCtrl)
+					str.EXPECT().handleStreamFrame(gomock.Any())
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+					Expect(conn.handleStreamFrame(&wire.StreamFrame{StreamID: 5, Data: []byte("foobar")})).To(Succeed())
+					Expect(infos).To(BeEmpty())
+				})
+
+				It("rejects unidirectional streams", func() {
+					decision = IncomingStreamDecision{Reject: true, ErrorCode: 42}
+					str := NewMockReceiveStreamI(mockCtrl)
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(2)).Return(str, nil).Times(2)
+					streamManager.EXPECT().RejectIncomingStream(protocol.StreamID(2))
+					str.EXPECT().CancelRead(StreamErrorCode(42))
+					str.EXPECT().handleStreamFrame(gomock.Any())
+					Expect(conn.handleStreamFrame(&wire.StreamFrame{StreamID: 2, Data: []byte("foobar")})).To(Succeed())
+				})
+
+				It("rejects bidirectional streams", func() {
+					decision = IncomingStreamDecision{Reject: true, ErrorCode: 42}
+					str := NewMockStreamI(mockCtrl)
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(0)).Return(str, nil).Times(2)
+					streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(0)).Return(str, nil)
+					streamManager.EXPECT().RejectIncomingStream(protocol.StreamID(0))
+					str.EXPECT().CancelRead(StreamErrorCode(42))
+					str.EXPECT().CancelWrite(StreamErrorCode(42))
+					str.EXPECT().handleStreamFrame(gomock.Any())
+					Expect(conn.handleStreamFrame(&wire.StreamFrame{StreamID: 0, Data: []byte("foobar")})).To(Succeed())
+				})
+
+				It("sets the write policy", func() {
+					policy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+					decision = IncomingStreamDecision{Policy: &policy}
+					str := NewMockStreamI(mockCtrl)
+					streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(0)).Return(str, nil).Times(2)
+					streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(0)).Return(str, nil)
+					str.EXPECT().SetPRPolicy(&policy)
+					str.EXPECT().handleStreamFrame(gomock.Any())
+					Expect(conn.handleStreamFrame(&wire.StreamFrame{StreamID: 0, Data: []byte("foobar")})).To(Succeed())
+				})
+			})
 		})
 
 		Context("handling ACK frames", func() {
@@ -164,6 +329,34 @@ var _ = Describe("Connection", func() {
 				err := conn.handleAckFrame(f, protocol.EncryptionHandshake)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("updates the PR stats when 1-RTT packets are acknowledged", func() {
+				conn.rttStats.UpdateRTT(40*time.Millisecond, 0, time.Now())
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any()).Return(true, nil)
+				sph.EXPECT().AckMetrics().Return(ackhandler.AckMetrics{
+					SmoothedAckDelay:       5 * time.Millisecond,
+					MaxAckDelay:            25 * time.Millisecond,
+					ReorderingDistance:     3,
+					ReorderingTime:         8 * time.Millisecond,
+					SmoothedReorderingTime: 2 * time.Millisecond,
+					BandwidthEstimate:      10_000_000,
+				})
+				cryptoSetup.EXPECT().SetLargest1RTTAcked(protocol.PacketNumber(3))
+				conn.sentPacketHandler = sph
+				Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(conn.PRStats()).To(Equal(PRStats{
+					SmoothedRTT:            40 * time.Millisecond,
+					AckDelay:               5 * time.Millisecond,
+					MaxAckDelay:            25 * time.Millisecond,
+					ReorderingDistance:     3,
+					ReorderingTime:         8 * time.Millisecond,
+					SmoothedReorderingTime: 2 * time.Millisecond,
+					DeadlineMargin:         (20 + 5 + 2) * time.Millisecond,
+					BandwidthEstimate:      10_000_000,
+				}))
+			})
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -178,6 +371,7 @@ var _ = Describe("Connection", func() {
 				str.EXPECT().handleResetStreamFrame(f)
 				err := conn.handleResetStreamFrame(f)
 				Expect(err).ToNot(HaveOccurred())
+				Expect(conn.Events()).To(Receive(Equal(StreamResetEvent{StreamID: 555, ErrorCode: 42, FinalSize: 0x1337})))
 			})
 
 			It("returns errors", func() {
@@ -236,6 +430,18 @@ var _ = Describe("Connection", func() {
 			})
 		})
 
+		It("uses the window update strategy for stream flow control", func() {
+			conn.config.WindowUpdateStrategy = &ThresholdWindowUpdateStrategy{Threshold: 100, Increment: 10000}
+			conn.peerParams = &wire.TransportParameters{}
+			fc := conn.newFlowController(5)
+			window := protocol.ByteCount(conn.config.InitialStreamReceiveWindow)
+			Expect(fc.UpdateHighestReceived(window-50, false)).To(Succeed())
+			fc.AddBytesRead(window - 101)
+			Expect(fc.GetWindowUpdate()).To(BeZero())
+			fc.AddBytesRead(2)
+			Expect(fc.GetWindowUpdate()).To(Equal(window - 99 + 10000))
+		})
+
 		Context("handling MAX_STREAM_ID frames", func() {
 			It("passes the frame to the streamsMap", func() {
 				f := &wire.MaxStreamsFrame{
@@ -303,13 +509,25 @@ var _ = Describe("Connection", func() {
 			Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 		})
 
-		It("handles BLOCKED frames", func() {
+		It("evicts expired data when receiving BLOCKED frames", func() {
+			expiry := time.Now().Add(time.Hour)
+			streamManager.EXPECT().EvictExpiredData(gomock.Any()).Return(expiry)
 			err := conn.handleFrame(&wire.DataBlockedFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
+			Expect(conn.nextEvictionTime).To(Equal(expiry))
+		})
+
+		It("evicts expired data when receiving STREAM_BLOCKED frames", func() {
+			str := NewMockReceiveStreamI(mockCtrl)
+			streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(4)).Return(str, nil)
+			str.EXPECT().evictExpiredData(gomock.Any())
+			err := conn.handleFrame(&wire.StreamDataBlockedFrame{StreamID: 4}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("handles STREAM_BLOCKED frames", func() {
-			err := conn.handleFrame(&wire.StreamDataBlockedFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
+		It("ignores STREAM_BLOCKED frames for closed streams", func() {
+			streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(4))
+			err := conn.handleFrame(&wire.StreamDataBlockedFrame{StreamID: 4}, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -575,7 +793,7 @@ var _ = Describe("Connection", func() {
 			// don't EXPECT any calls to packer.PackPacket()
 			conn.handlePacket(&receivedPacket{
 				rcvTime:    time.Now(),
-				remoteAddr: &net.UDPAddr{},
+				remoteAddr: remoteAddr,
 				buffer:     getPacketBuffer(),
 				data:       buf.Bytes(),
 			})
@@ -793,6 +1011,123 @@ var _ = Describe("Connection", func() {
 			Expect(conn.handlePacketImpl(packet)).To(BeFalse())
 		})
 
+		Context("migrating to a new client address", func() {
+			var sph *mockackhandler.MockSentPacketHandler
+
+			BeforeEach(func() {
+				sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedBytes(gomock.Any()).AnyTimes()
+				conn.sentPacketHandler = sph
+				conn.handshakeConfirmed = true
+				conn.pathManager = newPathManager(1)
+			})
+
+			receivePacket := func(addr net.Addr, pn protocol.PacketNumber, frame wire.Frame) {
+				b, err := frame.Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				packet := getPacket(&wire.ExtendedHeader{
+					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumber:    0x37,
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}, nil)
+				packet.remoteAddr = addr
+				unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(pn, protocol.PacketNumberLen2, protocol.KeyPhaseZero, b, nil)
+				tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any())
+				Expect(conn.handlePacketImpl(packet)).To(BeTrue())
+			}
+
+			getPathChallenge := func() *wire.PathChallengeFrame {
+				frames, _ := conn.framer.AppendControlFrames(nil, 1000)
+				ExpectWithOffset(1, frames).To(HaveLen(1))
+				ExpectWithOffset(1, frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+				return frames[0].Frame.(*wire.PathChallengeFrame)
+			}
+
+			It("migrates and validates the new address, keeping the congestion state after a NAT rebinding", func() {
+				newAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				sph.EXPECT().StartPathValidation(gomock.Any())
+				receivePacket(newAddr, 10, &wire.PingFrame{})
+				f := getPathChallenge()
+				Expect(conn.pathManager.ValidationDeadline()).ToNot(BeZero())
+				sph.EXPECT().PathValidated()
+				Expect(conn.handleFrame(&wire.PathResponseFrame{Data: f.Data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(conn.pathManager.ValidationDeadline()).To(BeZero())
+			})
+
+			It("only counts the bytes received on the current path towards the amplification limit, until it is validated", func() {
+				sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				conn.sentPacketHandler = sph
+				newAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}
+				sph.EXPECT().ReceivedBytes(gomock.Any())
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				var bytesReceived protocol.ByteCount
+				sph.EXPECT().StartPathValidation(gomock.Any()).Do(func(n protocol.ByteCount) { bytesReceived = n })
+				receivePacket(newAddr, 10, &wire.PingFrame{})
+				Expect(bytesReceived).ToNot(BeZero())
+				getPathChallenge()
+				// The mock conn keeps returning remoteAddr as the current address.
+				receivePacket(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4343}, 11, &wire.PathChallengeFrame{})
+				sph.EXPECT().ReceivedBytes(gomock.Any())
+				receivePacket(remoteAddr, 12, &wire.PingFrame{})
+			})
+
+			It("resets the congestion state if the IP address changed", func() {
+				newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				sph.EXPECT().MigratedPath(gomock.Any())
+				sph.EXPECT().StartPathValidation(gomock.Any())
+				receivePacket(newAddr, 10, &wire.PingFrame{})
+				getPathChallenge()
+			})
+
+			It("requires the configured number of packets before migrating", func() {
+				conn.pathManager = newPathManager(2)
+				newAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}
+				receivePacket(newAddr, 10, &wire.PingFrame{})
+				frames, _ := conn.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(BeEmpty())
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				sph.EXPECT().StartPathValidation(gomock.Any())
+				receivePacket(newAddr, 11, &wire.PingFrame{})
+				getPathChallenge()
+			})
+
+			It("doesn't migrate when receiving a probing packet", func() {
+				newAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}
+				receivePacket(newAddr, 10, &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}})
+				frames, _ := conn.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathResponseFrame{}))
+			})
+
+			It("doesn't migrate before the handshake is confirmed", func() {
+				conn.handshakeConfirmed = false
+				receivePacket(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}, 10, &wire.PingFrame{})
+				frames, _ := conn.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(BeEmpty())
+			})
+
+			It("doesn't migrate if migration is disabled", func() {
+				conn.pathManager = nil
+				receivePacket(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}, 10, &wire.PingFrame{})
+				frames, _ := conn.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(BeEmpty())
+			})
+
+			It("returns to the previous address if the new address can't be validated", func() {
+				newAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4242}
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				sph.EXPECT().StartPathValidation(gomock.Any())
+				receivePacket(newAddr, 10, &wire.PingFrame{})
+				getPathChallenge()
+				mconn.EXPECT().SetRemoteAddr(remoteAddr)
+				sph.EXPECT().PathValidated()
+				conn.onPathValidationTimeout()
+				Expect(conn.pathManager.ValidationDeadline()).To(BeZero())
+			})
+		})
+
 		It("drops a packet when unpacking fails", func() {
 			unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1239,6 +1574,83 @@ var _ = Describe("Connection", func() {
 			Eventually(sent).Should(BeClosed())
 		})
 
+		It("blocks PR streams while the scavenger congestion controller doesn't allow sending", func() {
+			conn.handshakeConfirmed = true
+			conn.config.PR.ScavengerCongestionControl = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().CanSendPR().Return(false)
+			conn.sentPacketHandler = sph
+			runConn()
+			packed := make(chan struct{})
+			packer.EXPECT().PackPacket(false).DoAndReturn(func(bool) (*packedPacket, error) {
+				close(packed)
+				return nil, nil
+			})
+			conn.scheduleSending()
+			Eventually(packed).Should(BeClosed())
+			Expect(conn.framer.(*framerI).prBlocked).To(BeTrue())
+		})
+
+		It("marks packets that carry partially reliable data", func() {
+			conn.handshakeConfirmed = true
+			conn.config.PRPacketMarking = PacketMarking{DSCP: 34}
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any()).Times(2)
+			conn.sentPacketHandler = sph
+			runConn()
+			p1 := getPacket(1)
+			p1.frames = []ackhandler.Frame{{Frame: &wire.PRStreamFrame{StreamID: 4, Data: []byte("foobar")}}}
+			p2 := getPacket(2)
+			p2.frames = []ackhandler.Frame{{Frame: &wire.StreamFrame{StreamID: 8, Data: []byte("foobar")}}}
+			packer.EXPECT().PackPacket(false).Return(p1, nil)
+			packer.EXPECT().PackPacket(false).Return(p2, nil)
+			packer.EXPECT().PackPacket(false).Return(nil, nil).AnyTimes()
+			sent := make(chan bool, 2)
+			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { sent <- packet.partiallyReliable }).Times(2)
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			conn.scheduleSending()
+			Eventually(sent).Should(Receive(BeTrue()))
+			Eventually(sent).Should(Receive(BeFalse()))
+		})
+
+		It("drops packets selected by the loss injector", func() {
+			conn.handshakeConfirmed = true
+			var injected []protocol.PacketNumber
+			conn.config.TestingLossInjector = func(pn protocol.PacketNumber) bool {
+				injected = append(injected, pn)
+				return pn == 1
+			}
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			// the dropped packet is tracked by the sent packet handler, such that the loss is detected
+			var sentPNs []protocol.PacketNumber
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) { sentPNs = append(sentPNs, p.PacketNumber) }).Times(2)
+			conn.sentPacketHandler = sph
+			runConn()
+			packer.EXPECT().PackPacket(false).Return(getPacket(1), nil)
+			packer.EXPECT().PackPacket(false).Return(getPacket(2), nil)
+			packer.EXPECT().PackPacket(false).Return(nil, nil).AnyTimes()
+			sent := make(chan []byte, 2)
+			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { sent <- packet.Data })
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			conn.scheduleSending()
+			Eventually(sent).Should(Receive(Equal([]byte("foobar"))))
+			Consistently(sent).ShouldNot(Receive())
+			Expect(sentPNs).To(Equal([]protocol.PacketNumber{1, 2}))
+			Expect(injected).To(Equal([]protocol.PacketNumber{1, 2}))
+		})
+
 		It("doesn't send packets if there's nothing to send", func() {
 			conn.handshakeConfirmed = true
 			runConn()
@@ -1833,6 +2245,8 @@ var _ = Describe("Connection", func() {
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
 		close(finishHandshake)
 		Eventually(handshakeCtx.Done()).Should(BeClosed())
+		// the server confirms the handshake as soon as it completes
+		Expect(conn.HandshakeConfirmed().Done()).To(BeClosed())
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
@@ -2004,6 +2418,7 @@ var _ = Describe("Connection", func() {
 	Context("transport parameters", func() {
 		It("processes transport parameters received from the client", func() {
 			params := &wire.TransportParameters{
+				PartialReliability:            true,
 				MaxIdleTimeout:                90 * time.Second,
 				InitialMaxStreamDataBidiLocal: 0x5000,
 				InitialMaxData:                0x5000,
@@ -2022,6 +2437,51 @@ var _ = Describe("Connection", func() {
 			conn.handleTransportParameters(params)
 			Expect(conn.earlyConnReady()).To(BeClosed())
 		})
+
+		It("disables partial reliability if the client doesn't support it", func() {
+			params := &wire.TransportParameters{
+				ActiveConnectionIDLimit:   3,
+				InitialSourceConnectionID: destConnID,
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().PackCoalescedPacket(false).MaxTimes(3)
+			connRunner.EXPECT().GetStatelessResetToken(gomock.Any()).Times(2)
+			connRunner.EXPECT().Add(gomock.Any(), conn).Times(2)
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().DisabledPR(logging.PRDisabledNotNegotiated)
+			Expect(conn.prPolicies.Enabled()).To(BeTrue())
+			conn.handleTransportParameters(params)
+			Expect(conn.prPolicies.Enabled()).To(BeFalse())
+		})
+
+		It("delivers events for the transport parameters and the PR negotiation", func() {
+			params := &wire.TransportParameters{
+				MaxIdleTimeout:            time.Minute,
+				InitialMaxData:            0x5000,
+				MaxBidiStreamNum:          4,
+				MaxUniStreamNum:           2,
+				MaxDatagramFrameSize:      protocol.InvalidByteCount,
+				ActiveConnectionIDLimit:   3,
+				InitialSourceConnectionID: destConnID,
+				PartialReliability:        true,
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().PackCoalescedPacket(false).MaxTimes(3)
+			connRunner.EXPECT().GetStatelessResetToken(gomock.Any()).Times(2)
+			connRunner.EXPECT().Add(gomock.Any(), conn).Times(2)
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			conn.handleTransportParameters(params)
+			Expect(conn.Events()).To(Receive(Equal(PeerTransportParametersEvent{
+				MaxIdleTimeout:     time.Minute,
+				InitialMaxData:     0x5000,
+				MaxBidiStreams:     4,
+				MaxUniStreams:      2,
+				PartialReliability: true,
+			})))
+			Expect(conn.Events()).To(Receive(Equal(PRNegotiatedEvent{Enabled: true})))
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -2030,6 +2490,7 @@ var _ = Describe("Connection", func() {
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			tracer.EXPECT().ReceivedTransportParameters(gomock.Any())
 			conn.handleTransportParameters(&wire.TransportParameters{
+				PartialReliability:        true,
 				MaxIdleTimeout:            t,
 				InitialSourceConnectionID: destConnID,
 			})
@@ -2340,6 +2801,153 @@ var _ = Describe("Connection", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
+
+		It("calls the OnStream callback for accepted streams", func() {
+			mstr1 := NewMockStreamI(mockCtrl)
+			mstr2 := NewMockStreamI(mockCtrl)
+			gomock.InOrder(
+				streamManager.EXPECT().AcceptStream(gomock.Any()).Return(mstr1, nil),
+				streamManager.EXPECT().AcceptStream(gomock.Any()).Return(mstr2, nil),
+				streamManager.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("closed")),
+			)
+			streams := make(chan Stream, 2)
+			conn.OnStream(func(str Stream) { streams <- str })
+			Eventually(streams).Should(HaveLen(2))
+			Expect(streams).To(Receive(Or(Equal(mstr1), Equal(mstr2))))
+			Expect(streams).To(Receive(Or(Equal(mstr1), Equal(mstr2))))
+		})
+
+		It("calls the OnUniStream callback for accepted streams", func() {
+			mstr := NewMockReceiveStreamI(mockCtrl)
+			gomock.InOrder(
+				streamManager.EXPECT().AcceptUniStream(gomock.Any()).Return(mstr, nil),
+				streamManager.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("closed")),
+			)
+			streams := make(chan ReceiveStream, 1)
+			conn.OnUniStream(func(str ReceiveStream) { streams <- str })
+			Eventually(streams).Should(Receive(Equal(mstr)))
+		})
+	})
+
+	Context("sending datagrams", func() {
+		BeforeEach(func() {
+			conn.peerParams = &wire.TransportParameters{MaxDatagramFrameSize: protocol.MaxDatagramFrameSize}
+		})
+
+		It("returns an error when the peer doesn't support datagrams", func() {
+			conn.peerParams = &wire.TransportParameters{}
+			Expect(conn.SendMessage([]byte("foobar"))).To(MatchError("datagram support disabled"))
+		})
+
+		It("limits the message size to the peer's max_datagram_frame_size", func() {
+			conn.peerParams.MaxDatagramFrameSize = 1000
+			cryptoSetup.EXPECT().ConnectionState().AnyTimes()
+			size := conn.ConnectionState().MaxDatagramSize
+			Expect(size).To(BeNumerically("<", 1000))
+			Expect(size).To(BeNumerically(">", 990))
+			Expect(conn.SendMessage(make([]byte, size+1))).To(MatchError("message too large"))
+		})
+
+		It("limits the message size to the packet size", func() {
+			conn.peerParams.MaxDatagramFrameSize = protocol.MaxPacketBufferSize
+			cryptoSetup.EXPECT().ConnectionState().AnyTimes()
+			size := conn.ConnectionState().MaxDatagramSize
+			Expect(size).To(BeNumerically("<", protocol.MaxDatagramFrameSize))
+			conn.maxPacketSize = int64(protocol.MaxPacketBufferSize)
+			Expect(conn.ConnectionState().MaxDatagramSize).To(Equal(size + int(protocol.MaxPacketBufferSize-protocol.InitialPacketSizeIPv4)))
+		})
+
+		It("returns a handle for tracked messages", func() {
+			conn.datagramQueue = newDatagramQueue(func() {}, func(int, bool, bool) {}, 1, DatagramOverflowDropNewest, utils.DefaultLogger, conn.version)
+			handle, err := conn.SendMessageTracked([]byte("foobar"), PRPolicy{})
+			Expect(err).ToNot(HaveOccurred())
+			f, h := conn.datagramQueue.Get(protocol.MaxByteCount)
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(h).To(Equal(handle))
+			_, err = conn.SendMessageTracked([]byte("foo"), PRPolicy{PTDA: 0x42})
+			Expect(err).To(MatchError("invalid PR policy: PTDA 0x42"))
+		})
+
+		It("rejects invalid policies", func() {
+			Expect(conn.SendMessageWithPolicy([]byte("foobar"), PRPolicy{PTDA: 0x42})).To(MatchError("invalid PR policy: PTDA 0x42"))
+		})
+
+		It("uses the priority of the policy when the send queue overflows", func() {
+			conn.datagramQueue = newDatagramQueue(func() {}, func(int, bool, bool) {}, 1, DatagramOverflowDropLowestPriority, utils.DefaultLogger, conn.version)
+			Expect(conn.SendMessageWithPolicy([]byte("foo"), PRPolicy{PTDA: PTDAPriority, Value: 2})).To(Succeed())
+			Expect(conn.SendMessageWithPolicy([]byte("bar"), PRPolicy{PTDA: PTDAPriority, Value: 1})).To(MatchError(ErrDatagramDropped))
+			Expect(conn.SendMessage([]byte("baz"))).To(MatchError(ErrDatagramDropped))
+			Expect(conn.SendMessageWithPolicy([]byte("qux"), PRPolicy{PTDA: PTDAPriority, Value: 3})).To(Succeed())
+			f, _ := conn.datagramQueue.Get(protocol.MaxByteCount)
+			Expect(f.Data).To(Equal([]byte("qux")))
+		})
+	})
+
+	It("says how much stream data fits into a packet", func() {
+		size := conn.MaxStreamFrameDataSize(4)
+		f := &wire.PRStreamFrame{
+			StreamID:       4,
+			Offset:         1 << 40,
+			Data:           make([]byte, size),
+			DataLenPresent: true,
+			PTDA:           PTDADeadline,
+			PtdaC:          quicvarint.Max,
+		}
+		Expect(f.Length(conn.version) + protocol.DatagramFramePacketOverhead).To(BeNumerically("<=", conn.maxPacketSize))
+		Expect(f.Length(conn.version) + protocol.DatagramFramePacketOverhead).To(BeNumerically(">", conn.maxPacketSize-10))
+		conn.maxPacketSize = int64(protocol.MaxPacketBufferSize)
+		Expect(conn.MaxStreamFrameDataSize(4)).To(BeNumerically(">", size))
+	})
+
+	It("sets the connection PR policy", func() {
+		policy := PRPolicy{PTDA: PTDATimes, Value: 3}
+		conn.SetPRPolicy(&policy)
+		p, source := conn.prPolicies.Get()
+		Expect(p).To(Equal(policy))
+		Expect(source).To(Equal(PRPolicySourceConnection))
+	})
+
+	It("traces streams that are canceled because they were idle", func() {
+		tracer.EXPECT().CanceledIdleStream(protocol.StreamID(5))
+		conn.onIdleStreamCanceled(5)
+	})
+
+	It("announces PR policy changes", func() {
+		policy := PRPolicy{PTDA: PTDADeadline, Value: 50}
+		tracer.EXPECT().UpdatedPRPolicy(protocol.StreamID(5), protocol.ByteCount(1000), PTDADeadline, uint64(50), false)
+		conn.onPRPolicyChanged(5, 1000, policy)
+		frames, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+		Expect(frames).To(HaveLen(1))
+		Expect(frames[0].Frame).To(Equal(&wire.PRStreamPolicyFrame{StreamID: 5, Offset: 1000, PTDA: PTDADeadline, Value: 50}))
+	})
+
+	It("uses the configured Logger", func() {
+		logger := &recordingLogger{}
+		serverLogger := utils.DefaultLogger.WithPrefix("server")
+		serverLogger.SetLogLevel(utils.LogLevelDebug)
+		conn.logger = newConnectionLogger(&Config{Logger: logger}, serverLogger, protocol.PerspectiveServer, "deadbeef")
+		tracer.EXPECT().CanceledIdleStream(protocol.StreamID(5))
+		conn.onIdleStreamCanceled(5)
+		Expect(logger.messages).To(Equal([]loggedMessage{{
+			msg:    "server Canceled stream, since no data was written for the idle timeout.",
+			fields: []interface{}{"conn_id", "deadbeef", "stream_id", protocol.StreamID(5)},
+		}}))
+	})
+
+	It("uses the log level of the logger of the server for the configured Logger", func() {
+		logger := &recordingLogger{}
+		serverLogger := utils.DefaultLogger.WithPrefix("server")
+		serverLogger.SetLogLevel(utils.LogLevelInfo)
+		conn.logger = newConnectionLogger(&Config{Logger: logger}, serverLogger, protocol.PerspectiveServer, "deadbeef")
+		Expect(conn.logger.Debug()).To(BeFalse())
+		tracer.EXPECT().CanceledIdleStream(protocol.StreamID(5))
+		conn.onIdleStreamCanceled(5)
+		Expect(logger.messages).To(BeEmpty())
+	})
+
+	It("uses the logger of the server, if no Logger is configured", func() {
+		logger := utils.DefaultLogger.WithPrefix("server")
+		Expect(newConnectionLogger(&Config{}, logger, protocol.PerspectiveServer, "deadbeef")).To(BeIdenticalTo(logger))
 	})
 
 	It("returns the local address", func() {
@@ -2396,6 +3004,7 @@ var _ = Describe("Client Connection", func() {
 		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
 		tracer.EXPECT().NegotiatedVersion(gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
+		tracer.EXPECT().SetPRRandSeed(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
 		conn = newClientConnection(
@@ -2495,6 +3104,25 @@ var _ = Describe("Client Connection", func() {
 		sph.EXPECT().SetHandshakeConfirmed()
 		cryptoSetup.EXPECT().SetHandshakeConfirmed()
 		Expect(conn.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(conn.HandshakeConfirmed().Done()).To(BeClosed())
+	})
+
+	It("records when the handshake was confirmed", func() {
+		conn.peerParams = &wire.TransportParameters{}
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		conn.sentPacketHandler = sph
+		sph.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().ConnectionState().Times(2)
+		state := conn.ConnectionState().Handshake
+		Expect(state.Start).To(BeTemporally("~", time.Now(), scaleDuration(100*time.Millisecond)))
+		Expect(state.Confirmed).To(BeZero())
+		Expect(state.Duration()).To(BeZero())
+		Consistently(conn.HandshakeConfirmed().Done()).ShouldNot(BeClosed())
+		Expect(conn.handleHandshakeDoneFrame()).To(Succeed())
+		state = conn.ConnectionState().Handshake
+		Expect(state.Confirmed).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+		Expect(state.Confirmed).To(BeTemporally(">=", state.Start))
 	})
 
 	It("interprets an ACK for 1-RTT packets as confirmation of the handshake", func() {
@@ -2503,6 +3131,7 @@ var _ = Describe("Client Connection", func() {
 		conn.sentPacketHandler = sph
 		ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}
 		sph.EXPECT().ReceivedAck(ack, protocol.Encryption1RTT, gomock.Any()).Return(true, nil)
+		sph.EXPECT().AckMetrics()
 		sph.EXPECT().SetHandshakeConfirmed()
 		cryptoSetup.EXPECT().SetLargest1RTTAcked(protocol.PacketNumber(3))
 		cryptoSetup.EXPECT().SetHandshakeConfirmed()
@@ -2528,6 +3157,76 @@ var _ = Describe("Client Connection", func() {
 		Eventually(areConnsRunning).Should(BeFalse())
 	})
 
+	Context("servers rejecting PR frames", func() {
+		var origHostCache *prHostCache
+
+		BeforeEach(func() {
+			origHostCache = prIncapableHosts
+			prIncapableHosts = newPRHostCache()
+		})
+
+		AfterEach(func() {
+			prIncapableHosts = origHostCache
+		})
+
+		It("disables partial reliability for the server, when it closes the connection because of a PR frame", func() {
+			expectedErr := &qerr.TransportError{
+				Remote:    true,
+				ErrorCode: qerr.FrameEncodingError,
+				FrameType: 0x48,
+			}
+			streamManager := NewMockStreamManager(mockCtrl)
+			conn.streamsMap = streamManager
+			streamManager.EXPECT().CloseWithError(expectedErr)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().DisabledPR(logging.PRDisabledFrameRejected),
+				tracer.EXPECT().ClosedConnection(expectedErr),
+				tracer.EXPECT().Close(),
+			)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				Expect(conn.run()).To(MatchError(expectedErr))
+			}()
+			Expect(conn.handleFrame(&wire.ConnectionCloseFrame{
+				ErrorCode: uint64(qerr.FrameEncodingError),
+				FrameType: 0x48,
+			}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Eventually(conn.Context().Done()).Should(BeClosed())
+			Expect(prIncapableHosts.Contains(conn.RemoteAddr().String())).To(BeTrue())
+		})
+
+		It("doesn't disable partial reliability when the server rejects other frames", func() {
+			expectedErr := &qerr.TransportError{
+				Remote:    true,
+				ErrorCode: qerr.FrameEncodingError,
+				FrameType: 0x1,
+			}
+			streamManager := NewMockStreamManager(mockCtrl)
+			conn.streamsMap = streamManager
+			streamManager.EXPECT().CloseWithError(expectedErr)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(expectedErr),
+				tracer.EXPECT().Close(),
+			)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				Expect(conn.run()).To(MatchError(expectedErr))
+			}()
+			Expect(conn.handleFrame(&wire.ConnectionCloseFrame{
+				ErrorCode: uint64(qerr.FrameEncodingError),
+				FrameType: 0x1,
+			}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Eventually(conn.Context().Done()).Should(BeClosed())
+			Expect(prIncapableHosts.Contains(conn.RemoteAddr().String())).To(BeFalse())
+		})
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
@@ -2733,6 +3432,7 @@ var _ = Describe("Client Connection", func() {
 
 		It("uses the preferred_address connection ID", func() {
 			params := &wire.TransportParameters{
+				PartialReliability:              true,
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       destConnID,
 				PreferredAddress: &wire.PreferredAddress{
@@ -2760,6 +3460,7 @@ var _ = Describe("Client Connection", func() {
 		It("uses the minimum of the peers' idle timeouts", func() {
 			conn.config.MaxIdleTimeout = 19 * time.Second
 			params := &wire.TransportParameters{
+				PartialReliability:              true,
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       destConnID,
 				MaxIdleTimeout:                  18 * time.Second,
@@ -2775,6 +3476,7 @@ var _ = Describe("Client Connection", func() {
 		It("errors if the transport parameters contain a wrong initial_source_connection_id", func() {
 			conn.handshakeDestConnID = protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
 			params := &wire.TransportParameters{
+				PartialReliability:              true,
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad}),
 				StatelessResetToken:             &protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
@@ -2792,6 +3494,7 @@ var _ = Describe("Client Connection", func() {
 			rcid := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
 			conn.retrySrcConnID = &rcid
 			params := &wire.TransportParameters{
+				PartialReliability:              true,
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       destConnID,
 				StatelessResetToken:             &protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
@@ -2810,6 +3513,7 @@ var _ = Describe("Client Connection", func() {
 			rcid2 := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde})
 			conn.retrySrcConnID = &rcid
 			params := &wire.TransportParameters{
+				PartialReliability:              true,
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       destConnID,
 				RetrySourceConnectionID:         &rcid2,
@@ -2827,6 +3531,7 @@ var _ = Describe("Client Connection", func() {
 		It("errors if the transport parameters contain the retry_source_connection_id, if no Retry was performed", func() {
 			rcid := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde})
 			params := &wire.TransportParameters{
+				PartialReliability:              true,
 				OriginalDestinationConnectionID: destConnID,
 				InitialSourceConnectionID:       destConnID,
 				RetrySourceConnectionID:         &rcid,
@@ -2844,6 +3549,7 @@ var _ = Describe("Client Connection", func() {
 		It("errors if the transport parameters contain a wrong original_destination_connection_id", func() {
 			conn.origDestConnID = protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
 			params := &wire.TransportParameters{
+				PartialReliability:              true,
 				OriginalDestinationConnectionID: protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad}),
 				InitialSourceConnectionID:       conn.handshakeDestConnID,
 				StatelessResetToken:             &protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
@@ -0,0 +1,256 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// maxPartialMessages is the maximum number of messages a DatagramFragmenter reassembles at the same time.
+// When a fragment of a new message arrives, the oldest incomplete message is discarded.
+const maxPartialMessages = 32
+
+// A DatagramFragmenter sends and receives application messages that are larger than a single DATAGRAM frame.
+// Messages are split into fragments, each of them sent in its own DATAGRAM frame, and reassembled by the receiver.
+// Fragments are never retransmitted: if one of them is lost, the whole message is lost.
+//
+// Every fragment starts with a small header:
+//
+//	Message ID (i), Fragment Index (i), Fragment Count (i), PTDA (8), [PtdaC (i)]
+//
+// PTDA and PtdaC carry the PR policy of the message, like the PR_DATAGRAM frame.
+// PtdaC is only present if PTDA is non-zero.
+// If the policy uses PTDADeadline, the receiver discards incomplete messages once the deadline has passed,
+// counted from the arrival of the first fragment.
+//
+// Both endpoints need to use a DatagramFragmenter. It must not be combined with
+// Connection.SendMessage and Connection.ReceiveMessage on the same connection.
+type DatagramFragmenter struct {
+	conn           Connection
+	maxMessageSize int
+
+	sendMx        sync.Mutex
+	nextMessageID uint64
+
+	rcvMx    sync.Mutex
+	partials []*partialMessage // ordered by the arrival of the first fragment
+}
+
+type partialMessage struct {
+	id        uint64
+	fragments [][]byte
+	received  int
+	size      int
+	expiry    time.Time // zero if the message doesn't expire
+}
+
+type fragmentHeader struct {
+	messageID uint64
+	index     uint64
+	count     uint64
+	policy    PRPolicy
+}
+
+func (h *fragmentHeader) Append(b []byte) []byte {
+	b = quicvarint.Append(b, h.messageID)
+	b = quicvarint.Append(b, h.index)
+	b = quicvarint.Append(b, h.count)
+	b = append(b, h.policy.PTDA)
+	if h.policy.PTDA != 0 {
+		b = quicvarint.Append(b, h.policy.Value)
+	}
+	return b
+}
+
+func (h *fragmentHeader) Length() int {
+	l := int(quicvarint.Len(h.messageID) + quicvarint.Len(h.index) + quicvarint.Len(h.count) + 1)
+	if h.policy.PTDA != 0 {
+		l += int(quicvarint.Len(h.policy.Value))
+	}
+	return l
+}
+
+func parseFragmentHeader(r *bytes.Reader) (*fragmentHeader, error) {
+	h := &fragmentHeader{}
+	var err error
+	if h.messageID, err = quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+	if h.index, err = quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+	if h.count, err = quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+	if h.policy.PTDA, err = r.ReadByte(); err != nil {
+		return nil, err
+	}
+	if h.policy.PTDA != 0 {
+		if h.policy.Value, err = quicvarint.Read(r); err != nil {
+			return nil, err
+		}
+	}
+	if h.count == 0 || h.index >= h.count {
+		return nil, fmt.Errorf("invalid fragment %d of %d", h.index, h.count)
+	}
+	return h, nil
+}
+
+// NewDatagramFragmenter creates a new DatagramFragmenter.
+// Messages larger than maxMessageSize are neither sent nor reassembled.
+func NewDatagramFragmenter(conn Connection, maxMessageSize int) *DatagramFragmenter {
+	return &DatagramFragmenter{
+		conn:           conn,
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+// SendMessage sends a message, splitting it into multiple DATAGRAM frames if necessary.
+func (f *DatagramFragmenter) SendMessage(p []byte) error {
+	return f.SendMessageWithPolicy(p, PRPolicy{})
+}
+
+// SendMessageWithPolicy sends a message, splitting it into multiple DATAGRAM frames if necessary.
+// The policy is applied to every fragment, and transmitted to the receiver in the fragment header.
+func (f *DatagramFragmenter) SendMessageWithPolicy(p []byte, policy PRPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	if len(p) > f.maxMessageSize {
+		return fmt.Errorf("message too large: %d bytes (maximum %d bytes)", len(p), f.maxMessageSize)
+	}
+	state := f.conn.ConnectionState()
+	if !state.SupportsDatagrams {
+		return errors.New("datagram support disabled")
+	}
+
+	f.sendMx.Lock()
+	id := f.nextMessageID
+	f.nextMessageID++
+	f.sendMx.Unlock()
+
+	// The fragment index and count are at most len(p), use that to calculate the maximum header length.
+	hdr := &fragmentHeader{messageID: id, index: uint64(len(p)), count: uint64(len(p)), policy: policy}
+	fragmentSize := state.MaxDatagramSize - hdr.Length()
+	if fragmentSize <= 0 {
+		return errors.New("datagram too small for message fragments")
+	}
+	hdr.count = uint64((len(p) + fragmentSize - 1) / fragmentSize)
+	if hdr.count == 0 {
+		hdr.count = 1
+	}
+	b := make([]byte, 0, state.MaxDatagramSize)
+	for hdr.index = 0; hdr.index < hdr.count; hdr.index++ {
+		start := int(hdr.index) * fragmentSize
+		end := start + fragmentSize
+		if end > len(p) {
+			end = len(p)
+		}
+		b = hdr.Append(b[:0])
+		b = append(b, p[start:end]...)
+		if err := f.conn.SendMessageWithPolicy(b, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReceiveMessage receives the next message that was completely reassembled.
+// Fragments that can't be parsed are ignored.
+func (f *DatagramFragmenter) ReceiveMessage() ([]byte, error) {
+	for {
+		data, err := f.conn.ReceiveMessage()
+		if err != nil {
+			return nil, err
+		}
+		if msg := f.handleFragment(data, time.Now()); msg != nil {
+			return msg, nil
+		}
+	}
+}
+
+func (f *DatagramFragmenter) handleFragment(data []byte, now time.Time) []byte {
+	r := bytes.NewReader(data)
+	hdr, err := parseFragmentHeader(r)
+	if err != nil {
+		return nil
+	}
+	payload := data[len(data)-r.Len():]
+	if hdr.count == 1 {
+		if len(payload) > f.maxMessageSize {
+			return nil
+		}
+		return payload
+	}
+	if hdr.count > uint64(f.maxMessageSize) {
+		return nil
+	}
+
+	f.rcvMx.Lock()
+	defer f.rcvMx.Unlock()
+
+	f.discardExpired(now)
+	msg := f.getPartial(hdr, now)
+	if msg.fragments[hdr.index] != nil {
+		return nil // duplicate
+	}
+	if msg.size+len(payload) > f.maxMessageSize {
+		f.removePartial(msg.id)
+		return nil
+	}
+	msg.fragments[hdr.index] = payload
+	msg.received++
+	msg.size += len(payload)
+	if msg.received < len(msg.fragments) {
+		return nil
+	}
+	f.removePartial(msg.id)
+	return bytes.Join(msg.fragments, nil)
+}
+
+func (f *DatagramFragmenter) getPartial(hdr *fragmentHeader, now time.Time) *partialMessage {
+	for _, msg := range f.partials {
+		if msg.id == hdr.messageID && len(msg.fragments) == int(hdr.count) {
+			return msg
+		}
+	}
+	if len(f.partials) >= maxPartialMessages {
+		f.partials[0] = nil
+		f.partials = f.partials[1:]
+	}
+	msg := &partialMessage{
+		id:        hdr.messageID,
+		fragments: make([][]byte, hdr.count),
+	}
+	if hdr.policy.PTDA == PTDADeadline {
+		msg.expiry = now.Add(time.Duration(hdr.policy.Value) * time.Millisecond)
+	}
+	f.partials = append(f.partials, msg)
+	return msg
+}
+
+func (f *DatagramFragmenter) removePartial(id uint64) {
+	for i, msg := range f.partials {
+		if msg.id == id {
+			f.partials = append(f.partials[:i], f.partials[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *DatagramFragmenter) discardExpired(now time.Time) {
+	partials := f.partials[:0]
+	for _, msg := range f.partials {
+		if msg.expiry.IsZero() || now.Before(msg.expiry) {
+			partials = append(partials, msg)
+		}
+	}
+	for i := len(partials); i < len(f.partials); i++ {
+		f.partials[i] = nil
+	}
+	f.partials = partials
+}
@@ -0,0 +1,143 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Datagram Fragmenter", func() {
+	const maxDatagramSize = 100
+
+	var (
+		conn       *MockQuicConn
+		fragmenter *DatagramFragmenter
+		sent       chan []byte
+	)
+
+	BeforeEach(func() {
+		conn = NewMockQuicConn(mockCtrl)
+		conn.EXPECT().ConnectionState().Return(ConnectionState{SupportsDatagrams: true, MaxDatagramSize: maxDatagramSize}).AnyTimes()
+		sent = make(chan []byte, 100)
+		conn.EXPECT().SendMessageWithPolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(b []byte, _ PRPolicy) error {
+			Expect(len(b)).To(BeNumerically("<=", maxDatagramSize))
+			sent <- append([]byte{}, b...)
+			return nil
+		}).AnyTimes()
+		conn.EXPECT().ReceiveMessage().DoAndReturn(func() ([]byte, error) {
+			select {
+			case b := <-sent:
+				return b, nil
+			default:
+				return nil, errors.New("no more datagrams")
+			}
+		}).AnyTimes()
+		fragmenter = NewDatagramFragmenter(conn, 10000)
+	})
+
+	It("sends small messages in a single datagram", func() {
+		Expect(fragmenter.SendMessage([]byte("foobar"))).To(Succeed())
+		Expect(sent).To(HaveLen(1))
+		msg, err := fragmenter.ReceiveMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal([]byte("foobar")))
+	})
+
+	It("sends empty messages", func() {
+		Expect(fragmenter.SendMessage(nil)).To(Succeed())
+		Expect(sent).To(HaveLen(1))
+		msg, err := fragmenter.ReceiveMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(BeEmpty())
+	})
+
+	It("fragments and reassembles large messages", func() {
+		data := bytes.Repeat([]byte("foobar"), 500)
+		Expect(fragmenter.SendMessageWithPolicy(data, PRPolicy{PTDA: PTDAPriority, Value: 3})).To(Succeed())
+		Expect(len(sent)).To(BeNumerically(">", len(data)/maxDatagramSize))
+		msg, err := fragmenter.ReceiveMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal(data))
+	})
+
+	It("reassembles fragments received out of order", func() {
+		data := bytes.Repeat([]byte("foobar"), 50)
+		Expect(fragmenter.SendMessage(data)).To(Succeed())
+		var fragments [][]byte
+		for len(sent) > 0 {
+			fragments = append(fragments, <-sent)
+		}
+		Expect(len(fragments)).To(BeNumerically(">", 2))
+		for i := len(fragments) - 1; i > 0; i-- {
+			Expect(fragmenter.handleFragment(fragments[i], time.Now())).To(BeNil())
+		}
+		// duplicates are ignored
+		Expect(fragmenter.handleFragment(fragments[1], time.Now())).To(BeNil())
+		Expect(fragmenter.handleFragment(fragments[0], time.Now())).To(Equal(data))
+	})
+
+	It("doesn't deliver messages with missing fragments", func() {
+		Expect(fragmenter.SendMessage(bytes.Repeat([]byte("foo"), 100))).To(Succeed())
+		<-sent // drop the first fragment
+		Expect(fragmenter.SendMessage([]byte("bar"))).To(Succeed())
+		msg, err := fragmenter.ReceiveMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal([]byte("bar")))
+		Expect(fragmenter.partials).To(HaveLen(1))
+	})
+
+	It("discards incomplete messages after the deadline", func() {
+		Expect(fragmenter.SendMessageWithPolicy(bytes.Repeat([]byte("foo"), 100), PRPolicy{PTDA: PTDADeadline, Value: 100})).To(Succeed())
+		var fragments [][]byte
+		for len(sent) > 0 {
+			fragments = append(fragments, <-sent)
+		}
+		now := time.Now()
+		for _, f := range fragments[:len(fragments)-1] {
+			Expect(fragmenter.handleFragment(f, now)).To(BeNil())
+		}
+		Expect(fragmenter.partials).To(HaveLen(1))
+		fragmenter.discardExpired(now.Add(99 * time.Millisecond))
+		Expect(fragmenter.partials).To(HaveLen(1))
+		Expect(fragmenter.handleFragment(fragments[len(fragments)-1], now.Add(100*time.Millisecond))).To(BeNil())
+		Expect(fragmenter.partials).To(HaveLen(1))
+		Expect(fragmenter.partials[0].received).To(Equal(1))
+	})
+
+	It("limits the number of messages that are reassembled at the same time", func() {
+		for i := 0; i < maxPartialMessages+1; i++ {
+			Expect(fragmenter.SendMessage(bytes.Repeat([]byte("foo"), 50))).To(Succeed())
+			<-sent // drop the first fragment
+			for len(sent) > 0 {
+				Expect(fragmenter.handleFragment(<-sent, time.Now())).To(BeNil())
+			}
+		}
+		Expect(fragmenter.partials).To(HaveLen(maxPartialMessages))
+		Expect(fragmenter.partials[0].id).To(BeEquivalentTo(1))
+	})
+
+	It("refuses to send messages that are too large", func() {
+		fragmenter = NewDatagramFragmenter(conn, 100)
+		Expect(fragmenter.SendMessage(make([]byte, 101))).To(MatchError("message too large: 101 bytes (maximum 100 bytes)"))
+		Expect(sent).To(BeEmpty())
+	})
+
+	It("doesn't reassemble messages that are too large", func() {
+		Expect(fragmenter.SendMessage(make([]byte, 1000))).To(Succeed())
+		fragmenter = NewDatagramFragmenter(conn, 500)
+		_, err := fragmenter.ReceiveMessage()
+		Expect(err).To(MatchError("no more datagrams"))
+	})
+
+	It("returns an error when datagrams are not supported", func() {
+		conn = NewMockQuicConn(mockCtrl)
+		conn.EXPECT().ConnectionState().Return(ConnectionState{})
+		fragmenter = NewDatagramFragmenter(conn, 100)
+		Expect(fragmenter.SendMessage([]byte("foobar"))).To(MatchError("datagram support disabled"))
+	})
+})
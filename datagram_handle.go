@@ -0,0 +1,77 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// A DatagramState is the delivery state of a DATAGRAM sent with Connection.SendMessageTracked.
+type DatagramState uint8
+
+const (
+	// DatagramPending means that the DATAGRAM is queued or in flight.
+	DatagramPending DatagramState = iota
+	// DatagramAcked means that the packet containing the DATAGRAM was acknowledged by the peer.
+	DatagramAcked
+	// DatagramLost means that the packet containing the DATAGRAM was declared lost,
+	// or that the DATAGRAM was dropped from the send queue to make room for another one.
+	DatagramLost
+	// DatagramExpired means that the deadline of the DATAGRAM passed before it could be sent.
+	DatagramExpired
+)
+
+func (s DatagramState) String() string {
+	switch s {
+	case DatagramPending:
+		return "pending"
+	case DatagramAcked:
+		return "acked"
+	case DatagramLost:
+		return "lost"
+	case DatagramExpired:
+		return "expired"
+	default:
+		return "unknown datagram state"
+	}
+}
+
+// A DatagramHandle reports what happened to a DATAGRAM sent with Connection.SendMessageTracked.
+// DATAGRAM frames are never retransmitted. The handle allows applications to implement
+// their own retransmission or FEC scheme for the messages they care about.
+type DatagramHandle struct {
+	mx    sync.Mutex
+	state DatagramState
+	done  chan struct{}
+}
+
+func newDatagramHandle() *DatagramHandle {
+	return &DatagramHandle{done: make(chan struct{})}
+}
+
+// Done returns a channel that is closed once the DATAGRAM was acknowledged, lost or expired.
+// If the connection is closed while the DATAGRAM is in flight, the channel is never closed,
+// use Connection.Context to detect that case.
+func (h *DatagramHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// State returns the delivery state of the DATAGRAM.
+func (h *DatagramHandle) State() DatagramState {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	return h.state
+}
+
+func (h *DatagramHandle) resolve(state DatagramState) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if h.state != DatagramPending {
+		return
+	}
+	h.state = state
+	close(h.done)
+}
+
+func (h *DatagramHandle) onAcked(wire.Frame) { h.resolve(DatagramAcked) }
+func (h *DatagramHandle) onLost(wire.Frame)  { h.resolve(DatagramLost) }
@@ -1,83 +1,240 @@
 package quic
 
 import (
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
+// A DatagramOverflowPolicy determines what happens when a DATAGRAM is sent while the send queue is full.
+type DatagramOverflowPolicy uint8
+
+const (
+	// DatagramOverflowBlock blocks SendMessage until there's room in the send queue.
+	// SendMessage only returns once the DATAGRAM was packed into a packet.
+	DatagramOverflowBlock DatagramOverflowPolicy = iota
+	// DatagramOverflowDropOldest drops the oldest queued DATAGRAM to make room for the new one.
+	DatagramOverflowDropOldest
+	// DatagramOverflowDropNewest drops the new DATAGRAM, SendMessage returns ErrDatagramDropped.
+	DatagramOverflowDropNewest
+	// DatagramOverflowDropLowestPriority drops the DATAGRAM with the lowest priority,
+	// using the Value of PRPolicies with PTDAPriority, see Connection.SendMessageWithPolicy.
+	// If that's the new DATAGRAM, SendMessage returns ErrDatagramDropped.
+	DatagramOverflowDropLowestPriority
+)
+
+// ErrDatagramDropped is returned by SendMessage when the DATAGRAM was dropped because the send queue is full.
+var ErrDatagramDropped = errors.New("DATAGRAM dropped: send queue full")
+
+type queuedDatagram struct {
+	frame    *wire.DatagramFrame
+	priority uint64
+	expiry   time.Time       // zero if the frame doesn't expire
+	handle   *DatagramHandle // nil if the frame is not tracked
+	dequeued chan struct{}   // closed when the frame is dequeued, only used with DatagramOverflowBlock
+}
+
+// drop removes a frame from the queue without sending it.
+func (d *queuedDatagram) drop(state DatagramState) {
+	if d.handle != nil {
+		d.handle.resolve(state)
+	}
+	if d.dequeued != nil {
+		close(d.dequeued)
+	}
+}
+
 type datagramQueue struct {
-	mx            sync.Mutex
-	nextFrameSize protocol.ByteCount
+	mx             sync.Mutex
+	sendQueue      []*queuedDatagram
+	sendQueueLen   int
+	overflowPolicy DatagramOverflowPolicy
+	queueSpace     chan struct{} // signaled when a frame is dequeued
 
-	sendQueue chan *wire.DatagramFrame
-	rcvQueue  chan []byte
+	rcvQueue chan []byte
 
 	closeErr error
 	closed   chan struct{}
 
 	hasData func()
-
-	dequeued chan struct{}
+	// dropped is called when a DATAGRAM is dropped, i.e. a queued DATAGRAM is not sent, or a received DATAGRAM is not delivered.
+	dropped func(length int, expired, received bool)
 
 	logger  utils.Logger
 	version protocol.VersionNumber
 }
 
-func newDatagramQueue(hasData func(), logger utils.Logger, v protocol.VersionNumber) *datagramQueue {
+func newDatagramQueue(
+	hasData func(),
+	dropped func(length int, expired, received bool),
+	sendQueueLen int,
+	overflowPolicy DatagramOverflowPolicy,
+	logger utils.Logger,
+	v protocol.VersionNumber,
+) *datagramQueue {
 	return &datagramQueue{
-		hasData:       hasData,
-		sendQueue:     make(chan *wire.DatagramFrame, 1),
-		nextFrameSize: protocol.InvalidByteCount,
-		rcvQueue:      make(chan []byte, protocol.DatagramRcvQueueLen),
-		dequeued:      make(chan struct{}),
-		closed:        make(chan struct{}),
-		logger:        logger,
-		version:       v,
+		hasData:        hasData,
+		dropped:        dropped,
+		sendQueueLen:   sendQueueLen,
+		overflowPolicy: overflowPolicy,
+		queueSpace:     make(chan struct{}, 1),
+		rcvQueue:       make(chan []byte, protocol.DatagramRcvQueueLen),
+		closed:         make(chan struct{}),
+		logger:         logger,
+		version:        v,
 	}
 }
 
-// AddAndWait queues a new DATAGRAM frame for sending.
-// It blocks until the frame has been dequeued.
-func (h *datagramQueue) AddAndWait(f *wire.DatagramFrame) error {
-	select {
-	case h.sendQueue <- f:
-		h.mx.Lock()
-		h.nextFrameSize = f.Length(h.version)
-		h.mx.Unlock()
-		h.hasData()
-	case <-h.closed:
-		return h.closeErr
+// Add queues a new DATAGRAM frame for sending.
+// What happens if the send queue is full depends on the overflow policy.
+// With DatagramOverflowBlock, it blocks until the frame has been dequeued.
+// The policy determines the priority of the frame, and when it expires.
+// If a handle is passed, it is resolved when the frame is dropped from the queue.
+func (h *datagramQueue) Add(f *wire.DatagramFrame, policy PRPolicy, handle *DatagramHandle) error {
+	d := &queuedDatagram{frame: f, handle: handle}
+	switch policy.PTDA {
+	case PTDAPriority:
+		d.priority = policy.Value
+	case PTDADeadline:
+		d.expiry = time.Now().Add(time.Duration(policy.Value) * time.Millisecond)
+	}
+	if h.overflowPolicy == DatagramOverflowBlock {
+		d.dequeued = make(chan struct{})
+	}
+	for {
+		queued, err := h.tryAdd(d)
+		if err != nil {
+			return err
+		}
+		if queued {
+			break
+		}
+		// the queue is full, and the policy is DatagramOverflowBlock
+		select {
+		case <-h.queueSpace:
+		case <-h.closed:
+			return h.closeErr
+		}
 	}
+	h.hasData()
 
+	if d.dequeued == nil {
+		return nil
+	}
 	select {
-	case <-h.dequeued:
+	case <-d.dequeued:
 		return nil
 	case <-h.closed:
 		return h.closeErr
 	}
 }
 
-// Get dequeues a DATAGRAM frame for sending.
-func (h *datagramQueue) Get() *wire.DatagramFrame {
+func (h *datagramQueue) tryAdd(d *queuedDatagram) (bool /* queued */, error) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
 	select {
-	case f := <-h.sendQueue:
-		h.mx.Lock()
-		h.nextFrameSize = protocol.InvalidByteCount
-		h.mx.Unlock()
-		h.dequeued <- struct{}{}
-		return f
+	case <-h.closed:
+		return false, h.closeErr
 	default:
-		return nil
+	}
+	if len(h.sendQueue) < h.sendQueueLen {
+		h.sendQueue = append(h.sendQueue, d)
+		if len(h.sendQueue) < h.sendQueueLen {
+			// there's still room, wake up the next blocked sender (if any)
+			select {
+			case h.queueSpace <- struct{}{}:
+			default:
+			}
+		}
+		return true, nil
+	}
+	switch h.overflowPolicy {
+	case DatagramOverflowDropOldest:
+		h.logger.Debugf("Discarding queued DATAGRAM frame (%d bytes payload)", len(h.sendQueue[0].frame.Data))
+		h.sendQueue[0].drop(DatagramLost)
+		h.dropped(len(h.sendQueue[0].frame.Data), false, false)
+		h.sendQueue = append(h.sendQueue[1:], d)
+		return true, nil
+	case DatagramOverflowDropNewest:
+		h.dropped(len(d.frame.Data), false, false)
+		return false, ErrDatagramDropped
+	case DatagramOverflowDropLowestPriority:
+		lowest := 0
+		for i, q := range h.sendQueue {
+			if q.priority < h.sendQueue[lowest].priority {
+				lowest = i
+			}
+		}
+		if d.priority <= h.sendQueue[lowest].priority {
+			h.dropped(len(d.frame.Data), false, false)
+			return false, ErrDatagramDropped
+		}
+		h.logger.Debugf("Discarding queued DATAGRAM frame (%d bytes payload)", len(h.sendQueue[lowest].frame.Data))
+		h.sendQueue[lowest].drop(DatagramLost)
+		h.dropped(len(h.sendQueue[lowest].frame.Data), false, false)
+		h.sendQueue = append(h.sendQueue[:lowest], h.sendQueue[lowest+1:]...)
+		h.sendQueue = append(h.sendQueue, d)
+		return true, nil
+	default:
+		return false, nil
 	}
 }
 
-func (h *datagramQueue) NextFrameSize() protocol.ByteCount {
+// Get dequeues a DATAGRAM frame for sending, if the next frame is at most maxSize bytes long.
+// It returns the handle passed to Add, which needs to be resolved when the frame is acknowledged or lost.
+// Expired frames are dropped from the queue.
+// Checking the size and dequeueing the frame is a single operation,
+// since Add might drop the next frame (depending on the overflow policy) at any time.
+func (h *datagramQueue) Get(maxSize protocol.ByteCount) (*wire.DatagramFrame, *DatagramHandle) {
 	h.mx.Lock()
-	defer h.mx.Unlock()
-	return h.nextFrameSize
+	h.dropExpired(time.Now())
+	if len(h.sendQueue) == 0 || h.sendQueue[0].frame.Length(h.version) > maxSize {
+		h.mx.Unlock()
+		return nil, nil
+	}
+	d := h.sendQueue[0]
+	h.sendQueue[0] = nil
+	h.sendQueue = h.sendQueue[1:]
+	h.mx.Unlock()
+
+	if d.dequeued != nil {
+		close(d.dequeued)
+	}
+	select {
+	case h.queueSpace <- struct{}{}:
+	default:
+	}
+	return d.frame, d.handle
+}
+
+func (h *datagramQueue) dropExpired(now time.Time) {
+	var dropped bool
+	queue := h.sendQueue[:0]
+	for _, d := range h.sendQueue {
+		if d.expiry.IsZero() || now.Before(d.expiry) {
+			queue = append(queue, d)
+			continue
+		}
+		h.logger.Debugf("Discarding expired DATAGRAM frame (%d bytes payload)", len(d.frame.Data))
+		d.drop(DatagramExpired)
+		h.dropped(len(d.frame.Data), true, false)
+		dropped = true
+	}
+	for i := len(queue); i < len(h.sendQueue); i++ {
+		h.sendQueue[i] = nil
+	}
+	h.sendQueue = queue
+	if dropped {
+		select {
+		case h.queueSpace <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // HandleDatagramFrame handles a received DATAGRAM frame.
@@ -88,6 +245,7 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 	case h.rcvQueue <- data:
 	default:
 		h.logger.Debugf("Discarding DATAGRAM frame (%d bytes payload)", len(f.Data))
+		h.dropped(len(f.Data), false, true)
 	}
 }
 
@@ -102,6 +260,15 @@ func (h *datagramQueue) Receive() ([]byte, error) {
 }
 
 func (h *datagramQueue) CloseWithError(e error) {
+	h.mx.Lock()
+	for _, d := range h.sendQueue {
+		if d.handle != nil {
+			d.handle.resolve(DatagramLost)
+		}
+	}
+	h.sendQueue = nil
+	h.mx.Unlock()
+
 	h.closeErr = e
 	close(h.closed)
 }
@@ -2,6 +2,8 @@ package quic
 
 import (
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -14,18 +16,25 @@ import (
 var _ = Describe("Datagram Queue", func() {
 	var queue *datagramQueue
 	var queued chan struct{}
+	var dropped []DatagramDroppedEvent
+
+	newQueue := func(l int, p DatagramOverflowPolicy) *datagramQueue {
+		return newDatagramQueue(func() {
+			queued <- struct{}{}
+		}, func(length int, expired, received bool) {
+			dropped = append(dropped, DatagramDroppedEvent{Length: length, Expired: expired, Received: received})
+		}, l, p, utils.DefaultLogger, protocol.Version1)
+	}
 
 	BeforeEach(func() {
 		queued = make(chan struct{}, 100)
-		queue = newDatagramQueue(func() {
-			queued <- struct{}{}
-		}, utils.DefaultLogger, protocol.Version1)
+		dropped = nil
+		queue = newQueue(1, DatagramOverflowBlock)
 	})
 
 	Context("sending", func() {
 		It("returns nil when there's no datagram to send", func() {
-			Expect(queue.NextFrameSize()).To(Equal(protocol.InvalidByteCount))
-			Expect(queue.Get()).To(BeNil())
+			Expect(queue.Get(protocol.MaxByteCount)).To(BeNil())
 		})
 
 		It("queues a datagram", func() {
@@ -34,26 +43,52 @@ var _ = Describe("Datagram Queue", func() {
 			go func() {
 				defer GinkgoRecover()
 				defer close(done)
-				Expect(queue.AddAndWait(frame)).To(Succeed())
+				Expect(queue.Add(frame, PRPolicy{}, nil)).To(Succeed())
 			}()
 
 			Eventually(queued).Should(HaveLen(1))
 			Consistently(done).ShouldNot(BeClosed())
-			l := queue.NextFrameSize()
-			f := queue.Get()
-			Expect(l).To(Equal(f.Length(protocol.Version1)))
-			Expect(queue.NextFrameSize()).To(Equal(protocol.InvalidByteCount))
+			size := (&wire.DatagramFrame{Data: []byte("foobar")}).Length(protocol.Version1)
+			// the frame doesn't fit
+			Expect(queue.Get(size - 1)).To(BeNil())
+			Consistently(done).ShouldNot(BeClosed())
+			f, _ := queue.Get(size)
 			Expect(f).ToNot(BeNil())
 			Expect(f.Data).To(Equal([]byte("foobar")))
 			Eventually(done).Should(BeClosed())
-			Expect(queue.Get()).To(BeNil())
+			Expect(queue.Get(protocol.MaxByteCount)).To(BeNil())
+		})
+
+		It("doesn't dequeue a frame that doesn't fit if the queue overflows concurrently", func() {
+			queue = newQueue(1, DatagramOverflowDropOldest)
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{}, nil)).To(Succeed())
+			maxSize := (&wire.DatagramFrame{Data: []byte("foo")}).Length(protocol.Version1)
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					Expect(queue.Add(&wire.DatagramFrame{Data: make([]byte, 1+i%2*100)}, PRPolicy{}, nil)).To(Succeed())
+				}
+			}()
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					if f, _ := queue.Get(maxSize); f != nil {
+						Expect(f.Length(protocol.Version1)).To(BeNumerically("<=", maxSize))
+					}
+				}
+			}()
+			wg.Wait()
 		})
 
 		It("closes", func() {
 			errChan := make(chan error, 1)
 			go func() {
 				defer GinkgoRecover()
-				errChan <- queue.AddAndWait(&wire.DatagramFrame{Data: []byte("foobar")})
+				errChan <- queue.Add(&wire.DatagramFrame{Data: []byte("foobar")}, PRPolicy{}, nil)
 			}()
 
 			Consistently(errChan).ShouldNot(Receive())
@@ -62,6 +97,149 @@ var _ = Describe("Datagram Queue", func() {
 		})
 	})
 
+	Context("overflowing", func() {
+		getAll := func() []string {
+			var data []string
+			for {
+				f, _ := queue.Get(protocol.MaxByteCount)
+				if f == nil {
+					return data
+				}
+				data = append(data, string(f.Data))
+			}
+		}
+
+		It("blocks until there's room in the queue", func() {
+			queue = newQueue(2, DatagramOverflowBlock)
+			done := make(chan struct{}, 3)
+			for _, d := range []string{"foo", "bar", "baz"} {
+				go func(d string) {
+					defer GinkgoRecover()
+					Expect(queue.Add(&wire.DatagramFrame{Data: []byte(d)}, PRPolicy{}, nil)).To(Succeed())
+					done <- struct{}{}
+				}(d)
+			}
+			Eventually(queued).Should(HaveLen(2))
+			Consistently(queued).Should(HaveLen(2))
+			Expect(queue.Get(protocol.MaxByteCount)).ToNot(BeNil())
+			Eventually(done).Should(Receive())
+			Eventually(queued).Should(HaveLen(3))
+			Expect(queue.Get(protocol.MaxByteCount)).ToNot(BeNil())
+			Expect(queue.Get(protocol.MaxByteCount)).ToNot(BeNil())
+			// receive from the channel (instead of checking its length), such that the race detector sees that the Add calls returned
+			Eventually(done).Should(Receive())
+			Eventually(done).Should(Receive())
+			Expect(queue.Get(protocol.MaxByteCount)).To(BeNil())
+		})
+
+		It("drops the oldest datagram", func() {
+			queue = newQueue(2, DatagramOverflowDropOldest)
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{}, nil)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("bar")}, PRPolicy{}, nil)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("baz")}, PRPolicy{}, nil)).To(Succeed())
+			Expect(queued).To(HaveLen(3))
+			Expect(getAll()).To(Equal([]string{"bar", "baz"}))
+			Expect(dropped).To(Equal([]DatagramDroppedEvent{{Length: 3}}))
+		})
+
+		It("drops the newest datagram", func() {
+			queue = newQueue(2, DatagramOverflowDropNewest)
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{}, nil)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("bar")}, PRPolicy{}, nil)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("baz")}, PRPolicy{}, nil)).To(MatchError(ErrDatagramDropped))
+			Expect(queued).To(HaveLen(2))
+			Expect(getAll()).To(Equal([]string{"foo", "bar"}))
+			Expect(dropped).To(Equal([]DatagramDroppedEvent{{Length: 3}}))
+		})
+
+		It("drops the datagram with the lowest priority", func() {
+			queue = newQueue(2, DatagramOverflowDropLowestPriority)
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{PTDA: PTDAPriority, Value: 5}, nil)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("bar")}, PRPolicy{PTDA: PTDAPriority, Value: 3}, nil)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("baz")}, PRPolicy{PTDA: PTDAPriority, Value: 4}, nil)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("qux")}, PRPolicy{PTDA: PTDAPriority, Value: 4}, nil)).To(MatchError(ErrDatagramDropped))
+			Expect(getAll()).To(Equal([]string{"foo", "baz"}))
+			Expect(dropped).To(HaveLen(2))
+		})
+
+		It("unblocks senders waiting for room when closed", func() {
+			errChan := make(chan error, 2)
+			for i := 0; i < 2; i++ {
+				go func() {
+					defer GinkgoRecover()
+					errChan <- queue.Add(&wire.DatagramFrame{Data: []byte("foobar")}, PRPolicy{}, nil)
+				}()
+			}
+			Eventually(queued).Should(HaveLen(1))
+			queue.CloseWithError(errors.New("test error"))
+			Eventually(errChan).Should(Receive(MatchError("test error")))
+			Eventually(errChan).Should(Receive(MatchError("test error")))
+		})
+	})
+
+	Context("tracking", func() {
+		It("returns the handle with the frame", func() {
+			queue = newQueue(1, DatagramOverflowDropNewest)
+			handle := newDatagramHandle()
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{}, handle)).To(Succeed())
+			f, h := queue.Get(protocol.MaxByteCount)
+			Expect(f.Data).To(Equal([]byte("foo")))
+			Expect(h).To(Equal(handle))
+			Expect(handle.State()).To(Equal(DatagramPending))
+		})
+
+		It("reports frames dropped to make room for new frames as lost", func() {
+			queue = newQueue(1, DatagramOverflowDropOldest)
+			handle := newDatagramHandle()
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{}, handle)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("bar")}, PRPolicy{}, nil)).To(Succeed())
+			Expect(handle.Done()).To(BeClosed())
+			Expect(handle.State()).To(Equal(DatagramLost))
+		})
+
+		It("drops expired frames", func() {
+			queue = newQueue(2, DatagramOverflowDropNewest)
+			handle := newDatagramHandle()
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{PTDA: PTDADeadline, Value: 10}, handle)).To(Succeed())
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("bar")}, PRPolicy{}, nil)).To(Succeed())
+			time.Sleep(15 * time.Millisecond)
+			f, _ := queue.Get(protocol.MaxByteCount)
+			Expect(f.Data).To(Equal([]byte("bar")))
+			Expect(handle.Done()).To(BeClosed())
+			Expect(handle.State()).To(Equal(DatagramExpired))
+			Expect(dropped).To(Equal([]DatagramDroppedEvent{{Length: 3, Expired: true}}))
+		})
+
+		It("unblocks senders when the frame expires", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{PTDA: PTDADeadline, Value: 10}, nil)).To(Succeed())
+			}()
+			Eventually(queued).Should(HaveLen(1))
+			Consistently(done, 20*time.Millisecond).ShouldNot(BeClosed())
+			Expect(queue.Get(protocol.MaxByteCount)).To(BeNil())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("reports queued frames as lost when closed", func() {
+			queue = newQueue(1, DatagramOverflowDropNewest)
+			handle := newDatagramHandle()
+			Expect(queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, PRPolicy{}, handle)).To(Succeed())
+			queue.CloseWithError(errors.New("test error"))
+			Expect(handle.State()).To(Equal(DatagramLost))
+		})
+
+		It("only resolves handles once", func() {
+			handle := newDatagramHandle()
+			handle.onAcked(nil)
+			handle.onLost(nil)
+			Expect(handle.Done()).To(BeClosed())
+			Expect(handle.State()).To(Equal(DatagramAcked))
+		})
+	})
+
 	Context("receiving", func() {
 		It("receives DATAGRAM frames", func() {
 			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
@@ -74,6 +252,15 @@ var _ = Describe("Datagram Queue", func() {
 			Expect(data).To(Equal([]byte("bar")))
 		})
 
+		It("drops DATAGRAM frames when the receive queue is full", func() {
+			for i := 0; i < protocol.DatagramRcvQueueLen; i++ {
+				queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			}
+			Expect(dropped).To(BeEmpty())
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			Expect(dropped).To(Equal([]DatagramDroppedEvent{{Length: 6, Received: true}}))
+		})
+
 		It("blocks until a frame is received", func() {
 			c := make(chan []byte, 1)
 			go func() {
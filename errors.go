@@ -1,7 +1,10 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"reflect"
 
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 )
@@ -56,3 +59,103 @@ func (e *StreamError) Is(target error) bool {
 func (e *StreamError) Error() string {
 	return fmt.Sprintf("stream %d canceled with error code %d", e.StreamID, e.ErrorCode)
 }
+
+// ErrIdleStreamTimeout is returned by Write if the stream was canceled because no data was written for too long.
+// See PRConfig.IdleStreamTimeout for details.
+var ErrIdleStreamTimeout = errors.New("idle stream timeout")
+
+// ErrTooManyPRStreams is returned by Write if a PR stream limit was reached and PRConfig.RejectExcessPRStreams is set.
+// See PRConfig.MaxPRStreams for details.
+var ErrTooManyPRStreams = errors.New("too many PR streams")
+
+// ErrPRDisabled is returned by WriteWithPolicy when writing data with a partially reliable policy
+// to a stream that only sends data reliably, see SendStream.DisablePR.
+var ErrPRDisabled = errors.New("partial reliability disabled for this stream")
+
+// ErrWouldBlock is returned by TryRead and TryWrite if the stream isn't ready to read or write data.
+var ErrWouldBlock = errors.New("operation would block")
+
+// A DataSkippedError is returned by Read instead of reading zeros for data that wasn't delivered,
+// if enabled using ReceiveStream.SetReadSkippedAsError.
+// Offset and Length give the range of the data, counted from the beginning of the stream.
+// The data is consumed: the next Read continues after the range.
+type DataSkippedError struct {
+	StreamID StreamID
+	Offset   uint64
+	Length   uint64
+}
+
+func (e *DataSkippedError) Error() string {
+	return fmt.Sprintf("stream %d: skipped %d bytes at offset %d", e.StreamID, e.Length, e.Offset)
+}
+
+// A ConnectionClosedError is returned from Stream.Read and Stream.Write when the connection was closed.
+// It says who closed the connection, and why.
+// The underlying connection error (e.g. a *TransportError, *ApplicationError, *IdleTimeoutError or *StatelessResetError)
+// can be retrieved using errors.As.
+// A stateless reset usually means that the peer restarted and lost the connection state,
+// whereas an idle timeout hints at a network failure.
+type ConnectionClosedError struct {
+	StreamID StreamID
+	// Remote is true if the connection was closed by the peer.
+	Remote bool
+	// IsApplicationError is true if the connection was closed with an application error code.
+	IsApplicationError bool
+	// ErrorCode is the transport or application error code, depending on IsApplicationError.
+	ErrorCode uint64
+	// ReasonPhrase is the reason phrase of the CONNECTION_CLOSE frame.
+	ReasonPhrase string
+	// Err is the error the connection was closed with.
+	Err error
+}
+
+var _ net.Error = &ConnectionClosedError{}
+
+func newConnectionClosedError(id StreamID, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := &ConnectionClosedError{StreamID: id, Err: err}
+	var (
+		appErr       *ApplicationError
+		transportErr *TransportError
+		resetErr     *StatelessResetError
+	)
+	switch {
+	case errors.As(err, &appErr):
+		e.Remote = appErr.Remote
+		e.IsApplicationError = true
+		e.ErrorCode = uint64(appErr.ErrorCode)
+		e.ReasonPhrase = appErr.ErrorMessage
+	case errors.As(err, &transportErr):
+		e.Remote = transportErr.Remote
+		e.ErrorCode = uint64(transportErr.ErrorCode)
+		e.ReasonPhrase = transportErr.ErrorMessage
+	case errors.As(err, &resetErr):
+		e.Remote = true
+	}
+	return e
+}
+
+func (e *ConnectionClosedError) Error() string {
+	return fmt.Sprintf("stream %d: %s", e.StreamID, e.Err)
+}
+
+func (e *ConnectionClosedError) Unwrap() error { return e.Err }
+
+// Is says if the connection was closed with an error equal to target.
+// This allows comparing against errors that don't carry any state, e.g. errors.Is(err, &IdleTimeoutError{}).
+func (e *ConnectionClosedError) Is(target error) bool {
+	return reflect.DeepEqual(e.Err, target)
+}
+
+// Timeout says if the connection was closed due to a timeout.
+func (e *ConnectionClosedError) Timeout() bool {
+	var nerr net.Error
+	return errors.As(e.Err, &nerr) && nerr.Timeout()
+}
+
+func (e *ConnectionClosedError) Temporary() bool {
+	var nerr net.Error
+	return errors.As(e.Err, &nerr) && nerr.Temporary()
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+)
+
+// The bitrates the video is encoded in, in kbit/s.
+var bitrates = []int{250, 500, 1000, 2000, 4000}
+
+const (
+	segmentDuration = 500 * time.Millisecond
+	// the player doesn't request the next segment while the buffer is full
+	maxBuffer = 2 * time.Second
+	// the fraction of the estimated bandwidth the player uses when choosing a bitrate
+	bandwidthSafetyFactor = 0.8
+)
+
+// The server reports the bandwidth estimate of the congestion controller of its connection (see quic.PRStats) in this trailer, in kbit/s.
+const bandwidthEstimateTrailer = "Bandwidth-Estimate"
+
+// We start an HTTP/3 server that serves a video in multiple bitrates, and play it with an ABR (adaptive bitrate) client,
+// through a link with a varying bandwidth.
+// The client paces its requests: it only requests the next segment when there's room in its buffer.
+// Every segment is requested with a deadline policy (see http3.PRPolicyHeader): the time until the segment is played.
+// Lost packets are only retransmitted before the deadline, after that the player would rather play the segment with holes than stall.
+// The server reports the bandwidth estimated by its congestion controller with every segment.
+// The client chooses the bitrate of every segment using this estimate and the throughput of the previous segments,
+// and reports the rebuffering and the deadline misses at the end.
+func main() {
+	segments := flag.Int("segments", 40, "number of segments of the video")
+	bandwidths := flag.String("bandwidth", "5000,1500,800,3000", "bandwidths of the link in kbit/s, each used for one phase")
+	phase := flag.Duration("phase", 5*time.Second, "duration of a phase")
+	rtt := flag.Duration("rtt", 40*time.Millisecond, "RTT of the link")
+	flag.Parse()
+
+	var rates []int
+	for _, s := range strings.Split(*bandwidths, ",") {
+		r, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || r <= 0 {
+			log.Fatalf("invalid bandwidth: %q", s)
+		}
+		rates = append(rates, r)
+	}
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer udpConn.Close()
+	server := &http3.Server{
+		Handler:              http.HandlerFunc(handleSegment),
+		TLSConfig:            http3.ConfigureTLSConfig(testdata.GetTLSConfig()),
+		QuicConfig:           quicConfig(),
+		EnablePRPolicyHeader: true,
+	}
+	defer server.Close()
+	go server.Serve(udpConn)
+
+	l := &link{rates: rates, phase: *phase, rtt: *rtt, start: time.Now()}
+	proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+		RemoteAddr:  udpConn.LocalAddr().String(),
+		DropPacket:  l.drop,
+		DelayPacket: l.delay,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer proxy.Close()
+
+	roundTripper := &http3.RoundTripper{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		QuicConfig:      quicConfig(),
+	}
+	defer roundTripper.Close()
+	p := &player{
+		client: &http.Client{Transport: roundTripper},
+		url:    fmt.Sprintf("https://localhost:%d/", proxy.LocalPort()),
+	}
+	if err := p.play(*segments); err != nil {
+		log.Fatal(err)
+	}
+	p.printStats()
+}
+
+func quicConfig() *quic.Config {
+	return &quic.Config{
+		// The requests and responses are reliable, unless a policy is requested using the PRPolicyHeader.
+		PR: quic.PRConfig{DefaultPolicy: &quic.PRPolicy{}},
+	}
+}
+
+// handleSegment serves the segments of the video: /<segment>?bitrate=<kbit/s>
+func handleSegment(w http.ResponseWriter, r *http.Request) {
+	segment, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil || segment < 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	bitrate, err := strconv.Atoi(r.URL.Query().Get("bitrate"))
+	if err != nil || bitrate <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	size := bitrate * 1000 / 8 * int(segmentDuration/time.Millisecond) / 1000
+	w.Header().Set("Content-Length", strconv.Itoa(size))
+	w.Write(make([]byte, size))
+	// The estimate is only available once the RTT was measured.
+	if conn, ok := w.(http3.Hijacker).StreamCreator().(interface{ PRStats() quic.PRStats }); ok {
+		if bw := conn.PRStats().BandwidthEstimate; bw > 0 {
+			w.Header().Set(http.TrailerPrefix+bandwidthEstimateTrailer, strconv.FormatUint(bw/1000, 10))
+		}
+	}
+}
+
+// A link emulates the bottleneck link between the server and the client.
+// Its bandwidth changes every phase. Packets that would be queued for too long are dropped.
+type link struct {
+	rates []int // kbit/s
+	phase time.Duration
+	rtt   time.Duration
+	start time.Time
+
+	mutex sync.Mutex
+	// the time the link will have sent all queued packets
+	free time.Time
+}
+
+const maxQueueDelay = 100 * time.Millisecond
+
+func (l *link) drop(dir quicproxy.Direction, b []byte) bool {
+	// Only packets from the server to the client are queued.
+	// Packets with a long header are never dropped, such that the handshake always completes quickly.
+	if dir != quicproxy.DirectionOutgoing || len(b) == 0 || b[0]&0x80 > 0 {
+		return false
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return time.Until(l.free) > maxQueueDelay
+}
+
+func (l *link) delay(dir quicproxy.Direction, b []byte) time.Duration {
+	if dir != quicproxy.DirectionOutgoing {
+		return l.rtt / 2
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	if l.free.Before(now) {
+		l.free = now
+	}
+	rate := l.rates[len(l.rates)-1]
+	if i := int(now.Sub(l.start) / l.phase); i < len(l.rates) {
+		rate = l.rates[i]
+	}
+	l.free = l.free.Add(time.Duration(len(b)) * 8 * time.Millisecond / time.Duration(rate))
+	return l.free.Sub(now) + l.rtt/2
+}
+
+// A player plays the video, keeping track of how long it stalled.
+type player struct {
+	client *http.Client
+	url    string
+
+	// the estimated bandwidth, in kbit/s
+	bandwidth float64
+	// the time the player will have played all buffered segments
+	bufferedUntil time.Time
+	playing       bool
+
+	segments       int
+	bitrateSum     int
+	switches       int
+	lastBitrate    int
+	stalls         int
+	rebuffering    time.Duration
+	deadlineMisses int
+	damaged        int
+	skippedBytes   uint64
+}
+
+func (p *player) play(segments int) error {
+	for segment := 0; segment < segments; segment++ {
+		// Pace the requests: wait until there's room for the segment in the buffer.
+		if p.playing {
+			if d := time.Until(p.bufferedUntil) - (maxBuffer - segmentDuration); d > 0 {
+				time.Sleep(d)
+			}
+		}
+		if err := p.fetch(segment); err != nil {
+			return err
+		}
+	}
+	// play the rest of the buffer
+	time.Sleep(time.Until(p.bufferedUntil))
+	return nil
+}
+
+// chooseBitrate chooses the highest bitrate below the estimated bandwidth.
+func (p *player) chooseBitrate() int {
+	bitrate := bitrates[0]
+	for _, b := range bitrates {
+		if float64(b) <= bandwidthSafetyFactor*p.bandwidth {
+			bitrate = b
+		}
+	}
+	return bitrate
+}
+
+func (p *player) fetch(segment int) error {
+	bitrate := p.chooseBitrate()
+	// The segment is needed when all buffered segments are played.
+	// Before playback starts, we give the segment twice its duration.
+	deadline := 2 * segmentDuration
+	if p.playing {
+		deadline = time.Until(p.bufferedUntil)
+	}
+	if deadline < time.Millisecond {
+		deadline = time.Millisecond
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%d?bitrate=%d", p.url, segment, bitrate), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(http3.PRPolicyHeader, fmt.Sprintf("deadline=%s", deadline.Round(time.Millisecond)))
+	start := time.Now()
+	rsp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	// Skipped parts of the body are read as zeros.
+	n, err := io.Copy(io.Discard, rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("segment %d: status %d", segment, rsp.StatusCode)
+	}
+	now := time.Now()
+	took := now.Sub(start)
+
+	ranges, err := http3.ParsePRSkippedRanges(rsp.Trailer.Get(http3.PRSkippedRangesTrailer))
+	if err != nil {
+		return err
+	}
+	var skipped uint64
+	for _, r := range ranges {
+		skipped += r.End - r.Start
+	}
+
+	// Estimate the bandwidth using an exponentially weighted moving average of the throughput of the segments.
+	// The throughput underestimates the bandwidth, since the requests are paced and the connection is idle in between.
+	// The estimate of the server is derived from its congestion window, which includes the packets queued at the bottleneck,
+	// so it overestimates the bandwidth. If the server reported an estimate, we use the mean of both.
+	estimate := float64(n) * 8 / float64(took/time.Millisecond+1)
+	if s := rsp.Trailer.Get(bandwidthEstimateTrailer); s != "" {
+		bw, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid bandwidth estimate: %q", s)
+		}
+		estimate = (estimate + float64(bw)) / 2
+	}
+	if p.bandwidth == 0 {
+		p.bandwidth = estimate
+	} else {
+		p.bandwidth = 0.7*p.bandwidth + 0.3*estimate
+	}
+
+	if took > deadline {
+		p.deadlineMisses++
+	}
+	if p.playing && now.After(p.bufferedUntil) {
+		p.stalls++
+		p.rebuffering += now.Sub(p.bufferedUntil)
+	}
+	if p.bufferedUntil.Before(now) {
+		p.bufferedUntil = now
+	}
+	p.bufferedUntil = p.bufferedUntil.Add(segmentDuration)
+	p.playing = true
+
+	p.segments++
+	p.bitrateSum += bitrate
+	if p.lastBitrate != 0 && bitrate != p.lastBitrate {
+		p.switches++
+	}
+	p.lastBitrate = bitrate
+	if skipped > 0 {
+		p.damaged++
+		p.skippedBytes += skipped
+	}
+	fmt.Printf("Client: segment %2d: %4d kbit/s, %6d bytes in %4dms (deadline %4dms, %5d bytes skipped), estimated bandwidth %5.0f kbit/s, buffer %s\n",
+		segment, bitrate, n, took.Milliseconds(), deadline.Milliseconds(), skipped, p.bandwidth, time.Until(p.bufferedUntil).Round(time.Millisecond))
+	return nil
+}
+
+func (p *player) printStats() {
+	fmt.Printf("Client: played %d segments, average bitrate %d kbit/s, %d bitrate switches\n", p.segments, p.bitrateSum/p.segments, p.switches)
+	fmt.Printf("Client: stalled %d times, rebuffering for %s\n", p.stalls, p.rebuffering.Round(time.Millisecond))
+	fmt.Printf("Client: %d deadline misses, %d segments with %d skipped bytes\n", p.deadlineMisses, p.damaged, p.skippedBytes)
+}
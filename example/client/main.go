@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/http3"
@@ -112,5 +113,5 @@ func main() {
 		}(addr)
 	}
 	wg.Wait()
-	fmt.Printf("quic.Frames_recv_num: %v\n", quic.Frames_recv_num)
+	fmt.Printf("quic.Frames_recv_num: %v\n", atomic.LoadInt64(&quic.Frames_recv_num))
 }
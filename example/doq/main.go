@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/framing"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const addr = "localhost:8853"
+
+// The ALPN of DNS over QUIC, see RFC 9250, section 4.1.1.
+const alpn = "doq"
+
+// The error codes of DNS over QUIC, see RFC 9250, section 4.3.
+const (
+	doqNoError          = 0x0
+	doqInternalError    = 0x1
+	doqProtocolError    = 0x2
+	doqRequestCancelled = 0x3
+)
+
+// The zone served by the example server.
+var zone = map[string][4]byte{
+	"example.org.":      {192, 0, 2, 1},
+	"www.example.org.":  {192, 0, 2, 2},
+	"live.example.org.": {192, 0, 2, 3},
+}
+
+// We start a DNS over QUIC (RFC 9250) server, then connect with a client,
+// and resolve a few names concurrently. Every query is sent on its own stream,
+// so a lost packet only delays the query it belongs to.
+func main() {
+	ln, err := quic.ListenAddr(addr, serverTLSConfig(), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer ln.Close()
+	go serve(ln)
+
+	if err := clientMain(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serve accepts connections until the listener is closed.
+func serve(ln quic.Listener) {
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			for {
+				str, err := conn.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				go handleQuery(conn, str)
+			}
+		}()
+	}
+}
+
+func handleQuery(conn quic.Connection, str quic.Stream) {
+	defer str.Close()
+
+	b, err := framing.ReadLengthPrefixed(str)
+	if err != nil {
+		str.CancelRead(doqProtocolError)
+		str.CancelWrite(doqProtocolError)
+		return
+	}
+	// The client must send a single query, and close the stream afterwards.
+	if n, err := str.Read(make([]byte, 1)); n > 0 || err != io.EOF {
+		conn.CloseWithError(doqProtocolError, "unexpected data after the query")
+		return
+	}
+	var query dnsmessage.Message
+	if err := query.Unpack(b); err != nil {
+		conn.CloseWithError(doqProtocolError, "malformed query")
+		return
+	}
+	// The Message ID must be 0, see RFC 9250, section 4.2.1.
+	if query.Header.ID != 0 || len(query.Questions) != 1 {
+		conn.CloseWithError(doqProtocolError, "invalid query")
+		return
+	}
+	q := query.Questions[0]
+	fmt.Printf("Server: Got query for %s on stream %d\n", q.Name, str.StreamID())
+
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{Response: true, Authoritative: true},
+		Questions: query.Questions,
+	}
+	if a, ok := zone[q.Name.String()]; ok && q.Type == dnsmessage.TypeA {
+		resp.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300},
+			Body:   &dnsmessage.AResource{A: a},
+		}}
+	} else {
+		resp.Header.RCode = dnsmessage.RCodeNameError
+	}
+	b, err = resp.Pack()
+	if err != nil {
+		str.CancelWrite(doqInternalError)
+		return
+	}
+	if err := framing.WriteLengthPrefixed(str, b); err != nil {
+		str.CancelWrite(doqInternalError)
+	}
+}
+
+func clientMain() error {
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: true, // the certificate from the testdata is not valid for localhost
+		NextProtos:         []string{alpn},
+	}
+	conn, err := quic.DialAddr(addr, tlsConf, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.CloseWithError(doqNoError, "")
+
+	names := []string{"example.org.", "www.example.org.", "live.example.org.", "unknown.example.org."}
+	type result struct {
+		name string
+		ips  []net.IP
+		err  error
+	}
+	results := make(chan result, len(names))
+	for _, name := range names {
+		go func(name string) {
+			ips, err := resolve(conn, name)
+			results <- result{name: name, ips: ips, err: err}
+		}(name)
+	}
+	for range names {
+		r := <-results
+		if r.err != nil {
+			fmt.Printf("Client: Resolving %s failed: %s\n", r.name, r.err)
+			continue
+		}
+		fmt.Printf("Client: %s resolves to %v\n", r.name, r.ips)
+	}
+	return nil
+}
+
+// resolve sends a query for the A records of name on a new stream.
+func resolve(conn quic.Connection, name string) ([]net.IP, error) {
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true}, // the Message ID must be 0
+		Questions: []dnsmessage.Question{{Name: n, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	b, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	str, err := conn.OpenStreamSync(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err := framing.WriteLengthPrefixed(str, b); err != nil {
+		str.CancelRead(doqRequestCancelled)
+		return nil, err
+	}
+	// Closing the send side of the stream signals that the query is complete.
+	if err := str.Close(); err != nil {
+		return nil, err
+	}
+	b, err = framing.ReadLengthPrefixed(str)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(b); err != nil {
+		return nil, err
+	}
+	if resp.Header.RCode != dnsmessage.RCodeSuccess {
+		return nil, errors.New(resp.Header.RCode.String())
+	}
+	var ips []net.IP
+	for _, a := range resp.Answers {
+		if r, ok := a.Body.(*dnsmessage.AResource); ok {
+			ips = append(ips, net.IP(r.A[:]))
+		}
+	}
+	return ips, nil
+}
+
+// serverTLSConfig returns the TLS config of the server, using the certificate from the testdata.
+func serverTLSConfig() *tls.Config {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{alpn}
+	return tlsConf
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"crypto/md5"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
@@ -11,9 +12,11 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 
 	_ "net/http/pprof"
 
@@ -152,6 +155,10 @@ func main() {
 	www := flag.String("www", "dash.js", "www data") //默认在dash.js文件目录下提供服务
 	tcp := flag.Bool("tcp", false, "also listen on TCP")
 	enableQlog := flag.Bool("qlog", false, "output a qlog (in the same directory)")
+	defaultCertFile, defaultKeyFile := testdata.GetCertificatePaths()
+	certFile := flag.String("cert", defaultCertFile, "certificate file")
+	keyFile := flag.String("key", defaultKeyFile, "key file")
+	ocspFile := flag.String("ocsp", "", "DER encoded OCSP response stapled to the certificate")
 	flag.Parse()
 
 	logger := utils.DefaultLogger
@@ -183,6 +190,30 @@ func main() {
 		})
 	}
 
+	// Load the certificate from disk, and reload it when receiving a SIGHUP.
+	// Only new handshakes use the new certificate, existing connections are kept alive.
+	// The OCSP response, if used, is reloaded together with the certificate.
+	certReloader, err := quic.NewCertificateReloader(*certFile, *keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *ocspFile != "" {
+		if err := certReloader.SetOCSPStapleFile(*ocspFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			if err := certReloader.Reload(); err != nil {
+				log.Printf("Reloading certificate failed: %s\n", err)
+				continue
+			}
+			log.Println("Reloaded certificate.")
+		}
+	}()
+
 	var wg sync.WaitGroup
 	wg.Add(len(bs))
 	for _, b := range bs {
@@ -190,15 +221,15 @@ func main() {
 		go func() {
 			var err error
 			if *tcp {
-				certFile, keyFile := testdata.GetCertificatePaths()
-				err = http3.ListenAndServe(bCap, certFile, keyFile, handler)
+				err = http3.ListenAndServe(bCap, *certFile, *keyFile, handler)
 			} else {
 				server := http3.Server{
 					Handler:    handler,
 					Addr:       bCap,
 					QuicConfig: quicConf,
+					TLSConfig:  &tls.Config{GetCertificate: certReloader.GetCertificate},
 				}
-				err = server.ListenAndServeTLS(testdata.GetCertificatePaths())
+				err = server.ListenAndServe()
 			}
 			if err != nil {
 				fmt.Println(err)
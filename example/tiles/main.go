@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+)
+
+// We start a server that sends a sample video, cut into tiles, and connect with a client through a lossy link.
+// Every tile is sent on its own PR stream, with a policy depending on the distance of the tile to the viewport:
+// the tiles in the viewport are delivered completely, the tiles further away lose the chunks that were lost in the network.
+// At the end, the client prints the delivery statistics of every tile.
+func main() {
+	cols := flag.Int("cols", 5, "number of tile columns")
+	rows := flag.Int("rows", 5, "number of tile rows")
+	segments := flag.Int("segments", 50, "number of segments of the video")
+	chunkSize := flag.Int("chunk-size", 2000, "size of a chunk, in bytes")
+	fps := flag.Float64("fps", 25, "segments per second (0: send all segments at once)")
+	loss := flag.Float64("loss", 0.05, "packet loss rate of the link")
+	rtt := flag.Duration("rtt", 10*time.Millisecond, "RTT of the link")
+	seed := flag.Int64("seed", 1, "seed of the packet loss")
+	flag.Parse()
+
+	v := &video{
+		Cols:      *cols,
+		Rows:      *rows,
+		Segments:  *segments,
+		ChunkSize: *chunkSize,
+	}
+	if *fps > 0 {
+		v.SegmentInterval = time.Duration(float64(time.Second) / *fps)
+	}
+	start := time.Now()
+	stats, err := run(v, *loss, *rtt, *seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Received %d tiles of %d segments in %s\n", len(stats), v.Segments, time.Since(start))
+	printStats(stats)
+}
+
+// run sends the video over a link with the given packet loss rate and RTT, and returns the statistics of the client.
+func run(v *video, loss float64, rtt time.Duration, seed int64) ([]tileStats, error) {
+	ln, err := quic.ListenAddr("localhost:0", serverTLSConfig(), config())
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	go serve(ln, v)
+
+	// Only packets with a short header are dropped, such that the handshake always completes quickly.
+	var mutex sync.Mutex
+	r := rand.New(rand.NewSource(seed))
+	proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+		RemoteAddr: ln.Addr().String(),
+		DropPacket: func(_ quicproxy.Direction, b []byte) bool {
+			if len(b) == 0 || b[0]&0x80 > 0 {
+				return false
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			return r.Float64() < loss
+		},
+		DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return rtt / 2 },
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer proxy.Close()
+
+	conn, err := quic.DialAddr(fmt.Sprintf("localhost:%d", proxy.LocalPort()), clientTLSConfig(), config())
+	if err != nil {
+		return nil, err
+	}
+	stats, err := receiveVideo(conn, v.numTiles())
+	if err != nil {
+		conn.CloseWithError(1, err.Error())
+		return nil, err
+	}
+	conn.CloseWithError(0, "")
+	return stats, nil
+}
+
+func printStats(stats []tileStats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "tile\tpriority\tdelivered\tskipped\tskipped bytes\tcorrupted\t")
+	var total tileStats
+	for _, s := range stats {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%d\t\n", s.Tile, s.Priority, s.Delivered, s.Skipped, s.SkippedBytes, s.Corrupted)
+		total.Delivered += s.Delivered
+		total.Skipped += s.Skipped
+		total.SkippedBytes += s.SkippedBytes
+		total.Corrupted += s.Corrupted
+	}
+	fmt.Fprintf(w, "total\t\t%d\t%d\t%d\t%d\t\n", total.Delivered, total.Skipped, total.SkippedBytes, total.Corrupted)
+	w.Flush()
+}
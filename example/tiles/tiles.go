@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+)
+
+const alpn = "pr-tiles"
+
+// The tiles are sent on unidirectional streams, one stream per tile.
+// Every stream starts with a header, which is sent reliably:
+//
+//	Tile Header {
+//	  Tile (16),
+//	  Priority (8),
+//	  Number of Chunks (32),
+//	  Chunk Size (32),
+//	}
+//
+// It is followed by the chunks of the tile, every chunk is the part of one segment of the video that covers the tile.
+// The chunks are sent with the PR policy of the priority of the tile, every chunk is a message (see SendStream.EndMessage).
+const headerLen = 2 + 1 + 4 + 4
+
+// The policies of the tile priorities.
+// The tiles in the viewport are needed to play the video, the other tiles only when the viewer looks around.
+var tilePolicies = [...]quic.PRPolicy{
+	{},                               // in the viewport: always retransmitted
+	{PTDA: quic.PTDATimes, Value: 1}, // next to the viewport: retransmitted once
+	{PTDA: quic.PTDAAbandon},         // the rest: never retransmitted
+}
+
+// A video is a sample video, cut into a grid of tiles and into segments.
+type video struct {
+	// the tiles form a Cols x Rows grid. The viewport is in its center.
+	Cols, Rows int
+	// the number of segments, i.e. the number of chunks of every tile
+	Segments int
+	// the size of every chunk
+	ChunkSize int
+	// the time between two segments. If zero, all segments are sent right away.
+	SegmentInterval time.Duration
+}
+
+func (v *video) numTiles() int { return v.Cols * v.Rows }
+
+// priority returns the priority of a tile: its distance to the center of the grid, in tiles.
+func (v *video) priority(tile int) int {
+	dist := func(i, n int) int {
+		// the center of 2*i+1 in units of half tiles, such that grids with an even number of tiles have 2 center tiles
+		d := (2*i + 1) - n
+		if d < 0 {
+			d = -d
+		}
+		return d / 2
+	}
+	p := dist(tile%v.Cols, v.Cols)
+	if d := dist(tile/v.Cols, v.Rows); d > p {
+		p = d
+	}
+	if p >= len(tilePolicies) {
+		p = len(tilePolicies) - 1
+	}
+	return p
+}
+
+// chunk returns the content of a chunk of the sample video.
+// See https://en.wikipedia.org/wiki/Lehmer_random_number_generator
+func (v *video) chunk(tile, segment int) []byte {
+	b := make([]byte, v.ChunkSize)
+	seed := uint64(tile*v.Segments+segment) + 1
+	for i := range b {
+		seed = seed * 48271 % 2147483647
+		b[i] = byte(seed)
+	}
+	return b
+}
+
+// serve accepts connections and sends the video on every connection, until the listener is closed.
+func serve(ln quic.Listener, v *video) {
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			if err := sendVideo(conn, v); err != nil {
+				conn.CloseWithError(1, err.Error())
+			}
+		}()
+	}
+}
+
+// sendVideo sends every tile on its own stream. The chunks of all tiles of a segment are sent at the same time.
+func sendVideo(conn quic.Connection, v *video) error {
+	strs := make([]quic.SendStream, v.numTiles())
+	for tile := range strs {
+		str, err := conn.OpenUniStreamSync(context.Background())
+		if err != nil {
+			return err
+		}
+		hdr := make([]byte, headerLen)
+		binary.BigEndian.PutUint16(hdr, uint16(tile))
+		hdr[2] = byte(v.priority(tile))
+		binary.BigEndian.PutUint32(hdr[3:], uint32(v.Segments))
+		binary.BigEndian.PutUint32(hdr[7:], uint32(v.ChunkSize))
+		if _, err := str.WriteWithPolicy(hdr, quic.PRPolicy{}); err != nil {
+			return err
+		}
+		strs[tile] = str
+	}
+
+	ticker := time.NewTicker(v.SegmentInterval + time.Nanosecond)
+	defer ticker.Stop()
+	for segment := 0; segment < v.Segments; segment++ {
+		if v.SegmentInterval > 0 && segment > 0 {
+			<-ticker.C
+		}
+		for tile, str := range strs {
+			if _, err := str.WriteWithPolicy(v.chunk(tile, segment), tilePolicies[v.priority(tile)]); err != nil {
+				return err
+			}
+			if err := str.EndMessage(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, str := range strs {
+		if err := str.Close(); err != nil {
+			return err
+		}
+	}
+	// The client closes the connection once it received all tiles.
+	<-conn.Context().Done()
+	return nil
+}
+
+// tileStats are the delivery statistics of a tile, as seen by the client.
+type tileStats struct {
+	Tile     int
+	Priority int
+	// the number of chunks that were delivered completely
+	Delivered int
+	// the number of chunks that were (partially) skipped by the server
+	Skipped int
+	// the number of bytes that were skipped
+	SkippedBytes int
+	// the number of chunks that were delivered, but had the wrong content. This would be a bug.
+	Corrupted int
+}
+
+// receiveVideo receives all tiles sent on the connection, and returns their statistics, ordered by tile.
+func receiveVideo(conn quic.Connection, numTiles int) ([]tileStats, error) {
+	var mutex sync.Mutex
+	stats := make([]tileStats, numTiles)
+	var wg sync.WaitGroup
+	errChan := make(chan error, numTiles)
+	for i := 0; i < numTiles; i++ {
+		str, err := conn.AcceptUniStream(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := receiveTile(str)
+			if err != nil {
+				errChan <- fmt.Errorf("stream %d: %w", str.StreamID(), err)
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if s.Tile >= numTiles || stats[s.Tile].Delivered+stats[s.Tile].Skipped > 0 {
+				errChan <- fmt.Errorf("stream %d: unexpected tile %d", str.StreamID(), s.Tile)
+				return
+			}
+			stats[s.Tile] = *s
+		}()
+	}
+	wg.Wait()
+	close(errChan)
+	if err := <-errChan; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// receiveTile reassembles the chunks of a tile.
+// Skipped data is reported by Read as a DataSkippedError, the chunks it belongs to are counted as skipped.
+func receiveTile(str quic.ReceiveStream) (*tileStats, error) {
+	hdr := make([]byte, headerLen)
+	if _, err := io.ReadFull(str, hdr); err != nil {
+		return nil, err
+	}
+	tile := int(binary.BigEndian.Uint16(hdr))
+	v := &video{
+		Segments:  int(binary.BigEndian.Uint32(hdr[3:])),
+		ChunkSize: int(binary.BigEndian.Uint32(hdr[7:])),
+	}
+	s := &tileStats{Tile: tile, Priority: int(hdr[2])}
+
+	str.SetReadSkippedAsError(true)
+	// one more byte, such that the last Read returns io.EOF
+	data := make([]byte, v.Segments*v.ChunkSize+1)
+	skipped := make([]bool, v.Segments)
+	var pos int
+	for {
+		n, err := str.Read(data[pos:])
+		pos += n
+		if err == io.EOF {
+			break
+		}
+		var skipErr *quic.DataSkippedError
+		if errors.As(err, &skipErr) {
+			start := int(skipErr.Offset) - headerLen
+			end := start + int(skipErr.Length)
+			if start != pos || end >= len(data) {
+				return nil, fmt.Errorf("unexpected skipped range %d-%d", start, end)
+			}
+			for i := start / v.ChunkSize; i*v.ChunkSize < end; i++ {
+				skipped[i] = true
+			}
+			s.SkippedBytes += end - start
+			pos = end
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pos == len(data) {
+			return nil, errors.New("too much data")
+		}
+	}
+	if pos != len(data)-1 {
+		return nil, fmt.Errorf("received %d bytes, expected %d", pos, len(data)-1)
+	}
+	for segment := 0; segment < v.Segments; segment++ {
+		if skipped[segment] {
+			s.Skipped++
+			continue
+		}
+		s.Delivered++
+		chunk := data[segment*v.ChunkSize : (segment+1)*v.ChunkSize]
+		if !bytes.Equal(chunk, v.chunk(tile, segment)) {
+			s.Corrupted++
+		}
+	}
+	return s, nil
+}
+
+func config() *quic.Config {
+	return &quic.Config{
+		// Data written using Write is sent reliably. The tiles explicitly use WriteWithPolicy.
+		PR: quic.PRConfig{DefaultPolicy: &quic.PRPolicy{}},
+	}
+}
+
+func serverTLSConfig() *tls.Config {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{alpn}
+	return tlsConf
+}
+
+func clientTLSConfig() *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{alpn},
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tiles", func() {
+	v := &video{
+		Cols:      3,
+		Rows:      3,
+		Segments:  30,
+		ChunkSize: 1500,
+	}
+
+	It("assigns priorities by the distance to the viewport", func() {
+		v := &video{Cols: 6, Rows: 5}
+		var priorities []int
+		for tile := 0; tile < v.numTiles(); tile++ {
+			priorities = append(priorities, v.priority(tile))
+		}
+		Expect(priorities).To(Equal([]int{
+			2, 2, 2, 2, 2, 2,
+			2, 1, 1, 1, 1, 2,
+			2, 1, 0, 0, 1, 2,
+			2, 1, 1, 1, 1, 2,
+			2, 2, 2, 2, 2, 2,
+		}))
+	})
+
+	It("delivers all chunks without packet loss", func() {
+		stats, err := run(v, 0, 2*time.Millisecond, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats).To(HaveLen(9))
+		for tile, s := range stats {
+			Expect(s.Tile).To(Equal(tile))
+			Expect(s.Priority).To(Equal(v.priority(tile)))
+			Expect(s.Delivered).To(Equal(v.Segments))
+			Expect(s.Skipped).To(BeZero())
+			Expect(s.Corrupted).To(BeZero())
+		}
+	})
+
+	It("only skips chunks of tiles outside of the viewport", func() {
+		stats, err := run(v, 0.2, 2*time.Millisecond, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats).To(HaveLen(9))
+		var skipped int
+		for tile, s := range stats {
+			Expect(s.Tile).To(Equal(tile))
+			Expect(s.Delivered + s.Skipped).To(Equal(v.Segments))
+			Expect(s.Corrupted).To(BeZero())
+			if s.Priority == 0 {
+				Expect(s.Skipped).To(BeZero())
+			}
+			skipped += s.Skipped
+		}
+		Expect(skipped).ToNot(BeZero())
+	})
+})
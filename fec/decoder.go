@@ -0,0 +1,262 @@
+package fec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// maxPendingBlocks is the number of blocks the Decoder keeps.
+// Once a datagram of a newer block is received, the oldest block is given up.
+const maxPendingBlocks = 16
+
+// maxBlockGap is the maximum number of blocks a datagram may be ahead of the newest block received.
+// The Encoder numbers blocks sequentially, so a larger gap means that thousands of datagrams were lost in a row,
+// or that the datagram is bogus.
+const maxBlockGap = 1 << 10
+
+type decoderBlock struct {
+	numSources, numRepairs int // set once a repair symbol was received
+	maxSourceIndex         int
+
+	sources     map[int][]byte // the symbols received, without padding
+	repairs     map[int][]byte
+	numReceived int
+	decoded     bool
+}
+
+// A Decoder decodes datagrams sent by an Encoder.
+type Decoder struct {
+	mutex sync.Mutex
+
+	blocks    map[uint64]*decoderBlock
+	minBlock  uint64 // datagrams of blocks older than this are ignored
+	maxBlock  uint64
+	hasBlocks bool
+	feedback  Feedback
+}
+
+// NewDecoder creates a new Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{blocks: make(map[uint64]*decoderBlock)}
+}
+
+// Decode decodes a datagram, and returns the messages that became available:
+// the message carried in a source symbol, or the messages recovered using a repair symbol.
+// Messages are returned in the order they become available, so recovered messages arrive after messages sent later.
+// Duplicate datagrams and datagrams of blocks that were given up are ignored.
+func (d *Decoder) Decode(datagram []byte) ([][]byte, error) {
+	r := bytes.NewReader(datagram)
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if typ != typeSource && typ != typeRepair {
+		return nil, fmt.Errorf("unknown datagram type %d", typ)
+	}
+	blockNum, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	index, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.hasBlocks && blockNum > d.maxBlock+maxBlockGap {
+		return nil, errors.New("block number too far ahead")
+	}
+	b := d.getBlock(blockNum)
+	if b == nil {
+		return nil, nil
+	}
+	if typ == typeSource {
+		if int(index) >= maxBlockSize {
+			return nil, errors.New("invalid source symbol")
+		}
+		if _, ok := b.sources[int(index)]; ok {
+			return nil, nil
+		}
+		p := datagram[len(datagram)-r.Len():]
+		if len(p) > MaxMessageSize {
+			return nil, errors.New("message too large")
+		}
+		symbol := make([]byte, 2+len(p))
+		symbol[0], symbol[1] = byte(len(p)>>8), byte(len(p))
+		copy(symbol[2:], p)
+		b.sources[int(index)] = symbol
+		b.numReceived++
+		if int(index) > b.maxSourceIndex {
+			b.maxSourceIndex = int(index)
+		}
+		// The message is copied, such that the application can't modify the symbol used for recovery.
+		msgs := [][]byte{append([]byte(nil), p...)}
+		recovered, err := d.recover(b)
+		return append(msgs, recovered...), err
+	}
+
+	numSources, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	numRepairs, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if numSources == 0 || int(numSources) > maxBlockSize || int(index) >= int(numRepairs) || int(numRepairs) > maxRepairSymbols {
+		return nil, errors.New("invalid repair symbol")
+	}
+	// the symbol starts with the 2 byte message length
+	if r.Len() < 2 {
+		return nil, errors.New("invalid repair symbol")
+	}
+	if b.numSources != 0 && (b.numSources != int(numSources) || b.numRepairs != int(numRepairs)) {
+		return nil, errors.New("inconsistent repair symbols")
+	}
+	if _, ok := b.repairs[int(index)]; ok {
+		return nil, nil
+	}
+	b.numSources = int(numSources)
+	b.numRepairs = int(numRepairs)
+	b.repairs[int(index)] = append([]byte(nil), datagram[len(datagram)-r.Len():]...)
+	b.numReceived++
+	return d.recover(b)
+}
+
+// getBlock returns the block, creating it if necessary.
+// It returns nil if the block was already given up.
+func (d *Decoder) getBlock(num uint64) *decoderBlock {
+	if num < d.minBlock {
+		return nil
+	}
+	if b, ok := d.blocks[num]; ok {
+		return b
+	}
+	if !d.hasBlocks || num > d.maxBlock {
+		d.maxBlock = num
+		d.hasBlocks = true
+	}
+	if d.maxBlock >= maxPendingBlocks && d.minBlock <= d.maxBlock-maxPendingBlocks {
+		d.minBlock = d.maxBlock - maxPendingBlocks + 1
+		for n, b := range d.blocks {
+			if n < d.minBlock {
+				d.giveUp(b)
+				delete(d.blocks, n)
+			}
+		}
+		if num < d.minBlock {
+			return nil
+		}
+	}
+	b := &decoderBlock{
+		sources: make(map[int][]byte),
+		repairs: make(map[int][]byte),
+	}
+	d.blocks[num] = b
+	return b
+}
+
+// giveUp counts the symbols of a block that won't be received any more.
+func (d *Decoder) giveUp(b *decoderBlock) {
+	// If no repair symbol was received, the size of the block is not known.
+	expected := b.maxSourceIndex + 1
+	if b.numSources != 0 {
+		expected = b.numSources + b.numRepairs
+	}
+	d.feedback.Received += uint64(b.numReceived)
+	if expected > b.numReceived {
+		d.feedback.Lost += uint64(expected - b.numReceived)
+	}
+}
+
+// recover recovers the lost source symbols of a block, if enough symbols were received.
+func (d *Decoder) recover(b *decoderBlock) ([][]byte, error) {
+	if b.decoded || b.numSources == 0 {
+		return nil, nil
+	}
+	var missing []int
+	for i := 0; i < b.numSources; i++ {
+		if _, ok := b.sources[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		b.decoded = true
+		return nil, nil
+	}
+	if len(missing) > len(b.repairs) {
+		return nil, nil
+	}
+	b.decoded = true
+
+	repairIndices := make([]int, 0, len(b.repairs))
+	for j := range b.repairs {
+		repairIndices = append(repairIndices, j)
+	}
+	sort.Ints(repairIndices)
+	repairIndices = repairIndices[:len(missing)]
+	size := len(b.repairs[repairIndices[0]])
+	for _, j := range repairIndices {
+		if len(b.repairs[j]) != size {
+			return nil, errors.New("inconsistent repair symbol sizes")
+		}
+	}
+
+	// For every repair symbol j, subtract the received source symbols:
+	// repair_j - sum(c_ji * source_i, i received) = sum(c_ji * source_i, i missing)
+	matrix := make([][]byte, len(missing))
+	rhs := make([][]byte, len(missing))
+	for a, j := range repairIndices {
+		rhs[a] = make([]byte, size)
+		copy(rhs[a], b.repairs[j])
+		for i, s := range b.sources {
+			if i >= b.numSources {
+				continue
+			}
+			if len(s) > size {
+				return nil, errors.New("source symbol larger than repair symbol")
+			}
+			gfMulAdd(rhs[a], s, cauchy(b.numSources, j, i))
+		}
+		matrix[a] = make([]byte, len(missing))
+		for m, i := range missing {
+			matrix[a][m] = cauchy(b.numSources, j, i)
+		}
+	}
+	if err := invertMatrix(matrix); err != nil {
+		return nil, err
+	}
+	msgs := make([][]byte, 0, len(missing))
+	for m, i := range missing {
+		symbol := make([]byte, size)
+		for a := range rhs {
+			gfMulAdd(symbol, rhs[a], matrix[m][a])
+		}
+		l := int(symbol[0])<<8 | int(symbol[1])
+		if l > size-2 {
+			return nil, errors.New("invalid recovered symbol")
+		}
+		b.sources[i] = symbol[:2+l]
+		msgs = append(msgs, append([]byte(nil), symbol[2:2+l]...))
+	}
+	return msgs, nil
+}
+
+// Feedback returns the number of symbols received and lost in the blocks that were given up since the last call.
+// The application sends it to the Encoder, see Encoder.HandleFeedback.
+func (d *Decoder) Feedback() Feedback {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	f := d.feedback
+	d.feedback = Feedback{}
+	return f
+}
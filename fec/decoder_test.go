@@ -0,0 +1,131 @@
+package fec
+
+import (
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decoder", func() {
+	var (
+		sender  *recordingSender
+		encoder *Encoder
+		decoder *Decoder
+	)
+
+	BeforeEach(func() {
+		sender = &recordingSender{}
+		var err error
+		encoder, err = NewEncoder(sender, &Config{BlockSize: 10, MinRepairSymbols: 4})
+		Expect(err).ToNot(HaveOccurred())
+		decoder = NewDecoder()
+	})
+
+	decode := func(datagrams [][]byte) [][]byte {
+		var msgs [][]byte
+		for _, d := range datagrams {
+			m, err := decoder.Decode(d)
+			Expect(err).ToNot(HaveOccurred())
+			msgs = append(msgs, m...)
+		}
+		return msgs
+	}
+
+	messages := func(n int) [][]byte {
+		msgs := make([][]byte, n)
+		for i := range msgs {
+			msgs[i] = make([]byte, rand.Intn(1000))
+			rand.Read(msgs[i])
+		}
+		return msgs
+	}
+
+	It("decodes messages", func() {
+		msgs := messages(10)
+		for _, m := range msgs {
+			Expect(encoder.Send(m)).To(Succeed())
+		}
+		Expect(sender.datagrams).To(HaveLen(14))
+		Expect(decode(sender.datagrams)).To(Equal(msgs))
+	})
+
+	It("ignores duplicates", func() {
+		Expect(encoder.Send([]byte("foo"))).To(Succeed())
+		Expect(encoder.Flush()).To(Succeed())
+		Expect(decode(sender.datagrams)).To(Equal([][]byte{[]byte("foo")}))
+		Expect(decode(sender.datagrams)).To(BeEmpty())
+	})
+
+	It("recovers lost messages", func() {
+		msgs := messages(10)
+		for _, m := range msgs {
+			Expect(encoder.Send(m)).To(Succeed())
+		}
+		datagrams := sender.datagrams
+		// lose 4 of the 10 source symbols
+		received := [][]byte{datagrams[0], datagrams[2], datagrams[4], datagrams[6], datagrams[8], datagrams[9]}
+		Expect(decode(received)).To(Equal([][]byte{msgs[0], msgs[2], msgs[4], msgs[6], msgs[8], msgs[9]}))
+		Expect(decode(datagrams[10:13])).To(BeEmpty())
+		Expect(decode(datagrams[13:])).To(Equal([][]byte{msgs[1], msgs[3], msgs[5], msgs[7]}))
+	})
+
+	It("recovers from any combination of lost symbols", func() {
+		r := rand.New(rand.NewSource(42))
+		for i := 0; i < 100; i++ {
+			sender.datagrams = nil
+			decoder = NewDecoder()
+			msgs := messages(1 + r.Intn(10))
+			for _, m := range msgs {
+				Expect(encoder.Send(m)).To(Succeed())
+			}
+			Expect(encoder.Flush()).To(Succeed())
+			datagrams := sender.datagrams
+			r.Shuffle(len(datagrams), func(i, j int) { datagrams[i], datagrams[j] = datagrams[j], datagrams[i] })
+			// lose as many symbols as there are repair symbols
+			decoded := decode(datagrams[4:])
+			Expect(decoded).To(ConsistOf(msgs))
+		}
+	})
+
+	It("doesn't recover messages if too many symbols are lost", func() {
+		msgs := messages(10)
+		for _, m := range msgs {
+			Expect(encoder.Send(m)).To(Succeed())
+		}
+		Expect(decode(sender.datagrams[5:])).To(Equal(msgs[5:]))
+	})
+
+	It("reports the loss of symbols of blocks that were given up", func() {
+		for i := 0; i < 10; i++ {
+			Expect(encoder.Send([]byte("foo"))).To(Succeed())
+		}
+		decode(sender.datagrams[2:])
+		sender.datagrams = nil
+		Expect(decoder.Feedback()).To(Equal(Feedback{}))
+		for i := 0; i < maxPendingBlocks; i++ {
+			Expect(encoder.Send([]byte("foo"))).To(Succeed())
+			Expect(encoder.Flush()).To(Succeed())
+		}
+		decode(sender.datagrams)
+		Expect(decoder.Feedback()).To(Equal(Feedback{Received: 12, Lost: 2}))
+		Expect(decoder.Feedback()).To(Equal(Feedback{}))
+		// datagrams of the block that was given up are ignored
+		Expect(decoder.Decode(append([]byte{typeSource, 0, 0}, "foo"...))).To(BeEmpty())
+	})
+
+	It("errors on invalid datagrams", func() {
+		_, err := decoder.Decode([]byte{42, 0, 0})
+		Expect(err).To(MatchError("unknown datagram type 42"))
+		_, err = decoder.Decode([]byte{typeSource, 0})
+		Expect(err).To(HaveOccurred())
+		_, err = decoder.Decode([]byte{typeRepair, 0, 0, 0, 1})
+		Expect(err).To(MatchError("invalid repair symbol"))
+		_, err = decoder.Decode([]byte{typeRepair, 0, 1, 1, 1})
+		Expect(err).To(MatchError("invalid repair symbol"))
+		_, err = decoder.Decode([]byte{typeRepair, 0, 0, 1, 1, 0, 0})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = decoder.Decode([]byte{typeRepair, 0, 0, 2, 1, 0, 0})
+		Expect(err).To(MatchError("inconsistent repair symbols"))
+	})
+})
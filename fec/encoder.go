@@ -0,0 +1,153 @@
+package fec
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// A DatagramSender sends datagrams. It is implemented by quic.Connection.
+type DatagramSender interface {
+	SendMessageWithPolicy([]byte, quic.PRPolicy) error
+}
+
+// An Encoder sends messages as datagrams, protected by repair symbols.
+type Encoder struct {
+	mutex sync.Mutex
+
+	conn   DatagramSender
+	config *Config
+
+	block   uint64
+	sources [][]byte // the symbols of the current block, without padding
+
+	lossRate    float64
+	hasLossRate bool
+
+	buf []byte
+}
+
+// NewEncoder creates a new Encoder.
+func NewEncoder(conn DatagramSender, config *Config) (*Encoder, error) {
+	c, err := populateConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{conn: conn, config: c}, nil
+}
+
+// Send sends a message.
+// When the block is full, the repair symbols are sent as well.
+// Datagrams dropped by the connection (quic.ErrDatagramDropped) are treated like lost datagrams.
+func (e *Encoder) Send(p []byte) error {
+	if len(p) > MaxMessageSize {
+		return errors.New("message too large")
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	b := e.buf[:0]
+	b = append(b, typeSource)
+	b = quicvarint.Append(b, e.block)
+	b = append(b, byte(len(e.sources)))
+	b = append(b, p...)
+	e.buf = b
+	if err := e.send(b); err != nil {
+		return err
+	}
+	symbol := make([]byte, 2+len(p))
+	symbol[0], symbol[1] = byte(len(p)>>8), byte(len(p))
+	copy(symbol[2:], p)
+	e.sources = append(e.sources, symbol)
+	if len(e.sources) == e.config.BlockSize {
+		return e.flush()
+	}
+	return nil
+}
+
+// Flush ends the current block, and sends its repair symbols.
+// It can be used to limit the delay of recovering lost messages, e.g. after the last message of a video frame.
+func (e *Encoder) Flush() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.flush()
+}
+
+func (e *Encoder) flush() error {
+	k := len(e.sources)
+	if k == 0 {
+		return nil
+	}
+	var size int
+	for _, s := range e.sources {
+		if len(s) > size {
+			size = len(s)
+		}
+	}
+	block := e.block
+	sources := e.sources
+	e.block++
+	e.sources = nil
+
+	r := e.numRepairSymbols(k)
+	for j := 0; j < r; j++ {
+		b := e.buf[:0]
+		b = append(b, typeRepair)
+		b = quicvarint.Append(b, block)
+		b = append(b, byte(j), byte(k), byte(r))
+		hdrLen := len(b)
+		b = append(b, make([]byte, size)...)
+		for i, s := range sources {
+			gfMulAdd(b[hdrLen:], s, cauchy(k, j, i))
+		}
+		e.buf = b
+		if err := e.send(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) send(b []byte) error {
+	if err := e.conn.SendMessageWithPolicy(b, e.config.Policy); err != nil && !errors.Is(err, quic.ErrDatagramDropped) {
+		return err
+	}
+	return nil
+}
+
+// numRepairSymbols returns the number of repair symbols for a block of k messages.
+// It sends twice as many repair symbols as symbols are expected to be lost.
+func (e *Encoder) numRepairSymbols(k int) int {
+	r := int(math.Ceil(2 * e.lossRate * float64(k)))
+	if r < e.config.MinRepairSymbols {
+		r = e.config.MinRepairSymbols
+	}
+	if r > e.config.MaxRepairSymbols {
+		r = e.config.MaxRepairSymbols
+	}
+	return r
+}
+
+// HandleFeedback updates the loss rate, which determines the number of repair symbols sent for the following blocks.
+// The loss rate is smoothed, the same way as the RTT (RFC 9002, section 5.3).
+func (e *Encoder) HandleFeedback(f Feedback) {
+	total := f.Received + f.Lost
+	if total == 0 {
+		return
+	}
+	rate := float64(f.Lost) / float64(total)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.hasLossRate {
+		e.lossRate = rate
+		e.hasLossRate = true
+		return
+	}
+	e.lossRate = 7.0/8*e.lossRate + 1.0/8*rate
+}
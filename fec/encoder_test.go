@@ -0,0 +1,99 @@
+package fec
+
+import (
+	"errors"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingSender struct {
+	datagrams [][]byte
+	policies  []quic.PRPolicy
+	err       error
+}
+
+func (s *recordingSender) SendMessageWithPolicy(b []byte, policy quic.PRPolicy) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.datagrams = append(s.datagrams, append([]byte{}, b...))
+	s.policies = append(s.policies, policy)
+	return nil
+}
+
+var _ = Describe("Encoder", func() {
+	var (
+		sender  *recordingSender
+		encoder *Encoder
+	)
+
+	BeforeEach(func() {
+		sender = &recordingSender{}
+		var err error
+		encoder, err = NewEncoder(sender, &Config{BlockSize: 3, Policy: quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 100}})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("sends messages right away, and repair symbols when the block is full", func() {
+		Expect(encoder.Send([]byte("foo"))).To(Succeed())
+		Expect(sender.datagrams).To(Equal([][]byte{append([]byte{typeSource, 0, 0}, "foo"...)}))
+		Expect(encoder.Send([]byte("foobar"))).To(Succeed())
+		Expect(sender.datagrams).To(HaveLen(2))
+		Expect(encoder.Send([]byte("bar"))).To(Succeed())
+		Expect(sender.datagrams).To(HaveLen(4))
+		repair := sender.datagrams[3]
+		Expect(repair[:5]).To(Equal([]byte{typeRepair, 0, 0, 3, 1}))
+		Expect(repair[5:]).To(HaveLen(2 + 6)) // the length of the longest message, and its length
+		for _, p := range sender.policies {
+			Expect(p).To(Equal(quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 100}))
+		}
+		// the next block
+		Expect(encoder.Send([]byte("baz"))).To(Succeed())
+		Expect(sender.datagrams[4]).To(Equal(append([]byte{typeSource, 1, 0}, "baz"...)))
+	})
+
+	It("sends repair symbols when flushing", func() {
+		Expect(encoder.Flush()).To(Succeed())
+		Expect(sender.datagrams).To(BeEmpty())
+		Expect(encoder.Send([]byte("foo"))).To(Succeed())
+		Expect(encoder.Flush()).To(Succeed())
+		Expect(sender.datagrams).To(HaveLen(2))
+		Expect(sender.datagrams[1][:5]).To(Equal([]byte{typeRepair, 0, 0, 1, 1}))
+		Expect(encoder.Send([]byte("bar"))).To(Succeed())
+		Expect(sender.datagrams[2]).To(Equal(append([]byte{typeSource, 1, 0}, "bar"...)))
+	})
+
+	It("adapts the number of repair symbols to the loss rate", func() {
+		encoder, err := NewEncoder(sender, &Config{BlockSize: 10, MaxRepairSymbols: 5})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encoder.numRepairSymbols(10)).To(Equal(1))
+		encoder.HandleFeedback(Feedback{})
+		Expect(encoder.numRepairSymbols(10)).To(Equal(1))
+		encoder.HandleFeedback(Feedback{Received: 80, Lost: 20})
+		Expect(encoder.numRepairSymbols(10)).To(Equal(4))
+		Expect(encoder.numRepairSymbols(2)).To(Equal(1))
+		encoder.HandleFeedback(Feedback{Received: 100})
+		Expect(encoder.lossRate).To(BeNumerically("~", 0.175, 0.0001))
+		encoder.HandleFeedback(Feedback{Lost: 100})
+		Expect(encoder.numRepairSymbols(10)).To(Equal(5))
+		for i := 0; i < 10; i++ {
+			Expect(encoder.Send([]byte("foo"))).To(Succeed())
+		}
+		Expect(sender.datagrams).To(HaveLen(15))
+	})
+
+	It("ignores dropped datagrams", func() {
+		sender.err = quic.ErrDatagramDropped
+		Expect(encoder.Send([]byte("foo"))).To(Succeed())
+		testErr := errors.New("test err")
+		sender.err = testErr
+		Expect(encoder.Send([]byte("bar"))).To(MatchError(testErr))
+	})
+
+	It("refuses to send too large messages", func() {
+		Expect(encoder.Send(make([]byte, MaxMessageSize+1))).To(MatchError("message too large"))
+	})
+})
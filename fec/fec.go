@@ -0,0 +1,124 @@
+// Package fec implements application-layer forward error correction (FEC) for messages sent as QUIC datagrams.
+//
+// Messages are grouped into blocks. Every message is sent right away, as a source symbol.
+// At the end of a block, repair symbols are computed using a systematic Reed-Solomon erasure code over GF(2^8),
+// and sent as additional datagrams. The receiver can recover the messages of a block from any
+// combination of source and repair symbols, as long as it receives as many symbols as the block has messages.
+//
+// The number of repair symbols adapts to the loss rate reported by the receiver, see Feedback.
+// Since datagrams are not retransmitted, this allows recovering lost messages without waiting for a round trip,
+// for applications that prefer datagrams over streams, e.g. for real-time media.
+package fec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// Every datagram starts with a header:
+//
+//	Type (8), Block Number (i), Index (8)
+//
+// Source symbols carry the message.
+// Repair symbols carry the number of source and repair symbols of the block, followed by the symbol:
+//
+//	Source Symbols (8), Repair Symbols (8), Symbol (..)
+//
+// A symbol consists of the length of the message (16 bits) and the message, padded to the length of the longest message of the block.
+const (
+	typeSource byte = 0
+	typeRepair byte = 1
+)
+
+// MaxOverhead is the maximum number of bytes added to a message when sending it.
+// Messages must be at most this much smaller than the maximum datagram size (see quic.ConnectionState).
+// It consists of the header of a repair symbol (with the largest possible Block Number) and the message length.
+const MaxOverhead = 1 + 8 + 1 + 2 + 2
+
+// MaxMessageSize is the maximum size of a message.
+const MaxMessageSize = 1<<16 - 1
+
+const (
+	defaultBlockSize = 10
+	maxBlockSize     = 128
+	maxRepairSymbols = 128
+)
+
+// Config configures an Encoder.
+type Config struct {
+	// BlockSize is the number of messages protected together, at most 128.
+	// A block can end earlier, see Encoder.Flush.
+	// If zero, a block size of 10 is used.
+	BlockSize int
+	// MinRepairSymbols is the minimum number of repair symbols sent for every block.
+	// It is used as long as no loss is reported. If zero, 1 repair symbol is sent.
+	MinRepairSymbols int
+	// MaxRepairSymbols is the maximum number of repair symbols sent for every block, at most 128.
+	// If zero, the BlockSize is used.
+	MaxRepairSymbols int
+	// Policy is the PR policy used to send the datagrams.
+	Policy quic.PRPolicy
+}
+
+func populateConfig(config *Config) (*Config, error) {
+	c := &Config{}
+	if config != nil {
+		*c = *config
+	}
+	if c.BlockSize == 0 {
+		c.BlockSize = defaultBlockSize
+	}
+	if c.MinRepairSymbols == 0 {
+		c.MinRepairSymbols = 1
+	}
+	if c.MaxRepairSymbols == 0 {
+		c.MaxRepairSymbols = c.BlockSize
+	}
+	if c.BlockSize < 0 || c.BlockSize > maxBlockSize {
+		return nil, fmt.Errorf("invalid block size: %d", c.BlockSize)
+	}
+	if c.MaxRepairSymbols < 0 || c.MaxRepairSymbols > maxRepairSymbols {
+		return nil, fmt.Errorf("invalid maximum number of repair symbols: %d", c.MaxRepairSymbols)
+	}
+	if c.MinRepairSymbols < 0 || c.MinRepairSymbols > c.MaxRepairSymbols {
+		return nil, fmt.Errorf("invalid minimum number of repair symbols: %d", c.MinRepairSymbols)
+	}
+	return c, nil
+}
+
+// Feedback is sent by the receiver to adapt the number of repair symbols to the loss rate, see Decoder.Feedback.
+// It counts the symbols of the blocks that the Decoder stopped waiting for.
+type Feedback struct {
+	// Received is the number of symbols received.
+	Received uint64
+	// Lost is the number of symbols lost.
+	Lost uint64
+}
+
+// Append appends the encoded feedback.
+// The application is responsible for sending it to the peer, e.g. as a datagram or on a stream.
+func (f Feedback) Append(b []byte) []byte {
+	b = quicvarint.Append(b, f.Received)
+	return quicvarint.Append(b, f.Lost)
+}
+
+// ParseFeedback parses feedback encoded using Feedback.Append.
+func ParseFeedback(b []byte) (Feedback, error) {
+	r := bytes.NewReader(b)
+	received, err := quicvarint.Read(r)
+	if err != nil {
+		return Feedback{}, err
+	}
+	lost, err := quicvarint.Read(r)
+	if err != nil {
+		return Feedback{}, err
+	}
+	if r.Len() > 0 {
+		return Feedback{}, errors.New("trailing data after feedback")
+	}
+	return Feedback{Received: received, Lost: lost}, nil
+}
@@ -0,0 +1,13 @@
+package fec
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFEC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "FEC Suite")
+}
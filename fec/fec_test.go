@@ -0,0 +1,45 @@
+package fec
+
+import (
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	It("populates the config", func() {
+		c, err := populateConfig(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(&Config{BlockSize: 10, MinRepairSymbols: 1, MaxRepairSymbols: 10}))
+		c, err = populateConfig(&Config{BlockSize: 20, MinRepairSymbols: 2, Policy: quic.PRPolicy{PTDA: quic.PTDAAbandon}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(&Config{BlockSize: 20, MinRepairSymbols: 2, MaxRepairSymbols: 20, Policy: quic.PRPolicy{PTDA: quic.PTDAAbandon}}))
+	})
+
+	It("rejects invalid configs", func() {
+		_, err := populateConfig(&Config{BlockSize: 129})
+		Expect(err).To(MatchError("invalid block size: 129"))
+		_, err = populateConfig(&Config{MaxRepairSymbols: 200})
+		Expect(err).To(MatchError("invalid maximum number of repair symbols: 200"))
+		_, err = populateConfig(&Config{MinRepairSymbols: 5, MaxRepairSymbols: 4})
+		Expect(err).To(MatchError("invalid minimum number of repair symbols: 5"))
+	})
+})
+
+var _ = Describe("Feedback", func() {
+	It("encodes and parses feedback", func() {
+		b := Feedback{Received: 1337, Lost: 42}.Append(nil)
+		f, err := ParseFeedback(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f).To(Equal(Feedback{Received: 1337, Lost: 42}))
+	})
+
+	It("errors on invalid feedback", func() {
+		b := Feedback{Received: 1337, Lost: 42}.Append(nil)
+		_, err := ParseFeedback(b[:len(b)-1])
+		Expect(err).To(HaveOccurred())
+		_, err = ParseFeedback(append(b, 0))
+		Expect(err).To(MatchError("trailing data after feedback"))
+	})
+})
@@ -0,0 +1,98 @@
+package fec
+
+import "errors"
+
+// Arithmetic in GF(2^8), using the polynomial x^8 + x^4 + x^3 + x^2 + 1 (0x11d).
+// Addition and subtraction are XOR.
+
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfExp[i+255] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfInv returns the multiplicative inverse of a. a must not be 0.
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfMulAdd adds c * src to dst.
+// dst must be at least as long as src.
+func gfMulAdd(dst, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	logC := int(gfLog[c])
+	for i, v := range src {
+		if v != 0 {
+			dst[i] ^= gfExp[logC+int(gfLog[v])]
+		}
+	}
+}
+
+// cauchy returns the coefficient of source symbol i in repair symbol j of a block with k source symbols.
+// The coefficients form a Cauchy matrix: every square submatrix is invertible,
+// such that any k symbols of a block suffice to recover the source symbols.
+func cauchy(k, j, i int) byte {
+	return gfInv(byte(k+j) ^ byte(i))
+}
+
+// invertMatrix inverts the square matrix m in place, using Gauss-Jordan elimination.
+func invertMatrix(m [][]byte) error {
+	n := len(m)
+	inv := make([][]byte, n)
+	for i := range inv {
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for row := col; row < n; row++ {
+			if m[row][col] != 0 {
+				pivot = row
+				break
+			}
+		}
+		if pivot == -1 {
+			return errors.New("singular matrix")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		if c := m[col][col]; c != 1 {
+			cInv := gfInv(c)
+			for i := 0; i < n; i++ {
+				m[col][i] = gfMul(m[col][i], cInv)
+				inv[col][i] = gfMul(inv[col][i], cInv)
+			}
+		}
+		for row := 0; row < n; row++ {
+			if row == col || m[row][col] == 0 {
+				continue
+			}
+			c := m[row][col]
+			gfMulAdd(m[row], m[col], c)
+			gfMulAdd(inv[row], inv[col], c)
+		}
+	}
+	copy(m, inv)
+	return nil
+}
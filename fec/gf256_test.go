@@ -0,0 +1,54 @@
+package fec
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GF(2^8)", func() {
+	It("multiplies", func() {
+		Expect(gfMul(0, 42)).To(BeZero())
+		Expect(gfMul(1, 42)).To(Equal(byte(42)))
+		Expect(gfMul(2, 0x80)).To(Equal(byte(0x1d))) // reduced by the polynomial
+		for a := 1; a < 256; a++ {
+			Expect(gfMul(byte(a), gfInv(byte(a)))).To(Equal(byte(1)))
+		}
+	})
+
+	It("multiplies and adds slices", func() {
+		dst := []byte{1, 2, 3}
+		gfMulAdd(dst, []byte{0, 1, 2}, 3)
+		Expect(dst).To(Equal([]byte{1, 2 ^ 3, 3 ^ 6}))
+	})
+
+	It("inverts Cauchy matrices", func() {
+		const k = 10
+		m := make([][]byte, 4)
+		orig := make([][]byte, 4)
+		for j := range m {
+			m[j] = make([]byte, 4)
+			for i := range m[j] {
+				m[j][i] = cauchy(k, j, i*2)
+			}
+			orig[j] = append([]byte{}, m[j]...)
+		}
+		Expect(invertMatrix(m)).To(Succeed())
+		for i := range m {
+			for j := range m {
+				var v byte
+				for l := range m {
+					v ^= gfMul(orig[i][l], m[l][j])
+				}
+				if i == j {
+					Expect(v).To(Equal(byte(1)))
+				} else {
+					Expect(v).To(BeZero())
+				}
+			}
+		}
+	})
+
+	It("refuses to invert singular matrices", func() {
+		Expect(invertMatrix([][]byte{{1, 2}, {1, 2}})).To(MatchError("singular matrix"))
+	})
+})
@@ -2,8 +2,10 @@ package quic
 
 import (
 	"errors"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	list "github.com/lucas-clemente/quic-go/internal/utils/linkedlist"
 )
 
@@ -16,12 +18,22 @@ type byteInterval struct {
 type frameSorterEntry struct {
 	Data   []byte
 	DoneCb func()
+	// Expiry is the time when the data may be evicted, see PopExpired.
+	// The zero value means that the data never expires.
+	Expiry time.Time
+	// Filler is set for the zeros queued for data that was skipped by the sender, see PushSkipped.
+	// Parts of it might have been overwritten by data that was received nonetheless.
+	Filler bool
+	// Received is set if parts of the Filler were overwritten.
+	Received bool
 }
 
 type frameSorter struct {
 	queue   map[protocol.ByteCount]frameSorterEntry
 	readPos protocol.ByteCount
 	gaps    *list.List[byteInterval]
+
+	numFillers int // number of entries in the queue that have Filler set
 }
 
 var errDuplicateStreamData = errors.New("duplicate stream data")
@@ -36,7 +48,49 @@ func newFrameSorter() *frameSorter {
 }
 
 func (s *frameSorter) Push(data []byte, offset protocol.ByteCount, doneCb func()) error {
-	err := s.push(data, offset, doneCb)
+	return s.PushWithExpiry(data, offset, doneCb, time.Time{})
+}
+
+// PushWithExpiry pushes data that may be evicted once it expires.
+func (s *frameSorter) PushWithExpiry(data []byte, offset protocol.ByteCount, doneCb func(), expiry time.Time) error {
+	return s.pushEntry(data, offset, doneCb, expiry)
+}
+
+// PushSkipped pushes the filler for data that the sender skipped, i.e. a zero-filled buffer that is owned by the frameSorter.
+// Applying a filler is idempotent, PRAckNotify frames may be duplicated and reordered:
+//   - data always takes precedence over the filler, no matter if it arrives before or after it,
+//     since the sender might have declared it lost spuriously.
+//   - a filler never replaces anything that is queued already, be it data or another filler. It only fills the gaps.
+//   - a filler for data that was read already is ignored.
+//
+// It returns the number of bytes of the filler that were queued, i.e. that weren't received or skipped before.
+func (s *frameSorter) PushSkipped(filler []byte, offset protocol.ByteCount, doneCb func()) (protocol.ByteCount, error) {
+	// the filler is owned by the frameSorter, so doneCb can be called right away
+	if doneCb != nil {
+		defer doneCb()
+	}
+	end := offset + protocol.ByteCount(len(filler))
+	var queued protocol.ByteCount
+	var nextGap *list.Element[byteInterval]
+	for gap := s.gaps.Front(); gap != nil && gap.Value.Start < end; gap = nextGap {
+		// Filling a gap removes or shrinks it. It only splits it if the filler ends within the gap.
+		nextGap = gap.Next()
+		start := utils.Max(gap.Value.Start, offset)
+		stop := utils.Min(gap.Value.End, end)
+		if start >= stop {
+			continue
+		}
+		if err := s.push(filler[start-offset:stop-offset], start, nil, time.Time{}, true); err != nil {
+			return queued, err
+		}
+		queued += stop - start
+	}
+	return queued, nil
+}
+
+func (s *frameSorter) pushEntry(data []byte, offset protocol.ByteCount, doneCb func(), expiry time.Time) error {
+	s.copyIntoFillers(data, offset)
+	err := s.push(data, offset, doneCb, expiry, false)
 	if err == errDuplicateStreamData {
 		if doneCb != nil {
 			doneCb()
@@ -46,7 +100,27 @@ func (s *frameSorter) Push(data []byte, offset protocol.ByteCount, doneCb func()
 	return err
 }
 
-func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()) error {
+// copyIntoFillers copies data into the fillers it overlaps with, since data takes precedence over the filler.
+// Afterwards, it doesn't matter which of the two push keeps for the overlapping range.
+func (s *frameSorter) copyIntoFillers(data []byte, offset protocol.ByteCount) {
+	if s.numFillers == 0 {
+		return
+	}
+	end := offset + protocol.ByteCount(len(data))
+	for pos, entry := range s.queue {
+		entryEnd := pos + protocol.ByteCount(len(entry.Data))
+		if !entry.Filler || entryEnd <= offset || pos >= end {
+			continue
+		}
+		start := utils.Max(pos, offset)
+		stop := utils.Min(entryEnd, end)
+		copy(entry.Data[start-pos:stop-pos], data[start-offset:stop-offset])
+		entry.Received = true
+		s.queue[pos] = entry
+	}
+}
+
+func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func(), expiry time.Time, filler bool) error {
 	if len(data) == 0 {
 		return errDuplicateStreamData
 	}
@@ -85,7 +159,7 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
 		if end-pos > oldEntryLen || (hasReplacedAtLeastOne && end-pos == oldEntryLen) {
 			// The existing frame is shorter than the new frame. Replace it.
-			delete(s.queue, pos)
+			s.deleteEntry(pos, oldEntry)
 			pos += oldEntryLen
 			hasReplacedAtLeastOne = true
 			if oldEntry.DoneCb != nil {
@@ -166,10 +240,20 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		return errors.New("too many gaps in received data")
 	}
 
-	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb}
+	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb, Expiry: expiry, Filler: filler}
+	if filler {
+		s.numFillers++
+	}
 	return nil
 }
 
+func (s *frameSorter) deleteEntry(pos protocol.ByteCount, entry frameSorterEntry) {
+	delete(s.queue, pos)
+	if entry.Filler {
+		s.numFillers--
+	}
+}
+
 func (s *frameSorter) findStartGap(offset protocol.ByteCount) (*list.Element[byteInterval], bool) {
 	for gap := s.gaps.Front(); gap != nil; gap = gap.Next() {
 		if offset >= gap.Value.Start && offset <= gap.Value.End {
@@ -202,7 +286,7 @@ func (s *frameSorter) deleteConsecutive(pos protocol.ByteCount) {
 			break
 		}
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
-		delete(s.queue, pos)
+		s.deleteEntry(pos, oldEntry)
 		if oldEntry.DoneCb != nil {
 			oldEntry.DoneCb()
 		}
@@ -215,7 +299,7 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	if !ok {
 		return s.readPos, nil, nil
 	}
-	delete(s.queue, s.readPos)
+	s.deleteEntry(s.readPos, entry)
 	offset := s.readPos
 	s.readPos += protocol.ByteCount(len(entry.Data))
 	if s.gaps.Front().Value.End <= s.readPos {
@@ -224,6 +308,41 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	return offset, entry.Data, entry.DoneCb
 }
 
+// IsSkipped says if the data at the read position was skipped by the sender, i.e. it is a filler, and none of it was received.
+func (s *frameSorter) IsSkipped() bool {
+	entry, ok := s.queue[s.readPos]
+	return ok && entry.Filler && !entry.Received
+}
+
+// PopExpired pops the frame at the read position, if it expired at now.
+// If there's no expired frame at the read position, it returns the time when that frame expires,
+// or the zero value if it never expires.
+func (s *frameSorter) PopExpired(now time.Time) ([]byte, func(), time.Time) {
+	entry, ok := s.queue[s.readPos]
+	if !ok || entry.Expiry.IsZero() {
+		return nil, nil, time.Time{}
+	}
+	if now.Before(entry.Expiry) {
+		return nil, nil, entry.Expiry
+	}
+	_, data, doneCb := s.Pop()
+	return data, doneCb, time.Time{}
+}
+
+// Discard releases all queued data and fillers. The read position is kept.
+// It is used when the stream is canceled or reset, since the queued data will never be read.
+func (s *frameSorter) Discard() {
+	for _, entry := range s.queue {
+		if entry.DoneCb != nil {
+			entry.DoneCb()
+		}
+	}
+	s.queue = make(map[protocol.ByteCount]frameSorterEntry)
+	s.numFillers = 0
+	s.gaps.Init()
+	s.gaps.PushFront(byteInterval{Start: s.readPos, End: protocol.MaxByteCount})
+}
+
 // HasMoreData says if there is any more data queued at *any* offset.
 func (s *frameSorter) HasMoreData() bool {
 	return len(s.queue) > 0
@@ -113,6 +113,199 @@ var _ = Describe("frame sorter", func() {
 		Expect(doneCb).To(BeNil())
 	})
 
+	It("discards all queued data and fillers", func() {
+		cb1, t1 := getCallback()
+		cb2, t2 := getCallback()
+		Expect(s.Push([]byte("foo"), 0, cb1)).To(Succeed())
+		_, _, doneCb := s.Pop()
+		doneCb()
+		checkCallbackCalled(t1)
+		Expect(s.Push([]byte("bar"), 6, cb2)).To(Succeed())
+		n, err := s.PushSkipped(make([]byte, 10), 3, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(7))
+		s.Discard()
+		checkCallbackCalled(t2)
+		Expect(s.HasMoreData()).To(BeFalse())
+		Expect(s.numFillers).To(BeZero())
+		checkGaps([]byteInterval{{Start: 3, End: protocol.MaxByteCount}})
+		offset, data, _ := s.Pop()
+		Expect(offset).To(BeEquivalentTo(3))
+		Expect(data).To(BeNil())
+	})
+
+	Context("expiring data", func() {
+		It("pops expired frames", func() {
+			now := time.Now()
+			cb, t := getCallback()
+			Expect(s.PushWithExpiry([]byte("foo"), 0, cb, now)).To(Succeed())
+			Expect(s.PushWithExpiry([]byte("bar"), 3, nil, now.Add(time.Second))).To(Succeed())
+			data, doneCb, _ := s.PopExpired(now)
+			Expect(data).To(Equal([]byte("foo")))
+			Expect(doneCb).ToNot(BeNil())
+			checkCallbackNotCalled(t)
+			data, _, expiry := s.PopExpired(now)
+			Expect(data).To(BeNil())
+			Expect(expiry).To(Equal(now.Add(time.Second)))
+			data, _, _ = s.PopExpired(now.Add(time.Second))
+			Expect(data).To(Equal([]byte("bar")))
+			Expect(s.HasMoreData()).To(BeFalse())
+		})
+
+		It("doesn't pop frames that don't expire", func() {
+			Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+			data, _, expiry := s.PopExpired(time.Now())
+			Expect(data).To(BeNil())
+			Expect(expiry).To(BeZero())
+			_, data, _ = s.Pop()
+			Expect(data).To(Equal([]byte("foo")))
+		})
+
+		It("doesn't pop frames after a gap", func() {
+			Expect(s.PushWithExpiry([]byte("bar"), 3, nil, time.Now())).To(Succeed())
+			data, _, expiry := s.PopExpired(time.Now())
+			Expect(data).To(BeNil())
+			Expect(expiry).To(BeZero())
+		})
+	})
+
+	Context("skipped data", func() {
+		popAll := func() []byte {
+			var b []byte
+			for {
+				_, data, _ := s.Pop()
+				if data == nil {
+					return b
+				}
+				b = append(b, data...)
+			}
+		}
+
+		It("replaces the filler with data received later", func() {
+			Expect(s.PushSkipped(make([]byte, 6), 0, nil)).To(BeEquivalentTo(6))
+			Expect(s.numFillers).To(Equal(1))
+			Expect(s.Push([]byte("oob"), 1, nil)).To(Succeed())
+			Expect(popAll()).To(Equal([]byte{0, 'o', 'o', 'b', 0, 0}))
+			Expect(s.numFillers).To(BeZero())
+		})
+
+		It("replaces the filler with data received later, if it is longer than the filler", func() {
+			Expect(s.PushSkipped(make([]byte, 3), 3, nil)).To(BeEquivalentTo(3))
+			Expect(s.Push([]byte("foobar"), 0, nil)).To(Succeed())
+			Expect(popAll()).To(Equal([]byte("foobar")))
+			Expect(s.numFillers).To(BeZero())
+		})
+
+		It("keeps data received before the filler", func() {
+			Expect(s.Push([]byte("bar"), 3, nil)).To(Succeed())
+			Expect(s.PushSkipped(make([]byte, 8), 1, nil)).To(BeEquivalentTo(5))
+			Expect(s.Push([]byte("f"), 0, nil)).To(Succeed())
+			Expect(popAll()).To(Equal([]byte{'f', 0, 0, 'b', 'a', 'r', 0, 0, 0}))
+			Expect(s.numFillers).To(BeZero())
+		})
+
+		It("says if the data at the read position was skipped", func() {
+			Expect(s.IsSkipped()).To(BeFalse())
+			Expect(s.PushSkipped(make([]byte, 3), 0, nil)).To(BeEquivalentTo(3))
+			Expect(s.PushSkipped(make([]byte, 3), 3, nil)).To(BeEquivalentTo(3))
+			Expect(s.Push([]byte("r"), 5, nil)).To(Succeed())
+			Expect(s.IsSkipped()).To(BeTrue())
+			s.Pop()
+			// parts of this filler were received
+			Expect(s.IsSkipped()).To(BeFalse())
+			_, data, _ := s.Pop()
+			Expect(data).To(Equal([]byte{0, 0, 'r'}))
+		})
+
+		Context("duplicated and reordered fillers", func() {
+			It("ignores a duplicate filler", func() {
+				Expect(s.PushSkipped(make([]byte, 6), 0, nil)).To(BeEquivalentTo(6))
+				Expect(s.PushSkipped(make([]byte, 6), 0, nil)).To(BeZero())
+				Expect(s.numFillers).To(Equal(1))
+				Expect(popAll()).To(Equal(make([]byte, 6)))
+			})
+
+			It("calls the callback of the filler right away", func() {
+				var called bool
+				Expect(s.PushSkipped(make([]byte, 6), 0, func() { called = true })).To(BeEquivalentTo(6))
+				Expect(called).To(BeTrue())
+			})
+
+			It("doesn't replace data received before a larger filler", func() {
+				Expect(s.PushSkipped(make([]byte, 3), 0, nil)).To(BeEquivalentTo(3))
+				Expect(s.Push([]byte("o"), 1, nil)).To(Succeed())
+				Expect(s.PushSkipped(make([]byte, 6), 0, nil)).To(BeEquivalentTo(3))
+				Expect(popAll()).To(Equal([]byte{0, 'o', 0, 0, 0, 0}))
+				Expect(s.numFillers).To(BeZero())
+			})
+
+			It("only fills the gaps", func() {
+				Expect(s.Push([]byte("bar"), 3, nil)).To(Succeed())
+				Expect(s.PushSkipped(make([]byte, 9), 0, nil)).To(BeEquivalentTo(6))
+				Expect(s.numFillers).To(Equal(2))
+				checkGaps([]byteInterval{{Start: 9, End: protocol.MaxByteCount}})
+				Expect(s.IsSkipped()).To(BeTrue())
+				Expect(popAll()).To(Equal([]byte{0, 0, 0, 'b', 'a', 'r', 0, 0, 0}))
+			})
+
+			It("ignores a filler for data that was received", func() {
+				Expect(s.Push([]byte("foobar"), 0, nil)).To(Succeed())
+				Expect(s.PushSkipped(make([]byte, 6), 0, nil)).To(BeZero())
+				Expect(s.numFillers).To(BeZero())
+				Expect(popAll()).To(Equal([]byte("foobar")))
+			})
+
+			It("ignores a filler for data that was read", func() {
+				Expect(s.PushSkipped(make([]byte, 3), 0, nil)).To(BeEquivalentTo(3))
+				Expect(popAll()).To(HaveLen(3))
+				Expect(s.PushSkipped(make([]byte, 3), 0, nil)).To(BeZero())
+				Expect(s.HasMoreData()).To(BeFalse())
+				Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+				Expect(s.HasMoreData()).To(BeFalse())
+			})
+
+			It("results in the same data, no matter in which order data and fillers arrive", func() {
+				type push struct {
+					offset protocol.ByteCount
+					data   []byte // nil for a filler
+					length int
+				}
+				pushes := []push{
+					{offset: 0, length: 10},
+					{offset: 5, length: 10},
+					{offset: 0, length: 20},
+					{offset: 12, length: 6},
+					{offset: 2, data: []byte("foo")},
+					{offset: 8, data: []byte("barbaz")},
+					{offset: 16, data: []byte("qux")},
+					{offset: 16, data: []byte("q")},
+				}
+				expected := make([]byte, 20)
+				copy(expected[2:], "foo")
+				copy(expected[8:], "barbaz")
+				copy(expected[16:], "qux")
+
+				for i := 0; i < 100; i++ {
+					s = newFrameSorter()
+					r := rand.New(rand.NewSource(int64(i)))
+					r.Shuffle(len(pushes), func(i, j int) { pushes[i], pushes[j] = pushes[j], pushes[i] })
+					for _, p := range pushes {
+						if p.data == nil {
+							_, err := s.PushSkipped(make([]byte, p.length), p.offset, nil)
+							Expect(err).ToNot(HaveOccurred())
+						} else {
+							b := make([]byte, len(p.data))
+							copy(b, p.data)
+							Expect(s.Push(b, p.offset, nil)).To(Succeed())
+						}
+					}
+					Expect(popAll()).To(Equal(expected), fmt.Sprintf("order: %v", pushes))
+					Expect(s.numFillers).To(BeZero())
+				}
+			})
+		})
+	})
+
 	It("says if has more data", func() {
 		Expect(s.HasMoreData()).To(BeFalse())
 		Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
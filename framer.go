@@ -3,9 +3,11 @@ package quic
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/quicvarint"
 )
@@ -18,40 +20,106 @@ type framer interface {
 
 	AddActiveStream(protocol.StreamID)
 	AppendStreamFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
+	// SetPRBlocked blocks the PR streams, if the packets of the two classes are separate.
+	// It is used when the packets carrying PR data are congestion limited, see Config.PR.ScavengerCongestionControl.
+	SetPRBlocked(bool)
 
 	Handle0RTTRejection() error
 }
 
+// FramerQuotas divide the space in a packet between control frames,
+// reliable streams and partially reliable (PR) streams.
+// As long as more than one class has data to send, each class receives a share of the packet
+// proportional to its quota. Space that isn't used by one class is given to the others.
+// This prevents a large number of PR streams from starving reliable streams.
+// Zero values are replaced by the default quota of 1.
+type FramerQuotas struct {
+	Control  uint8
+	Reliable uint8
+	PR       uint8
+}
+
+func (q FramerQuotas) withDefaults() FramerQuotas {
+	if q.Control == 0 {
+		q.Control = 1
+	}
+	if q.Reliable == 0 {
+		q.Reliable = 1
+	}
+	if q.PR == 0 {
+		q.PR = 1
+	}
+	return q
+}
+
 type framerI struct {
 	mutex sync.Mutex
 
 	streamGetter streamGetter
 	version      protocol.VersionNumber
+	quotas       FramerQuotas
 
 	activeStreams map[protocol.StreamID]struct{}
+	// Streams are queued by their class, see FramerQuotas.
+	// The class of a stream is the class of the last frame it popped: partially reliable for PR_STREAM frames,
+	// reliable for STREAM frames. A stream that became active is first queued as a reliable stream.
 	streamQueue   []protocol.StreamID
+	prStreamQueue []protocol.StreamID
+	// Streams that changed their class are queued in their new class once the packet is filled,
+	// so that they're not asked for data twice for the same packet.
+	toReliable, toPR []protocol.StreamID
+	// If separatePR is set, a packet carries either STREAM frames or PR_STREAM frames.
+	// The packets are divided between the classes according to the quotas.
+	// A frame popped for a packet of the other class is held back until the next packet of its class.
+	separatePR         bool
+	numReliablePackets uint64
+	numPRPackets       uint64
+	prBlocked          bool
+	heldFrames         []ackhandler.Frame
+	heldPRFrames       []ackhandler.Frame
+
+	// If a Scheduler is set, it replaces the stream queues.
+	// Streams that became active are added to the scheduler once their deadline is known, see reportDeadline.
+	scheduler  Scheduler
+	newStreams []protocol.StreamID
+	deadlines  map[protocol.StreamID]time.Time // the deadlines reported to the scheduler
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
+
+	// the PRAckNotify frames are packed by the packet packer, but count as data to send
+	prAckNotifies *prAckNotifyQueue
 }
 
 var _ framer = &framerI{}
 
 func newFramer(
 	streamGetter streamGetter,
+	quotas FramerQuotas,
+	separatePR bool,
+	scheduler Scheduler,
+	prAckNotifies *prAckNotifyQueue,
 	v protocol.VersionNumber,
 ) framer {
-	return &framerI{
+	f := &framerI{
 		streamGetter:  streamGetter,
 		activeStreams: make(map[protocol.StreamID]struct{}),
+		quotas:        quotas.withDefaults(),
+		separatePR:    separatePR,
+		scheduler:     scheduler,
+		prAckNotifies: prAckNotifies,
 		version:       v,
 	}
+	if scheduler != nil {
+		f.deadlines = make(map[protocol.StreamID]time.Time)
+	}
+	return f
 }
 
 // 首先检查流队列，然后检查控制帧
 func (f *framerI) HasData() bool {
 	f.mutex.Lock()
-	hasData := len(f.streamQueue) > 0
+	hasData := len(f.activeStreams) > 0 || len(f.heldFrames) > 0 || len(f.heldPRFrames) > 0
 	f.mutex.Unlock()
 	if hasData {
 		return true
@@ -62,12 +130,18 @@ func (f *framerI) HasData() bool {
 	if hasData {
 		return true
 	}
-	hasData = len(PRAckNotifyFrames) > 0
-	return hasData
+	return f.prAckNotifies.HasData()
 }
 
 // 添加新的控制帧去队列里
+// PRAckNotify frames carry no stream data. They're handed to the PRAckNotify queue,
+// which keeps track of their acknowledgement. They're packed into every packet the congestion controller allows sending,
+// alongside an ACK frame if there's one to send, but not into ACK-only packets, since they're ack-eliciting.
 func (f *framerI) QueueControlFrame(frame wire.Frame) {
+	if nf, ok := frame.(*wire.PRAckNotifyFrame); ok && f.prAckNotifies != nil {
+		f.prAckNotifies.Add(nf)
+		return
+	}
 	f.controlFrameMutex.Lock()
 	f.controlFrames = append(f.controlFrames, frame)
 	f.controlFrameMutex.Unlock()
@@ -75,12 +149,22 @@ func (f *framerI) QueueControlFrame(frame wire.Frame) {
 
 // 把队列里的控制帧一个一个放入[]ackhandler.Frame中
 func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+	// If there are STREAM frames to send, control frames only use their share of the packet.
+	// The first control frame is always added, such that control frames can't be starved.
+	maxControlLen := maxLen
+	f.mutex.Lock()
+	if len(f.activeStreams) > 0 {
+		total := protocol.ByteCount(f.quotas.Control) + protocol.ByteCount(f.quotas.Reliable) + protocol.ByteCount(f.quotas.PR)
+		maxControlLen = maxLen * protocol.ByteCount(f.quotas.Control) / total
+	}
+	f.mutex.Unlock()
+
 	var length protocol.ByteCount
 	f.controlFrameMutex.Lock()
 	for len(f.controlFrames) > 0 {
 		frame := f.controlFrames[len(f.controlFrames)-1]
 		frameLen := frame.Length(f.version)
-		if length+frameLen > maxLen {
+		if length+frameLen > maxLen || (length > 0 && length+frameLen > maxControlLen) {
 			break
 		}
 		frames = append(frames, ackhandler.Frame{Frame: frame})
@@ -95,7 +179,11 @@ func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol
 func (f *framerI) AddActiveStream(id protocol.StreamID) {
 	f.mutex.Lock()
 	if _, ok := f.activeStreams[id]; !ok {
-		f.streamQueue = append(f.streamQueue, id)
+		if f.scheduler != nil {
+			f.newStreams = append(f.newStreams, id)
+		} else {
+			f.streamQueue = append(f.streamQueue, id)
+		}
 		f.activeStreams[id] = struct{}{}
 	}
 	f.mutex.Unlock()
@@ -104,17 +192,121 @@ func (f *framerI) AddActiveStream(id protocol.StreamID) {
 // 轮流从各个流中取出一帧放到第一个[]ackhandler.Frame中
 // 第二个用来存放PRAckNotify帧
 func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+	startLen := len(frames)
 	var length protocol.ByteCount
-	var lastFrame *ackhandler.Frame
 	f.mutex.Lock()
+	if f.scheduler != nil {
+		frames, length = f.appendScheduledStreamFrames(frames, maxLen)
+	} else if f.separatePR && f.prBlocked {
+		frames, length = f.appendStreamFramesOfClass(frames, false, length, maxLen)
+	} else if f.separatePR {
+		contended := f.hasDataOfClass(false) && f.hasDataOfClass(true)
+		pr := f.nextPacketIsPR()
+		frames, length = f.appendStreamFramesOfClass(frames, pr, length, maxLen)
+		if len(frames) == startLen {
+			// None of the streams queued for this class had data, or they were moved to the other class.
+			pr = !pr
+			frames, length = f.appendStreamFramesOfClass(frames, pr, length, maxLen)
+		}
+		if contended && len(frames) > startLen {
+			if pr {
+				f.numPRPackets++
+			} else {
+				f.numReliablePackets++
+			}
+		}
+	} else if len(f.streamQueue) > 0 && len(f.prStreamQueue) > 0 {
+		// Reliable streams first fill their share of the packet, then PR streams fill the rest.
+		// Space left by the PR streams is given back to the reliable streams.
+		reliableLen := maxLen * protocol.ByteCount(f.quotas.Reliable) / (protocol.ByteCount(f.quotas.Reliable) + protocol.ByteCount(f.quotas.PR))
+		frames, length = f.appendStreamFramesOfClass(frames, false, length, reliableLen)
+		frames, length = f.appendStreamFramesOfClass(frames, true, length, maxLen)
+		frames, length = f.appendStreamFramesOfClass(frames, false, length, maxLen)
+	} else {
+		frames, length = f.appendStreamFramesOfClass(frames, false, length, maxLen)
+		frames, length = f.appendStreamFramesOfClass(frames, true, length, maxLen)
+	}
+	f.streamQueue = append(f.streamQueue, f.toReliable...)
+	f.prStreamQueue = append(f.prStreamQueue, f.toPR...)
+	f.toReliable = f.toReliable[:0]
+	f.toPR = f.toPR[:0]
+	f.mutex.Unlock()
+	if len(frames) > startLen {
+		lastFrame := frames[len(frames)-1]
+		lastFrameLen := lastFrame.Length(f.version)
+		// account for the smaller size of the last STREAM frame
+		switch sf := lastFrame.Frame.(type) {
+		case *wire.PRStreamFrame:
+			sf.DataLenPresent = false
+		case *wire.StreamFrame:
+			sf.DataLenPresent = false
+		}
+		length += lastFrame.Length(f.version) - lastFrameLen
+	}
+	return frames, length
+}
+
+func (f *framerI) SetPRBlocked(blocked bool) {
+	f.mutex.Lock()
+	f.prBlocked = blocked
+	f.mutex.Unlock()
+}
+
+// nextPacketIsPR says if the next packet is filled with STREAM frames of PR streams, if separatePR is set.
+// If both classes have data to send, a class is chosen if it received less than its share of the packets.
+// must be called after locking the mutex
+func (f *framerI) nextPacketIsPR() bool {
+	if !f.hasDataOfClass(true) {
+		return false
+	}
+	if !f.hasDataOfClass(false) {
+		return true
+	}
+	return f.numPRPackets*uint64(f.quotas.Reliable) < f.numReliablePackets*uint64(f.quotas.PR)
+}
+
+// hasDataOfClass says if streams of a class are queued, or frames of that class are held back.
+// must be called after locking the mutex
+func (f *framerI) hasDataOfClass(pr bool) bool {
+	if pr {
+		return len(f.prStreamQueue) > 0 || len(f.heldPRFrames) > 0
+	}
+	return len(f.streamQueue) > 0 || len(f.heldFrames) > 0
+}
+
+// appendStreamFramesOfClass pops STREAM frames from the streams of a class, until length reaches maxLen.
+// Every stream is asked for data at most once.
+// must be called after locking the mutex
+func (f *framerI) appendStreamFramesOfClass(
+	frames []ackhandler.Frame,
+	pr bool,
+	length, maxLen protocol.ByteCount,
+) ([]ackhandler.Frame, protocol.ByteCount) {
+	queue := &f.streamQueue
+	held := &f.heldFrames
+	if pr {
+		queue = &f.prStreamQueue
+		held = &f.heldPRFrames
+	}
+	for len(*held) > 0 && length < maxLen {
+		// The frame was popped with its DataLen field, which is removed if it's the last frame, see popStreamFrames.
+		remainingLen := maxLen - length
+		frameLen := (*held)[0].Length(f.version)
+		if frameLen > remainingLen+quicvarint.Len(uint64(remainingLen)) {
+			break
+		}
+		frames = append(frames, (*held)[0])
+		length += frameLen
+		*held = (*held)[1:]
+	}
 	// pop STREAM frames, until less than MinStreamFrameSize bytes are left in the packet
-	numActiveStreams := len(f.streamQueue)
+	numActiveStreams := len(*queue)
 	for i := 0; i < numActiveStreams; i++ {
 		if protocol.MinStreamFrameSize+length > maxLen {
 			break
 		}
-		id := f.streamQueue[0]
-		f.streamQueue = f.streamQueue[1:]
+		id := (*queue)[0]
+		*queue = (*queue)[1:]
 		// This should never return an error. Better check it anyway.
 		// The stream will only be in the streamQueue, if it enqueued itself there.
 		str, err := f.streamGetter.GetOrOpenSendStream(id)
@@ -123,50 +315,233 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 			delete(f.activeStreams, id)
 			continue
 		}
+		var now time.Time
+		if str.rateLimiter() != nil {
+			now = time.Now()
+		}
+		numFrames := len(frames)
+		var hasMoreData, rateLimited bool
+		frames, length, hasMoreData, rateLimited = f.popStreamFrames(str, frames, length, maxLen, now)
+		strPR := pr
+		if len(frames) > numFrames {
+			_, strPR = frames[len(frames)-1].Frame.(*wire.PRStreamFrame)
+		}
+		if f.separatePR {
+			frames, length = f.holdFramesOfOtherClass(frames, numFrames, pr, length)
+		}
+		if rateLimited {
+			// The stream is rate-limited. It will be queued again once enough tokens are available.
+			delete(f.activeStreams, id)
+			continue
+		}
+
+		// The class of a stream is the class of the last frame it popped.
+		// A stream that became active is first queued as a reliable stream.
+		if hasMoreData { // put the stream back in the queue of its class (at the end)
+			switch {
+			case strPR == pr:
+				*queue = append(*queue, id)
+			case strPR:
+				f.toPR = append(f.toPR, id)
+			default:
+				f.toReliable = append(f.toReliable, id)
+			}
+		} else { // no more data to send. Stream is not active any more
+			delete(f.activeStreams, id)
+		}
+	}
+	return frames, length
+}
+
+// holdFramesOfOtherClass removes the frames of the other class from frames[start:], and holds them back
+// until the next packet of their class.
+// must be called after locking the mutex
+func (f *framerI) holdFramesOfOtherClass(frames []ackhandler.Frame, start int, pr bool, length protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+	n := start
+	for _, frame := range frames[start:] {
+		if _, isPR := frame.Frame.(*wire.PRStreamFrame); isPR != pr {
+			length -= frame.Length(f.version)
+			if isPR {
+				f.heldPRFrames = append(f.heldPRFrames, frame)
+			} else {
+				f.heldFrames = append(f.heldFrames, frame)
+			}
+			continue
+		}
+		frames[n] = frame
+		n++
+	}
+	return frames[:n], length
+}
+
+// popStreamFrames pops STREAM frames from a stream, and appends them to frames.
+// Usually, a stream sends a single STREAM frame per packet.
+// PR STREAM frames end where the policy of the data changes, and retransmissions are never merged,
+// so a stream that writes small messages with different policies (e.g. audio frames with their own deadlines)
+// would send packets that are mostly empty. The stream is therefore asked again, as long as it pops PR STREAM frames
+// and the remaining space allows another STREAM frame.
+// now is only used for rate-limited streams. If the stream is rate-limited, rateLimited is true,
+// and the limiter will notify the stream once enough tokens are available.
+// must be called after locking the mutex
+func (f *framerI) popStreamFrames(
+	str sendStreamI,
+	frames []ackhandler.Frame,
+	length, maxLen protocol.ByteCount,
+	now time.Time,
+) (_ []ackhandler.Frame, _ protocol.ByteCount, hasMoreData, rateLimited bool) {
+	limiter := str.rateLimiter()
+	for first := true; ; first = false {
 		remainingLen := maxLen - length
 		// For the last STREAM frame, we'll remove the DataLen field later.
 		// Therefore, we can pretend to have more bytes available when popping
 		// the STREAM frame (which will always have the DataLen set).
 		remainingLen += quicvarint.Len(uint64(remainingLen))
 
-		frame, hasMoreData := str.popStreamFrame(remainingLen) //包含从stream帧的重传队列取数据
-
-		if hasMoreData { // put the stream back in the queue (at the end)
-			f.streamQueue = append(f.streamQueue, id)
-		} else { // no more data to send. Stream is not active any more
-			delete(f.activeStreams, id)
+		if limiter != nil {
+			available := limiter.Available(now)
+			if available < protocol.MinStreamFrameSize {
+				if first {
+					limiter.WaitFor(protocol.MinStreamFrameSize, now)
+					return frames, length, false, true
+				}
+				// The stream still has data. It is rate-limited when it is asked the next time.
+				return frames, length, true, false
+			}
+			remainingLen = utils.Min(remainingLen, available)
 		}
 
+		frame, more := str.popStreamFrame(remainingLen) //包含从stream帧的重传队列取数据
+		hasMoreData = more
 		// The frame can be nil
 		// * if the receiveStream was canceled after it said it had data
 		// * the remaining size doesn't allow us to add another STREAM frame
 		if frame == nil {
-			continue
+			return frames, length, hasMoreData, false
+		}
+		frameLen := frame.Length(f.version)
+		if limiter != nil {
+			limiter.Consume(frameLen, now)
 		}
+		_, isPR := frame.Frame.(*wire.PRStreamFrame)
 		frames = append(frames, *frame)
-		length += frame.Length(f.version)
-		lastFrame = frame
+		length += frameLen
+		ackhandler.PutFrame(frame)
+		if !isPR || !hasMoreData || protocol.MinStreamFrameSize+length > maxLen {
+			return frames, length, hasMoreData, false
+		}
 	}
-	f.mutex.Unlock()
-	if lastFrame != nil {
-		lastFrameLen := lastFrame.Length(f.version)
-		// account for the smaller size of the last STREAM frame
-		if PR_ENABLED {
-			lastFrame.Frame.(*wire.PRStreamFrame).DataLenPresent = false
+}
+
+// appendScheduledStreamFrames pops STREAM frames from the streams returned by the scheduler, until length reaches maxLen.
+// must be called after locking the mutex
+func (f *framerI) appendScheduledStreamFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+	for _, id := range f.newStreams {
+		if str, err := f.streamGetter.GetOrOpenSendStream(id); str != nil && err == nil {
+			f.reportDeadline(id, str)
+		}
+		f.scheduler.AddStream(id)
+	}
+	f.newStreams = f.newStreams[:0]
+
+	var length protocol.ByteCount
+	now := time.Now()
+	var asked []protocol.StreamID
+	for protocol.MinStreamFrameSize+length <= maxLen {
+		id, ok := f.scheduler.NextStream(SchedulerContext{Now: now, RemainingLen: uint64(maxLen - length)})
+		if !ok {
+			break
+		}
+		if _, ok := f.activeStreams[id]; !ok {
+			continue
+		}
+		if containsStreamID(asked, id) { // every stream is asked for data at most once per packet
+			f.scheduler.AddStream(id)
+			break
+		}
+		asked = append(asked, id)
+		str, err := f.streamGetter.GetOrOpenSendStream(id)
+		// The stream can be nil if it completed after it said it had data.
+		if str == nil || err != nil {
+			f.removeScheduledStream(id)
+			continue
+		}
+		numFrames := len(frames)
+		var hasMoreData, rateLimited bool
+		frames, length, hasMoreData, rateLimited = f.popStreamFrames(str, frames, length, maxLen, now)
+		if rateLimited {
+			// The stream is rate-limited. It will be added again once enough tokens are available.
+			f.removeScheduledStream(id)
+			f.scheduler.OnStreamBlocked(id)
+			continue
+		}
+		if hasMoreData {
+			f.reportDeadline(id, str)
+			f.scheduler.AddStream(id)
 		} else {
-			lastFrame.Frame.(*wire.StreamFrame).DataLenPresent = false
+			f.removeScheduledStream(id)
+			if len(frames) == numFrames && str.hasData() { // blocked by flow control
+				f.scheduler.OnStreamBlocked(id)
+			}
 		}
-		length += lastFrame.Length(f.version) - lastFrameLen
 	}
 	return frames, length
 }
 
+// reportDeadline tells the scheduler when the data queued on a stream expires, if that changed.
+// must be called after locking the mutex
+func (f *framerI) reportDeadline(id protocol.StreamID, str sendStreamI) {
+	deadline := str.nextDeadline()
+	if deadline.Equal(f.deadlines[id]) {
+		return
+	}
+	if deadline.IsZero() {
+		delete(f.deadlines, id)
+	} else {
+		f.deadlines[id] = deadline
+	}
+	f.scheduler.OnDeadlineApproaching(id, deadline)
+}
+
+// removeScheduledStream removes a stream that doesn't have any data to send any more.
+// must be called after locking the mutex
+func (f *framerI) removeScheduledStream(id protocol.StreamID) {
+	delete(f.activeStreams, id)
+	if _, ok := f.deadlines[id]; ok {
+		delete(f.deadlines, id)
+		f.scheduler.OnDeadlineApproaching(id, time.Time{})
+	}
+}
+
+func containsStreamID(ids []protocol.StreamID, id protocol.StreamID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (f *framerI) Handle0RTTRejection() error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
 	f.controlFrameMutex.Lock()
 	f.streamQueue = f.streamQueue[:0]
+	f.prStreamQueue = f.prStreamQueue[:0]
+	f.heldFrames = nil
+	f.heldPRFrames = nil
+	if f.scheduler != nil {
+		f.newStreams = f.newStreams[:0]
+		for {
+			if _, ok := f.scheduler.NextStream(SchedulerContext{Now: time.Now()}); !ok {
+				break
+			}
+		}
+		for id := range f.deadlines {
+			delete(f.deadlines, id)
+			f.scheduler.OnDeadlineApproaching(id, time.Time{})
+		}
+	}
 	for id := range f.activeStreams {
 		delete(f.activeStreams, id)
 	}
@@ -3,10 +3,12 @@ package quic
 import (
 	"bytes"
 	"math/rand"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quicvarint"
 
 	"github.com/golang/mock/gomock"
 
@@ -24,6 +26,7 @@ var _ = Describe("Framer", func() {
 		framer           framer
 		stream1, stream2 *MockSendStreamI
 		streamGetter     *MockStreamGetter
+		prAckNotifies    *prAckNotifyQueue
 		version          protocol.VersionNumber
 	)
 
@@ -31,9 +34,12 @@ var _ = Describe("Framer", func() {
 		streamGetter = NewMockStreamGetter(mockCtrl)
 		stream1 = NewMockSendStreamI(mockCtrl)
 		stream1.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
+		stream1.EXPECT().rateLimiter().AnyTimes()
 		stream2 = NewMockSendStreamI(mockCtrl)
 		stream2.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
-		framer = newFramer(streamGetter, version)
+		stream2.EXPECT().rateLimiter().AnyTimes()
+		prAckNotifies = newPRAckNotifyQueue()
+		framer = newFramer(streamGetter, FramerQuotas{}, false, nil, prAckNotifies, version)
 	})
 
 	Context("handling control frames", func() {
@@ -60,6 +66,23 @@ var _ = Describe("Framer", func() {
 			Expect(framer.HasData()).To(BeFalse())
 		})
 
+		It("says that it has data if PR_ACK_NOTIFY frames are queued", func() {
+			Expect(framer.HasData()).To(BeFalse())
+			prAckNotifies.Add(&wire.PRAckNotifyFrame{StreamID: 5, PRDataLen: 10})
+			Expect(framer.HasData()).To(BeTrue())
+			prAckNotifies.PopAll()
+			Expect(framer.HasData()).To(BeFalse())
+		})
+
+		It("hands PR_ACK_NOTIFY frames to the PR_ACK_NOTIFY queue", func() {
+			f := &wire.PRAckNotifyFrame{StreamID: 5, PRDataLen: 10}
+			framer.QueueControlFrame(f)
+			Expect(framer.HasData()).To(BeTrue())
+			frames, _ := framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+			Expect(prAckNotifies.PopAll()).To(Equal([]*wire.PRAckNotifyFrame{f}))
+		})
+
 		It("appends to the slice given", func() {
 			ping := &wire.PingFrame{}
 			mdf := &wire.MaxDataFrame{MaximumData: 0x42}
@@ -377,6 +400,89 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(f.Length(version)))
 		})
 
+		Context("aggregating PR STREAM frames", func() {
+			prFrame := func(offset protocol.ByteCount, data string) *wire.PRStreamFrame {
+				return &wire.PRStreamFrame{
+					StreamID:       id1,
+					Offset:         offset,
+					Data:           []byte(data),
+					DataLenPresent: true,
+					PTDA:           PTDADeadline,
+					D:              true,
+					PtdaC:          20,
+				}
+			}
+
+			It("pops multiple small PR STREAM frames from a stream", func() {
+				streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+				f1 := prFrame(0, "foo")
+				f2 := prFrame(3, "bar")
+				f3 := prFrame(6, "baz")
+				gomock.InOrder(
+					stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true),
+					stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) (*ackhandler.Frame, bool) {
+						Expect(size).To(Equal(1000 - f1.Length(version) + quicvarint.Len(uint64(1000-f1.Length(version)))))
+						return &ackhandler.Frame{Frame: f2}, true
+					}),
+					stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f3}, false),
+				)
+				framer.AddActiveStream(id1)
+				frames, length := framer.AppendStreamFrames(nil, 1000)
+				Expect(frames).To(HaveLen(3))
+				Expect(frames[0].Frame).To(Equal(f1))
+				Expect(frames[1].Frame).To(Equal(f2))
+				Expect(frames[2].Frame).To(Equal(f3))
+				// only the DataLen of the last frame is removed
+				Expect(f1.DataLenPresent).To(BeTrue())
+				Expect(f2.DataLenPresent).To(BeTrue())
+				Expect(f3.DataLenPresent).To(BeFalse())
+				Expect(length).To(Equal(f1.Length(version) + f2.Length(version) + f3.Length(version)))
+				Expect(framer.HasData()).To(BeFalse())
+			})
+
+			It("keeps the stream queued if it stops popping PR STREAM frames", func() {
+				streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+				f1 := prFrame(0, "foo")
+				f2 := &wire.StreamFrame{StreamID: id1, Offset: 3, Data: []byte("bar"), DataLenPresent: true}
+				gomock.InOrder(
+					stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true),
+					stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, true),
+				)
+				framer.AddActiveStream(id1)
+				frames, _ := framer.AppendStreamFrames(nil, 1000)
+				Expect(frames).To(HaveLen(2))
+				Expect(framer.HasData()).To(BeTrue())
+			})
+
+			It("stops popping when the remaining size is smaller than the minimum STREAM frame size", func() {
+				streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+				f := prFrame(0, string(bytes.Repeat([]byte("f"), int(500-protocol.MinStreamFrameSize))))
+				stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f}, true)
+				framer.AddActiveStream(id1)
+				frames, _ := framer.AppendStreamFrames(nil, 500)
+				Expect(frames).To(HaveLen(1))
+				Expect(framer.HasData()).To(BeTrue())
+			})
+
+			It("stops popping when the stream is rate-limited", func() {
+				limited := NewMockSendStreamI(mockCtrl)
+				limiter := newTokenBucket(1000, func() {})
+				defer limiter.Stop()
+				limiter.Consume(limiter.Available(time.Now())-200, time.Now())
+				limited.EXPECT().rateLimiter().Return(limiter).AnyTimes()
+				streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(limited, nil)
+				f := prFrame(0, string(bytes.Repeat([]byte("f"), 100)))
+				limited.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) (*ackhandler.Frame, bool) {
+					Expect(size).To(BeNumerically("<=", 200))
+					return &ackhandler.Frame{Frame: f}, true
+				})
+				framer.AddActiveStream(id1)
+				frames, _ := framer.AppendStreamFrames(nil, 1000)
+				Expect(frames).To(HaveLen(1))
+				Expect(framer.HasData()).To(BeTrue())
+			})
+		})
+
 		It("drops all STREAM frames when 0-RTT is rejected", func() {
 			framer.AddActiveStream(id1)
 			Expect(framer.Handle0RTTRejection()).To(Succeed())
@@ -385,4 +491,380 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(BeZero())
 		})
 	})
+	Context("sharing packets between classes", func() {
+		const prID = protocol.StreamID(12)
+
+		var prStream *MockSendStreamI
+
+		popFullFrame := func(id protocol.StreamID, hasMoreData bool) func(protocol.ByteCount) (*ackhandler.Frame, bool) {
+			return func(size protocol.ByteCount) (*ackhandler.Frame, bool) {
+				f := &wire.StreamFrame{StreamID: id, DataLenPresent: true}
+				f.Data = make([]byte, f.MaxDataLen(size, version))
+				return &ackhandler.Frame{Frame: f}, hasMoreData
+			}
+		}
+
+		popFullPRFrame := func(id protocol.StreamID, hasMoreData bool) func(protocol.ByteCount) (*ackhandler.Frame, bool) {
+			return func(size protocol.ByteCount) (*ackhandler.Frame, bool) {
+				f := &wire.PRStreamFrame{StreamID: id, DataLenPresent: true, PTDA: PTDATimes, T: true}
+				f.Data = make([]byte, f.MaxDataLen(size, version))
+				return &ackhandler.Frame{Frame: f}, hasMoreData
+			}
+		}
+
+		// streamID returns the stream ID of a STREAM or a PR_STREAM frame
+		streamID := func(f ackhandler.Frame) protocol.StreamID {
+			if prf, ok := f.Frame.(*wire.PRStreamFrame); ok {
+				return prf.StreamID
+			}
+			return f.Frame.(*wire.StreamFrame).StreamID
+		}
+
+		BeforeEach(func() {
+			prStream = NewMockSendStreamI(mockCtrl)
+			prStream.EXPECT().rateLimiter().AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(prID).Return(prStream, nil).AnyTimes()
+		})
+
+		It("limits control frames, if there are STREAM frames to send", func() {
+			bf := &wire.DataBlockedFrame{MaximumData: 0x1337}
+			for i := 0; i < 100; i++ {
+				framer.QueueControlFrame(bf)
+			}
+			framer.AddActiveStream(id1)
+			frames, length := framer.AppendControlFrames(nil, 900)
+			Expect(length).To(BeNumerically("<=", 300))
+			Expect(length).To(BeNumerically(">", 300-bf.Length(version)))
+			Expect(frames).To(HaveLen(int(length / bf.Length(version))))
+		})
+
+		It("always adds one control frame", func() {
+			f := &wire.NewTokenFrame{Token: make([]byte, 500)}
+			framer.QueueControlFrame(f)
+			framer.AddActiveStream(id1)
+			frames, _ := framer.AppendControlFrames(nil, 900)
+			Expect(frames).To(HaveLen(1))
+		})
+
+		It("shares the packet between reliable and PR streams", func() {
+			stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullFrame(id1, true)).AnyTimes()
+			prStream.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullPRFrame(prID, true)).AnyTimes()
+			framer.AddActiveStream(prID)
+			framer.AddActiveStream(id1)
+			// The PR stream is queued as a reliable stream first, and moved to its class when popping.
+			// In this packet, the PR stream uses all the space.
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(streamID(frames[0])).To(Equal(prID))
+			frames, length := framer.AppendStreamFrames(nil, 1000)
+			Expect(length).To(BeEquivalentTo(1000))
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame.(*wire.StreamFrame).StreamID).To(Equal(id1))
+			Expect(frames[0].Length(version)).To(BeNumerically("~", 500, 2))
+			Expect(streamID(frames[1])).To(Equal(prID))
+		})
+
+		It("uses the configured quotas", func() {
+			framer = newFramer(streamGetter, FramerQuotas{Reliable: 1, PR: 3}, false, nil, prAckNotifies, version)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullFrame(id1, true)).AnyTimes()
+			prStream.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullPRFrame(prID, true)).AnyTimes()
+			framer.AddActiveStream(prID)
+			framer.AddActiveStream(id1)
+			framer.AppendStreamFrames(nil, 1000)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Length(version)).To(BeNumerically("~", 250, 2))
+		})
+
+		Context("sending PR streams in separate packets", func() {
+			BeforeEach(func() {
+				stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullFrame(id1, true)).AnyTimes()
+				prStream.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullPRFrame(prID, true)).AnyTimes()
+			})
+
+			getStreamIDs := func(frames []ackhandler.Frame) []protocol.StreamID {
+				var ids []protocol.StreamID
+				for _, f := range frames {
+					ids = append(ids, streamID(f))
+				}
+				return ids
+			}
+
+			It("alternates between reliable and PR streams", func() {
+				framer = newFramer(streamGetter, FramerQuotas{}, true, nil, prAckNotifies, version)
+				framer.AddActiveStream(prID)
+				framer.AddActiveStream(id1)
+				// The PR stream is queued as a reliable stream first, and moved to its class when popping.
+				// Its first frame is held back until the next PR packet.
+				frames, _ := framer.AppendStreamFrames(nil, 2000)
+				Expect(getStreamIDs(frames)).To(Equal([]protocol.StreamID{id1}))
+				for i := 0; i < 2; i++ {
+					frames, _ = framer.AppendStreamFrames(nil, 2000)
+					Expect(getStreamIDs(frames)).To(Equal([]protocol.StreamID{id1}))
+					frames, _ = framer.AppendStreamFrames(nil, 2000)
+					Expect(getStreamIDs(frames)).To(Equal([]protocol.StreamID{prID}))
+				}
+			})
+
+			It("divides the packets according to the quotas", func() {
+				framer = newFramer(streamGetter, FramerQuotas{Reliable: 1, PR: 3}, true, nil, prAckNotifies, version)
+				framer.AddActiveStream(prID)
+				framer.AddActiveStream(id1)
+				framer.AppendStreamFrames(nil, 1000)
+				var ids []protocol.StreamID
+				for i := 0; i < 8; i++ {
+					frames, _ := framer.AppendStreamFrames(nil, 1000)
+					Expect(frames).To(HaveLen(1))
+					ids = append(ids, getStreamIDs(frames)...)
+				}
+				Expect(ids).To(Equal([]protocol.StreamID{id1, prID, prID, prID, id1, prID, prID, prID}))
+			})
+
+			It("sends PR streams right away, if no reliable stream has data", func() {
+				framer = newFramer(streamGetter, FramerQuotas{}, true, nil, prAckNotifies, version)
+				framer.AddActiveStream(prID)
+				frames, _ := framer.AppendStreamFrames(nil, 1000)
+				Expect(getStreamIDs(frames)).To(Equal([]protocol.StreamID{prID}))
+			})
+
+			It("holds back PR_STREAM frames popped for a reliable packet", func() {
+				framer = newFramer(streamGetter, FramerQuotas{}, true, nil, prAckNotifies, version)
+				framer.AddActiveStream(prID)
+				framer.SetPRBlocked(true)
+				frames, length := framer.AppendStreamFrames(nil, 1000)
+				Expect(frames).To(BeEmpty())
+				Expect(length).To(BeZero())
+				Expect(framer.HasData()).To(BeTrue())
+				framer.SetPRBlocked(false)
+				frames, length = framer.AppendStreamFrames(nil, 1000)
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				Expect(length).To(BeNumerically("~", 1000, 2))
+			})
+
+			It("only sends reliable streams while the PR streams are blocked", func() {
+				framer = newFramer(streamGetter, FramerQuotas{}, true, nil, prAckNotifies, version)
+				framer.AddActiveStream(prID)
+				framer.AddActiveStream(id1)
+				framer.SetPRBlocked(true)
+				for i := 0; i < 3; i++ {
+					frames, _ := framer.AppendStreamFrames(nil, 1000)
+					Expect(getStreamIDs(frames)).To(Equal([]protocol.StreamID{id1}))
+				}
+				framer.SetPRBlocked(false)
+				frames, _ := framer.AppendStreamFrames(nil, 1000)
+				Expect(getStreamIDs(frames)).To(Equal([]protocol.StreamID{id1}))
+				frames, _ = framer.AppendStreamFrames(nil, 1000)
+				Expect(getStreamIDs(frames)).To(Equal([]protocol.StreamID{prID}))
+			})
+		})
+
+		It("classifies streams by the frames they pop", func() {
+			// The stream sends PR data first, and then reliable data.
+			gomock.InOrder(
+				prStream.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullPRFrame(prID, true)),
+				prStream.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullFrame(prID, true)),
+			)
+			framer.AddActiveStream(prID)
+			framer.AppendStreamFrames(nil, 1000)
+			Expect(framer.(*framerI).prStreamQueue).To(Equal([]protocol.StreamID{prID}))
+			Expect(framer.(*framerI).streamQueue).To(BeEmpty())
+			framer.AppendStreamFrames(nil, 1000)
+			Expect(framer.(*framerI).prStreamQueue).To(BeEmpty())
+			Expect(framer.(*framerI).streamQueue).To(Equal([]protocol.StreamID{prID}))
+		})
+
+		It("gives space not used by PR streams to reliable streams", func() {
+			stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullFrame(id1, true)).AnyTimes()
+			framer.AddActiveStream(prID)
+			framer.AddActiveStream(id1)
+			prStream.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(popFullPRFrame(prID, true))
+			framer.AppendStreamFrames(nil, 1000)
+			prStream.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: &wire.PRStreamFrame{
+				StreamID:       prID,
+				Data:           []byte("foobar"),
+				DataLenPresent: true,
+				PTDA:           PTDATimes,
+				T:              true,
+			}}, false)
+			frames, length := framer.AppendStreamFrames(nil, 1000)
+			Expect(length).To(BeEquivalentTo(1000))
+			Expect(frames).To(HaveLen(3))
+			Expect(frames[0].Frame.(*wire.StreamFrame).StreamID).To(Equal(id1))
+			Expect(streamID(frames[1])).To(Equal(prID))
+			Expect(frames[2].Frame.(*wire.StreamFrame).StreamID).To(Equal(id1))
+		})
+	})
+
+	Context("rate limiting", func() {
+		It("doesn't send more than the limiter allows", func() {
+			limited := NewMockSendStreamI(mockCtrl)
+			wakeup := make(chan struct{})
+			limiter := newTokenBucket(1000, func() { close(wakeup) })
+			limited.EXPECT().rateLimiter().Return(limiter).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(limited, nil).Times(2)
+			limited.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) (*ackhandler.Frame, bool) {
+				Expect(size).To(BeNumerically("<=", protocol.MaxPacketBufferSize))
+				f := &wire.StreamFrame{StreamID: id1, DataLenPresent: true}
+				f.Data = make([]byte, f.MaxDataLen(size, version))
+				return &ackhandler.Frame{Frame: f}, true
+			})
+			framer.AddActiveStream(id1)
+			frames, length := framer.AppendStreamFrames(nil, 3000)
+			Expect(frames).To(HaveLen(1))
+			Expect(length).To(BeNumerically("<=", protocol.MaxPacketBufferSize))
+			// all tokens were used, the stream has to wait until the bucket is refilled
+			frames, _ = framer.AppendStreamFrames(nil, 3000)
+			Expect(frames).To(BeEmpty())
+			Expect(framer.(*framerI).activeStreams).ToNot(HaveKey(id1))
+			// The wait time is determined by the rate, so it's not scaled.
+			Consistently(wakeup, 50*time.Millisecond).ShouldNot(BeClosed())
+			Eventually(wakeup).Should(BeClosed())
+		})
+
+		It("doesn't limit streams that don't have a limiter", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) (*ackhandler.Frame, bool) {
+				Expect(size).To(BeNumerically(">", 2000))
+				return &ackhandler.Frame{Frame: &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}}, false
+			})
+			framer.AddActiveStream(id1)
+			frames, _ := framer.AppendStreamFrames(nil, 3000)
+			Expect(frames).To(HaveLen(1))
+		})
+	})
+
+	Context("using a scheduler", func() {
+		var scheduler *MockScheduler
+
+		BeforeEach(func() {
+			scheduler = NewMockScheduler(mockCtrl)
+			framer = newFramer(streamGetter, FramerQuotas{}, false, scheduler, prAckNotifies, version)
+			stream1.EXPECT().nextDeadline().AnyTimes()
+			stream2.EXPECT().nextDeadline().AnyTimes()
+		})
+
+		It("pops STREAM frames in the order determined by the scheduler", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).AnyTimes()
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			Expect(framer.HasData()).To(BeTrue())
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foo")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("bar")}
+			gomock.InOrder(
+				scheduler.EXPECT().AddStream(id1),
+				scheduler.EXPECT().AddStream(id2),
+				scheduler.EXPECT().NextStream(gomock.Any()).DoAndReturn(func(ctx SchedulerContext) (protocol.StreamID, bool) {
+					Expect(ctx.RemainingLen).To(BeEquivalentTo(1000))
+					Expect(ctx.Now).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+					return id2, true
+				}),
+				stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false),
+				scheduler.EXPECT().NextStream(gomock.Any()).DoAndReturn(func(ctx SchedulerContext) (protocol.StreamID, bool) {
+					Expect(ctx.RemainingLen).To(BeEquivalentTo(1000 - f2.Length(version)))
+					return id1, true
+				}),
+				stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true),
+				scheduler.EXPECT().AddStream(id1),
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(protocol.StreamID(0), false),
+			)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(frames[1].Frame).To(Equal(f1))
+			Expect(framer.HasData()).To(BeTrue())
+		})
+
+		It("only asks every stream for data once per packet", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			framer.AddActiveStream(id1)
+			scheduler.EXPECT().AddStream(id1).Times(3)
+			scheduler.EXPECT().NextStream(gomock.Any()).Return(id1, true).Times(2)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}}, true)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(framer.HasData()).To(BeTrue())
+		})
+
+		It("tells the scheduler about deadlines", func() {
+			deadline := time.Now().Add(time.Second)
+			str := NewMockSendStreamI(mockCtrl)
+			str.EXPECT().rateLimiter().AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(str, nil).AnyTimes()
+			framer.AddActiveStream(id1)
+			gomock.InOrder(
+				str.EXPECT().nextDeadline().Return(deadline),
+				scheduler.EXPECT().OnDeadlineApproaching(id1, deadline),
+				scheduler.EXPECT().AddStream(id1),
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(id1, true),
+				str.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: &wire.StreamFrame{StreamID: id1, Data: []byte("foo")}}, true),
+				// the deadline didn't change
+				str.EXPECT().nextDeadline().Return(deadline),
+				scheduler.EXPECT().AddStream(id1),
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(protocol.StreamID(0), false),
+			)
+			framer.AppendStreamFrames(nil, 1000)
+			gomock.InOrder(
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(id1, true),
+				str.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: &wire.StreamFrame{StreamID: id1, Data: []byte("bar")}}, false),
+				scheduler.EXPECT().OnDeadlineApproaching(id1, time.Time{}),
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(protocol.StreamID(0), false),
+			)
+			framer.AppendStreamFrames(nil, 1000)
+			Expect(framer.HasData()).To(BeFalse())
+		})
+
+		It("adds multiple small PR STREAM frames of a stream in its turn", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			framer.AddActiveStream(id1)
+			f1 := &wire.PRStreamFrame{StreamID: id1, Data: []byte("foo"), DataLenPresent: true, PTDA: PTDAAbandon}
+			f2 := &wire.PRStreamFrame{StreamID: id1, Offset: 3, Data: []byte("bar"), DataLenPresent: true, PTDA: PTDAAbandon}
+			gomock.InOrder(
+				scheduler.EXPECT().AddStream(id1),
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(id1, true),
+				stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true),
+				stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false),
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(protocol.StreamID(0), false),
+			)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f1))
+			Expect(frames[1].Frame).To(Equal(f2))
+			Expect(framer.HasData()).To(BeFalse())
+		})
+
+		It("tells the scheduler when a stream is rate-limited", func() {
+			limited := NewMockSendStreamI(mockCtrl)
+			limited.EXPECT().nextDeadline().AnyTimes()
+			limiter := newTokenBucket(1000, func() {})
+			limiter.Consume(limiter.Available(time.Now()), time.Now())
+			limited.EXPECT().rateLimiter().Return(limiter).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(limited, nil).AnyTimes()
+			framer.AddActiveStream(id1)
+			scheduler.EXPECT().AddStream(id1)
+			scheduler.EXPECT().NextStream(gomock.Any()).Return(id1, true)
+			scheduler.EXPECT().OnStreamBlocked(id1)
+			scheduler.EXPECT().NextStream(gomock.Any()).Return(protocol.StreamID(0), false)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(BeEmpty())
+			Expect(framer.HasData()).To(BeFalse())
+			limiter.Stop()
+		})
+
+		It("removes all streams from the scheduler when 0-RTT is rejected", func() {
+			framer.AddActiveStream(id1)
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			scheduler.EXPECT().AddStream(id1)
+			scheduler.EXPECT().NextStream(gomock.Any()).Return(protocol.StreamID(0), false)
+			framer.AppendStreamFrames(nil, 1000) // adds the stream to the scheduler
+			gomock.InOrder(
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(id1, true),
+				scheduler.EXPECT().NextStream(gomock.Any()).Return(protocol.StreamID(0), false),
+			)
+			Expect(framer.Handle0RTTRejection()).To(Succeed())
+			Expect(framer.HasData()).To(BeFalse())
+		})
+	})
 })
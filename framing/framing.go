@@ -0,0 +1,109 @@
+// Package framing implements length-prefixed message framing on top of QUIC streams.
+//
+// Every frame consists of the length of the payload, encoded as an unsigned varint (see binary.PutUvarint),
+// followed by the payload:
+//
+//	Length (uvarint), Payload (..)
+//
+// Frames can be written with a partially reliable policy (see quic.PRPolicy). The length is then always sent reliably,
+// such that the receiver stays in sync with the frame boundaries, even if the payload is skipped.
+// Skipped payload bytes are read as zeros.
+//
+// WriteLengthPrefixed and ReadLengthPrefixed implement the 2-byte length prefix used by DNS over QUIC instead.
+package framing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// DefaultMaxFrameSize is the maximum payload size accepted by ReadFrame, if no maximum is given.
+const DefaultMaxFrameSize = 1 << 20 // 1 MB
+
+// ErrFrameTooLarge is returned by ReadFrame when the peer sends a frame larger than the maximum frame size.
+var ErrFrameTooLarge = errors.New("framing: frame too large")
+
+// A PolicyWriter writes data using a partially reliable policy.
+// It is implemented by quic.SendStream and by the http3.PRWriter.
+type PolicyWriter interface {
+	WriteWithPolicy(p []byte, policy quic.PRPolicy) (int, error)
+}
+
+// WriteFrame writes p as a single frame.
+// The length and the payload are written using a single call to Write.
+func WriteFrame(w io.Writer, p []byte) error {
+	_, err := w.Write(appendFrame(make([]byte, 0, binary.MaxVarintLen64+len(p)), p))
+	return err
+}
+
+// WriteFrameWithPolicy writes p as a single frame, sending the payload with the given policy.
+// The length is always sent reliably, and never in the same STREAM frame as a partially reliable payload.
+func WriteFrameWithPolicy(w PolicyWriter, p []byte, policy quic.PRPolicy) error {
+	if policy.IsReliable() {
+		_, err := w.WriteWithPolicy(appendFrame(make([]byte, 0, binary.MaxVarintLen64+len(p)), p), policy)
+		return err
+	}
+	var l [binary.MaxVarintLen64]byte
+	if _, err := w.WriteWithPolicy(l[:binary.PutUvarint(l[:], uint64(len(p)))], quic.PRPolicy{}); err != nil {
+		return err
+	}
+	if len(p) == 0 {
+		return nil
+	}
+	_, err := w.WriteWithPolicy(p, policy)
+	return err
+}
+
+func appendFrame(b, p []byte) []byte {
+	var l [binary.MaxVarintLen64]byte
+	b = append(b, l[:binary.PutUvarint(l[:], uint64(len(p)))]...)
+	return append(b, p...)
+}
+
+// ReadFrame reads a single frame, and returns its payload.
+// Frames larger than maxSize are rejected with ErrFrameTooLarge. If maxSize is 0, DefaultMaxFrameSize is used.
+// It returns io.EOF if r ends before the frame, such that frames can be read until the peer closes the stream,
+// and io.ErrUnexpectedEOF if r ends within a frame.
+func ReadFrame(r io.Reader, maxSize uint64) ([]byte, error) {
+	if maxSize == 0 {
+		maxSize = DefaultMaxFrameSize
+	}
+	br := &countingByteReader{Reader: quicvarint.NewReader(r)}
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		if err == io.EOF && br.n > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if l > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes (max: %d)", ErrFrameTooLarge, l, maxSize)
+	}
+	p := make([]byte, l)
+	if _, err := io.ReadFull(br.Reader, p); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// countingByteReader counts the bytes read using ReadByte.
+type countingByteReader struct {
+	quicvarint.Reader
+	n int
+}
+
+func (r *countingByteReader) ReadByte() (byte, error) {
+	b, err := r.Reader.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
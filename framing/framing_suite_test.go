@@ -0,0 +1,13 @@
+package framing
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFraming(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Framing Suite")
+}
@@ -0,0 +1,116 @@
+package framing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type policyWrite struct {
+	data   []byte
+	policy quic.PRPolicy
+}
+
+type recordingPolicyWriter struct {
+	writes []policyWrite
+	err    error
+}
+
+func (w *recordingPolicyWriter) WriteWithPolicy(p []byte, policy quic.PRPolicy) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.writes = append(w.writes, policyWrite{data: append([]byte{}, p...), policy: policy})
+	return len(p), nil
+}
+
+var _ = Describe("Framing", func() {
+	It("writes and reads frames", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteFrame(buf, []byte("foo"))).To(Succeed())
+		Expect(WriteFrame(buf, nil)).To(Succeed())
+		Expect(WriteFrame(buf, make([]byte, 300))).To(Succeed())
+		Expect(buf.Bytes()[:4]).To(Equal([]byte{3, 'f', 'o', 'o'}))
+		p, err := ReadFrame(buf, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p).To(Equal([]byte("foo")))
+		p, err = ReadFrame(buf, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p).To(BeEmpty())
+		Expect(buf.Bytes()[:2]).To(Equal([]byte{0xac, 0x02})) // 300 as a uvarint
+		p, err = ReadFrame(buf, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p).To(HaveLen(300))
+		_, err = ReadFrame(buf, 0)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("reads from readers that don't implement io.ByteReader", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteFrame(buf, []byte("foobar"))).To(Succeed())
+		p, err := ReadFrame(io.MultiReader(buf), 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p).To(Equal([]byte("foobar")))
+	})
+
+	It("rejects frames that are too large", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteFrame(buf, make([]byte, 11))).To(Succeed())
+		_, err := ReadFrame(bytes.NewReader(buf.Bytes()), 10)
+		Expect(err).To(MatchError(ErrFrameTooLarge))
+		Expect(err.Error()).To(ContainSubstring("11 bytes (max: 10)"))
+		_, err = ReadFrame(bytes.NewReader(buf.Bytes()), 11)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("uses the default maximum frame size", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteFrame(buf, make([]byte, DefaultMaxFrameSize+1))).To(Succeed())
+		_, err := ReadFrame(buf, 0)
+		Expect(err).To(MatchError(ErrFrameTooLarge))
+	})
+
+	It("errors when the reader ends within a frame", func() {
+		_, err := ReadFrame(bytes.NewReader([]byte{0xac}), 0) // incomplete length
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		_, err = ReadFrame(bytes.NewReader([]byte{3, 'f'}), 0)
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		_, err = ReadFrame(bytes.NewReader([]byte{3}), 0)
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+
+	Context("writing with a policy", func() {
+		It("writes the length reliably, and the payload with the policy", func() {
+			w := &recordingPolicyWriter{}
+			policy := quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 100}
+			Expect(WriteFrameWithPolicy(w, []byte("foobar"), policy)).To(Succeed())
+			Expect(w.writes).To(Equal([]policyWrite{
+				{data: []byte{6}, policy: quic.PRPolicy{}},
+				{data: []byte("foobar"), policy: policy},
+			}))
+		})
+
+		It("writes the whole frame at once with the reliable policy", func() {
+			w := &recordingPolicyWriter{}
+			Expect(WriteFrameWithPolicy(w, []byte("foobar"), quic.PRPolicy{})).To(Succeed())
+			Expect(w.writes).To(Equal([]policyWrite{{data: append([]byte{6}, "foobar"...)}}))
+		})
+
+		It("writes empty frames", func() {
+			w := &recordingPolicyWriter{}
+			Expect(WriteFrameWithPolicy(w, nil, quic.PRPolicy{PTDA: quic.PTDAAbandon})).To(Succeed())
+			Expect(w.writes).To(Equal([]policyWrite{{data: []byte{0}}}))
+		})
+
+		It("returns write errors", func() {
+			testErr := errors.New("test error")
+			w := &recordingPolicyWriter{err: testErr}
+			Expect(WriteFrameWithPolicy(w, []byte("foobar"), quic.PRPolicy{PTDA: quic.PTDAAbandon})).To(MatchError(testErr))
+		})
+	})
+})
@@ -0,0 +1,44 @@
+package framing
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// ErrMessageTooLarge is returned by WriteLengthPrefixed when the message doesn't fit into a 2-byte length prefix.
+var ErrMessageTooLarge = errors.New("framing: message too large for a 2-byte length prefix")
+
+// WriteLengthPrefixed writes msg to w, preceded by its length as a 2-byte unsigned integer in network byte order.
+// This is the message format used by DNS over QUIC (RFC 9250) and DNS over TCP (RFC 1035, section 4.2.2).
+// The length prefix and the message are written using a single call to Write,
+// such that they are sent in the same STREAM frame if possible.
+func WriteLengthPrefixed(w io.Writer, msg []byte) error {
+	if len(msg) > math.MaxUint16 {
+		return ErrMessageTooLarge
+	}
+	b := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(b, uint16(len(msg)))
+	copy(b[2:], msg)
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadLengthPrefixed reads a message written by WriteLengthPrefixed.
+// It returns io.EOF if r ends before the length prefix, such that a sequence of messages
+// can be read until the peer closes the stream, and io.ErrUnexpectedEOF if r ends within a message.
+func ReadLengthPrefixed(r io.Reader) ([]byte, error) {
+	var l [2]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
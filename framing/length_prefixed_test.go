@@ -0,0 +1,79 @@
+package framing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Length-prefixed messages", func() {
+	It("writes and reads messages", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteLengthPrefixed(buf, []byte("foo"))).To(Succeed())
+		Expect(WriteLengthPrefixed(buf, nil)).To(Succeed())
+		Expect(WriteLengthPrefixed(buf, []byte("foobar"))).To(Succeed())
+		Expect(buf.Bytes()[:5]).To(Equal([]byte{0, 3, 'f', 'o', 'o'}))
+		msg, err := ReadLengthPrefixed(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal([]byte("foo")))
+		msg, err = ReadLengthPrefixed(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(BeEmpty())
+		msg, err = ReadLengthPrefixed(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal([]byte("foobar")))
+		_, err = ReadLengthPrefixed(buf)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("writes the length prefix and the message at once", func() {
+		w := &writeCounter{}
+		Expect(WriteLengthPrefixed(w, make([]byte, 1000))).To(Succeed())
+		Expect(w.writes).To(Equal(1))
+		Expect(w.n).To(Equal(1002))
+	})
+
+	It("writes the largest message", func() {
+		buf := &bytes.Buffer{}
+		Expect(WriteLengthPrefixed(buf, make([]byte, 0xffff))).To(Succeed())
+		msg, err := ReadLengthPrefixed(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(HaveLen(0xffff))
+	})
+
+	It("refuses to write messages that are too large", func() {
+		Expect(WriteLengthPrefixed(&bytes.Buffer{}, make([]byte, 0x10000))).To(MatchError(ErrMessageTooLarge))
+	})
+
+	It("errors when the reader ends within a message", func() {
+		_, err := ReadLengthPrefixed(bytes.NewReader([]byte{0}))
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		_, err = ReadLengthPrefixed(bytes.NewReader([]byte{0, 3, 'f'}))
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		_, err = ReadLengthPrefixed(bytes.NewReader([]byte{0, 3}))
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+
+	It("returns read errors", func() {
+		testErr := errors.New("test error")
+		_, err := ReadLengthPrefixed(io.MultiReader(bytes.NewReader([]byte{0, 3}), &errorReader{err: testErr}))
+		Expect(err).To(MatchError(testErr))
+	})
+})
+
+type writeCounter struct {
+	writes, n int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	w.n += len(p)
+	return len(p), nil
+}
+
+type errorReader struct{ err error }
+
+func (r *errorReader) Read([]byte) (int, error) { return 0, r.err }
@@ -106,6 +106,7 @@ func main() {
 		runner,
 		config,
 		false,
+		nil,
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
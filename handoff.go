@@ -0,0 +1,363 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// ErrHandedOff is returned by the calls on a connection (and its streams) after it was handed off, see Connection.Handoff.
+var ErrHandedOff = errors.New("quic: connection handed off")
+
+// A ConnectionHandoff is the state of a server connection that is handed off to another process,
+// e.g. by a media server that restarts without dropping its connections.
+// It is returned by Connection.Handoff, and the connection is resumed by Listener.Import.
+//
+// Only the state needed to continue the connection is handed off: the connection IDs, the 1-RTT keys,
+// the peer's transport parameters (including the partial reliability negotiation), and the flow control and stream limits.
+// The new process must use the same Config, in particular the same StatelessResetKey.
+// Streams are not resumed: the new process resets the streams that were open,
+// and the application resumes its transfers on new streams, using the offsets in Streams to skip the data that was already delivered.
+// This requires the application protocol to make these transfers idempotent.
+//
+// The ConnectionHandoff contains the traffic secrets of the connection.
+// It must only be passed over a secure channel (e.g. a unix socket), and it must never be persisted.
+// All fields are exported, such that it can be serialized, e.g. using encoding/json.
+type ConnectionHandoff struct {
+	Version    VersionNumber
+	RemoteAddr string // the UDP address of the client
+
+	ServerName         string
+	NegotiatedProtocol string
+	CipherSuite        uint16
+	KeyPhase           uint64
+	// The traffic secrets of key phase 0. The keys of the current key phase are derived from them.
+	ReceiveTrafficSecret []byte
+	SendTrafficSecret    []byte
+
+	// The connection IDs issued to the client that weren't retired yet,
+	// and the highest sequence number issued so far.
+	SourceConnectionIDs       []HandoffConnectionID
+	HighestSourceConnectionID uint64
+	// The connection IDs issued by the client. The first one is used to send packets.
+	DestinationConnectionIDs []HandoffConnectionID
+
+	NextPacketNumber            int64
+	LargestReceivedPacketNumber int64
+	SmoothedRTT                 time.Duration
+
+	// the transport parameters sent by the client, in wire format
+	PeerTransportParameters []byte
+
+	// Connection-level flow control
+	BytesSent     uint64
+	SendWindow    uint64
+	BytesReceived uint64
+	ReceiveWindow uint64
+
+	// The number of streams opened, and the number of streams that may be opened, by the server and by the client.
+	OpenedBidiStreams     uint64
+	MaxBidiStreams        uint64
+	OpenedUniStreams      uint64
+	MaxUniStreams         uint64
+	PeerOpenedBidiStreams uint64
+	PeerMaxBidiStreams    uint64
+	PeerOpenedUniStreams  uint64
+	PeerMaxUniStreams     uint64
+
+	// the streams that were open, sorted by stream ID
+	Streams []HandoffStream
+}
+
+// A HandoffConnectionID is a connection ID of a handed off connection.
+type HandoffConnectionID struct {
+	SequenceNumber uint64
+	ConnectionID   []byte
+	// Only set for connection IDs issued by the client, except for the one used during the handshake.
+	StatelessResetToken []byte
+}
+
+// A HandoffStream is the state of a stream of a handed off connection.
+// The application uses the offsets to resume the transfers of the stream on a new stream.
+type HandoffStream struct {
+	StreamID StreamID
+
+	// The send direction. Not used for streams opened by the client using OpenUniStream.
+	// SendFinished is set if the client received all data, including the FIN.
+	// Otherwise, the new process resets the stream, using BytesSent as the final size.
+	SendFinished bool
+	BytesSent    uint64
+	// All data below this offset was acknowledged, or skipped according to its PR policy.
+	BytesDelivered uint64
+
+	// The receive direction. Not used for streams opened by the server using OpenUniStream.
+	// ReceiveFinished is set if the application read all data, or if the client reset the stream.
+	// Otherwise, the new process asks the client to stop sending (using a STOP_SENDING frame).
+	ReceiveFinished bool
+	// the number of bytes the application read
+	BytesRead uint64
+}
+
+func (s *connection) Handoff() (*ConnectionHandoff, error) {
+	if s.perspective == protocol.PerspectiveClient {
+		return nil, errors.New("quic: only server connections can be handed off")
+	}
+	select {
+	case <-s.HandshakeComplete().Done():
+	default:
+		return nil, errors.New("quic: can't hand off a connection before the handshake completes")
+	}
+	s.closeOnce.Do(func() {
+		s.logger.Infof("Handing off connection.")
+		// The peer is not notified, and the connection IDs are replaced with a closed connection that drops all packets.
+		s.closeChan <- closeError{err: ErrHandedOff, immediate: true, remote: true}
+	})
+	<-s.ctx.Done()
+	if s.handoff == nil && s.handoffErr == nil {
+		return nil, errors.New("quic: connection closed before it was handed off")
+	}
+	return s.handoff, s.handoffErr
+}
+
+// handoffState collects the state of the connection, see Connection.Handoff.
+// It is called by the run loop, after it stopped.
+func (s *connection) handoffState() (*ConnectionHandoff, error) {
+	keys, err := s.cryptoStreamHandler.Export1RTTKeys()
+	if err != nil {
+		return nil, err
+	}
+	pn, _ := s.sentPacketHandler.PeekPacketNumber(protocol.Encryption1RTT)
+	connState := s.cryptoStreamHandler.ConnectionState()
+	fc := s.connFlowController.HandoffState()
+	h := &ConnectionHandoff{
+		Version:                     s.version,
+		RemoteAddr:                  s.conn.RemoteAddr().String(),
+		ServerName:                  connState.ServerName,
+		NegotiatedProtocol:          connState.NegotiatedProtocol,
+		CipherSuite:                 keys.CipherSuite,
+		KeyPhase:                    uint64(keys.KeyPhase),
+		ReceiveTrafficSecret:        keys.RcvTrafficSecret,
+		SendTrafficSecret:           keys.SendTrafficSecret,
+		DestinationConnectionIDs:    s.connIDManager.HandoffState(),
+		NextPacketNumber:            int64(pn),
+		LargestReceivedPacketNumber: int64(keys.HighestRcvdPacketNumber),
+		SmoothedRTT:                 s.rttStats.SmoothedRTT(),
+		PeerTransportParameters:     s.peerParams.Marshal(protocol.PerspectiveClient),
+		BytesSent:                   uint64(fc.BytesSent),
+		SendWindow:                  uint64(fc.SendWindow),
+		BytesReceived:               uint64(fc.BytesReceived),
+		ReceiveWindow:               uint64(fc.ReceiveWindow),
+	}
+	h.SourceConnectionIDs, h.HighestSourceConnectionID = s.connIDGenerator.HandoffState()
+	s.streamsMap.HandoffState(h)
+	return h, nil
+}
+
+// newImportedConnection creates a server connection that was handed off by another process, see Listener.Import.
+// declare this as a variable, such that we can it mock it in the tests
+var newImportedConnection = func(
+	conn sendConn,
+	runner connRunner,
+	h *ConnectionHandoff,
+	streamErrorCode StreamErrorCode,
+	conf *Config,
+	tracer logging.ConnectionTracer,
+	tracingID uint64,
+	logger utils.Logger,
+) (quicConn, error) {
+	if len(h.SourceConnectionIDs) == 0 || len(h.DestinationConnectionIDs) == 0 {
+		return nil, errors.New("quic: handoff without connection IDs")
+	}
+	srcConnID, err := parseHandoffConnectionID(h.SourceConnectionIDs[0].ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	destConnID, err := parseHandoffConnectionID(h.DestinationConnectionIDs[0].ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	peerParams := &wire.TransportParameters{}
+	if err := peerParams.Unmarshal(h.PeerTransportParameters, protocol.PerspectiveClient); err != nil {
+		return nil, err
+	}
+	s := &connection{
+		conn:                conn,
+		config:              conf,
+		handshakeDestConnID: destConnID,
+		srcConnIDLen:        srcConnID.Len(),
+		oneRTTStream:        newCryptoStream(),
+		perspective:         protocol.PerspectiveServer,
+		tracer:              tracer,
+		logID:               srcConnID.String(),
+		version:             h.Version,
+	}
+	s.logger = newConnectionLogger(conf, logger, s.perspective, s.logID)
+	s.connIDManager = newConnIDManager(
+		destConnID,
+		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
+		runner.RemoveResetToken,
+		s.queueControlFrame,
+	)
+	s.connIDGenerator = newConnIDGenerator(
+		srcConnID,
+		nil,
+		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
+		runner.GetStatelessResetToken,
+		runner.Remove,
+		runner.Retire,
+		runner.ReplaceWithClosed,
+		s.queueControlFrame,
+		s.config.ConnectionIDGenerator,
+		s.version,
+	)
+	s.preSetup()
+	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
+	if h.SmoothedRTT > 0 {
+		s.rttStats.SetInitialRTT(h.SmoothedRTT)
+	}
+	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
+		0,
+		getMaxPacketSize(s.conn.RemoteAddr()),
+		s.rttStats,
+		true,
+		s.config.AmplificationFactor,
+		s.config.LossDetectionPacketThreshold,
+		s.config.LossDetectionTimeThreshold,
+		s.config.PR.ScavengerCongestionControl,
+		s.perspective,
+		s.tracer,
+		s.logger,
+		s.version,
+	)
+	cs, err := handshake.NewResumedCryptoSetup(
+		&handshake.OneRTTKeys{
+			CipherSuite:             h.CipherSuite,
+			KeyPhase:                protocol.KeyPhase(h.KeyPhase),
+			RcvTrafficSecret:        h.ReceiveTrafficSecret,
+			SendTrafficSecret:       h.SendTrafficSecret,
+			HighestRcvdPacketNumber: protocol.PacketNumber(h.LargestReceivedPacketNumber),
+		},
+		tls.ConnectionState{
+			Version:            tls.VersionTLS13,
+			HandshakeComplete:  true,
+			CipherSuite:        h.CipherSuite,
+			NegotiatedProtocol: h.NegotiatedProtocol,
+			ServerName:         h.ServerName,
+		},
+		s.closeLocal,
+		s.rttStats,
+		tracerWithEvents(tracer, s.events),
+		logger,
+		s.version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.cryptoStreamHandler = cs
+	initialStream := newCryptoStream()
+	handshakeStream := newCryptoStream()
+	s.packer = newPacketPacker(
+		srcConnID,
+		s.connIDManager.Get,
+		initialStream,
+		handshakeStream,
+		s.sentPacketHandler,
+		s.retransmissionQueue,
+		s.RemoteAddr(),
+		cs,
+		s.framer,
+		s.receivedPacketHandler,
+		s.datagramQueue,
+		s.prPolicies.ackNotifies,
+		s.config.BatchHeaderProtection,
+		protocol.ByteCount(s.config.ClientHelloFragmentSize),
+		s.perspective,
+		s.version,
+	)
+	s.unpacker = newPacketUnpacker(cs, s.srcConnIDLen, s.version)
+	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, s.oneRTTStream)
+	if err := s.resume(h, peerParams, streamErrorCode); err != nil {
+		return nil, err
+	}
+	if s.tracer != nil {
+		s.tracer.StartedConnection(s.conn.LocalAddr(), s.conn.RemoteAddr(), destConnID, srcConnID)
+	}
+	return s, nil
+}
+
+// resume restores the state of a connection that was handed off by another process.
+func (s *connection) resume(h *ConnectionHandoff, params *wire.TransportParameters, streamErrorCode StreamErrorCode) error {
+	if err := s.connIDManager.Resume(h.DestinationConnectionIDs); err != nil {
+		return err
+	}
+	// This adds the connection IDs to the packet handler map, so it must be the last step that can fail.
+	if err := s.connIDGenerator.Resume(h.SourceConnectionIDs, h.HighestSourceConnectionID); err != nil {
+		return err
+	}
+
+	// the handshake was completed (and confirmed) by the other process
+	s.receivedFirstPacket = true
+	s.received1RTTPacket = true
+	s.handshakeComplete = true
+	s.sentPacketHandler.DropPackets(protocol.EncryptionInitial)
+	s.sentPacketHandler.DropPackets(protocol.EncryptionHandshake)
+	s.receivedPacketHandler.DropPackets(protocol.EncryptionInitial)
+	s.receivedPacketHandler.DropPackets(protocol.EncryptionHandshake)
+	s.sentPacketHandler.ResumeAt(protocol.PacketNumber(h.NextPacketNumber))
+	s.connIDManager.SetHandshakeComplete()
+	s.peerParams = params
+	s.handlePRSupport(params)
+	s.applyTransportParameters()
+	s.connFlowController.Resume(flowcontrol.HandoffState{
+		BytesSent:     protocol.ByteCount(h.BytesSent),
+		SendWindow:    protocol.ByteCount(h.SendWindow),
+		BytesReceived: protocol.ByteCount(h.BytesReceived),
+		ReceiveWindow: protocol.ByteCount(h.ReceiveWindow),
+	})
+	s.streamsMap.Resume(h)
+	now := time.Now()
+	s.updateHandshakeState(func(state *HandshakeState) {
+		state.TLSComplete = now
+		state.First1RTTPacketReceived = now
+	})
+	s.handshakeCtxCancel()
+	close(s.earlyConnReadyChan)
+	s.handleHandshakeConfirmed()
+
+	for _, str := range h.Streams {
+		id := protocol.StreamID(str.StreamID)
+		hasSendSide := id.Type() == protocol.StreamTypeBidi || id.InitiatedBy() == protocol.PerspectiveServer
+		if hasSendSide && !str.SendFinished {
+			s.queueControlFrame(&wire.ResetStreamFrame{
+				StreamID:  id,
+				ErrorCode: streamErrorCode,
+				FinalSize: protocol.ByteCount(str.BytesSent),
+			})
+		}
+		if hasReceiveSide := id.Type() == protocol.StreamTypeBidi || id.InitiatedBy() == protocol.PerspectiveClient; hasReceiveSide && !str.ReceiveFinished {
+			s.queueControlFrame(&wire.StopSendingFrame{StreamID: id, ErrorCode: streamErrorCode})
+		}
+	}
+	// Frames sent by the other process might have been lost.
+	// Send the current limits, and make sure that the handshake is confirmed.
+	s.queueControlFrame(&wire.MaxDataFrame{MaximumData: protocol.ByteCount(h.ReceiveWindow)})
+	s.queueControlFrame(&wire.HandshakeDoneFrame{})
+	return nil
+}
+
+func parseHandoffConnectionID(b []byte) (protocol.ConnectionID, error) {
+	if len(b) > protocol.MaxConnIDLen {
+		return protocol.ConnectionID{}, fmt.Errorf("invalid connection ID length: %d", len(b))
+	}
+	return protocol.ParseConnectionID(b), nil
+}
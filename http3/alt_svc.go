@@ -0,0 +1,76 @@
+package http3
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// defaultAltSvcMaxAge is the freshness lifetime of the Alt-Svc header, if no max age is configured.
+const defaultAltSvcMaxAge = 30 * 24 * time.Hour
+
+// An AltSvcEndpoint is an alternative service advertised in the Alt-Svc header (see RFC 7838).
+type AltSvcEndpoint struct {
+	// ALPN is the protocol ID of the alternative service, e.g. "h3".
+	// Custom protocol IDs can be used, as long as the QUIC listener accepts them during the handshake.
+	ALPN string
+	// Host is the host of the alternative service.
+	// If empty, clients use the host of the origin.
+	Host string
+	// Port is the UDP port of the alternative service.
+	Port int
+	// MaxAge is the time clients may use the alternative service without receiving the header again.
+	// It is rounded down to seconds. If zero, 30 days are used.
+	MaxAge time.Duration
+}
+
+// String formats the endpoint as a value of the Alt-Svc header, e.g. `h3=":443"; ma=2592000`.
+func (e AltSvcEndpoint) String() string {
+	maxAge := e.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultAltSvcMaxAge
+	}
+	var b strings.Builder
+	b.WriteString(encodeAltSvcProtocolID(e.ALPN))
+	b.WriteString(`="`)
+	if strings.IndexByte(e.Host, ':') >= 0 { // IPv6 address
+		b.WriteString(net.JoinHostPort(e.Host, strconv.Itoa(e.Port)))
+	} else {
+		b.WriteString(e.Host)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(e.Port))
+	}
+	b.WriteString(`"; ma=`)
+	b.WriteString(strconv.FormatInt(int64(maxAge/time.Second), 10))
+	return b.String()
+}
+
+// formatAltSvc formats the endpoints as the value of an Alt-Svc header.
+func formatAltSvc(endpoints []AltSvcEndpoint) string {
+	values := make([]string, 0, len(endpoints))
+	for _, e := range endpoints {
+		values = append(values, e.String())
+	}
+	return strings.Join(values, ",")
+}
+
+// encodeAltSvcProtocolID percent-encodes the ALPN protocol ID, see RFC 7838, section 3.1.
+// Octets that are not allowed in a token, as well as "%" and "=", are encoded.
+func encodeAltSvcProtocolID(alpn string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(alpn); i++ {
+		c := alpn[i]
+		if httpguts.IsTokenRune(rune(c)) && c != '%' && c != '=' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package http3
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alt-Svc", func() {
+	It("formats an endpoint", func() {
+		Expect(AltSvcEndpoint{ALPN: "h3", Port: 443}.String()).To(Equal(`h3=":443"; ma=2592000`))
+	})
+
+	It("formats an endpoint with a host and a max age", func() {
+		e := AltSvcEndpoint{ALPN: "h3", Host: "quic.clemente.io", Port: 8443, MaxAge: time.Hour + 1500*time.Millisecond}
+		Expect(e.String()).To(Equal(`h3="quic.clemente.io:8443"; ma=3601`))
+	})
+
+	It("formats an endpoint with an IPv6 address", func() {
+		Expect(AltSvcEndpoint{ALPN: "h3", Host: "::1", Port: 443}.String()).To(Equal(`h3="[::1]:443"; ma=2592000`))
+	})
+
+	It("percent-encodes the ALPN", func() {
+		Expect(encodeAltSvcProtocolID("h3-pr")).To(Equal("h3-pr"))
+		Expect(encodeAltSvcProtocolID("w=x%y z")).To(Equal("w%3Dx%25y%20z"))
+		Expect(encodeAltSvcProtocolID("\xff")).To(Equal("%FF"))
+	})
+
+	It("formats multiple endpoints", func() {
+		Expect(formatAltSvc([]AltSvcEndpoint{
+			{ALPN: "h3", Port: 443},
+			{ALPN: "h3-pr", Port: 8443, MaxAge: time.Minute},
+		})).To(Equal(`h3=":443"; ma=2592000,h3-pr=":8443"; ma=60`))
+	})
+})
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"strconv"
 	"sync"
 	"time"
@@ -26,6 +28,9 @@ const MethodGet0RTT = "GET_0RTT"
 const (
 	defaultUserAgent              = "quic-go HTTP/3"
 	defaultMaxResponseHeaderBytes = 10 * 1 << 20 // 10 MB
+	// max1xxResponses is the maximum number of 1xx responses accepted for a request,
+	// unless a httptrace.ClientTrace with a Got1xxResponse hook is used.
+	max1xxResponses = 5
 )
 
 var defaultQuicConfig = &quic.Config{
@@ -34,6 +39,10 @@ var defaultQuicConfig = &quic.Config{
 	Versions:           []protocol.VersionNumber{protocol.VersionTLS},
 }
 
+// errGoAway is returned when the server didn't process a request, because it is shutting down the connection.
+// The request can safely be retried on a new connection.
+var errGoAway = errors.New("http3: server is going away")
+
 type dialFunc func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error)
 
 var dialAddr = quic.DialAddrEarlyContext
@@ -45,6 +54,9 @@ type roundTripperOpts struct {
 	AdditionalSettings map[uint64]uint64
 	StreamHijacker     func(FrameType, quic.Connection, quic.Stream, error) (hijacked bool, err error)
 	UniStreamHijacker  func(StreamType, quic.Connection, quic.ReceiveStream, error) (hijacked bool)
+
+	QPACKMaxTableCapacity uint64
+	QPACKBlockedStreams   uint64
 }
 
 // client is a HTTP3 client doing requests
@@ -59,11 +71,19 @@ type client struct {
 
 	requestWriter *requestWriter
 
-	decoder *qpack.Decoder
+	// nil if the QPACK dynamic table is disabled
+	decoder *qpackDecoder
+	encoder *qpackEncoder
 
 	hostname string
 	conn     quic.EarlyConnection
 
+	mutex sync.Mutex
+	// set when the server sent a GOAWAY frame
+	goingAway bool
+	// requests on streams with this or higher stream IDs are not processed by the server
+	goAwayStreamID quic.StreamID
+
 	logger utils.Logger
 }
 
@@ -95,7 +115,6 @@ func newClient(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, con
 		hostname:      authorityAddr("https", hostname),
 		tlsConf:       tlsConf,
 		requestWriter: newRequestWriter(logger),
-		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
 		config:        conf,
 		opts:          opts,
 		dialer:        dialer,
@@ -113,6 +132,11 @@ func (c *client) dial(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if c.opts.QPACKMaxTableCapacity > 0 {
+		c.decoder = newQPACKDecoder(c.conn, c.opts.QPACKMaxTableCapacity, c.opts.QPACKBlockedStreams)
+		c.encoder = newQPACKEncoder(c.conn, c.opts.QPACKMaxTableCapacity)
+		c.requestWriter.encoder = c.encoder
+	}
 
 	// send the SETTINGs frame, using 0-RTT data, if possible
 	go func() {
@@ -135,10 +159,17 @@ func (c *client) setupConn() error {
 	if err != nil {
 		return err
 	}
+	// Skipping data on the control stream would corrupt the HTTP/3 state.
+	str.DisablePR()
 	b := make([]byte, 0, 64)
 	b = quicvarint.Append(b, streamTypeControlStream)
 	// send the SETTINGS frame
-	b = (&settingsFrame{Datagram: c.opts.EnableDatagram, Other: c.opts.AdditionalSettings}).Append(b)
+	b = (&settingsFrame{
+		Datagram:              c.opts.EnableDatagram,
+		QPACKMaxTableCapacity: c.opts.QPACKMaxTableCapacity,
+		QPACKBlockedStreams:   c.opts.QPACKBlockedStreams,
+		Other:                 c.opts.AdditionalSettings,
+	}).Append(b)
 	_, err = str.Write(b)
 	return err
 }
@@ -185,9 +216,16 @@ func (c *client) handleUnidirectionalStreams() {
 			// We're only interested in the control stream here.
 			switch streamType {
 			case streamTypeControlStream:
-			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
-				// Our QPACK implementation doesn't use the dynamic table yet.
-				// TODO: check that only one stream of each type is opened.
+			case streamTypeQPACKEncoderStream:
+				// Without a dynamic table, the server can't insert any entries.
+				if c.decoder != nil {
+					handleQPACKStream(c.conn, str, errorQPACKEncoderStreamError, c.decoder.HandleEncoderStream)
+				}
+				return
+			case streamTypeQPACKDecoderStream:
+				if c.encoder != nil {
+					handleQPACKStream(c.conn, str, errorQPACKDecoderStreamError, c.encoder.HandleDecoderStream)
+				}
 				return
 			case streamTypePushStream:
 				// We never increased the Push ID, so we don't expect any push streams.
@@ -200,6 +238,8 @@ func (c *client) handleUnidirectionalStreams() {
 				str.CancelRead(quic.StreamErrorCode(errorStreamCreationError))
 				return
 			}
+			// The peer must send the control stream reliably.
+			str.SetReadSkippedAsError(true)
 			f, err := parseNextFrame(str, nil)
 			if err != nil {
 				c.conn.CloseWithError(quic.ApplicationErrorCode(errorFrameError), "")
@@ -210,19 +250,73 @@ func (c *client) handleUnidirectionalStreams() {
 				c.conn.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
-			}
+			c.encoder.SetPeerSettings(sf.QPACKMaxTableCapacity, sf.QPACKBlockedStreams)
 			// If datagram support was enabled on our side as well as on the server side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if c.opts.EnableDatagram && !c.conn.ConnectionState().SupportsDatagrams {
+			if sf.Datagram && c.opts.EnableDatagram && !c.conn.ConnectionState().SupportsDatagrams {
 				c.conn.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+				return
 			}
+			c.handleControlStream(str)
 		}(str)
 	}
 }
 
+// handleControlStream handles the frames that the server sends on the control stream after the SETTINGS frame.
+func (c *client) handleControlStream(str quic.ReceiveStream) {
+	for {
+		f, err := parseNextFrame(str, nil)
+		if err != nil {
+			if err == io.EOF {
+				c.conn.CloseWithError(quic.ApplicationErrorCode(errorClosedCriticalStream), "")
+			}
+			return
+		}
+		switch f := f.(type) {
+		case *goAwayFrame:
+			if err := c.handleGoAway(f.StreamID); err != nil {
+				c.conn.CloseWithError(quic.ApplicationErrorCode(errorIDError), err.Error())
+				return
+			}
+		default:
+			c.conn.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), fmt.Sprintf("unexpected frame on the control stream: %T", f))
+			return
+		}
+	}
+}
+
+func (c *client) handleGoAway(id quic.StreamID) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if id.Type() != protocol.StreamTypeBidi || id.InitiatedBy() != protocol.PerspectiveClient {
+		return fmt.Errorf("invalid stream ID in GOAWAY frame: %d", id)
+	}
+	// The server may send multiple GOAWAY frames, but it can't increase the stream ID.
+	if c.goingAway && id > c.goAwayStreamID {
+		return fmt.Errorf("GOAWAY frame increased the stream ID from %d to %d", c.goAwayStreamID, id)
+	}
+	c.goingAway = true
+	c.goAwayStreamID = id
+	return nil
+}
+
+// processedByServer says if the server still processes requests sent on the stream with the stream ID.
+func (c *client) processedByServer(id quic.StreamID) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return !c.goingAway || id < c.goAwayStreamID
+}
+
+// isGoingAway says if the server sent a GOAWAY frame.
+// No new requests should be sent on this connection.
+func (c *client) isGoingAway() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.goingAway
+}
+
 func (c *client) Close() error {
 	if c.conn == nil {
 		return nil
@@ -263,10 +357,18 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 		}
 	}
 
+	if c.isGoingAway() {
+		return nil, errGoAway
+	}
 	str, err := c.conn.OpenStreamSync(req.Context())
 	if err != nil {
 		return nil, err
 	}
+	if !c.processedByServer(str.StreamID()) {
+		str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+		str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
+		return nil, errGoAway
+	}
 
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTripOpt() returns.
@@ -279,6 +381,10 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 		case <-req.Context().Done():
 			str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
 			str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
+			// The server might have referenced dynamic table entries in a response header that is never decoded.
+			if c.decoder != nil {
+				c.decoder.CancelStream(str.StreamID())
+			}
 		case <-reqDone:
 		}
 	}()
@@ -302,6 +408,11 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 			}
 			c.conn.CloseWithError(quic.ApplicationErrorCode(rerr.connErr), reason)
 		}
+		// The server rejects requests it receives after sending the GOAWAY frame.
+		var serr *quic.StreamError
+		if errors.As(rerr.err, &serr) && serr.ErrorCode == quic.StreamErrorCode(errorRequestRejected) {
+			return nil, errGoAway
+		}
 	}
 	if opt.DontCloseRequestStream {
 		close(reqDone)
@@ -357,12 +468,87 @@ func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt,
 		go func() {
 			if err := c.sendRequestBody(hstr, req.Body); err != nil {
 				c.logger.Errorf("Error writing request: %s", err)
+			} else if len(req.Trailer) > 0 {
+				if err := c.requestWriter.WriteRequestTrailer(str, req); err != nil {
+					c.logger.Errorf("Error writing request trailers: %s", err)
+				}
 			}
 			if !opt.DontCloseRequestStream {
 				hstr.Close()
 			}
 		}()
 	}
+	// Interim responses (1xx) are followed by further HEADERS frames, until the final response is received.
+	trace := httptrace.ContextClientTrace(req.Context())
+	var num1xx int
+	var res *http.Response
+	for {
+		var rerr requestError
+		res, rerr = c.readResponseHeader(str)
+		if rerr.err != nil {
+			return nil, rerr
+		}
+		if res.StatusCode < 100 || res.StatusCode >= 200 {
+			break
+		}
+		num1xx++
+		if trace != nil && trace.Got1xxResponse != nil {
+			if err := trace.Got1xxResponse(res.StatusCode, textproto.MIMEHeader(res.Header)); err != nil {
+				return nil, newStreamError(errorRequestCanceled, err)
+			}
+		} else if num1xx > max1xxResponses {
+			return nil, newStreamError(errorExcessiveLoad, errors.New("too many 1xx informational responses"))
+		}
+	}
+	// Declared trailers are announced with nil values, see http.Response.Trailer.
+	for _, key := range declaredTrailers(res.Header) {
+		if res.Trailer == nil {
+			res.Trailer = make(http.Header)
+		}
+		res.Trailer[key] = nil
+	}
+	res.Header.Del("Trailer")
+	hstr.maxTrailerBytes = c.maxHeaderBytes()
+	hstr.decoder = c.decoder
+	hstr.onTrailers = func(hfs []qpack.HeaderField) {
+		if res.Trailer == nil {
+			res.Trailer = make(http.Header)
+		}
+		for _, hf := range hfs {
+			res.Trailer.Add(hf.Name, hf.Value)
+		}
+	}
+	respBody := newResponseBody(hstr, c.conn, reqDone)  
+	
+	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
+	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
+	isInformational := res.StatusCode >= 100 && res.StatusCode < 200
+	isNoContent := res.StatusCode == 204
+	isSuccessfulConnect := req.Method == http.MethodConnect && res.StatusCode >= 200 && res.StatusCode < 300
+	if !hasTransferEncoding && !isInformational && !isNoContent && !isSuccessfulConnect {
+		res.ContentLength = -1
+		if clens, ok := res.Header["Content-Length"]; ok && len(clens) == 1 {
+			if clen64, err := strconv.ParseInt(clens[0], 10, 64); err == nil {
+				res.ContentLength = clen64
+			}
+		}
+	}
+	
+	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = newGzipReader(respBody)
+		res.Uncompressed = true
+	} else {
+		res.Body = respBody
+	}
+	return res, requestError{}
+}
+
+// readResponseHeader reads a HEADERS frame and parses it into a response.
+// The response body is not set.
+func (c *client) readResponseHeader(str quic.Stream) (*http.Response, requestError) {
 	frame, err := parseNextFrame(str, nil)
 	if err != nil {
 		return nil, newStreamError(errorFrameError, err)
@@ -378,10 +564,9 @@ func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt,
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
 		return nil, newStreamError(errorRequestIncomplete, err)
 	}
-	hfs, err := c.decoder.DecodeFull(headerBlock)
+	hfs, err := c.decoder.Decode(str, headerBlock)
 	if err != nil {
-		// TODO: use the right error code
-		return nil, newConnError(errorGeneralProtocolError, err)
+		return nil, newConnError(errorQPACKDecompressionFailed, err)
 	}
 
 	connState := qtls.ToTLSConnectionState(c.conn.ConnectionState().TLS)
@@ -404,30 +589,5 @@ func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt,
 			res.Header.Add(hf.Name, hf.Value)
 		}
 	}
-	respBody := newResponseBody(hstr, c.conn, reqDone)  
-	
-	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
-	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
-	isInformational := res.StatusCode >= 100 && res.StatusCode < 200
-	isNoContent := res.StatusCode == 204
-	isSuccessfulConnect := req.Method == http.MethodConnect && res.StatusCode >= 200 && res.StatusCode < 300
-	if !hasTransferEncoding && !isInformational && !isNoContent && !isSuccessfulConnect {
-		res.ContentLength = -1
-		if clens, ok := res.Header["Content-Length"]; ok && len(clens) == 1 {
-			if clen64, err := strconv.ParseInt(clens[0], 10, 64); err == nil {
-				res.ContentLength = clen64
-			}
-		}
-	}
-	
-	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
-		res.Header.Del("Content-Encoding")
-		res.Header.Del("Content-Length")
-		res.ContentLength = -1
-		res.Body = newGzipReader(respBody)
-		res.Uncompressed = true
-	} else {
-		res.Body = respBody
-	}
 	return res, requestError{}
 }
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
@@ -201,6 +203,7 @@ var _ = Describe("Client", func() {
 				close(settingsFrameWritten)
 			})
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+			controlStr.EXPECT().DisablePR()
 			conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 			conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
 			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, errors.New("done"))
@@ -330,6 +333,7 @@ var _ = Describe("Client", func() {
 				close(settingsFrameWritten)
 			})
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+			controlStr.EXPECT().DisablePR()
 			conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 			conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
 			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, errors.New("done"))
@@ -438,6 +442,7 @@ var _ = Describe("Client", func() {
 				close(settingsFrameWritten)
 			})
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+			controlStr.EXPECT().DisablePR()
 			conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 			conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
 			conn.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, errors.New("done"))
@@ -457,9 +462,10 @@ var _ = Describe("Client", func() {
 		It("parses the SETTINGS frame", func() {
 			b := quicvarint.Append(nil, streamTypeControlStream)
 			b = (&settingsFrame{}).Append(b)
-			r := bytes.NewReader(b)
+			r := io.MultiReader(bytes.NewReader(b), errReader{err: errors.New("connection closed")})
 			controlStr := mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+			controlStr.EXPECT().SetReadSkippedAsError(true)
 			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				return controlStr, nil
 			})
@@ -526,6 +532,7 @@ var _ = Describe("Client", func() {
 			r := bytes.NewReader(b)
 			controlStr := mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+			controlStr.EXPECT().SetReadSkippedAsError(true)
 			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				return controlStr, nil
 			})
@@ -550,6 +557,7 @@ var _ = Describe("Client", func() {
 			r := bytes.NewReader(b[:len(b)-1])
 			controlStr := mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+			controlStr.EXPECT().SetReadSkippedAsError(true)
 			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				return controlStr, nil
 			})
@@ -568,6 +576,114 @@ var _ = Describe("Client", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		Context("GOAWAY frames", func() {
+			// sendControlFrames makes the server open the control stream and send the SETTINGS frame, followed by frames
+			sendControlFrames := func(frames ...[]byte) {
+				b := quicvarint.Append(nil, streamTypeControlStream)
+				b = (&settingsFrame{}).Append(b)
+				for _, f := range frames {
+					b = append(b, f...)
+				}
+				r := io.MultiReader(bytes.NewReader(b), errReader{err: errors.New("connection closed")})
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+				controlStr.EXPECT().SetReadSkippedAsError(true)
+				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					return controlStr, nil
+				})
+				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+			}
+
+			It("doesn't send new requests after receiving a GOAWAY frame", func() {
+				sendControlFrames((&goAwayFrame{StreamID: 8}).Append(nil))
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("done"))
+				Eventually(client.isGoingAway).Should(BeTrue())
+				Expect(client.processedByServer(4)).To(BeTrue())
+				Expect(client.processedByServer(8)).To(BeFalse())
+				// don't EXPECT any calls to OpenStreamSync
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				_, err = client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError(errGoAway))
+			})
+
+			It("accepts GOAWAY frames that decrease the stream ID", func() {
+				sendControlFrames((&goAwayFrame{StreamID: 8}).Append(nil), (&goAwayFrame{StreamID: 4}).Append(nil))
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("done"))
+				Eventually(func() bool { return client.processedByServer(4) }).Should(BeFalse())
+			})
+
+			It("errors when the GOAWAY frame increases the stream ID", func() {
+				sendControlFrames((&goAwayFrame{StreamID: 4}).Append(nil), (&goAwayFrame{StreamID: 8}).Append(nil))
+				done := make(chan struct{})
+				conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorIDError))
+					Expect(reason).To(Equal("GOAWAY frame increased the stream ID from 4 to 8"))
+					close(done)
+				})
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("done"))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("errors when the GOAWAY frame contains an invalid stream ID", func() {
+				sendControlFrames((&goAwayFrame{StreamID: 2}).Append(nil))
+				done := make(chan struct{})
+				conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorIDError))
+					Expect(reason).To(Equal("invalid stream ID in GOAWAY frame: 2"))
+					close(done)
+				})
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("done"))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("errors on unexpected frames on the control stream", func() {
+				sendControlFrames((&dataFrame{}).Append(nil))
+				done := make(chan struct{})
+				conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, _ string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorFrameUnexpected))
+					close(done)
+				})
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("done"))
+				Eventually(done).Should(BeClosed())
+			})
+		})
+
+		It("errors when the server closes the control stream", func() {
+			b := quicvarint.Append(nil, streamTypeControlStream)
+			b = (&settingsFrame{}).Append(b)
+			r := bytes.NewReader(b)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+			controlStr.EXPECT().SetReadSkippedAsError(true)
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, _ string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorClosedCriticalStream))
+				close(done)
+			})
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("errors when parsing the server opens a push stream", func() {
 			buf := &bytes.Buffer{}
 			quicvarint.Write(buf, streamTypePushStream)
@@ -598,6 +714,7 @@ var _ = Describe("Client", func() {
 			r := bytes.NewReader(b)
 			controlStr := mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+			controlStr.EXPECT().SetReadSkippedAsError(true)
 			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				return controlStr, nil
 			})
@@ -663,6 +780,7 @@ var _ = Describe("Client", func() {
 			buf := &bytes.Buffer{}
 			rstr := mockquic.NewMockStream(mockCtrl)
 			rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
+			rstr.EXPECT().EffectivePRPolicy().AnyTimes()
 			rw := newResponseWriter(rstr, nil, utils.DefaultLogger)
 			rw.WriteHeader(status)
 			rw.Flush()
@@ -681,7 +799,9 @@ var _ = Describe("Client", func() {
 				close(settingsFrameWritten)
 			}) // SETTINGS frame
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
+			controlStr.EXPECT().DisablePR()
 			conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				<-testDone
@@ -743,6 +863,97 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("returns the trailers", func() {
+			b := getHeadersFrame(map[string]string{
+				":status": "200",
+				"trailer": "pr-skipped-ranges",
+			})
+			b = (&dataFrame{Length: 0x6}).Append(b)
+			b = append(b, []byte("foobar")...)
+			b = append(b, getHeadersFrame(map[string]string{"pr-skipped-ranges": "2-3"})...)
+			rspBuf := bytes.NewBuffer(b)
+			gomock.InOrder(
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{}),
+			)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			rsp, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Header).ToNot(HaveKey("Trailer"))
+			Expect(rsp.Trailer).To(Equal(http.Header{PRSkippedRangesTrailer: nil}))
+			data, err := io.ReadAll(rsp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("foobar"))
+			Expect(rsp.Trailer.Get(PRSkippedRangesTrailer)).To(Equal("2-3"))
+		})
+
+		Context("informational responses", func() {
+			BeforeEach(func() {
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{}).AnyTimes()
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+			})
+
+			It("skips 1xx responses", func() {
+				b := getHeadersFrame(map[string]string{":status": "103", "link": "</manifest.mpd>; rel=preload"})
+				b = append(b, getResponse(200)...)
+				rspBuf := bytes.NewBuffer(b)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(rsp.Header).ToNot(HaveKey("Link"))
+			})
+
+			It("passes 1xx responses to the Got1xxResponse hook", func() {
+				b := getHeadersFrame(map[string]string{":status": "103", "link": "</manifest.mpd>; rel=preload"})
+				b = append(b, getHeadersFrame(map[string]string{":status": "103", "link": "</init.mp4>; rel=preload"})...)
+				b = append(b, getResponse(200)...)
+				rspBuf := bytes.NewBuffer(b)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				var links []string
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+						Expect(code).To(Equal(http.StatusEarlyHints))
+						links = append(links, header.Get("Link"))
+						return nil
+					},
+				}
+				rsp, err := client.RoundTripOpt(req.WithContext(httptrace.WithClientTrace(context.Background(), trace)), RoundTripOpt{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(links).To(Equal([]string{"</manifest.mpd>; rel=preload", "</init.mp4>; rel=preload"}))
+			})
+
+			It("aborts the request if the Got1xxResponse hook returns an error", func() {
+				rspBuf := bytes.NewBuffer(getResponse(103))
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(int, textproto.MIMEHeader) error { return errors.New("no hints, please") },
+				}
+				_, err := client.RoundTripOpt(req.WithContext(httptrace.WithClientTrace(context.Background(), trace)), RoundTripOpt{})
+				Expect(err).To(MatchError("no hints, please"))
+			})
+
+			It("limits the number of 1xx responses", func() {
+				var b []byte
+				for i := 0; i <= max1xxResponses; i++ {
+					b = append(b, getResponse(103)...)
+				}
+				rspBuf := bytes.NewBuffer(b)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorExcessiveLoad))
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("too many 1xx informational responses"))
+			})
+		})
+
 		Context("requests containing a Body", func() {
 			var strBuf *bytes.Buffer
 
@@ -778,6 +989,28 @@ var _ = Describe("Client", func() {
 				Expect(hfs).To(HaveKeyWithValue(":path", "/upload"))
 			})
 
+			It("sends the trailers after the body", func() {
+				req.Trailer = http.Header{"Checksum": []string{"deadbeef"}}
+				req.Body.(*mockBody).SetData([]byte("foobar"))
+				done := make(chan struct{})
+				gomock.InOrder(
+					str.EXPECT().Close().Do(func() { close(done) }),
+					str.EXPECT().CancelWrite(gomock.Any()).MaxTimes(1), // when reading the response errors
+				)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+					<-done
+					return 0, errors.New("test done")
+				})
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("test done"))
+				Expect(decodeHeader(strBuf)).To(HaveKeyWithValue("trailer", "Checksum"))
+				frame, err := parseNextFrame(strBuf, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&dataFrame{Length: 6}))
+				strBuf.Next(6)
+				Expect(decodeHeader(strBuf)).To(Equal(map[string]string{"checksum": "deadbeef"}))
+			})
+
 			It("returns the error that occurred when reading the body", func() {
 				req.Body.(*mockBody).readErr = errors.New("testErr")
 				done := make(chan struct{})
@@ -832,6 +1065,16 @@ var _ = Describe("Client", func() {
 				Eventually(closed).Should(BeClosed())
 			})
 
+			It("returns errGoAway when the server rejects the request", func() {
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorFrameError))
+				closed := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				str.EXPECT().Read(gomock.Any()).Return(0, &quic.StreamError{StreamID: 4, ErrorCode: quic.StreamErrorCode(errorRequestRejected)}).AnyTimes()
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError(errGoAway))
+				Eventually(closed).Should(BeClosed())
+			})
+
 			It("cancels the stream when the HEADERS frame is too large", func() {
 				b := (&headersFrame{Length: 1338}).Append(nil)
 				r := bytes.NewReader(b)
@@ -974,6 +1217,7 @@ var _ = Describe("Client", func() {
 				buf := &bytes.Buffer{}
 				rstr := mockquic.NewMockStream(mockCtrl)
 				rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
+				rstr.EXPECT().EffectivePRPolicy().AnyTimes()
 				rw := newResponseWriter(rstr, nil, utils.DefaultLogger)
 				rw.Header().Set("Content-Encoding", "gzip")
 				gz := gzip.NewWriter(rw)
@@ -1000,6 +1244,7 @@ var _ = Describe("Client", func() {
 				buf := &bytes.Buffer{}
 				rstr := mockquic.NewMockStream(mockCtrl)
 				rstr.EXPECT().Write(gomock.Any()).Do(buf.Write).AnyTimes()
+				rstr.EXPECT().EffectivePRPolicy().AnyTimes()
 				rw := newResponseWriter(rstr, nil, utils.DefaultLogger)
 				rw.Write([]byte("not gzipped"))
 				rw.Flush()
@@ -27,6 +27,10 @@ const (
 	errorConnectError         errorCode = 0x10f
 	errorVersionFallback      errorCode = 0x110
 	errorDatagramError        errorCode = 0x4a1268
+
+	errorQPACKDecompressionFailed errorCode = 0x200
+	errorQPACKEncoderStreamError  errorCode = 0x201
+	errorQPACKDecoderStreamError  errorCode = 0x202
 )
 
 func (e errorCode) String() string {
@@ -65,6 +69,12 @@ func (e errorCode) String() string {
 		return "H3_CONNECT_ERROR"
 	case errorVersionFallback:
 		return "H3_VERSION_FALLBACK"
+	case errorQPACKDecompressionFailed:
+		return "QPACK_DECOMPRESSION_FAILED"
+	case errorQPACKEncoderStreamError:
+		return "QPACK_ENCODER_STREAM_ERROR"
+	case errorQPACKDecoderStreamError:
+		return "QPACK_DECODER_STREAM_ERROR"
 	case errorDatagramError:
 		return "H3_DATAGRAM_ERROR"
 	default:
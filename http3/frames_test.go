@@ -106,7 +106,7 @@ var _ = Describe("Frames", func() {
 
 		It("writes", func() {
 			sf := &settingsFrame{Other: map[uint64]uint64{
-				1:  2,
+				3:  2,
 				99: 999,
 				13: 37,
 			}}
@@ -176,6 +176,123 @@ var _ = Describe("Frames", func() {
 				Expect(frame).To(Equal(sf))
 			})
 		})
+
+		Context("SETTINGS_ENABLE_CONNECT_PROTOCOL", func() {
+			It("reads the SETTINGS_ENABLE_CONNECT_PROTOCOL value", func() {
+				settings := appendVarInt(nil, settingExtendedConnect)
+				settings = appendVarInt(settings, 1)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				f, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(Equal(&settingsFrame{ExtendedConnect: true}))
+			})
+
+			It("rejects duplicate SETTINGS_ENABLE_CONNECT_PROTOCOL entries", func() {
+				settings := appendVarInt(nil, settingExtendedConnect)
+				settings = appendVarInt(settings, 1)
+				settings = appendVarInt(settings, settingExtendedConnect)
+				settings = appendVarInt(settings, 1)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				_, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).To(MatchError(fmt.Sprintf("duplicate setting: %d", settingExtendedConnect)))
+			})
+
+			It("rejects invalid values for the SETTINGS_ENABLE_CONNECT_PROTOCOL entry", func() {
+				settings := appendVarInt(nil, settingExtendedConnect)
+				settings = appendVarInt(settings, 2)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				_, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).To(MatchError("invalid value for SETTINGS_ENABLE_CONNECT_PROTOCOL: 2"))
+			})
+
+			It("writes the SETTINGS_ENABLE_CONNECT_PROTOCOL setting", func() {
+				sf := &settingsFrame{ExtendedConnect: true}
+				frame, err := parseNextFrame(bytes.NewReader(sf.Append(nil)), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(sf))
+			})
+		})
+
+		Context("QPACK", func() {
+			It("reads the QPACK settings", func() {
+				settings := appendVarInt(nil, settingQPACKMaxTableCapacity)
+				settings = appendVarInt(settings, 4096)
+				settings = appendVarInt(settings, settingQPACKBlockedStreams)
+				settings = appendVarInt(settings, 16)
+				data := appendVarInt(nil, 4) // type byte
+				data = appendVarInt(data, uint64(len(settings)))
+				data = append(data, settings...)
+				f, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				sf := f.(*settingsFrame)
+				Expect(sf.QPACKMaxTableCapacity).To(BeEquivalentTo(4096))
+				Expect(sf.QPACKBlockedStreams).To(BeEquivalentTo(16))
+				Expect(sf.Other).To(BeEmpty())
+			})
+
+			It("rejects duplicate QPACK settings", func() {
+				for _, id := range []uint64{settingQPACKMaxTableCapacity, settingQPACKBlockedStreams} {
+					settings := appendVarInt(nil, id)
+					settings = appendVarInt(settings, 1)
+					settings = appendVarInt(settings, id)
+					settings = appendVarInt(settings, 2)
+					data := appendVarInt(nil, 4) // type byte
+					data = appendVarInt(data, uint64(len(settings)))
+					data = append(data, settings...)
+					_, err := parseNextFrame(bytes.NewReader(data), nil)
+					Expect(err).To(MatchError(fmt.Sprintf("duplicate setting: %d", id)))
+				}
+			})
+
+			It("writes the QPACK settings", func() {
+				sf := &settingsFrame{QPACKMaxTableCapacity: 4096, QPACKBlockedStreams: 16}
+				frame, err := parseNextFrame(bytes.NewReader(sf.Append(nil)), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(sf))
+			})
+		})
+	})
+
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(100)))
+			data = appendVarInt(data, 100)
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 100}))
+		})
+
+		It("writes", func() {
+			b := (&goAwayFrame{StreamID: 1337}).Append(nil)
+			frame, err := parseNextFrame(bytes.NewReader(b), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 1337}))
+		})
+
+		It("errors on frames with trailing data", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 3)
+			data = appendVarInt(data, 4)
+			data = append(data, 0, 0)
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).To(MatchError("unexpected size for GOAWAY frame: 3"))
+		})
+
+		It("errors on EOF", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 2)
+			data = appendVarInt(data, 1000) // a 2 byte varint
+			_, err := parseNextFrame(bytes.NewReader(data[:len(data)-1]), nil)
+			Expect(err).To(MatchError(io.EOF))
+		})
 	})
 
 	Context("hijacking", func() {
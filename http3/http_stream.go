@@ -2,8 +2,13 @@ package http3
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 
 	"github.com/lucas-clemente/quic-go"
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http/httpguts"
 )
 
 // A Stream is a HTTP/3 stream.
@@ -19,6 +24,13 @@ type stream struct {
 
 	onFrameError          func()
 	bytesRemainingInFrame uint64
+
+	// onTrailers is called with the trailer fields, when a HEADERS frame is received after the DATA frames.
+	// If nil, the HEADERS frame is skipped.
+	onTrailers      func([]qpack.HeaderField)
+	maxTrailerBytes uint64
+	// decoder decodes the trailers. If nil, only the QPACK static table is used.
+	decoder *qpackDecoder
 }
 
 var _ Stream = &stream{}
@@ -41,7 +53,9 @@ func (s *stream) Read(b []byte) (int, error) {
 			}
 			switch f := frame.(type) {
 			case *headersFrame:
-				// skip HEADERS frames
+				if err := s.readTrailers(f.Length); err != nil {
+					return 0, err
+				}
 				continue
 			case *dataFrame:
 				s.bytesRemainingInFrame = f.Length
@@ -66,6 +80,26 @@ func (s *stream) Read(b []byte) (int, error) {
 	return n, err
 }
 
+func (s *stream) readTrailers(length uint64) error {
+	if s.onTrailers == nil {
+		_, err := io.CopyN(io.Discard, s.Stream, int64(length))
+		return err
+	}
+	if length > s.maxTrailerBytes {
+		return fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", length, s.maxTrailerBytes)
+	}
+	headerBlock := make([]byte, length)
+	if _, err := io.ReadFull(s.Stream, headerBlock); err != nil {
+		return err
+	}
+	hfs, err := s.decoder.Decode(s.Stream, headerBlock)
+	if err != nil {
+		return err
+	}
+	s.onTrailers(hfs)
+	return nil
+}
+
 func (s *stream) Write(b []byte) (int, error) {
 	s.buf = s.buf[:0]
 	s.buf = (&dataFrame{Length: uint64(len(b))}).Append(s.buf)
@@ -74,3 +108,18 @@ func (s *stream) Write(b []byte) (int, error) {
 	}
 	return s.Stream.Write(b)
 }
+
+// declaredTrailers returns the canonicalized names of the trailers announced in the Trailer header.
+// Fields that are not allowed in trailers are ignored.
+func declaredTrailers(h http.Header) []string {
+	var trailers []string
+	for _, v := range h["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if key != "" && httpguts.ValidTrailerHeader(key) {
+				trailers = append(trailers, key)
+			}
+		}
+	}
+	return trailers
+}
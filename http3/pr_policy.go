@@ -0,0 +1,65 @@
+package http3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// PRPolicyHeader is the request header a client uses to ask for a partially reliable policy (see quic.PRPolicy)
+// for the response. The server only uses it if Server.EnablePRPolicyHeader is set.
+// Its value is one of:
+//   - "reliable"
+//   - "abandon"
+//   - "probability=N", N being the retransmission probability in 1/10000
+//   - "times=N", N being the maximum number of retransmissions
+//   - "deadline=D", D being a duration as parsed by time.ParseDuration, e.g. "deadline=200ms"
+//   - "priority=N", N being the priority of the content
+//
+// Use ParsePRPolicy to parse the value.
+const PRPolicyHeader = "Pr-Policy"
+
+// ParsePRPolicy parses the value of the PRPolicyHeader.
+func ParsePRPolicy(value string) (quic.PRPolicy, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "reliable":
+		return quic.PRPolicy{}, nil
+	case "abandon":
+		return quic.PRPolicy{PTDA: quic.PTDAAbandon}, nil
+	}
+	kind, param, ok := strings.Cut(value, "=")
+	if !ok {
+		return quic.PRPolicy{}, fmt.Errorf("invalid PR policy: %q", value)
+	}
+	kind, param = strings.TrimSpace(kind), strings.TrimSpace(param)
+	if kind == "deadline" {
+		d, err := time.ParseDuration(param)
+		if err != nil || d <= 0 {
+			return quic.PRPolicy{}, fmt.Errorf("invalid PR policy deadline: %q", param)
+		}
+		// round up to the next millisecond
+		return quic.PRPolicy{PTDA: quic.PTDADeadline, Value: uint64((d + time.Millisecond - 1) / time.Millisecond)}, nil
+	}
+	v, err := strconv.ParseUint(param, 10, 64)
+	if err != nil || v > quicvarint.Max {
+		return quic.PRPolicy{}, fmt.Errorf("invalid PR policy value: %q", param)
+	}
+	switch kind {
+	case "probability":
+		if v > 10000 {
+			return quic.PRPolicy{}, fmt.Errorf("invalid PR policy probability: %d (maximum 10000)", v)
+		}
+		return quic.PRPolicy{PTDA: quic.PTDAProbability, Value: v}, nil
+	case "times":
+		return quic.PRPolicy{PTDA: quic.PTDATimes, Value: v}, nil
+	case "priority":
+		return quic.PRPolicy{PTDA: quic.PTDAPriority, Value: v}, nil
+	default:
+		return quic.PRPolicy{}, fmt.Errorf("invalid PR policy: %q", value)
+	}
+}
@@ -0,0 +1,45 @@
+package http3
+
+import (
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR Policy Header", func() {
+	It("parses policies", func() {
+		for value, policy := range map[string]quic.PRPolicy{
+			"reliable":          {},
+			"abandon":           {PTDA: quic.PTDAAbandon},
+			"probability=3000":  {PTDA: quic.PTDAProbability, Value: 3000},
+			"times=2":           {PTDA: quic.PTDATimes, Value: 2},
+			"deadline=200ms":    {PTDA: quic.PTDADeadline, Value: 200},
+			"deadline=1s":       {PTDA: quic.PTDADeadline, Value: 1000},
+			"deadline=1500us":   {PTDA: quic.PTDADeadline, Value: 2},
+			"priority=5":        {PTDA: quic.PTDAPriority, Value: 5},
+			" Deadline = 10ms ": {PTDA: quic.PTDADeadline, Value: 10},
+		} {
+			p, err := ParsePRPolicy(value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p).To(Equal(policy))
+		}
+	})
+
+	It("rejects invalid policies", func() {
+		for _, value := range []string{
+			"",
+			"foo",
+			"foo=1",
+			"times",
+			"times=-1",
+			"times=4611686018427387904", // larger than quicvarint.Max
+			"probability=10001",
+			"deadline=200",
+			"deadline=0ms",
+		} {
+			_, err := ParsePRPolicy(value)
+			Expect(err).To(HaveOccurred(), value)
+		}
+	})
+})
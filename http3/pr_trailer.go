@@ -0,0 +1,96 @@
+package http3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// PRSkippedRangesTrailer is the trailer the server sends when the response body was sent on a stream
+// with a partially reliable policy (see quic.PRPolicy).
+// It lists the ranges of the response body that were skipped, and that the client therefore read as zeros,
+// such that HTTP clients learn which parts of the body are missing without access to the QUIC stream.
+// The ranges are given as inclusive byte offsets into the body as sent by the handler,
+// in the same format as a HTTP Range header without the unit, e.g. "100-199, 500-549".
+// An empty value means that the whole body was delivered.
+// Use ParsePRSkippedRanges to parse the value.
+const PRSkippedRangesTrailer = "Pr-Skipped-Ranges"
+
+// ParsePRSkippedRanges parses the value of the PRSkippedRangesTrailer.
+func ParsePRSkippedRanges(value string) ([]quic.ByteRange, error) {
+	var ranges []quic.ByteRange
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		i := strings.IndexByte(s, '-')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid skipped range: %q", s)
+		}
+		first, err := strconv.ParseUint(s[:i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid skipped range: %q", s)
+		}
+		last, err := strconv.ParseUint(s[i+1:], 10, 64)
+		if err != nil || last < first {
+			return nil, fmt.Errorf("invalid skipped range: %q", s)
+		}
+		ranges = append(ranges, quic.ByteRange{Start: first, End: last + 1})
+	}
+	return ranges, nil
+}
+
+func formatPRSkippedRanges(ranges []quic.ByteRange) string {
+	var b strings.Builder
+	for i, r := range ranges {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strconv.FormatUint(r.Start, 10))
+		b.WriteByte('-')
+		b.WriteString(strconv.FormatUint(r.End-1, 10))
+	}
+	return b.String()
+}
+
+// A bodySegment is the payload of a DATA frame of the response body.
+type bodySegment struct {
+	offset     uint64 // offset of the payload in the stream
+	bodyOffset uint64 // offset of the payload in the response body
+	length     uint64
+}
+
+// skippedBodyRanges returns the ranges of the response body that are not covered by the acknowledged stream data.
+// Adjacent ranges are merged, even if they are separated by a frame header in the stream.
+func skippedBodyRanges(segments []bodySegment, acked []quic.ByteRange) []quic.ByteRange {
+	var skipped []quic.ByteRange
+	add := func(seg bodySegment, start, end uint64) {
+		start, end = seg.bodyOffset+start-seg.offset, seg.bodyOffset+end-seg.offset
+		if n := len(skipped); n > 0 && skipped[n-1].End == start {
+			skipped[n-1].End = end
+			return
+		}
+		skipped = append(skipped, quic.ByteRange{Start: start, End: end})
+	}
+	var i int
+	for _, seg := range segments {
+		pos, end := seg.offset, seg.offset+seg.length
+		for pos < end {
+			for i < len(acked) && acked[i].End <= pos {
+				i++
+			}
+			if i == len(acked) || acked[i].Start >= end {
+				add(seg, pos, end)
+				break
+			}
+			if acked[i].Start > pos {
+				add(seg, pos, acked[i].Start)
+			}
+			pos = acked[i].End
+		}
+	}
+	return skipped
+}
@@ -0,0 +1,66 @@
+package http3
+
+import (
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR Skipped Ranges Trailer", func() {
+	It("formats and parses the skipped ranges", func() {
+		ranges := []quic.ByteRange{{Start: 0, End: 1}, {Start: 100, End: 200}}
+		value := formatPRSkippedRanges(ranges)
+		Expect(value).To(Equal("0-0, 100-199"))
+		parsed, err := ParsePRSkippedRanges(value)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal(ranges))
+	})
+
+	It("parses an empty value", func() {
+		Expect(formatPRSkippedRanges(nil)).To(BeEmpty())
+		parsed, err := ParsePRSkippedRanges("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(BeEmpty())
+	})
+
+	It("rejects invalid ranges", func() {
+		for _, value := range []string{"foo", "1-", "-1", "10-9"} {
+			_, err := ParsePRSkippedRanges(value)
+			Expect(err).To(MatchError("invalid skipped range: \"" + value + "\""))
+		}
+	})
+
+	Context("calculating the skipped ranges of the body", func() {
+		// two DATA frames, with a 2 byte frame header before each of them
+		segments := []bodySegment{
+			{offset: 12, bodyOffset: 0, length: 10},
+			{offset: 24, bodyOffset: 10, length: 10},
+		}
+
+		It("doesn't report anything if all data was acknowledged", func() {
+			Expect(skippedBodyRanges(segments, []quic.ByteRange{{Start: 0, End: 34}})).To(BeEmpty())
+		})
+
+		It("reports the whole body if nothing was acknowledged", func() {
+			Expect(skippedBodyRanges(segments, nil)).To(Equal([]quic.ByteRange{{Start: 0, End: 20}}))
+		})
+
+		It("ignores the frame headers", func() {
+			Expect(skippedBodyRanges(segments, []quic.ByteRange{{Start: 0, End: 12}, {Start: 22, End: 24}})).To(Equal([]quic.ByteRange{{Start: 0, End: 20}}))
+		})
+
+		It("reports gaps", func() {
+			acked := []quic.ByteRange{
+				{Start: 0, End: 14},
+				{Start: 16, End: 26},
+				{Start: 30, End: 32},
+			}
+			Expect(skippedBodyRanges(segments, acked)).To(Equal([]quic.ByteRange{
+				{Start: 2, End: 4},
+				{Start: 12, End: 16},
+				{Start: 18, End: 20},
+			}))
+		})
+	})
+})
@@ -0,0 +1,433 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+)
+
+// A qpackDecoder decodes the field sections received on the streams of a connection.
+// It maintains the dynamic table, using the instructions the peer sends on the encoder stream (RFC 9204, section 2.2).
+// A nil qpackDecoder only supports the static table.
+type qpackDecoder struct {
+	conn quic.Connection
+	// the SETTINGS_QPACK_MAX_TABLE_CAPACITY and SETTINGS_QPACK_BLOCKED_STREAMS sent to the peer
+	maxCapacity       uint64
+	maxBlockedStreams uint64
+
+	mutex sync.Mutex
+	table qpackDynamicTable
+	// the number of insertions the peer knows that we received, see RFC 9204, section 2.2.2.3
+	knownReceivedCount uint64
+	numBlockedStreams  uint64
+	// closed (and replaced) when entries are inserted
+	inserted chan struct{}
+	// the decoder instructions that weren't sent yet
+	instructions []byte
+	// set when handling the encoder stream
+	handlingEncoderStream bool
+
+	// serializes the writes to the decoder stream
+	writeMutex sync.Mutex
+	str        quic.SendStream
+}
+
+func newQPACKDecoder(conn quic.Connection, maxCapacity, maxBlockedStreams uint64) *qpackDecoder {
+	return &qpackDecoder{
+		conn:              conn,
+		maxCapacity:       maxCapacity,
+		maxBlockedStreams: maxBlockedStreams,
+		inserted:          make(chan struct{}),
+	}
+}
+
+// Decode decodes a field section received on str.
+// If the field section references dynamic table entries that weren't received yet, it blocks until they are.
+func (d *qpackDecoder) Decode(str quic.Stream, b []byte) ([]qpack.HeaderField, error) {
+	if d == nil || len(b) == 0 {
+		return qpack.NewDecoder(nil).DecodeFull(b)
+	}
+	r := bytes.NewReader(b)
+	encodedInsertCount, err := readQPACKPrefixedInt(r, 8)
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	deltaBase, err := readQPACKInt(r, first, 7)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	requiredInsertCount, err := d.decodeRequiredInsertCount(encodedInsertCount)
+	if err != nil {
+		d.mutex.Unlock()
+		return nil, err
+	}
+	base := requiredInsertCount + deltaBase
+	if first&0x80 > 0 {
+		if deltaBase >= requiredInsertCount {
+			d.mutex.Unlock()
+			return nil, fmt.Errorf("invalid Base: Required Insert Count %d, Delta Base %d", requiredInsertCount, deltaBase)
+		}
+		base = requiredInsertCount - deltaBase - 1
+	}
+	if err := d.waitForInsertions(requiredInsertCount); err != nil {
+		d.mutex.Unlock()
+		return nil, err
+	}
+	fields, err := d.decodeFieldLines(r, base, requiredInsertCount)
+	if err != nil {
+		d.mutex.Unlock()
+		return nil, err
+	}
+	if requiredInsertCount > 0 {
+		d.instructions = appendQPACKInt(d.instructions, 7, 0x80, uint64(str.StreamID())) // Section Acknowledgment
+		if requiredInsertCount > d.knownReceivedCount {
+			d.knownReceivedCount = requiredInsertCount
+		}
+	}
+	d.mutex.Unlock()
+	return fields, d.flush()
+}
+
+// decodeRequiredInsertCount decodes the Required Insert Count, see RFC 9204, section 4.5.1.1.
+func (d *qpackDecoder) decodeRequiredInsertCount(encoded uint64) (uint64, error) {
+	if encoded == 0 {
+		return 0, nil
+	}
+	maxEntries := d.maxCapacity / qpackEntryOverhead
+	fullRange := 2 * maxEntries
+	if encoded > fullRange {
+		return 0, fmt.Errorf("invalid Required Insert Count: %d", encoded)
+	}
+	maxValue := d.table.InsertCount() + maxEntries
+	maxWrapped := (maxValue / fullRange) * fullRange
+	requiredInsertCount := maxWrapped + encoded - 1
+	if requiredInsertCount > maxValue {
+		if requiredInsertCount <= fullRange {
+			return 0, fmt.Errorf("invalid Required Insert Count: %d", encoded)
+		}
+		requiredInsertCount -= fullRange
+	}
+	if requiredInsertCount == 0 {
+		return 0, fmt.Errorf("invalid Required Insert Count: %d", encoded)
+	}
+	return requiredInsertCount, nil
+}
+
+// waitForInsertions blocks until the dynamic table contains insertCount entries.
+// It must be called with the mutex held.
+func (d *qpackDecoder) waitForInsertions(insertCount uint64) error {
+	if insertCount <= d.table.InsertCount() {
+		return nil
+	}
+	if d.numBlockedStreams >= d.maxBlockedStreams {
+		return errors.New("too many blocked streams")
+	}
+	d.numBlockedStreams++
+	defer func() { d.numBlockedStreams-- }()
+	for insertCount > d.table.InsertCount() {
+		inserted := d.inserted
+		d.mutex.Unlock()
+		select {
+		case <-inserted:
+		case <-d.conn.Context().Done():
+			d.mutex.Lock()
+			return d.conn.Context().Err()
+		}
+		d.mutex.Lock()
+	}
+	return nil
+}
+
+// decodeFieldLines decodes the field line representations (RFC 9204, section 4.5).
+// It must be called with the mutex held.
+func (d *qpackDecoder) decodeFieldLines(r *bytes.Reader, base, requiredInsertCount uint64) ([]qpack.HeaderField, error) {
+	// the field section can't be longer than the HEADERS frame
+	maxLen := uint64(r.Len())
+	// get returns the dynamic table entry with the absolute index i
+	get := func(i uint64) (qpack.HeaderField, error) {
+		if i >= requiredInsertCount {
+			return qpack.HeaderField{}, fmt.Errorf("reference to entry %d beyond the Required Insert Count", i)
+		}
+		hf, ok := d.table.Get(i)
+		if !ok {
+			return qpack.HeaderField{}, fmt.Errorf("reference to evicted entry %d", i)
+		}
+		return hf, nil
+	}
+	// relative returns the absolute index for a relative index
+	relative := func(i uint64) (uint64, error) {
+		if i >= base {
+			return 0, fmt.Errorf("invalid relative index %d (Base %d)", i, base)
+		}
+		return base - 1 - i, nil
+	}
+
+	var fields []qpack.HeaderField
+	for r.Len() > 0 {
+		b, _ := r.ReadByte()
+		var hf qpack.HeaderField
+		switch {
+		case b&0x80 > 0: // Indexed Field Line
+			i, err := readQPACKInt(r, b, 6)
+			if err != nil {
+				return nil, err
+			}
+			if b&0x40 > 0 {
+				if i >= uint64(len(qpackStaticTable)) {
+					return nil, fmt.Errorf("invalid static index %d", i)
+				}
+				hf = qpackStaticTable[i]
+				break
+			}
+			if i, err = relative(i); err != nil {
+				return nil, err
+			}
+			if hf, err = get(i); err != nil {
+				return nil, err
+			}
+		case b&0xc0 == 0x40: // Literal Field Line with Name Reference
+			i, err := readQPACKInt(r, b, 4)
+			if err != nil {
+				return nil, err
+			}
+			if b&0x10 > 0 {
+				if i >= uint64(len(qpackStaticTable)) {
+					return nil, fmt.Errorf("invalid static index %d", i)
+				}
+				hf.Name = qpackStaticTable[i].Name
+			} else {
+				if i, err = relative(i); err != nil {
+					return nil, err
+				}
+				entry, err := get(i)
+				if err != nil {
+					return nil, err
+				}
+				hf.Name = entry.Name
+			}
+			if hf.Value, err = readQPACKPrefixedString(r, 7, maxLen); err != nil {
+				return nil, err
+			}
+		case b&0xe0 == 0x20: // Literal Field Line with Literal Name
+			var err error
+			if hf.Name, err = readQPACKString(r, b, 3, maxLen); err != nil {
+				return nil, err
+			}
+			if hf.Value, err = readQPACKPrefixedString(r, 7, maxLen); err != nil {
+				return nil, err
+			}
+		case b&0xf0 == 0x10: // Indexed Field Line with Post-Base Index
+			i, err := readQPACKInt(r, b, 4)
+			if err != nil {
+				return nil, err
+			}
+			if hf, err = get(base + i); err != nil {
+				return nil, err
+			}
+		default: // Literal Field Line with Post-Base Name Reference
+			i, err := readQPACKInt(r, b, 3)
+			if err != nil {
+				return nil, err
+			}
+			entry, err := get(base + i)
+			if err != nil {
+				return nil, err
+			}
+			hf.Name = entry.Name
+			if hf.Value, err = readQPACKPrefixedString(r, 7, maxLen); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, hf)
+	}
+	return fields, nil
+}
+
+// CancelStream is called when a stream is reset before its field sections were decoded.
+func (d *qpackDecoder) CancelStream(id quic.StreamID) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	d.instructions = appendQPACKInt(d.instructions, 6, 0x40, uint64(id)) // Stream Cancellation
+	d.mutex.Unlock()
+	d.flush()
+}
+
+// HandleEncoderStream handles the encoder instructions sent by the peer on the encoder stream (RFC 9204, section 4.3).
+// It returns when the stream is closed, or when the peer sends an invalid instruction.
+func (d *qpackDecoder) HandleEncoderStream(str io.Reader) error {
+	d.mutex.Lock()
+	if d.handlingEncoderStream {
+		d.mutex.Unlock()
+		return errors.New("duplicate QPACK encoder stream")
+	}
+	d.handlingEncoderStream = true
+	d.mutex.Unlock()
+
+	r := bufio.NewReader(str)
+	for {
+		if err := d.handleEncoderInstruction(r); err != nil {
+			return err
+		}
+		// Acknowledge the insertions once all instructions received so far were processed.
+		if r.Buffered() == 0 {
+			d.mutex.Lock()
+			if n := d.table.InsertCount() - d.knownReceivedCount; n > 0 {
+				d.instructions = appendQPACKInt(d.instructions, 6, 0, n) // Insert Count Increment
+				d.knownReceivedCount += n
+			}
+			d.mutex.Unlock()
+			if err := d.flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *qpackDecoder) handleEncoderInstruction(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	// No entry is larger than the table, so neither its name nor its value can be longer than the table capacity.
+	maxLen := d.maxCapacity
+	switch {
+	case b&0x80 > 0: // Insert with Name Reference
+		i, err := readQPACKInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		value, err := readQPACKPrefixedString(r, 7, maxLen)
+		if err != nil {
+			return err
+		}
+		static := b&0x40 > 0
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		var name string
+		if static {
+			if i >= uint64(len(qpackStaticTable)) {
+				return fmt.Errorf("invalid static index %d", i)
+			}
+			name = qpackStaticTable[i].Name
+		} else {
+			if i >= d.table.InsertCount() {
+				return fmt.Errorf("invalid relative index %d", i)
+			}
+			entry, ok := d.table.Get(d.table.InsertCount() - 1 - i)
+			if !ok {
+				return fmt.Errorf("invalid relative index %d", i)
+			}
+			name = entry.Name
+		}
+		return d.insert(qpack.HeaderField{Name: name, Value: value})
+	case b&0xc0 == 0x40: // Insert with Literal Name
+		name, err := readQPACKString(r, b, 5, maxLen)
+		if err != nil {
+			return err
+		}
+		value, err := readQPACKPrefixedString(r, 7, maxLen)
+		if err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		return d.insert(qpack.HeaderField{Name: name, Value: value})
+	case b&0xe0 == 0x20: // Set Dynamic Table Capacity
+		capacity, err := readQPACKInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		if capacity > d.maxCapacity {
+			return fmt.Errorf("dynamic table capacity %d exceeds the maximum (%d)", capacity, d.maxCapacity)
+		}
+		d.mutex.Lock()
+		d.table.SetCapacity(capacity)
+		d.mutex.Unlock()
+		return nil
+	default: // Duplicate
+		i, err := readQPACKInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if i >= d.table.InsertCount() {
+			return fmt.Errorf("invalid relative index %d", i)
+		}
+		entry, ok := d.table.Get(d.table.InsertCount() - 1 - i)
+		if !ok {
+			return fmt.Errorf("invalid relative index %d", i)
+		}
+		return d.insert(entry)
+	}
+}
+
+// insert inserts an entry and unblocks the streams waiting for it.
+// It must be called with the mutex held.
+func (d *qpackDecoder) insert(hf qpack.HeaderField) error {
+	if !d.table.Insert(hf) {
+		return fmt.Errorf("entry too large for the dynamic table: %d bytes", qpackEntrySize(hf))
+	}
+	close(d.inserted)
+	d.inserted = make(chan struct{})
+	return nil
+}
+
+// flush sends the decoder instructions on the decoder stream, opening it if necessary.
+func (d *qpackDecoder) flush() error {
+	d.writeMutex.Lock()
+	defer d.writeMutex.Unlock()
+
+	d.mutex.Lock()
+	b := d.instructions
+	d.instructions = nil
+	d.mutex.Unlock()
+	if len(b) == 0 {
+		return nil
+	}
+	if d.str == nil {
+		str, err := d.conn.OpenUniStream()
+		if err != nil {
+			return err
+		}
+		// Skipping data on the decoder stream would make the peer's encoder wait forever.
+		str.DisablePR()
+		d.str = str
+		b = append(quicvarint.Append(nil, streamTypeQPACKDecoderStream), b...)
+	}
+	_, err := d.str.Write(b)
+	return err
+}
+
+// readQPACKPrefixedInt reads an integer with an n-bit prefix, including the first byte.
+func readQPACKPrefixedInt(r qpackReader, n uint8) (uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return readQPACKInt(r, b, n)
+}
+
+// readQPACKPrefixedString reads a string literal with an n-bit prefix, including the first byte.
+func readQPACKPrefixedString(r qpackReader, n uint8, maxLen uint64) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	return readQPACKString(r, b, n, maxLen)
+}
@@ -0,0 +1,261 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK decoder", func() {
+	var (
+		decoder        *qpackDecoder
+		conn           *mockquic.MockEarlyConnection
+		ctx            context.Context
+		cancel         context.CancelFunc
+		encoderStream  *io.PipeWriter
+		encoderErr     chan error
+		decoderStream  chan []byte
+		str            *mockquic.MockStream
+		insertFooBar   []byte // Insert with Literal Name foo: bar
+		insertFooBaz   []byte // Insert with Literal Name foo: baz
+		decoderStrOpen bool
+	)
+
+	// sendInstructions sends instructions on the encoder stream
+	sendInstructions := func(b []byte) {
+		_, err := encoderStream.Write(b)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	}
+
+	// expectDecoderInstructions expects the decoder to send instructions on the decoder stream
+	expectDecoderInstructions := func(b []byte) {
+		var data []byte
+		if !decoderStrOpen {
+			decoderStrOpen = true
+			b = append(quicvarint.Append(nil, streamTypeQPACKDecoderStream), b...)
+		}
+		for len(data) < len(b) {
+			EventuallyWithOffset(1, decoderStream).Should(Receive(&data))
+		}
+		ExpectWithOffset(1, data).To(Equal(b))
+	}
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		conn = mockquic.NewMockEarlyConnection(mockCtrl)
+		conn.EXPECT().Context().Return(ctx).AnyTimes()
+		decoderStream = make(chan []byte, 10)
+		decoderStrOpen = false
+		decStr := mockquic.NewMockStream(mockCtrl)
+		decStr.EXPECT().DisablePR().MaxTimes(1)
+		decStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+			decoderStream <- append([]byte{}, b...)
+			return len(b), nil
+		}).AnyTimes()
+		conn.EXPECT().OpenUniStream().Return(decStr, nil).MaxTimes(1)
+		decoder = newQPACKDecoder(conn, 4096, 1)
+		var r *io.PipeReader
+		r, encoderStream = io.Pipe()
+		errChan := make(chan error, 1)
+		encoderErr = errChan
+		go func() {
+			defer GinkgoRecover()
+			errChan <- decoder.HandleEncoderStream(r)
+		}()
+		sendInstructions(appendQPACKInt(nil, 5, 0x20, 4096)) // Set Dynamic Table Capacity
+		str = newMockStreamWithID(4)
+		insertFooBar = appendQPACKString(nil, 5, 0x40, "foo")
+		insertFooBar = appendQPACKString(insertFooBar, 7, 0, "bar")
+		insertFooBaz = appendQPACKString(nil, 5, 0x40, "foo")
+		insertFooBaz = appendQPACKString(insertFooBaz, 7, 0, "baz")
+	})
+
+	AfterEach(func() {
+		cancel()
+		encoderStream.Close()
+	})
+
+	It("only uses the static table if nil", func() {
+		var decoder *qpackDecoder
+		fields := []qpack.HeaderField{{Name: ":status", Value: "200"}, {Name: "foo", Value: "bar"}}
+		decoded, err := decoder.Decode(nil, encodeStaticFieldSection(fields))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(fields))
+		decoder.CancelStream(4)
+	})
+
+	It("decodes field sections that only use the static table", func() {
+		fields := []qpack.HeaderField{
+			{Name: ":status", Value: "200"},
+			{Name: "content-type", Value: "video/mp4"},
+			{Name: "foo", Value: "bar"},
+		}
+		decoded, err := decoder.Decode(str, encodeStaticFieldSection(fields))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(fields))
+		Consistently(decoderStream).ShouldNot(Receive())
+	})
+
+	It("acknowledges insertions", func() {
+		sendInstructions(append(insertFooBar, insertFooBaz...))
+		expectDecoderInstructions(appendQPACKInt(nil, 6, 0, 2)) // Insert Count Increment
+	})
+
+	It("decodes references to the dynamic table", func() {
+		sendInstructions(append(insertFooBar, insertFooBaz...))
+		expectDecoderInstructions(appendQPACKInt(nil, 6, 0, 2))
+		// Required Insert Count 2, Base 2
+		b := []byte{3, 0}
+		b = appendQPACKInt(b, 6, 0x80, 1)      // Indexed Field Line: foo: bar
+		b = appendQPACKInt(b, 4, 0x40, 0)      // Literal Field Line with Name Reference: foo
+		b = appendQPACKString(b, 7, 0, "1337") // ... with the value 1337
+		decoded, err := decoder.Decode(str, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal([]qpack.HeaderField{
+			{Name: "foo", Value: "bar"},
+			{Name: "foo", Value: "1337"},
+		}))
+		expectDecoderInstructions(appendQPACKInt(nil, 7, 0x80, 4)) // Section Acknowledgment
+	})
+
+	It("decodes post-base references", func() {
+		// Required Insert Count 2, Base 0
+		b := []byte{3, 0x81}
+		b = appendQPACKInt(b, 4, 0x10, 1)     // Indexed Field Line with Post-Base Index: foo: baz
+		b = appendQPACKInt(b, 3, 0, 0)        // Literal Field Line with Post-Base Name Reference: foo
+		b = appendQPACKString(b, 7, 0, "qux") // ... with the value qux
+		sendInstructions(append(insertFooBar, insertFooBaz...))
+		decoded, err := decoder.Decode(str, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal([]qpack.HeaderField{
+			{Name: "foo", Value: "baz"},
+			{Name: "foo", Value: "qux"},
+		}))
+	})
+
+	It("blocks until the referenced entries are inserted", func() {
+		b := []byte{2, 0}                 // Required Insert Count 1, Base 1
+		b = appendQPACKInt(b, 6, 0x80, 0) // Indexed Field Line: foo: bar
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			decoded, err := decoder.Decode(str, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal([]qpack.HeaderField{{Name: "foo", Value: "bar"}}))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		sendInstructions(insertFooBar)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("unblocks when the connection is closed", func() {
+		b := []byte{2, 0}                 // Required Insert Count 1, Base 1
+		b = appendQPACKInt(b, 6, 0x80, 0) // Indexed Field Line: foo: bar
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := decoder.Decode(str, b)
+			Expect(err).To(MatchError(context.Canceled))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("errors when too many streams are blocked", func() {
+		b := []byte{2, 0}                 // Required Insert Count 1, Base 1
+		b = appendQPACKInt(b, 6, 0x80, 0) // Indexed Field Line: foo: bar
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			decoder.Decode(str, b)
+		}()
+		Eventually(func() uint64 {
+			decoder.mutex.Lock()
+			defer decoder.mutex.Unlock()
+			return decoder.numBlockedStreams
+		}).Should(BeEquivalentTo(1))
+		_, err := decoder.Decode(newMockStreamWithID(8), b)
+		Expect(err).To(MatchError("too many blocked streams"))
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("errors on invalid Required Insert Counts", func() {
+		_, err := decoder.Decode(str, []byte{0xff, 0x01, 0})
+		Expect(err).To(MatchError("invalid Required Insert Count: 256"))
+	})
+
+	It("errors on references beyond the Required Insert Count", func() {
+		sendInstructions(append(insertFooBar, insertFooBaz...))
+		expectDecoderInstructions(appendQPACKInt(nil, 6, 0, 2))
+		b := []byte{2, 1}                 // Required Insert Count 1, Base 2
+		b = appendQPACKInt(b, 6, 0x80, 0) // Indexed Field Line: the entry with the absolute index 1
+		_, err := decoder.Decode(str, b)
+		Expect(err).To(MatchError("reference to entry 1 beyond the Required Insert Count"))
+	})
+
+	It("errors on invalid static table references", func() {
+		_, err := decoder.Decode(str, appendQPACKInt([]byte{0, 0}, 6, 0xc0, 99))
+		Expect(err).To(MatchError("invalid static index 99"))
+	})
+
+	It("decodes insertions referencing the static and the dynamic table, and duplications", func() {
+		b := appendQPACKInt(nil, 6, 0xc0, 2) // Insert with Name Reference: age (static)
+		b = appendQPACKString(b, 7, 0, "42")
+		b = appendQPACKInt(b, 6, 0x80, 0) // Insert with Name Reference: age (dynamic)
+		b = appendQPACKString(b, 7, 0, "43")
+		b = appendQPACKInt(b, 5, 0, 1) // Duplicate: age: 42
+		sendInstructions(b)
+		expectDecoderInstructions(appendQPACKInt(nil, 6, 0, 3))
+		decoder.mutex.Lock()
+		defer decoder.mutex.Unlock()
+		Expect(decoder.table.entries).To(Equal([]qpack.HeaderField{
+			{Name: "age", Value: "42"},
+			{Name: "age", Value: "43"},
+			{Name: "age", Value: "42"},
+		}))
+	})
+
+	It("errors when the capacity exceeds the maximum", func() {
+		sendInstructions(appendQPACKInt(nil, 5, 0x20, 4097))
+		Eventually(encoderErr).Should(Receive(MatchError("dynamic table capacity 4097 exceeds the maximum (4096)")))
+	})
+
+	It("errors on entries that don't fit into the dynamic table", func() {
+		sendInstructions(appendQPACKInt(nil, 5, 0x20, 10))
+		sendInstructions(insertFooBar)
+		Eventually(encoderErr).Should(Receive(MatchError("entry too large for the dynamic table: 38 bytes")))
+	})
+
+	It("errors on invalid references on the encoder stream", func() {
+		sendInstructions(appendQPACKInt(nil, 5, 0, 0)) // Duplicate
+		Eventually(encoderErr).Should(Receive(MatchError("invalid relative index 0")))
+	})
+
+	It("rejects a second encoder stream", func() {
+		Eventually(func() bool {
+			decoder.mutex.Lock()
+			defer decoder.mutex.Unlock()
+			return decoder.handlingEncoderStream
+		}).Should(BeTrue())
+		Expect(decoder.HandleEncoderStream(&bytes.Buffer{})).To(MatchError("duplicate QPACK encoder stream"))
+	})
+
+	It("sends Stream Cancellations", func() {
+		decoder.CancelStream(8)
+		expectDecoderInstructions(appendQPACKInt(nil, 6, 0x40, 8))
+	})
+})
@@ -0,0 +1,378 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+	"github.com/marten-seemann/qpack"
+)
+
+// qpackNeverIndexed are the header fields that are never inserted into the dynamic table,
+// either because they're sensitive, or because their values are (almost) never repeated.
+var qpackNeverIndexed = map[string]struct{}{
+	":path":             {},
+	"age":               {},
+	"authorization":     {},
+	"content-length":    {},
+	"content-range":     {},
+	"cookie":            {},
+	"date":              {},
+	"etag":              {},
+	"if-modified-since": {},
+	"if-none-match":     {},
+	"last-modified":     {},
+	"location":          {},
+	"set-cookie":        {},
+}
+
+// A qpackEncoder encodes the field sections sent on the streams of a connection.
+// If the peer allows it, repeated header fields are inserted into the dynamic table (RFC 9204, section 2.1),
+// such that later field sections only reference them.
+// This saves a lot of overhead when making many small requests with similar header fields,
+// e.g. when fetching the segments of a video stream.
+// A nil qpackEncoder only uses the static table.
+type qpackEncoder struct {
+	conn quic.Connection
+	// the maximum capacity of the dynamic table, limiting the memory used
+	maxCapacity uint64
+
+	mutex sync.Mutex
+	table qpackDynamicTable
+	// the SETTINGS_QPACK_MAX_TABLE_CAPACITY and SETTINGS_QPACK_BLOCKED_STREAMS sent by the peer
+	peerMaxCapacity    uint64
+	peerBlockedStreams uint64
+	receivedSettings   bool
+	// the number of insertions the peer acknowledged
+	knownReceivedCount uint64
+	// the field sections that reference the dynamic table and weren't acknowledged yet, in the order they were sent
+	sections map[quic.StreamID][]qpackFieldSection
+	// the encoder instructions that weren't sent yet
+	instructions []byte
+	// set when handling the decoder stream
+	handlingDecoderStream bool
+
+	// serializes the writes to the encoder stream
+	writeMutex sync.Mutex
+	str        quic.SendStream
+}
+
+type qpackFieldSection struct {
+	requiredInsertCount uint64
+	// the smallest absolute index referenced
+	minIndex uint64
+}
+
+func newQPACKEncoder(conn quic.Connection, maxCapacity uint64) *qpackEncoder {
+	return &qpackEncoder{
+		conn:        conn,
+		maxCapacity: maxCapacity,
+		sections:    make(map[quic.StreamID][]qpackFieldSection),
+	}
+}
+
+// SetPeerSettings is called with the QPACK settings from the peer's SETTINGS frame.
+func (e *qpackEncoder) SetPeerSettings(maxCapacity, blockedStreams uint64) {
+	if e == nil {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.receivedSettings {
+		return
+	}
+	e.receivedSettings = true
+	e.peerMaxCapacity = maxCapacity
+	e.peerBlockedStreams = blockedStreams
+	capacity := maxCapacity
+	if capacity > e.maxCapacity {
+		capacity = e.maxCapacity
+	}
+	if capacity == 0 {
+		return
+	}
+	e.table.SetCapacity(capacity)
+	e.instructions = appendQPACKInt(e.instructions, 5, 0x20, capacity) // Set Dynamic Table Capacity
+}
+
+// A qpackFieldLine is the representation chosen for a header field.
+type qpackFieldLine struct {
+	hf      qpack.HeaderField
+	index   uint64 // the static index, or the absolute index in the dynamic table
+	static  bool
+	indexed bool // if false, the index refers to the name only
+	literal bool // if true, the name is sent as a string literal
+}
+
+// Encode encodes a field section sent on str.
+// New dynamic table entries have to be sent on the encoder stream using Flush,
+// before the field section is sent on str.
+func (e *qpackEncoder) Encode(str quic.Stream, fields []qpack.HeaderField) []byte {
+	if e == nil {
+		return encodeStaticFieldSection(fields)
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.table.capacity == 0 {
+		return encodeStaticFieldSection(fields)
+	}
+
+	id := str.StreamID()
+	// Referencing entries that the peer didn't acknowledge yet might block the stream.
+	canBlock := e.isBlocked(id) || e.numBlockedStreams() < e.peerBlockedStreams
+	lines := make([]qpackFieldLine, 0, len(fields))
+	var requiredInsertCount uint64
+	minIndex := uint64(math.MaxUint64)
+	for _, hf := range fields {
+		line := e.encodeField(hf, canBlock, minIndex)
+		if !line.static && !line.literal {
+			if line.index+1 > requiredInsertCount {
+				requiredInsertCount = line.index + 1
+			}
+			if line.index < minIndex {
+				minIndex = line.index
+			}
+		}
+		lines = append(lines, line)
+	}
+	if requiredInsertCount > 0 {
+		e.sections[id] = append(e.sections[id], qpackFieldSection{
+			requiredInsertCount: requiredInsertCount,
+			minIndex:            minIndex,
+		})
+	}
+
+	// All references are relative to the Base, which is the number of insertions so far.
+	base := e.table.InsertCount()
+	var b []byte
+	if requiredInsertCount == 0 {
+		b = append(b, 0, 0)
+	} else {
+		maxEntries := e.peerMaxCapacity / qpackEntryOverhead
+		b = appendQPACKInt(b, 8, 0, requiredInsertCount%(2*maxEntries)+1)
+		b = appendQPACKInt(b, 7, 0, base-requiredInsertCount)
+	}
+	for _, l := range lines {
+		switch {
+		case l.indexed && l.static:
+			b = appendQPACKInt(b, 6, 0xc0, l.index)
+		case l.indexed:
+			b = appendQPACKInt(b, 6, 0x80, base-1-l.index)
+		case l.literal:
+			b = appendQPACKString(b, 3, 0x20, l.hf.Name)
+			b = appendQPACKString(b, 7, 0, l.hf.Value)
+		case l.static:
+			b = appendQPACKInt(b, 4, 0x50, l.index)
+			b = appendQPACKString(b, 7, 0, l.hf.Value)
+		default:
+			b = appendQPACKInt(b, 4, 0x40, base-1-l.index)
+			b = appendQPACKString(b, 7, 0, l.hf.Value)
+		}
+	}
+	return b
+}
+
+// encodeField chooses the representation of a header field, inserting it into the dynamic table if that's useful.
+// Entries at or above pinned are referenced by the field section and must not be evicted.
+func (e *qpackEncoder) encodeField(hf qpack.HeaderField, canBlock bool, pinned uint64) qpackFieldLine {
+	// Header fields with an empty value might only match the name of a static table entry.
+	if i, ok := qpackStaticIndex[hf]; ok && qpackStaticTable[i] == hf {
+		return qpackFieldLine{hf: hf, index: i, static: true, indexed: true}
+	}
+	i, found := e.find(hf, false)
+	if found && (i < e.knownReceivedCount || canBlock) {
+		return qpackFieldLine{hf: hf, index: i, indexed: true}
+	}
+	staticName, hasStaticName := qpackStaticIndex[qpack.HeaderField{Name: hf.Name}]
+	if !found && e.shouldIndex(hf) && e.makeRoom(qpackEntrySize(hf), pinned) {
+		if hasStaticName {
+			e.instructions = appendQPACKInt(e.instructions, 6, 0xc0, staticName) // Insert with Name Reference
+		} else {
+			e.instructions = appendQPACKString(e.instructions, 5, 0x40, hf.Name) // Insert with Literal Name
+		}
+		e.instructions = appendQPACKString(e.instructions, 7, 0, hf.Value)
+		e.table.Insert(hf)
+		// If the stream can't be blocked, the entry can only be used once the peer acknowledged it.
+		if canBlock {
+			return qpackFieldLine{hf: hf, index: e.table.InsertCount() - 1, indexed: true}
+		}
+	}
+	if hasStaticName {
+		return qpackFieldLine{hf: hf, index: staticName, static: true}
+	}
+	if i, ok := e.find(hf, true); ok && (i < e.knownReceivedCount || canBlock) {
+		return qpackFieldLine{hf: hf, index: i}
+	}
+	return qpackFieldLine{hf: hf, literal: true}
+}
+
+// find finds the newest dynamic table entry for a header field, or only its name.
+func (e *qpackEncoder) find(hf qpack.HeaderField, nameOnly bool) (uint64, bool) {
+	for i := len(e.table.entries) - 1; i >= 0; i-- {
+		entry := e.table.entries[i]
+		if entry.Name == hf.Name && (nameOnly || entry.Value == hf.Value) {
+			return e.table.evicted + uint64(i), true
+		}
+	}
+	return 0, false
+}
+
+func (e *qpackEncoder) shouldIndex(hf qpack.HeaderField) bool {
+	if _, ok := qpackNeverIndexed[hf.Name]; ok {
+		return false
+	}
+	// Large entries would evict many other entries.
+	return qpackEntrySize(hf) <= e.table.capacity/4
+}
+
+// makeRoom says if an entry of size can be inserted, without evicting entries that might still be referenced:
+// entries at or above pinned, entries that the peer didn't acknowledge yet,
+// and entries referenced by field sections that weren't acknowledged yet.
+func (e *qpackEncoder) makeRoom(size, pinned uint64) bool {
+	if e.knownReceivedCount < pinned {
+		pinned = e.knownReceivedCount
+	}
+	for _, sections := range e.sections {
+		for _, s := range sections {
+			if s.minIndex < pinned {
+				pinned = s.minIndex
+			}
+		}
+	}
+	available := e.table.capacity - e.table.size
+	for i := e.table.evicted; available < size; i++ {
+		if i >= pinned {
+			return false
+		}
+		entry, _ := e.table.Get(i)
+		available += qpackEntrySize(entry)
+	}
+	return true
+}
+
+func (e *qpackEncoder) isBlocked(id quic.StreamID) bool {
+	for _, s := range e.sections[id] {
+		if s.requiredInsertCount > e.knownReceivedCount {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *qpackEncoder) numBlockedStreams() uint64 {
+	var n uint64
+	for id := range e.sections {
+		if e.isBlocked(id) {
+			n++
+		}
+	}
+	return n
+}
+
+// Flush sends the encoder instructions on the encoder stream, opening it if necessary.
+func (e *qpackEncoder) Flush() error {
+	if e == nil {
+		return nil
+	}
+	e.writeMutex.Lock()
+	defer e.writeMutex.Unlock()
+
+	e.mutex.Lock()
+	b := e.instructions
+	e.instructions = nil
+	e.mutex.Unlock()
+	if len(b) == 0 {
+		return nil
+	}
+	if e.str == nil {
+		str, err := e.conn.OpenUniStream()
+		if err != nil {
+			return err
+		}
+		// Skipping data on the encoder stream would corrupt the dynamic table.
+		str.DisablePR()
+		e.str = str
+		b = append(quicvarint.Append(nil, streamTypeQPACKEncoderStream), b...)
+	}
+	_, err := e.str.Write(b)
+	return err
+}
+
+// HandleDecoderStream handles the decoder instructions sent by the peer on the decoder stream (RFC 9204, section 4.4).
+// It returns when the stream is closed, or when the peer sends an invalid instruction.
+func (e *qpackEncoder) HandleDecoderStream(str io.Reader) error {
+	e.mutex.Lock()
+	if e.handlingDecoderStream {
+		e.mutex.Unlock()
+		return errors.New("duplicate QPACK decoder stream")
+	}
+	e.handlingDecoderStream = true
+	e.mutex.Unlock()
+
+	r := bufio.NewReader(str)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		var n uint8 = 6
+		if b&0x80 > 0 {
+			n = 7
+		}
+		v, err := readQPACKInt(r, b, n)
+		if err != nil {
+			return err
+		}
+		if err := e.handleDecoderInstruction(b, v); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *qpackEncoder) handleDecoderInstruction(b byte, v uint64) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	switch {
+	case b&0x80 > 0: // Section Acknowledgment
+		id := quic.StreamID(v)
+		sections := e.sections[id]
+		if len(sections) == 0 {
+			return fmt.Errorf("unexpected Section Acknowledgment for stream %d", id)
+		}
+		if sections[0].requiredInsertCount > e.knownReceivedCount {
+			e.knownReceivedCount = sections[0].requiredInsertCount
+		}
+		if len(sections) == 1 {
+			delete(e.sections, id)
+		} else {
+			e.sections[id] = sections[1:]
+		}
+	case b&0xc0 == 0x40: // Stream Cancellation
+		delete(e.sections, quic.StreamID(v))
+	default: // Insert Count Increment
+		if v == 0 || e.knownReceivedCount+v > e.table.InsertCount() {
+			return fmt.Errorf("invalid Insert Count Increment: %d", v)
+		}
+		e.knownReceivedCount += v
+	}
+	return nil
+}
+
+// encodeStaticFieldSection encodes a field section using only the static table.
+func encodeStaticFieldSection(fields []qpack.HeaderField) []byte {
+	var b bytes.Buffer
+	enc := qpack.NewEncoder(&b)
+	for _, hf := range fields {
+		enc.WriteField(hf)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,224 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// newQPACKInstructionStream creates a stream for the QPACK encoder or decoder stream.
+// Everything written to it (after the stream type) is passed to handle.
+func newQPACKInstructionStream(streamType uint64, handle func(io.Reader) error) (*mockquic.MockStream, io.Closer) {
+	r, w := io.Pipe()
+	str := mockquic.NewMockStream(mockCtrl)
+	str.EXPECT().DisablePR().MaxTimes(1)
+	str.EXPECT().Write(gomock.Any()).DoAndReturn(w.Write).AnyTimes()
+	go func() {
+		defer GinkgoRecover()
+		t, err := quicvarint.Read(quicvarint.NewReader(r))
+		if err != nil {
+			return
+		}
+		Expect(t).To(Equal(streamType))
+		handle(r)
+	}()
+	return str, w
+}
+
+func newMockStreamWithID(id quic.StreamID) *mockquic.MockStream {
+	str := mockquic.NewMockStream(mockCtrl)
+	str.EXPECT().StreamID().Return(id).AnyTimes()
+	return str
+}
+
+var _ = Describe("QPACK encoder", func() {
+	var (
+		encoder       *qpackEncoder
+		decoder       *qpackDecoder
+		encoderConn   *mockquic.MockEarlyConnection
+		decoderConn   *mockquic.MockEarlyConnection
+		closers       []io.Closer
+		ctx           context.Context
+		cancel        context.CancelFunc
+		exampleFields = []qpack.HeaderField{
+			{Name: ":method", Value: "GET"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":path", Value: "/segment1.m4s"},
+			{Name: "user-agent", Value: "quic-go HTTP/3"},
+			{Name: "x-pr-policy", Value: "deadline=100ms"},
+		}
+	)
+
+	// setup creates an encoder and a decoder, and connects the encoder and the decoder stream
+	setup := func(decoderMaxCapacity, decoderBlockedStreams uint64) {
+		encoderConn = mockquic.NewMockEarlyConnection(mockCtrl)
+		decoderConn = mockquic.NewMockEarlyConnection(mockCtrl)
+		decoderConn.EXPECT().Context().Return(ctx).AnyTimes()
+		encoder = newQPACKEncoder(encoderConn, 4096)
+		decoder = newQPACKDecoder(decoderConn, decoderMaxCapacity, decoderBlockedStreams)
+		encStr, c1 := newQPACKInstructionStream(streamTypeQPACKEncoderStream, decoder.HandleEncoderStream)
+		decStr, c2 := newQPACKInstructionStream(streamTypeQPACKDecoderStream, encoder.HandleDecoderStream)
+		closers = append(closers, c1, c2)
+		encoderConn.EXPECT().OpenUniStream().Return(encStr, nil).MaxTimes(1)
+		decoderConn.EXPECT().OpenUniStream().Return(decStr, nil).MaxTimes(1)
+		encoder.SetPeerSettings(decoderMaxCapacity, decoderBlockedStreams)
+	}
+
+	// roundTrip encodes a field section, sends the encoder instructions, and decodes the field section
+	roundTrip := func(id quic.StreamID, fields []qpack.HeaderField) []byte {
+		str := newMockStreamWithID(id)
+		b := encoder.Encode(str, fields)
+		Expect(encoder.Flush()).To(Succeed())
+		decoded, err := decoder.Decode(str, b)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, decoded).To(Equal(fields))
+		return b
+	}
+
+	// acknowledged says if the decoder acknowledged all insertions and field sections
+	acknowledged := func() bool {
+		encoder.mutex.Lock()
+		defer encoder.mutex.Unlock()
+		return encoder.knownReceivedCount == encoder.table.InsertCount() && len(encoder.sections) == 0
+	}
+
+	BeforeEach(func() {
+		closers = nil
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		for _, c := range closers {
+			c.Close()
+		}
+	})
+
+	It("only uses the static table if nil", func() {
+		var encoder *qpackEncoder
+		b := encoder.Encode(nil, exampleFields)
+		Expect(b).To(Equal(encodeStaticFieldSection(exampleFields)))
+		Expect(encoder.Flush()).To(Succeed())
+	})
+
+	It("only uses the static table before receiving the peer's SETTINGS", func() {
+		encoder := newQPACKEncoder(nil, 4096)
+		Expect(encoder.Encode(nil, exampleFields)).To(Equal(encodeStaticFieldSection(exampleFields)))
+		Expect(encoder.Flush()).To(Succeed())
+	})
+
+	It("only uses the static table if the peer doesn't allow a dynamic table", func() {
+		encoder := newQPACKEncoder(nil, 4096)
+		encoder.SetPeerSettings(0, 100)
+		Expect(encoder.Encode(nil, exampleFields)).To(Equal(encodeStaticFieldSection(exampleFields)))
+		Expect(encoder.Flush()).To(Succeed())
+	})
+
+	It("uses the smaller capacity", func() {
+		encoder := newQPACKEncoder(nil, 4096)
+		encoder.SetPeerSettings(1<<20, 100)
+		Expect(encoder.table.capacity).To(BeEquivalentTo(4096))
+		Expect(encoder.instructions).To(Equal(appendQPACKInt(nil, 5, 0x20, 4096)))
+	})
+
+	It("inserts header fields into the dynamic table", func() {
+		setup(4096, 100)
+		roundTrip(0, exampleFields)
+		Expect(encoder.table.entries).To(ContainElement(qpack.HeaderField{Name: "x-pr-policy", Value: "deadline=100ms"}))
+		Expect(encoder.table.entries).To(ContainElement(qpack.HeaderField{Name: ":authority", Value: "quic.clemente.io"}))
+		// :path is never indexed
+		Expect(encoder.table.entries).To(HaveLen(3))
+		Eventually(acknowledged).Should(BeTrue())
+
+		fields := make([]qpack.HeaderField, len(exampleFields))
+		copy(fields, exampleFields)
+		fields[2].Value = "/segment2.m4s"
+		b := roundTrip(4, fields)
+		Expect(len(b)).To(BeNumerically("<", len(encodeStaticFieldSection(fields))/2))
+		Expect(encoder.table.entries).To(HaveLen(3))
+		Eventually(acknowledged).Should(BeTrue())
+	})
+
+	It("doesn't reference unacknowledged entries if the peer doesn't allow blocked streams", func() {
+		setup(4096, 0)
+		b := roundTrip(0, exampleFields)
+		Expect(b[:2]).To(Equal([]byte{0, 0})) // Required Insert Count 0
+		Expect(encoder.table.InsertCount()).To(BeEquivalentTo(3))
+		Eventually(acknowledged).Should(BeTrue())
+		b = roundTrip(4, exampleFields)
+		Expect(b[0]).ToNot(BeZero())
+	})
+
+	It("doesn't insert large header fields", func() {
+		setup(4096, 100)
+		fields := []qpack.HeaderField{{Name: "foo", Value: string(bytes.Repeat([]byte{'a'}, 1024))}}
+		roundTrip(0, fields)
+		Expect(encoder.table.InsertCount()).To(BeZero())
+	})
+
+	It("evicts entries", func() {
+		setup(256, 3)
+		for i := 0; i < 100; i++ {
+			fields := []qpack.HeaderField{
+				{Name: ":method", Value: "GET"},
+				{Name: "x-segment", Value: fmt.Sprintf("%d", i%7)},
+				{Name: "x-quality", Value: fmt.Sprintf("%d", i%3)},
+			}
+			roundTrip(quic.StreamID(4*i), fields)
+			Expect(encoder.table.size).To(BeNumerically("<=", 256))
+		}
+		Eventually(acknowledged).Should(BeTrue())
+		Expect(encoder.table.evicted).ToNot(BeZero())
+	})
+
+	It("handles Stream Cancellations", func() {
+		setup(4096, 100)
+		str := newMockStreamWithID(8)
+		encoder.Encode(str, exampleFields)
+		Expect(encoder.Flush()).To(Succeed())
+		Expect(encoder.sections).To(HaveKey(quic.StreamID(8)))
+		decoder.CancelStream(8)
+		Eventually(func() bool {
+			encoder.mutex.Lock()
+			defer encoder.mutex.Unlock()
+			_, ok := encoder.sections[8]
+			return ok
+		}).Should(BeFalse())
+	})
+
+	Context("handling the decoder stream", func() {
+		It("errors on Insert Count Increments beyond the number of insertions", func() {
+			encoder := newQPACKEncoder(nil, 4096)
+			err := encoder.HandleDecoderStream(bytes.NewReader(appendQPACKInt(nil, 6, 0, 1)))
+			Expect(err).To(MatchError("invalid Insert Count Increment: 1"))
+		})
+
+		It("errors on unexpected Section Acknowledgments", func() {
+			encoder := newQPACKEncoder(nil, 4096)
+			err := encoder.HandleDecoderStream(bytes.NewReader(appendQPACKInt(nil, 7, 0x80, 4)))
+			Expect(err).To(MatchError("unexpected Section Acknowledgment for stream 4"))
+		})
+
+		It("returns the error when the stream is closed", func() {
+			encoder := newQPACKEncoder(nil, 4096)
+			Expect(encoder.HandleDecoderStream(&bytes.Buffer{})).To(MatchError(io.EOF))
+		})
+
+		It("rejects a second decoder stream", func() {
+			encoder := newQPACKEncoder(nil, 4096)
+			Expect(encoder.HandleDecoderStream(&bytes.Buffer{})).To(MatchError(io.EOF))
+			Expect(encoder.HandleDecoderStream(&bytes.Buffer{})).To(MatchError("duplicate QPACK decoder stream"))
+		})
+	})
+})
@@ -0,0 +1,272 @@
+package http3
+
+import (
+	"errors"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http2/hpack"
+)
+
+// The QPACK static table (RFC 9204, Appendix A).
+var qpackStaticTable = [...]qpack.HeaderField{
+	{Name: ":authority"},
+	{Name: ":path", Value: "/"},
+	{Name: "age", Value: "0"},
+	{Name: "content-disposition"},
+	{Name: "content-length", Value: "0"},
+	{Name: "cookie"},
+	{Name: "date"},
+	{Name: "etag"},
+	{Name: "if-modified-since"},
+	{Name: "if-none-match"},
+	{Name: "last-modified"},
+	{Name: "link"},
+	{Name: "location"},
+	{Name: "referer"},
+	{Name: "set-cookie"},
+	{Name: ":method", Value: "CONNECT"},
+	{Name: ":method", Value: "DELETE"},
+	{Name: ":method", Value: "GET"},
+	{Name: ":method", Value: "HEAD"},
+	{Name: ":method", Value: "OPTIONS"},
+	{Name: ":method", Value: "POST"},
+	{Name: ":method", Value: "PUT"},
+	{Name: ":scheme", Value: "http"},
+	{Name: ":scheme", Value: "https"},
+	{Name: ":status", Value: "103"},
+	{Name: ":status", Value: "200"},
+	{Name: ":status", Value: "304"},
+	{Name: ":status", Value: "404"},
+	{Name: ":status", Value: "503"},
+	{Name: "accept", Value: "*/*"},
+	{Name: "accept", Value: "application/dns-message"},
+	{Name: "accept-encoding", Value: "gzip, deflate, br"},
+	{Name: "accept-ranges", Value: "bytes"},
+	{Name: "access-control-allow-headers", Value: "cache-control"},
+	{Name: "access-control-allow-headers", Value: "content-type"},
+	{Name: "access-control-allow-origin", Value: "*"},
+	{Name: "cache-control", Value: "max-age=0"},
+	{Name: "cache-control", Value: "max-age=2592000"},
+	{Name: "cache-control", Value: "max-age=604800"},
+	{Name: "cache-control", Value: "no-cache"},
+	{Name: "cache-control", Value: "no-store"},
+	{Name: "cache-control", Value: "public, max-age=31536000"},
+	{Name: "content-encoding", Value: "br"},
+	{Name: "content-encoding", Value: "gzip"},
+	{Name: "content-type", Value: "application/dns-message"},
+	{Name: "content-type", Value: "application/javascript"},
+	{Name: "content-type", Value: "application/json"},
+	{Name: "content-type", Value: "application/x-www-form-urlencoded"},
+	{Name: "content-type", Value: "image/gif"},
+	{Name: "content-type", Value: "image/jpeg"},
+	{Name: "content-type", Value: "image/png"},
+	{Name: "content-type", Value: "text/css"},
+	{Name: "content-type", Value: "text/html; charset=utf-8"},
+	{Name: "content-type", Value: "text/plain"},
+	{Name: "content-type", Value: "text/plain;charset=utf-8"},
+	{Name: "range", Value: "bytes=0-"},
+	{Name: "strict-transport-security", Value: "max-age=31536000"},
+	{Name: "strict-transport-security", Value: "max-age=31536000; includesubdomains"},
+	{Name: "strict-transport-security", Value: "max-age=31536000; includesubdomains; preload"},
+	{Name: "vary", Value: "accept-encoding"},
+	{Name: "vary", Value: "origin"},
+	{Name: "x-content-type-options", Value: "nosniff"},
+	{Name: "x-xss-protection", Value: "1; mode=block"},
+	{Name: ":status", Value: "100"},
+	{Name: ":status", Value: "204"},
+	{Name: ":status", Value: "206"},
+	{Name: ":status", Value: "302"},
+	{Name: ":status", Value: "400"},
+	{Name: ":status", Value: "403"},
+	{Name: ":status", Value: "421"},
+	{Name: ":status", Value: "425"},
+	{Name: ":status", Value: "500"},
+	{Name: "accept-language"},
+	{Name: "access-control-allow-credentials", Value: "FALSE"},
+	{Name: "access-control-allow-credentials", Value: "TRUE"},
+	{Name: "access-control-allow-headers", Value: "*"},
+	{Name: "access-control-allow-methods", Value: "get"},
+	{Name: "access-control-allow-methods", Value: "get, post, options"},
+	{Name: "access-control-allow-methods", Value: "options"},
+	{Name: "access-control-expose-headers", Value: "content-length"},
+	{Name: "access-control-request-headers", Value: "content-type"},
+	{Name: "access-control-request-method", Value: "get"},
+	{Name: "access-control-request-method", Value: "post"},
+	{Name: "alt-svc", Value: "clear"},
+	{Name: "authorization"},
+	{Name: "content-security-policy", Value: "script-src 'none'; object-src 'none'; base-uri 'none'"},
+	{Name: "early-data", Value: "1"},
+	{Name: "expect-ct"},
+	{Name: "forwarded"},
+	{Name: "if-range"},
+	{Name: "origin"},
+	{Name: "purpose", Value: "prefetch"},
+	{Name: "server"},
+	{Name: "timing-allow-origin", Value: "*"},
+	{Name: "upgrade-insecure-requests", Value: "1"},
+	{Name: "user-agent"},
+	{Name: "x-forwarded-for"},
+	{Name: "x-frame-options", Value: "deny"},
+	{Name: "x-frame-options", Value: "sameorigin"},
+}
+
+// qpackStaticIndex maps header fields to their index in the static table.
+// For header names, the value is the index of the first entry with that name.
+var qpackStaticIndex = func() map[qpack.HeaderField]uint64 {
+	m := make(map[qpack.HeaderField]uint64, 2*len(qpackStaticTable))
+	for i, hf := range qpackStaticTable {
+		m[hf] = uint64(i)
+		if _, ok := m[qpack.HeaderField{Name: hf.Name}]; !ok {
+			m[qpack.HeaderField{Name: hf.Name}] = uint64(i)
+		}
+	}
+	return m
+}()
+
+// qpackEntryOverhead is added to the length of name and value to calculate the size of a dynamic table entry.
+const qpackEntryOverhead = 32
+
+func qpackEntrySize(hf qpack.HeaderField) uint64 {
+	return uint64(len(hf.Name)+len(hf.Value)) + qpackEntryOverhead
+}
+
+// A qpackDynamicTable is the QPACK dynamic table (RFC 9204, section 3.2).
+// Entries are addressed by their absolute index: the first entry inserted has the absolute index 0.
+type qpackDynamicTable struct {
+	capacity uint64
+	size     uint64
+	entries  []qpack.HeaderField
+	// the absolute index of entries[0]
+	evicted uint64
+}
+
+// InsertCount is the total number of insertions into the table.
+func (t *qpackDynamicTable) InsertCount() uint64 {
+	return t.evicted + uint64(len(t.entries))
+}
+
+// Get returns the entry with the absolute index i, if it wasn't evicted yet.
+func (t *qpackDynamicTable) Get(i uint64) (qpack.HeaderField, bool) {
+	if i < t.evicted || i >= t.InsertCount() {
+		return qpack.HeaderField{}, false
+	}
+	return t.entries[i-t.evicted], true
+}
+
+// Insert inserts an entry, evicting the oldest entries as necessary.
+// It returns false if the entry doesn't fit into the table.
+func (t *qpackDynamicTable) Insert(hf qpack.HeaderField) bool {
+	size := qpackEntrySize(hf)
+	if size > t.capacity {
+		return false
+	}
+	t.evict(t.capacity - size)
+	t.entries = append(t.entries, hf)
+	t.size += size
+	return true
+}
+
+// SetCapacity sets the capacity of the table, evicting entries as necessary.
+func (t *qpackDynamicTable) SetCapacity(c uint64) {
+	t.capacity = c
+	t.evict(c)
+}
+
+// evict evicts the oldest entries until the size of the table is at most size
+func (t *qpackDynamicTable) evict(size uint64) {
+	for t.size > size {
+		t.size -= qpackEntrySize(t.entries[0])
+		t.entries[0] = qpack.HeaderField{}
+		t.entries = t.entries[1:]
+		t.evicted++
+	}
+}
+
+var errQPACKIntegerOverflow = errors.New("QPACK integer overflow")
+
+// appendQPACKInt appends the integer i, using an n-bit prefix (RFC 7541, section 5.1).
+// The bits of the first byte that are not used by the prefix are set to flags.
+func appendQPACKInt(b []byte, n uint8, flags byte, i uint64) []byte {
+	max := uint64(1)<<n - 1
+	if i < max {
+		return append(b, flags|byte(i))
+	}
+	b = append(b, flags|byte(max))
+	i -= max
+	for ; i >= 0x80; i >>= 7 {
+		b = append(b, byte(0x80|i&0x7f))
+	}
+	return append(b, byte(i))
+}
+
+// readQPACKInt reads an integer with an n-bit prefix. first is the first byte of the integer, which was already read.
+func readQPACKInt(r io.ByteReader, first byte, n uint8) (uint64, error) {
+	max := uint64(1)<<n - 1
+	i := uint64(first) & max
+	if i < max {
+		return i, nil
+	}
+	for m := uint(0); ; m += 7 {
+		if m > 56 {
+			return 0, errQPACKIntegerOverflow
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		i += uint64(b&0x7f) << m
+		if b&0x80 == 0 {
+			return i, nil
+		}
+	}
+}
+
+// appendQPACKString appends a string literal with an n-bit prefix, Huffman-encoded if that's shorter.
+// The Huffman flag is the bit right before the prefix.
+func appendQPACKString(b []byte, n uint8, flags byte, s string) []byte {
+	if l := hpack.HuffmanEncodeLength(s); l < uint64(len(s)) {
+		b = appendQPACKInt(b, n, flags|1<<n, l)
+		return hpack.AppendHuffmanString(b, s)
+	}
+	b = appendQPACKInt(b, n, flags, uint64(len(s)))
+	return append(b, s...)
+}
+
+type qpackReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readQPACKString reads a string literal with an n-bit prefix.
+// Strings longer than maxLen are rejected.
+func readQPACKString(r qpackReader, first byte, n uint8, maxLen uint64) (string, error) {
+	l, err := readQPACKInt(r, first, n)
+	if err != nil {
+		return "", err
+	}
+	if l > maxLen {
+		return "", errors.New("QPACK string literal too long")
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	if first&(1<<n) == 0 {
+		return string(b), nil
+	}
+	return hpack.HuffmanDecodeToString(b)
+}
+
+// handleQPACKStream handles the QPACK encoder or decoder stream opened by the peer.
+// Both streams are critical streams: closing them, or sending an invalid instruction, is a connection error.
+func handleQPACKStream(conn quic.Connection, str quic.ReceiveStream, code errorCode, handle func(io.Reader) error) {
+	// The peer must send the QPACK streams reliably.
+	str.SetReadSkippedAsError(true)
+	err := handle(str)
+	if err == io.EOF {
+		code = errorClosedCriticalStream
+	}
+	conn.CloseWithError(quic.ApplicationErrorCode(code), err.Error())
+}
@@ -0,0 +1,134 @@
+package http3
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK tables", func() {
+	Context("static table", func() {
+		It("uses the same indices as the qpack package", func() {
+			for i, hf := range qpackStaticTable {
+				var buf bytes.Buffer
+				enc := qpack.NewEncoder(&buf)
+				Expect(enc.WriteField(hf)).To(Succeed())
+				// The qpack package encodes header fields contained in the static table as Indexed Field Lines.
+				Expect(buf.Bytes()).To(Equal(appendQPACKInt([]byte{0, 0}, 6, 0xc0, uint64(i))))
+			}
+		})
+
+		It("finds header fields and names", func() {
+			Expect(qpackStaticIndex).To(HaveKeyWithValue(qpack.HeaderField{Name: ":method", Value: "GET"}, uint64(17)))
+			Expect(qpackStaticIndex).To(HaveKeyWithValue(qpack.HeaderField{Name: ":authority"}, uint64(0)))
+			// for names that appear multiple times, the first index is used
+			Expect(qpackStaticIndex).To(HaveKeyWithValue(qpack.HeaderField{Name: ":method"}, uint64(15)))
+			Expect(qpackStaticIndex).ToNot(HaveKey(qpack.HeaderField{Name: "foo"}))
+		})
+	})
+
+	Context("dynamic table", func() {
+		It("inserts entries", func() {
+			var t qpackDynamicTable
+			t.SetCapacity(100)
+			hf := qpack.HeaderField{Name: "foo", Value: "bar"}
+			Expect(t.Insert(hf)).To(BeTrue())
+			Expect(t.InsertCount()).To(BeEquivalentTo(1))
+			Expect(t.size).To(BeEquivalentTo(qpackEntryOverhead + 6))
+			e, ok := t.Get(0)
+			Expect(ok).To(BeTrue())
+			Expect(e).To(Equal(hf))
+			_, ok = t.Get(1)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("evicts the oldest entries", func() {
+			var t qpackDynamicTable
+			t.SetCapacity(2 * (qpackEntryOverhead + 6))
+			Expect(t.Insert(qpack.HeaderField{Name: "foo", Value: "bar"})).To(BeTrue())
+			Expect(t.Insert(qpack.HeaderField{Name: "baz", Value: "bar"})).To(BeTrue())
+			Expect(t.Insert(qpack.HeaderField{Name: "foo", Value: "baz"})).To(BeTrue())
+			Expect(t.InsertCount()).To(BeEquivalentTo(3))
+			_, ok := t.Get(0)
+			Expect(ok).To(BeFalse())
+			e, ok := t.Get(2)
+			Expect(ok).To(BeTrue())
+			Expect(e).To(Equal(qpack.HeaderField{Name: "foo", Value: "baz"}))
+			// reducing the capacity evicts entries
+			t.SetCapacity(qpackEntryOverhead + 6)
+			_, ok = t.Get(1)
+			Expect(ok).To(BeFalse())
+			_, ok = t.Get(2)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("rejects entries larger than the capacity", func() {
+			var t qpackDynamicTable
+			t.SetCapacity(qpackEntryOverhead + 5)
+			Expect(t.Insert(qpack.HeaderField{Name: "foo", Value: "bar"})).To(BeFalse())
+			Expect(t.InsertCount()).To(BeZero())
+		})
+	})
+
+	Context("integers", func() {
+		It("encodes and decodes integers", func() {
+			for _, n := range []uint8{3, 4, 5, 6, 7, 8} {
+				for _, i := range []uint64{0, 1, 6, 7, 8, 30, 31, 32, 127, 128, 254, 255, 256, 1337, 1 << 20, 1<<62 - 1} {
+					b := appendQPACKInt(nil, n, 0, i)
+					r := bytes.NewReader(b)
+					first, err := r.ReadByte()
+					Expect(err).ToNot(HaveOccurred())
+					v, err := readQPACKInt(r, first, n)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(v).To(Equal(i))
+					Expect(r.Len()).To(BeZero())
+				}
+			}
+		})
+
+		It("encodes the example from RFC 7541", func() {
+			Expect(appendQPACKInt(nil, 5, 0xe0, 1337)).To(Equal([]byte{0xff, 0x9a, 0x0a}))
+		})
+
+		It("errors on overflows", func() {
+			b := append([]byte{0x7f}, bytes.Repeat([]byte{0xff}, 10)...)
+			_, err := readQPACKInt(bytes.NewReader(b[1:]), b[0], 7)
+			Expect(err).To(MatchError(errQPACKIntegerOverflow))
+		})
+	})
+
+	Context("strings", func() {
+		It("encodes and decodes strings", func() {
+			for _, s := range []string{"", "foobar", "www.example.com", strings.Repeat("a", 200), "\x00\x01\xff"} {
+				b := appendQPACKString(nil, 5, 0x40, s)
+				Expect(b[0] & 0xc0).To(Equal(byte(0x40)))
+				r := bytes.NewReader(b)
+				first, err := r.ReadByte()
+				Expect(err).ToNot(HaveOccurred())
+				v, err := readQPACKString(r, first, 5, 1000)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(v).To(Equal(s))
+				Expect(r.Len()).To(BeZero())
+			}
+		})
+
+		It("uses Huffman encoding if it's shorter", func() {
+			b := appendQPACKString(nil, 7, 0, "www.example.com")
+			Expect(b[0] & 0x80).ToNot(BeZero())
+			Expect(len(b)).To(BeNumerically("<", 1+len("www.example.com")))
+		})
+
+		It("rejects strings that are too long", func() {
+			b := appendQPACKString(nil, 7, 0, strings.Repeat("\x00", 20))
+			r := bytes.NewReader(b)
+			first, err := r.ReadByte()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = readQPACKString(r, first, 7, 10)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
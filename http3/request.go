@@ -44,6 +44,9 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	isConnect := method == http.MethodConnect
 	// Extended CONNECT, see https://datatracker.ietf.org/doc/html/rfc8441#section-4
 	isExtendedConnected := isConnect && protocol != ""
+	if protocol != "" && !isConnect {
+		return nil, errors.New(":protocol is only allowed for CONNECT requests")
+	}
 	if isExtendedConnected {
 		if scheme == "" || path == "" || authority == "" {
 			return nil, errors.New("extended CONNECT: :scheme, :path and :authority must not be empty")
@@ -90,6 +93,16 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		}
 	}
 
+	// Declared trailers are announced with nil values, see http.Request.Trailer.
+	var trailer http.Header
+	for _, key := range declaredTrailers(httpHeaders) {
+		if trailer == nil {
+			trailer = make(http.Header)
+		}
+		trailer[key] = nil
+	}
+	httpHeaders.Del("Trailer")
+
 	return &http.Request{
 		Method:        method,
 		URL:           u,
@@ -97,6 +110,7 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		ProtoMajor:    3,
 		ProtoMinor:    0,
 		Header:        httpHeaders,
+		Trailer:       trailer,
 		Body:          nil,
 		ContentLength: contentLength,
 		Host:          authority,
@@ -105,6 +119,12 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}, nil
 }
 
+// isExtendedConnect says if a request parsed by requestFromHeaders is an extended CONNECT request (RFC 9220).
+// The :protocol is stored in the Proto field.
+func isExtendedConnect(req *http.Request) bool {
+	return req.Method == http.MethodConnect && req.Proto != ""
+}
+
 func hostnameFromRequest(req *http.Request) string {
 	if req.URL != nil {
 		return req.URL.Host
@@ -81,6 +81,20 @@ var _ = Describe("Request", func() {
 		}))
 	})
 
+	It("announces the declared trailers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "POST"},
+			{Name: "trailer", Value: "checksum, expires"},
+			{Name: "trailer", Value: "content-length"}, // not allowed in trailers
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Header).ToNot(HaveKey("Trailer"))
+		Expect(req.Trailer).To(Equal(http.Header{"Checksum": nil, "Expires": nil}))
+	})
+
 	It("errors with missing path", func() {
 		headers := []qpack.HeaderField{
 			{Name: ":authority", Value: "quic.clemente.io"},
@@ -168,6 +182,18 @@ var _ = Describe("Request", func() {
 		})
 	})
 
+	It("errors when :protocol is used without the CONNECT method", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":method", Value: http.MethodGet},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":path", Value: "/foo"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":protocol is only allowed for CONNECT requests"))
+	})
+
 	Context("extracting the hostname from a request", func() {
 		var url *url.URL
 
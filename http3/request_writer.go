@@ -1,11 +1,10 @@
 package http3
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,52 +20,85 @@ import (
 const bodyCopyBufferSize = 8 * 1024
 
 type requestWriter struct {
-	mutex     sync.Mutex
-	encoder   *qpack.Encoder
-	headerBuf *bytes.Buffer
+	mutex  sync.Mutex
+	fields []qpack.HeaderField
+	// encoder encodes the header fields. If nil, only the QPACK static table is used.
+	encoder *qpackEncoder
 
 	logger utils.Logger
 }
 
 func newRequestWriter(logger utils.Logger) *requestWriter {
-	headerBuf := &bytes.Buffer{}
-	encoder := qpack.NewEncoder(headerBuf)
-	return &requestWriter{
-		encoder:   encoder,
-		headerBuf: headerBuf,
-		logger:    logger,
-	}
+	return &requestWriter{logger: logger}
 }
 
 func (w *requestWriter) WriteRequestHeader(str quic.Stream, req *http.Request, gzip bool) error {
-	// TODO: figure out how to add support for trailers
-	buf := &bytes.Buffer{}
-	if err := w.writeHeaders(buf, req, gzip); err != nil {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	defer func() { w.fields = w.fields[:0] }()
+
+	trailers, err := commaSeparatedTrailers(req)
+	if err != nil {
 		return err
 	}
-	_, err := str.Write(buf.Bytes())
-	return err
+	if err := w.encodeHeaders(req, gzip, trailers, actualContentLength(req)); err != nil {
+		return err
+	}
+	return w.writeHeadersFrame(str)
 }
 
-func (w *requestWriter) writeHeaders(wr io.Writer, req *http.Request, gzip bool) error {
+// WriteRequestTrailer sends the trailers of the request.
+// It must be called after the request body was sent.
+func (w *requestWriter) WriteRequestTrailer(str quic.Stream, req *http.Request) error {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	defer w.encoder.Close()
-	defer w.headerBuf.Reset()
+	defer func() { w.fields = w.fields[:0] }()
 
-	if err := w.encodeHeaders(req, gzip, "", actualContentLength(req)); err != nil {
-		return err
+	for k, vv := range req.Trailer {
+		if !httpguts.ValidHeaderFieldName(k) {
+			return fmt.Errorf("invalid HTTP trailer name %q", k)
+		}
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				return fmt.Errorf("invalid HTTP trailer value %q for trailer %q", v, k)
+			}
+			w.fields = append(w.fields, qpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
 	}
+	return w.writeHeadersFrame(str)
+}
 
-	b := make([]byte, 0, 128)
-	b = (&headersFrame{Length: uint64(w.headerBuf.Len())}).Append(b)
-	if _, err := wr.Write(b); err != nil {
+func (w *requestWriter) writeHeadersFrame(str quic.Stream) error {
+	headerBlock := w.encoder.Encode(str, w.fields)
+	// Send the new dynamic table entries first, so that the server doesn't have to wait for them.
+	if err := w.encoder.Flush(); err != nil {
 		return err
 	}
-	_, err := wr.Write(w.headerBuf.Bytes())
+	b := make([]byte, 0, 128+len(headerBlock))
+	b = (&headersFrame{Length: uint64(len(headerBlock))}).Append(b)
+	b = append(b, headerBlock...)
+	_, err := str.Write(b)
 	return err
 }
 
+// copied from net/http2/transport.go
+func commaSeparatedTrailers(req *http.Request) (string, error) {
+	keys := make([]string, 0, len(req.Trailer))
+	for k := range req.Trailer {
+		k = http.CanonicalHeaderKey(k)
+		switch k {
+		case "Transfer-Encoding", "Trailer", "Content-Length":
+			return "", fmt.Errorf("invalid Trailer key %q", k)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return strings.Join(keys, ","), nil
+	}
+	return "", nil
+}
+
 // copied from net/transport.go
 // Modified to support Extended CONNECT:
 // Contrary to what the godoc for the http.Request says,
@@ -199,7 +231,7 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// Header list size is ok. Write the headers.
 	enumerateHeaders(func(name, value string) {
 		name = strings.ToLower(name)
-		w.encoder.WriteField(qpack.HeaderField{Name: name, Value: value})
+		w.fields = append(w.fields, qpack.HeaderField{Name: name, Value: value})
 		// if traceHeaders {
 		// 	traceWroteHeaderField(trace, name, value)
 		// }
@@ -109,4 +109,28 @@ var _ = Describe("Request Writer", func() {
 		Expect(headerFields).To(HaveKeyWithValue(":scheme", "https"))
 		Expect(headerFields).To(HaveKeyWithValue(":protocol", "webtransport"))
 	})
+
+	It("announces the trailers", func() {
+		req, err := http.NewRequest(http.MethodPost, "https://quic.clemente.io/upload", bytes.NewReader([]byte("foobar")))
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"expires": nil, "Checksum": nil}
+		Expect(rw.WriteRequestHeader(str, req, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue("trailer", "Checksum,Expires"))
+	})
+
+	It("refuses to announce trailers that are not allowed", func() {
+		req, err := http.NewRequest(http.MethodPost, "https://quic.clemente.io/upload", bytes.NewReader([]byte("foobar")))
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Content-Length": nil}
+		Expect(rw.WriteRequestHeader(str, req, false)).To(MatchError(`invalid Trailer key "Content-Length"`))
+	})
+
+	It("writes the trailers", func() {
+		req, err := http.NewRequest(http.MethodPost, "https://quic.clemente.io/upload", bytes.NewReader([]byte("foobar")))
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Checksum": []string{"deadbeef"}}
+		Expect(rw.WriteRequestTrailer(str, req)).To(Succeed())
+		Expect(decode(strBuf)).To(Equal(map[string]string{"checksum": "deadbeef"}))
+	})
 })
@@ -2,7 +2,7 @@ package http3
 
 import (
 	"bufio"
-	"bytes"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,24 +10,60 @@ import (
 	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http/httpguts"
 )
 
 type responseWriter struct {
 	conn        quic.Connection
+	str         quic.Stream
 	bufferedStr *bufio.Writer
 	buf         []byte
+	// encoder encodes the response header. If nil, only the QPACK static table is used.
+	encoder *qpackEncoder
+
+	// If the stream uses a partially reliable policy, or if the handler writes data using a partially reliable policy,
+	// the HTTP/3 frames (except for the payload of DATA frames) are sent reliably, and the PRSkippedRangesTrailer is sent after the body.
+	pr           bool
+	prTrailer    bool
+	offset       uint64 // number of bytes written to the stream
+	bodyOffset   uint64
+	bodySegments []bodySegment
 
 	header        http.Header
 	status        int // status code passed to WriteHeader
 	headerWritten bool
+	trailers      []string // trailers declared in the Trailer header of the response
 
 	logger utils.Logger
 }
 
+// The PRPolicySetter allows a handler to choose how reliably the response is delivered,
+// e.g. depending on the requested object. It is implemented by the http.ResponseWriter of the Server.
+type PRPolicySetter interface {
+	// SetPRPolicy sets the policy of the response stream, see quic.SendStream.SetPRPolicy.
+	// It takes precedence over the PRPolicyHeader.
+	// It must be called before the response header is written.
+	SetPRPolicy(*quic.PRPolicy) error
+}
+
+// The PRWriter allows a handler to choose the policy for every write of the response body,
+// e.g. to give every chunk of a live media stream its own deadline.
+// It is implemented by the http.ResponseWriter of the Server.
+type PRWriter interface {
+	// WriteWithPolicy writes p as a DATA frame, sending the payload with the given policy,
+	// see quic.SendStream.WriteWithPolicy. The data is not buffered.
+	// If parts of the body are skipped, the PRSkippedRangesTrailer is sent after the body.
+	WriteWithPolicy(p []byte, policy quic.PRPolicy) (int, error)
+	// FlushError sends all buffered data, and returns the error that occurred.
+	FlushError() error
+}
+
 var (
 	_ http.ResponseWriter = &responseWriter{}
 	_ http.Flusher        = &responseWriter{}
 	_ Hijacker            = &responseWriter{}
+	_ PRPolicySetter      = &responseWriter{}
+	_ PRWriter            = &responseWriter{}
 )
 
 func newResponseWriter(str quic.Stream, conn quic.Connection, logger utils.Logger) *responseWriter {
@@ -35,6 +71,7 @@ func newResponseWriter(str quic.Stream, conn quic.Connection, logger utils.Logge
 		header:      http.Header{},
 		buf:         make([]byte, 16),
 		conn:        conn,
+		str:         str,
 		bufferedStr: bufio.NewWriter(str),
 		logger:      logger,
 	}
@@ -49,29 +86,53 @@ func (w *responseWriter) WriteHeader(status int) {
 		return
 	}
 
+	policy, _ := w.str.EffectivePRPolicy()
+	w.pr = !policy.IsReliable()
 	if status < 100 || status >= 200 {
 		w.headerWritten = true
+		w.prTrailer = w.pr && bodyAllowedForStatus(status)
+		w.trailers = declaredTrailers(w.header)
 	}
 	w.status = status
 
-	var headers bytes.Buffer
-	enc := qpack.NewEncoder(&headers)
-	enc.WriteField(qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
-
+	fields := make([]qpack.HeaderField, 0, len(w.header)+2)
+	fields = append(fields, qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
 	for k, v := range w.header {
+		// Trailers are only announced with the final response.
+		if !w.headerWritten && k == "Trailer" {
+			continue
+		}
+		// Trailers set using the http.TrailerPrefix are sent after the body.
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
 		for index := range v {
-			enc.WriteField(qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
+			fields = append(fields, qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
 		}
 	}
+	if w.prTrailer {
+		fields = append(fields, qpack.HeaderField{Name: "trailer", Value: strings.ToLower(PRSkippedRangesTrailer)})
+	}
+	headers := w.encoder.Encode(w.str, fields)
+	if err := w.encoder.Flush(); err != nil {
+		w.logger.Errorf("could not write QPACK encoder instructions: %s", err.Error())
+	}
 
 	w.buf = w.buf[:0]
-	w.buf = (&headersFrame{Length: uint64(headers.Len())}).Append(w.buf)
+	w.buf = (&headersFrame{Length: uint64(len(headers))}).Append(w.buf)
 	w.logger.Infof("Responding with %d", status)
-	if _, err := w.bufferedStr.Write(w.buf); err != nil {
-		w.logger.Errorf("could not write headers frame: %s", err.Error())
-	}
-	if _, err := w.bufferedStr.Write(headers.Bytes()); err != nil {
-		w.logger.Errorf("could not write header frame payload: %s", err.Error())
+	if w.pr {
+		w.buf = append(w.buf, headers...)
+		if _, err := w.writeReliably(w.buf); err != nil {
+			w.logger.Errorf("could not write headers frame: %s", err.Error())
+		}
+	} else {
+		if _, err := w.write(w.buf); err != nil {
+			w.logger.Errorf("could not write headers frame: %s", err.Error())
+		}
+		if _, err := w.write(headers); err != nil {
+			w.logger.Errorf("could not write header frame payload: %s", err.Error())
+		}
 	}
 	if !w.headerWritten {
 		w.Flush()
@@ -85,21 +146,159 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 	if !bodyAllowedForStatus(w.status) {
 		return 0, http.ErrBodyNotAllowed
 	}
+	if w.prTrailer {
+		return w.writeSegment(p, w.str.Write)
+	}
 	df := &dataFrame{Length: uint64(len(p))}
 	w.buf = w.buf[:0]
 	w.buf = df.Append(w.buf)
-	if _, err := w.bufferedStr.Write(w.buf); err != nil {
+	if _, err := w.write(w.buf); err != nil {
 		return 0, err
 	}
-	return w.bufferedStr.Write(p)
+	w.bodyOffset += uint64(len(p))
+	return w.write(p)
 }
 
-func (w *responseWriter) Flush() {
+// WriteWithPolicy writes p as a DATA frame, sending the payload with the given policy.
+// The data is sent right away, without being buffered.
+func (w *responseWriter) WriteWithPolicy(p []byte, policy quic.PRPolicy) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(200)
+	}
+	if !bodyAllowedForStatus(w.status) {
+		return 0, http.ErrBodyNotAllowed
+	}
+	// Skipped ranges are reported even if the response header didn't announce the trailer,
+	// since the stream itself might use the reliable policy.
+	if !policy.IsReliable() {
+		w.prTrailer = true
+	}
+	return w.writeSegment(p, func(b []byte) (int, error) { return w.str.WriteWithPolicy(b, policy) })
+}
+
+// writeSegment writes p as a DATA frame, and records it as a bodySegment.
+// The DATA frame header is sent reliably, the payload is sent using write.
+func (w *responseWriter) writeSegment(p []byte, write func([]byte) (int, error)) (int, error) {
+	w.buf = w.buf[:0]
+	w.buf = (&dataFrame{Length: uint64(len(p))}).Append(w.buf)
+	// The client can only parse the frames if the DATA frame header is delivered, even if the payload is skipped.
+	if _, err := w.writeReliably(w.buf); err != nil {
+		return 0, err
+	}
+	w.bodySegments = append(w.bodySegments, bodySegment{offset: w.offset, bodyOffset: w.bodyOffset, length: uint64(len(p))})
+	w.bodyOffset += uint64(len(p))
+	n, err := write(p)
+	w.offset += uint64(n)
+	return n, err
+}
+
+func (w *responseWriter) write(p []byte) (int, error) {
+	n, err := w.bufferedStr.Write(p)
+	w.offset += uint64(n)
+	return n, err
+}
+
+// writeReliably writes p to the stream, ignoring its partially reliable policy.
+func (w *responseWriter) writeReliably(p []byte) (int, error) {
 	if err := w.bufferedStr.Flush(); err != nil {
+		return 0, err
+	}
+	n, err := w.str.WriteWithPolicy(p, quic.PRPolicy{})
+	w.offset += uint64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if err := w.FlushError(); err != nil {
 		w.logger.Errorf("could not flush to stream: %s", err.Error())
 	}
 }
 
+// FlushError flushes buffered data to the stream, like Flush, and returns the error that occurred.
+func (w *responseWriter) FlushError() error {
+	return w.bufferedStr.Flush()
+}
+
+// writeTrailers sends the trailers declared by the handler, and the PRSkippedRangesTrailer,
+// if the response body was sent with a partially reliable policy.
+// It must be called after the handler returned.
+func (w *responseWriter) writeTrailers() {
+	fields := make([]qpack.HeaderField, 0, len(w.trailers)+1)
+	for _, k := range w.trailers {
+		if k == PRSkippedRangesTrailer {
+			continue
+		}
+		for _, v := range w.header[k] {
+			fields = append(fields, qpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
+	}
+	for k, vv := range w.header {
+		if !strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(k, http.TrailerPrefix))
+		if !httpguts.ValidTrailerHeader(name) {
+			continue
+		}
+		for _, v := range vv {
+			fields = append(fields, qpack.HeaderField{Name: name, Value: v})
+		}
+	}
+	// The PRSkippedRangesTrailer can only be computed once all data of the response was either acknowledged or skipped.
+	if w.prTrailer && w.waitForDelivery() {
+		fields = append(fields, qpack.HeaderField{
+			Name:  strings.ToLower(PRSkippedRangesTrailer),
+			Value: formatPRSkippedRanges(skippedBodyRanges(w.bodySegments, w.str.AckedRanges())),
+		})
+	}
+	if len(fields) == 0 {
+		return
+	}
+	trailers := w.encoder.Encode(w.str, fields)
+	if err := w.encoder.Flush(); err != nil {
+		w.logger.Errorf("could not write QPACK encoder instructions: %s", err.Error())
+	}
+	w.buf = w.buf[:0]
+	w.buf = (&headersFrame{Length: uint64(len(trailers))}).Append(w.buf)
+	w.buf = append(w.buf, trailers...)
+	var err error
+	if w.pr {
+		_, err = w.writeReliably(w.buf)
+	} else {
+		_, err = w.write(w.buf)
+	}
+	if err != nil {
+		w.logger.Errorf("could not write trailers: %s", err.Error())
+	}
+}
+
+// waitForDelivery blocks until all data written to the stream was acknowledged or skipped.
+// It returns false if the write side of the stream was closed before.
+func (w *responseWriter) waitForDelivery() bool {
+	signals := make(chan bool, 2)
+	w.str.SetWriteBufferWatermarks(0, 1, func(pause bool) { signals <- pause })
+	defer w.str.SetWriteBufferWatermarks(0, 0, nil)
+	// If data is buffered, the callback is called with pause set to true before SetWriteBufferWatermarks returns.
+	if len(signals) == 0 {
+		return true
+	}
+	<-signals
+	select {
+	case <-signals:
+		return true
+	case <-w.str.Context().Done():
+		return false
+	}
+}
+
+func (w *responseWriter) SetPRPolicy(policy *quic.PRPolicy) error {
+	if w.headerWritten {
+		return errors.New("http3: SetPRPolicy called after the response header was written")
+	}
+	w.str.SetPRPolicy(policy)
+	return nil
+}
+
 func (w *responseWriter) StreamCreator() StreamCreator {
 	return w.conn
 }
@@ -2,9 +2,12 @@ package http3
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
 
+	"github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 
@@ -17,14 +20,20 @@ import (
 
 var _ = Describe("Response Writer", func() {
 	var (
-		rw     *responseWriter
-		strBuf *bytes.Buffer
+		rw       *responseWriter
+		str      *mockquic.MockStream
+		strBuf   *bytes.Buffer
+		prPolicy quic.PRPolicy
 	)
 
 	BeforeEach(func() {
 		strBuf = &bytes.Buffer{}
-		str := mockquic.NewMockStream(mockCtrl)
+		prPolicy = quic.PRPolicy{}
+		str = mockquic.NewMockStream(mockCtrl)
 		str.EXPECT().Write(gomock.Any()).DoAndReturn(strBuf.Write).AnyTimes()
+		str.EXPECT().EffectivePRPolicy().DoAndReturn(func() (quic.PRPolicy, quic.PRPolicySource) {
+			return prPolicy, quic.PRPolicySourceStream
+		}).AnyTimes()
 		rw = newResponseWriter(str, nil, utils.DefaultLogger)
 	})
 
@@ -59,6 +68,22 @@ var _ = Describe("Response Writer", func() {
 		return data
 	}
 
+	getTrailer := func(str io.Reader) map[string][]string {
+		frame, err := parseNextFrame(str, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
+		data := make([]byte, frame.(*headersFrame).Length)
+		_, err = io.ReadFull(str, data)
+		Expect(err).ToNot(HaveOccurred())
+		hfs, err := qpack.NewDecoder(nil).DecodeFull(data)
+		Expect(err).ToNot(HaveOccurred())
+		fields := make(map[string][]string)
+		for _, p := range hfs {
+			fields[p.Name] = append(fields[p.Name], p.Value)
+		}
+		return fields
+	}
+
 	It("writes status", func() {
 		rw.WriteHeader(http.StatusTeapot)
 		fields := decodeHeader(strBuf)
@@ -141,10 +166,261 @@ var _ = Describe("Response Writer", func() {
 		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
 	})
 
+	It("doesn't announce trailers in informational responses", func() {
+		rw.Header().Set("Trailer", "Checksum")
+		rw.WriteHeader(http.StatusEarlyHints)
+		rw.WriteHeader(http.StatusOK)
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"103"}))
+		Expect(fields).ToNot(HaveKey("trailer"))
+		fields = decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(fields).To(HaveKeyWithValue("trailer", []string{"Checksum"}))
+	})
+
+	It("sends the declared trailers after the body", func() {
+		rw.Header().Set("Trailer", "Checksum, Expires")
+		rw.WriteHeader(http.StatusOK)
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		rw.Header().Set("Checksum", "deadbeef")
+		rw.Header().Set("Not-Declared", "foo")
+		rw.writeTrailers()
+		rw.Flush()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue("trailer", []string{"Checksum, Expires"}))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		Expect(getTrailer(strBuf)).To(Equal(map[string][]string{"checksum": {"deadbeef"}}))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("sends trailers set using the TrailerPrefix", func() {
+		rw.WriteHeader(http.StatusOK)
+		rw.Header().Set(http.TrailerPrefix+"Checksum", "deadbeef")
+		rw.Header().Set(http.TrailerPrefix+"Content-Length", "42") // not allowed in trailers
+		rw.writeTrailers()
+		rw.Flush()
+		Expect(decodeHeader(strBuf)).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(getTrailer(strBuf)).To(Equal(map[string][]string{"checksum": {"deadbeef"}}))
+	})
+
+	It("doesn't send a HEADERS frame if there are no trailers", func() {
+		rw.WriteHeader(http.StatusOK)
+		rw.Flush()
+		strBuf.Reset()
+		rw.writeTrailers()
+		rw.Flush()
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
 	It("doesn't allow writes if the status code doesn't allow a body", func() {
 		rw.WriteHeader(304)
 		n, err := rw.Write([]byte("foobar"))
 		Expect(n).To(BeZero())
 		Expect(err).To(MatchError(http.ErrBodyNotAllowed))
 	})
+
+	It("sets the PR policy", func() {
+		policy := &quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 100}
+		str.EXPECT().SetPRPolicy(policy)
+		Expect(rw.SetPRPolicy(policy)).To(Succeed())
+	})
+
+	It("refuses to set the PR policy after the header was written", func() {
+		rw.WriteHeader(http.StatusOK)
+		Expect(rw.SetPRPolicy(&quic.PRPolicy{PTDA: quic.PTDAAbandon})).To(MatchError("http3: SetPRPolicy called after the response header was written"))
+	})
+
+	It("returns the error when flushing", func() {
+		testErr := errors.New("test error")
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().EffectivePRPolicy().AnyTimes()
+		str.EXPECT().Write(gomock.Any()).Return(0, testErr)
+		rw := newResponseWriter(str, nil, utils.DefaultLogger)
+		rw.WriteHeader(http.StatusOK)
+		Expect(rw.FlushError()).To(MatchError(testErr))
+	})
+
+	Context("writing with a policy", func() {
+		var policyWrites []quic.PRPolicy
+
+		BeforeEach(func() {
+			policyWrites = nil
+			str.EXPECT().WriteWithPolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(b []byte, policy quic.PRPolicy) (int, error) {
+				policyWrites = append(policyWrites, policy)
+				return strBuf.Write(b)
+			}).AnyTimes()
+		})
+
+		It("writes the chunk without buffering it", func() {
+			rw.Header().Set("Content-Type", "video/mp4")
+			policy := quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 500}
+			n, err := rw.WriteWithPolicy([]byte("chunk"), policy)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(5))
+			// the HEADERS frame and the DATA frame header are sent reliably
+			Expect(policyWrites).To(Equal([]quic.PRPolicy{{}, policy}))
+			Expect(decodeHeader(strBuf)).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(getData(strBuf)).To(Equal([]byte("chunk")))
+		})
+
+		It("keeps track of the body offset when mixing buffered writes and writes with a policy", func() {
+			_, err := rw.Write([]byte("manifest"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rw.WriteWithPolicy([]byte("chunk"), quic.PRPolicy{PTDA: quic.PTDAAbandon})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.bodySegments).To(HaveLen(1))
+			Expect(rw.bodySegments[0].bodyOffset).To(BeEquivalentTo(8))
+			Expect(rw.bodySegments[0].length).To(BeEquivalentTo(5))
+			Expect(decodeHeader(strBuf)).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(getData(strBuf)).To(Equal([]byte("manifest")))
+			Expect(getData(strBuf)).To(Equal([]byte("chunk")))
+		})
+
+		It("sends the skipped ranges, even if the trailer wasn't announced", func() {
+			_, err := rw.Write([]byte("manifest"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rw.WriteWithPolicy([]byte("chunk"), quic.PRPolicy{PTDA: quic.PTDAAbandon})
+			Expect(err).ToNot(HaveOccurred())
+			seg := rw.bodySegments[0]
+			str.EXPECT().SetWriteBufferWatermarks(uint64(0), uint64(1), gomock.Any())
+			str.EXPECT().SetWriteBufferWatermarks(uint64(0), uint64(0), nil)
+			str.EXPECT().AckedRanges().Return([]quic.ByteRange{{Start: 0, End: seg.offset + 2}})
+			rw.writeTrailers()
+			rw.Flush()
+			Expect(decodeHeader(strBuf)).ToNot(HaveKey("trailer"))
+			Expect(getData(strBuf)).To(Equal([]byte("manifest")))
+			Expect(getData(strBuf)).To(Equal([]byte("chunk")))
+			Expect(getTrailer(strBuf)).To(Equal(map[string][]string{"pr-skipped-ranges": {"10-12"}}))
+		})
+
+		It("doesn't allow writes if the status code doesn't allow a body", func() {
+			rw.WriteHeader(http.StatusNoContent)
+			_, err := rw.WriteWithPolicy([]byte("chunk"), quic.PRPolicy{PTDA: quic.PTDAAbandon})
+			Expect(err).To(MatchError(http.ErrBodyNotAllowed))
+		})
+	})
+
+	Context("partially reliable bodies", func() {
+		var (
+			watermarkCallback chan func(bool)
+			reliableWrites    [][]byte
+		)
+
+		BeforeEach(func() {
+			prPolicy = quic.PRPolicy{PTDA: quic.PTDAAbandon}
+			reliableWrites = nil
+			str.EXPECT().WriteWithPolicy(gomock.Any(), quic.PRPolicy{}).DoAndReturn(func(b []byte, _ quic.PRPolicy) (int, error) {
+				reliableWrites = append(reliableWrites, append([]byte{}, b...))
+				return strBuf.Write(b)
+			}).AnyTimes()
+			watermarkCallback = make(chan func(bool), 1)
+			str.EXPECT().SetWriteBufferWatermarks(uint64(0), uint64(1), gomock.Any()).Do(func(_, _ uint64, cb func(bool)) {
+				cb(true) // the response is still buffered
+				watermarkCallback <- cb
+			}).MaxTimes(1)
+			str.EXPECT().SetWriteBufferWatermarks(uint64(0), uint64(0), nil).MaxTimes(1)
+		})
+
+		It("announces the trailer", func() {
+			rw.WriteHeader(http.StatusOK)
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue("trailer", []string{"pr-skipped-ranges"}))
+		})
+
+		It("doesn't announce the trailer if the status code doesn't allow a body", func() {
+			rw.WriteHeader(http.StatusNotModified)
+			fields := decodeHeader(strBuf)
+			Expect(fields).ToNot(HaveKey("trailer"))
+			rw.writeTrailers()
+			Expect(strBuf.Len()).To(BeZero())
+		})
+
+		It("sends the HEADERS frame and the DATA frame headers reliably", func() {
+			_, err := rw.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reliableWrites).To(HaveLen(2))
+			fields := decodeHeader(bytes.NewReader(reliableWrites[0]))
+			Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(reliableWrites[1]).To(Equal((&dataFrame{Length: 6}).Append(nil)))
+			// the payload is sent with the policy of the stream
+			fields = decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		})
+
+		It("sends the skipped ranges once the body was delivered", func() {
+			_, err := rw.Write([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rw.Write([]byte("barbaz"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.bodySegments).To(HaveLen(2))
+			// skip "o" from the first DATA frame and "b" from the second one
+			acked := []quic.ByteRange{
+				{Start: 0, End: rw.bodySegments[0].offset + 2},
+				{Start: rw.bodySegments[1].offset + 1, End: rw.bodySegments[1].offset + 6},
+			}
+			str.EXPECT().Context().Return(context.Background())
+			str.EXPECT().AckedRanges().Return(acked)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				rw.writeTrailers()
+			}()
+			var cb func(bool)
+			Eventually(watermarkCallback).Should(Receive(&cb))
+			Consistently(done).ShouldNot(BeClosed())
+			cb(false) // all data was acknowledged or skipped
+			Eventually(done).Should(BeClosed())
+
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue("trailer", []string{"pr-skipped-ranges"}))
+			Expect(getData(strBuf)).To(Equal([]byte("foo")))
+			Expect(getData(strBuf)).To(Equal([]byte("barbaz")))
+			Expect(getTrailer(strBuf)).To(Equal(map[string][]string{"pr-skipped-ranges": {"2-3"}}))
+		})
+
+		It("sends the declared trailers together with the skipped ranges", func() {
+			rw.Header().Set("Trailer", "Checksum")
+			_, err := rw.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			rw.Header().Set("Checksum", "deadbeef")
+			str.EXPECT().Context().Return(context.Background())
+			str.EXPECT().AckedRanges().Return([]quic.ByteRange{{Start: 0, End: rw.offset}})
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				rw.writeTrailers()
+			}()
+			var cb func(bool)
+			Eventually(watermarkCallback).Should(Receive(&cb))
+			cb(false)
+			Eventually(done).Should(BeClosed())
+
+			trailer := reliableWrites[len(reliableWrites)-1]
+			Expect(getTrailer(bytes.NewReader(trailer))).To(Equal(map[string][]string{
+				"checksum":          {"deadbeef"},
+				"pr-skipped-ranges": {""},
+			}))
+		})
+
+		It("doesn't send the trailer if the stream is canceled before the body was delivered", func() {
+			_, err := rw.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			ctx, cancel := context.WithCancel(context.Background())
+			str.EXPECT().Context().Return(ctx)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				rw.writeTrailers()
+			}()
+			Eventually(watermarkCallback).Should(Receive())
+			Consistently(done).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	})
 })
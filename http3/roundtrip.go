@@ -75,6 +75,18 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table, in bytes (see RFC 9204).
+	// Header fields that are repeated across requests and responses are inserted into the dynamic table,
+	// which greatly reduces the header overhead when making many small requests, e.g. for the segments of a video stream.
+	// It limits the table used to decode the responses as well as the table used to encode the requests.
+	// If zero, only the static table is used.
+	QPACKMaxTableCapacity uint64
+
+	// QPACKBlockedStreams is the maximum number of request streams that can be blocked,
+	// waiting for the server to send the dynamic table entries referenced by the response header.
+	// It is only used if QPACKMaxTableCapacity is set.
+	QPACKBlockedStreams uint64
+
 	clients map[string]roundTripCloser
 }
 
@@ -137,9 +149,40 @@ func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.
 	if err != nil {
 		return nil, err
 	}
+	rsp, err := cl.RoundTripOpt(req, opt)
+	if err != errGoAway {
+		return rsp, err
+	}
+	// The server is shutting down the connection, and didn't process the request.
+	// Retry it on a new connection.
+	r.removeClient(hostname, cl)
+	if req, err = rewindBody(req); err != nil {
+		return nil, err
+	}
+	if cl, err = r.getClient(hostname, opt.OnlyCachedConn); err != nil {
+		return nil, err
+	}
 	return cl.RoundTripOpt(req, opt)
 }
 
+// rewindBody returns a request that can be sent again.
+// A request with a body can only be sent again if the body can be obtained using GetBody.
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errGoAway
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	newReq := *req
+	newReq.Body = body
+	return &newReq, nil
+}
+
 // RoundTrip does a round trip.
 func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return r.RoundTripOpt(req, RoundTripOpt{})
@@ -163,11 +206,13 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (roundTripClo
 			hostname,
 			r.TLSClientConfig,
 			&roundTripperOpts{
-				EnableDatagram:     r.EnableDatagrams,
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
-				StreamHijacker:     r.StreamHijacker,
-				UniStreamHijacker:  r.UniStreamHijacker,
+				EnableDatagram:        r.EnableDatagrams,
+				DisableCompression:    r.DisableCompression,
+				MaxHeaderBytes:        r.MaxResponseHeaderBytes,
+				QPACKMaxTableCapacity: r.QPACKMaxTableCapacity,
+				QPACKBlockedStreams:   r.QPACKBlockedStreams,
+				StreamHijacker:        r.StreamHijacker,
+				UniStreamHijacker:     r.UniStreamHijacker,
 			},
 			r.QuicConfig,
 			r.Dial,
@@ -180,6 +225,16 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (roundTripClo
 	return client, nil
 }
 
+// removeClient removes the client for hostname, if it wasn't replaced yet.
+// The client's connection is not closed, since other requests might still be running on it.
+func (r *RoundTripper) removeClient(hostname string, cl roundTripCloser) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.clients[hostname] == cl {
+		delete(r.clients, hostname)
+	}
+}
+
 // Close closes the QUIC connections that this RoundTripper has used
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
@@ -17,10 +17,14 @@ import (
 )
 
 type mockClient struct {
-	closed bool
+	closed       bool
+	roundTripErr error
 }
 
 func (m *mockClient) RoundTripOpt(req *http.Request, _ RoundTripOpt) (*http.Response, error) {
+	if m.roundTripErr != nil {
+		return nil, m.roundTripErr
+	}
 	return &http.Response{Request: req}, nil
 }
 
@@ -231,6 +235,38 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("GOAWAY handling", func() {
+		It("retries the request on a new connection", func() {
+			rt.clients = make(map[string]roundTripCloser)
+			rt.clients["quic.clemente.io:443"] = &mockClient{roundTripErr: errGoAway}
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			// The cached client is removed, and OnlyCachedConn prevents dialing a new connection.
+			_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			Expect(rt.clients).To(BeEmpty())
+		})
+
+		It("rewinds the request body", func() {
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/upload", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.ReadAll(req.Body)
+			Expect(err).ToNot(HaveOccurred())
+			newReq, err := rewindBody(req)
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(newReq.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't retry requests with a body that can't be rewound", func() {
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/upload", &mockBody{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rewindBody(req)
+			Expect(err).To(MatchError(errGoAway))
+		})
+	})
+
 	Context("closing", func() {
 		It("closes", func() {
 			rt.clients = make(map[string]roundTripCloser)
@@ -9,7 +9,6 @@ import (
 	"net"
 	"net/http"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
@@ -143,6 +142,12 @@ type Server struct {
 	// listening on.
 	Port int
 
+	// AltSvc are the endpoints advertised in Alt-Svc response headers set with SetQuicHeaders.
+	// If set, they are advertised instead of the ports derived from the listeners, Port and Addr,
+	// even if the server isn't listening yet.
+	// This allows advertising multiple ports or custom ALPNs, e.g. when running behind a load balancer.
+	AltSvc []AltSvcEndpoint
+
 	// TLSConfig provides a TLS configuration for use by server. It must be
 	// set for ListenAndServe and Serve methods.
 	TLSConfig *tls.Config
@@ -169,6 +174,32 @@ type Server struct {
 	// used.
 	MaxHeaderBytes int
 
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table, in bytes (see RFC 9204).
+	// Header fields that are repeated across requests and responses are inserted into the dynamic table,
+	// which greatly reduces the header overhead when serving many small requests, e.g. for the segments of a video stream.
+	// It limits the table used to decode the requests as well as the table used to encode the responses.
+	// If zero, only the static table is used.
+	QPACKMaxTableCapacity uint64
+
+	// QPACKBlockedStreams is the maximum number of request streams that can be blocked,
+	// waiting for the client to send the dynamic table entries referenced by the request header.
+	// It is only used if QPACKMaxTableCapacity is set.
+	QPACKBlockedStreams uint64
+
+	// EnableExtendedConnect enables support for extended CONNECT requests (see RFC 9220).
+	// The :protocol pseudo-header field is stored in the Proto field of the http.Request.
+	// The handler can take over the request stream using the HTTPStreamer implemented by the request body,
+	// and open new streams using the Hijacker implemented by the http.ResponseWriter,
+	// e.g. to implement WebTransport.
+	// If not set, extended CONNECT requests are rejected.
+	EnableExtendedConnect bool
+
+	// EnablePRPolicyHeader makes the server send the response with the partially reliable policy
+	// requested by the client in the PRPolicyHeader.
+	// Malformed values are ignored. Handlers can override the policy using the PRPolicySetter,
+	// or choose the policy for every write using the PRWriter.
+	EnablePRPolicyHeader bool
+
 	// AdditionalSettings specifies additional HTTP/3 settings.
 	// It is invalid to specify any settings defined by the HTTP/3 draft and the datagram draft.
 	AdditionalSettings map[uint64]uint64
@@ -191,6 +222,8 @@ type Server struct {
 	listeners map[*quic.EarlyListener]listenerInfo
 
 	closed bool
+	// closed when CloseGracefully is called
+	closing chan struct{}
 
 	altSvcHeader string
 
@@ -339,10 +372,10 @@ func (s *Server) generateAltSvcHeader() {
 		}
 	}
 
-	var altSvc []string
+	var altSvc []AltSvcEndpoint
 	addPort := func(port int) {
 		for _, v := range versionStrings {
-			altSvc = append(altSvc, fmt.Sprintf(`%s=":%d"; ma=2592000`, v, port))
+			altSvc = append(altSvc, AltSvcEndpoint{ALPN: v, Port: port})
 		}
 	}
 
@@ -367,7 +400,7 @@ func (s *Server) generateAltSvcHeader() {
 		}
 	}
 
-	s.altSvcHeader = strings.Join(altSvc, ",")
+	s.altSvcHeader = formatAltSvc(altSvc)
 }
 
 // We store a pointer to interface in the map set. This is safe because we only
@@ -406,19 +439,62 @@ func (s *Server) removeListener(l *quic.EarlyListener) {
 }
 
 func (s *Server) handleConn(conn quic.EarlyConnection) {
-	decoder := qpack.NewDecoder(nil)
+	var decoder *qpackDecoder
+	var encoder *qpackEncoder
+	if s.QPACKMaxTableCapacity > 0 {
+		decoder = newQPACKDecoder(conn, s.QPACKMaxTableCapacity, s.QPACKBlockedStreams)
+		encoder = newQPACKEncoder(conn, s.QPACKMaxTableCapacity)
+	}
+
+	closing := s.closingChan()
 
 	// send a SETTINGS frame
-	str, err := conn.OpenUniStream()
+	ctrlStr, err := conn.OpenUniStream()
 	if err != nil {
 		s.logger.Debugf("Opening the control stream failed.")
 		return
 	}
+	// Skipping data on the control stream would corrupt the HTTP/3 state.
+	ctrlStr.DisablePR()
 	b := make([]byte, 0, 64)
 	b = quicvarint.Append(b, streamTypeControlStream) // stream type
-	b = (&settingsFrame{Datagram: s.EnableDatagrams, Other: s.AdditionalSettings}).Append(b)
-	str.Write(b)
-	go s.handleUnidirectionalStreams(conn)
+	b = (&settingsFrame{
+		Datagram:              s.EnableDatagrams,
+		ExtendedConnect:       s.EnableExtendedConnect,
+		QPACKMaxTableCapacity: s.QPACKMaxTableCapacity,
+		QPACKBlockedStreams:   s.QPACKBlockedStreams,
+		Other:                 s.AdditionalSettings,
+	}).Append(b)
+	ctrlStr.Write(b)
+	go s.handleUnidirectionalStreams(conn, decoder, encoder)
+
+	var (
+		mutex    sync.Mutex
+		requests sync.WaitGroup
+		// the lowest stream ID that wasn't accepted yet
+		nextStreamID quic.StreamID
+		goAwaySent   bool
+	)
+	// closed when the connection is closed, and no more streams are accepted
+	connDone := make(chan struct{})
+	defer close(connDone)
+	// When the server is closed gracefully, send a GOAWAY frame, and close the connection once all requests completed.
+	go func() {
+		select {
+		case <-closing:
+		case <-connDone:
+			return
+		}
+		mutex.Lock()
+		goAwaySent = true
+		// Since streams are accepted in order, all requests below nextStreamID were already accepted.
+		if _, err := ctrlStr.Write((&goAwayFrame{StreamID: nextStreamID}).Append(nil)); err != nil {
+			s.logger.Debugf("Sending the GOAWAY frame failed: %s", err)
+		}
+		mutex.Unlock()
+		requests.Wait()
+		conn.CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
+	}()
 
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
@@ -428,8 +504,20 @@ func (s *Server) handleConn(conn quic.EarlyConnection) {
 			s.logger.Debugf("Accepting stream failed: %s", err)
 			return
 		}
+		mutex.Lock()
+		if goAwaySent {
+			mutex.Unlock()
+			// The client can safely retry this request on a new connection.
+			str.CancelRead(quic.StreamErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.StreamErrorCode(errorRequestRejected))
+			continue
+		}
+		nextStreamID = str.StreamID() + 4
+		requests.Add(1)
+		mutex.Unlock()
 		go func() {
-			rerr := s.handleRequest(conn, str, decoder, func() {
+			defer requests.Done()
+			rerr := s.handleRequest(conn, str, decoder, encoder, func() {
 				conn.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
 			})
 			if rerr.err == errHijacked {
@@ -454,7 +542,18 @@ func (s *Server) handleConn(conn quic.EarlyConnection) {
 	}
 }
 
-func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection) { 
+// closingChan returns a channel that is closed when the server is closed gracefully.
+func (s *Server) closingChan() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closing == nil {
+		s.closing = make(chan struct{})
+	}
+	return s.closing
+}
+
+func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection, decoder *qpackDecoder, encoder *qpackEncoder) {
 	//EarlyConnection也是Connection，其接口中的方法被Connection实现
 	for {
 		str, err := conn.AcceptUniStream(context.Background())
@@ -474,9 +573,16 @@ func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection) {
 			// We're only interested in the control stream here.
 			switch streamType {
 			case streamTypeControlStream:
-			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
-				// Our QPACK implementation doesn't use the dynamic table yet.
-				// TODO: check that only one stream of each type is opened.
+			case streamTypeQPACKEncoderStream:
+				// Without a dynamic table, the client can't insert any entries.
+				if decoder != nil {
+					handleQPACKStream(conn, str, errorQPACKEncoderStreamError, decoder.HandleEncoderStream)
+				}
+				return
+			case streamTypeQPACKDecoderStream:
+				if encoder != nil {
+					handleQPACKStream(conn, str, errorQPACKDecoderStreamError, encoder.HandleDecoderStream)
+				}
 				return
 			case streamTypePushStream: // only the server can push
 				conn.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), "")
@@ -488,6 +594,8 @@ func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection) {
 				str.CancelRead(quic.StreamErrorCode(errorStreamCreationError))
 				return
 			}
+			// The peer must send the control stream reliably.
+			str.SetReadSkippedAsError(true)
 			f, err := parseNextFrame(str, nil)
 			if err != nil {
 				conn.CloseWithError(quic.ApplicationErrorCode(errorFrameError), "")
@@ -498,6 +606,7 @@ func (s *Server) handleUnidirectionalStreams(conn quic.EarlyConnection) {
 				conn.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
+			encoder.SetPeerSettings(sf.QPACKMaxTableCapacity, sf.QPACKBlockedStreams)
 			if !sf.Datagram {
 				return
 			}
@@ -518,7 +627,7 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
+func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *qpackDecoder, encoder *qpackEncoder, onFrameError func()) requestError {
 	var ufh unknownFrameHandlerFunc
 	if s.StreamHijacker != nil {
 		ufh = func(ft FrameType, e error) (processed bool, err error) { return s.StreamHijacker(ft, conn, str, e) }
@@ -541,19 +650,35 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
 		return newStreamError(errorRequestIncomplete, err)
 	}
-	hfs, err := decoder.DecodeFull(headerBlock)
+	hfs, err := decoder.Decode(str, headerBlock)
 	if err != nil {
-		// TODO: use the right error code
-		return newConnError(errorGeneralProtocolError, err)
+		return newConnError(errorQPACKDecompressionFailed, err)
 	}
 	req, err := requestFromHeaders(hfs)
 	if err != nil {
 		// TODO: use the right error code
 		return newStreamError(errorGeneralProtocolError, err)
 	}
+	// Clients may only use extended CONNECT if we enabled it in our SETTINGS, see RFC 9220, section 3.
+	if isExtendedConnect(req) && !s.EnableExtendedConnect {
+		return newStreamError(errorMessageError, errors.New("extended CONNECT not enabled"))
+	}
 
 	req.RemoteAddr = conn.RemoteAddr().String()
-	body := newRequestBody(newStream(str, onFrameError))
+	hstr := newStream(str, onFrameError)
+	hstr.maxTrailerBytes = s.maxHeaderBytes()
+	hstr.decoder = decoder
+	trailer := req.Trailer
+	hstr.onTrailers = func(hfs []qpack.HeaderField) {
+		if trailer == nil {
+			trailer = make(http.Header)
+			req.Trailer = trailer
+		}
+		for _, hf := range hfs {
+			trailer.Add(hf.Name, hf.Value)
+		}
+	}
+	body := newRequestBody(hstr)
 	req.Body = body
 
 	if s.logger.Debug() {
@@ -567,7 +692,17 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, conn.LocalAddr())
 	req = req.WithContext(ctx)
 	r := newResponseWriter(str, conn, s.logger)
+	r.encoder = encoder
 	defer r.Flush()
+	if s.EnablePRPolicyHeader {
+		if v := req.Header.Get(PRPolicyHeader); v != "" {
+			if policy, err := ParsePRPolicy(v); err != nil {
+				s.logger.Debugf("Ignoring %s header: %s", PRPolicyHeader, err)
+			} else {
+				r.SetPRPolicy(&policy)
+			}
+		}
+	}
 	handler := s.Handler
 	if handler == nil {
 		handler = http.DefaultServeMux
@@ -597,6 +732,7 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	} else {
 		r.WriteHeader(200)
 	}
+	r.writeTrailers()
 	// If the EOF was read by the handler, CancelRead() is a no-op.
 	// 处理对端的请求时读取流中的数据
 	str.CancelRead(quic.StreamErrorCode(errorNoError))
@@ -621,10 +757,41 @@ func (s *Server) Close() error {
 }
 
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
+// Requests that the clients send after receiving the GOAWAY frame are rejected, and can be retried on a new connection.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	s.mutex.Lock()
+	s.closed = true
+	if s.closing == nil {
+		s.closing = make(chan struct{})
+	}
+	select {
+	case <-s.closing:
+	default:
+		close(s.closing)
+	}
+	listeners := make([]quic.EarlyListener, 0, len(s.listeners))
+	for ln := range s.listeners {
+		listeners = append(listeners, *ln)
+	}
+	s.mutex.Unlock()
+
+	// Every connection is closed once all its requests completed.
+	// The listeners stop accepting new connections, and close the remaining connections when the timeout triggers.
+	errChan := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func(ln quic.EarlyListener) { errChan <- ln.CloseGracefully(ctx) }(ln)
+	}
+	var err error
+	for range listeners {
+		if cerr := <-errChan; cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
 // ErrNoAltSvcPort is the error returned by SetQuicHeaders when no port was found
@@ -641,6 +808,11 @@ var ErrNoAltSvcPort = errors.New("no port can be announced, specify it explicitl
 //
 //	Alt-Svc: h3=":443"; ma=2592000,h3-29=":443"; ma=2592000
 func (s *Server) SetQuicHeaders(hdr http.Header) error {
+	if len(s.AltSvc) > 0 {
+		hdr["Alt-Svc"] = append(hdr["Alt-Svc"], formatAltSvc(s.AltSvc))
+		return nil
+	}
+
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
@@ -653,6 +825,20 @@ func (s *Server) SetQuicHeaders(hdr http.Header) error {
 	return nil
 }
 
+// ConfigureHTTPServer makes a HTTP/1.1 or HTTP/2 server advertise this server,
+// by setting the Alt-Svc header on every response using SetQuicHeaders.
+// It wraps srv.Handler (or http.DefaultServeMux, if nil), and must be called before srv is started.
+func (s *Server) ConfigureHTTPServer(srv *http.Server) {
+	handler := srv.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.SetQuicHeaders(w.Header())
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // ListenAndServeQUIC listens on the UDP network address addr and calls the
 // handler for HTTP/3 requests on incoming connections. http.DefaultServeMux is
 // used when handler is nil.
@@ -718,12 +904,8 @@ func ListenAndServe(addr, certFile, keyFile string, handler http.Handler) error
 		TLSConfig: config,
 		Handler:   handler,
 	}
-	httpServer := &http.Server{
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			quicServer.SetQuicHeaders(w.Header())
-			handler.ServeHTTP(w, r)
-		}),
-	}
+	httpServer := &http.Server{Handler: handler}
+	quicServer.ConfigureHTTPServer(httpServer)
 
 	hErr := make(chan error)
 	qErr := make(chan error)
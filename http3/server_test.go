@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"runtime"
 	"sync/atomic"
 	"time"
@@ -104,7 +105,6 @@ var _ = Describe("Server", func() {
 
 	Context("handling requests", func() {
 		var (
-			qpackDecoder       *qpack.Decoder
 			str                *mockquic.MockStream
 			conn               *mockquic.MockEarlyConnection
 			exampleGetRequest  *http.Request
@@ -157,8 +157,9 @@ var _ = Describe("Server", func() {
 			examplePostRequest, err = http.NewRequest("POST", "https://www.example.com", bytes.NewReader([]byte("foobar")))
 			Expect(err).ToNot(HaveOccurred())
 
-			qpackDecoder = qpack.NewDecoder(nil)
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
+			str.EXPECT().EffectivePRPolicy().AnyTimes()
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
 			addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
 			conn.EXPECT().RemoteAddr().Return(addr).AnyTimes()
@@ -178,7 +179,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(conn, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(conn, str, nil, nil, nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
@@ -195,12 +196,127 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
 		})
 
+		It("passes the request trailers to the handler", func() {
+			trailerChan := make(chan http.Header, 1)
+			s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				Expect(r.Trailer).To(Equal(http.Header{"Checksum": nil}))
+				body, err := io.ReadAll(r.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("foobar"))
+				trailerChan <- r.Trailer
+			})
+
+			examplePostRequest.Trailer = http.Header{"Checksum": []string{"deadbeef"}}
+			data := encodeRequest(examplePostRequest)
+			data = (&dataFrame{Length: 6}).Append(data)
+			data = append(data, []byte("foobar")...)
+			reqStr := mockquic.NewMockStream(mockCtrl)
+			trailerBuf := &bytes.Buffer{}
+			reqStr.EXPECT().Write(gomock.Any()).DoAndReturn(trailerBuf.Write).AnyTimes()
+			Expect(newRequestWriter(utils.DefaultLogger).WriteRequestTrailer(reqStr, examplePostRequest)).To(Succeed())
+			setRequest(append(data, trailerBuf.Bytes()...))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			Expect(s.handleRequest(conn, str, nil, nil, nil)).To(Equal(requestError{}))
+			var trailer http.Header
+			Eventually(trailerChan).Should(Receive(&trailer))
+			Expect(trailer).To(Equal(http.Header{"Checksum": []string{"deadbeef"}}))
+		})
+
+		It("sends the response trailers", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", "Checksum")
+				w.Write([]byte("foobar"))
+				w.Header().Set("Checksum", "deadbeef")
+			})
+
+			responseBuf := &bytes.Buffer{}
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			serr := s.handleRequest(conn, str, nil, nil, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			Expect(decodeHeader(responseBuf)).To(HaveKeyWithValue("trailer", []string{"Checksum"}))
+			frame, err := parseNextFrame(responseBuf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 6}))
+			responseBuf.Next(6)
+			Expect(decodeHeader(responseBuf)).To(Equal(map[string][]string{"checksum": {"deadbeef"}}))
+		})
+
+		Context("extended CONNECT", func() {
+			var connectRequest *http.Request
+
+			BeforeEach(func() {
+				var err error
+				connectRequest, err = http.NewRequest(http.MethodConnect, "https://www.example.com/webtransport", nil)
+				Expect(err).ToNot(HaveOccurred())
+				connectRequest.Proto = "webtransport"
+				setRequest(encodeRequest(connectRequest))
+			})
+
+			It("passes extended CONNECT requests to the handler, if enabled", func() {
+				s.EnableExtendedConnect = true
+				requestChan := make(chan *http.Request, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Expect(w).To(BeAssignableToTypeOf(&responseWriter{}))
+					Expect(w.(Hijacker).StreamCreator()).To(Equal(conn))
+					requestChan <- r
+				})
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+				Expect(s.handleRequest(conn, str, nil, nil, nil)).To(Equal(requestError{}))
+				var req *http.Request
+				Expect(requestChan).To(Receive(&req))
+				Expect(req.Method).To(Equal(http.MethodConnect))
+				Expect(req.Proto).To(Equal("webtransport"))
+				Expect(req.URL.Path).To(Equal("/webtransport"))
+			})
+
+			It("rejects extended CONNECT requests, if not enabled", func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Fail("Handler should not be called.")
+				})
+				serr := s.handleRequest(conn, str, nil, nil, nil)
+				Expect(serr.err).To(MatchError("extended CONNECT not enabled"))
+				Expect(serr.streamErr).To(Equal(errorMessageError))
+			})
+		})
+
+		Context("PR policy header", func() {
+			BeforeEach(func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+				exampleGetRequest.Header.Set(PRPolicyHeader, "deadline=200ms")
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+			})
+
+			It("uses the policy requested by the client", func() {
+				s.EnablePRPolicyHeader = true
+				str.EXPECT().SetPRPolicy(&quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 200})
+				Expect(s.handleRequest(conn, str, nil, nil, nil)).To(Equal(requestError{}))
+			})
+
+			It("ignores the header, if not enabled", func() {
+				Expect(s.handleRequest(conn, str, nil, nil, nil)).To(Equal(requestError{}))
+			})
+		})
+
 		It("handles a panicking handler", func() {
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				panic("foobar")
@@ -212,7 +328,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
@@ -227,6 +343,7 @@ var _ = Describe("Server", func() {
 				conn = mockquic.NewMockEarlyConnection(mockCtrl)
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Write(gomock.Any())
+				controlStr.EXPECT().DisablePR()
 				conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 				conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}).AnyTimes()
 				conn.EXPECT().LocalAddr().AnyTimes()
@@ -245,6 +362,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x41)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(unknownStr, nil)
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
@@ -268,6 +386,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x41)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				unknownStr.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestIncomplete))
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(unknownStr, nil)
@@ -292,6 +411,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x41)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				unknownStr.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestIncomplete))
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(unknownStr, nil)
@@ -309,6 +429,7 @@ var _ = Describe("Server", func() {
 				testErr := errors.New("test error")
 				done := make(chan struct{})
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				s.StreamHijacker = func(ft FrameType, _ quic.Connection, str quic.Stream, err error) (bool, error) {
 					defer close(done)
 					Expect(ft).To(BeZero())
@@ -339,6 +460,7 @@ var _ = Describe("Server", func() {
 				conn = mockquic.NewMockEarlyConnection(mockCtrl)
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Write(gomock.Any())
+				controlStr.EXPECT().DisablePR()
 				conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
 				conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}).AnyTimes()
@@ -358,6 +480,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x54)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return unknownStr, nil
@@ -375,6 +498,7 @@ var _ = Describe("Server", func() {
 				testErr := errors.New("test error")
 				done := make(chan struct{})
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				s.UniStreamHijacker = func(st StreamType, _ quic.Connection, str quic.ReceiveStream, err error) bool {
 					defer close(done)
 					Expect(st).To(BeZero())
@@ -405,6 +529,7 @@ var _ = Describe("Server", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, 0x54)
 				unknownStr := mockquic.NewMockStream(mockCtrl)
+				unknownStr.EXPECT().StreamID().AnyTimes()
 				unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
 				unknownStr.EXPECT().CancelRead(quic.StreamErrorCode(errorStreamCreationError))
 
@@ -429,6 +554,7 @@ var _ = Describe("Server", func() {
 				conn = mockquic.NewMockEarlyConnection(mockCtrl)
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Write(gomock.Any())
+				controlStr.EXPECT().DisablePR()
 				conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
 				conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}).AnyTimes()
@@ -443,6 +569,7 @@ var _ = Describe("Server", func() {
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				r := bytes.NewReader(b)
 				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+				controlStr.EXPECT().SetReadSkippedAsError(true)
 				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return controlStr, nil
 				})
@@ -506,6 +633,7 @@ var _ = Describe("Server", func() {
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				r := bytes.NewReader(b)
 				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+				controlStr.EXPECT().SetReadSkippedAsError(true)
 				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return controlStr, nil
 				})
@@ -529,6 +657,7 @@ var _ = Describe("Server", func() {
 				r := bytes.NewReader(b[:len(b)-1])
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+				controlStr.EXPECT().SetReadSkippedAsError(true)
 				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return controlStr, nil
 				})
@@ -576,6 +705,7 @@ var _ = Describe("Server", func() {
 				r := bytes.NewReader(b)
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+				controlStr.EXPECT().SetReadSkippedAsError(true)
 				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return controlStr, nil
 				})
@@ -606,6 +736,7 @@ var _ = Describe("Server", func() {
 				conn = mockquic.NewMockEarlyConnection(mockCtrl)
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Write(gomock.Any())
+				controlStr.EXPECT().DisablePR()
 				conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					<-testDone
@@ -758,7 +889,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -781,10 +912,86 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
+
+		It("closes connections gracefully", func() {
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().DisablePR()
+			goAwayChan := make(chan []byte, 1)
+			gomock.InOrder(
+				controlStr.EXPECT().Write(gomock.Any()), // SETTINGS frame
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+					goAwayChan <- b
+					return len(b), nil
+				}),
+			)
+			conn.EXPECT().OpenUniStream().Return(controlStr, nil)
+			testDone := make(chan struct{})
+			defer close(testDone)
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+
+			handlerCalled := make(chan struct{})
+			unblockHandler := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(handlerCalled)
+				<-unblockHandler
+			})
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
+			str.EXPECT().Close()
+
+			// the request sent after the GOAWAY frame
+			rejectedStr := mockquic.NewMockStream(mockCtrl)
+			rejected := make(chan struct{})
+			rejectedStr.EXPECT().CancelRead(quic.StreamErrorCode(errorRequestRejected))
+			rejectedStr.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestRejected)).Do(func(quic.StreamErrorCode) { close(rejected) })
+			acceptRejected := make(chan struct{})
+			connClosed := make(chan struct{})
+			gomock.InOrder(
+				conn.EXPECT().AcceptStream(gomock.Any()).Return(str, nil),
+				conn.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-acceptRejected
+					return rejectedStr, nil
+				}),
+				conn.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-connClosed
+					return nil, errors.New("connection closed")
+				}),
+			)
+			conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(errorNoError), "").Do(func(quic.ApplicationErrorCode, string) { close(connClosed) })
+
+			ln := mockquic.NewMockEarlyListener(mockCtrl)
+			ln.EXPECT().CloseGracefully(gomock.Any()).DoAndReturn(func(context.Context) error {
+				<-connClosed
+				return nil
+			})
+			var l quic.EarlyListener = ln
+			s.listeners = map[*quic.EarlyListener]listenerInfo{&l: {}}
+
+			go s.handleConn(conn)
+			Eventually(handlerCalled).Should(BeClosed())
+			errChan := make(chan error, 1)
+			go func() { errChan <- s.CloseGracefully(time.Minute) }()
+			// the request on stream 0 was accepted
+			Eventually(goAwayChan).Should(Receive(Equal((&goAwayFrame{StreamID: 4}).Append(nil))))
+			close(acceptRejected)
+			Eventually(rejected).Should(BeClosed())
+			Consistently(errChan).ShouldNot(Receive())
+			Expect(connClosed).ToNot(BeClosed())
+			close(unblockHandler)
+			Eventually(errChan).Should(Receive(BeNil()))
+			Eventually(connClosed).Should(BeClosed())
+		})
 	})
 
 	Context("setting http headers", func() {
@@ -888,6 +1095,31 @@ var _ = Describe("Server", func() {
 			checkSetHeaderError()
 		})
 
+		It("uses the configured endpoints", func() {
+			s.AltSvc = []AltSvcEndpoint{
+				{ALPN: "h3", Port: 443},
+				{ALPN: "h3-pr", Port: 8443},
+			}
+			// no listener required
+			checkSetHeaders(Equal(http.Header{"Alt-Svc": {`h3=":443"; ma=2592000,h3-pr=":8443"; ma=2592000`}}))
+			addListener(":1337", &ln1)
+			checkSetHeaders(Equal(http.Header{"Alt-Svc": {`h3=":443"; ma=2592000,h3-pr=":8443"; ma=2592000`}}))
+			removeListener(&ln1)
+		})
+
+		It("configures a http.Server", func() {
+			addListener(":443", &ln1)
+			defer removeListener(&ln1)
+			srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})}
+			s.ConfigureHTTPServer(srv)
+			rec := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			Expect(rec.Code).To(Equal(http.StatusTeapot))
+			Expect(rec.Header()).To(Equal(expected))
+		})
+
 		It("doesn't duplicate Alt-Svc values", func() {
 			s.QuicConfig.Versions = []quic.VersionNumber{quic.Version1, quic.Version1}
 			addListener(":443", &ln1)
@@ -0,0 +1,130 @@
+package quic
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// A HybridWriter writes messages to a stream, each of them consisting of a reliable header
+// and a partially reliable payload.
+// The header is always delivered, even if the payload is lost and skipped by the PR policy.
+// This way, the receiver learns about every message, and can tell if its payload arrived intact.
+//
+// Every message starts with a header, written with the fully reliable policy:
+//
+//	Sequence Number (i), Payload Length (i), Flags (8), Payload Checksum (32)
+//
+// The checksum is the CRC-32C of the payload. It is followed by the payload, written with the policy of the HybridWriter.
+// Flags are opaque to quic-go, they can be used by the application to describe the payload, e.g. its codec.
+type HybridWriter struct {
+	mx      sync.Mutex
+	str     SendStream
+	policy  PRPolicy
+	nextSeq uint64
+	hdr     []byte
+}
+
+// NewHybridWriter creates a new HybridWriter.
+// The payload of all messages is written using the policy.
+func NewHybridWriter(str SendStream, policy PRPolicy) *HybridWriter {
+	return &HybridWriter{str: str, policy: policy}
+}
+
+// WriteMessage writes a message, and returns its sequence number.
+// The header and the payload are never sent in the same STREAM frame.
+func (w *HybridWriter) WriteMessage(flags byte, p []byte) (uint64, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	seq := w.nextSeq
+	b := w.hdr[:0]
+	b = quicvarint.Append(b, seq)
+	b = quicvarint.Append(b, uint64(len(p)))
+	b = append(b, flags)
+	checksum := crc32.Checksum(p, crc32cTable)
+	b = append(b, byte(checksum>>24), byte(checksum>>16), byte(checksum>>8), byte(checksum))
+	w.hdr = b
+	if _, err := w.str.WriteWithPolicy(b, PRPolicy{}); err != nil {
+		return 0, err
+	}
+	w.nextSeq++
+	if len(p) == 0 {
+		return seq, nil
+	}
+	if _, err := w.str.WriteWithPolicy(p, w.policy); err != nil {
+		return 0, err
+	}
+	return seq, nil
+}
+
+// A HybridMessage is a message read by a HybridReader.
+type HybridMessage struct {
+	Seq   uint64
+	Flags byte
+	// Data is the payload of the message.
+	// Parts of the payload that were lost and skipped by the sender's PR policy are zero-filled.
+	Data []byte
+	// Intact says if the payload arrived completely, i.e. if it matches the checksum sent in the header.
+	Intact bool
+}
+
+// A HybridReader reads messages written by a HybridWriter.
+type HybridReader struct {
+	r              *bufio.Reader
+	maxMessageSize uint64
+}
+
+// NewHybridReader creates a new HybridReader.
+// Messages larger than maxMessageSize cause ReadMessage to return an error.
+func NewHybridReader(str ReceiveStream, maxMessageSize int) *HybridReader {
+	return &HybridReader{
+		r:              bufio.NewReader(str),
+		maxMessageSize: uint64(maxMessageSize),
+	}
+}
+
+// ReadMessage reads the next message.
+// It returns io.EOF when the stream was closed after a complete message.
+func (r *HybridReader) ReadMessage() (*HybridMessage, error) {
+	seq, err := quicvarint.Read(r.r)
+	if err != nil {
+		return nil, err
+	}
+	length, err := quicvarint.Read(r.r)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if length > r.maxMessageSize {
+		return nil, fmt.Errorf("message %d too large: %d bytes (maximum %d bytes)", seq, length, r.maxMessageSize)
+	}
+	var hdr [5]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		return nil, noEOF(err)
+	}
+	msg := &HybridMessage{
+		Seq:   seq,
+		Flags: hdr[0],
+		Data:  make([]byte, length),
+	}
+	if _, err := io.ReadFull(r.r, msg.Data); err != nil {
+		return nil, noEOF(err)
+	}
+	checksum := uint32(hdr[1])<<24 | uint32(hdr[2])<<16 | uint32(hdr[3])<<8 | uint32(hdr[4])
+	msg.Intact = crc32.Checksum(msg.Data, crc32cTable) == checksum
+	return msg, nil
+}
+
+// noEOF converts an io.EOF in the middle of a message into an io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
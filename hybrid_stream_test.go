@@ -0,0 +1,125 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hybrid Stream", func() {
+	type write struct {
+		data   []byte
+		policy PRPolicy
+	}
+
+	var (
+		sendStr *MockSendStreamI
+		writes  []write
+		writer  *HybridWriter
+	)
+
+	prPolicy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+
+	BeforeEach(func() {
+		writes = nil
+		sendStr = NewMockSendStreamI(mockCtrl)
+		sendStr.EXPECT().WriteWithPolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(p []byte, policy PRPolicy) (int, error) {
+			writes = append(writes, write{data: append([]byte{}, p...), policy: policy})
+			return len(p), nil
+		}).AnyTimes()
+		writer = NewHybridWriter(sendStr, prPolicy)
+	})
+
+	newReader := func(data []byte, maxSize int) *HybridReader {
+		r := bytes.NewReader(data)
+		str := NewMockReceiveStreamI(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+		return NewHybridReader(str, maxSize)
+	}
+
+	streamData := func() []byte {
+		var b []byte
+		for _, w := range writes {
+			b = append(b, w.data...)
+		}
+		return b
+	}
+
+	It("writes the header reliably, and the payload using the policy", func() {
+		seq, err := writer.WriteMessage(0x1, []byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(seq).To(BeZero())
+		seq, err = writer.WriteMessage(0x2, []byte("raboof"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(seq).To(BeEquivalentTo(1))
+		Expect(writes).To(HaveLen(4))
+		Expect(writes[0].policy).To(Equal(PRPolicy{}))
+		Expect(writes[1]).To(Equal(write{data: []byte("foobar"), policy: prPolicy}))
+		Expect(writes[2].policy).To(Equal(PRPolicy{}))
+		Expect(writes[3]).To(Equal(write{data: []byte("raboof"), policy: prPolicy}))
+	})
+
+	It("reads messages", func() {
+		_, err := writer.WriteMessage(0x1, []byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = writer.WriteMessage(0x2, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(writes).To(HaveLen(3))
+		reader := newReader(streamData(), 100)
+		msg, err := reader.ReadMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal(&HybridMessage{Seq: 0, Flags: 0x1, Data: []byte("foobar"), Intact: true}))
+		msg, err = reader.ReadMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal(&HybridMessage{Seq: 1, Flags: 0x2, Data: []byte{}, Intact: true}))
+		_, err = reader.ReadMessage()
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("detects payloads that were skipped", func() {
+		_, err := writer.WriteMessage(0x1, []byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = writer.WriteMessage(0x1, []byte("raboof"))
+		Expect(err).ToNot(HaveOccurred())
+		// the receive stream zero-fills data that was skipped by the sender
+		copy(writes[1].data, make([]byte, 3))
+		reader := newReader(streamData(), 100)
+		msg, err := reader.ReadMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg.Intact).To(BeFalse())
+		Expect(msg.Data).To(Equal([]byte{0, 0, 0, 'b', 'a', 'r'}))
+		msg, err = reader.ReadMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg.Seq).To(BeEquivalentTo(1))
+		Expect(msg.Intact).To(BeTrue())
+	})
+
+	It("errors on messages that are too large", func() {
+		_, err := writer.WriteMessage(0, make([]byte, 101))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = newReader(streamData(), 100).ReadMessage()
+		Expect(err).To(MatchError("message 0 too large: 101 bytes (maximum 100 bytes)"))
+	})
+
+	It("errors when the stream ends in the middle of a message", func() {
+		_, err := writer.WriteMessage(0, []byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		data := streamData()
+		_, err = newReader(data[:len(data)-1], 100).ReadMessage()
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+
+	It("returns write errors", func() {
+		sendStr = NewMockSendStreamI(mockCtrl)
+		testErr := errors.New("test error")
+		sendStr.EXPECT().WriteWithPolicy(gomock.Any(), PRPolicy{}).Return(0, testErr)
+		writer = NewHybridWriter(sendStr, prPolicy)
+		_, err := writer.WriteMessage(0, []byte("foobar"))
+		Expect(err).To(MatchError(testErr))
+	})
+})
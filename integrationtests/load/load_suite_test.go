@@ -0,0 +1,82 @@
+//go:build load
+
+package load_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const alpn = "quic-go load tests"
+
+var (
+	numConns       int
+	numStreams     int
+	streamSize     int
+	lossRate       float64
+	maxHeapInUse   uint64
+	goroutineSlack int
+
+	tlsConfig       *tls.Config
+	tlsClientConfig *tls.Config
+)
+
+// The load tests are only built with the load build tag:
+//
+//	go test -tags load ./integrationtests/load/ -conns 200 -streams 100
+func init() {
+	flag.IntVar(&numConns, "conns", 50, "number of client connections")
+	flag.IntVar(&numStreams, "streams", 40, "number of PR streams per connection")
+	flag.IntVar(&streamSize, "size", 20*1024, "number of bytes sent on every stream")
+	flag.Float64Var(&lossRate, "loss", 0.05, "share of packets dropped by the proxy, in both directions")
+	flag.Uint64Var(&maxHeapInUse, "maxheap", 512<<20, "maximum heap in use (in bytes), measured during the test")
+	flag.IntVar(&goroutineSlack, "goroutines", 10, "number of goroutines allowed to outlive the test")
+}
+
+func TestLoad(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Load Tests Suite")
+}
+
+var _ = BeforeSuite(func() {
+	mrand.Seed(GinkgoRandomSeed())
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	certTempl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTempl, certTempl, &priv.PublicKey, priv)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(certBytes)
+	Expect(err).ToNot(HaveOccurred())
+
+	tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certBytes}, PrivateKey: priv}},
+		NextProtos:   []string{alpn},
+	}
+	root := x509.NewCertPool()
+	root.AddCert(cert)
+	tlsClientConfig = &tls.Config{
+		RootCAs:    root,
+		NextProtos: []string{alpn},
+	}
+})
@@ -0,0 +1,155 @@
+//go:build load
+
+package load_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// heapMonitor samples the heap in use, and records the maximum.
+type heapMonitor struct {
+	max     uint64 // accessed atomically
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func startHeapMonitor() *heapMonitor {
+	m := &heapMonitor{stop: make(chan struct{}), stopped: make(chan struct{})}
+	go func() {
+		defer close(m.stopped)
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > atomic.LoadUint64(&m.max) {
+				atomic.StoreUint64(&m.max, stats.HeapInuse)
+			}
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+	return m
+}
+
+func (m *heapMonitor) Stop() uint64 {
+	close(m.stop)
+	<-m.stopped
+	return atomic.LoadUint64(&m.max)
+}
+
+var _ = Describe("PR load", func() {
+	It("transfers many PR streams on lossy connections, without leaking memory or goroutines", func() {
+		runtime.GC()
+		goroutinesBefore := runtime.NumGoroutine()
+		heap := startHeapMonitor()
+
+		server, err := quic.ListenAddr("localhost:0", tlsConfig, &quic.Config{
+			MaxIncomingUniStreams: int64(numStreams),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		var completed, corrupted int64 // accessed atomically
+		var serverWg sync.WaitGroup
+		serverWg.Add(1)
+		go func() {
+			defer serverWg.Done()
+			for {
+				conn, err := server.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				serverWg.Add(1)
+				go func() {
+					defer serverWg.Done()
+					for {
+						str, err := conn.AcceptUniStream(context.Background())
+						if err != nil {
+							return
+						}
+						serverWg.Add(1)
+						go func() {
+							defer serverWg.Done()
+							// Skipped data is read as zeros, so the stream always has the same length.
+							n, err := io.Copy(io.Discard, str)
+							if err != nil {
+								return
+							}
+							if n != int64(streamSize) {
+								atomic.AddInt64(&corrupted, 1)
+								return
+							}
+							atomic.AddInt64(&completed, 1)
+						}()
+					}
+				}()
+			}
+		}()
+
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket:  func(quicproxy.Direction, []byte) bool { return mrand.Float64() < lossRate },
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		data := make([]byte, streamSize)
+		mrand.Read(data)
+		conns := make([]quic.Connection, numConns)
+		var clientWg sync.WaitGroup
+		for i := 0; i < numConns; i++ {
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+				tlsClientConfig,
+				&quic.Config{PR: quic.PRConfig{DefaultPolicy: &quic.PRPolicy{PTDA: quic.PTDAAbandon}}},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			conns[i] = conn
+			for j := 0; j < numStreams; j++ {
+				clientWg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer clientWg.Done()
+					str, err := conn.OpenUniStreamSync(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write(data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+		}
+		clientWg.Wait()
+		total := int64(numConns * numStreams)
+		Eventually(func() int64 { return atomic.LoadInt64(&completed) }, 5*time.Minute, 100*time.Millisecond).Should(Equal(total))
+		Expect(atomic.LoadInt64(&corrupted)).To(BeZero())
+
+		for _, conn := range conns {
+			Expect(conn.CloseWithError(0, "")).To(Succeed())
+		}
+		Expect(proxy.Close()).To(Succeed())
+		Expect(server.Close()).To(Succeed())
+		serverWg.Wait()
+
+		maxHeap := heap.Stop()
+		fmt.Fprintf(GinkgoWriter, "%d streams on %d connections, maximum heap in use: %d MB\n", total, numConns, maxHeap>>20)
+		Expect(maxHeap).To(BeNumerically("<=", maxHeapInUse))
+		Eventually(runtime.NumGoroutine, 10*time.Second).Should(BeNumerically("<=", goroutinesBefore+goroutineSlack))
+	})
+})
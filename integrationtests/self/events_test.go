@@ -0,0 +1,68 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport Events", func() {
+	It("delivers transport events", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			str.CancelWrite(42)
+			<-done
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		var events []quic.TransportEvent
+		var reset quic.StreamResetEvent
+	loop:
+		for {
+			select {
+			case ev, ok := <-conn.Events():
+				Expect(ok).To(BeTrue())
+				events = append(events, ev)
+				if r, ok := ev.(quic.StreamResetEvent); ok {
+					reset = r
+					break loop
+				}
+			case <-time.After(5 * time.Second):
+				Fail("timeout waiting for the STREAM_RESET event")
+			}
+		}
+		Expect(reset.StreamID).To(Equal(str.StreamID()))
+		Expect(reset.ErrorCode).To(BeEquivalentTo(42))
+		Expect(events).To(ContainElement(quic.PRNegotiatedEvent{Enabled: true}))
+		Expect(events).To(ContainElement(BeAssignableToTypeOf(quic.PeerTransportParametersEvent{})))
+
+		close(done)
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+		Eventually(conn.Events()).Should(BeClosed())
+	})
+})
@@ -0,0 +1,97 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Handoff", func() {
+	It("hands off a connection to another listener", func() {
+		statelessResetKey := make([]byte, 32)
+		rand.Read(statelessResetKey)
+		serverConfig := getQuicConfig(&quic.Config{StatelessResetKey: statelessResetKey})
+
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		serverPort := ln.Addr().(*net.UDPAddr).Port
+
+		handoffChan := make(chan *quic.ConnectionHandoff, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data := make([]byte, 3)
+			_, err = io.ReadFull(str, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+			h, err := conn.Handoff()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = conn.OpenStream()
+			Expect(err).To(MatchError(quic.ErrHandedOff))
+			Expect(ln.Close()).To(Succeed())
+			handoffChan <- h
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", serverPort),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+
+		var h *quic.ConnectionHandoff
+		Eventually(handoffChan).Should(Receive(&h))
+		Expect(h.Streams).To(HaveLen(1))
+		Expect(h.Streams[0].StreamID).To(Equal(str.StreamID()))
+		Expect(h.Streams[0].BytesRead).To(BeEquivalentTo(3))
+		Expect(h.Streams[0].ReceiveFinished).To(BeFalse())
+
+		ln2, err := quic.ListenAddr(fmt.Sprintf("localhost:%d", serverPort), getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln2.Close()
+		serverConn, err := ln2.Import(h, 42)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverConn.ConnectionState().TLS.NegotiatedProtocol).To(Equal(conn.ConnectionState().TLS.NegotiatedProtocol))
+
+		// the stream that was open is reset
+		_, err = str.Read([]byte{0})
+		var streamErr *quic.StreamError
+		Expect(errors.As(err, &streamErr)).To(BeTrue())
+		Expect(streamErr.ErrorCode).To(BeEquivalentTo(42))
+
+		// the connection can be used to open new streams
+		go func() {
+			defer GinkgoRecover()
+			str, err := serverConn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(str, str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+		str2, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str2.StreamID()).To(BeNumerically(">", str.StreamID()))
+		_, err = str2.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str2.Close()).To(Succeed())
+		data, err := io.ReadAll(str2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+	})
+})
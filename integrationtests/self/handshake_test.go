@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
@@ -70,6 +71,33 @@ func (t *versionNegotiationTracer) NegotiatedVersion(chosen logging.VersionNumbe
 	t.serverVersions = serverVersions
 }
 
+type clientHelloTracer struct {
+	logging.NullConnectionTracer
+
+	mutex          sync.Mutex
+	initialPackets []packet
+	initialSizes   []logging.ByteCount
+	receivedParams *logging.TransportParameters
+}
+
+var _ logging.ConnectionTracer = &clientHelloTracer{}
+
+func (t *clientHelloTracer) ReceivedLongHeaderPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, frames []logging.Frame) {
+	if hdr.Type != protocol.PacketTypeInitial {
+		return
+	}
+	t.mutex.Lock()
+	t.initialPackets = append(t.initialPackets, packet{time: time.Now(), hdr: hdr, frames: frames})
+	t.initialSizes = append(t.initialSizes, size)
+	t.mutex.Unlock()
+}
+
+func (t *clientHelloTracer) ReceivedTransportParameters(params *logging.TransportParameters) {
+	t.mutex.Lock()
+	t.receivedParams = params
+	t.mutex.Unlock()
+}
+
 func (t *versionNegotiationTracer) ReceivedVersionNegotiationPacket(dest, src logging.ArbitraryLenConnectionID, _ []logging.VersionNumber) {
 	t.receivedVersionNegotiation = true
 }
@@ -473,6 +501,72 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	Context("ClientHello fragmentation", func() {
+		It("splits the ClientHello into multiple Initial packets", func() {
+			const fragmentSize = 100
+			tracer := &clientHelloTracer{}
+			serverConfig.Tracer = newTracer(func() logging.ConnectionTracer { return tracer })
+			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := conn.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = io.Copy(str, str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}()
+
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{ClientHelloFragmentSize: fragmentSize}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			str, err := conn.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRData))
+			Eventually(done).Should(BeClosed())
+
+			tracer.mutex.Lock()
+			defer tracer.mutex.Unlock()
+			// The PR negotiation parameters are part of the ClientHello.
+			Expect(tracer.receivedParams).ToNot(BeNil())
+			Expect(tracer.receivedParams.PartialReliability).To(BeTrue())
+			var numCryptoPackets int
+			var clientHelloLen logging.ByteCount
+			for i, p := range tracer.initialPackets {
+				var cryptoLen logging.ByteCount
+				for _, f := range p.frames {
+					if cf, ok := f.(*logging.CryptoFrame); ok {
+						cryptoLen += cf.Length
+					}
+				}
+				if cryptoLen == 0 {
+					continue
+				}
+				numCryptoPackets++
+				clientHelloLen += cryptoLen
+				Expect(cryptoLen).To(BeNumerically("<", fragmentSize))
+				Expect(tracer.initialSizes[i]).To(BeNumerically(">=", protocol.MinInitialPacketSize))
+			}
+			Expect(numCryptoPackets).To(BeNumerically(">=", int(clientHelloLen/fragmentSize)+1))
+			Expect(numCryptoPackets).To(BeNumerically(">", 1))
+		})
+	})
+
 	Context("using tokens", func() {
 		It("uses tokens provided in NEW_TOKEN frames", func() {
 			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
@@ -0,0 +1,110 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Loss injection", func() {
+	// runTransfer sends the data with the policy on a server that drops every 5th 1-RTT packet,
+	// and returns the data received by the client, with skipped data filled with zeros, and the number of dropped packets.
+	runTransfer := func(data []byte, policy quic.PRPolicy) ([]byte, uint64, int32) {
+		var dropped int32
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				TestingLossInjector: func(pn logging.PacketNumber) bool {
+					if pn%5 != 0 {
+						return false
+					}
+					atomic.AddInt32(&dropped, 1)
+					return true
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.WriteWithPolicy(data, policy)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		str.SetReadSkippedAsError(true)
+		// one more byte, such that the last Read returns io.EOF
+		received := make([]byte, len(data)+1)
+		var pos int
+		var skipped uint64
+		for {
+			n, err := str.Read(received[pos:])
+			pos += n
+			if err == io.EOF {
+				break
+			}
+			var skipErr *quic.DataSkippedError
+			if errors.As(err, &skipErr) {
+				Expect(skipErr.Offset).To(BeEquivalentTo(pos))
+				skipped += skipErr.Length
+				pos += int(skipErr.Length)
+				continue
+			}
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(pos).To(Equal(len(data)))
+		Eventually(done).Should(BeClosed())
+		return received[:pos], skipped, atomic.LoadInt32(&dropped)
+	}
+
+	It("retransmits reliable data lost due to the loss injector", func() {
+		data := GeneratePRData(200 * 1024)
+		received, skipped, dropped := runTransfer(data, quic.PRPolicy{})
+		Expect(dropped).ToNot(BeZero())
+		Expect(skipped).To(BeZero())
+		Expect(received).To(Equal(data))
+	})
+
+	It("skips partially reliable data lost due to the loss injector", func() {
+		data := GeneratePRData(200 * 1024)
+		received, skipped, dropped := runTransfer(data, quic.PRPolicy{PTDA: quic.PTDAAbandon})
+		Expect(dropped).ToNot(BeZero())
+		Expect(skipped).ToNot(BeZero())
+		Expect(skipped).To(BeNumerically("<", len(data)/2))
+		// all data that wasn't skipped was received correctly
+		var diff int
+		for i := range data {
+			if received[i] != data[i] {
+				Expect(received[i]).To(BeZero())
+				diff++
+			}
+		}
+		Expect(diff).To(BeNumerically("<=", skipped))
+	})
+})
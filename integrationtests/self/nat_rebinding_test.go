@@ -0,0 +1,239 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// A natRebinder forwards packets between a single client and the server.
+// Rebind simulates a NAT rebinding: packets to the server are sent from a new port from then on.
+type natRebinder struct {
+	conn       *net.UDPConn // the connection to the client
+	serverAddr *net.UDPAddr
+
+	mutex      sync.Mutex
+	clientAddr *net.UDPAddr
+	serverConn *net.UDPConn
+}
+
+func newNATRebinder(serverAddr *net.UDPAddr) (*natRebinder, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	r := &natRebinder{conn: conn, serverAddr: serverAddr}
+	if err := r.Rebind(); err != nil {
+		return nil, err
+	}
+	go r.runClientSide()
+	return r, nil
+}
+
+func (r *natRebinder) LocalAddr() *net.UDPAddr { return r.conn.LocalAddr().(*net.UDPAddr) }
+
+// ServerSideAddr is the address the server receives packets from.
+func (r *natRebinder) ServerSideAddr() *net.UDPAddr {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.serverConn.LocalAddr().(*net.UDPAddr)
+}
+
+// Rebind changes the port packets are sent to the server from.
+// Packets the server sends to the old port are lost.
+func (r *natRebinder) Rebind() error {
+	serverConn, err := net.DialUDP("udp", nil, r.serverAddr)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	if r.serverConn != nil {
+		r.serverConn.Close()
+	}
+	r.serverConn = serverConn
+	r.mutex.Unlock()
+	go r.runServerSide(serverConn)
+	return nil
+}
+
+func (r *natRebinder) runClientSide() {
+	b := make([]byte, 1500)
+	for {
+		n, addr, err := r.conn.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+		r.mutex.Lock()
+		r.clientAddr = addr
+		serverConn := r.serverConn
+		r.mutex.Unlock()
+		serverConn.Write(b[:n])
+	}
+}
+
+func (r *natRebinder) runServerSide(serverConn *net.UDPConn) {
+	b := make([]byte, 1500)
+	for {
+		n, err := serverConn.Read(b)
+		if err != nil {
+			return
+		}
+		r.mutex.Lock()
+		clientAddr := r.clientAddr
+		r.mutex.Unlock()
+		r.conn.WriteToUDP(b[:n], clientAddr)
+	}
+}
+
+func (r *natRebinder) Close() error {
+	r.mutex.Lock()
+	r.serverConn.Close()
+	r.mutex.Unlock()
+	return r.conn.Close()
+}
+
+type cwndTracer struct {
+	logging.NullConnectionTracer
+
+	mutex sync.Mutex
+	cwnd  []logging.ByteCount
+}
+
+func (t *cwndTracer) UpdatedMetrics(_ *logging.RTTStats, cwnd, _ logging.ByteCount, _ int) {
+	t.mutex.Lock()
+	t.cwnd = append(t.cwnd, cwnd)
+	t.mutex.Unlock()
+}
+
+// Mark returns the number of congestion window updates so far.
+func (t *cwndTracer) Mark() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.cwnd)
+}
+
+func (t *cwndTracer) Last() logging.ByteCount {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.cwnd[len(t.cwnd)-1]
+}
+
+// MinSince returns the smallest congestion window since the mark.
+func (t *cwndTracer) MinSince(mark int) logging.ByteCount {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	min := t.cwnd[mark]
+	for _, cwnd := range t.cwnd[mark:] {
+		if cwnd < min {
+			min = cwnd
+		}
+	}
+	return min
+}
+
+var _ = Describe("NAT rebinding", func() {
+	const dataLen = 6 << 20
+	data := GeneratePRData(dataLen)
+
+	for _, t := range []int{1, 3} {
+		threshold := t
+
+		It(fmt.Sprintf("keeps sending PR data after a NAT rebinding, with a path validation packet threshold of %d", threshold), func() {
+			tracer := &cwndTracer{}
+			server, err := quic.ListenAddr(
+				"localhost:0",
+				getTLSConfig(),
+				getQuicConfig(&quic.Config{
+					PathValidationPacketThreshold: threshold,
+					Tracer:                        newTracer(func() logging.ConnectionTracer { return tracer }),
+				}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			rebinder, err := newNATRebinder(server.Addr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			defer rebinder.Close()
+
+			serverConnChan := make(chan quic.Connection, 1)
+			go func() {
+				defer GinkgoRecover()
+				conn, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				serverConnChan <- conn
+				go func() {
+					defer GinkgoRecover()
+					str, err := conn.AcceptUniStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					io.Copy(io.Discard, str)
+				}()
+				str, err := conn.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				// send the data like a video: in chunks, each of them becoming useless after one second
+				for i := 0; i < dataLen; i += 64 << 10 {
+					_, err := str.WriteWithPolicy(data[i:i+64<<10], quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 1000})
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(str.Close()).To(Succeed())
+			}()
+
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", rebinder.LocalAddr().Port),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			var serverConn quic.Connection
+			Eventually(serverConnChan).Should(Receive(&serverConn))
+
+			// Like a video receiver, the client regularly sends feedback to the server.
+			// The server only migrates the connection once it receives packets from the new address.
+			feedbackStr, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				defer GinkgoRecover()
+				ticker := time.NewTicker(5 * time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						if _, err := feedbackStr.Write([]byte("feedback")); err != nil {
+							return
+						}
+					}
+				}
+			}()
+
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			received := make([]byte, dataLen)
+			_, err = io.ReadFull(str, received[:dataLen/2])
+			Expect(err).ToNot(HaveOccurred())
+
+			mark := tracer.Mark()
+			cwnd := tracer.Last()
+			Expect(rebinder.Rebind()).To(Succeed())
+			_, err = io.ReadFull(str, received[dataLen/2:])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(received).To(Equal(data))
+
+			Expect(serverConn.RemoteAddr().String()).To(Equal(rebinder.ServerSideAddr().String()))
+			// The connection loses the packets sent to the old port, but the congestion window isn't reset.
+			Expect(tracer.MinSince(mark)).To(BeNumerically(">=", cwnd/2))
+		})
+	}
+})
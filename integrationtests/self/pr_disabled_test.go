@@ -0,0 +1,85 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Disabling partial reliability", func() {
+	prPolicy := quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 1}
+
+	// countPRFrames counts the frames that an implementation without partial reliability wouldn't understand.
+	countPRFrames := func(packets []packet) (n int) {
+		for _, p := range packets {
+			for _, f := range p.frames {
+				if strings.Contains(fmt.Sprintf("%T", f), ".PR") {
+					n++
+				}
+			}
+		}
+		return
+	}
+
+	for _, disableOnServer := range []bool{true, false} {
+		disableOnServer := disableOnServer
+
+		It(fmt.Sprintf("sends all data reliably, if disabled on the %s", map[bool]string{true: "server", false: "client"}[disableOnServer]), func() {
+			data := GeneratePRData(50 * 1024)
+			serverTracer := newPacketTracer()
+			server, err := quic.ListenAddr(
+				"localhost:0",
+				getTLSConfig(),
+				getQuicConfig(&quic.Config{
+					PR:     quic.PRConfig{Disabled: disableOnServer},
+					Tracer: newTracer(func() logging.ConnectionTracer { return serverTracer }),
+				}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := conn.OpenUniStreamSync(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.WriteWithPolicy(data, prPolicy)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.EndMessage()).To(Succeed())
+				Expect(str.Close()).To(Succeed())
+			}()
+
+			clientTracer := newPacketTracer()
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{
+					PR:     quic.PRConfig{Disabled: !disableOnServer},
+					Tracer: newTracer(func() logging.ConnectionTracer { return clientTracer }),
+				}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			b, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(data))
+			Eventually(done).Should(BeClosed())
+			Expect(conn.CloseWithError(0, "")).To(Succeed())
+
+			Expect(countPRFrames(serverTracer.getSentPackets())).To(BeZero())
+			Expect(countPRFrames(clientTracer.getSentPackets())).To(BeZero())
+		})
+	}
+})
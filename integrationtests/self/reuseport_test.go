@@ -0,0 +1,73 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SO_REUSEPORT sockets", func() {
+	BeforeEach(func() {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "freebsd" {
+			Skip("SO_REUSEPORT is not supported on " + runtime.GOOS)
+		}
+	})
+
+	It("handles connections on multiple sockets", func() {
+		const numClients = 20
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{ReusePortSockets: 4}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			for {
+				conn, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					defer GinkgoRecover()
+					str, err := conn.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					_, err = io.Copy(str, str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+		}()
+
+		var wg sync.WaitGroup
+		wg.Add(numClients)
+		for i := 0; i < numClients; i++ {
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				conn, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(nil),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.CloseWithError(0, "")
+				str, err := conn.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+			}()
+		}
+		wg.Wait()
+	})
+})
@@ -0,0 +1,73 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedulers", func() {
+	It("sends the data with the earliest deadline first, using the EDF scheduler", func() {
+		reliableData := GeneratePRData(200 * 1024)
+		deadlineData := GeneratePRData(300 * 1024)
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{Scheduler: quic.SchedulerEDF}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			reliableStr, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			deadlineStr, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			go func() {
+				defer GinkgoRecover()
+				_, err := reliableStr.WriteWithPolicy(reliableData, quic.PRPolicy{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reliableStr.Close()).To(Succeed())
+			}()
+			_, err = deadlineStr.WriteWithPolicy(deadlineData, quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 1000})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deadlineStr.Close()).To(Succeed())
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		results := make(chan []byte, 2)
+		for i := 0; i < 2; i++ {
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			go func() {
+				defer GinkgoRecover()
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				results <- data
+			}()
+		}
+		var first, second []byte
+		Eventually(results).Should(Receive(&first))
+		Eventually(results).Should(Receive(&second))
+		// Both streams have data all the time, so the stream with a deadline sends 3 out of 4 STREAM frames.
+		// With a round-robin scheduler, the reliable stream would complete first.
+		Expect(first).To(Equal(deadlineData))
+		Expect(second).To(Equal(reliableData))
+	})
+})
@@ -0,0 +1,15 @@
+module peer
+
+go 1.18
+
+// This module uses an unmodified version of quic-go, and not the code checked out in this repository.
+// The fork is based on this version.
+require github.com/lucas-clemente/quic-go v0.29.0
+
+require (
+	github.com/marten-seemann/qtls-go1-19 v0.1.0 // indirect
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519 // indirect
+	golang.org/x/exp v0.0.0-20220722155223-a9213eeb770e // indirect
+	golang.org/x/net v0.0.0-20220624214902-1bab6f366d9e // indirect
+	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
+)
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// This is a QUIC endpoint built with an unmodified quic-go, see go.mod.
+// It is used by the tests in the parent directory, to check that the partially reliable fork
+// is indistinguishable from quic-go on the wire if partial reliability is not used.
+//
+// The server prints its address, then sends size bytes of pseudo-random data on a unidirectional stream.
+// The client reads and checks the data. Once the connection is closed,
+// both print a report (see report) on stdout.
+func main() {
+	role := flag.String("role", "server", "server or client")
+	addr := flag.String("addr", "localhost:0", "address to listen on (server), or to dial (client)")
+	size := flag.Int("size", 50*1024, "number of bytes sent by the server")
+	flag.Parse()
+
+	tracer := &connTracer{}
+	conf := &quic.Config{
+		Tracer: &tracerAdapter{conn: tracer},
+	}
+	var err error
+	switch *role {
+	case "server":
+		err = runServer(*addr, *size, conf)
+	case "client":
+		err = runClient(*addr, *size, conf)
+	default:
+		err = fmt.Errorf("invalid role: %s", *role)
+	}
+	r := tracer.report()
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(r); err != nil {
+		log.Fatal(err)
+	}
+}
+
+const alpn = "quic-go upstream interop"
+
+func runServer(addr string, size int, conf *quic.Config) error {
+	tlsConf, err := generateTLSConfig()
+	if err != nil {
+		return err
+	}
+	ln, err := quic.ListenAddr(addr, tlsConf, conf)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	fmt.Println(ln.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := ln.Accept(ctx)
+	if err != nil {
+		return err
+	}
+	str, err := conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := str.Write(generatePRData(size)); err != nil {
+		return err
+	}
+	if err := str.Close(); err != nil {
+		return err
+	}
+	// the client closes the connection once it received all data
+	select {
+	case <-conn.Context().Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func runClient(addr string, size int, conf *quic.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddrContext(ctx, addr, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{alpn}}, conf)
+	if err != nil {
+		return err
+	}
+	str, err := conn.AcceptUniStream(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(str)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, generatePRData(size)) {
+		return fmt.Errorf("received corrupted data (%d bytes)", len(data))
+	}
+	if err := conn.CloseWithError(0, ""); err != nil {
+		return err
+	}
+	// give the CONNECTION_CLOSE some time to arrive at the server
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+
+// See https://en.wikipedia.org/wiki/Lehmer_random_number_generator
+func generatePRData(l int) []byte {
+	res := make([]byte, l)
+	seed := uint64(1)
+	for i := 0; i < l; i++ {
+		seed = seed * 48271 % 2147483647
+		res[i] = byte(seed)
+	}
+	return res
+}
+
+func generateTLSConfig() (*tls.Config, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	certTempl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTempl, certTempl, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certBytes}, PrivateKey: priv}},
+		NextProtos:   []string{alpn},
+	}, nil
+}
+
+// A report describes what the endpoint received from its peer.
+type report struct {
+	TransportParameters *logging.TransportParameters `json:"transport_parameters"`
+	// Packets are the 1-RTT packets received.
+	Packets []packet `json:"packets"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type packet struct {
+	Size   logging.ByteCount `json:"size"`
+	Frames []frame           `json:"frames"`
+}
+
+type frame struct {
+	Type string `json:"type"`
+	// only set for STREAM frames
+	StreamID logging.StreamID  `json:"stream_id,omitempty"`
+	Offset   logging.ByteCount `json:"offset,omitempty"`
+	Length   logging.ByteCount `json:"length,omitempty"`
+	Fin      bool              `json:"fin,omitempty"`
+}
+
+type tracerAdapter struct {
+	logging.NullTracer
+	conn *connTracer
+}
+
+func (t *tracerAdapter) TracerForConnection(context.Context, logging.Perspective, logging.ConnectionID) logging.ConnectionTracer {
+	return t.conn
+}
+
+type connTracer struct {
+	logging.NullConnectionTracer
+
+	mutex   sync.Mutex
+	params  *logging.TransportParameters
+	packets []packet
+}
+
+func (t *connTracer) ReceivedTransportParameters(p *logging.TransportParameters) {
+	t.mutex.Lock()
+	t.params = p
+	t.mutex.Unlock()
+}
+
+func (t *connTracer) ReceivedPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, frames []logging.Frame) {
+	if hdr.IsLongHeader {
+		return
+	}
+	p := packet{Size: size}
+	for _, f := range frames {
+		if sf, ok := f.(*logging.StreamFrame); ok {
+			p.Frames = append(p.Frames, frame{Type: "stream", StreamID: sf.StreamID, Offset: sf.Offset, Length: sf.Length, Fin: sf.Fin})
+			continue
+		}
+		p.Frames = append(p.Frames, frame{Type: fmt.Sprintf("%T", f)})
+	}
+	t.mutex.Lock()
+	t.packets = append(t.packets, p)
+	t.mutex.Unlock()
+}
+
+func (t *connTracer) report() report {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return report{TransportParameters: t.params, Packets: t.packets}
+}
@@ -0,0 +1,173 @@
+package upstream_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The peer is built with quic-go v0.29.0, which can't be built on Go 1.20 and newer,
+// see the go.mod in the peer directory. The tests are skipped if the go command is too new.
+// Note that the peer is built using the go command, and needs to download its dependencies.
+
+const alpn = "quic-go upstream interop"
+
+var (
+	peerBinary string
+	tlsConfig  *tls.Config
+	// set if the peer can't be built
+	skipReason string
+)
+
+func TestUpstream(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Upstream Interop Suite")
+}
+
+// goMinorVersion returns the minor version of the go command used to build the peer, e.g. 19 for go1.19.13.
+func goMinorVersion() (int, error) {
+	cmd := exec.Command("go", "env", "GOVERSION")
+	cmd.Dir = "peer"
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, err
+	}
+	v := strings.TrimPrefix(strings.TrimSpace(string(out)), "go1.")
+	if i := strings.IndexAny(v, ".rb"); i >= 0 { // patch releases, release candidates and betas
+		v = v[:i]
+	}
+	return strconv.Atoi(v)
+}
+
+var _ = BeforeSuite(func() {
+	minor, err := goMinorVersion()
+	Expect(err).ToNot(HaveOccurred())
+	if minor >= 20 {
+		skipReason = fmt.Sprintf("quic-go v0.29.0 can't be built with Go 1.%d", minor)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "quic-go-upstream")
+	Expect(err).ToNot(HaveOccurred())
+	peerBinary = filepath.Join(dir, "peer")
+	cmd := exec.Command("go", "build", "-o", peerBinary, "main.go")
+	cmd.Dir = "peer"
+	out, err := cmd.CombinedOutput()
+	Expect(err).ToNot(HaveOccurred(), "building the peer failed: %s", out)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	certTempl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, certTempl, certTempl, &priv.PublicKey, priv)
+	Expect(err).ToNot(HaveOccurred())
+	tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certBytes}, PrivateKey: priv}},
+		NextProtos:   []string{alpn},
+	}
+})
+
+var _ = AfterSuite(func() {
+	if peerBinary != "" {
+		Expect(os.RemoveAll(filepath.Dir(peerBinary))).To(Succeed())
+	}
+})
+
+var _ = BeforeEach(func() {
+	if skipReason != "" {
+		Skip(skipReason)
+	}
+})
+
+func getTLSConfig() *tls.Config {
+	return tlsConfig.Clone()
+}
+
+func getTLSClientConfig() *tls.Config {
+	// the peer uses a self-signed certificate
+	return &tls.Config{InsecureSkipVerify: true, NextProtos: []string{alpn}}
+}
+
+// See https://en.wikipedia.org/wiki/Lehmer_random_number_generator
+func GeneratePRData(l int) []byte {
+	res := make([]byte, l)
+	seed := uint64(1)
+	for i := 0; i < l; i++ {
+		seed = seed * 48271 % 2147483647
+		res[i] = byte(seed)
+	}
+	return res
+}
+
+// A peerReport describes what the peer received, see the report in peer/main.go.
+type peerReport struct {
+	// TransportParameters are the transport parameters received, as parsed by quic-go v0.29.0.
+	TransportParameters map[string]interface{} `json:"transport_parameters"`
+	Packets             []peerPacket           `json:"packets"`
+	Error               string                 `json:"error"`
+}
+
+type peerPacket struct {
+	Size   uint64      `json:"size"`
+	Frames []peerFrame `json:"frames"`
+}
+
+type peerFrame struct {
+	Type     string `json:"type"`
+	StreamID int64  `json:"stream_id"`
+	Offset   uint64 `json:"offset"`
+	Length   uint64 `json:"length"`
+	Fin      bool   `json:"fin"`
+}
+
+// startPeerServer starts the peer as a server, sending size bytes.
+// It returns the address of the server, and a function that waits until the server exited, and returns its report.
+func startPeerServer(size int) (string, func() *peerReport) {
+	cmd := exec.Command(peerBinary, "-role", "server", "-addr", "localhost:0", "-size", strconv.Itoa(size))
+	cmd.Stderr = GinkgoWriter
+	stdout, err := cmd.StdoutPipe()
+	Expect(err).ToNot(HaveOccurred())
+	Expect(cmd.Start()).To(Succeed())
+	r := bufio.NewReader(stdout)
+	addr, err := r.ReadString('\n')
+	Expect(err).ToNot(HaveOccurred())
+	return addr[:len(addr)-1], func() *peerReport {
+		var rep peerReport
+		Expect(json.NewDecoder(r).Decode(&rep)).To(Succeed())
+		Expect(cmd.Wait()).To(Succeed())
+		return &rep
+	}
+}
+
+// runPeerClient runs the peer as a client, receiving size bytes from the server at addr.
+func runPeerClient(addr string, size int) *peerReport {
+	cmd := exec.Command(peerBinary, "-role", "client", "-addr", addr, "-size", strconv.Itoa(size))
+	cmd.Stderr = GinkgoWriter
+	out, err := cmd.Output()
+	Expect(err).ToNot(HaveOccurred())
+	var rep peerReport
+	Expect(json.Unmarshal(out, &rep)).To(Succeed())
+	return &rep
+}
@@ -0,0 +1,255 @@
+package upstream_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+	"golang.org/x/crypto/cryptobyte"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	extensionQUICTransportParameters uint16 = 0x39
+	typeClientHello                  uint8  = 1
+
+	initialSourceConnectionIDParameterID uint64 = 0xf
+	partialReliabilityParameterID        uint64 = 0x7072
+)
+
+// parseClientTransportParameters decrypts the first Initial packet sent by a client,
+// and returns the transport parameters of the ClientHello, by their ID.
+// Reserved transport parameters, which are used for greasing, are omitted.
+func parseClientTransportParameters(data []byte) (map[uint64][]byte, error) {
+	hdr, data, _, err := wire.ParsePacket(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if hdr.Type != protocol.PacketTypeInitial {
+		return nil, fmt.Errorf("expected an Initial packet, got %s", hdr.PacketType())
+	}
+	_, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
+	hdrLen := int(hdr.ParsedLen())
+	// decrypt the header, assuming a 4 byte packet number
+	origPNBytes := make([]byte, 4)
+	copy(origPNBytes, data[hdrLen:hdrLen+4])
+	opener.DecryptHeader(data[hdrLen+4:hdrLen+4+16], &data[0], data[hdrLen:hdrLen+4])
+	extHdr, err := hdr.ParseExtended(bytes.NewReader(data), hdr.Version)
+	if err != nil {
+		return nil, err
+	}
+	extHdrLen := int(extHdr.ParsedLen())
+	copy(data[extHdrLen:hdrLen+4], origPNBytes[int(extHdr.PacketNumberLen):])
+	pn := opener.DecodePacketNumber(extHdr.PacketNumber, extHdr.PacketNumberLen)
+	payload, err := opener.Open(nil, data[extHdrLen:], pn, data[:extHdrLen])
+	if err != nil {
+		return nil, err
+	}
+
+	var clientHello []byte
+	parser := wire.NewFrameParser(false, false, hdr.Version)
+	for len(payload) > 0 {
+		l, frame, err := parser.ParseNext(payload, protocol.EncryptionInitial)
+		if err != nil {
+			return nil, err
+		}
+		payload = payload[l:]
+		if frame == nil { // only PADDING frames left
+			break
+		}
+		if f, ok := frame.(*wire.CryptoFrame); ok {
+			if int(f.Offset) != len(clientHello) {
+				return nil, errors.New("CRYPTO frames out of order")
+			}
+			clientHello = append(clientHello, f.Data...)
+		}
+	}
+
+	s := cryptobyte.String(clientHello)
+	var msgType uint8
+	var body, sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !s.ReadUint8(&msgType) || msgType != typeClientHello ||
+		!s.ReadUint24LengthPrefixed(&body) ||
+		!body.Skip(2+32) || // legacy_version, random
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!body.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!body.ReadUint16LengthPrefixed(&extensions) {
+		return nil, errors.New("invalid ClientHello")
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return nil, errors.New("invalid ClientHello extension")
+		}
+		if extType != extensionQUICTransportParameters {
+			continue
+		}
+		params := make(map[uint64][]byte)
+		r := bytes.NewReader(extData)
+		for r.Len() > 0 {
+			id, err := quicvarint.Read(r)
+			if err != nil {
+				return nil, err
+			}
+			l, err := quicvarint.Read(r)
+			if err != nil {
+				return nil, err
+			}
+			val := make([]byte, l)
+			if _, err := io.ReadFull(r, val); err != nil {
+				return nil, err
+			}
+			if id%31 == 27 { // reserved
+				continue
+			}
+			params[id] = val
+		}
+		return params, nil
+	}
+	return nil, errors.New("ClientHello doesn't contain transport parameters")
+}
+
+var _ = Describe("Interoperability with upstream quic-go", func() {
+	const dataLen = 50 * 1024
+
+	prPolicy := quic.PRPolicy{PTDA: quic.PTDADeadline, Value: 1}
+
+	Context("transport parameters", func() {
+		// clientTransportParameters runs dial against a proxy in front of the peer,
+		// and returns the transport parameters sent in the client's first Initial packet.
+		clientTransportParameters := func(dial func(addr string)) map[uint64][]byte {
+			addr, wait := startPeerServer(dataLen)
+			var mutex sync.Mutex
+			var initial []byte
+			proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+				RemoteAddr: addr,
+				DropPacket: func(dir quicproxy.Direction, b []byte) bool {
+					mutex.Lock()
+					defer mutex.Unlock()
+					if dir == quicproxy.DirectionIncoming && initial == nil {
+						initial = make([]byte, len(b))
+						copy(initial, b)
+					}
+					return false
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer proxy.Close()
+			dial(fmt.Sprintf("localhost:%d", proxy.LocalPort()))
+			Expect(wait().Error).To(BeEmpty())
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			params, err := parseClientTransportParameters(initial)
+			Expect(err).ToNot(HaveOccurred())
+			// the connection ID is chosen randomly
+			Expect(params).To(HaveKey(initialSourceConnectionIDParameterID))
+			delete(params, initialSourceConnectionIDParameterID)
+			return params
+		}
+
+		dialFork := func(conf *quic.Config) func(string) {
+			return func(addr string) {
+				conn, err := quic.DialAddr(addr, getTLSClientConfig(), conf)
+				Expect(err).ToNot(HaveOccurred())
+				str, err := conn.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(GeneratePRData(dataLen)))
+				Expect(conn.CloseWithError(0, "")).To(Succeed())
+			}
+		}
+
+		It("sends the same transport parameters as upstream quic-go, if partial reliability is disabled", func() {
+			upstream := clientTransportParameters(func(addr string) {
+				Expect(runPeerClient(addr, dataLen).Error).To(BeEmpty())
+			})
+			Expect(clientTransportParameters(dialFork(&quic.Config{PR: quic.PRConfig{Disabled: true}}))).To(Equal(upstream))
+
+			// If partial reliability is enabled, the partial_reliability transport parameter is the only difference.
+			params := clientTransportParameters(dialFork(&quic.Config{}))
+			Expect(params).To(HaveKeyWithValue(partialReliabilityParameterID, []byte{}))
+			delete(params, partialReliabilityParameterID)
+			Expect(params).To(Equal(upstream))
+		})
+	})
+
+	Context("sending data", func() {
+		// the fields of the transport parameters that differ for every connection
+		randomParams := []string{"OriginalDestinationConnectionID", "InitialSourceConnectionID", "RetrySourceConnectionID", "StatelessResetToken"}
+
+		// streamPacketSizes returns the sizes of the packets containing STREAM frames received by the peer,
+		// except for the packets containing the end of the data.
+		// Depending on the timing, the FIN bit is sent with the last data, or in a separate STREAM frame.
+		streamPacketSizes := func(rep *peerReport) []uint64 {
+			var sizes []uint64
+			for _, p := range rep.Packets {
+				for _, f := range p.Frames {
+					if f.Type == "stream" && f.Offset+f.Length < dataLen {
+						sizes = append(sizes, p.Size)
+						break
+					}
+				}
+			}
+			return sizes
+		}
+
+		for _, d := range []bool{true, false} {
+			disabled := d
+
+			It(fmt.Sprintf("sends the same packets as upstream quic-go, %s", map[bool]string{true: "if partial reliability is disabled", false: "if the peer doesn't support partial reliability"}[disabled]), func() {
+				addr, wait := startPeerServer(dataLen)
+				upstream := runPeerClient(addr, dataLen)
+				Expect(upstream.Error).To(BeEmpty())
+				Expect(wait().Error).To(BeEmpty())
+
+				server, err := quic.ListenAddr("localhost:0", getTLSConfig(), &quic.Config{PR: quic.PRConfig{Disabled: disabled}})
+				Expect(err).ToNot(HaveOccurred())
+				defer server.Close()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					conn, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := conn.OpenUniStreamSync(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					// Partially reliable data is sent reliably.
+					_, err = str.WriteWithPolicy(GeneratePRData(dataLen), prPolicy)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.EndMessage()).To(Succeed())
+					Expect(str.Close()).To(Succeed())
+				}()
+				rep := runPeerClient(fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port), dataLen)
+				Eventually(done).Should(BeClosed())
+				Expect(rep.Error).To(BeEmpty())
+
+				for _, p := range randomParams {
+					delete(upstream.TransportParameters, p)
+					delete(rep.TransportParameters, p)
+				}
+				Expect(rep.TransportParameters).To(Equal(upstream.TransportParameters))
+				// The peer would have closed the connection if it received a frame it doesn't know (e.g. a PR_STREAM frame).
+				// The data is packed into packets of the same size.
+				// If space for the partial reliability header was reserved in popStreamFrame, the packets would be smaller.
+				// Other frames (e.g. ACK frames) might be packed into different packets, depending on the timing.
+				Expect(streamPacketSizes(rep)).To(Equal(streamPacketSizes(upstream)))
+			})
+		}
+	})
+})
@@ -86,6 +86,25 @@ type ReceiveStream interface {
 	// Read will unblock immediately, and future Read calls will fail.
 	// When called multiple times or after reading the io.EOF it is a no-op.
 	CancelRead(StreamErrorCode)
+	// ReadMessageBoundary discards the data up to the next message boundary, marked by the peer using SendStream.EndMessage.
+	// This allows resynchronizing after data of a partially reliable stream was skipped (skipped data is read as zeros),
+	// without scanning the stream for the start of the next message.
+	// It returns the number of bytes discarded, which is 0 if the stream is already positioned at a message boundary.
+	// It blocks until the data up to the next boundary was received or skipped.
+	// The end of the stream is treated as a message boundary, at which io.EOF is returned.
+	// It must not be called concurrently with Read.
+	ReadMessageBoundary() (int, error)
+	// TryRead is the non-blocking variant of Read, for applications that handle many streams from an event loop.
+	// It reads the data that was already received, and returns ErrWouldBlock if no data is available.
+	// It also returns ErrWouldBlock if a call to Read is blocked.
+	TryRead(p []byte) (int, error)
+	// SetReadSkippedAsError makes Read return a *DataSkippedError instead of reading zeros,
+	// when it reaches data that the peer skipped because of its PR policy, or that expired before it was read.
+	// Data that was received is never mixed with skipped data: Read returns the data read so far first.
+	// The error then covers the skipped data at the read position, up to len(p) bytes,
+	// such that the application can tell where in the stream the following data belongs.
+	// Skipped data that was partially received nonetheless is read as usual, with zeros in place of the missing bytes.
+	SetReadSkippedAsError(bool)
 	// SetReadDeadline sets the deadline for future Read calls and
 	// any currently-blocked Read call.
 	// A zero value for t means Read will not time out.
@@ -105,6 +124,32 @@ type SendStream interface {
 	// If the connection was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Writer
+	// WriteWithPolicy writes data to the stream, using policy for this data.
+	// This allows the reliability to vary within a single stream,
+	// e.g. sending key frames reliably, and other frames partially reliably.
+	// Data written with different policies is never sent in the same STREAM frame.
+	// Write uses the default policy.
+	// When the policy changes after data was written, the change is announced to the peer.
+	// Data written before the change, including data that is in flight or retransmitted, keeps its policy.
+	// To write chunks from multiple goroutines in a well-defined order, use a ChunkWriter.
+	WriteWithPolicy(p []byte, policy PRPolicy) (int, error)
+	// WriteBuffers writes the contents of multiple slices to the stream, using the policy used by Write,
+	// e.g. for scatter-gather encoders that produce a header and a payload separately.
+	// The slices are not concatenated. Where possible, STREAM frames start at the beginning of a slice.
+	// It returns the total number of bytes written.
+	WriteBuffers(bufs net.Buffers) (int64, error)
+	// TryWrite is the non-blocking variant of Write, for applications that handle many streams from an event loop.
+	// It buffers as much data as possible without blocking (up to the size of a packet), using the policy used by Write.
+	// If not all data could be written, it returns the number of bytes written and ErrWouldBlock.
+	// It also returns ErrWouldBlock if a call to Write is blocked.
+	// Once the buffered data was sent, more data can be written.
+	// SetWriteBufferWatermarks can be used to limit the data that is in flight.
+	TryWrite(p []byte) (int, error)
+	// EndMessage marks the end of an application message, after the data written so far.
+	// The peer can use ReceiveStream.ReadMessageBoundary to skip to the beginning of the next message.
+	// The boundary is sent reliably, regardless of the policy of the data it delimits.
+	// It must not be called concurrently with Write.
+	EndMessage() error
 	// Close closes the write-direction of the stream.
 	// Future calls to Write are not permitted after calling Close.
 	// It must not be called concurrently with Write.
@@ -125,6 +170,60 @@ type SendStream interface {
 	// some data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SetPRPolicy sets the policy used by Write on this stream.
+	// It takes precedence over the connection policy and the PRConfig.DefaultPolicy, see PRPolicySource.
+	// If nil, the stream policy is removed, and the policy of the connection is used.
+	// An invalid policy makes Write fail.
+	// It can be called after data was written: the new policy applies to the data written by subsequent calls to Write,
+	// see WriteWithPolicy.
+	SetPRPolicy(*PRPolicy)
+	// EffectivePRPolicy returns the policy used by Write, and where it was configured.
+	// It is intended for debugging.
+	EffectivePRPolicy() (PRPolicy, PRPolicySource)
+	// DisablePR makes the stream send all data reliably, regardless of the stream policy,
+	// the connection policy and the PRConfig.DefaultPolicy.
+	// WriteWithPolicy then returns ErrPRDisabled for partially reliable policies.
+	// It is intended for control streams, where skipped data would corrupt the state of the application protocol
+	// (e.g. the HTTP/3 control and QPACK streams).
+	// It can't be undone, and should be called before writing any data.
+	DisablePR()
+	// SetIdleTimeout sets the inactivity timeout of the stream, overriding PRConfig.IdleStreamTimeout.
+	// It only applies once data was written with a partially reliable policy.
+	// If no data is written for this duration, the write-direction of the stream is canceled.
+	// A zero value disables the timeout.
+	SetIdleTimeout(time.Duration)
+	// ExpireDataBefore abandons the data below offset (counted from the beginning of the stream)
+	// that was written with a partially reliable policy, e.g. when it is no longer useful to the application.
+	// Lost data in this range is not retransmitted, instead the peer is told to skip it.
+	// Data that was written reliably is always delivered.
+	ExpireDataBefore(offset uint64)
+	// SetRateLimit limits the rate at which data is sent on this stream, in bytes per second,
+	// e.g. to pace a media track at its encoded bitrate.
+	// This prevents a single stream from using up the congestion window, delaying the data of other streams.
+	// Short bursts (of at least one packet) are allowed.
+	// A value of 0 removes the limit.
+	SetRateLimit(bytesPerSecond uint64)
+	// AckedRanges returns the ranges of the data written so far that the peer acknowledged, sorted by offset.
+	// Adjacent ranges are merged.
+	// Data that was skipped because of its PR policy is not included, unless it was acknowledged after all.
+	// This allows the application to repair the gaps left by skipped data, e.g. by resending the important parts.
+	AckedRanges() []ByteRange
+	// SetWriteBufferWatermarks signals backpressure to the producer of the data, e.g. a real-time encoder,
+	// such that it can pause producing data, instead of blocking in Write.
+	// The buffered data is the data written to the stream that wasn't acknowledged or skipped yet.
+	// Once it reaches high bytes, callback is called with pause set to true.
+	// Once it then drops to low bytes or less, callback is called with pause set to false.
+	// If the buffered data already reached high, callback is called right away.
+	// The callback is called from Write, or from the connection's run loop, but never concurrently.
+	// It must not block, and it must not call any methods of the stream.
+	// A nil callback removes the watermarks.
+	SetWriteBufferWatermarks(low, high uint64, callback func(pause bool))
+}
+
+// A ByteRange is a range of stream data, from Start (inclusive) to End (exclusive),
+// counted from the beginning of the stream.
+type ByteRange struct {
+	Start, End uint64
 }
 
 // A Connection is a QUIC connection between two peers.
@@ -144,6 +243,26 @@ type Connection interface {
 	// If the connection was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	AcceptUniStream(context.Context) (ReceiveStream, error)
+	// SetMaxIncomingStreams changes the maximum number of concurrent bidirectional streams
+	// that the peer is allowed to open (see Config.MaxIncomingStreams).
+	// If set to a negative value, the peer isn't allowed to open any new bidirectional streams.
+	// The stream limit that was already granted to the peer can't be reduced:
+	// Lowering the maximum only takes effect as streams are closed.
+	SetMaxIncomingStreams(int64)
+	// OnStream calls cb in a new go routine for every bidirectional stream opened by the peer.
+	// It is an alternative to calling AcceptStream in a loop, and must not be used together with AcceptStream.
+	// It must only be called once.
+	OnStream(cb func(Stream))
+	// OnUniStream calls cb in a new go routine for every unidirectional stream opened by the peer.
+	// It is an alternative to calling AcceptUniStream in a loop, and must not be used together with AcceptUniStream.
+	// It must only be called once.
+	OnUniStream(cb func(ReceiveStream))
+	// SetMaxIncomingUniStreams changes the maximum number of concurrent unidirectional streams
+	// that the peer is allowed to open (see Config.MaxIncomingUniStreams).
+	// If set to a negative value, the peer isn't allowed to open any new unidirectional streams.
+	// The stream limit that was already granted to the peer can't be reduced:
+	// Lowering the maximum only takes effect as streams are closed.
+	SetMaxIncomingUniStreams(int64)
 	// OpenStream opens a new bidirectional QUIC stream.
 	// There is no signaling to the peer about new streams:
 	// The peer can only accept the stream after data has been sent on the stream.
@@ -153,6 +272,7 @@ type Connection interface {
 	OpenStream() (Stream, error)
 	// OpenStreamSync opens a new bidirectional QUIC stream.
 	// It blocks until a new stream can be opened.
+	// The context only applies to opening the stream, use OpenStreamWithContext to bind the stream to it.
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// If the connection was closed due to a timeout, Timeout() will be true.
 	OpenStreamSync(context.Context) (Stream, error)
@@ -163,6 +283,7 @@ type Connection interface {
 	OpenUniStream() (SendStream, error)
 	// OpenUniStreamSync opens a new outgoing unidirectional QUIC stream.
 	// It blocks until a new stream can be opened.
+	// The context only applies to opening the stream, use OpenUniStreamWithContext to bind the stream to it.
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// If the connection was closed due to a timeout, Timeout() will be true.
 	OpenUniStreamSync(context.Context) (SendStream, error)
@@ -179,11 +300,54 @@ type Connection interface {
 	// It blocks until the handshake completes.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// HandshakeConfirmed is canceled when the handshake is confirmed (see RFC 9001, section 4.1.2).
+	// For the server, the handshake is confirmed as soon as it completes.
+	// For the client, it is confirmed when the server's HANDSHAKE_DONE frame is received.
+	// It is not canceled if the connection is closed before that.
+	HandshakeConfirmed() context.Context
+
+	// MaxStreamFrameDataSize returns the maximum amount of stream data that fits into a single PR stream frame,
+	// given the current maximum packet size (see Config.DisablePathMTUDiscovery) and the overhead of the PR frame header.
+	// Encoders can use it to slice their output, e.g. NAL units, such that every slice is sent in a single packet:
+	// When a slice spans multiple packets, losing any of them makes the parts that arrived useless to the decoder.
+	// The value is conservative, and it increases when a larger path MTU is discovered.
+	// It only holds when a frame doesn't share the packet with other frames, e.g. ACK frames.
+	MaxStreamFrameDataSize(StreamID) int
+
+	// SetPRPolicy sets the policy used by Write on all streams of this connection that don't have a stream policy.
+	// It takes precedence over PRConfig.DefaultPolicy, see PRPolicySource.
+	// If nil, the connection policy is removed.
+	// An invalid policy makes Write fail.
+	SetPRPolicy(*PRPolicy)
+	// PRStats returns the RTT, ack delay and reordering statistics used when evaluating the deadline policy.
+	// Lost data written with the deadline policy is skipped if it can't be retransmitted more than PRStats.DeadlineMargin before the deadline.
+	// It also returns the bandwidth estimate of the congestion controller. The statistics are updated when an ACK is received.
+	PRStats() PRStats
 
 	// SendMessage sends a message as a datagram, as specified in RFC 9221.
 	SendMessage([]byte) error
+	// SendMessageWithPolicy sends a message as a datagram.
+	// If the policy uses PTDAPriority, its Value is used as the priority of the datagram
+	// when the send queue overflows, see DatagramOverflowDropLowestPriority.
+	SendMessageWithPolicy([]byte, PRPolicy) error
+	// SendMessageTracked sends a message as a datagram, and returns a handle that reports
+	// if the datagram was acknowledged, lost, or expired before it could be sent.
+	// If the policy uses PTDADeadline, the datagram expires if it wasn't sent within Value milliseconds.
+	SendMessageTracked([]byte, PRPolicy) (*DatagramHandle, error)
 	// ReceiveMessage gets a message received in a datagram, as specified in RFC 9221.
 	ReceiveMessage() ([]byte, error)
+
+	// Events returns a channel that delivers changes of the transport state, see TransportEvent.
+	// This allows the application to react to e.g. a stream reset or the outcome of the PR negotiation, without parsing a qlog.
+	// Events are not blocked on: if the application doesn't receive them fast enough, they are dropped.
+	// The channel is closed when the connection is closed.
+	Events() <-chan TransportEvent
+
+	// Handoff stops the connection without notifying the peer, and returns its state,
+	// such that another process can resume it using Listener.Import, e.g. when restarting a server without dropping its connections.
+	// Calls on the connection and its streams return ErrHandedOff afterwards.
+	// Only server connections that completed the handshake can be handed off.
+	Handoff() (*ConnectionHandoff, error)
 }
 
 // An EarlyConnection is a connection that is handshaking.
@@ -224,6 +388,156 @@ type ConnectionIDGenerator interface {
 	ConnectionIDLen() int
 }
 
+// A Logger is a structured logger, see Config.Logger.
+// The key-value pairs alternate between keys (strings) and values.
+// *slog.Logger implements this interface. Other loggers, e.g. zap's SugaredLogger, only need a small adapter.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// IncomingStreamInfo describes a stream opened by the peer.
+type IncomingStreamInfo struct {
+	StreamID StreamID
+	// Data is the beginning of the stream data.
+	// It is nil if the stream was opened by a frame that doesn't start at offset 0.
+	// It must not be retained after the callback returns.
+	Data []byte
+}
+
+// IncomingStreamDecision is returned by Config.AcceptIncomingStream.
+// The zero value accepts the stream.
+type IncomingStreamDecision struct {
+	// Reject rejects the stream, using ErrorCode.
+	// For the receive direction a STOP_SENDING frame is sent, and for the send direction of bidirectional streams a RESET_STREAM frame.
+	Reject    bool
+	ErrorCode StreamErrorCode
+	// Policy is the PR policy used by Write on bidirectional streams.
+	// If nil, the default policy is used.
+	Policy *PRPolicy
+}
+
+// PRConfig configures partial reliability.
+type PRConfig struct {
+	// Disabled disables partial reliability.
+	// All data is sent reliably, no matter which PR policy is used, and the connection doesn't send or accept
+	// any of the frames used for partial reliability, nor the partial_reliability transport parameter.
+	// On the wire, the connection is then indistinguishable from a connection of an implementation without partial reliability,
+	// e.g. to compare the two in production.
+	// Partial reliability is also disabled for a connection if the peer didn't send the partial_reliability transport parameter.
+	// If a server closes the connection because it doesn't understand a frame used for partial reliability,
+	// the client disables partial reliability for the following connections to this server, for 24 hours.
+	// If this happens before the handshake completed, the client redials right away.
+	Disabled bool
+	// IdleStreamTimeout is the default inactivity timeout of streams that use a partially reliable policy.
+	// If no data is written on such a stream for this duration, because the producer stalled,
+	// the write-direction of the stream is canceled using IdleStreamErrorCode, and its resources are reclaimed.
+	// The peer is informed by a RESET_STREAM frame, and reclaims the receive-direction.
+	// The timeout can be changed for every stream using SendStream.SetIdleTimeout.
+	// If zero, streams are never canceled for inactivity.
+	IdleStreamTimeout time.Duration
+	// IdleStreamErrorCode is the error code used to cancel idle streams.
+	IdleStreamErrorCode StreamErrorCode
+	// DefaultPolicy is the policy used by Write, unless a connection or stream policy is set, see PRPolicySource.
+	// If nil, the policy is derived from the deprecated package-level variables PR_ENABLED, PTDA and PtadC.
+	DefaultPolicy *PRPolicy
+	// MaxPRStreams is the maximum number of streams per connection that send data with a partially reliable policy at the same time.
+	// The sender keeps state for every such stream, e.g. the policies of the data in flight and the data to skip.
+	// A stream counts towards the limit from the first Write with a partially reliable policy until it is completed.
+	// Once the limit is reached, data written on other streams is sent reliably, unless RejectExcessPRStreams is set.
+	// If zero, the number of PR streams is not limited.
+	MaxPRStreams int
+	// StreamLimiter limits the number of PR streams of all connections that use it, in addition to MaxPRStreams.
+	// A server usually shares a single limiter between all its connections.
+	StreamLimiter *PRStreamLimiter
+	// RejectExcessPRStreams makes Write fail with ErrTooManyPRStreams when a PR stream limit is reached,
+	// instead of sending the data reliably.
+	RejectExcessPRStreams bool
+	// SeparatePRPackets prevents STREAM frames of reliable streams and of partially reliable streams from being sent in the same packet.
+	// This way, losing a packet never requires retransmitting the reliable data together with PR data,
+	// and the loss of a packet carrying PR data doesn't delay reliable data.
+	// Instead of dividing the space in a packet, the packets are divided between the two classes according to the FramerQuotas.
+	SeparatePRPackets bool
+	// ScavengerCongestionControl is an experimental mode that limits the packets carrying partially reliable STREAM data
+	// by a second, scavenger congestion controller (similar to LEDBAT, RFC 6817), in addition to the congestion controller of the connection.
+	// The scavenger controller backs off as soon as the queuing delay grows, such that partially reliable streams,
+	// e.g. used for prefetching in the background, don't delay the reliable streams (and other traffic) on the same path.
+	// Data of reliable streams, control frames and datagrams are not limited by the scavenger controller.
+	// It requires SeparatePRPackets, and can't be used together with a Config.Scheduler.
+	ScavengerCongestionControl bool
+	// MaxSkipRatio is the maximum fraction of the data of a stream that the peer may skip when sending to us,
+	// e.g. 0.1 if at least 90% of the data must be delivered.
+	// It is advertised in a transport parameter. Once skipping lost data would exceed the ratio,
+	// the peer retransmits it instead, as if it had been written reliably.
+	// It must be between 0 and 1. If zero or 1, the skip ratio is not limited.
+	MaxSkipRatio float64
+	// RandSeed seeds the random number generator of the connection, which decides if lost data sent with the probability policy
+	// (PTDAProbability) is retransmitted. Given the same losses, a connection using the same seed makes the same decisions,
+	// which makes experiments reproducible.
+	// If zero, a seed is chosen at random. The seed is passed to the ConnectionTracer (and recorded in the qlog) when the connection starts.
+	RandSeed int64
+}
+
+// A WindowState is the state of a receive flow control window, see WindowUpdateStrategy.
+type WindowState struct {
+	// BytesRead is the number of bytes read by the application.
+	BytesRead uint64
+	// Window is the highest offset advertised to the peer.
+	Window uint64
+	// WindowSize is the size of the window, starting at the initial receive window.
+	// It is increased by auto-tuning, up to the maximum receive window.
+	WindowSize uint64
+}
+
+// A WindowUpdateStrategy decides when the receive windows of a connection are increased.
+// Its methods are called after the application read data, and return the new offset to advertise to the peer.
+// If that's not larger than WindowState.Window, no window update is sent.
+// The offset is capped at WindowState.BytesRead plus the maximum receive window,
+// i.e. Config.MaxStreamReceiveWindow for streams, and Config.MaxConnectionReceiveWindow for the connection.
+// The methods may be called multiple times for the same state, and must not block.
+// To avoid deadlocks, it is not valid to call functions on the connection or on streams in these methods.
+type WindowUpdateStrategy interface {
+	// StreamWindowUpdate returns the new offset to advertise in a MAX_STREAM_DATA frame for a stream.
+	StreamWindowUpdate(StreamID, WindowState) uint64
+	// ConnectionWindowUpdate returns the new offset to advertise in a MAX_DATA frame.
+	ConnectionWindowUpdate(WindowState) uint64
+}
+
+// SchedulerContext describes the packet that is filled with STREAM frames.
+type SchedulerContext struct {
+	// Now is the time the packet is packed.
+	Now time.Time
+	// RemainingLen is the space left in the packet.
+	RemainingLen uint64
+}
+
+// A Scheduler decides which stream sends the next STREAM frame, see Config.Scheduler.
+// Control frames and DATAGRAM frames are not affected.
+// The methods are never called concurrently. They must not block.
+// To avoid deadlocks, it is not valid to call functions on the connection or on streams in these methods.
+type Scheduler interface {
+	// AddStream is called when a stream has data to send.
+	// It is not called again for a stream until the stream was returned by NextStream.
+	AddStream(StreamID)
+	// NextStream returns the stream that sends the next STREAM frame in the packet,
+	// or false if no stream is supposed to send data in this packet.
+	// The stream is removed from the scheduler. AddStream is called again if it has more data to send after sending the frame.
+	// Every stream is asked for data at most once per packet:
+	// If a stream is returned a second time for the same packet, no more STREAM frames are added to the packet.
+	// A stream sends a single STREAM frame, unless it sends small PR STREAM frames: then it adds as many as fit into the packet.
+	NextStream(SchedulerContext) (StreamID, bool)
+	// OnStreamBlocked is called when a stream returned by NextStream couldn't send any data,
+	// because it is rate-limited or blocked by flow control.
+	// AddStream is called again once the stream can send.
+	OnStreamBlocked(StreamID)
+	// OnDeadlineApproaching is called with the time the oldest data queued on a stream expires,
+	// if that data was written with the PTDADeadline policy, i.e. the time it was written plus the deadline.
+	// It is called before the stream is added, and whenever the time changes as the data is sent.
+	// The zero time is used when the stream doesn't have such data any more.
+	OnDeadlineApproaching(StreamID, time.Time)
+}
+
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// The QUIC versions that can be negotiated.
@@ -256,6 +570,42 @@ type Config struct {
 	// See https://datatracker.ietf.org/doc/html/rfc9000#section-8 for details.
 	// If not set, every client is forced to prove its remote address.
 	RequireAddressValidation func(net.Addr) bool
+	// MaxUnvalidatedHandshakes is the maximum number of concurrent handshakes with clients whose address wasn't validated.
+	// Once this limit is reached, the server sends a Retry packet for new connections, in addition to the clients
+	// selected by RequireAddressValidation. This protects servers that don't always require address validation under load.
+	// If not set, the number of handshakes is not limited. Only valid for a server.
+	MaxUnvalidatedHandshakes int
+	// AmplificationFactor limits the amount of data the server sends before the client's address is validated:
+	// it sends at most AmplificationFactor times the number of bytes it received from the client (RFC 9000, Section 8).
+	// Servers with a large first flight (e.g. a large certificate chain) may be blocked by this limit,
+	// which is reported by logging.ConnectionTracer.AmplificationLimited.
+	// If not set, or if larger than 3, it defaults to 3, the maximum allowed by RFC 9000. Only valid for a server.
+	AmplificationFactor int
+	// LossDetectionPacketThreshold is the reordering threshold in packets (kPacketThreshold, RFC 9002, Section 6.1.1).
+	// A packet is declared lost once a packet sent that many packets later is acknowledged.
+	// Lower values detect losses faster, at the cost of spurious retransmissions when packets are reordered.
+	// For PR streams, this means that the PR policy is applied earlier, e.g. data is skipped sooner.
+	// If not set, it defaults to 3.
+	LossDetectionPacketThreshold int
+	// LossDetectionTimeThreshold is the time threshold, as a multiple of the RTT (kTimeThreshold, RFC 9002, Section 6.1.2).
+	// A packet is declared lost if it was sent this long before a packet that was acknowledged.
+	// If not set, it defaults to 9/8. Values smaller than 1 are increased to 1.
+	LossDetectionTimeThreshold float64
+	// PathValidationPacketThreshold enables connection migration on the server side,
+	// and controls how aggressively the server migrates a connection
+	// when packets start arriving from a new client address, e.g. after a NAT rebinding.
+	// The server switches to the new address once it received that many packets from it,
+	// each carrying more than probing frames and a packet number larger than any packet received before,
+	// and validates the new address using a PATH_CHALLENGE frame.
+	// Until the address is validated, the server sends at most 3x the bytes it received from the new address.
+	// If the address isn't validated within 3 PTOs, the server returns to the previous address.
+	// Higher values make it harder for an attacker to redirect the connection using copies of the client's packets,
+	// at the cost of losing more packets after a rebinding. They also require the client to keep sending packets:
+	// a client that only acknowledges the server's packets might not send enough packets to trigger the migration.
+	// RTT and congestion controller state are kept if only the client's port changed.
+	// A value of 1 makes the server validate a new address immediately.
+	// If not set, or set to a negative value, the server never migrates connections. Only valid for a server.
+	PathValidationPacketThreshold int
 	// MaxRetryTokenAge is the maximum age of a Retry token.
 	// If not set, it defaults to 5 seconds. Only valid for a server.
 	MaxRetryTokenAge time.Duration
@@ -263,6 +613,24 @@ type Config struct {
 	// for tokens that were issued on a previous connection.
 	// If not set, it defaults to 24 hours. Only valid for a server.
 	MaxTokenAge time.Duration
+	// TokenIPv4PrefixLen and TokenIPv6PrefixLen bind tokens issued in NEW_TOKEN frames to a network prefix
+	// instead of the exact IP address of the client.
+	// This allows returning clients whose address changed within their network (e.g. due to NAT or IPv6 privacy addresses)
+	// to skip address validation.
+	// If not set, tokens are bound to the exact IP address. Retry tokens are always bound to the exact IP address.
+	// Only valid for a server.
+	TokenIPv4PrefixLen int
+	TokenIPv6PrefixLen int
+	// Allow0RTT is called on the server when a client attempts to send 0-RTT data using a session ticket that is valid for 0-RTT.
+	// 0-RTT data is not protected against replays, so servers should only accept it for idempotent requests,
+	// e.g. for fetching media segments on partially reliable streams. If it returns false, 0-RTT is rejected,
+	// and the client sends the data again after the handshake completed.
+	// If nil, 0-RTT is accepted. It is only used by listeners created with ListenEarly or ListenAddrEarly.
+	Allow0RTT func(*ZeroRTTInfo) bool
+	// ZeroRTTReplayFilter rejects 0-RTT for replayed ClientHellos, and for session tickets older than its replay window.
+	// It is applied after Allow0RTT. A server usually shares a single filter between all its connections.
+	// If nil, replays are not detected. Only valid for a server.
+	ZeroRTTReplayFilter *ZeroRTTReplayFilter
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
@@ -284,6 +652,14 @@ type Config struct {
 	// MaxConnectionReceiveWindow is the connection-level flow control window for receiving data.
 	// If this value is zero, it will default to 15 MB.
 	MaxConnectionReceiveWindow uint64
+	// MaxReceiveBufferSize is the maximum amount of data buffered, but not yet read by the application,
+	// across all streams of a connection.
+	// It is enforced by flow control: the connection-level flow control window never exceeds this value.
+	// Once the peer is blocked by flow control, data sent with the deadline policy that expired
+	// before the application read it is evicted from the receive buffers, and read as zeros.
+	// This protects servers from slow readers.
+	// If this value is zero, the receive buffers are only limited by MaxConnectionReceiveWindow.
+	MaxReceiveBufferSize uint64
 	// AllowConnectionWindowIncrease is called every time the connection flow controller attempts
 	// to increase the connection flow control window.
 	// If set, the caller can prevent an increase of the window. Typically, it would do so to
@@ -291,6 +667,16 @@ type Config struct {
 	// To avoid deadlocks, it is not valid to call other functions on the connection or on streams
 	// in this callback.
 	AllowConnectionWindowIncrease func(sess Connection, delta uint64) bool
+	// WindowUpdateStrategy decides when MAX_STREAM_DATA and MAX_DATA frames are sent, and which offset they advertise.
+	// If nil, the receive window is increased once 25% of it was consumed.
+	WindowUpdateStrategy WindowUpdateStrategy
+	// AcceptIncomingStream is called when the peer opens a new stream.
+	// It allows rejecting the stream right away, or setting the PR policy used for writing on the stream,
+	// before the stream is returned by AcceptStream / AcceptUniStream.
+	// Rejected streams are never returned by AcceptStream / AcceptUniStream.
+	// To avoid deadlocks, it is not valid to call other functions on the connection or on streams
+	// in this callback.
+	AcceptIncomingStream func(conn Connection, info IncomingStreamInfo) IncomingStreamDecision
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// Values above 2^60 are invalid.
 	// If not set, it will default to 100.
@@ -310,31 +696,146 @@ type Config struct {
 	KeepAlivePeriod time.Duration
 	// DisablePathMTUDiscovery disables Path MTU Discovery (RFC 8899).
 	// Packets will then be at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
+	// Path MTU Discovery is always disabled if the Don't Fragment (DF) bit can't be set on the socket.
 	// Note that if Path MTU discovery is causing issues on your system, please open a new issue
 	DisablePathMTUDiscovery bool
+	// ClientHelloFragmentSize splits the ClientHello into multiple Initial packets (client only).
+	// Every Initial packet sent by the client then carries at most this many bytes of CRYPTO frames,
+	// and is padded to the full packet size, such that all packets of the first flight have the same size.
+	// Middleboxes that only inspect the first packet of a connection (e.g. to read the SNI) don't see the complete ClientHello,
+	// and the size of the ClientHello (which depends on the transport parameters, e.g. the partial_reliability parameter) is hidden.
+	// This costs an additional packet for every ClientHelloFragmentSize bytes of the ClientHello.
+	// If zero, the ClientHello is sent in as few packets as possible. Values below 32 are invalid.
+	ClientHelloFragmentSize int
+	// DisableOOB forces the use of the portable ReadFrom and WriteTo methods of the net.PacketConn,
+	// instead of reading ECN bits and packet info from socket control messages (OOB data), in batches.
+	// OOB is already disabled automatically when the platform or the socket doesn't support it,
+	// this option is useful if it is supported, but misbehaves.
+	// All Dial and Listen calls using the same net.PacketConn should use the same value.
+	DisableOOB bool
+	// BatchHeaderProtection moves the header protection of 1-RTT packets from the connection's run loop
+	// to the goroutine writing the packets to the socket, which applies it to all packets queued for sending at once.
+	// This reduces the per-packet overhead of connections sending at high packet rates, e.g. of partially reliable media streams.
+	BatchHeaderProtection bool
+	// ReusePortSockets is the number of sockets that ListenAddr and ListenAddrEarly open on the address, using SO_REUSEPORT.
+	// The kernel distributes the incoming packets between the sockets, and each socket is read by its own goroutine,
+	// such that servers receiving many packets (e.g. of partially reliable media streams) scale across CPU cores.
+	// Every connection is handled by one socket: the first byte of the connection IDs issued by the server,
+	// modulo the number of sockets, is the index of this socket. Packets delivered to a different socket are passed on,
+	// therefore a value larger than 1 requires connection IDs of at least 1 byte.
+	// To steer packets to the right socket in the kernel, see ReusePortSocket and ReusePortSteeringProgram.
+	// Limits like MaxUnvalidatedHandshakes apply to each socket.
+	// Values up to 1 use a single socket, and values larger than 256 are invalid.
+	// SO_REUSEPORT is supported on Linux, macOS and FreeBSD. This option has no effect for Listen and Dial.
+	ReusePortSockets int
+	// PacketMarking is the marking of the IP header of the packets sent on a connection.
+	// Many networks prioritize traffic marked with a DSCP, e.g. to forward interactive media with low latency.
+	// Marking requires a net.UDPConn on Linux, macOS or FreeBSD, and isn't applied if DisableOOB is set.
+	// The IPv6 flow label can only be set on Linux.
+	PacketMarking PacketMarking
+	// PRPacketMarking is the marking of packets that carry partially reliable data,
+	// i.e. STREAM frames of partially reliable streams and partially reliable datagrams.
+	// Combined with PRConfig.SeparatePRPackets, the data of reliable and partially reliable streams can be marked differently.
+	// If it is the zero value, PacketMarking is used for all packets.
+	PRPacketMarking PacketMarking
 	// DisableVersionNegotiationPackets disables the sending of Version Negotiation packets.
 	// This can be useful if version information is exchanged out-of-band.
 	// It has no effect for a client.
 	DisableVersionNegotiationPackets bool
 	// Enable QUIC datagram support (RFC 9221).
 	EnableDatagrams bool
-	Tracer          logging.Tracer
+	// MaxDatagramFrameSize is the max_datagram_frame_size we advertise to the peer.
+	// It only has an effect if EnableDatagrams is set.
+	// If not set, it will default to 1220 bytes, such that a DATAGRAM frame fits into the smallest QUIC packet.
+	// Values larger than the maximum QUIC packet size (1452 bytes) are reduced to that size.
+	MaxDatagramFrameSize uint64
+	// DatagramSendQueueLen is the maximum number of DATAGRAM frames queued for sending.
+	// If not set, it will default to 1.
+	DatagramSendQueueLen int
+	// DatagramOverflowPolicy determines what happens when SendMessage is called while the send queue is full.
+	// By default, SendMessage blocks until the DATAGRAM was sent.
+	DatagramOverflowPolicy DatagramOverflowPolicy
+	// FramerQuotas divide the space in a packet between control frames, reliable streams and partially reliable streams.
+	// By default, every class receives an equal share of the packet, as long as the other classes have data to send.
+	FramerQuotas FramerQuotas
+	// Scheduler creates the Scheduler of a connection, which decides which stream sends data next.
+	// SchedulerEDF sends the data that expires first, for streams that use the PTDADeadline policy.
+	// If a Scheduler is used, FramerQuotas only limit the space used by control frames, and PRConfig.SeparatePRPackets has no effect.
+	// By default, streams are served round-robin, and the packet is divided between reliable and PR streams according to the FramerQuotas.
+	Scheduler func() Scheduler
+	// TestingLossInjector is called for every 1-RTT packet before it is sent.
+	// If it returns true, the packet is dropped instead of being sent, as if it was lost in the network:
+	// it is still tracked for loss detection and congestion control, such that the loss is detected and handled as usual.
+	// This allows exercising the partial reliability policies in tests and examples without a lossy network.
+	// Packets sent during the handshake are never dropped.
+	// It is called from the connection's run loop, and must not block. It must not be used in production.
+	TestingLossInjector func(logging.PacketNumber) bool
+	Tracer              logging.Tracer
+	// Logger receives the log messages of the connections.
+	// If not set, the messages are logged using the log package.
+	// In both cases, the log level is set by the QUIC_GO_LOG_LEVEL environment variable.
+	// All messages of a connection carry its connection ID (key "conn_id"),
+	// messages concerning partially reliable streams carry the stream ID ("stream_id") and the PR policy ("pr_policy").
+	Logger Logger
+	// PR configures partial reliability.
+	PR PRConfig
 }
 
 // ConnectionState records basic details about a QUIC connection
 type ConnectionState struct {
 	TLS               handshake.ConnectionState
 	SupportsDatagrams bool
+	// MaxDatagramSize is the size of the largest message that can currently be sent using SendMessage.
+	// It depends on the peer's max_datagram_frame_size and on the packet size, and may grow
+	// when Path MTU Discovery increases the packet size.
+	MaxDatagramSize int
+	// Handshake contains timing information about the handshake.
+	Handshake HandshakeState
+}
+
+// HandshakeState contains the times when the handshake reached its different stages.
+// Times are zero if the stage wasn't reached (yet).
+type HandshakeState struct {
+	// Start is the time when the connection was created.
+	Start time.Time
+	// FirstFlightSent is the time when the first packet was sent.
+	FirstFlightSent time.Time
+	// TLSComplete is the time when the TLS handshake completed.
+	TLSComplete time.Time
+	// First1RTTPacketReceived is the time when the first 1-RTT packet was received.
+	First1RTTPacketReceived time.Time
+	// Confirmed is the time when the handshake was confirmed.
+	Confirmed time.Time
+}
+
+// Duration returns the time it took to complete the TLS handshake.
+// It returns 0 if the handshake didn't complete (yet).
+func (s HandshakeState) Duration() time.Duration {
+	if s.TLSComplete.IsZero() {
+		return 0
+	}
+	return s.TLSComplete.Sub(s.Start)
 }
 
 // A Listener for incoming QUIC connections
 type Listener interface {
 	// Close the server. All active connections will be closed.
 	Close() error
+	// CloseGracefully stops accepting new connections, and waits until all active connections are closed,
+	// or until the context is done. Then it closes the server.
+	CloseGracefully(context.Context) error
 	// Addr returns the local network addr that the server is listening on.
 	Addr() net.Addr
 	// Accept returns new connections. It should be called in a loop.
 	Accept(context.Context) (Connection, error)
+	// Serve accepts connections, and calls handler for every connection in a new go routine.
+	// It is an alternative to calling Accept in a loop.
+	// It blocks until the listener is closed, and returns the error that Accept returned.
+	Serve(handler func(Connection)) error
+	// Import resumes a connection that another process handed off, see Connection.Handoff.
+	// The streams that were open are reset with the error code, see ConnectionHandoff.
+	// The connection is not returned by Accept.
+	Import(*ConnectionHandoff, StreamErrorCode) (Connection, error)
 }
 
 // An EarlyListener listens for incoming QUIC connections,
@@ -342,8 +843,19 @@ type Listener interface {
 type EarlyListener interface {
 	// Close the server. All active connections will be closed.
 	Close() error
+	// CloseGracefully stops accepting new connections, and waits until all active connections are closed,
+	// or until the context is done. Then it closes the server.
+	CloseGracefully(context.Context) error
 	// Addr returns the local network addr that the server is listening on.
 	Addr() net.Addr
 	// Accept returns new early connections. It should be called in a loop.
 	Accept(context.Context) (EarlyConnection, error)
+	// Serve accepts early connections, and calls handler for every connection in a new go routine.
+	// It is an alternative to calling Accept in a loop.
+	// It blocks until the listener is closed, and returns the error that Accept returned.
+	Serve(handler func(EarlyConnection)) error
+	// Import resumes a connection that another process handed off, see Connection.Handoff.
+	// The streams that were open are reset with the error code, see ConnectionHandoff.
+	// The connection is not returned by Accept.
+	Import(*ConnectionHandoff, StreamErrorCode) (Connection, error)
 }
@@ -1,9 +1,31 @@
 package ackhandler
 
-import "github.com/lucas-clemente/quic-go/internal/wire"
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
 
 type Frame struct {
 	wire.Frame // nil if the frame has already been acknowledged in another packet
 	OnLost     func(wire.Frame)
 	OnAcked    func(wire.Frame)
+	// OnAckedAfterLoss is called if the packet is acknowledged after it was declared lost,
+	// i.e. if the loss was spurious. It is optional.
+	// Since OnLost already handed the frame back, it is not passed to this callback.
+	OnAckedAfterLoss func()
+}
+
+var framePool = sync.Pool{New: func() any { return &Frame{} }}
+
+// GetFrame returns a Frame from the pool.
+func GetFrame() *Frame {
+	return framePool.Get().(*Frame)
+}
+
+// PutFrame returns a Frame to the pool.
+// Frames are copied into the packet when it is packed, so the Frame can be returned right after that.
+func PutFrame(f *Frame) {
+	*f = Frame{} // don't keep the wire.Frame and the callbacks alive
+	framePool.Put(f)
 }
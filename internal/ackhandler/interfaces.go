@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -16,6 +17,18 @@ type SentPacketHandler interface {
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry() error
 	SetHandshakeConfirmed()
+	// ResumeAt is used when resuming a connection that was handed off by another process.
+	// The 1-RTT packet number space continues at the given packet number.
+	ResumeAt(protocol.PacketNumber)
+	// MigratedPath resets the RTT estimate and the congestion controller,
+	// when the peer moved to a new path that might have different characteristics.
+	MigratedPath(maxDatagramSize protocol.ByteCount)
+	// StartPathValidation is called when the server starts sending to a new client address, before validating it.
+	// Until PathValidated is called, the server won't send more than 3x the bytes received on the new path,
+	// starting with the bytes received in the packet that caused the migration (RFC 9000, Section 9.3).
+	StartPathValidation(bytesReceived protocol.ByteCount)
+	// PathValidated is called when the client address the server is sending to was validated.
+	PathValidated()
 
 	// The SendMode determines if and what kind of packets can be sent.
 	SendMode() SendMode
@@ -25,6 +38,10 @@ type SentPacketHandler interface {
 	TimeUntilSend() time.Time
 	// HasPacingBudget says if the pacer allows sending of a (full size) packet at this moment.
 	HasPacingBudget() bool
+	// CanSendPR says if packets carrying only partially reliable frames can be sent,
+	// if they're limited by a scavenger congestion controller, see Config.PR.ScavengerCongestionControl.
+	// Otherwise, it always returns true.
+	CanSendPR() bool
 	SetMaxDatagramSize(count protocol.ByteCount)
 
 	// only to be called once the handshake is complete
@@ -35,6 +52,30 @@ type SentPacketHandler interface {
 
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
+
+	// AckMetrics returns statistics about the ACKs received for 1-RTT packets.
+	AckMetrics() AckMetrics
+}
+
+// AckMetrics are statistics about the ACKs received for 1-RTT packets.
+// The ack delays are limited to the peer's max_ack_delay, like when they're used for the RTT estimate.
+type AckMetrics struct {
+	// SmoothedAckDelay is the exponentially weighted moving average of the ack delay reported by the peer.
+	SmoothedAckDelay time.Duration
+	// MaxAckDelay is the largest ack delay reported by the peer.
+	MaxAckDelay time.Duration
+	// ReorderingDistance is the largest number of packets a packet was reordered by:
+	// the difference between the largest packet number acknowledged before, and the packet number of a packet acknowledged later.
+	ReorderingDistance protocol.PacketNumber
+	// ReorderingTime is the largest time a packet was reordered by:
+	// how much earlier it was sent than the largest packet acknowledged before.
+	ReorderingTime time.Duration
+	// SmoothedReorderingTime is the exponentially weighted moving average of the reordering time of the ACKs.
+	// ACKs that only acknowledge packets in order count as no reordering, so the estimate decays once the reordering stops.
+	SmoothedReorderingTime time.Duration
+	// BandwidthEstimate is the bandwidth estimated by the congestion controller, in bits per second.
+	// It is 0 until the RTT was measured.
+	BandwidthEstimate congestion.Bandwidth
 }
 
 type sentPacketTracker interface {
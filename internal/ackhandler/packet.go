@@ -5,8 +5,43 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
+// A PacketClass classifies a packet by the reliability of the frames it carries.
+type PacketClass uint8
+
+const (
+	// PacketClassReliable packets contain at least one frame that is retransmitted when lost.
+	PacketClassReliable PacketClass = iota
+	// PacketClassPR packets only contain partially reliable frames.
+	// The PR policy might abandon these frames when the packet is lost.
+	PacketClassPR
+)
+
+func (c PacketClass) String() string {
+	switch c {
+	case PacketClassReliable:
+		return "reliable"
+	case PacketClassPR:
+		return "partially reliable"
+	default:
+		return "unknown packet class"
+	}
+}
+
+// classifyFrames determines the class of an ack-eliciting packet.
+func classifyFrames(frames []Frame) PacketClass {
+	for _, f := range frames {
+		switch f.Frame.(type) {
+		case *wire.PRStreamFrame, *wire.PRDatagramFrame:
+		default:
+			return PacketClassReliable
+		}
+	}
+	return PacketClassPR
+}
+
 // A Packet is a packet
 type Packet struct {
 	PacketNumber    protocol.PacketNumber
@@ -18,6 +53,9 @@ type Packet struct {
 
 	IsPathMTUProbePacket bool // We don't report the loss of Path MTU probe packets to the congestion controller.
 
+	// Class is set by the SentPacketHandler when the packet is sent.
+	Class PacketClass
+
 	includedInBytesInFlight bool
 	declaredLost            bool
 	skippedPacket           bool
@@ -38,6 +76,7 @@ func GetPacket() *Packet {
 	p.EncryptionLevel = protocol.EncryptionLevel(0)
 	p.SendTime = time.Time{}
 	p.IsPathMTUProbePacket = false
+	p.Class = PacketClassReliable
 	p.includedInBytesInFlight = false
 	p.declaredLost = false
 	p.skippedPacket = false
@@ -14,15 +14,12 @@ import (
 )
 
 const (
-	// Maximum reordering in time space before time based loss detection considers a packet lost.
-	// Specified as an RTT multiplier.
-	timeThreshold = 9.0 / 8
-	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
-	packetThreshold = 3
-	// Before validating the client's address, the server won't send more than 3x bytes than it received.
-	amplificationFactor = 3
 	// We use Retry packets to derive an RTT estimate. Make sure we don't set the RTT to a super low value yet.
 	minRTTAfterRetry = 5 * time.Millisecond
+	// If all outstanding application data packets are of class PacketClassPR, the PTO is multiplied by this factor.
+	// The PR policy might abandon the frames in these packets anyway,
+	// so sending probe packets as early as for reliable data would mostly be spurious.
+	prPTOMultiplier = 2
 )
 
 type packetNumberSpace struct {
@@ -64,6 +61,8 @@ type sentPacketHandler struct {
 	// Have we validated the peer's address yet?
 	// Always true for the client.
 	peerAddressValidated bool
+	// Before validating the client's address, the server won't send more than amplificationFactor times the bytes it received.
+	amplificationFactor protocol.ByteCount
 
 	handshakeConfirmed bool
 
@@ -75,11 +74,22 @@ type sentPacketHandler struct {
 
 	ackedPackets []*Packet // to avoid allocations in detectAndRemoveAckedPackets
 
+	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
+	packetThreshold protocol.PacketNumber
+	// Maximum reordering in time space before time based loss detection considers a packet lost.
+	// Specified as an RTT multiplier.
+	timeThreshold float64
+
 	bytesInFlight protocol.ByteCount
 
 	congestion congestion.SendAlgorithmWithDebugInfos
 	rttStats   *utils.RTTStats
 
+	// If set, packets of class PacketClassPR are also limited by a scavenger congestion controller,
+	// see Config.PR.ScavengerCongestionControl.
+	prCongestion    congestion.SendAlgorithmWithDebugInfos
+	prBytesInFlight protocol.ByteCount
+
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
 	ptoMode  SendMode
@@ -90,6 +100,12 @@ type sentPacketHandler struct {
 	// The alarm timeout
 	alarm time.Time
 
+	ackMetrics AckMetrics
+	// set once an ack delay was recorded in the ackMetrics
+	hasAckDelay bool
+	// the send time of the largest acknowledged 1-RTT packet, used to measure the reordering time
+	largestAckedSendTime time.Time
+
 	perspective protocol.Perspective
 
 	tracer logging.ConnectionTracer
@@ -108,10 +124,19 @@ func newSentPacketHandler(
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
 	clientAddressValidated bool,
+	amplificationFactor int,
+	packetThreshold int,
+	timeThreshold float64,
+	scavengerPR bool,
 	pers protocol.Perspective,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
+	var prCongestion congestion.SendAlgorithmWithDebugInfos
+	if scavengerPR {
+		prCongestion = congestion.NewLedbatSender(rttStats, initialMaxDatagramSize)
+	}
+
 	congestion := congestion.NewCubicSender(
 		congestion.DefaultClock{},
 		rttStats,
@@ -123,11 +148,15 @@ func newSentPacketHandler(
 	return &sentPacketHandler{
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
 		peerAddressValidated:           pers == protocol.PerspectiveClient || clientAddressValidated,
+		amplificationFactor:            protocol.ByteCount(amplificationFactor),
+		packetThreshold:                protocol.PacketNumber(packetThreshold),
+		timeThreshold:                  timeThreshold,
 		initialPackets:                 newPacketNumberSpace(initialPN, false, rttStats),
 		handshakePackets:               newPacketNumberSpace(0, false, rttStats),
 		appDataPackets:                 newPacketNumberSpace(0, true, rttStats),
 		rttStats:                       rttStats,
 		congestion:                     congestion,
+		prCongestion:                   prCongestion,
 		perspective:                    pers,
 		tracer:                         tracer,
 		logger:                         logger,
@@ -150,10 +179,18 @@ func (h *sentPacketHandler) removeFromBytesInFlight(p *Packet) {
 			panic("negative bytes_in_flight")
 		}
 		h.bytesInFlight -= p.Length
+		if h.isScavengerPacket(p) {
+			h.prBytesInFlight -= p.Length
+		}
 		p.includedInBytesInFlight = false
 	}
 }
 
+// isScavengerPacket says if a packet is limited by the scavenger congestion controller.
+func (h *sentPacketHandler) isScavengerPacket(p *Packet) bool {
+	return h.prCongestion != nil && p.Class == PacketClassPR
+}
+
 func (h *sentPacketHandler) dropPackets(encLevel protocol.EncryptionLevel) {
 	// The server won't await address validation after the handshake is confirmed.
 	// This applies even if we didn't receive an ACK for a Handshake packet.
@@ -227,7 +264,11 @@ func (h *sentPacketHandler) packetsInFlight() int {
 }
 
 func (h *sentPacketHandler) SentPacket(p *Packet) {
+	wasAmplificationLimit := h.isAmplificationLimited()
 	h.bytesSent += p.Length
+	if !wasAmplificationLimit && h.isAmplificationLimited() && h.tracer != nil {
+		h.tracer.AmplificationLimited(h.bytesSent, h.bytesReceived)
+	}
 	// For the client, drop the Initial packet number space when the first Handshake packet is sent.
 	if h.perspective == protocol.PerspectiveClient && p.EncryptionLevel == protocol.EncryptionHandshake && h.initialPackets != nil {
 		h.dropPackets(protocol.EncryptionInitial)
@@ -274,6 +315,7 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 	isAckEliciting := len(packet.Frames) > 0
 
 	if isAckEliciting {
+		packet.Class = classifyFrames(packet.Frames)
 		pnSpace.lastAckElicitingPacketTime = packet.SendTime
 		packet.includedInBytesInFlight = true
 		h.bytesInFlight += packet.Length
@@ -282,6 +324,10 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 		}
 	}
 	h.congestion.OnPacketSent(packet.SendTime, h.bytesInFlight, packet.PacketNumber, packet.Length, isAckEliciting)
+	if isAckEliciting && h.isScavengerPacket(packet) {
+		h.prBytesInFlight += packet.Length
+		h.prCongestion.OnPacketSent(packet.SendTime, h.prBytesInFlight, packet.PacketNumber, packet.Length, true)
+	}
 
 	return isAckEliciting
 }
@@ -296,6 +342,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		}
 	}
 
+	priorLargestAcked := pnSpace.largestAcked
 	pnSpace.largestAcked = utils.Max(pnSpace.largestAcked, largestAcked)
 
 	// Servers complete address validation when a protected packet is received.
@@ -308,10 +355,14 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	}
 
 	priorInFlight := h.bytesInFlight
+	priorPRInFlight := h.prBytesInFlight
 	ackedPackets, err := h.detectAndRemoveAckedPackets(ack, encLevel)
 	if err != nil || len(ackedPackets) == 0 {
 		return false, err
 	}
+	if encLevel == protocol.Encryption1RTT && priorLargestAcked != protocol.InvalidPacketNumber {
+		h.updateReordering(ackedPackets, priorLargestAcked)
+	}
 	// update the RTT, if the largest acked is newly acknowledged
 	if len(ackedPackets) > 0 {
 		if p := ackedPackets[len(ackedPackets)-1]; p.PacketNumber == ack.LargestAcked() {
@@ -319,12 +370,17 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 			var ackDelay time.Duration
 			if encLevel == protocol.Encryption1RTT {
 				ackDelay = utils.Min(ack.DelayTime, h.rttStats.MaxAckDelay())
+				h.updateAckDelay(ackDelay)
+				h.largestAckedSendTime = p.SendTime
 			}
 			h.rttStats.UpdateRTT(rcvTime.Sub(p.SendTime), ackDelay, rcvTime)
 			if h.logger.Debug() {
 				h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
 			}
 			h.congestion.MaybeExitSlowStart()
+			if h.prCongestion != nil {
+				h.prCongestion.MaybeExitSlowStart()
+			}
 		}
 	}
 	if err := h.detectLostPackets(rcvTime, encLevel); err != nil {
@@ -334,6 +390,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	for _, p := range ackedPackets {
 		if p.includedInBytesInFlight && !p.declaredLost {
 			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
+			if h.isScavengerPacket(p) {
+				h.prCongestion.OnPacketAcked(p.PacketNumber, p.Length, priorPRInFlight, rcvTime)
+			}
 		}
 		if p.EncryptionLevel == protocol.Encryption1RTT {
 			acked1RTTPacket = true
@@ -363,6 +422,46 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	return acked1RTTPacket, nil
 }
 
+// updateAckDelay updates the ack delay statistics with an ack delay reported by the peer.
+// The moving average uses the same weight as the smoothed RTT.
+func (h *sentPacketHandler) updateAckDelay(ackDelay time.Duration) {
+	m := &h.ackMetrics
+	if !h.hasAckDelay {
+		h.hasAckDelay = true
+		m.SmoothedAckDelay = ackDelay
+	} else {
+		m.SmoothedAckDelay = time.Duration(float32(m.SmoothedAckDelay/time.Microsecond)*7/8+float32(ackDelay/time.Microsecond)/8) * time.Microsecond
+	}
+	m.MaxAckDelay = utils.Max(m.MaxAckDelay, ackDelay)
+}
+
+// updateReordering updates the reordering statistics with the packets acknowledged after priorLargestAcked was acknowledged.
+// ackedPackets are sorted by packet number.
+// The moving average uses the same weight as the smoothed RTT.
+func (h *sentPacketHandler) updateReordering(ackedPackets []*Packet, priorLargestAcked protocol.PacketNumber) {
+	m := &h.ackMetrics
+	var reorderingTime time.Duration
+	for _, p := range ackedPackets {
+		if p.PacketNumber >= priorLargestAcked {
+			break
+		}
+		m.ReorderingDistance = utils.Max(m.ReorderingDistance, priorLargestAcked-p.PacketNumber)
+		if !h.largestAckedSendTime.IsZero() {
+			reorderingTime = utils.Max(reorderingTime, h.largestAckedSendTime.Sub(p.SendTime))
+		}
+	}
+	m.ReorderingTime = utils.Max(m.ReorderingTime, reorderingTime)
+	m.SmoothedReorderingTime = time.Duration(float32(m.SmoothedReorderingTime/time.Microsecond)*7/8+float32(reorderingTime/time.Microsecond)/8) * time.Microsecond
+}
+
+func (h *sentPacketHandler) AckMetrics() AckMetrics {
+	m := h.ackMetrics
+	if h.rttStats.SmoothedRTT() > 0 {
+		m.BandwidthEstimate = h.congestion.BandwidthEstimate()
+	}
+	return m
+}
+
 func (h *sentPacketHandler) GetLowestPacketNotConfirmedAcked() protocol.PacketNumber {
 	return h.lowestNotConfirmedAcked
 }
@@ -422,6 +521,12 @@ func (h *sentPacketHandler) detectAndRemoveAckedPackets(ack *wire.AckFrame, encL
 		}
 
 		for _, f := range p.Frames {  
+			if p.declaredLost {
+				if f.OnAckedAfterLoss != nil {
+					f.OnAckedAfterLoss()
+				}
+				continue
+			}
 			if f.OnAcked != nil {
 				f.OnAcked(f.Frame)  //对确认的包中的所有帧执行相应函数
 			}
@@ -485,7 +590,7 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (pto time.Time, encLevel protoc
 		}
 	}
 	if h.handshakeConfirmed && !h.appDataPackets.lastAckElicitingPacketTime.IsZero() {
-		t := h.appDataPackets.lastAckElicitingPacketTime.Add(h.rttStats.PTO(true) << h.ptoCount)
+		t := h.appDataPackets.lastAckElicitingPacketTime.Add(h.appDataPTO())
 		if pto.IsZero() || (!t.IsZero() && t.Before(pto)) {
 			pto = t
 			encLevel = protocol.Encryption1RTT
@@ -494,6 +599,17 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (pto time.Time, encLevel protoc
 	return pto, encLevel, true
 }
 
+// appDataPTO returns the PTO duration for the application data packet number space (including the backoff).
+// If only packets of class PacketClassPR are outstanding, the PTO is extended.
+func (h *sentPacketHandler) appDataPTO() time.Duration {
+	pto := h.rttStats.PTO(true) << h.ptoCount
+	history := h.appDataPackets.history
+	if history.HasOutstandingPackets() && !history.HasOutstandingReliablePackets() {
+		pto *= prPTOMultiplier
+	}
+	return pto
+}
+
 func (h *sentPacketHandler) hasOutstandingCryptoPackets() bool {
 	if h.initialPackets != nil && h.initialPackets.history.HasOutstandingPackets() {
 		return true
@@ -567,7 +683,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 	pnSpace.lossTime = time.Time{}
 
 	maxRTT := float64(utils.Max(h.rttStats.LatestRTT(), h.rttStats.SmoothedRTT()))
-	lossDelay := time.Duration(timeThreshold * maxRTT)
+	lossDelay := time.Duration(h.timeThreshold * maxRTT)
 
 	// Minimum time of granularity before packets are deemed lost.
 	lossDelay = utils.Max(lossDelay, protocol.TimerGranularity)
@@ -576,6 +692,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 	lostSendTime := now.Add(-lossDelay)
 
 	priorInFlight := h.bytesInFlight
+	priorPRInFlight := h.prBytesInFlight
 	return pnSpace.history.Iterate(func(p *Packet) (bool, error) {
 		if p.PacketNumber > pnSpace.largestAcked {
 			return false, nil
@@ -593,7 +710,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			if h.tracer != nil {
 				h.tracer.LostPacket(p.EncryptionLevel, p.PacketNumber, logging.PacketLossTimeThreshold)
 			}
-		} else if pnSpace.largestAcked >= p.PacketNumber+packetThreshold {  //乱序空洞太大丢包
+		} else if pnSpace.largestAcked >= p.PacketNumber+h.packetThreshold {  //乱序空洞太大丢包
 			packetLost = true
 			if h.logger.Debug() {
 				h.logger.Debugf("\tlost packet %d (reordering threshold)", p.PacketNumber)
@@ -616,6 +733,9 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			h.queueFramesForRetransmission(p)
 			if !p.IsPathMTUProbePacket {
 				h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
+				if h.isScavengerPacket(p) {
+					h.prCongestion.OnPacketLost(p.PacketNumber, p.Length, priorPRInFlight)
+				}
 			}
 		}
 		return true, nil
@@ -750,6 +870,16 @@ func (h *sentPacketHandler) SendMode() SendMode {
 	return SendAny
 }
 
+func (h *sentPacketHandler) CanSendPR() bool {
+	if h.prCongestion == nil || h.prCongestion.CanSend(h.prBytesInFlight) {
+		return true
+	}
+	if h.logger.Debug() {
+		h.logger.Debugf("Scavenger congestion limited: PR bytes in flight %d, window %d", h.prBytesInFlight, h.prCongestion.GetCongestionWindow())
+	}
+	return false
+}
+
 func (h *sentPacketHandler) TimeUntilSend() time.Time {
 	return h.congestion.TimeUntilSend(h.bytesInFlight)
 }
@@ -760,13 +890,45 @@ func (h *sentPacketHandler) HasPacingBudget() bool {
 
 func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
 	h.congestion.SetMaxDatagramSize(s)
+	if h.prCongestion != nil {
+		h.prCongestion.SetMaxDatagramSize(s)
+	}
+}
+
+func (h *sentPacketHandler) MigratedPath(maxDatagramSize protocol.ByteCount) {
+	h.rttStats.OnConnectionMigration()
+	h.congestion = congestion.NewCubicSender(
+		congestion.DefaultClock{},
+		h.rttStats,
+		maxDatagramSize,
+		true, // use Reno
+		h.tracer,
+	)
+	if h.prCongestion != nil {
+		h.prCongestion = congestion.NewLedbatSender(h.rttStats, maxDatagramSize)
+	}
+}
+
+func (h *sentPacketHandler) StartPathValidation(bytesReceived protocol.ByteCount) {
+	h.peerAddressValidated = false
+	h.bytesSent = 0
+	h.bytesReceived = bytesReceived
+	h.setLossDetectionTimer()
+}
+
+func (h *sentPacketHandler) PathValidated() {
+	if h.peerAddressValidated {
+		return
+	}
+	h.peerAddressValidated = true
+	h.setLossDetectionTimer()
 }
 
 func (h *sentPacketHandler) isAmplificationLimited() bool {
 	if h.peerAddressValidated {
 		return false
 	}
-	return h.bytesSent >= amplificationFactor*h.bytesReceived
+	return h.bytesSent >= h.amplificationFactor*h.bytesReceived
 }
 
 func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel) bool {
@@ -787,10 +949,15 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *Packet) {
 	if len(p.Frames) == 0 {
 		panic("no frames")
 	}
+	var lateAckHandlers []Frame
 	for _, f := range p.Frames {
 		f.OnLost(f.Frame)  //执行钩子函数，针对不同帧有不同处理，丢失的帧加入重传队列
+		if f.OnAckedAfterLoss != nil {
+			// The frame itself might already be reused, only keep the callback.
+			lateAckHandlers = append(lateAckHandlers, Frame{OnAckedAfterLoss: f.OnAckedAfterLoss})
+		}
 	}
-	p.Frames = nil
+	p.Frames = lateAckHandlers
 }
 
 func (h *sentPacketHandler) ResetForRetry() error {
@@ -848,3 +1015,13 @@ func (h *sentPacketHandler) SetHandshakeConfirmed() {
 	// Make sure the timer is armed now, if necessary.
 	h.setLossDetectionTimer()
 }
+
+func (h *sentPacketHandler) ResumeAt(pn protocol.PacketNumber) {
+	h.appDataPackets = newPacketNumberSpace(pn, true, h.rttStats)
+	// The peer might still acknowledge packets sent before the connection was handed off.
+	// These packets are not in the history, so they're not declared lost, and they're not treated as skipped packets.
+	h.appDataPackets.largestSent = pn - 1
+	h.appDataPackets.history.highestSent = pn - 1
+	// The peer's address was validated before the connection was handed off.
+	h.peerAddressValidated = true
+}
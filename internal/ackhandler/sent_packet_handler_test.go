@@ -7,6 +7,7 @@ import (
 	"github.com/golang/mock/gomock"
 
 	"github.com/lucas-clemente/quic-go/internal/mocks"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -29,7 +30,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, false, perspective, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, false, protocol.MaxAmplificationFactor, protocol.DefaultPacketThreshold, protocol.DefaultTimeThreshold, false, perspective, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -422,6 +423,104 @@ var _ = Describe("SentPacketHandler", func() {
 			})
 		})
 
+		Context("ACK metrics", func() {
+			It("smoothes the ack delay", func() {
+				handler.rttStats.SetMaxAckDelay(time.Hour)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}, DelayTime: 80 * time.Millisecond}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().SmoothedAckDelay).To(Equal(80 * time.Millisecond))
+				Expect(handler.AckMetrics().MaxAckDelay).To(Equal(80 * time.Millisecond))
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}, DelayTime: 8 * time.Millisecond}
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().SmoothedAckDelay).To(Equal(71 * time.Millisecond))
+				Expect(handler.AckMetrics().MaxAckDelay).To(Equal(80 * time.Millisecond))
+			})
+
+			It("limits the ack delay to max_ack_delay", func() {
+				handler.rttStats.SetMaxAckDelay(25 * time.Millisecond)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}, DelayTime: time.Second}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().SmoothedAckDelay).To(Equal(25 * time.Millisecond))
+				Expect(handler.AckMetrics().MaxAckDelay).To(Equal(25 * time.Millisecond))
+			})
+
+			It("ignores the ack delay of ACKs that don't acknowledge a new largest packet", func() {
+				handler.rttStats.SetMaxAckDelay(time.Hour)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}, DelayTime: 10 * time.Millisecond}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}, {Smallest: 1, Largest: 2}}, DelayTime: time.Second}
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().SmoothedAckDelay).To(Equal(10 * time.Millisecond))
+			})
+
+			It("reports the bandwidth estimate of the congestion controller", func() {
+				Expect(handler.AckMetrics().BandwidthEstimate).To(Equal(handler.congestion.BandwidthEstimate()))
+				Expect(handler.AckMetrics().BandwidthEstimate).ToNot(BeZero())
+			})
+
+			It("measures the reordering", func() {
+				now := time.Now()
+				getPacket(2, protocol.Encryption1RTT).SendTime = now.Add(-50 * time.Millisecond)
+				getPacket(3, protocol.Encryption1RTT).SendTime = now.Add(-40 * time.Millisecond)
+				getPacket(7, protocol.Encryption1RTT).SendTime = now.Add(-20 * time.Millisecond)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 7, Largest: 7}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().ReorderingDistance).To(BeZero())
+				Expect(handler.AckMetrics().ReorderingTime).To(BeZero())
+				// packet 3 arrives after packet 7
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 7, Largest: 7}, {Smallest: 3, Largest: 3}}}
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().ReorderingDistance).To(Equal(protocol.PacketNumber(4)))
+				Expect(handler.AckMetrics().ReorderingTime).To(Equal(20 * time.Millisecond))
+				Expect(handler.AckMetrics().SmoothedReorderingTime).To(Equal(20 * time.Millisecond / 8))
+				// packet 2 arrives even later
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 7, Largest: 7}, {Smallest: 2, Largest: 3}}}
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().ReorderingDistance).To(Equal(protocol.PacketNumber(5)))
+				Expect(handler.AckMetrics().ReorderingTime).To(Equal(30 * time.Millisecond))
+				Expect(handler.AckMetrics().SmoothedReorderingTime).To(BeNumerically("~", 20*time.Millisecond*7/64+30*time.Millisecond/8, time.Microsecond))
+			})
+
+			It("decays the smoothed reordering time once packets are acknowledged in order", func() {
+				now := time.Now()
+				getPacket(2, protocol.Encryption1RTT).SendTime = now.Add(-50 * time.Millisecond)
+				getPacket(3, protocol.Encryption1RTT).SendTime = now.Add(-10 * time.Millisecond)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 3}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.AckMetrics().SmoothedReorderingTime).To(Equal(40 * time.Millisecond / 8))
+				for largest := protocol.PacketNumber(4); largest <= 9; largest++ {
+					ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: largest}}}
+					_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(handler.AckMetrics().SmoothedReorderingTime).To(BeNumerically("<", 40*time.Millisecond/16))
+				// the largest reordering time is still reported
+				Expect(handler.AckMetrics().ReorderingTime).To(Equal(40 * time.Millisecond))
+			})
+
+			It("doesn't count packets acknowledged in order as reordered", func() {
+				for _, largest := range []protocol.PacketNumber{2, 4, 8} {
+					ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: largest}}}
+					_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(handler.AckMetrics().ReorderingDistance).To(BeZero())
+				Expect(handler.AckMetrics().ReorderingTime).To(BeZero())
+			})
+		})
+
 		Context("determining which ACKs we have received an ACK for", func() {
 			JustBeforeEach(func() {
 				morePackets := []*Packet{
@@ -689,6 +788,52 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(4 * timeout))
 		})
 
+		Context("partially reliable packets", func() {
+			prPacket := func(p *Packet) *Packet {
+				p.Frames = []Frame{{Frame: &wire.PRStreamFrame{StreamID: 5, Data: []byte("foobar")}}}
+				return ackElicitingPacket(p)
+			}
+
+			It("classifies packets", func() {
+				handler.SentPacket(prPacket(&Packet{PacketNumber: 1}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+				handler.SentPacket(ackElicitingPacket(&Packet{
+					PacketNumber: 3,
+					Frames: []Frame{
+						{Frame: &wire.PRStreamFrame{StreamID: 5, Data: []byte("foo")}},
+						{Frame: &wire.PingFrame{}},
+					},
+				}))
+				Expect(getPacket(1, protocol.Encryption1RTT).Class).To(Equal(PacketClassPR))
+				Expect(getPacket(2, protocol.Encryption1RTT).Class).To(Equal(PacketClassReliable))
+				Expect(getPacket(3, protocol.Encryption1RTT).Class).To(Equal(PacketClassReliable))
+			})
+
+			It("uses a longer PTO if only PR packets are outstanding", func() {
+				handler.peerAddressValidated = true
+				handler.SetHandshakeConfirmed()
+				sendTime := time.Now().Add(-time.Hour)
+				handler.SentPacket(prPacket(&Packet{PacketNumber: 1, SendTime: sendTime}))
+				Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(prPTOMultiplier * handler.rttStats.PTO(true)))
+				handler.ptoCount = 1
+				handler.setLossDetectionTimer()
+				Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(2 * prPTOMultiplier * handler.rttStats.PTO(true)))
+			})
+
+			It("uses the regular PTO if a reliable packet is outstanding", func() {
+				handler.peerAddressValidated = true
+				handler.SetHandshakeConfirmed()
+				sendTime := time.Now().Add(-time.Hour)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: sendTime}))
+				handler.SentPacket(prPacket(&Packet{PacketNumber: 2, SendTime: sendTime}))
+				Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(handler.rttStats.PTO(true)))
+				// once the reliable packet is acknowledged, only the PR packet is outstanding
+				_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}, protocol.Encryption1RTT, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(prPTOMultiplier * handler.rttStats.PTO(true)))
+			})
+		})
+
 		It("reset the PTO count when receiving an ACK", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			now := time.Now()
@@ -901,6 +1046,21 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendNone))
 		})
 
+		It("uses the configured amplification factor, and traces when it becomes amplification limited", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), false, 2, protocol.DefaultPacketThreshold, protocol.DefaultTimeThreshold, false, perspective, tracer, utils.DefaultLogger)
+			handler.ReceivedBytes(200)
+			// send packets that are not ack-eliciting, such that no other events are traced
+			handler.SentPacket(&Packet{PacketNumber: 1, Length: 399, EncryptionLevel: protocol.EncryptionInitial, SendTime: time.Now()})
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			tracer.EXPECT().AmplificationLimited(protocol.ByteCount(400), protocol.ByteCount(200))
+			handler.SentPacket(&Packet{PacketNumber: 2, Length: 1, EncryptionLevel: protocol.EncryptionInitial, SendTime: time.Now()})
+			Expect(handler.SendMode()).To(Equal(SendNone))
+			// the event is only traced once
+			handler.SentPacket(&Packet{PacketNumber: 3, Length: 1, EncryptionLevel: protocol.EncryptionInitial, SendTime: time.Now()})
+		})
+
 		It("cancels the loss detection timer when it is amplification limited, and resets it when becoming unblocked", func() {
 			handler.ReceivedBytes(300)
 			handler.SentPacket(&Packet{
@@ -947,7 +1107,7 @@ var _ = Describe("SentPacketHandler", func() {
 	Context("amplification limit, for the server, with validated address", func() {
 		JustBeforeEach(func() {
 			rttStats := utils.NewRTTStats()
-			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, true, perspective, nil, utils.DefaultLogger)
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, true, protocol.MaxAmplificationFactor, protocol.DefaultPacketThreshold, protocol.DefaultTimeThreshold, false, perspective, nil, utils.DefaultLogger)
 		})
 
 		It("do not limits the window", func() {
@@ -1077,6 +1237,70 @@ var _ = Describe("SentPacketHandler", func() {
 			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
 		})
+
+		It("uses the configured packet threshold", func() {
+			handler.packetThreshold = 1
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 3; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 3}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2}))
+		})
+
+		It("hands the frames of PR packets to the PR policy, with an aggressive packet threshold", func() {
+			handler.packetThreshold = 1
+			// The OnLost callback of a STREAM frame sent on a PR stream applies the PR policy.
+			// Here, the frame is abandoned the second time it's lost, just like with PTDATimes.
+			var numLost int
+			var abandoned bool
+			onLost := func(wire.Frame) {
+				numLost++
+				if numLost >= 2 {
+					abandoned = true
+				}
+			}
+			prFrame := func() []Frame {
+				return []Frame{{Frame: &wire.PRStreamFrame{StreamID: 5, Data: []byte("foobar")}, OnLost: onLost}}
+			}
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Frames: prFrame()}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numLost).To(Equal(1))
+			Expect(abandoned).To(BeFalse())
+			// the retransmission is lost as well
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, Frames: prFrame()}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4}))
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}, {Smallest: 2, Largest: 2}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numLost).To(Equal(2))
+			Expect(abandoned).To(BeTrue())
+		})
+
+		It("calls OnAckedAfterLoss when a packet is acknowledged after it was declared lost", func() {
+			handler.packetThreshold = 1
+			var lost, acked, ackedAfterLoss bool
+			f := Frame{
+				Frame:            &wire.PRStreamFrame{StreamID: 5, Data: []byte("foobar")},
+				OnLost:           func(wire.Frame) { lost = true },
+				OnAcked:          func(wire.Frame) { acked = true },
+				OnAckedAfterLoss: func() { ackedAfterLoss = true },
+			}
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Frames: []Frame{f}}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lost).To(BeTrue())
+			Expect(ackedAfterLoss).To(BeFalse())
+			// the ACK for packet 1 arrives late
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ackedAfterLoss).To(BeTrue())
+			Expect(acked).To(BeFalse())
+		})
 	})
 
 	Context("Delay-based loss detection", func() {
@@ -1118,6 +1342,20 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendAny))
 		})
 
+		It("uses the configured time threshold", func() {
+			handler.timeThreshold = 1.5
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			handler.handshakeConfirmed = true
+			now := time.Now()
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-2 * time.Second)}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now.Add(-2 * time.Second)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(-time.Second))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.rttStats.SmoothedRTT()).To(Equal(time.Second))
+			Expect(handler.GetLossDetectionTimeout().Sub(getPacket(1, protocol.Encryption1RTT).SendTime)).To(Equal(time.Second * 3 / 2))
+		})
+
 		It("sets the early retransmit alarm for crypto packets", func() {
 			handler.ReceivedBytes(1000)
 			now := time.Now()
@@ -1304,6 +1542,118 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("scavenger congestion control for PR packets", func() {
+		var prCong *mocks.MockSendAlgorithmWithDebugInfos
+
+		JustBeforeEach(func() {
+			prCong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			handler.prCongestion = prCong
+		})
+
+		prPacket := func(p *Packet) *Packet {
+			p = ackElicitingPacket(p)
+			p.Frames = []Frame{{Frame: &wire.PRStreamFrame{StreamID: 4, Data: []byte("foobar")}, OnLost: func(wire.Frame) {}}}
+			return p
+		}
+
+		It("always allows sending PR packets if it is disabled", func() {
+			handler.prCongestion = nil
+			handler.SentPacket(prPacket(&Packet{PacketNumber: 1, Length: 42}))
+			Expect(handler.prBytesInFlight).To(BeZero())
+			Expect(handler.CanSendPR()).To(BeTrue())
+		})
+
+		It("only passes PR packets to the scavenger congestion controller", func() {
+			prCong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(42), protocol.PacketNumber(2), protocol.ByteCount(42), true)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 100}))
+			handler.SentPacket(prPacket(&Packet{PacketNumber: 2, Length: 42}))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(142)))
+			Expect(handler.prBytesInFlight).To(Equal(protocol.ByteCount(42)))
+			prCong.EXPECT().CanSend(protocol.ByteCount(42)).Return(false)
+			Expect(handler.CanSendPR()).To(BeFalse())
+			prCong.EXPECT().CanSend(protocol.ByteCount(42)).Return(true)
+			Expect(handler.CanSendPR()).To(BeTrue())
+		})
+
+		It("passes acknowledged PR packets to the scavenger congestion controller", func() {
+			rcvTime := time.Now()
+			prCong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			handler.SentPacket(prPacket(&Packet{PacketNumber: 1, Length: 10}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, Length: 10}))
+			handler.SentPacket(prPacket(&Packet{PacketNumber: 3, Length: 10}))
+			gomock.InOrder(
+				prCong.EXPECT().MaybeExitSlowStart(),
+				prCong.EXPECT().OnPacketAcked(protocol.PacketNumber(1), protocol.ByteCount(10), protocol.ByteCount(20), rcvTime),
+				prCong.EXPECT().OnPacketAcked(protocol.PacketNumber(3), protocol.ByteCount(10), protocol.ByteCount(20), rcvTime),
+			)
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}, protocol.Encryption1RTT, rcvTime)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.prBytesInFlight).To(BeZero())
+		})
+
+		It("passes lost PR packets to the scavenger congestion controller", func() {
+			prCong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
+			for i := protocol.PacketNumber(1); i <= 4; i++ {
+				handler.SentPacket(prPacket(&Packet{PacketNumber: i, Length: 10}))
+			}
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 5, Length: 10}))
+			prCong.EXPECT().MaybeExitSlowStart()
+			prCong.EXPECT().OnPacketLost(protocol.PacketNumber(1), protocol.ByteCount(10), protocol.ByteCount(40))
+			prCong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 5}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.prBytesInFlight).To(BeZero())
+		})
+	})
+
+	Context("resuming a connection that was handed off", func() {
+		It("continues at the packet number", func() {
+			handler.ResumeAt(1000)
+			pn, _ := handler.PeekPacketNumber(protocol.Encryption1RTT)
+			Expect(pn).To(Equal(protocol.PacketNumber(1000)))
+			Expect(handler.peerAddressValidated).To(BeTrue())
+		})
+
+		It("accepts ACKs for packets sent before the handoff", func() {
+			handler.ResumeAt(1000)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 990, Largest: 1000}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.appDataPackets.largestAcked).To(Equal(protocol.PacketNumber(1000)))
+			Expect(handler.bytesInFlight).To(BeZero())
+		})
+	})
+
+	Context("migrating to a new path", func() {
+		It("resets the RTT estimate and the congestion controller", func() {
+			handler.rttStats.UpdateRTT(time.Second, 0, time.Now())
+			handler.congestion.OnPacketLost(1, 1000, 1000)
+			cwnd := handler.congestion.GetCongestionWindow()
+			handler.MigratedPath(protocol.InitialPacketSizeIPv4)
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically(">", cwnd))
+			Expect(handler.congestion.InSlowStart()).To(BeTrue())
+		})
+
+		It("applies the amplification limit until the new path is validated", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake) // validates the client's address
+			handler.SetHandshakeConfirmed()
+			handler.ReceivedBytes(100)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 1000}))
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
+			handler.StartPathValidation(100)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, Length: 299}))
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, Length: 1}))
+			Expect(handler.SendMode()).To(Equal(SendNone))
+			Expect(handler.GetLossDetectionTimeout()).To(BeZero())
+			handler.PathValidated()
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
+		})
+	})
+
 	Context("for the client", func() {
 		BeforeEach(func() {
 			perspective = protocol.PerspectiveClient
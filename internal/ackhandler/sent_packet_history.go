@@ -15,6 +15,9 @@ type sentPacketHistory struct {
 	etcPacketList         *list.List[*Packet]
 	packetMap             map[protocol.PacketNumber]*list.Element[*Packet]
 	highestSent           protocol.PacketNumber
+
+	// the number of outstanding packets of class PacketClassPR
+	numOutstandingPR int
 }
 
 func newSentPacketHistory(rttStats *utils.RTTStats) *sentPacketHistory {
@@ -37,6 +40,9 @@ func (h *sentPacketHistory) SentAckElicitingPacket(p *Packet) {
 	var el *list.Element[*Packet]
 	if p.outstanding() {
 		el = h.outstandingPacketList.PushBack(p)
+		if p.Class == PacketClassPR {
+			h.numOutstandingPR++
+		}
 	} else {
 		el = h.etcPacketList.PushBack(p)
 	}
@@ -108,6 +114,7 @@ func (h *sentPacketHistory) Remove(p protocol.PacketNumber) error {
 	if !ok {
 		return fmt.Errorf("packet %d not found in sent packet history", p)
 	}
+	h.removedOutstanding(el.Value)
 	h.outstandingPacketList.Remove(el)
 	h.etcPacketList.Remove(el)
 	delete(h.packetMap, p)
@@ -118,6 +125,18 @@ func (h *sentPacketHistory) HasOutstandingPackets() bool {
 	return h.outstandingPacketList.Len() > 0
 }
 
+// HasOutstandingReliablePackets says if there are outstanding packets of class PacketClassReliable.
+func (h *sentPacketHistory) HasOutstandingReliablePackets() bool {
+	return h.outstandingPacketList.Len() > h.numOutstandingPR
+}
+
+// removedOutstanding must be called before a packet is removed from the outstanding packet list.
+func (h *sentPacketHistory) removedOutstanding(p *Packet) {
+	if p.outstanding() && p.Class == PacketClassPR {
+		h.numOutstandingPR--
+	}
+}
+
 func (h *sentPacketHistory) DeleteOldPackets(now time.Time) {
 	maxAge := 3 * h.rttStats.PTO(false)
 	var nextEl *list.Element[*Packet]
@@ -141,6 +160,7 @@ func (h *sentPacketHistory) DeclareLost(p *Packet) *Packet {
 	}
 	// try to remove it from both lists, as we don't know which one it currently belongs to.
 	// Remove is a no-op for elements that are not in the list.
+	h.removedOutstanding(p)
 	h.outstandingPacketList.Remove(el)
 	h.etcPacketList.Remove(el)
 	p.declaredLost = true
@@ -225,6 +225,34 @@ var _ = Describe("SentPacketHistory", func() {
 			Expect(hist.Remove(10)).To(Succeed())
 			Expect(hist.HasOutstandingPackets()).To(BeFalse())
 		})
+
+		It("says if it has outstanding reliable packets", func() {
+			hist.SentAckElicitingPacket(&Packet{PacketNumber: 10, Class: PacketClassPR})
+			Expect(hist.HasOutstandingPackets()).To(BeTrue())
+			Expect(hist.HasOutstandingReliablePackets()).To(BeFalse())
+			hist.SentAckElicitingPacket(&Packet{PacketNumber: 11})
+			Expect(hist.HasOutstandingReliablePackets()).To(BeTrue())
+			Expect(hist.Remove(11)).To(Succeed())
+			Expect(hist.HasOutstandingReliablePackets()).To(BeFalse())
+		})
+
+		It("accounts for lost PR packets", func() {
+			p := &Packet{PacketNumber: 10, Class: PacketClassPR}
+			hist.SentAckElicitingPacket(p)
+			hist.SentAckElicitingPacket(&Packet{PacketNumber: 11})
+			hist.DeclareLost(p)
+			Expect(hist.numOutstandingPR).To(BeZero())
+			Expect(hist.Remove(10)).To(Succeed())
+			Expect(hist.numOutstandingPR).To(BeZero())
+			Expect(hist.HasOutstandingReliablePackets()).To(BeTrue())
+		})
+
+		It("doesn't count PR packets that are not outstanding", func() {
+			hist.SentAckElicitingPacket(&Packet{PacketNumber: 10, Class: PacketClassPR, IsPathMTUProbePacket: true})
+			Expect(hist.numOutstandingPR).To(BeZero())
+			Expect(hist.Remove(10)).To(Succeed())
+			Expect(hist.numOutstandingPR).To(BeZero())
+		})
 	})
 
 	Context("deleting old packets", func() {
@@ -0,0 +1,171 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const (
+	// ledbatTarget is the queuing delay the LEDBAT sender aims for.
+	// It is lower than the 100ms of RFC 6817, like in LEDBAT++, such that interactive traffic isn't delayed much.
+	ledbatTarget = 60 * time.Millisecond
+	// ledbatGain determines how fast the congestion window grows, relative to Reno.
+	ledbatGain = 1
+	// ledbatInitialCongestionWindow is the initial congestion window in packets.
+	ledbatInitialCongestionWindow = 2
+)
+
+// The ledbatSender is a scavenger congestion controller, based on LEDBAT (RFC 6817).
+// It estimates the queuing delay as the difference between the latest and the minimum RTT,
+// and reduces the congestion window when the queuing delay exceeds the target.
+// This way, it only uses capacity that isn't used by other traffic.
+// It doesn't pace packets.
+type ledbatSender struct {
+	rttStats *utils.RTTStats
+
+	congestionWindow protocol.ByteCount
+	inSlowStart      bool
+
+	largestSentPacketNumber  protocol.PacketNumber
+	largestAckedPacketNumber protocol.PacketNumber
+	// Track the largest packet number outstanding when a CWND cutback occurs.
+	largestSentAtLastCutback protocol.PacketNumber
+
+	maxDatagramSize protocol.ByteCount
+}
+
+var _ SendAlgorithmWithDebugInfos = &ledbatSender{}
+
+// NewLedbatSender makes a new LEDBAT sender.
+func NewLedbatSender(rttStats *utils.RTTStats, initialMaxDatagramSize protocol.ByteCount) *ledbatSender {
+	return &ledbatSender{
+		rttStats:                 rttStats,
+		congestionWindow:         ledbatInitialCongestionWindow * initialMaxDatagramSize,
+		inSlowStart:              true,
+		largestSentPacketNumber:  protocol.InvalidPacketNumber,
+		largestAckedPacketNumber: protocol.InvalidPacketNumber,
+		largestSentAtLastCutback: protocol.InvalidPacketNumber,
+		maxDatagramSize:          initialMaxDatagramSize,
+	}
+}
+
+func (l *ledbatSender) TimeUntilSend(protocol.ByteCount) time.Time { return time.Time{} }
+
+func (l *ledbatSender) HasPacingBudget() bool { return true }
+
+func (l *ledbatSender) OnPacketSent(
+	_ time.Time,
+	_ protocol.ByteCount,
+	packetNumber protocol.PacketNumber,
+	_ protocol.ByteCount,
+	isRetransmittable bool,
+) {
+	if !isRetransmittable {
+		return
+	}
+	l.largestSentPacketNumber = packetNumber
+}
+
+func (l *ledbatSender) CanSend(bytesInFlight protocol.ByteCount) bool {
+	return bytesInFlight < l.congestionWindow
+}
+
+func (l *ledbatSender) MaybeExitSlowStart() {
+	if l.inSlowStart && l.queuingDelay() > ledbatTarget/2 {
+		l.inSlowStart = false
+	}
+}
+
+func (l *ledbatSender) queuingDelay() time.Duration {
+	if l.rttStats.MinRTT() == 0 {
+		return 0
+	}
+	return l.rttStats.LatestRTT() - l.rttStats.MinRTT()
+}
+
+func (l *ledbatSender) OnPacketAcked(
+	ackedPacketNumber protocol.PacketNumber,
+	ackedBytes protocol.ByteCount,
+	_ protocol.ByteCount,
+	_ time.Time,
+) {
+	l.largestAckedPacketNumber = utils.Max(ackedPacketNumber, l.largestAckedPacketNumber)
+	if l.InRecovery() {
+		return
+	}
+	if l.inSlowStart {
+		l.setCongestionWindow(l.congestionWindow + ackedBytes)
+		return
+	}
+	// cwnd += GAIN * off_target * bytes_newly_acked * MSS / cwnd
+	offTarget := float64(ledbatTarget-l.queuingDelay()) / float64(ledbatTarget)
+	delta := ledbatGain * offTarget * float64(ackedBytes) * float64(l.maxDatagramSize) / float64(l.congestionWindow)
+	if delta < 0 {
+		// Back off at most by one packet per acknowledged packet.
+		delta = utils.Max(delta, -float64(ackedBytes))
+		l.setCongestionWindow(l.congestionWindow - protocol.ByteCount(-delta))
+		return
+	}
+	l.setCongestionWindow(l.congestionWindow + protocol.ByteCount(delta))
+}
+
+func (l *ledbatSender) OnPacketLost(packetNumber protocol.PacketNumber, _, _ protocol.ByteCount) {
+	// Only reduce the congestion window once per RTT.
+	if packetNumber <= l.largestSentAtLastCutback {
+		return
+	}
+	l.inSlowStart = false
+	l.setCongestionWindow(l.congestionWindow / 2)
+	l.largestSentAtLastCutback = l.largestSentPacketNumber
+}
+
+func (l *ledbatSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	l.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	if !packetsRetransmitted {
+		return
+	}
+	l.inSlowStart = false
+	l.congestionWindow = l.minCongestionWindow()
+}
+
+func (l *ledbatSender) setCongestionWindow(cwnd protocol.ByteCount) {
+	l.congestionWindow = utils.Min(
+		utils.Max(cwnd, l.minCongestionWindow()),
+		l.maxDatagramSize*protocol.MaxCongestionWindowPackets,
+	)
+}
+
+func (l *ledbatSender) minCongestionWindow() protocol.ByteCount {
+	return l.maxDatagramSize * minCongestionWindowPackets
+}
+
+func (l *ledbatSender) SetMaxDatagramSize(s protocol.ByteCount) {
+	if s < l.maxDatagramSize {
+		panic("congestion BUG: decreased max datagram size")
+	}
+	cwndIsMinCwnd := l.congestionWindow == l.minCongestionWindow()
+	l.maxDatagramSize = s
+	if cwndIsMinCwnd {
+		l.congestionWindow = l.minCongestionWindow()
+	}
+}
+
+func (l *ledbatSender) InSlowStart() bool { return l.inSlowStart }
+
+func (l *ledbatSender) InRecovery() bool {
+	return l.largestAckedPacketNumber != protocol.InvalidPacketNumber && l.largestAckedPacketNumber <= l.largestSentAtLastCutback
+}
+
+func (l *ledbatSender) GetCongestionWindow() protocol.ByteCount { return l.congestionWindow }
+
+// BandwidthEstimate returns the current bandwidth estimate
+func (l *ledbatSender) BandwidthEstimate() Bandwidth {
+	srtt := l.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		// If we haven't measured an rtt, the bandwidth estimate is unknown.
+		return infBandwidth
+	}
+	return BandwidthFromDelta(l.congestionWindow, srtt)
+}
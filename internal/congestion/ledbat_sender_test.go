@@ -0,0 +1,122 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LEDBAT Sender", func() {
+	const mss = protocol.ByteCount(protocol.InitialPacketSizeIPv4)
+
+	var (
+		sender       *ledbatSender
+		rttStats     *utils.RTTStats
+		packetNumber protocol.PacketNumber
+	)
+
+	BeforeEach(func() {
+		rttStats = utils.NewRTTStats()
+		sender = NewLedbatSender(rttStats, mss)
+		packetNumber = 1
+	})
+
+	sendPackets := func(n int) (first protocol.PacketNumber) {
+		first = packetNumber
+		for i := 0; i < n; i++ {
+			sender.OnPacketSent(time.Now(), 0, packetNumber, mss, true)
+			packetNumber++
+		}
+		return first
+	}
+
+	// exitSlowStart makes the queuing delay exceed half the target
+	exitSlowStart := func() {
+		rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+		rttStats.UpdateRTT(50*time.Millisecond+ledbatTarget, 0, time.Now())
+		sender.MaybeExitSlowStart()
+		Expect(sender.InSlowStart()).To(BeFalse())
+		rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+	}
+
+	It("starts with a small congestion window", func() {
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * mss))
+		Expect(sender.CanSend(mss)).To(BeTrue())
+		Expect(sender.CanSend(2 * mss)).To(BeFalse())
+		Expect(sender.HasPacingBudget()).To(BeTrue())
+		Expect(sender.TimeUntilSend(0)).To(BeZero())
+	})
+
+	It("estimates the bandwidth from the congestion window and the RTT", func() {
+		Expect(sender.BandwidthEstimate()).To(Equal(infBandwidth))
+		rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+		Expect(sender.BandwidthEstimate()).To(Equal(BandwidthFromDelta(2*mss, 50*time.Millisecond)))
+	})
+
+	It("increases the congestion window in slow start", func() {
+		Expect(sender.InSlowStart()).To(BeTrue())
+		first := sendPackets(2)
+		sender.OnPacketAcked(first, mss, 2*mss, time.Now())
+		sender.OnPacketAcked(first+1, mss, 2*mss, time.Now())
+		Expect(sender.GetCongestionWindow()).To(Equal(4 * mss))
+	})
+
+	It("doesn't exit slow start while the queuing delay is low", func() {
+		rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+		rttStats.UpdateRTT(50*time.Millisecond+ledbatTarget/4, 0, time.Now())
+		sender.MaybeExitSlowStart()
+		Expect(sender.InSlowStart()).To(BeTrue())
+	})
+
+	It("increases the congestion window when the queuing delay is below the target", func() {
+		exitSlowStart()
+		sender.setCongestionWindow(10 * mss)
+		first := sendPackets(1)
+		sender.OnPacketAcked(first, mss, 10*mss, time.Now())
+		Expect(sender.GetCongestionWindow()).To(Equal(10*mss + mss/10))
+	})
+
+	It("decreases the congestion window when the queuing delay exceeds the target", func() {
+		exitSlowStart()
+		sender.setCongestionWindow(10 * mss)
+		rttStats.UpdateRTT(50*time.Millisecond+2*ledbatTarget, 0, time.Now())
+		first := sendPackets(1)
+		sender.OnPacketAcked(first, mss, 10*mss, time.Now())
+		// off target is -1
+		Expect(sender.GetCongestionWindow()).To(Equal(10*mss - mss/10))
+	})
+
+	It("halves the congestion window once per RTT when packets are lost", func() {
+		sender.setCongestionWindow(20 * mss)
+		first := sendPackets(10)
+		sender.OnPacketLost(first, mss, 10*mss)
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(10 * mss))
+		sender.OnPacketLost(first+1, mss, 9*mss)
+		Expect(sender.GetCongestionWindow()).To(Equal(10 * mss))
+		sender.OnPacketAcked(first+2, mss, 8*mss, time.Now())
+		Expect(sender.InRecovery()).To(BeTrue())
+		Expect(sender.GetCongestionWindow()).To(Equal(10 * mss))
+		// packets sent after the cutback
+		first = sendPackets(2)
+		sender.OnPacketAcked(first, mss, 2*mss, time.Now())
+		Expect(sender.InRecovery()).To(BeFalse())
+		sender.OnPacketLost(first+1, mss, mss)
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", 6*mss))
+	})
+
+	It("doesn't decrease the congestion window below the minimum", func() {
+		first := sendPackets(1)
+		sender.OnPacketLost(first, mss, mss)
+		Expect(sender.GetCongestionWindow()).To(Equal(minCongestionWindowPackets * mss))
+	})
+
+	It("resets the congestion window on a retransmission timeout", func() {
+		sender.setCongestionWindow(20 * mss)
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(minCongestionWindowPackets * mss))
+	})
+})
@@ -24,6 +24,7 @@ type baseFlowController struct {
 	maxReceiveWindowSize protocol.ByteCount
 
 	allowWindowIncrease func(size protocol.ByteCount) bool
+	windowUpdate        WindowUpdateFunc
 
 	epochStartTime   time.Time
 	epochStartOffset protocol.ByteCount
@@ -73,6 +74,9 @@ func (c *baseFlowController) addBytesRead(n protocol.ByteCount) {
 }
 
 func (c *baseFlowController) hasWindowUpdate() bool {
+	if c.windowUpdate != nil {
+		return c.windowUpdate(c.bytesRead, c.receiveWindow, c.receiveWindowSize) > c.receiveWindow
+	}
 	bytesRemaining := c.receiveWindow - c.bytesRead
 	// update the window when more than the threshold was consumed
 	return bytesRemaining <= protocol.ByteCount(float64(c.receiveWindowSize)*(1-protocol.WindowUpdateThreshold))
@@ -86,7 +90,16 @@ func (c *baseFlowController) getWindowUpdate() protocol.ByteCount {
 	}
 
 	c.maybeAdjustWindowSize()
-	c.receiveWindow = c.bytesRead + c.receiveWindowSize
+	if c.windowUpdate == nil {
+		c.receiveWindow = c.bytesRead + c.receiveWindowSize
+		return c.receiveWindow
+	}
+	// Never allow the peer to send more than the maximum window size beyond what was read.
+	offset := utils.Min(c.windowUpdate(c.bytesRead, c.receiveWindow, c.receiveWindowSize), c.bytesRead+c.maxReceiveWindowSize)
+	if offset <= c.receiveWindow {
+		return 0
+	}
+	c.receiveWindow = offset
 	return c.receiveWindow
 }
 
@@ -126,6 +126,51 @@ var _ = Describe("Base Flow controller", func() {
 			Expect(offset).To(BeZero())
 		})
 
+		Context("using a window update function", func() {
+			// a static window: update after half of the window was consumed
+			staticWindow := func(bytesRead, receiveWindow, windowSize protocol.ByteCount) protocol.ByteCount {
+				if receiveWindow-bytesRead > windowSize/2 {
+					return receiveWindow
+				}
+				return bytesRead + windowSize
+			}
+
+			BeforeEach(func() {
+				controller.maxReceiveWindowSize = 5000
+			})
+
+			It("triggers window updates", func() {
+				controller.windowUpdate = staticWindow
+				controller.bytesRead = receiveWindow - receiveWindowSize/2 - 1
+				Expect(controller.hasWindowUpdate()).To(BeFalse())
+				Expect(controller.getWindowUpdate()).To(BeZero())
+				controller.bytesRead = receiveWindow - receiveWindowSize/2
+				Expect(controller.hasWindowUpdate()).To(BeTrue())
+				offset := controller.getWindowUpdate()
+				Expect(offset).To(Equal(controller.bytesRead + receiveWindowSize))
+				Expect(controller.receiveWindow).To(Equal(offset))
+			})
+
+			It("passes the window state", func() {
+				var bytesRead, window, windowSize protocol.ByteCount
+				controller.windowUpdate = func(r, w, s protocol.ByteCount) protocol.ByteCount {
+					bytesRead, window, windowSize = r, w, s
+					return 0
+				}
+				controller.bytesRead = receiveWindow - 100
+				Expect(controller.getWindowUpdate()).To(BeZero())
+				Expect(bytesRead).To(Equal(receiveWindow - 100))
+				Expect(window).To(Equal(receiveWindow))
+				Expect(windowSize).To(Equal(receiveWindowSize))
+				Expect(controller.receiveWindow).To(Equal(receiveWindow))
+			})
+
+			It("doesn't allow the window to grow larger than the maximum window size", func() {
+				controller.windowUpdate = func(bytesRead, _, _ protocol.ByteCount) protocol.ByteCount { return bytesRead + 1e6 }
+				Expect(controller.getWindowUpdate()).To(Equal(controller.bytesRead + 5000))
+			})
+		})
+
 		Context("receive window size auto-tuning", func() {
 			var oldWindowSize protocol.ByteCount
 
@@ -25,6 +25,7 @@ func NewConnectionFlowController(
 	maxReceiveWindow protocol.ByteCount,
 	queueWindowUpdate func(),
 	allowWindowIncrease func(size protocol.ByteCount) bool,
+	windowUpdate WindowUpdateFunc,
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 ) ConnectionFlowController {
@@ -35,6 +36,7 @@ func NewConnectionFlowController(
 			receiveWindowSize:    receiveWindow,
 			maxReceiveWindowSize: maxReceiveWindow,
 			allowWindowIncrease:  allowWindowIncrease,
+			windowUpdate:         windowUpdate,
 			logger:               logger,
 		},
 		queueWindowUpdate: queueWindowUpdate,
@@ -110,3 +112,28 @@ func (c *connectionFlowController) Reset() error {
 	c.lastBlockedAt = 0
 	return nil
 }
+
+func (c *connectionFlowController) HandoffState() HandoffState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return HandoffState{
+		BytesSent:     c.bytesSent,
+		SendWindow:    c.sendWindow,
+		BytesReceived: c.highestReceived,
+		ReceiveWindow: c.receiveWindow,
+	}
+}
+
+// Resume restores the state of a connection that was handed off by another process.
+// The streams aren't handed off, so all data received so far is considered read.
+func (c *connectionFlowController) Resume(state HandoffState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.bytesSent = state.BytesSent
+	c.sendWindow = state.SendWindow
+	c.highestReceived = state.BytesReceived
+	c.bytesRead = state.BytesReceived
+	c.receiveWindow = state.ReceiveWindow
+}
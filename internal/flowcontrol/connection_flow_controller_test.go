@@ -42,6 +42,7 @@ var _ = Describe("Connection Flow controller", func() {
 				maxReceiveWindow,
 				nil,
 				func(protocol.ByteCount) bool { return true },
+				nil,
 				rttStats,
 				utils.DefaultLogger).(*connectionFlowController)
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
@@ -182,4 +183,24 @@ var _ = Describe("Connection Flow controller", func() {
 			Expect(blockedAt).To(Equal(initialWindow))
 		})
 	})
+
+	Context("handing off", func() {
+		It("resumes the state", func() {
+			controller.UpdateSendWindow(10000)
+			controller.AddBytesSent(1000)
+			controller.receiveWindow = 5000
+			controller.IncrementHighestReceived(3000)
+			state := controller.HandoffState()
+			Expect(state).To(Equal(HandoffState{BytesSent: 1000, SendWindow: 10000, BytesReceived: 3000, ReceiveWindow: 5000}))
+
+			resumed := &connectionFlowController{}
+			resumed.Resume(state)
+			Expect(resumed.SendWindowSize()).To(Equal(protocol.ByteCount(9000)))
+			Expect(resumed.highestReceived).To(Equal(protocol.ByteCount(3000)))
+			// all data received by the other process is considered read
+			Expect(resumed.bytesRead).To(Equal(protocol.ByteCount(3000)))
+			Expect(resumed.IncrementHighestReceived(2000)).To(Succeed())
+			Expect(resumed.IncrementHighestReceived(1)).ToNot(Succeed())
+		})
+	})
 })
@@ -2,6 +2,12 @@ package flowcontrol
 
 import "github.com/lucas-clemente/quic-go/internal/protocol"
 
+// A WindowUpdateFunc decides when the receive window is increased, i.e. when a MAX_DATA or MAX_STREAM_DATA frame is sent.
+// It is passed the number of bytes read, the receive window (the highest offset advertised to the peer),
+// and the window size, which is increased by auto-tuning.
+// It returns the new receive window. If that's not larger than the current receive window, no update is sent.
+type WindowUpdateFunc func(bytesRead, receiveWindow, windowSize protocol.ByteCount) protocol.ByteCount
+
 type flowController interface {
 	// for sending
 	SendWindowSize() protocol.ByteCount
@@ -21,6 +27,10 @@ type StreamFlowController interface {
 	// final has to be to true if this is the final offset of the stream,
 	// as contained in a STREAM frame with FIN bit, and the RESET_STREAM frame
 	UpdateHighestReceived(offset protocol.ByteCount, final bool) error
+	// AddBytesSkipped credits the connection for bytes that the peer skipped (with a PRAckNotify frame) before they are read.
+	// They never arrive as data, so they shouldn't block the other streams of the connection.
+	// They still count towards the stream's window until they are read with AddBytesRead.
+	AddBytesSkipped(protocol.ByteCount)
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
@@ -30,6 +40,17 @@ type StreamFlowController interface {
 type ConnectionFlowController interface {
 	flowController
 	Reset() error
+	// HandoffState and Resume are used to hand off the connection to another process.
+	HandoffState() HandoffState
+	Resume(HandoffState)
+}
+
+// HandoffState is the state of the connection flow controller of a connection that is handed off to another process.
+type HandoffState struct {
+	BytesSent     protocol.ByteCount
+	SendWindow    protocol.ByteCount
+	BytesReceived protocol.ByteCount // the highest offset received, summed over all streams
+	ReceiveWindow protocol.ByteCount
 }
 
 type connectionFlowControllerI interface {
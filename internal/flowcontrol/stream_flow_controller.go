@@ -18,6 +18,8 @@ type streamFlowController struct {
 	connection connectionFlowControllerI
 
 	receivedFinalOffset bool
+	// the skipped bytes credited to the connection that weren't read yet, see AddBytesSkipped
+	skippedCredit protocol.ByteCount
 }
 
 var _ StreamFlowController = &streamFlowController{}
@@ -30,6 +32,7 @@ func NewStreamFlowController(
 	maxReceiveWindow protocol.ByteCount,
 	initialSendWindow protocol.ByteCount,
 	queueWindowUpdate func(protocol.StreamID),
+	windowUpdate WindowUpdateFunc,
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 ) StreamFlowController {
@@ -43,6 +46,7 @@ func NewStreamFlowController(
 			receiveWindowSize:    receiveWindow,
 			maxReceiveWindowSize: maxReceiveWindow,
 			sendWindow:           initialSendWindow,
+			windowUpdate:         windowUpdate,
 			logger:               logger,
 		},
 	}
@@ -101,16 +105,30 @@ func (c *streamFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.mutex.Lock()
 	c.baseFlowController.addBytesRead(n)
 	shouldQueueWindowUpdate := c.shouldQueueWindowUpdate()
+	// The connection was credited for the skipped bytes already.
+	// It doesn't matter which bytes are read first, the connection is credited for every byte exactly once.
+	credited := utils.Min(n, c.skippedCredit)
+	c.skippedCredit -= credited
 	c.mutex.Unlock()
 	if shouldQueueWindowUpdate {
 		c.queueWindowUpdate()
 	}
+	if n > credited {
+		c.connection.AddBytesRead(n - credited)
+	}
+}
+
+func (c *streamFlowController) AddBytesSkipped(n protocol.ByteCount) {
+	c.mutex.Lock()
+	c.skippedCredit += n
+	c.mutex.Unlock()
 	c.connection.AddBytesRead(n)
 }
 
 func (c *streamFlowController) Abandon() {
 	c.mutex.Lock()
-	unread := c.highestReceived - c.bytesRead
+	unread := c.highestReceived - c.bytesRead - c.skippedCredit
+	c.skippedCredit = 0
 	c.mutex.Unlock()
 	if unread > 0 {
 		c.connection.AddBytesRead(unread)
@@ -27,6 +27,7 @@ var _ = Describe("Stream Flow controller", func() {
 				1000,
 				func() {},
 				func(protocol.ByteCount) bool { return true },
+				nil,
 				rttStats,
 				utils.DefaultLogger,
 			).(*connectionFlowController),
@@ -44,8 +45,8 @@ var _ = Describe("Stream Flow controller", func() {
 		const sendWindow protocol.ByteCount = 4000
 
 		It("sets the send and receive windows", func() {
-			cc := NewConnectionFlowController(0, 0, nil, func(protocol.ByteCount) bool { return true }, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, nil, func(protocol.ByteCount) bool { return true }, nil, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(5)))
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
@@ -59,8 +60,8 @@ var _ = Describe("Stream Flow controller", func() {
 				queued = true
 			}
 
-			cc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, func() {}, func(protocol.ByteCount) bool { return true }, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, func() {}, func(protocol.ByteCount) bool { return true }, nil, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
 			fc.AddBytesRead(receiveWindow)
 			Expect(queued).To(BeTrue())
 		})
@@ -167,6 +168,47 @@ var _ = Describe("Stream Flow controller", func() {
 			Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(200)))
 		})
 
+		Context("skipped data", func() {
+			BeforeEach(func() {
+				controller.receiveWindow = 10000
+				controller.receiveWindowSize = 600
+			})
+
+			It("credits the connection for skipped bytes right away, and only once", func() {
+				Expect(controller.UpdateHighestReceived(100, false)).To(Succeed())
+				controller.AddBytesSkipped(60)
+				Expect(controller.bytesRead).To(BeZero())
+				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(60)))
+				controller.AddBytesRead(40)
+				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(60)))
+				controller.AddBytesRead(60)
+				Expect(controller.bytesRead).To(Equal(protocol.ByteCount(100)))
+				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(100)))
+			})
+
+			It("doesn't credit the skipped bytes twice when a stream is abandoned", func() {
+				Expect(controller.UpdateHighestReceived(100, true)).To(Succeed())
+				controller.AddBytesSkipped(60)
+				controller.AddBytesRead(10)
+				controller.Abandon()
+				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(100)))
+			})
+
+			It("doesn't block the connection when the skipped data of a stream isn't read", func() {
+				var queued bool
+				cc := NewConnectionFlowController(100, 100, func() { queued = true }, func(protocol.ByteCount) bool { return true }, nil, &utils.RTTStats{}, utils.DefaultLogger)
+				fc := NewStreamFlowController(5, cc, 1000, 1000, 0, func(protocol.StreamID) {}, nil, &utils.RTTStats{}, utils.DefaultLogger)
+				// the peer used up the whole connection window, and then skipped all the data
+				Expect(fc.UpdateHighestReceived(100, false)).To(Succeed())
+				fc.AddBytesSkipped(100)
+				Expect(queued).To(BeTrue())
+				Expect(cc.GetWindowUpdate()).To(BeNumerically(">", 100))
+				// another stream can use the window
+				fc2 := NewStreamFlowController(9, cc, 1000, 1000, 0, func(protocol.StreamID) {}, nil, &utils.RTTStats{}, utils.DefaultLogger)
+				Expect(fc2.UpdateHighestReceived(100, false)).To(Succeed())
+			})
+		})
+
 		Context("generating window updates", func() {
 			var oldWindowSize protocol.ByteCount
 
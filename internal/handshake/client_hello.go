@@ -0,0 +1,48 @@
+package handshake
+
+import "golang.org/x/crypto/cryptobyte"
+
+const extensionServerName uint16 = 0
+
+// serverNameFromClientHello parses the server_name extension (RFC 6066, section 3) of a ClientHello message.
+// It returns an empty string if the ClientHello doesn't contain a host name, or if it can't be parsed.
+func serverNameFromClientHello(msg []byte) string {
+	s := cryptobyte.String(msg)
+	var msgType uint8
+	var body, sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !s.ReadUint8(&msgType) || messageType(msgType) != typeClientHello ||
+		!s.ReadUint24LengthPrefixed(&body) ||
+		!body.Skip(2+32) || // legacy_version, random
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!body.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!body.ReadUint16LengthPrefixed(&extensions) {
+		return ""
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return ""
+		}
+		if extType != extensionServerName {
+			continue
+		}
+		var nameList cryptobyte.String
+		if !extData.ReadUint16LengthPrefixed(&nameList) {
+			return ""
+		}
+		for !nameList.Empty() {
+			var nameType uint8
+			var name cryptobyte.String
+			if !nameList.ReadUint8(&nameType) || !nameList.ReadUint16LengthPrefixed(&name) {
+				return ""
+			}
+			if nameType == 0 { // host_name
+				return string(name)
+			}
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientHello parsing", func() {
+	// getClientHello returns the ClientHello message sent by a crypto/tls client.
+	getClientHello := func(serverName string) []byte {
+		c, s := net.Pipe()
+		go tls.Client(c, &tls.Config{ServerName: serverName, InsecureSkipVerify: true}).Handshake()
+		defer c.Close()
+		defer s.Close()
+		hdr := make([]byte, 5) // the TLS record header
+		_, err := s.Read(hdr)
+		Expect(err).ToNot(HaveOccurred())
+		msg := make([]byte, int(hdr[3])<<8|int(hdr[4]))
+		_, err = s.Read(msg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(messageType(msg[0])).To(Equal(typeClientHello))
+		return msg
+	}
+
+	It("parses the server name", func() {
+		Expect(serverNameFromClientHello(getClientHello("quic.clemente.io"))).To(Equal("quic.clemente.io"))
+	})
+
+	It("returns an empty server name, if the ClientHello doesn't contain one", func() {
+		// IP addresses are not sent in the server_name extension
+		Expect(serverNameFromClientHello(getClientHello("127.0.0.1"))).To(BeEmpty())
+	})
+
+	It("returns an empty server name for invalid ClientHellos", func() {
+		msg := getClientHello("quic.clemente.io")
+		for i := range msg {
+			Expect(serverNameFromClientHello(msg[:i])).To(BeEmpty())
+		}
+		msg[0] = byte(typeServerHello)
+		Expect(serverNameFromClientHello(msg)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,11 @@
+//go:build !quic_stdtls
+
+package handshake
+
+import "github.com/lucas-clemente/quic-go/internal/qerr"
+
+// qtls sends an unexpected_message alert when it receives a malformed handshake message.
+var malformedMessageError = &qerr.TransportError{
+	ErrorCode:    0x100 + qerr.TransportErrorCode(alertUnexpectedMessage),
+	ErrorMessage: "local error: tls: unexpected message",
+}
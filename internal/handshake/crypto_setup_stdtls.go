@@ -0,0 +1,446 @@
+//go:build go1.21 && quic_stdtls
+
+package handshake
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qtls"
+)
+
+// TLS internal_error alert
+const alertInternalError uint8 = 80
+
+// The tlsBackend of the crypto/tls backend.
+// The tls.QUICConn doesn't run in a separate go routine. It is driven by RunHandshake, HandleMessage and GetSessionTicket,
+// which then handle the events emitted by the tls.QUICConn.
+// crypto/tls only implements the QUIC v1 codepoint of the transport parameters extension,
+// so this backend can't be used with draft versions of QUIC.
+type tlsBackend struct {
+	// protects conn, since RunHandshake and HandleMessage are called from different go routines
+	tlsMutex sync.Mutex
+	conn     *tls.QUICConn
+	started  bool
+	closed   bool
+
+	allow0RTT bool
+	used0RTT  bool // protected by mutex
+
+	// handshakeComplete is closed as soon as the tls.QUICConn completes the handshake
+	handshakeComplete chan struct{}
+	// handshakeFailed is closed when the tls.QUICConn returns an error
+	handshakeFailed     chan struct{}
+	handshakeFailedOnce sync.Once
+}
+
+func (h *cryptoSetup) initTLS(_, _ net.Addr, enable0RTT bool) {
+	h.allow0RTT = enable0RTT
+	h.handshakeComplete = make(chan struct{})
+	h.handshakeFailed = make(chan struct{})
+
+	if h.tlsConf == nil {
+		h.tlsConf = &tls.Config{}
+	}
+	// The tls.Config is modified below, and the callbacks are specific to this connection.
+	tlsConf := h.tlsConf.Clone()
+	tlsConf.MinVersion = tls.VersionTLS13
+	if h.perspective == protocol.PerspectiveClient {
+		if tlsConf.ClientSessionCache != nil {
+			tlsConf.ClientSessionCache = &clientSessionCache{
+				ClientSessionCache: tlsConf.ClientSessionCache,
+				getData:            h.marshalDataForSessionState,
+				setData:            h.setDataFromSessionState,
+			}
+		}
+		h.tlsConf = tlsConf
+		h.conn = tls.QUICClient(&tls.QUICConfig{TLSConfig: tlsConf})
+		return
+	}
+	// The session ticket keys are generated lazily, and they're not shared with clones of the tls.Config.
+	// Use the original tls.Config to encrypt and decrypt tickets, so that they can be used across connections.
+	origConf := h.tlsConf
+	origWrapSession := tlsConf.WrapSession
+	tlsConf.WrapSession = func(cs tls.ConnectionState, state *tls.SessionState) ([]byte, error) {
+		// Save transport parameters to the session ticket if we're allowing 0-RTT.
+		if state.EarlyData {
+			state.Extra = append(state.Extra, addSessionStateExtraPrefix((&sessionTicket{
+				Parameters: h.ourParams,
+				RTT:        h.rttStats.SmoothedRTT(),
+				IssuedAt:   time.Now(),
+			}).Marshal()))
+		}
+		if origWrapSession != nil {
+			return origWrapSession(cs, state)
+		}
+		return origConf.EncryptTicket(cs, state)
+	}
+	origUnwrapSession := tlsConf.UnwrapSession
+	tlsConf.UnwrapSession = func(identity []byte, cs tls.ConnectionState) (*tls.SessionState, error) {
+		var state *tls.SessionState
+		var err error
+		if origUnwrapSession != nil {
+			state, err = origUnwrapSession(identity, cs)
+		} else {
+			state, err = origConf.DecryptTicket(identity, cs)
+		}
+		if err != nil || state == nil {
+			return nil, err
+		}
+		if state.EarlyData {
+			extra := findSessionStateExtraData(state.Extra)
+			state.EarlyData = h.allow0RTT && extra != nil && h.accept0RTT(extra)
+		}
+		return state, nil
+	}
+	h.tlsConf = tlsConf
+	h.conn = tls.QUICServer(&tls.QUICConfig{TLSConfig: tlsConf})
+}
+
+func (h *cryptoSetup) RunHandshake() {
+	h.tlsMutex.Lock()
+	if h.closed {
+		h.tlsMutex.Unlock()
+		return
+	}
+	err := h.start()
+	h.tlsMutex.Unlock()
+	if err != nil {
+		h.onTLSError(err)
+		return
+	}
+
+	if h.perspective == protocol.PerspectiveClient {
+		h.mutex.Lock()
+		h.clientHelloWritten = true
+		doing0RTT := h.zeroRTTSealer != nil && h.zeroRTTParameters != nil
+		h.mutex.Unlock()
+		close(h.clientHelloWrittenChan)
+		if doing0RTT {
+			h.logger.Debugf("Doing 0-RTT.")
+			h.zeroRTTParametersChan <- h.zeroRTTParameters
+		} else {
+			h.logger.Debugf("Not doing 0-RTT.")
+			h.zeroRTTParametersChan <- nil
+		}
+	}
+
+	select {
+	case <-h.handshakeComplete: // return when the handshake is done
+		h.mutex.Lock()
+		h.handshakeCompleteTime = time.Now()
+		h.mutex.Unlock()
+		h.runner.OnHandshakeComplete()
+	case <-h.handshakeFailed: // the error was already reported by onTLSError
+	case <-h.closeChan:
+	}
+}
+
+// Close closes the crypto setup.
+// It aborts the handshake, if it is still running.
+// It must only be called once.
+func (h *cryptoSetup) Close() error {
+	close(h.closeChan)
+	h.tlsMutex.Lock()
+	defer h.tlsMutex.Unlock()
+	h.closed = true
+	// crypto/tls returns an error when the handshake is aborted before it completed.
+	// This is expected when closing the connection, so there's no need to report it.
+	h.conn.Close()
+	return nil
+}
+
+// handleMessage handles a TLS handshake message.
+// It is called by the crypto streams when a new message is available.
+// It returns if it is done with messages on the same encryption level.
+func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLevel) bool /* stream finished */ {
+	msgType := messageType(data[0])
+	h.logger.Debugf("Received %s message (%d bytes, encryption level: %s)", msgType, len(data), encLevel)
+	if err := h.checkEncryptionLevel(msgType, encLevel); err != nil {
+		h.onError(alertUnexpectedMessage, err.Error())
+		return false
+	}
+	h.saveClientHello(msgType, data)
+	h.tlsMutex.Lock()
+	if h.closed {
+		h.tlsMutex.Unlock()
+		return false
+	}
+	// The server might receive the ClientHello before RunHandshake was called.
+	err := h.start()
+	if err == nil {
+		err = h.conn.HandleData(toTLSEncryptionLevel(encLevel), data)
+	}
+	if err == nil {
+		err = h.handleEvents()
+	}
+	h.tlsMutex.Unlock()
+	if err != nil {
+		h.onTLSError(err)
+		return false
+	}
+	if encLevel == protocol.Encryption1RTT {
+		return false
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	// We're done with the Initial encryption level after processing a ClientHello / ServerHello,
+	// but only if a handshake opener and sealer was created.
+	// Otherwise, a HelloRetryRequest was performed.
+	// We're done with the Handshake encryption level after processing the Finished message.
+	return ((msgType == typeClientHello || msgType == typeServerHello) && h.handshakeOpener != nil && h.handshakeSealer != nil) ||
+		msgType == typeFinished
+}
+
+// start starts the handshake, unless it was already started.
+// For the client, this writes the ClientHello.
+// It must be called with the tlsMutex held.
+func (h *cryptoSetup) start() error {
+	if h.started {
+		return nil
+	}
+	h.started = true
+	if h.perspective == protocol.PerspectiveClient {
+		h.conn.SetTransportParameters(h.ourParams.Marshal(h.perspective))
+	}
+	if err := h.conn.Start(context.Background()); err != nil {
+		return err
+	}
+	return h.handleEvents()
+}
+
+// handleEvents handles all events emitted by the tls.QUICConn.
+// It must be called with the tlsMutex held.
+func (h *cryptoSetup) handleEvents() error {
+	for {
+		ev := h.conn.NextEvent()
+		switch ev.Kind {
+		case tls.QUICNoEvent:
+			return nil
+		case tls.QUICSetReadSecret:
+			h.SetReadKey(ev.Level, qtls.CipherSuiteTLS13ByID(ev.Suite), ev.Data)
+			if ev.Level == tls.QUICEncryptionLevelEarly {
+				h.set0RTTUsed(true)
+			}
+		case tls.QUICSetWriteSecret:
+			h.SetWriteKey(ev.Level, qtls.CipherSuiteTLS13ByID(ev.Suite), ev.Data)
+			if ev.Level == tls.QUICEncryptionLevelEarly {
+				h.set0RTTUsed(true)
+			}
+		case tls.QUICTransportParameters:
+			h.handleTransportParameters(ev.Data)
+		case tls.QUICTransportParametersRequired:
+			h.conn.SetTransportParameters(h.ourParams.Marshal(h.perspective))
+		case tls.QUICRejectedEarlyData:
+			h.set0RTTUsed(false)
+			h.rejected0RTT()
+		case tls.QUICWriteData:
+			if err := h.writeHandshakeData(ev.Level, ev.Data); err != nil {
+				return err
+			}
+		case tls.QUICHandshakeDone:
+			close(h.handshakeComplete)
+		default:
+			// Events added to crypto/tls in later Go versions are only emitted if they are enabled in the tls.QUICConfig.
+			return fmt.Errorf("unexpected event: %d", ev.Kind)
+		}
+	}
+}
+
+func (h *cryptoSetup) set0RTTUsed(used bool) {
+	h.mutex.Lock()
+	h.used0RTT = used
+	h.mutex.Unlock()
+}
+
+// writeHandshakeData writes the handshake messages to the crypto stream of their encryption level.
+// crypto/tls coalesces consecutive messages of the same encryption level into a single event.
+// Like qtls, the messages are written one by one, such that every write contains exactly one message.
+func (h *cryptoSetup) writeHandshakeData(level tls.QUICEncryptionLevel, data []byte) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var str io.Writer
+	//nolint:exhaustive // handshake data can only be written for Initial and Handshake.
+	switch level {
+	case tls.QUICEncryptionLevelInitial:
+		str = h.initialStream
+	case tls.QUICEncryptionLevelHandshake:
+		str = h.handshakeStream
+	default:
+		return fmt.Errorf("unexpected write encryption level: %s", level)
+	}
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return errors.New("incomplete handshake message header")
+		}
+		msgLen := 4 + (int(data[1])<<16 | int(data[2])<<8 | int(data[3]))
+		if len(data) < msgLen {
+			return errors.New("incomplete handshake message")
+		}
+		if _, err := str.Write(data[:msgLen]); err != nil {
+			return err
+		}
+		data = data[msgLen:]
+	}
+	return nil
+}
+
+// onTLSError reports an error returned by the tls.QUICConn, and aborts RunHandshake.
+// If the error was caused by a TLS alert, it is sent in a CRYPTO_ERROR.
+// crypto/tls reports local errors as an internal_error alert, these are sent as an INTERNAL_ERROR instead.
+func (h *cryptoSetup) onTLSError(err error) {
+	defer h.handshakeFailedOnce.Do(func() { close(h.handshakeFailed) })
+	select {
+	case <-h.closeChan:
+		// no need to report an error when we've already closed
+		return
+	default:
+	}
+	var alertErr tls.AlertError
+	if errors.As(err, &alertErr) && alertErr != tls.AlertError(alertInternalError) {
+		h.onError(uint8(alertErr), err.Error())
+		return
+	}
+	h.onError(0, err.Error())
+}
+
+// only valid for the server
+func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
+	h.tlsMutex.Lock()
+	defer h.tlsMutex.Unlock()
+
+	if err := qtls.SendSessionTicket(h.conn, h.allow0RTT); err != nil {
+		return nil, err
+	}
+	var ticket []byte
+	for {
+		ev := h.conn.NextEvent()
+		switch ev.Kind {
+		case tls.QUICNoEvent:
+			// No ticket is written if session tickets are disabled.
+			return ticket, nil
+		case tls.QUICWriteData:
+			if ev.Level != tls.QUICEncryptionLevelApplication {
+				return nil, fmt.Errorf("unexpected encryption level for the session ticket: %s", ev.Level)
+			}
+			ticket = append(ticket, ev.Data...)
+		default:
+			return nil, fmt.Errorf("unexpected event while sending a session ticket: %d", ev.Kind)
+		}
+	}
+}
+
+// setDataFromSessionState restores the data saved by marshalDataForSessionState.
+// It returns false if 0-RTT can't be used with this session state.
+func (h *cryptoSetup) setDataFromSessionState(data []byte) bool /* allow 0-RTT */ {
+	if data == nil {
+		return false
+	}
+	h.handleDataFromSessionState(data)
+	return h.allow0RTT && h.zeroRTTParameters != nil
+}
+
+func (h *cryptoSetup) ConnectionState() ConnectionState {
+	h.tlsMutex.Lock()
+	cs := h.conn.ConnectionState()
+	h.tlsMutex.Unlock()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return ConnectionState{ConnectionState: cs, Used0RTT: h.used0RTT}
+}
+
+func toTLSEncryptionLevel(encLevel protocol.EncryptionLevel) tls.QUICEncryptionLevel {
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		return tls.QUICEncryptionLevelInitial
+	case protocol.EncryptionHandshake:
+		return tls.QUICEncryptionLevelHandshake
+	case protocol.Encryption1RTT:
+		return tls.QUICEncryptionLevelApplication
+	default:
+		panic(fmt.Sprintf("unexpected encryption level: %s", encLevel))
+	}
+}
+
+// The data saved in the session state is stored in tls.SessionState.Extra.
+// Other users of the tls.Config might add their own data, so our entry is marked by a prefix.
+const sessionStateExtraPrefix = "quic-go1"
+
+func addSessionStateExtraPrefix(b []byte) []byte {
+	return append([]byte(sessionStateExtraPrefix), b...)
+}
+
+func findSessionStateExtraData(extras [][]byte) []byte {
+	prefix := []byte(sessionStateExtraPrefix)
+	for _, extra := range extras {
+		if bytes.HasPrefix(extra, prefix) {
+			return extra[len(prefix):]
+		}
+	}
+	return nil
+}
+
+// clientSessionCache wraps the application's tls.ClientSessionCache,
+// and stores the data needed for 0-RTT along with the session state.
+type clientSessionCache struct {
+	tls.ClientSessionCache
+	getData func() []byte
+	setData func([]byte) bool /* allow 0-RTT */
+}
+
+var _ tls.ClientSessionCache = &clientSessionCache{}
+
+func (c *clientSessionCache) Put(key string, cs *tls.ClientSessionState) {
+	if cs == nil {
+		c.ClientSessionCache.Put(key, nil)
+		return
+	}
+	ticket, state, err := cs.ResumptionState()
+	if err != nil || state == nil {
+		c.ClientSessionCache.Put(key, cs)
+		return
+	}
+	state.Extra = append(state.Extra, addSessionStateExtraPrefix(c.getData()))
+	newCS, err := tls.NewResumptionState(ticket, state)
+	if err != nil {
+		// It's not clear why this would error. Just save the original state.
+		c.ClientSessionCache.Put(key, cs)
+		return
+	}
+	c.ClientSessionCache.Put(key, newCS)
+}
+
+func (c *clientSessionCache) Get(key string) (*tls.ClientSessionState, bool) {
+	cs, ok := c.ClientSessionCache.Get(key)
+	if !ok || cs == nil {
+		return cs, ok
+	}
+	ticket, state, err := cs.ResumptionState()
+	if err != nil || state == nil {
+		// It's not clear why this would error.
+		// Remove the ticket from the session cache, so we don't run into this error over and over again
+		c.ClientSessionCache.Put(key, nil)
+		return nil, false
+	}
+	state.EarlyData = c.setData(findSessionStateExtraData(state.Extra)) && state.EarlyData
+	if state.EarlyData {
+		// Tickets used for 0-RTT can be replayed by an attacker, so they must only be used once.
+		c.ClientSessionCache.Put(key, nil)
+	}
+	newCS, err := tls.NewResumptionState(ticket, state)
+	if err != nil {
+		// It's not clear why this would error.
+		c.ClientSessionCache.Put(key, nil)
+		return nil, false
+	}
+	return newCS, true
+}
@@ -0,0 +1,32 @@
+//go:build go1.21 && quic_stdtls
+
+package handshake
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// crypto/tls sends a decode_error alert when it receives a malformed handshake message.
+var malformedMessageError = &qerr.TransportError{
+	ErrorCode:    0x100 + 50,
+	ErrorMessage: "local error: tls: error decoding message",
+}
+
+var _ = Describe("Session State Extra Data", func() {
+	It("finds the data saved by quic-go", func() {
+		extras := [][]byte{
+			[]byte("foo"),
+			addSessionStateExtraPrefix([]byte("bar")),
+			[]byte("baz"),
+		}
+		Expect(findSessionStateExtraData(extras)).To(Equal([]byte("bar")))
+	})
+
+	It("returns nil if there's no data saved by quic-go", func() {
+		Expect(findSessionStateExtraData([][]byte{[]byte("foo")})).To(BeNil())
+		Expect(findSessionStateExtraData(nil)).To(BeNil())
+	})
+})
@@ -74,7 +74,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 		serverConf = testdata.GetTLSConfig()
 		serverConf.NextProtos = []string{"crypto-setup"}
 		clientConf = &tls.Config{
-			ServerName: "localhost",
+			// the name the certificate in the testdata package is issued for
+			ServerName: "3unique.top",
 			RootCAs:    testdata.GetRootCA(),
 			NextProtos: []string{"crypto-setup"},
 		}
@@ -96,6 +97,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -106,10 +108,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 		go func() {
 			defer GinkgoRecover()
 			server.RunHandshake()
-			Expect(sErrChan).To(Receive(MatchError(&qerr.TransportError{
-				ErrorCode:    0x100 + qerr.TransportErrorCode(alertUnexpectedMessage),
-				ErrorMessage: "local error: tls: unexpected message",
-			})))
+			Expect(sErrChan).To(Receive(MatchError(malformedMessageError)))
 			close(done)
 		}()
 
@@ -178,6 +177,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -219,6 +219,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -232,7 +233,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			var err error
 			Expect(sErrChan).To(Receive(&err))
 			Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
-			Expect(err.(*qerr.TransportError).ErrorCode).To(BeEquivalentTo(0x100 + int(alertUnexpectedMessage)))
+			Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(malformedMessageError.ErrorCode))
 			close(done)
 		}()
 
@@ -254,6 +255,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -399,6 +401,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				enable0RTT,
+				nil,
 				serverRTTStats,
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -537,6 +540,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				false,
+				nil,
 				&utils.RTTStats{},
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -592,6 +596,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
@@ -651,6 +656,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
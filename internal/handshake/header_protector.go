@@ -16,8 +16,14 @@ import (
 type headerProtector interface {
 	EncryptHeader(sample []byte, firstByte *byte, hdrBytes []byte)
 	DecryptHeader(sample []byte, firstByte *byte, hdrBytes []byte)
+	// EncryptHeaders applies the header protection of multiple packets.
+	// It doesn't use any state that is modified by the other methods, so it may be called concurrently.
+	EncryptHeaders([]HeaderProtection)
 }
 
+// headerProtectionBatchSize is the number of masks computed at once by EncryptHeaders.
+const headerProtectionBatchSize = 8
+
 func hkdfHeaderProtectionLabel(v protocol.VersionNumber) string {
 	if v == protocol.Version2 {
 		return "quicv2 hp"
@@ -71,13 +77,27 @@ func (p *aesHeaderProtector) apply(sample []byte, firstByte *byte, hdrBytes []by
 		panic("invalid sample size")
 	}
 	p.block.Encrypt(p.mask, sample)
-	if p.isLongHeader {
-		*firstByte ^= p.mask[0] & 0xf
-	} else {
-		*firstByte ^= p.mask[0] & 0x1f
-	}
-	for i := range hdrBytes {
-		hdrBytes[i] ^= p.mask[i+1]
+	applyHeaderProtectionMask(p.mask, p.isLongHeader, firstByte, hdrBytes)
+}
+
+// EncryptHeaders first computes the masks of up to headerProtectionBatchSize packets, and then applies them.
+func (p *aesHeaderProtector) EncryptHeaders(hps []HeaderProtection) {
+	var masks [headerProtectionBatchSize * aes.BlockSize]byte
+	for len(hps) > 0 {
+		batch := hps
+		if len(batch) > headerProtectionBatchSize {
+			batch = batch[:headerProtectionBatchSize]
+		}
+		hps = hps[len(batch):]
+		for i, hp := range batch {
+			if len(hp.Sample) != aes.BlockSize {
+				panic("invalid sample size")
+			}
+			p.block.Encrypt(masks[i*aes.BlockSize:], hp.Sample)
+		}
+		for i, hp := range batch {
+			applyHeaderProtectionMask(masks[i*aes.BlockSize:], p.isLongHeader, hp.FirstByte, hp.PNBytes)
+		}
 	}
 }
 
@@ -109,28 +129,40 @@ func (p *chachaHeaderProtector) EncryptHeader(sample []byte, firstByte *byte, hd
 }
 
 func (p *chachaHeaderProtector) apply(sample []byte, firstByte *byte, hdrBytes []byte) {
+	p.computeMask(&p.mask, sample)
+	applyHeaderProtectionMask(p.mask[:], p.isLongHeader, firstByte, hdrBytes)
+}
+
+func (p *chachaHeaderProtector) EncryptHeaders(hps []HeaderProtection) {
+	var mask [5]byte
+	for _, hp := range hps {
+		p.computeMask(&mask, hp.Sample)
+		applyHeaderProtectionMask(mask[:], p.isLongHeader, hp.FirstByte, hp.PNBytes)
+	}
+}
+
+func (p *chachaHeaderProtector) computeMask(mask *[5]byte, sample []byte) {
 	if len(sample) != 16 {
 		panic("invalid sample size")
 	}
 	for i := 0; i < 5; i++ {
-		p.mask[i] = 0
+		mask[i] = 0
 	}
 	cipher, err := chacha20.NewUnauthenticatedCipher(p.key[:], sample[4:])
 	if err != nil {
 		panic(err)
 	}
 	cipher.SetCounter(binary.LittleEndian.Uint32(sample[:4]))
-	cipher.XORKeyStream(p.mask[:], p.mask[:])
-	p.applyMask(firstByte, hdrBytes)
+	cipher.XORKeyStream(mask[:], mask[:])
 }
 
-func (p *chachaHeaderProtector) applyMask(firstByte *byte, hdrBytes []byte) {
-	if p.isLongHeader {
-		*firstByte ^= p.mask[0] & 0xf
+func applyHeaderProtectionMask(mask []byte, isLongHeader bool, firstByte *byte, hdrBytes []byte) {
+	if isLongHeader {
+		*firstByte ^= mask[0] & 0xf
 	} else {
-		*firstByte ^= p.mask[0] & 0x1f
+		*firstByte ^= mask[0] & 0x1f
 	}
 	for i := range hdrBytes {
-		hdrBytes[i] ^= p.mask[i+1]
+		hdrBytes[i] ^= mask[i+1]
 	}
 }
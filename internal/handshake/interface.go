@@ -55,13 +55,17 @@ type LongHeaderSealer interface {
 type ShortHeaderSealer interface {
 	LongHeaderSealer
 	KeyPhase() protocol.KeyPhaseBit
+	// EncryptHeaders applies the header protection of multiple packets at once.
+	// In contrast to the other methods, it may be called concurrently with the other methods of the sealer.
+	EncryptHeaders([]HeaderProtection)
 }
 
-// A tlsExtensionHandler sends and received the QUIC TLS extension.
-type tlsExtensionHandler interface {
-	GetExtensions(msgType uint8) []qtls.Extension
-	ReceivedExtensions(msgType uint8, exts []qtls.Extension)
-	TransportParameters() <-chan []byte
+// HeaderProtection is the header protection of a sealed packet that hasn't been applied yet,
+// see ShortHeaderSealer.EncryptHeaders.
+type HeaderProtection struct {
+	Sample    []byte
+	FirstByte *byte
+	PNBytes   []byte
 }
 
 type handshakeRunner interface {
@@ -92,6 +96,21 @@ type CryptoSetup interface {
 	GetHandshakeSealer() (LongHeaderSealer, error)
 	Get0RTTSealer() (LongHeaderSealer, error)
 	Get1RTTSealer() (ShortHeaderSealer, error)
+
+	Export1RTTKeys() (*OneRTTKeys, error)
+}
+
+// OneRTTKeys is the state of the 1-RTT keys of a connection.
+// It is used to resume a connection in another process, see NewResumedCryptoSetup.
+type OneRTTKeys struct {
+	CipherSuite uint16
+	KeyPhase    protocol.KeyPhase
+	// The traffic secrets of key phase 0.
+	// The header protection keys and the keys of all later key phases are derived from them.
+	RcvTrafficSecret  []byte
+	SendTrafficSecret []byte
+	// needed to decode the packet numbers of the packets received next
+	HighestRcvdPacketNumber protocol.PacketNumber
 }
 
 // ConnWithVersion is the connection used in the ClientHelloInfo.
@@ -0,0 +1,105 @@
+package handshake
+
+import (
+	"crypto/tls"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// The resumedCryptoSetup is the crypto setup of a connection that was handed off by another process.
+// The other process completed the handshake, so only the 1-RTT keys are available.
+type resumedCryptoSetup struct {
+	aead      *updatableAEAD
+	connState ConnectionState
+	onError   func(error)
+}
+
+var _ CryptoSetup = &resumedCryptoSetup{}
+
+// NewResumedCryptoSetup creates the crypto setup of a connection that was handed off by another process,
+// using the keys returned by Export1RTTKeys.
+func NewResumedCryptoSetup(
+	keys *OneRTTKeys,
+	connState tls.ConnectionState,
+	onError func(error),
+	rttStats *utils.RTTStats,
+	tracer logging.ConnectionTracer,
+	logger utils.Logger,
+	version protocol.VersionNumber,
+) (CryptoSetup, error) {
+	aead := newUpdatableAEAD(rttStats, tracer, logger, version)
+	if err := aead.importKeys(keys); err != nil {
+		return nil, err
+	}
+	return &resumedCryptoSetup{
+		aead:      aead,
+		connState: ConnectionState{ConnectionState: connState},
+		onError:   onError,
+	}, nil
+}
+
+// RunHandshake returns immediately, the handshake was already completed.
+func (h *resumedCryptoSetup) RunHandshake() {}
+
+func (h *resumedCryptoSetup) Close() error { return nil }
+
+func (h *resumedCryptoSetup) ChangeConnectionID(protocol.ConnectionID) {}
+
+func (h *resumedCryptoSetup) GetSessionTicket() ([]byte, error) { return nil, nil }
+
+// HandleMessage handles post-handshake messages.
+// The client doesn't send any post-handshake messages in QUIC.
+func (h *resumedCryptoSetup) HandleMessage(data []byte, _ protocol.EncryptionLevel) bool {
+	h.onError(qerr.NewCryptoError(alertUnexpectedMessage, "unexpected "+messageType(data[0]).String()+" message"))
+	return false
+}
+
+func (h *resumedCryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) error {
+	return h.aead.SetLargestAcked(pn)
+}
+
+func (h *resumedCryptoSetup) SetHandshakeConfirmed() {
+	h.aead.SetHandshakeConfirmed()
+}
+
+func (h *resumedCryptoSetup) ConnectionState() ConnectionState { return h.connState }
+
+func (h *resumedCryptoSetup) GetInitialOpener() (LongHeaderOpener, error) {
+	return nil, ErrKeysDropped
+}
+
+func (h *resumedCryptoSetup) GetHandshakeOpener() (LongHeaderOpener, error) {
+	return nil, ErrKeysDropped
+}
+
+func (h *resumedCryptoSetup) Get0RTTOpener() (LongHeaderOpener, error) {
+	return nil, ErrKeysDropped
+}
+
+func (h *resumedCryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
+	return h.aead, nil
+}
+
+func (h *resumedCryptoSetup) GetInitialSealer() (LongHeaderSealer, error) {
+	return nil, ErrKeysDropped
+}
+
+func (h *resumedCryptoSetup) GetHandshakeSealer() (LongHeaderSealer, error) {
+	return nil, ErrKeysDropped
+}
+
+func (h *resumedCryptoSetup) Get0RTTSealer() (LongHeaderSealer, error) {
+	return nil, ErrKeysDropped
+}
+
+func (h *resumedCryptoSetup) Get1RTTSealer() (ShortHeaderSealer, error) {
+	return h.aead, nil
+}
+
+// Export1RTTKeys exports the keys, such that a resumed connection can be handed off again.
+func (h *resumedCryptoSetup) Export1RTTKeys() (*OneRTTKeys, error) {
+	return h.aead.exportKeys(), nil
+}
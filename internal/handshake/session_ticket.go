@@ -10,17 +10,19 @@ import (
 	"github.com/lucas-clemente/quic-go/quicvarint"
 )
 
-const sessionTicketRevision = 2
+const sessionTicketRevision = 3
 
 type sessionTicket struct {
 	Parameters *wire.TransportParameters
 	RTT        time.Duration // to be encoded in mus
+	IssuedAt   time.Time     // to be encoded in seconds since the Unix epoch
 }
 
 func (t *sessionTicket) Marshal() []byte {
 	b := make([]byte, 0, 256)
 	b = quicvarint.Append(b, sessionTicketRevision)
 	b = quicvarint.Append(b, uint64(t.RTT.Microseconds()))
+	b = quicvarint.Append(b, uint64(t.IssuedAt.Unix()))
 	return t.Parameters.MarshalForSessionTicket(b)
 }
 
@@ -37,11 +39,16 @@ func (t *sessionTicket) Unmarshal(b []byte) error {
 	if err != nil {
 		return errors.New("failed to read RTT")
 	}
+	issuedAt, err := quicvarint.Read(r)
+	if err != nil {
+		return errors.New("failed to read the issue time")
+	}
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(r); err != nil {
 		return fmt.Errorf("unmarshaling transport parameters from session ticket failed: %s", err.Error())
 	}
 	t.Parameters = &tp
 	t.RTT = time.Duration(rtt) * time.Microsecond
+	t.IssuedAt = time.Unix(int64(issuedAt), 0)
 	return nil
 }
@@ -1,3 +1,5 @@
+//go:build !quic_stdtls
+
 package handshake
 
 import (
@@ -19,6 +21,13 @@ type extensionHandler struct {
 	perspective protocol.Perspective
 }
 
+// A tlsExtensionHandler sends and received the QUIC TLS extension.
+type tlsExtensionHandler interface {
+	GetExtensions(msgType uint8) []qtls.Extension
+	ReceivedExtensions(msgType uint8, exts []qtls.Extension)
+	TransportParameters() <-chan []byte
+}
+
 var _ tlsExtensionHandler = &extensionHandler{}
 
 // newExtensionHandler creates a new extension handler
@@ -1,3 +1,5 @@
+//go:build !quic_stdtls
+
 package handshake
 
 import (
@@ -31,6 +31,38 @@ func (t *Token) ValidateRemoteAddr(addr net.Addr) bool {
 	return bytes.Equal(encodeRemoteAddr(addr), t.encodedRemoteAddr)
 }
 
+// ValidateRemoteAddrPrefix validates that the address is in the same network as the address the token was issued to,
+// but does not check expiration.
+// For IPv4 addresses, the first ipv4PrefixLen bits are compared, for IPv6 addresses the first ipv6PrefixLen bits.
+// A prefix length of 0 compares the full address.
+func (t *Token) ValidateRemoteAddrPrefix(addr net.Addr, ipv4PrefixLen, ipv6PrefixLen int) bool {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok || len(t.encodedRemoteAddr) == 0 || t.encodedRemoteAddr[0] != tokenPrefixIP {
+		return t.ValidateRemoteAddr(addr)
+	}
+	ip := udpAddr.IP
+	tokenIP := net.IP(t.encodedRemoteAddr[1:])
+	if ip4 := ip.To4(); ip4 != nil {
+		tokenIP4 := tokenIP.To4()
+		if tokenIP4 == nil {
+			return false
+		}
+		if ipv4PrefixLen == 0 {
+			return ip4.Equal(tokenIP4)
+		}
+		mask := net.CIDRMask(ipv4PrefixLen, 8*net.IPv4len)
+		return ip4.Mask(mask).Equal(tokenIP4.Mask(mask))
+	}
+	if len(ip) != net.IPv6len || len(tokenIP) != net.IPv6len || tokenIP.To4() != nil {
+		return false
+	}
+	if ipv6PrefixLen == 0 {
+		return ip.Equal(tokenIP)
+	}
+	mask := net.CIDRMask(ipv6PrefixLen, 8*net.IPv6len)
+	return ip.Mask(mask).Equal(tokenIP.Mask(mask))
+}
+
 // token is the struct that is used for ASN1 serialization and deserialization
 type token struct {
 	IsRetryToken             bool
@@ -120,4 +120,46 @@ var _ = Describe("Token Generator", func() {
 		Expect(token.ValidateRemoteAddr(&net.TCPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1338})).To(BeFalse())
 		Expect(token.SentTime).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
 	})
+	Context("validating address prefixes", func() {
+		decode := func(addr net.Addr) *Token {
+			tokenEnc, err := tokenGen.NewToken(addr)
+			Expect(err).ToNot(HaveOccurred())
+			token, err := tokenGen.DecodeToken(tokenEnc)
+			Expect(err).ToNot(HaveOccurred())
+			return token
+		}
+
+		It("compares the full address if no prefix length is set", func() {
+			token := decode(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337})
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4242}, 0, 0)).To(BeTrue())
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 1337}, 0, 0)).To(BeFalse())
+		})
+
+		It("compares IPv4 prefixes", func() {
+			token := decode(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337})
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 200)}, 24, 0)).To(BeTrue())
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.IPv4(192, 168, 1, 1)}, 24, 0)).To(BeFalse())
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.IPv4(192, 168, 1, 1)}, 16, 0)).To(BeTrue())
+		})
+
+		It("compares IPv6 prefixes", func() {
+			token := decode(&net.UDPAddr{IP: net.ParseIP("2001:db8::68"), Port: 1337})
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.ParseIP("2001:db8::1:2")}, 0, 64)).To(BeTrue())
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.ParseIP("2001:db8:0:1::68")}, 0, 64)).To(BeFalse())
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.ParseIP("2001:db8::1:2")}, 0, 0)).To(BeFalse())
+		})
+
+		It("doesn't match addresses from different IP versions", func() {
+			token := decode(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337})
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.ParseIP("2001:db8::68")}, 8, 8)).To(BeFalse())
+			token = decode(&net.UDPAddr{IP: net.ParseIP("2001:db8::68"), Port: 1337})
+			Expect(token.ValidateRemoteAddrPrefix(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)}, 8, 8)).To(BeFalse())
+		})
+
+		It("compares the string representation for addresses that are not UDP addresses", func() {
+			token := decode(&net.TCPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337})
+			Expect(token.ValidateRemoteAddrPrefix(&net.TCPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}, 24, 0)).To(BeTrue())
+			Expect(token.ValidateRemoteAddrPrefix(&net.TCPAddr{IP: net.IPv4(192, 168, 13, 38), Port: 1337}, 24, 0)).To(BeFalse())
+		})
+	})
 })
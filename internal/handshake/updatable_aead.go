@@ -19,6 +19,11 @@ import (
 // It's a package-level variable to allow modifying it for testing purposes.
 var KeyUpdateInterval uint64 = protocol.KeyUpdateInterval
 
+// maxImportedKeyPhase is the largest key phase accepted by importKeys.
+// The keys of every key phase are derived from the keys of the previous one,
+// so importing keys takes time proportional to the key phase.
+const maxImportedKeyPhase protocol.KeyPhase = 1 << 16
+
 type updatableAEAD struct {
 	suite *qtls.CipherSuiteTLS13
 
@@ -49,6 +54,9 @@ type updatableAEAD struct {
 	nextSendAEAD          cipher.AEAD
 	nextRcvTrafficSecret  []byte
 	nextSendTrafficSecret []byte
+	// the traffic secrets of key phase 0, see exportKeys
+	rcvTrafficSecret  []byte
+	sendTrafficSecret []byte
 
 	headerDecrypter headerProtector
 	headerEncrypter headerProtector
@@ -120,6 +128,7 @@ func (a *updatableAEAD) getNextTrafficSecret(hash crypto.Hash, ts []byte) []byte
 // For the server, this function is called after SetWriteKey.
 func (a *updatableAEAD) SetReadKey(suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
 	a.rcvAEAD = createAEAD(suite, trafficSecret, a.version)
+	a.rcvTrafficSecret = trafficSecret
 	a.headerDecrypter = newHeaderProtector(suite, trafficSecret, false, a.version)
 	if a.suite == nil {
 		a.setAEADParameters(a.rcvAEAD, suite)
@@ -133,6 +142,7 @@ func (a *updatableAEAD) SetReadKey(suite *qtls.CipherSuiteTLS13, trafficSecret [
 // For the server, this function is called before SetWriteKey.
 func (a *updatableAEAD) SetWriteKey(suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
 	a.sendAEAD = createAEAD(suite, trafficSecret, a.version)
+	a.sendTrafficSecret = trafficSecret
 	a.headerEncrypter = newHeaderProtector(suite, trafficSecret, false, a.version)
 	if a.suite == nil {
 		a.setAEADParameters(a.sendAEAD, suite)
@@ -142,6 +152,40 @@ func (a *updatableAEAD) SetWriteKey(suite *qtls.CipherSuiteTLS13, trafficSecret
 	a.nextSendAEAD = createAEAD(suite, a.nextSendTrafficSecret, a.version)
 }
 
+// exportKeys exports the keys, such that the connection can be resumed in another process.
+func (a *updatableAEAD) exportKeys() *OneRTTKeys {
+	return &OneRTTKeys{
+		CipherSuite:             a.suite.ID,
+		KeyPhase:                a.keyPhase,
+		RcvTrafficSecret:        a.rcvTrafficSecret,
+		SendTrafficSecret:       a.sendTrafficSecret,
+		HighestRcvdPacketNumber: a.highestRcvdPN,
+	}
+}
+
+// importKeys installs keys exported by exportKeys.
+// The keys of the previous key phase are not restored, packets sent by the peer using these keys can't be decrypted.
+func (a *updatableAEAD) importKeys(keys *OneRTTKeys) error {
+	switch keys.CipherSuite {
+	case tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384, tls.TLS_CHACHA20_POLY1305_SHA256:
+	default:
+		return fmt.Errorf("unknown cipher suite %d", keys.CipherSuite)
+	}
+	if keys.KeyPhase > maxImportedKeyPhase {
+		return fmt.Errorf("key phase %d too large", keys.KeyPhase)
+	}
+	suite := qtls.CipherSuiteTLS13ByID(keys.CipherSuite)
+	a.SetWriteKey(suite, keys.SendTrafficSecret)
+	a.SetReadKey(suite, keys.RcvTrafficSecret)
+	for a.keyPhase < keys.KeyPhase {
+		a.prevRcvAEAD = nil
+		a.rollKeys()
+	}
+	a.prevRcvAEAD = nil
+	a.highestRcvdPN = keys.HighestRcvdPacketNumber
+	return nil
+}
+
 func (a *updatableAEAD) setAEADParameters(aead cipher.AEAD, suite *qtls.CipherSuiteTLS13) {
 	a.nonceBuf = make([]byte, aead.NonceSize())
 	a.aeadOverhead = aead.Overhead()
@@ -314,6 +358,10 @@ func (a *updatableAEAD) EncryptHeader(sample []byte, firstByte *byte, hdrBytes [
 	a.headerEncrypter.EncryptHeader(sample, firstByte, hdrBytes)
 }
 
+func (a *updatableAEAD) EncryptHeaders(hps []HeaderProtection) {
+	a.headerEncrypter.EncryptHeaders(hps)
+}
+
 func (a *updatableAEAD) DecryptHeader(sample []byte, firstByte *byte, hdrBytes []byte) {
 	a.headerDecrypter.DecryptHeader(sample, firstByte, hdrBytes)
 }
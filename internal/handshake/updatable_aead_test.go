@@ -97,6 +97,27 @@ var _ = Describe("Updatable AEAD", func() {
 							}
 							Expect(lastFiveBitsDifferent).To(BeNumerically(">", 75))
 						})
+
+						It("encrypts the headers of multiple packets at once", func() {
+							const num = 2*headerProtectionBatchSize + 3
+							samples := make([][]byte, num)
+							headers := make([][]byte, num)
+							hps := make([]HeaderProtection, num)
+							for i := range hps {
+								samples[i] = make([]byte, 16)
+								rand.Read(samples[i])
+								headers[i] = []byte{0x45, 1, 2, 3, 4, 5, 6, 7, 8, 0xde, 0xad, 0xbe, byte(i)}
+								hps[i] = HeaderProtection{Sample: samples[i], FirstByte: &headers[i][0], PNBytes: headers[i][9:13]}
+							}
+							client.EncryptHeaders(hps)
+							for i := range hps {
+								expected := []byte{0x45, 1, 2, 3, 4, 5, 6, 7, 8, 0xde, 0xad, 0xbe, byte(i)}
+								client.EncryptHeader(samples[i], &expected[0], expected[9:13])
+								Expect(headers[i]).To(Equal(expected))
+								server.DecryptHeader(samples[i], &headers[i][0], headers[i][9:13])
+								Expect(headers[i]).To(Equal([]byte{0x45, 1, 2, 3, 4, 5, 6, 7, 8, 0xde, 0xad, 0xbe, byte(i)}))
+							}
+						})
 					})
 
 					Context("message encryption", func() {
@@ -159,6 +180,56 @@ var _ = Describe("Updatable AEAD", func() {
 							Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.AEADLimitReached))
 						})
 
+						Context("exporting keys", func() {
+							It("imports exported keys", func() {
+								encrypted := client.Seal(nil, msg, 0x42, ad)
+								_, err := server.Open(nil, encrypted, time.Now(), 0x42, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								keys := server.exportKeys()
+								Expect(keys.CipherSuite).To(Equal(cs.ID))
+								Expect(keys.HighestRcvdPacketNumber).To(Equal(protocol.PacketNumber(0x42)))
+								imported := newUpdatableAEAD(rttStats, nil, utils.DefaultLogger, v)
+								Expect(imported.importKeys(keys)).To(Succeed())
+								Expect(imported.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+								Expect(imported.DecodePacketNumber(0x43, protocol.PacketNumberLen1)).To(BeEquivalentTo(0x43))
+								encrypted = client.Seal(nil, msg, 0x43, ad)
+								opened, err := imported.Open(nil, encrypted, time.Now(), 0x43, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								opened, err = client.Open(nil, imported.Seal(nil, msg, 0x1337, ad), time.Now(), 0x1337, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+							})
+
+							It("imports exported keys after a key update", func() {
+								server.rollKeys()
+								client.rollKeys()
+								keys := server.exportKeys()
+								Expect(keys.KeyPhase).To(Equal(protocol.KeyPhase(1)))
+								imported := newUpdatableAEAD(rttStats, nil, utils.DefaultLogger, v)
+								Expect(imported.importKeys(keys)).To(Succeed())
+								Expect(imported.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+								opened, err := imported.Open(nil, client.Seal(nil, msg, 0x43, ad), time.Now(), 0x43, protocol.KeyPhaseOne, ad)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								opened, err = client.Open(nil, imported.Seal(nil, msg, 0x1337, ad), time.Now(), 0x1337, protocol.KeyPhaseOne, ad)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+							})
+
+							It("refuses to import keys for an unknown cipher suite", func() {
+								keys := server.exportKeys()
+								keys.CipherSuite = 0x1337
+								Expect(newUpdatableAEAD(rttStats, nil, utils.DefaultLogger, v).importKeys(keys)).To(MatchError("unknown cipher suite 4919"))
+							})
+
+							It("refuses to import keys with a too large key phase", func() {
+								keys := server.exportKeys()
+								keys.KeyPhase = maxImportedKeyPhase + 1
+								Expect(newUpdatableAEAD(rttStats, nil, utils.DefaultLogger, v).importKeys(keys)).To(MatchError(fmt.Sprintf("key phase %d too large", maxImportedKeyPhase+1)))
+							})
+						})
+
 						Context("key updates", func() {
 							Context("receiving key updates", func() {
 								It("updates keys", func() {
@@ -8,7 +8,7 @@ import (
 
 // ConvertFrame converts a wire.Frame into a logging.Frame.
 // This makes it possible for external packages to access the frames.
-// Furthermore, it removes the data slices from CRYPTO and STREAM frames.
+// Furthermore, it removes the data slices from CRYPTO, STREAM, PR_STREAM, DATAGRAM and PR_DATAGRAM frames.
 func ConvertFrame(frame wire.Frame) logging.Frame {
 	switch f := frame.(type) {
 	case *wire.AckFrame:
@@ -27,10 +27,25 @@ func ConvertFrame(frame wire.Frame) logging.Frame {
 			Length:   f.DataLen(),
 			Fin:      f.Fin,
 		}
+	case *wire.PRStreamFrame:
+		return &logging.PRStreamFrame{
+			StreamID: f.StreamID,
+			Offset:   f.Offset,
+			Length:   f.DataLen(),
+			Fin:      f.Fin,
+			PTDA:     f.PTDA,
+			Value:    f.PtdaC,
+		}
 	case *wire.DatagramFrame:
 		return &logging.DatagramFrame{
 			Length: logging.ByteCount(len(f.Data)),
 		}
+	case *wire.PRDatagramFrame:
+		return &logging.PRDatagramFrame{
+			Length: logging.ByteCount(len(f.Data)),
+			PTDA:   f.PTDA,
+			Value:  f.PtdaC,
+		}
 	default:
 		return logging.Frame(frame)
 	}
@@ -34,6 +34,25 @@ var _ = Describe("CRYPTO frame", func() {
 		Expect(sf.Fin).To(BeTrue())
 	})
 
+	It("converts PR_STREAM frames", func() {
+		f := ConvertFrame(&wire.PRStreamFrame{
+			StreamID: 42,
+			Offset:   1234,
+			Data:     []byte("foo"),
+			Fin:      true,
+			PTDA:     0x20,
+			PtdaC:    100,
+		})
+		Expect(f).To(Equal(&logging.PRStreamFrame{
+			StreamID: 42,
+			Offset:   1234,
+			Length:   3,
+			Fin:      true,
+			PTDA:     0x20,
+			Value:    100,
+		}))
+	})
+
 	It("converts DATAGRAM frames", func() {
 		f := ConvertFrame(&wire.DatagramFrame{Data: []byte("foobar")})
 		Expect(f).To(BeAssignableToTypeOf(&logging.DatagramFrame{}))
@@ -41,6 +60,15 @@ var _ = Describe("CRYPTO frame", func() {
 		Expect(df.Length).To(Equal(logging.ByteCount(6)))
 	})
 
+	It("converts PR_DATAGRAM frames", func() {
+		f := ConvertFrame(&wire.PRDatagramFrame{Data: []byte("foobar"), PTDA: 0x20, PtdaC: 100})
+		Expect(f).To(Equal(&logging.PRDatagramFrame{
+			Length: 6,
+			PTDA:   0x20,
+			Value:  100,
+		}))
+	})
+
 	It("converts other frames", func() {
 		f := ConvertFrame(&wire.MaxDataFrame{MaximumData: 1234})
 		Expect(f).To(BeAssignableToTypeOf(&logging.MaxDataFrame{}))
@@ -37,6 +37,34 @@ func (m *MockSentPacketHandler) EXPECT() *MockSentPacketHandlerMockRecorder {
 	return m.recorder
 }
 
+// AckMetrics mocks base method.
+func (m *MockSentPacketHandler) AckMetrics() ackhandler.AckMetrics {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckMetrics")
+	ret0, _ := ret[0].(ackhandler.AckMetrics)
+	return ret0
+}
+
+// AckMetrics indicates an expected call of AckMetrics.
+func (mr *MockSentPacketHandlerMockRecorder) AckMetrics() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckMetrics", reflect.TypeOf((*MockSentPacketHandler)(nil).AckMetrics))
+}
+
+// CanSendPR mocks base method.
+func (m *MockSentPacketHandler) CanSendPR() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CanSendPR")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// CanSendPR indicates an expected call of CanSendPR.
+func (mr *MockSentPacketHandlerMockRecorder) CanSendPR() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanSendPR", reflect.TypeOf((*MockSentPacketHandler)(nil).CanSendPR))
+}
+
 // DropPackets mocks base method.
 func (m *MockSentPacketHandler) DropPackets(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -77,6 +105,18 @@ func (mr *MockSentPacketHandlerMockRecorder) HasPacingBudget() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockSentPacketHandler)(nil).HasPacingBudget))
 }
 
+// MigratedPath mocks base method.
+func (m *MockSentPacketHandler) MigratedPath(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MigratedPath", arg0)
+}
+
+// MigratedPath indicates an expected call of MigratedPath.
+func (mr *MockSentPacketHandlerMockRecorder) MigratedPath(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratedPath", reflect.TypeOf((*MockSentPacketHandler)(nil).MigratedPath), arg0)
+}
+
 // OnLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -91,6 +131,18 @@ func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
 }
 
+// PathValidated mocks base method.
+func (m *MockSentPacketHandler) PathValidated() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PathValidated")
+}
+
+// PathValidated indicates an expected call of PathValidated.
+func (mr *MockSentPacketHandlerMockRecorder) PathValidated() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathValidated", reflect.TypeOf((*MockSentPacketHandler)(nil).PathValidated))
+}
+
 // PeekPacketNumber mocks base method.
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -175,6 +227,18 @@ func (mr *MockSentPacketHandlerMockRecorder) ResetForRetry() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetForRetry", reflect.TypeOf((*MockSentPacketHandler)(nil).ResetForRetry))
 }
 
+// ResumeAt mocks base method.
+func (m *MockSentPacketHandler) ResumeAt(arg0 protocol.PacketNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ResumeAt", arg0)
+}
+
+// ResumeAt indicates an expected call of ResumeAt.
+func (mr *MockSentPacketHandlerMockRecorder) ResumeAt(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeAt", reflect.TypeOf((*MockSentPacketHandler)(nil).ResumeAt), arg0)
+}
+
 // SendMode mocks base method.
 func (m *MockSentPacketHandler) SendMode() ackhandler.SendMode {
 	m.ctrl.T.Helper()
@@ -225,6 +289,18 @@ func (mr *MockSentPacketHandlerMockRecorder) SetMaxDatagramSize(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxDatagramSize), arg0)
 }
 
+// StartPathValidation mocks base method.
+func (m *MockSentPacketHandler) StartPathValidation(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartPathValidation", arg0)
+}
+
+// StartPathValidation indicates an expected call of StartPathValidation.
+func (mr *MockSentPacketHandlerMockRecorder) StartPathValidation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartPathValidation", reflect.TypeOf((*MockSentPacketHandler)(nil).StartPathValidation), arg0)
+}
+
 // TimeUntilSend mocks base method.
 func (m *MockSentPacketHandler) TimeUntilSend() time.Time {
 	m.ctrl.T.Helper()
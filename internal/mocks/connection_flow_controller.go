@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -72,6 +73,20 @@ func (mr *MockConnectionFlowControllerMockRecorder) GetWindowUpdate() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWindowUpdate", reflect.TypeOf((*MockConnectionFlowController)(nil).GetWindowUpdate))
 }
 
+// HandoffState mocks base method.
+func (m *MockConnectionFlowController) HandoffState() flowcontrol.HandoffState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandoffState")
+	ret0, _ := ret[0].(flowcontrol.HandoffState)
+	return ret0
+}
+
+// HandoffState indicates an expected call of HandoffState.
+func (mr *MockConnectionFlowControllerMockRecorder) HandoffState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandoffState", reflect.TypeOf((*MockConnectionFlowController)(nil).HandoffState))
+}
+
 // IsNewlyBlocked mocks base method.
 func (m *MockConnectionFlowController) IsNewlyBlocked() (bool, protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -101,6 +116,18 @@ func (mr *MockConnectionFlowControllerMockRecorder) Reset() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockConnectionFlowController)(nil).Reset))
 }
 
+// Resume mocks base method.
+func (m *MockConnectionFlowController) Resume(arg0 flowcontrol.HandoffState) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Resume", arg0)
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockConnectionFlowControllerMockRecorder) Resume(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockConnectionFlowController)(nil).Resume), arg0)
+}
+
 // SendWindowSize mocks base method.
 func (m *MockConnectionFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -76,6 +76,21 @@ func (mr *MockCryptoSetupMockRecorder) ConnectionState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionState", reflect.TypeOf((*MockCryptoSetup)(nil).ConnectionState))
 }
 
+// Export1RTTKeys mocks base method.
+func (m *MockCryptoSetup) Export1RTTKeys() (*handshake.OneRTTKeys, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export1RTTKeys")
+	ret0, _ := ret[0].(*handshake.OneRTTKeys)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export1RTTKeys indicates an expected call of Export1RTTKeys.
+func (mr *MockCryptoSetupMockRecorder) Export1RTTKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export1RTTKeys", reflect.TypeOf((*MockCryptoSetup)(nil).Export1RTTKeys))
+}
+
 // Get0RTTOpener mocks base method.
 func (m *MockCryptoSetup) Get0RTTOpener() (handshake.LongHeaderOpener, error) {
 	m.ctrl.T.Helper()
@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	qerr "github.com/lucas-clemente/quic-go/internal/qerr"
 )
 
@@ -109,6 +110,35 @@ func (mr *MockEarlyConnectionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockEarlyConnection)(nil).Context))
 }
 
+// Events mocks base method.
+func (m *MockEarlyConnection) Events() <-chan quic.TransportEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Events")
+	ret0, _ := ret[0].(<-chan quic.TransportEvent)
+	return ret0
+}
+
+// Events indicates an expected call of Events.
+func (mr *MockEarlyConnectionMockRecorder) Events() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Events", reflect.TypeOf((*MockEarlyConnection)(nil).Events))
+}
+
+// Handoff mocks base method.
+func (m *MockEarlyConnection) Handoff() (*quic.ConnectionHandoff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Handoff")
+	ret0, _ := ret[0].(*quic.ConnectionHandoff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Handoff indicates an expected call of Handoff.
+func (mr *MockEarlyConnectionMockRecorder) Handoff() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handoff", reflect.TypeOf((*MockEarlyConnection)(nil).Handoff))
+}
+
 // HandshakeComplete mocks base method.
 func (m *MockEarlyConnection) HandshakeComplete() context.Context {
 	m.ctrl.T.Helper()
@@ -123,6 +153,20 @@ func (mr *MockEarlyConnectionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlyConnection)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method.
+func (m *MockEarlyConnection) HandshakeConfirmed() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed.
+func (mr *MockEarlyConnectionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockEarlyConnection)(nil).HandshakeConfirmed))
+}
+
 // LocalAddr mocks base method.
 func (m *MockEarlyConnection) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -137,6 +181,20 @@ func (mr *MockEarlyConnectionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlyConnection)(nil).LocalAddr))
 }
 
+// MaxStreamFrameDataSize mocks base method.
+func (m *MockEarlyConnection) MaxStreamFrameDataSize(arg0 protocol.StreamID) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxStreamFrameDataSize", arg0)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// MaxStreamFrameDataSize indicates an expected call of MaxStreamFrameDataSize.
+func (mr *MockEarlyConnectionMockRecorder) MaxStreamFrameDataSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxStreamFrameDataSize", reflect.TypeOf((*MockEarlyConnection)(nil).MaxStreamFrameDataSize), arg0)
+}
+
 // NextConnection mocks base method.
 func (m *MockEarlyConnection) NextConnection() quic.Connection {
 	m.ctrl.T.Helper()
@@ -151,6 +209,30 @@ func (mr *MockEarlyConnectionMockRecorder) NextConnection() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextConnection", reflect.TypeOf((*MockEarlyConnection)(nil).NextConnection))
 }
 
+// OnStream mocks base method.
+func (m *MockEarlyConnection) OnStream(arg0 func(quic.Stream)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStream", arg0)
+}
+
+// OnStream indicates an expected call of OnStream.
+func (mr *MockEarlyConnectionMockRecorder) OnStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStream", reflect.TypeOf((*MockEarlyConnection)(nil).OnStream), arg0)
+}
+
+// OnUniStream mocks base method.
+func (m *MockEarlyConnection) OnUniStream(arg0 func(quic.ReceiveStream)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnUniStream", arg0)
+}
+
+// OnUniStream indicates an expected call of OnUniStream.
+func (mr *MockEarlyConnectionMockRecorder) OnUniStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnUniStream", reflect.TypeOf((*MockEarlyConnection)(nil).OnUniStream), arg0)
+}
+
 // OpenStream mocks base method.
 func (m *MockEarlyConnection) OpenStream() (quic.Stream, error) {
 	m.ctrl.T.Helper()
@@ -211,6 +293,20 @@ func (mr *MockEarlyConnectionMockRecorder) OpenUniStreamSync(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlyConnection)(nil).OpenUniStreamSync), arg0)
 }
 
+// PRStats mocks base method.
+func (m *MockEarlyConnection) PRStats() quic.PRStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PRStats")
+	ret0, _ := ret[0].(quic.PRStats)
+	return ret0
+}
+
+// PRStats indicates an expected call of PRStats.
+func (mr *MockEarlyConnectionMockRecorder) PRStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PRStats", reflect.TypeOf((*MockEarlyConnection)(nil).PRStats))
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockEarlyConnection) ReceiveMessage() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -253,3 +349,68 @@ func (mr *MockEarlyConnectionMockRecorder) SendMessage(arg0 interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockEarlyConnection)(nil).SendMessage), arg0)
 }
+
+// SendMessageTracked mocks base method.
+func (m *MockEarlyConnection) SendMessageTracked(arg0 []byte, arg1 quic.PRPolicy) (*quic.DatagramHandle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageTracked", arg0, arg1)
+	ret0, _ := ret[0].(*quic.DatagramHandle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMessageTracked indicates an expected call of SendMessageTracked.
+func (mr *MockEarlyConnectionMockRecorder) SendMessageTracked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageTracked", reflect.TypeOf((*MockEarlyConnection)(nil).SendMessageTracked), arg0, arg1)
+}
+
+// SendMessageWithPolicy mocks base method.
+func (m *MockEarlyConnection) SendMessageWithPolicy(arg0 []byte, arg1 quic.PRPolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageWithPolicy", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMessageWithPolicy indicates an expected call of SendMessageWithPolicy.
+func (mr *MockEarlyConnectionMockRecorder) SendMessageWithPolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageWithPolicy", reflect.TypeOf((*MockEarlyConnection)(nil).SendMessageWithPolicy), arg0, arg1)
+}
+
+// SetMaxIncomingStreams mocks base method.
+func (m *MockEarlyConnection) SetMaxIncomingStreams(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxIncomingStreams", arg0)
+}
+
+// SetMaxIncomingStreams indicates an expected call of SetMaxIncomingStreams.
+func (mr *MockEarlyConnectionMockRecorder) SetMaxIncomingStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingStreams", reflect.TypeOf((*MockEarlyConnection)(nil).SetMaxIncomingStreams), arg0)
+}
+
+// SetMaxIncomingUniStreams mocks base method.
+func (m *MockEarlyConnection) SetMaxIncomingUniStreams(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxIncomingUniStreams", arg0)
+}
+
+// SetMaxIncomingUniStreams indicates an expected call of SetMaxIncomingUniStreams.
+func (mr *MockEarlyConnectionMockRecorder) SetMaxIncomingUniStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingUniStreams", reflect.TypeOf((*MockEarlyConnection)(nil).SetMaxIncomingUniStreams), arg0)
+}
+
+// SetPRPolicy mocks base method.
+func (m *MockEarlyConnection) SetPRPolicy(arg0 *quic.PRPolicy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPRPolicy", arg0)
+}
+
+// SetPRPolicy indicates an expected call of SetPRPolicy.
+func (mr *MockEarlyConnectionMockRecorder) SetPRPolicy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPRPolicy", reflect.TypeOf((*MockEarlyConnection)(nil).SetPRPolicy), arg0)
+}
@@ -6,10 +6,12 @@ package mockquic
 
 import (
 	context "context"
+	net "net"
 	reflect "reflect"
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	quic "github.com/lucas-clemente/quic-go"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	qerr "github.com/lucas-clemente/quic-go/internal/qerr"
 )
@@ -37,6 +39,20 @@ func (m *MockStream) EXPECT() *MockStreamMockRecorder {
 	return m.recorder
 }
 
+// AckedRanges mocks base method.
+func (m *MockStream) AckedRanges() []quic.ByteRange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckedRanges")
+	ret0, _ := ret[0].([]quic.ByteRange)
+	return ret0
+}
+
+// AckedRanges indicates an expected call of AckedRanges.
+func (mr *MockStreamMockRecorder) AckedRanges() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckedRanges", reflect.TypeOf((*MockStream)(nil).AckedRanges))
+}
+
 // CancelRead mocks base method.
 func (m *MockStream) CancelRead(arg0 qerr.StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -89,6 +105,59 @@ func (mr *MockStreamMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStream)(nil).Context))
 }
 
+// DisablePR mocks base method.
+func (m *MockStream) DisablePR() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DisablePR")
+}
+
+// DisablePR indicates an expected call of DisablePR.
+func (mr *MockStreamMockRecorder) DisablePR() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisablePR", reflect.TypeOf((*MockStream)(nil).DisablePR))
+}
+
+// EffectivePRPolicy mocks base method.
+func (m *MockStream) EffectivePRPolicy() (quic.PRPolicy, quic.PRPolicySource) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EffectivePRPolicy")
+	ret0, _ := ret[0].(quic.PRPolicy)
+	ret1, _ := ret[1].(quic.PRPolicySource)
+	return ret0, ret1
+}
+
+// EffectivePRPolicy indicates an expected call of EffectivePRPolicy.
+func (mr *MockStreamMockRecorder) EffectivePRPolicy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EffectivePRPolicy", reflect.TypeOf((*MockStream)(nil).EffectivePRPolicy))
+}
+
+// EndMessage mocks base method.
+func (m *MockStream) EndMessage() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EndMessage")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EndMessage indicates an expected call of EndMessage.
+func (mr *MockStreamMockRecorder) EndMessage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndMessage", reflect.TypeOf((*MockStream)(nil).EndMessage))
+}
+
+// ExpireDataBefore mocks base method.
+func (m *MockStream) ExpireDataBefore(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExpireDataBefore", arg0)
+}
+
+// ExpireDataBefore indicates an expected call of ExpireDataBefore.
+func (mr *MockStreamMockRecorder) ExpireDataBefore(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireDataBefore", reflect.TypeOf((*MockStream)(nil).ExpireDataBefore), arg0)
+}
+
 // Read mocks base method.
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -104,6 +173,21 @@ func (mr *MockStreamMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// ReadMessageBoundary mocks base method.
+func (m *MockStream) ReadMessageBoundary() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadMessageBoundary")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadMessageBoundary indicates an expected call of ReadMessageBoundary.
+func (mr *MockStreamMockRecorder) ReadMessageBoundary() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadMessageBoundary", reflect.TypeOf((*MockStream)(nil).ReadMessageBoundary))
+}
+
 // SetDeadline mocks base method.
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -118,6 +202,42 @@ func (mr *MockStreamMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStream)(nil).SetDeadline), arg0)
 }
 
+// SetIdleTimeout mocks base method.
+func (m *MockStream) SetIdleTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetIdleTimeout", arg0)
+}
+
+// SetIdleTimeout indicates an expected call of SetIdleTimeout.
+func (mr *MockStreamMockRecorder) SetIdleTimeout(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdleTimeout", reflect.TypeOf((*MockStream)(nil).SetIdleTimeout), arg0)
+}
+
+// SetPRPolicy mocks base method.
+func (m *MockStream) SetPRPolicy(arg0 *quic.PRPolicy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPRPolicy", arg0)
+}
+
+// SetPRPolicy indicates an expected call of SetPRPolicy.
+func (mr *MockStreamMockRecorder) SetPRPolicy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPRPolicy", reflect.TypeOf((*MockStream)(nil).SetPRPolicy), arg0)
+}
+
+// SetRateLimit mocks base method.
+func (m *MockStream) SetRateLimit(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetRateLimit", arg0)
+}
+
+// SetRateLimit indicates an expected call of SetRateLimit.
+func (mr *MockStreamMockRecorder) SetRateLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRateLimit", reflect.TypeOf((*MockStream)(nil).SetRateLimit), arg0)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStream) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -132,6 +252,30 @@ func (mr *MockStreamMockRecorder) SetReadDeadline(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStream)(nil).SetReadDeadline), arg0)
 }
 
+// SetReadSkippedAsError mocks base method.
+func (m *MockStream) SetReadSkippedAsError(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadSkippedAsError", arg0)
+}
+
+// SetReadSkippedAsError indicates an expected call of SetReadSkippedAsError.
+func (mr *MockStreamMockRecorder) SetReadSkippedAsError(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadSkippedAsError", reflect.TypeOf((*MockStream)(nil).SetReadSkippedAsError), arg0)
+}
+
+// SetWriteBufferWatermarks mocks base method.
+func (m *MockStream) SetWriteBufferWatermarks(arg0, arg1 uint64, arg2 func(bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWriteBufferWatermarks", arg0, arg1, arg2)
+}
+
+// SetWriteBufferWatermarks indicates an expected call of SetWriteBufferWatermarks.
+func (mr *MockStreamMockRecorder) SetWriteBufferWatermarks(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteBufferWatermarks", reflect.TypeOf((*MockStream)(nil).SetWriteBufferWatermarks), arg0, arg1, arg2)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStream) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -160,6 +304,36 @@ func (mr *MockStreamMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStream)(nil).StreamID))
 }
 
+// TryRead mocks base method.
+func (m *MockStream) TryRead(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockStreamMockRecorder) TryRead(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockStream)(nil).TryRead), arg0)
+}
+
+// TryWrite mocks base method.
+func (m *MockStream) TryWrite(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockStreamMockRecorder) TryWrite(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockStream)(nil).TryWrite), arg0)
+}
+
 // Write mocks base method.
 func (m *MockStream) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -174,3 +348,33 @@ func (mr *MockStreamMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStream)(nil).Write), arg0)
 }
+
+// WriteBuffers mocks base method.
+func (m *MockStream) WriteBuffers(arg0 net.Buffers) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockStreamMockRecorder) WriteBuffers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockStream)(nil).WriteBuffers), arg0)
+}
+
+// WriteWithPolicy mocks base method.
+func (m *MockStream) WriteWithPolicy(arg0 []byte, arg1 quic.PRPolicy) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteWithPolicy", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteWithPolicy indicates an expected call of WriteWithPolicy.
+func (mr *MockStreamMockRecorder) WriteWithPolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteWithPolicy", reflect.TypeOf((*MockStream)(nil).WriteWithPolicy), arg0, arg1)
+}
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	handshake "github.com/lucas-clemente/quic-go/internal/handshake"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -46,6 +47,18 @@ func (mr *MockShortHeaderSealerMockRecorder) EncryptHeader(arg0, arg1, arg2 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptHeader", reflect.TypeOf((*MockShortHeaderSealer)(nil).EncryptHeader), arg0, arg1, arg2)
 }
 
+// EncryptHeaders mocks base method.
+func (m *MockShortHeaderSealer) EncryptHeaders(arg0 []handshake.HeaderProtection) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "EncryptHeaders", arg0)
+}
+
+// EncryptHeaders indicates an expected call of EncryptHeaders.
+func (mr *MockShortHeaderSealerMockRecorder) EncryptHeaders(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncryptHeaders", reflect.TypeOf((*MockShortHeaderSealer)(nil).EncryptHeaders), arg0)
+}
+
 // KeyPhase mocks base method.
 func (m *MockShortHeaderSealer) KeyPhase() protocol.KeyPhaseBit {
 	m.ctrl.T.Helper()
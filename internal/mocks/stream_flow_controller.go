@@ -58,6 +58,18 @@ func (mr *MockStreamFlowControllerMockRecorder) AddBytesRead(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBytesRead", reflect.TypeOf((*MockStreamFlowController)(nil).AddBytesRead), arg0)
 }
 
+// AddBytesSkipped mocks base method.
+func (m *MockStreamFlowController) AddBytesSkipped(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddBytesSkipped", arg0)
+}
+
+// AddBytesSkipped indicates an expected call of AddBytesSkipped.
+func (mr *MockStreamFlowControllerMockRecorder) AddBytesSkipped(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBytesSkipped", reflect.TypeOf((*MockStreamFlowController)(nil).AddBytesSkipped), arg0)
+}
+
 // AddBytesSent mocks base method.
 func (m *MockStreamFlowController) AddBytesSent(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -55,6 +55,16 @@ const SkipPacketInitialPeriod PacketNumber = 256
 // SkipPacketMaxPeriod is the maximum period length used for packet number skipping.
 const SkipPacketMaxPeriod PacketNumber = 128 * 1024
 
+// MaxAmplificationFactor is the maximum anti-amplification factor (RFC 9000, Section 8).
+// Before validating the client's address, the server won't send more than 3x bytes than it received.
+const MaxAmplificationFactor = 3
+
+// DefaultPacketThreshold is the default reordering threshold (in packets) for loss detection (RFC 9002, Section 6.1.1).
+const DefaultPacketThreshold = 3
+
+// DefaultTimeThreshold is the default time threshold for loss detection, as a multiple of the RTT (RFC 9002, Section 6.1.2).
+const DefaultTimeThreshold = 9.0 / 8
+
 // MaxAcceptQueueSize is the maximum number of connections that the server queues for accepting.
 // If the queue is full, new connection attempts will be rejected.
 const MaxAcceptQueueSize = 32
@@ -92,6 +102,10 @@ const MinStreamFrameBufferSize = 128
 // If a packet has less than this number of bytes, we won't coalesce any more packets onto it.
 const MinCoalescedPacketSize = 128
 
+// MinClientHelloFragmentSize is the minimum value of Config.ClientHelloFragmentSize.
+// Every fragment needs to fit the header of the CRYPTO frame, and some data.
+const MinClientHelloFragmentSize = 32
+
 // MaxCryptoStreamOffset is the maximum offset allowed on any of the crypto streams.
 // This limits the size of the ClientHello and Certificates that can be received.
 const MaxCryptoStreamOffset = 16 * (1 << 10)
@@ -132,13 +146,23 @@ const MaxPostHandshakeCryptoFrameSize = 1000
 // but must ensure that a maximum size ACK frame fits into one packet.
 const MaxAckFrameSize ByteCount = 1000
 
-// MaxDatagramFrameSize is the maximum size of a DATAGRAM frame (RFC 9221).
+// MaxDatagramFrameSize is the default maximum size of a DATAGRAM frame (RFC 9221).
 // The size is chosen such that a DATAGRAM frame fits into a QUIC packet.
 const MaxDatagramFrameSize ByteCount = 1220
 
+// DatagramFramePacketOverhead is the number of bytes of a 1-RTT packet that can't be used for a DATAGRAM frame.
+// It accounts for the packet header and the AEAD overhead.
+const DatagramFramePacketOverhead = InitialPacketSizeIPv4 - MaxDatagramFrameSize
+
 // DatagramRcvQueueLen is the length of the receive queue for DATAGRAM frames (RFC 9221)
 const DatagramRcvQueueLen = 128
 
+// DefaultDatagramSendQueueLen is the default length of the send queue for DATAGRAM frames (RFC 9221)
+const DefaultDatagramSendQueueLen = 1
+
+// TransportEventQueueLen is the number of transport events queued for the application, see Connection.Events.
+const TransportEventQueueLen = 64
+
 // MaxNumAckRanges is the maximum number of ACK ranges that we send in an ACK frame.
 // It also serves as a limit for the packet history.
 // If at any point we keep track of more ranges, old ranges are discarded.
@@ -191,3 +215,11 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxConnUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the connection, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 31
+
+// MaxQueuedMessageBoundaries is the maximum number of message boundaries (see PR_MESSAGE_BOUNDARY frames) that we buffer per stream.
+// Boundaries beyond that are ignored, starting with the one farthest from the read position.
+const MaxQueuedMessageBoundaries = 1024
+
+// SkipRatioScale is the denominator of the max_skip_ratio transport parameter,
+// i.e. a value of SkipRatioScale allows all data to be skipped.
+const SkipRatioScale = 10000
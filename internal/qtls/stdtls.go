@@ -0,0 +1,40 @@
+//go:build go1.21 && quic_stdtls
+
+package qtls
+
+import (
+	"crypto/tls"
+)
+
+// With the quic_stdtls build tag, the handshake is run by the QUIC API of the standard library's crypto/tls,
+// instead of by a fork of crypto/tls for every Go version.
+// This package then only provides the types and cipher suites used by the handshake package.
+
+type (
+	// Alert is a TLS alert
+	Alert = tls.AlertError
+	// A Config is a tls.Config.
+	Config = tls.Config
+	// EncryptionLevel is the encryption level of a message.
+	EncryptionLevel = tls.QUICEncryptionLevel
+)
+
+// ConnectionState contains information about the state of the connection.
+type ConnectionState struct {
+	tls.ConnectionState
+	Used0RTT bool
+}
+
+const (
+	// EncryptionHandshake is the Handshake encryption level
+	EncryptionHandshake = tls.QUICEncryptionLevelHandshake
+	// Encryption0RTT is the 0-RTT encryption level
+	Encryption0RTT = tls.QUICEncryptionLevelEarly
+	// EncryptionApplication is the application data encryption level
+	EncryptionApplication = tls.QUICEncryptionLevelApplication
+)
+
+// ToTLSConnectionState extracts the tls.ConnectionState
+func ToTLSConnectionState(cs ConnectionState) tls.ConnectionState {
+	return cs.ConnectionState
+}
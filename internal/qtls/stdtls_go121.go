@@ -0,0 +1,10 @@
+//go:build go1.21 && !go1.22 && quic_stdtls
+
+package qtls
+
+import "crypto/tls"
+
+// SendSessionTicket sends a session ticket to the client.
+func SendSessionTicket(c *tls.QUICConn, allow0RTT bool) error {
+	return c.SendSessionTicket(allow0RTT)
+}
@@ -0,0 +1,5 @@
+//go:build quic_stdtls && !go1.21
+
+package qtls
+
+var _ int = "The quic_stdtls build tag uses the QUIC API of crypto/tls, which requires Go 1.21 or newer."
@@ -27,8 +27,11 @@ const logEnv = "QUIC_GO_LOG_LEVEL"
 // A Logger logs.
 type Logger interface {
 	SetLogLevel(LogLevel)
+	LogLevel() LogLevel
 	SetLogTimeFormat(format string)
 	WithPrefix(prefix string) Logger
+	// With returns a Logger that adds the key-value pairs to every message.
+	With(keysAndValues ...interface{}) Logger
 	Debug() bool
 
 	Errorf(format string, args ...interface{})
@@ -41,6 +44,7 @@ var DefaultLogger Logger
 
 type defaultLogger struct {
 	prefix string
+	fields []interface{}
 
 	logLevel   LogLevel
 	timeFormat string
@@ -53,6 +57,11 @@ func (l *defaultLogger) SetLogLevel(level LogLevel) {
 	l.logLevel = level
 }
 
+// LogLevel returns the log level
+func (l *defaultLogger) LogLevel() LogLevel {
+	return l.logLevel
+}
+
 // SetLogTimeFormat sets the format of the timestamp
 // an empty string disables the logging of timestamps
 func (l *defaultLogger) SetLogTimeFormat(format string) {
@@ -90,7 +99,25 @@ func (l *defaultLogger) logMessage(format string, args ...interface{}) {
 	if len(l.prefix) > 0 {
 		pre += l.prefix + " "
 	}
-	log.Printf(pre+format, args...)
+	if len(l.fields) == 0 {
+		log.Printf(pre+format, args...)
+		return
+	}
+	log.Print(pre + fmt.Sprintf(format, args...) + formatFields(l.fields))
+}
+
+// formatFields formats key-value pairs as " key=value".
+// A key without a value is logged with the value "MISSING".
+func formatFields(keysAndValues []interface{}) string {
+	var sb strings.Builder
+	for i := 0; i < len(keysAndValues); i += 2 {
+		var val interface{} = "MISSING"
+		if i+1 < len(keysAndValues) {
+			val = keysAndValues[i+1]
+		}
+		fmt.Fprintf(&sb, " %v=%v", keysAndValues[i], val)
+	}
+	return sb.String()
 }
 
 func (l *defaultLogger) WithPrefix(prefix string) Logger {
@@ -101,9 +128,27 @@ func (l *defaultLogger) WithPrefix(prefix string) Logger {
 		logLevel:   l.logLevel,
 		timeFormat: l.timeFormat,
 		prefix:     prefix,
+		fields:     l.fields,
+	}
+}
+
+func (l *defaultLogger) With(keysAndValues ...interface{}) Logger {
+	return &defaultLogger{
+		logLevel:   l.logLevel,
+		timeFormat: l.timeFormat,
+		prefix:     l.prefix,
+		fields:     appendFields(l.fields, keysAndValues),
 	}
 }
 
+// appendFields appends key-value pairs without modifying the underlying array of fields,
+// which might be shared with other loggers.
+func appendFields(fields, keysAndValues []interface{}) []interface{} {
+	f := make([]interface{}, 0, len(fields)+len(keysAndValues))
+	f = append(f, fields...)
+	return append(f, keysAndValues...)
+}
+
 // Debug returns true if the log level is LogLevelDebug
 func (l *defaultLogger) Debug() bool {
 	return l.logLevel == LogLevelDebug
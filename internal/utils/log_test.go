@@ -91,6 +91,12 @@ var _ = Describe("Log", func() {
 		Expect(DefaultLogger.Debug()).To(BeTrue())
 	})
 
+	It("returns the log level", func() {
+		DefaultLogger.SetLogLevel(LogLevelInfo)
+		Expect(DefaultLogger.LogLevel()).To(Equal(LogLevelInfo))
+		Expect(DefaultLogger.WithPrefix("prefix").LogLevel()).To(Equal(LogLevelInfo))
+	})
+
 	It("adds a prefix", func() {
 		DefaultLogger.SetLogLevel(LogLevelDebug)
 		prefixLogger := DefaultLogger.WithPrefix("prefix")
@@ -108,6 +114,17 @@ var _ = Describe("Log", func() {
 		Expect(b.String()).To(ContainSubstring("debug"))
 	})
 
+	It("adds key-value pairs", func() {
+		DefaultLogger.SetLogLevel(LogLevelDebug)
+		DefaultLogger.SetLogTimeFormat("")
+		logger := DefaultLogger.WithPrefix("prefix").With("stream_id", 4)
+		logger.With("offset", 1337, "length").Debugf("debug %d", 42)
+		Expect(b.String()).To(Equal("prefix debug 42 stream_id=4 offset=1337 length=MISSING\n"))
+		b.Reset()
+		logger.Debugf("debug")
+		Expect(b.String()).To(Equal("prefix debug stream_id=4\n"))
+	})
+
 	Context("reading from env", func() {
 		BeforeEach(func() {
 			Expect(DefaultLogger.(*defaultLogger).logLevel).To(Equal(LogLevelNothing))
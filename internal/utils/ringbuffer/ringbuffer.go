@@ -0,0 +1,115 @@
+package ringbuffer
+
+// A RingBuffer is a ring buffer.
+// It acts as a FIFO queue that doesn't cause any allocations once it has grown to its working size.
+type RingBuffer[T any] struct {
+	ring             []T
+	headPos, tailPos int
+	full             bool
+}
+
+// Init preallocs a buffer with a certain size.
+func (r *RingBuffer[T]) Init(size int) {
+	r.ring = make([]T, size)
+}
+
+// Len returns the number of elements in the ring buffer.
+func (r *RingBuffer[T]) Len() int {
+	if r.full {
+		return len(r.ring)
+	}
+	if r.tailPos >= r.headPos {
+		return r.tailPos - r.headPos
+	}
+	return r.tailPos - r.headPos + len(r.ring)
+}
+
+// Empty says if the ring buffer is empty.
+func (r *RingBuffer[T]) Empty() bool {
+	return !r.full && r.headPos == r.tailPos
+}
+
+// PushBack adds a new element.
+// If the ring buffer is full, its capacity is increased first.
+func (r *RingBuffer[T]) PushBack(t T) {
+	if r.full || len(r.ring) == 0 {
+		r.grow()
+	}
+	r.ring[r.tailPos] = t
+	r.tailPos++
+	if r.tailPos == len(r.ring) {
+		r.tailPos = 0
+	}
+	if r.tailPos == r.headPos {
+		r.full = true
+	}
+}
+
+// PopFront returns the next element.
+// It must not be called when the buffer is empty, that means that
+// callers might need to check if there are elements in the buffer first.
+func (r *RingBuffer[T]) PopFront() T {
+	if r.Empty() {
+		panic("github.com/lucas-clemente/quic-go/internal/utils/ringbuffer: pop from an empty queue")
+	}
+	r.full = false
+	t := r.ring[r.headPos]
+	var zero T
+	r.ring[r.headPos] = zero // don't keep a reference to the element
+	r.headPos++
+	if r.headPos == len(r.ring) {
+		r.headPos = 0
+	}
+	return t
+}
+
+// PeekFront returns the next element, without removing it.
+// It must not be called when the buffer is empty.
+func (r *RingBuffer[T]) PeekFront() T {
+	if r.Empty() {
+		panic("github.com/lucas-clemente/quic-go/internal/utils/ringbuffer: peek from an empty queue")
+	}
+	return r.ring[r.headPos]
+}
+
+// Filter removes all elements for which keep returns false.
+// The order of the remaining elements is preserved.
+func (r *RingBuffer[T]) Filter(keep func(T) bool) {
+	n := r.Len()
+	for i := 0; i < n; i++ {
+		if t := r.PopFront(); keep(t) {
+			r.PushBack(t)
+		}
+	}
+}
+
+// Clear removes all elements.
+func (r *RingBuffer[T]) Clear() {
+	var zero T
+	for i := range r.ring {
+		r.ring[i] = zero
+	}
+	r.headPos = 0
+	r.tailPos = 0
+	r.full = false
+}
+
+// grow doubles the capacity of the ring buffer.
+func (r *RingBuffer[T]) grow() {
+	size := 2 * len(r.ring)
+	if size == 0 {
+		size = 1
+	}
+	ring := make([]T, size)
+	n := r.Len()
+	if r.headPos < r.tailPos || (r.headPos == r.tailPos && !r.full) {
+		copy(ring, r.ring[r.headPos:r.tailPos])
+	} else {
+		copy(ring, r.ring[r.headPos:])
+		copy(ring[len(r.ring)-r.headPos:], r.ring[:r.tailPos])
+	}
+	r.ring = ring
+	r.headPos = 0
+	r.tailPos = n
+	r.full = false
+}
@@ -0,0 +1,105 @@
+package ringbuffer
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RingBuffer", func() {
+	It("push and pop", func() {
+		r := RingBuffer[int]{}
+		Expect(len(r.ring)).To(Equal(0))
+		Expect(func() { r.PopFront() }).To(Panic())
+		r.Init(4)
+		r.PushBack(1)
+		r.PushBack(2)
+		r.PushBack(3)
+		Expect(r.PopFront()).To(Equal(1))
+		Expect(r.PopFront()).To(Equal(2))
+		r.PushBack(4)
+		r.PushBack(5)
+		Expect(r.Len()).To(Equal(3))
+		r.PushBack(6)
+		Expect(r.Len()).To(Equal(4))
+		Expect(r.PeekFront()).To(Equal(3))
+		Expect(r.PopFront()).To(Equal(3))
+		Expect(r.PopFront()).To(Equal(4))
+		Expect(r.PopFront()).To(Equal(5))
+		Expect(r.PopFront()).To(Equal(6))
+		Expect(r.Empty()).To(BeTrue())
+		Expect(func() { r.PeekFront() }).To(Panic())
+	})
+
+	It("grows when full", func() {
+		r := RingBuffer[int]{}
+		for i := 0; i < 3; i++ {
+			r.PushBack(i)
+		}
+		Expect(r.PopFront()).To(Equal(0))
+		// the ring wraps around before it grows
+		for i := 3; i < 10; i++ {
+			r.PushBack(i)
+		}
+		Expect(r.Len()).To(Equal(9))
+		for i := 1; i < 10; i++ {
+			Expect(r.PopFront()).To(Equal(i))
+		}
+		Expect(r.Empty()).To(BeTrue())
+	})
+
+	It("filters elements", func() {
+		r := RingBuffer[int]{}
+		r.Init(4)
+		r.PushBack(0)
+		r.PopFront()
+		for i := 1; i <= 4; i++ {
+			r.PushBack(i)
+		}
+		r.Filter(func(i int) bool { return i%2 == 0 })
+		Expect(r.Len()).To(Equal(2))
+		Expect(r.PopFront()).To(Equal(2))
+		Expect(r.PopFront()).To(Equal(4))
+		Expect(r.Empty()).To(BeTrue())
+	})
+
+	It("clears", func() {
+		r := RingBuffer[int]{}
+		r.Init(2)
+		r.PushBack(1)
+		r.PushBack(2)
+		r.Clear()
+		Expect(r.Empty()).To(BeTrue())
+		Expect(r.Len()).To(BeZero())
+		Expect(r.ring).To(Equal([]int{0, 0}))
+	})
+})
+
+// The retransmission queue of a send stream under heavy loss:
+// there are always about 100 frames queued, and frames are popped from the front.
+const benchmarkQueueLen = 100
+
+func BenchmarkRingBuffer(b *testing.B) {
+	b.ReportAllocs()
+	var r RingBuffer[*int]
+	for i := 0; i < benchmarkQueueLen; i++ {
+		r.PushBack(new(int))
+	}
+	for i := 0; i < b.N; i++ {
+		r.PushBack(r.PopFront())
+	}
+}
+
+func BenchmarkSlice(b *testing.B) {
+	b.ReportAllocs()
+	var s []*int
+	for i := 0; i < benchmarkQueueLen; i++ {
+		s = append(s, new(int))
+	}
+	for i := 0; i < b.N; i++ {
+		f := s[0]
+		s = s[1:]
+		s = append(s, f)
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import "fmt"
+
+// A StructuredLogger logs messages with key-value pairs.
+// Its method set is a subset of the method set of *slog.Logger.
+type StructuredLogger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+type structuredLogger struct {
+	logger StructuredLogger
+	prefix string
+	fields []interface{}
+
+	logLevel LogLevel
+}
+
+var _ Logger = &structuredLogger{}
+
+// NewStructuredLogger creates a Logger that passes the messages of the given log level to l.
+// The key-value pairs are added to every message.
+func NewStructuredLogger(l StructuredLogger, level LogLevel, keysAndValues ...interface{}) Logger {
+	return &structuredLogger{
+		logger:   l,
+		fields:   keysAndValues,
+		logLevel: level,
+	}
+}
+
+func (l *structuredLogger) SetLogLevel(level LogLevel) {
+	l.logLevel = level
+}
+
+func (l *structuredLogger) LogLevel() LogLevel {
+	return l.logLevel
+}
+
+// SetLogTimeFormat is a no-op. Timestamps are added by the StructuredLogger.
+func (l *structuredLogger) SetLogTimeFormat(string) {}
+
+func (l *structuredLogger) WithPrefix(prefix string) Logger {
+	if len(l.prefix) > 0 {
+		prefix = l.prefix + " " + prefix
+	}
+	return &structuredLogger{
+		logger:   l.logger,
+		prefix:   prefix,
+		fields:   l.fields,
+		logLevel: l.logLevel,
+	}
+}
+
+func (l *structuredLogger) With(keysAndValues ...interface{}) Logger {
+	return &structuredLogger{
+		logger:   l.logger,
+		prefix:   l.prefix,
+		fields:   appendFields(l.fields, keysAndValues),
+		logLevel: l.logLevel,
+	}
+}
+
+func (l *structuredLogger) Debug() bool {
+	return l.logLevel == LogLevelDebug
+}
+
+func (l *structuredLogger) Errorf(format string, args ...interface{}) {
+	if l.logLevel >= LogLevelError {
+		l.logger.Error(l.message(format, args...), l.fields...)
+	}
+}
+
+func (l *structuredLogger) Infof(format string, args ...interface{}) {
+	if l.logLevel >= LogLevelInfo {
+		l.logger.Info(l.message(format, args...), l.fields...)
+	}
+}
+
+func (l *structuredLogger) Debugf(format string, args ...interface{}) {
+	if l.logLevel == LogLevelDebug {
+		l.logger.Debug(l.message(format, args...), l.fields...)
+	}
+}
+
+func (l *structuredLogger) message(format string, args ...interface{}) string {
+	msg := fmt.Sprintf(format, args...)
+	if len(l.prefix) > 0 {
+		return l.prefix + " " + msg
+	}
+	return msg
+}
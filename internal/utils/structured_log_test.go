@@ -0,0 +1,68 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type loggedMessage struct {
+	level  string
+	msg    string
+	fields []interface{}
+}
+
+type recordingLogger struct{ messages []loggedMessage }
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, loggedMessage{level: "debug", msg: msg, fields: keysAndValues})
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, loggedMessage{level: "info", msg: msg, fields: keysAndValues})
+}
+
+func (l *recordingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.messages = append(l.messages, loggedMessage{level: "error", msg: msg, fields: keysAndValues})
+}
+
+var _ = Describe("Structured Logger", func() {
+	var rec *recordingLogger
+
+	BeforeEach(func() {
+		rec = &recordingLogger{}
+	})
+
+	It("passes all messages on, with log level debug", func() {
+		logger := NewStructuredLogger(rec, LogLevelDebug, "conn_id", "deadbeef")
+		Expect(logger.Debug()).To(BeTrue())
+		logger.Debugf("debug %d", 1)
+		logger.Infof("info %d", 2)
+		logger.Errorf("error %d", 3)
+		Expect(rec.messages).To(Equal([]loggedMessage{
+			{level: "debug", msg: "debug 1", fields: []interface{}{"conn_id", "deadbeef"}},
+			{level: "info", msg: "info 2", fields: []interface{}{"conn_id", "deadbeef"}},
+			{level: "error", msg: "error 3", fields: []interface{}{"conn_id", "deadbeef"}},
+		}))
+	})
+
+	It("respects the log level", func() {
+		logger := NewStructuredLogger(rec, LogLevelInfo)
+		Expect(logger.LogLevel()).To(Equal(LogLevelInfo))
+		Expect(logger.Debug()).To(BeFalse())
+		logger.Debugf("debug")
+		logger.Infof("info")
+		Expect(rec.messages).To(HaveLen(1))
+		Expect(rec.messages[0].msg).To(Equal("info"))
+	})
+
+	It("adds prefixes and key-value pairs", func() {
+		logger := NewStructuredLogger(rec, LogLevelDebug, "conn_id", "deadbeef").WithPrefix("server")
+		strLogger := logger.With("stream_id", 4)
+		strLogger.WithPrefix("PR").Debugf("foo")
+		logger.Debugf("bar")
+		Expect(rec.messages).To(Equal([]loggedMessage{
+			{level: "debug", msg: "server PR foo", fields: []interface{}{"conn_id", "deadbeef", "stream_id", 4}},
+			{level: "debug", msg: "server bar", fields: []interface{}{"conn_id", "deadbeef"}},
+		}))
+	})
+})
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/quicvarint"
 )
 
 type frameParser struct {
@@ -16,15 +18,18 @@ type frameParser struct {
 	ackDelayExponent uint8
 
 	supportsDatagrams bool
+	supportsPR        bool
 
 	version protocol.VersionNumber
 }
 
 // NewFrameParser creates a new frame parser.
-func NewFrameParser(supportsDatagrams bool, v protocol.VersionNumber) FrameParser {
+// If supportsPR is false, the frames used for partially reliable streams are rejected as unknown frame types.
+func NewFrameParser(supportsDatagrams, supportsPR bool, v protocol.VersionNumber) FrameParser {
 	return &frameParser{
 		r:                 *bytes.NewReader(nil),
 		supportsDatagrams: supportsDatagrams,
+		supportsPR:        supportsPR,
 		version:           v,
 	}
 }
@@ -48,10 +53,19 @@ func (p *frameParser) parseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 		}
 		r.UnreadByte()
 
-		f, err := p.parseFrame(r, typeByte, encLevel)
+		// Frame types that fit into a single byte are the common case.
+		typ := uint64(typeByte)
+		var err error
+		if typeByte > 0x3f {
+			typ, err = peekFrameType(r)
+		}
+		var f Frame
+		if err == nil {
+			f, err = p.parseFrame(r, typ, encLevel)
+		}
 		if err != nil {
 			return nil, &qerr.TransportError{
-				FrameType:    uint64(typeByte),
+				FrameType:    typ,
 				ErrorCode:    qerr.FrameEncodingError,
 				ErrorMessage: err.Error(),
 			}
@@ -61,17 +75,39 @@ func (p *frameParser) parseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 	return nil, nil
 }
 
-func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protocol.EncryptionLevel) (Frame, error) {
+// peekFrameType reads the frame type, without consuming it: the frames parse their frame type themselves.
+// The frame type is a variable-length integer. The frames defined in RFC 9000 use a single byte,
+// the PR frames use 2 bytes, see pr_frame_types.go.
+// Frame types that are not minimally encoded are rejected, such that e.g. 0x4008 can't be parsed as a STREAM frame.
+func peekFrameType(r *bytes.Reader) (uint64, error) {
+	startLen := r.Len()
+	typ, err := quicvarint.Read(r)
+	if err != nil {
+		return 0, err
+	}
+	n := startLen - r.Len()
+	if _, err := r.Seek(-int64(n), io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	if quicvarint.Len(typ) != protocol.ByteCount(n) {
+		return typ, errors.New("frame type not minimally encoded")
+	}
+	return typ, nil
+}
+
+func (p *frameParser) parseFrame(r *bytes.Reader, typ uint64, encLevel protocol.EncryptionLevel) (Frame, error) {
 	var frame Frame
 	var err error
-	if typeByte&0xf8 == 0x8 {
+	if typ&^0x7 == 0x8 {
 		frame, err = parseStreamFrame(r, p.version)
-	} else if typeByte&0xf8 == 0x48 { //0x48..0x4f是PR_STREAM帧
+	} else if !p.supportsPR && IsPRFrameType(typ) {
+		err = errors.New("unknown frame type")
+	} else if typ&^0x7 == PRStreamFrameType { //0x48..0x4f是PR_STREAM帧, only 0x48 is valid
 		frame, err = parsePRStreamFrame(r, p.version) // 添加PRStreamFrame类型及处理
-	} else if typeByte&0xf8 == 0x58 { //0x58..0x5f是PR_AckNotify帧
+	} else if typ&^0x7 == PRAckNotifyFrameType { //0x58..0x5f是PR_AckNotify帧
 		frame, err = parsePRAckNotifyFrame(r, p.version)
 	} else {
-		switch typeByte {
+		switch typ {
 		case 0x1:
 			frame, err = parsePingFrame(r, p.version)
 		case 0x2, 0x3:
@@ -114,14 +150,18 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseHandshakeDoneFrame(r, p.version)
 
 		// RFC9000:此注册表中的永久注册项遵循（[RFC8126]第4.6节）规约策略进行分配，但0x00和0x3f（十六进制）之间的值除外
-		// 0x50 52/53 分别为新增的PR_Ack、PR_Datagram帧
-		case 0x50:
+		// PR帧的类型见pr_frame_types.go
+		case PRAckFrameType:
 			ackDelayExponent := p.ackDelayExponent
 			if encLevel != protocol.Encryption1RTT {
 				ackDelayExponent = protocol.DefaultAckDelayExponent
 			}
 			frame, err = parsePRAckFrame(r, ackDelayExponent, p.version)
-		case 0x52, 0x53:
+		case PRMessageBoundaryFrameType:
+			frame, err = parsePRMessageBoundaryFrame(r, p.version)
+		case PRStreamPolicyFrameType:
+			frame, err = parsePRStreamPolicyFrame(r, p.version)
+		case PRDatagramFrameType, PRDatagramFrameType + 1:
 			if p.supportsDatagrams {
 				frame, err = parsePRDatagramFrame(r, p.version)
 				break
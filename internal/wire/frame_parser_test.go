@@ -1,10 +1,12 @@
 package wire
 
 import (
+	"bytes"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/quicvarint"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -13,7 +15,7 @@ var _ = Describe("Frame parsing", func() {
 	var parser FrameParser
 
 	BeforeEach(func() {
-		parser = NewFrameParser(true, protocol.Version1)
+		parser = NewFrameParser(true, true, protocol.Version1)
 	})
 
 	It("returns nil if there's nothing more to read", func() {
@@ -183,6 +185,34 @@ var _ = Describe("Frame parsing", func() {
 		Expect(l).To(Equal(len(b)))
 	})
 
+	It("unpacks PR_MESSAGE_BOUNDARY frames", func() {
+		f := &PRMessageBoundaryFrame{
+			StreamID: 0xdeadbeef,
+			Offset:   0xdecafbad,
+		}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+		Expect(l).To(Equal(len(b)))
+	})
+
+	It("unpacks PR_STREAM_POLICY frames", func() {
+		f := &PRStreamPolicyFrame{
+			StreamID: 0xdeadbeef,
+			Offset:   0xdecafbad,
+			PTDA:     0x20,
+			Value:    100,
+		}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+		Expect(l).To(Equal(len(b)))
+	})
+
 	It("unpacks MAX_STREAMS frames", func() {
 		f := &MaxStreamsFrame{
 			Type:         protocol.StreamTypeBidi,
@@ -314,7 +344,7 @@ var _ = Describe("Frame parsing", func() {
 	})
 
 	It("errors when DATAGRAM frames are not supported", func() {
-		parser = NewFrameParser(false, protocol.Version1)
+		parser = NewFrameParser(false, true, protocol.Version1)
 		f := &DatagramFrame{Data: []byte("foobar")}
 		b, err := f.Append(nil, protocol.Version1)
 		Expect(err).ToNot(HaveOccurred())
@@ -326,8 +356,34 @@ var _ = Describe("Frame parsing", func() {
 		}))
 	})
 
+	It("errors when PR frames are not supported", func() {
+		parser = NewFrameParser(true, false, protocol.Version1)
+		for _, f := range []Frame{
+			&PRStreamFrame{StreamID: 4, Data: []byte("foobar"), PTDA: 0x20, PtdaC: 100, D: true},
+			&PRStreamPolicyFrame{StreamID: 4, PTDA: 0x20, Value: 100},
+			&PRMessageBoundaryFrame{StreamID: 4, Offset: 42},
+		} {
+			b, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			typ, err := quicvarint.Read(bytes.NewReader(b))
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = parser.ParseNext(b, protocol.Encryption1RTT)
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.FrameEncodingError,
+				FrameType:    typ,
+				ErrorMessage: "unknown frame type",
+			}))
+		}
+	})
+
 	It("errors on invalid type", func() {
-		_, _, err := parser.ParseNext([]byte{0x42}, protocol.Encryption1RTT)
+		_, _, err := parser.ParseNext([]byte{0x2f}, protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0x2f,
+			ErrorMessage: "unknown frame type",
+		}))
+		_, _, err = parser.ParseNext(encodeVarInt(0x42), protocol.Encryption1RTT)
 		Expect(err).To(MatchError(&qerr.TransportError{
 			ErrorCode:    qerr.FrameEncodingError,
 			FrameType:    0x42,
@@ -335,6 +391,67 @@ var _ = Describe("Frame parsing", func() {
 		}))
 	})
 
+	Context("frame types", func() {
+		It("errors on truncated frame types", func() {
+			b := encodeVarInt(PRStreamFrameType)
+			Expect(b).To(HaveLen(2))
+			_, _, err := parser.ParseNext(b[:1], protocol.Encryption1RTT)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+		})
+
+		It("rejects frame types that are not minimally encoded", func() {
+			b, err := (&StreamFrame{StreamID: 4, Data: []byte("foobar")}).Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[0]).To(BeEquivalentTo(0x8))
+			// encode the frame type 0x8 in 2 bytes
+			b = append([]byte{0x40}, b...)
+			_, _, err = parser.ParseNext(b, protocol.Encryption1RTT)
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.FrameEncodingError,
+				FrameType:    0x8,
+				ErrorMessage: "frame type not minimally encoded",
+			}))
+		})
+
+		It("doesn't parse PR frames with a single byte frame type as STREAM frames", func() {
+			b, err := (&PRStreamFrame{StreamID: 4, Data: []byte("foobar"), PTDA: 0x20, PtdaC: 100, D: true}).Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:2]).To(Equal(encodeVarInt(PRStreamFrameType)))
+			// a single 0x48 byte is the beginning of a 2-byte encoded frame type in the range 0x800 - 0x8ff
+			_, _, err = parser.ParseNext(b[1:], protocol.Encryption1RTT)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.TransportError).FrameType).To(BeNumerically(">=", 0x800))
+			Expect(err.(*qerr.TransportError).ErrorMessage).To(Equal("unknown frame type"))
+		})
+
+		It("demultiplexes STREAM frames and PR frames in the same packet", func() {
+			frames := []Frame{
+				&StreamFrame{StreamID: 4, Offset: 10, Data: []byte("foo"), DataLenPresent: true},
+				&PRStreamFrame{StreamID: 8, Offset: 20, Data: []byte("bar"), DataLenPresent: true, PTDA: 0x40, PtdaC: 3, T: true},
+				&PRAckNotifyFrame{StreamID: 8, Offset: 3, PRDataLen: 17, DataLenPresent: true, PTDA: 0x40, PtdaC: 3, T: true},
+				&StreamFrame{StreamID: 8, Offset: 23, Data: []byte("baz"), Fin: true, DataLenPresent: true},
+				&PRStreamFrame{StreamID: 4, Data: []byte("lorem ipsum"), Fin: true, PTDA: 0x20, PtdaC: 100, D: true},
+			}
+			var b []byte
+			for _, f := range frames {
+				var err error
+				l := len(b)
+				b, err = f.Append(b, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(b) - l).To(BeEquivalentTo(f.Length(protocol.Version1)))
+			}
+			for _, f := range frames {
+				l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(f))
+				Expect(l).To(BeEquivalentTo(f.Length(protocol.Version1)))
+				b = b[l:]
+			}
+			Expect(b).To(BeEmpty())
+		})
+	})
+
 	It("errors on invalid frames", func() {
 		f := &MaxStreamDataFrame{
 			StreamID:          0x1337,
@@ -358,6 +475,11 @@ var _ = Describe("Frame parsing", func() {
 			&StreamFrame{Data: []byte("foobar")},
 			&MaxDataFrame{},
 			&MaxStreamDataFrame{},
+			&PRStreamFrame{StreamID: 4, Data: []byte("foobar")},
+			&PRAckNotifyFrame{StreamID: 4, PRDataLen: 6},
+			&PRDatagramFrame{Data: []byte("foobar")},
+			&PRMessageBoundaryFrame{},
+			&PRStreamPolicyFrame{},
 			&MaxStreamsFrame{},
 			&DataBlockedFrame{},
 			&StreamDataBlockedFrame{},
@@ -6,19 +6,60 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
-var pool sync.Pool
+// The data buffers of pooled (PR)STREAM frames have one of these sizes.
+// Most PR frames carry small media slices, and using a buffer of the maximum packet size
+// for every one of them would waste a lot of memory on servers handling many streams.
+var frameBufferSizes = [...]protocol.ByteCount{256, 512, protocol.MaxPacketBufferSize}
+
+var pool [len(frameBufferSizes)]sync.Pool
 
 func init() {
-	pool.New = func() interface{} {
-		return &StreamFrame{
-			Data:     make([]byte, 0, protocol.MaxPacketBufferSize),
-			fromPool: true,
+	for i := range pool {
+		size := frameBufferSizes[i]
+		pool[i].New = func() interface{} {
+			return &StreamFrame{
+				Data:     make([]byte, 0, size),
+				fromPool: true,
+			}
 		}
 	}
 }
 
+// sizeClass returns the index of the smallest buffer size that can hold l bytes.
+// If l is larger than the maximum packet size, the largest size class is returned.
+func sizeClass(l protocol.ByteCount) int {
+	for i, size := range frameBufferSizes {
+		if l <= size {
+			return i
+		}
+	}
+	return len(frameBufferSizes) - 1
+}
+
+// sizeClassOfBuffer returns the size class of a pooled buffer with capacity c.
+// It panics if c is not one of the buffer sizes.
+func sizeClassOfBuffer(c int) int {
+	for i, size := range frameBufferSizes {
+		if protocol.ByteCount(c) == size {
+			return i
+		}
+	}
+	panic("wire.PutStreamFrame called with packet of wrong size!")
+}
+
+// GetStreamFrame returns a STREAM frame that can hold data of the maximum packet size.
 func GetStreamFrame() *StreamFrame {
-	f := pool.Get().(*StreamFrame)
+	f := pool[len(pool)-1].Get().(*StreamFrame)
+	auditGet(f)
+	return f
+}
+
+// GetStreamFrameWithSize returns a STREAM frame that can hold at least l bytes of data,
+// unless l is larger than the maximum packet size.
+// The data can't be grown beyond the capacity of the buffer.
+func GetStreamFrameWithSize(l protocol.ByteCount) *StreamFrame {
+	f := pool[sizeClass(l)].Get().(*StreamFrame)
+	auditGet(f)
 	return f
 }
 
@@ -26,8 +67,6 @@ func putStreamFrame(f *StreamFrame) {
 	if !f.fromPool {
 		return
 	}
-	if protocol.ByteCount(cap(f.Data)) != protocol.MaxPacketBufferSize {
-		panic("wire.PutStreamFrame called with packet of wrong size!")
-	}
-	pool.Put(f)
+	auditPut(f)
+	pool[sizeClassOfBuffer(cap(f.Data))].Put(f)
 }
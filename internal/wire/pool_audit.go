@@ -0,0 +1,101 @@
+package wire
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A PoolAudit keeps track of the (PR)STREAM frames taken from and returned to the frame pools.
+// It is meant to be used in tests, to detect frames that are never returned (leaks),
+// and frames that are returned more than once.
+// Only one audit can be active at a time.
+type PoolAudit struct {
+	mutex sync.Mutex
+
+	// true if the frame is outstanding, false if it was returned to the pool
+	frames     map[interface{}]bool
+	gets, puts int
+	doublePuts int
+}
+
+var (
+	poolAuditEnabled utils.AtomicBool
+	poolAudit        *PoolAudit
+)
+
+// StartPoolAudit starts accounting for frames taken from and returned to the frame pools.
+// Frames that were taken from the pools before the audit was started are ignored.
+func StartPoolAudit() *PoolAudit {
+	a := &PoolAudit{frames: make(map[interface{}]bool)}
+	poolAudit = a
+	poolAuditEnabled.Set(true)
+	return a
+}
+
+// Stop stops the audit.
+func (a *PoolAudit) Stop() {
+	poolAuditEnabled.Set(false)
+}
+
+func (a *PoolAudit) onGet(f interface{}) {
+	a.mutex.Lock()
+	a.gets++
+	a.frames[f] = true
+	a.mutex.Unlock()
+}
+
+func (a *PoolAudit) onPut(f interface{}) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	outstanding, ok := a.frames[f]
+	if !ok {
+		return
+	}
+	if !outstanding {
+		a.doublePuts++
+		return
+	}
+	a.puts++
+	a.frames[f] = false
+}
+
+// Outstanding returns the number of frames that were taken from the pools, but not returned yet.
+func (a *PoolAudit) Outstanding() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.gets - a.puts
+}
+
+// DoublePuts returns the number of times a frame was returned to the pools that had already been returned.
+func (a *PoolAudit) DoublePuts() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.doublePuts
+}
+
+// Err returns an error if any frames are outstanding, or if a frame was returned more than once.
+func (a *PoolAudit) Err() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.doublePuts > 0 {
+		return fmt.Errorf("pool audit: %d frames returned more than once", a.doublePuts)
+	}
+	if n := a.gets - a.puts; n != 0 {
+		return fmt.Errorf("pool audit: %d frames not returned", n)
+	}
+	return nil
+}
+
+func auditGet(f interface{}) {
+	if poolAuditEnabled.Get() {
+		poolAudit.onGet(f)
+	}
+}
+
+func auditPut(f interface{}) {
+	if poolAuditEnabled.Get() {
+		poolAudit.onPut(f)
+	}
+}
@@ -0,0 +1,93 @@
+package wire
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pool audit", func() {
+	var audit *PoolAudit
+
+	BeforeEach(func() { audit = StartPoolAudit() })
+	AfterEach(func() { audit.Stop() })
+
+	It("counts outstanding frames", func() {
+		f1 := GetStreamFrame()
+		f2 := GetPRStreamFrameWithSize(10)
+		Expect(audit.Outstanding()).To(Equal(2))
+		Expect(audit.Err()).To(MatchError("pool audit: 2 frames not returned"))
+		f1.PutBack()
+		f2.PutBack()
+		Expect(audit.Outstanding()).To(BeZero())
+		Expect(audit.Err()).ToNot(HaveOccurred())
+	})
+
+	It("detects frames that are returned twice", func() {
+		f := GetStreamFrame()
+		f.PutBack()
+		f.PutBack()
+		Expect(audit.DoublePuts()).To(Equal(1))
+		Expect(audit.Err()).To(MatchError("pool audit: 1 frames returned more than once"))
+	})
+
+	It("ignores frames taken from the pool before the audit was started", func() {
+		audit.Stop()
+		f := GetStreamFrame()
+		audit = StartPoolAudit()
+		f.PutBack()
+		Expect(audit.Err()).ToNot(HaveOccurred())
+	})
+
+	It("accounts for split frames", func() {
+		f := GetStreamFrame()
+		f.Data = append(f.Data, make([]byte, 1000)...)
+		f.DataLenPresent = true
+		new, needsSplit := f.MaybeSplitOffFrame(500, protocol.Version1)
+		Expect(needsSplit).To(BeTrue())
+		Expect(audit.Outstanding()).To(Equal(2))
+		new.PutBack()
+		f.PutBack()
+		Expect(audit.Err()).ToNot(HaveOccurred())
+	})
+
+	Context("converting frames", func() {
+		It("converts STREAM frames to PRSTREAM frames", func() {
+			f := GetStreamFrameWithSize(6)
+			f.StreamID = 5
+			f.Offset = 10
+			f.Data = append(f.Data, []byte("foobar")...)
+			f.Fin = true
+			prf := f.ToPRStreamFrame()
+			Expect(prf.StreamID).To(BeEquivalentTo(5))
+			Expect(prf.Offset).To(BeEquivalentTo(10))
+			Expect(prf.Data).To(Equal([]byte("foobar")))
+			Expect(prf.Fin).To(BeTrue())
+			Expect(audit.Outstanding()).To(Equal(1))
+			prf.PutBack()
+			Expect(audit.Err()).ToNot(HaveOccurred())
+		})
+
+		It("converts PRSTREAM frames to STREAM frames", func() {
+			prf := GetPRStreamFrame()
+			prf.StreamID = 5
+			prf.Data = append(prf.Data, []byte("foobar")...)
+			prf.PTDA = 0x80
+			f := prf.ToStreamFrame()
+			Expect(f.StreamID).To(BeEquivalentTo(5))
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(audit.Outstanding()).To(Equal(1))
+			f.PutBack()
+			Expect(audit.Err()).ToNot(HaveOccurred())
+		})
+
+		It("converts frames that were not taken from the pool", func() {
+			prf := (&StreamFrame{StreamID: 5, Data: []byte("foobar")}).ToPRStreamFrame()
+			Expect(prf.Data).To(Equal([]byte("foobar")))
+			f := prf.ToStreamFrame()
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(audit.Outstanding()).To(BeZero())
+		})
+	})
+})
@@ -1,6 +1,10 @@
 package wire
 
 import (
+	"testing"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -8,6 +12,7 @@ import (
 var _ = Describe("Pool", func() {
 	It("gets and puts STREAM frames", func() {
 		f := GetStreamFrame()
+		Expect(cap(f.Data)).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
 		putStreamFrame(f)
 	})
 
@@ -21,4 +26,64 @@ var _ = Describe("Pool", func() {
 		f := &StreamFrame{Data: []byte("foobar")}
 		putStreamFrame(f)
 	})
+
+	It("gets STREAM frames of the smallest size that holds the data", func() {
+		Expect(cap(GetStreamFrameWithSize(1).Data)).To(Equal(256))
+		Expect(cap(GetStreamFrameWithSize(256).Data)).To(Equal(256))
+		Expect(cap(GetStreamFrameWithSize(257).Data)).To(Equal(512))
+		Expect(cap(GetStreamFrameWithSize(protocol.MaxPacketBufferSize).Data)).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+		Expect(cap(GetStreamFrameWithSize(protocol.MaxPacketBufferSize + 1).Data)).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+	})
+
+	It("puts STREAM frames back into the pool of their size", func() {
+		for _, size := range frameBufferSizes {
+			f := GetStreamFrameWithSize(size)
+			Expect(func() { putStreamFrame(f) }).ToNot(Panic())
+		}
+	})
+
+	It("gets and puts PRSTREAM frames of different sizes", func() {
+		f := GetPRStreamFrameWithSize(300)
+		Expect(cap(f.Data)).To(Equal(512))
+		putPRStreamFrame(f)
+		f = GetPRStreamFrame()
+		Expect(cap(f.Data)).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+		putPRStreamFrame(f)
+	})
+
+	It("panics when putting a PRSTREAM frame with a wrong capacity", func() {
+		f := GetPRStreamFrame()
+		f.Data = []byte("foobar")
+		Expect(func() { putPRStreamFrame(f) }).To(Panic())
+	})
 })
+
+// benchmarkHoldFrames simulates a server with 1000 streams,
+// each of them holding a received STREAM frame with a small media slice.
+func benchmarkHoldFrames(b *testing.B, get func() *StreamFrame) {
+	const numStreams = 1000
+	frames := make([]*StreamFrame, numStreams)
+	b.ReportAllocs()
+	var held int
+	for i := 0; i < b.N; i++ {
+		held = 0
+		for j := range frames {
+			frames[j] = get()
+			frames[j].Data = frames[j].Data[:200]
+			held += cap(frames[j].Data)
+		}
+		for _, f := range frames {
+			f.PutBack()
+		}
+	}
+	// the memory used for the buffers of all streams
+	b.ReportMetric(float64(held), "buffer-bytes")
+}
+
+func BenchmarkMaxSizeStreamFrames(b *testing.B) {
+	benchmarkHoldFrames(b, GetStreamFrame)
+}
+
+func BenchmarkSizeClassedStreamFrames(b *testing.B) {
+	benchmarkHoldFrames(b, func() *StreamFrame { return GetStreamFrameWithSize(200) })
+}
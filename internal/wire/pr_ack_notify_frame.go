@@ -33,13 +33,13 @@ type PRAckNotifyFrame struct {
 }
 
 func parsePRAckNotifyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRAckNotifyFrame, error) {
-	typeByte, err := r.ReadByte()
+	typ, err := quicvarint.Read(r)
 	if err != nil {
 		return nil, err
 	}
-	hasOffset := typeByte&0b100 > 0
-	fin := typeByte&0b1 > 0
-	hasDataLen := typeByte&0b10 > 0
+	hasOffset := typ&0b100 > 0
+	fin := typ&0b1 > 0
+	hasDataLen := typ&0b10 > 0
 
 	streamID, err := quicvarint.Read(r)
 	if err != nil {
@@ -106,23 +106,26 @@ func parsePRAckNotifyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRAckNot
 
 // Append writes a PRAckNotify frame
 func (f *PRAckNotifyFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
-	typeByte := byte(0x58)
+	typ := uint64(PRAckNotifyFrameType)
 	if f.Fin {
-		typeByte ^= 0b1
+		typ ^= 0b1
 	}
 	hasOffset := f.Offset != 0
 	if f.DataLenPresent {
-		typeByte ^= 0b10
+		typ ^= 0b10
 	}
 	if hasOffset {
-		typeByte ^= 0b100
+		typ ^= 0b100
 	}
-	b = append(b, typeByte)                      // 1. type
+	b = quicvarint.Append(b, typ)                // 1. type
 	b = quicvarint.Append(b, uint64(f.StreamID)) // 2. StreamID
 
 	//添加存放PTDA信息的字节
-	b = append(b, f.PTDA)                     // 3. PTDA
-	b = quicvarint.Append(b, uint64(f.PtdaC)) // 4.PtdaC
+	b = append(b, f.PTDA)                          // 3. PTDA
+	b, err := quicvarint.AppendChecked(b, f.PtdaC) // 4.PtdaC
+	if err != nil {
+		return nil, err
+	}
 
 	if hasOffset {
 		b = quicvarint.Append(b, uint64(f.Offset)) // 5. Offset
@@ -136,7 +139,7 @@ func (f *PRAckNotifyFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, e
 
 // Length returns the total length of the PRSTREAM frame
 func (f *PRAckNotifyFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
-	length := 1 + quicvarint.Len(uint64(f.StreamID))
+	length := prFrameTypeLen + quicvarint.Len(uint64(f.StreamID))
 	if f.Offset != 0 {
 		length += quicvarint.Len(uint64(f.Offset))
 	}
@@ -22,13 +22,13 @@ type PRDatagramFrame struct {
 }
 
 func parsePRDatagramFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRDatagramFrame, error) {
-	typeByte, err := r.ReadByte()
+	typ, err := quicvarint.Read(r)
 	if err != nil {
 		return nil, err
 	}
 
 	f := &PRDatagramFrame{}
-	f.DataLenPresent = typeByte&0x1 > 0  //最低位为1则存在length字段
+	f.DataLenPresent = typ&0x1 > 0  //最低位为1则存在length字段
 
 	var length uint64
 	if f.DataLenPresent {
@@ -41,8 +41,6 @@ func parsePRDatagramFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRDatagra
 			return nil, io.EOF
 		}
 		length = len
-	} else {
-		length = uint64(r.Len())
 	}
 
 	// 获取PtdaC的信息
@@ -64,6 +62,10 @@ func parsePRDatagramFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRDatagra
 	if err != nil {
 		return nil, err
 	}
+	// without the length field, the data extends to the end of the packet
+	if !f.DataLenPresent {
+		length = uint64(r.Len())
+	}
 
 	f.Data = make([]byte, length)
 	if _, err := io.ReadFull(r, f.Data); err != nil {
@@ -74,26 +76,30 @@ func parsePRDatagramFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRDatagra
 
 // 按照type length PTDA PtdaC data顺序组装帧
 func (f *PRDatagramFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
-	typeByte := uint8(0x52)
+	typ := uint64(PRDatagramFrameType)
 	if f.DataLenPresent {
-		typeByte ^= 0b1  //二进制异或
+		typ ^= 0b1  //二进制异或
 	}
-	b = append(b, typeByte)
+	b = quicvarint.Append(b, typ)
 	if f.DataLenPresent {
 		b = quicvarint.Append(b, uint64(len(f.Data)))
 	}
 
 	//添加存放PTDA信息的字节
-	b = append(b, f.PTDA)  
-	b = append(b, byte(f.PtdaC))
-	
+	b = append(b, f.PTDA)
+	b, err := quicvarint.AppendChecked(b, f.PtdaC)
+	if err != nil {
+		return nil, err
+	}
+
 	b = append(b, f.Data...)
 	return b, nil
 }
 
 // MaxDataLen returns the maximum data length
 func (f *PRDatagramFrame) MaxDataLen(maxSize protocol.ByteCount, version protocol.VersionNumber) protocol.ByteCount {
-	headerLen := protocol.ByteCount(1)
+	// type, PTDA byte and PtdaC
+	headerLen := prFrameTypeLen + 1 + quicvarint.Len(f.PtdaC)
 	if f.DataLenPresent {
 		// pretend that the data size will be 1 bytes
 		// if it turns out that varint encoding the length will consume 2 bytes, we need to adjust the data length afterwards
@@ -111,7 +117,7 @@ func (f *PRDatagramFrame) MaxDataLen(maxSize protocol.ByteCount, version protoco
 
 // Length of a written frame
 func (f *PRDatagramFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
-	length := 1 + protocol.ByteCount(len(f.Data))
+	length := prFrameTypeLen + 1 + quicvarint.Len(f.PtdaC) + protocol.ByteCount(len(f.Data))
 	if f.DataLenPresent {
 		length += quicvarint.Len(uint64(len(f.Data)))
 	}
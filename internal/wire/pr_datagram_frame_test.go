@@ -0,0 +1,57 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR_DATAGRAM frame", func() {
+	Context("round-tripping", func() {
+		frames := map[string]*PRDatagramFrame{
+			"without length": {Data: []byte("foobar"), PTDA: 0x80, PtdaC: 50},
+			"with length":    {Data: []byte("foobar"), DataLenPresent: true, PTDA: 0x20, PtdaC: 100},
+			"large PtdaC":    {Data: []byte("foobar"), DataLenPresent: true, PTDA: 0x40, PtdaC: quicvarint.Max},
+		}
+
+		for name, f := range frames {
+			name, f := name, f
+
+			It("round-trips a frame: "+name, func() {
+				b, err := f.Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(HaveLen(int(f.Length(protocol.Version1))))
+				r := bytes.NewReader(b)
+				frame, err := parsePRDatagramFrame(r, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.DataLenPresent).To(Equal(f.DataLenPresent))
+				Expect(frame.PTDA).To(Equal(f.PTDA))
+				Expect(frame.PtdaC).To(Equal(f.PtdaC))
+				Expect(frame.Data).To(Equal(f.Data))
+				Expect(r.Len()).To(BeZero())
+			})
+		}
+
+		It("refuses to write a PtdaC that doesn't fit into a varint", func() {
+			f := &PRDatagramFrame{Data: []byte("foobar"), PTDA: 0x80, PtdaC: quicvarint.Max + 1}
+			_, err := f.Append(nil, protocol.Version1)
+			Expect(err).To(MatchError(quicvarint.ErrTooLarge))
+		})
+	})
+
+	It("returns a data length such that the resulting frame has the right size", func() {
+		f := &PRDatagramFrame{DataLenPresent: true, PTDA: 0x20, PtdaC: 1000}
+		for i := 3; i < 100; i++ {
+			maxDataLen := f.MaxDataLen(protocol.ByteCount(i), protocol.Version1)
+			if maxDataLen == 0 {
+				continue
+			}
+			f.Data = make([]byte, maxDataLen)
+			Expect(f.Length(protocol.Version1)).To(BeNumerically("<=", i))
+		}
+	})
+})
@@ -0,0 +1,96 @@
+package wire
+
+// The frame types of the frames used for partial reliability.
+// They are not registered with IANA, and use codepoints from the range between the DATAGRAM frame (RFC 9221)
+// and the frames registered by the ACK frequency extension.
+// This is the single source of truth for these codepoints: the frame parser, the Append methods of the frames,
+// and the names used in qlog all refer to these constants.
+//
+// Frame types are variable-length integers (RFC 9000, section 12.4), in all QUIC versions supported by quic-go.
+// Since all PR frame types are larger than 0x3f, they are encoded in prFrameTypeLen bytes, e.g. 0x48 is sent as 0x40 0x48.
+// A single 0x48 byte would be read as the first byte of a 2-byte varint by other QUIC stacks,
+// and the frame parser couldn't tell the PR frames apart from the frames defined in RFC 9000.
+const (
+	// PRStreamFrameType is the type of the PR_STREAM frame.
+	// The range up to PRStreamFrameType+7 is reserved, but only PRStreamFrameType is valid: the flags are sent in a separate byte.
+	PRStreamFrameType = 0x48
+	// PRAckFrameType is the type of the PR_ACK frame.
+	PRAckFrameType = 0x50
+	// PRDatagramFrameType is the type of the PR_DATAGRAM frame without a length field.
+	// PRDatagramFrameType+1 is the type of the PR_DATAGRAM frame with a length field.
+	PRDatagramFrameType = 0x52
+	// PRMessageBoundaryFrameType is the type of the PR_MESSAGE_BOUNDARY frame.
+	PRMessageBoundaryFrameType = 0x54
+	// PRStreamPolicyFrameType is the type of the PR_STREAM_POLICY frame.
+	PRStreamPolicyFrameType = 0x55
+	// PRAckNotifyFrameType is the type of the PR_ACK_NOTIFY frame.
+	// Like for the STREAM frame, the 3 lowest bits encode the FIN, LEN and OFF flags.
+	PRAckNotifyFrameType = 0x58
+)
+
+// prFrameTypeLen is the length of the encoded frame type of all PR frames.
+const prFrameTypeLen = 2
+
+// The highest frame types registered with IANA that are close to the PR frame types:
+// 0x00 - 0x1e (RFC 9000), 0x1f (IMMEDIATE_ACK), 0x24 (RESET_STREAM_AT) and 0x30 - 0x31 (DATAGRAM, RFC 9221) are smaller,
+// 0xaf (ACK_FREQUENCY) is larger.
+const (
+	maxIANAFrameTypeBelowPR = 0x31
+	minIANAFrameTypeAbovePR = 0xaf
+)
+
+// Compile-time assertions that the PR frame types don't collide with each other,
+// nor with the frame types registered with IANA.
+// A negative constant can't be converted to an uint8.
+const (
+	_ = uint8(PRStreamFrameType - maxIANAFrameTypeBelowPR - 1)
+	_ = uint8(PRAckFrameType - (PRStreamFrameType + 7) - 1)
+	_ = uint8(PRDatagramFrameType - PRAckFrameType - 1)
+	_ = uint8(PRMessageBoundaryFrameType - (PRDatagramFrameType + 1) - 1)
+	_ = uint8(PRStreamPolicyFrameType - PRMessageBoundaryFrameType - 1)
+	_ = uint8(PRAckNotifyFrameType - PRStreamPolicyFrameType - 1)
+	_ = uint8(minIANAFrameTypeAbovePR - (PRAckNotifyFrameType + 7) - 1)
+	// all PR frame types are encoded in prFrameTypeLen bytes, i.e. they are in the range 0x40 - 0x3fff
+	_ = uint8(PRStreamFrameType - 0x40)
+	_ = uint16(0x3fff - (PRAckNotifyFrameType + 7))
+	_ = uint8(prFrameTypeLen - 2)
+	_ = uint8(2 - prFrameTypeLen)
+)
+
+type prFrameType struct {
+	// name is the name used in qlog
+	name     string
+	typ      uint64
+	numTypes uint64
+}
+
+var prFrameTypes = [...]prFrameType{
+	{name: "pr_stream", typ: PRStreamFrameType, numTypes: 8},
+	{name: "pr_ack", typ: PRAckFrameType, numTypes: 1},
+	{name: "pr_datagram", typ: PRDatagramFrameType, numTypes: 2},
+	{name: "pr_message_boundary", typ: PRMessageBoundaryFrameType, numTypes: 1},
+	{name: "pr_stream_policy", typ: PRStreamPolicyFrameType, numTypes: 1},
+	{name: "pr_ack_notify", typ: PRAckNotifyFrameType, numTypes: 8},
+}
+
+func lookupPRFrameType(typ uint64) (prFrameType, bool) {
+	for _, t := range prFrameTypes {
+		if typ >= t.typ && typ < t.typ+t.numTypes {
+			return t, true
+		}
+	}
+	return prFrameType{}, false
+}
+
+// IsPRFrameType says if the frame type belongs to one of the frames used for partial reliability.
+func IsPRFrameType(typ uint64) bool {
+	_, ok := lookupPRFrameType(typ)
+	return ok
+}
+
+// PRFrameTypeName returns the name of a frame used for partial reliability, as used in qlog, e.g. "pr_stream".
+// It returns an empty string for all other frame types.
+func PRFrameTypeName(typ uint64) string {
+	t, _ := lookupPRFrameType(typ)
+	return t.name
+}
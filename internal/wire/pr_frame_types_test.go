@@ -0,0 +1,53 @@
+package wire
+
+import (
+	"github.com/lucas-clemente/quic-go/quicvarint"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR frame types", func() {
+	It("recognizes the PR frame types", func() {
+		for typ := uint64(0x48); typ <= 0x4f; typ++ {
+			Expect(IsPRFrameType(typ)).To(BeTrue())
+			Expect(PRFrameTypeName(typ)).To(Equal("pr_stream"))
+		}
+		for typ := uint64(0x58); typ <= 0x5f; typ++ {
+			Expect(IsPRFrameType(typ)).To(BeTrue())
+			Expect(PRFrameTypeName(typ)).To(Equal("pr_ack_notify"))
+		}
+		Expect(PRFrameTypeName(0x50)).To(Equal("pr_ack"))
+		Expect(PRFrameTypeName(0x52)).To(Equal("pr_datagram"))
+		Expect(PRFrameTypeName(0x53)).To(Equal("pr_datagram"))
+		Expect(PRFrameTypeName(0x54)).To(Equal("pr_message_boundary"))
+		Expect(PRFrameTypeName(0x55)).To(Equal("pr_stream_policy"))
+	})
+
+	It("doesn't recognize other frame types", func() {
+		for typ := uint64(0); typ <= maxIANAFrameTypeBelowPR; typ++ {
+			Expect(IsPRFrameType(typ)).To(BeFalse())
+			Expect(PRFrameTypeName(typ)).To(BeEmpty())
+		}
+		for _, typ := range []uint64{0x47, 0x51, 0x56, 0x57, 0x60, minIANAFrameTypeAbovePR} {
+			Expect(IsPRFrameType(typ)).To(BeFalse())
+		}
+	})
+
+	It("encodes all PR frame types in 2 bytes", func() {
+		for _, t := range prFrameTypes {
+			Expect(quicvarint.Len(t.typ)).To(BeEquivalentTo(prFrameTypeLen))
+			Expect(quicvarint.Len(t.typ + t.numTypes - 1)).To(BeEquivalentTo(prFrameTypeLen))
+		}
+	})
+
+	It("doesn't register overlapping frame types", func() {
+		for i, t := range prFrameTypes {
+			Expect(t.typ).To(BeNumerically(">", maxIANAFrameTypeBelowPR))
+			Expect(t.typ + t.numTypes).To(BeNumerically("<=", minIANAFrameTypeAbovePR))
+			if i > 0 {
+				prev := prFrameTypes[i-1]
+				Expect(t.typ).To(BeNumerically(">=", prev.typ+prev.numTypes))
+			}
+		}
+	})
+})
@@ -0,0 +1,55 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// A PRMessageBoundaryFrame is a PR_MESSAGE_BOUNDARY frame.
+// It marks the end of an application message on a stream, at Offset.
+// Since it is sent reliably, the receiver learns about the boundary even if the stream data around it was skipped,
+// and can resume reading at the beginning of the next message.
+//
+//	PR_MESSAGE_BOUNDARY Frame {
+//	  Type (i) = PRMessageBoundaryFrameType,
+//	  Stream ID (i),
+//	  Offset (i),
+//	}
+type PRMessageBoundaryFrame struct {
+	StreamID protocol.StreamID
+	Offset   protocol.ByteCount
+}
+
+func parsePRMessageBoundaryFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRMessageBoundaryFrame, error) {
+	if _, err := quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+
+	sid, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRMessageBoundaryFrame{
+		StreamID: protocol.StreamID(sid),
+		Offset:   protocol.ByteCount(offset),
+	}, nil
+}
+
+func (f *PRMessageBoundaryFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	b = quicvarint.Append(b, PRMessageBoundaryFrameType)
+	b = quicvarint.Append(b, uint64(f.StreamID))
+	b = quicvarint.Append(b, uint64(f.Offset))
+	return b, nil
+}
+
+// Length of a written frame
+func (f *PRMessageBoundaryFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return prFrameTypeLen + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.Offset))
+}
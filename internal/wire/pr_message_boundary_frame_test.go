@@ -0,0 +1,56 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR_MESSAGE_BOUNDARY frame", func() {
+	Context("parsing", func() {
+		It("accepts sample frame", func() {
+			data := encodeVarInt(PRMessageBoundaryFrameType)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // Stream ID
+			data = append(data, encodeVarInt(0x12345678)...) // Offset
+			b := bytes.NewReader(data)
+			frame, err := parsePRMessageBoundaryFrame(b, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
+			Expect(frame.Offset).To(Equal(protocol.ByteCount(0x12345678)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(PRMessageBoundaryFrameType)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // Stream ID
+			data = append(data, encodeVarInt(0x12345678)...) // Offset
+			_, err := parsePRMessageBoundaryFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parsePRMessageBoundaryFrame(bytes.NewReader(data[0:i]), protocol.Version1)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("writing", func() {
+		It("has proper length", func() {
+			f := &PRMessageBoundaryFrame{StreamID: 0x1337, Offset: 0xdeadbeef}
+			Expect(f.Length(protocol.VersionWhatever)).To(Equal(2 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.Offset))))
+		})
+
+		It("writes a sample frame", func() {
+			f := &PRMessageBoundaryFrame{StreamID: 0xdecafbad, Offset: 0xdeadbeefcafe42}
+			expected := encodeVarInt(PRMessageBoundaryFrameType)
+			expected = append(expected, encodeVarInt(0xdecafbad)...)
+			expected = append(expected, encodeVarInt(0xdeadbeefcafe42)...)
+			b, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(expected))
+		})
+	})
+})
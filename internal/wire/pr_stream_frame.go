@@ -3,6 +3,7 @@ package wire
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -27,20 +28,56 @@ type PRStreamFrame struct {
 	fromPool bool
 }
 
+// The PRSTREAM frame has a single frame type.
+// Instead of using the low bits of the frame type like the STREAM frame, it carries a flags byte.
+//
+//	PRSTREAM Frame {
+//	  Type (i) = PRStreamFrameType,
+//	  Stream ID (i),
+//	  Flags (8),
+//	  PTDA (8),
+//	  PtdaC (i),
+//	  [Offset (i)],
+//	  [Length (i)],
+//	  Stream Data (..),
+//	}
+
+// The flags of the PRSTREAM frame. They use the same bits as the STREAM frame type.
+const (
+	prStreamFlagFin    = 0b1
+	prStreamFlagLen    = 0b10
+	prStreamFlagOffset = 0b100
+	prStreamFlagsMask  = prStreamFlagFin | prStreamFlagLen | prStreamFlagOffset
+)
+
+// MaxPRStreamFrameOverhead is the maximum number of bytes that the header of a PRSTREAM frame
+// is longer than the header of a STREAM frame with the same fields:
+// the longer frame type, the flags byte, the PTDA byte and the PtdaC.
+const MaxPRStreamFrameOverhead protocol.ByteCount = prFrameTypeLen - 1 + 2 + 8
+
 func parsePRStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRStreamFrame, error) {
-	typeByte, err := r.ReadByte()
+	typ, err := quicvarint.Read(r)
 	if err != nil {
 		return nil, err
 	}
-
-	hasOffset := typeByte&0b100 > 0
-	fin := typeByte&0b1 > 0
-	hasDataLen := typeByte&0b10 > 0
+	if typ != PRStreamFrameType {
+		return nil, fmt.Errorf("invalid PRSTREAM frame type: %#x", typ)
+	}
 
 	streamID, err := quicvarint.Read(r)
 	if err != nil {
 		return nil, err
 	}
+	flags, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if flags&^prStreamFlagsMask != 0 {
+		return nil, fmt.Errorf("invalid PRSTREAM frame flags: %#x", flags)
+	}
+	hasOffset := flags&prStreamFlagOffset > 0
+	fin := flags&prStreamFlagFin > 0
+	hasDataLen := flags&prStreamFlagLen > 0
 
 	// 获取PtdaC的信息
 	var P bool
@@ -81,6 +118,9 @@ func parsePRStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRStreamFra
 		if err != nil {
 			return nil, err
 		}
+		if dataLen > uint64(r.Len()) {
+			return nil, io.EOF
+		}
 	} else {
 		// The rest of the packet is data
 		dataLen = uint64(r.Len())
@@ -91,9 +131,9 @@ func parsePRStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRStreamFra
 	if dataLen < protocol.MinStreamFrameBufferSize {
 		frame = &PRStreamFrame{Data: make([]byte, dataLen)}
 	} else {
-		frame = GetPRStreamFrame()
+		frame = GetPRStreamFrameWithSize(protocol.ByteCount(dataLen))
 		// The PRSTREAM frame can't be larger than the PRStreamFrame we obtained from the buffer,
-		// since those PRStreamFrames have a buffer length of the maximum packet size.
+		// since the largest PRStreamFrames have a buffer length of the maximum packet size.
 		if dataLen > uint64(cap(frame.Data)) {
 			return nil, io.EOF
 		}
@@ -128,23 +168,27 @@ func (f *PRStreamFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, erro
 		return nil, errors.New("StreamFrame: attempting to write empty frame without FIN")
 	}
 
-	typeByte := byte(0x48)
+	var flags byte
 	if f.Fin {
-		typeByte ^= 0b1
+		flags |= prStreamFlagFin
 	}
 	hasOffset := f.Offset != 0
 	if f.DataLenPresent {
-		typeByte ^= 0b10
+		flags |= prStreamFlagLen
 	}
 	if hasOffset {
-		typeByte ^= 0b100
+		flags |= prStreamFlagOffset
 	}
-	b = append(b, typeByte)
+	b = quicvarint.Append(b, PRStreamFrameType)
 	b = quicvarint.Append(b, uint64(f.StreamID))
+	b = append(b, flags)
 
 	//添加存放PTDA信息的字节
-	b = append(b, f.PTDA)  
-	b = quicvarint.Append(b, uint64(f.PtdaC))
+	b = append(b, f.PTDA)
+	b, err := quicvarint.AppendChecked(b, f.PtdaC)
+	if err != nil {
+		return nil, err
+	}
 
 	if hasOffset {
 		b = quicvarint.Append(b, uint64(f.Offset))
@@ -159,21 +203,23 @@ func (f *PRStreamFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, erro
 
 // Length returns the total length of the PRSTREAM frame
 func (f *PRStreamFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
-	length := 1 + quicvarint.Len(uint64(f.StreamID))
-	if f.Offset != 0 {
-		length += quicvarint.Len(uint64(f.Offset))
-	}
+	length := f.headerLen()
 	if f.DataLenPresent {
 		length += quicvarint.Len(uint64(f.DataLen()))
 	}
-	
-	// 还要加上PR字段的开销
-	length ++   // PTDA字节
-	length += quicvarint.Len(uint64(f.PtdaC))
-
 	return length + f.DataLen()
 }
 
+// headerLen is the length of the header, without the data length
+func (f *PRStreamFrame) headerLen() protocol.ByteCount {
+	// type, flags byte and PTDA byte
+	length := prFrameTypeLen + 2 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(f.PtdaC)
+	if f.Offset != 0 {
+		length += quicvarint.Len(uint64(f.Offset))
+	}
+	return length
+}
+
 // DataLen gives the length of data in bytes
 func (f *PRStreamFrame) DataLen() protocol.ByteCount {
 	return protocol.ByteCount(len(f.Data))
@@ -182,10 +228,7 @@ func (f *PRStreamFrame) DataLen() protocol.ByteCount {
 // MaxDataLen returns the maximum data length
 // If 0 is returned, writing will fail (a STREAM frame must contain at least 1 byte of data).
 func (f *PRStreamFrame) MaxDataLen(maxSize protocol.ByteCount, version protocol.VersionNumber) protocol.ByteCount {
-	headerLen := 1 + quicvarint.Len(uint64(f.StreamID))
-	if f.Offset != 0 {
-		headerLen += quicvarint.Len(uint64(f.Offset))
-	}
+	headerLen := f.headerLen()
 	if f.DataLenPresent {
 		// pretend that the data size will be 1 bytes
 		// if it turns out that varint encoding the length will consume 2 bytes, we need to adjust the data length afterwards
@@ -194,11 +237,6 @@ func (f *PRStreamFrame) MaxDataLen(maxSize protocol.ByteCount, version protocol.
 	if headerLen > maxSize {
 		return 0
 	}
-
-	// PR字段消耗的头部长度
-	headerLen--
-	headerLen -= quicvarint.Len(uint64(f.PtdaC))
-
 	maxDataLen := maxSize - headerLen
 	if f.DataLenPresent && quicvarint.Len(uint64(maxDataLen)) != 1 {
 		maxDataLen--
@@ -221,7 +259,8 @@ func (f *PRStreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version p
 		return nil, true
 	}
 
-	new := GetPRStreamFrame()
+	// f keeps the buffer of the new frame, and only needs to hold the remaining data
+	new := GetPRStreamFrameWithSize(f.DataLen() - n)
 	new.StreamID = f.StreamID
 	new.Offset = f.Offset
 	new.Fin = false
@@ -250,3 +289,51 @@ func (f *PRStreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version p
 func (f *PRStreamFrame) PutBack() {
 	putPRStreamFrame(f)
 }
+
+// ToStreamFrame converts the PRSTREAM frame to a STREAM frame, taking over the data buffer.
+// If the buffer was taken from the pool, the STREAM frame is taken from the pool as well,
+// and the PRSTREAM frame is returned to the pool. f must not be used afterwards.
+func (f *PRStreamFrame) ToStreamFrame() *StreamFrame {
+	var sf *StreamFrame
+	if f.fromPool {
+		sf = GetStreamFrameWithSize(protocol.ByteCount(cap(f.Data)))
+		sf.Data, f.Data = f.Data, sf.Data[:0]
+	} else {
+		sf = &StreamFrame{Data: f.Data}
+	}
+	sf.StreamID = f.StreamID
+	sf.Offset = f.Offset
+	sf.Fin = f.Fin
+	sf.DataLenPresent = f.DataLenPresent
+	// Only return f to the pool after reading its fields, it might be reused right away.
+	if f.fromPool {
+		putPRStreamFrame(f)
+	}
+	return sf
+}
+
+// ToPRStreamFrame converts the STREAM frame to a PRSTREAM frame, taking over the data buffer.
+// The PR fields are not set.
+// If the buffer was taken from the pool, the PRSTREAM frame is taken from the pool as well,
+// and the STREAM frame is returned to the pool. f must not be used afterwards.
+func (f *StreamFrame) ToPRStreamFrame() *PRStreamFrame {
+	var prf *PRStreamFrame
+	if f.fromPool {
+		prf = GetPRStreamFrameWithSize(protocol.ByteCount(cap(f.Data)))
+		prf.Data, f.Data = f.Data, prf.Data[:0]
+	} else {
+		prf = &PRStreamFrame{Data: f.Data}
+	}
+	prf.StreamID = f.StreamID
+	prf.Offset = f.Offset
+	prf.Fin = f.Fin
+	prf.DataLenPresent = f.DataLenPresent
+	prf.PTDA = 0
+	prf.P, prf.T, prf.D, prf.A = false, false, false, false
+	prf.PtdaC = 0
+	// Only return f to the pool after reading its fields, it might be reused right away.
+	if f.fromPool {
+		putStreamFrame(f)
+	}
+	return prf
+}
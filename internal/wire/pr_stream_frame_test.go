@@ -0,0 +1,269 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PRSTREAM frame", func() {
+	Context("when parsing", func() {
+		It("parses a frame with offset", func() {
+			data := encodeVarInt(PRStreamFrameType)
+			data = append(data, encodeVarInt(0x12345)...) // stream ID
+			data = append(data, 0x4)                      // flags
+			data = append(data, 0x20)                     // PTDA
+			data = append(data, encodeVarInt(100)...)     // PtdaC
+			data = append(data, encodeVarInt(0xdecafbad)...)
+			data = append(data, []byte("foobar")...)
+			r := bytes.NewReader(data)
+			frame, err := parsePRStreamFrame(r, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0x12345)))
+			Expect(frame.Offset).To(Equal(protocol.ByteCount(0xdecafbad)))
+			Expect(frame.Data).To(Equal([]byte("foobar")))
+			Expect(frame.Fin).To(BeFalse())
+			Expect(frame.DataLenPresent).To(BeFalse())
+			Expect(frame.PTDA).To(Equal(byte(0x20)))
+			Expect(frame.D).To(BeTrue())
+			Expect(frame.PtdaC).To(BeEquivalentTo(100))
+			Expect(r.Len()).To(BeZero())
+		})
+
+		It("respects the length", func() {
+			data := encodeVarInt(PRStreamFrameType)
+			data = append(data, encodeVarInt(0x12345)...) // stream ID
+			data = append(data, 0x2)                      // flags
+			data = append(data, 0x80)                     // PTDA
+			data = append(data, encodeVarInt(5000)...)    // PtdaC
+			data = append(data, encodeVarInt(4)...)       // data length
+			data = append(data, []byte("foobar")...)
+			r := bytes.NewReader(data)
+			frame, err := parsePRStreamFrame(r, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal([]byte("foob")))
+			Expect(frame.DataLenPresent).To(BeTrue())
+			Expect(frame.Offset).To(BeZero())
+			Expect(frame.P).To(BeTrue())
+			Expect(r.Len()).To(Equal(2))
+		})
+
+		It("parses a frame with FIN", func() {
+			data := encodeVarInt(PRStreamFrameType)
+			data = append(data, encodeVarInt(9)...) // stream ID
+			data = append(data, 0x1)                // flags
+			data = append(data, 0x40)               // PTDA
+			data = append(data, encodeVarInt(3)...) // PtdaC
+			data = append(data, []byte("foobar")...)
+			r := bytes.NewReader(data)
+			frame, err := parsePRStreamFrame(r, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal([]byte("foobar")))
+			Expect(frame.Fin).To(BeTrue())
+			Expect(frame.T).To(BeTrue())
+			Expect(r.Len()).To(BeZero())
+		})
+
+		It("rejects other frame types", func() {
+			for typ := uint64(0x49); typ <= 0x4f; typ++ {
+				data := encodeVarInt(typ)
+				data = append(data, encodeVarInt(9)...) // stream ID
+				data = append(data, 0x0)                // flags
+				data = append(data, 0x80)               // PTDA
+				data = append(data, encodeVarInt(3)...) // PtdaC
+				data = append(data, []byte("foobar")...)
+				_, err := parsePRStreamFrame(bytes.NewReader(data), protocol.Version1)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid PRSTREAM frame type"))
+			}
+		})
+
+		It("rejects unknown flags", func() {
+			data := encodeVarInt(PRStreamFrameType)
+			data = append(data, encodeVarInt(9)...) // stream ID
+			data = append(data, 0x8)                // flags
+			data = append(data, 0x80)               // PTDA
+			data = append(data, encodeVarInt(3)...) // PtdaC
+			data = append(data, []byte("foobar")...)
+			_, err := parsePRStreamFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).To(MatchError("invalid PRSTREAM frame flags: 0x8"))
+		})
+
+		It("rejects frames that overflow the maximum offset", func() {
+			data := encodeVarInt(PRStreamFrameType)
+			data = append(data, encodeVarInt(0x12345)...) // stream ID
+			data = append(data, 0x4)                      // flags
+			data = append(data, 0x80)                     // PTDA
+			data = append(data, encodeVarInt(0)...)       // PtdaC
+			data = append(data, encodeVarInt(uint64(protocol.MaxByteCount-5))...)
+			data = append(data, []byte("foobar")...)
+			_, err := parsePRStreamFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).To(MatchError("PRstream data overflows maximum offset"))
+		})
+
+		It("rejects frames that claim to be longer than the packet size", func() {
+			data := encodeVarInt(PRStreamFrameType)
+			data = append(data, encodeVarInt(0x12345)...) // stream ID
+			data = append(data, 0x2)                      // flags
+			data = append(data, 0x80)                     // PTDA
+			data = append(data, encodeVarInt(0)...)       // PtdaC
+			data = append(data, encodeVarInt(7)...)       // data length
+			data = append(data, []byte("foobar")...)
+			_, err := parsePRStreamFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		It("errors on EOFs", func() {
+			f := &PRStreamFrame{
+				StreamID:       0x1337,
+				Offset:         0xdeadbeef,
+				DataLenPresent: true,
+				PTDA:           0x20,
+				PtdaC:          1000,
+				Data:           []byte("foobar"),
+			}
+			data, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = parsePRStreamFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parsePRStreamFrame(bytes.NewReader(data[0:i]), protocol.Version1)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("round-tripping", func() {
+		frames := map[string]*PRStreamFrame{
+			"offset 0":                 {StreamID: 4, Data: []byte("foobar"), PTDA: 0x80, PtdaC: 5000},
+			"offset 0, with length":    {StreamID: 4, Data: []byte("foobar"), DataLenPresent: true, PTDA: 0x40, PtdaC: 3},
+			"with offset":              {StreamID: 4, Offset: 0x1337, Data: []byte("foobar"), PTDA: 0x20, PtdaC: 100},
+			"with offset and length":   {StreamID: 4, Offset: 0x1337, Data: []byte("foobar"), DataLenPresent: true, PTDA: 0x10, PtdaC: 7},
+			"FIN only":                 {StreamID: 4, Offset: 0x1337, Fin: true, PTDA: 0x80},
+			"FIN only, offset 0":       {StreamID: 4, Fin: true, PTDA: 0x80},
+			"FIN only, with length":    {StreamID: 4, Offset: 0x1337, Fin: true, DataLenPresent: true, PTDA: 0x80},
+			"FIN with data":            {StreamID: 4, Offset: 0x1337, Fin: true, Data: []byte("foobar"), PTDA: 0x20, PtdaC: 1 << 40},
+			"FIN with data, length":    {StreamID: 4, Fin: true, Data: []byte("foobar"), DataLenPresent: true, PTDA: 0x08},
+			"large stream ID and PTDA": {StreamID: 1 << 50, Offset: 1 << 40, Data: []byte("foobar"), DataLenPresent: true, PTDA: 0x80, PtdaC: quicvarint.Max},
+		}
+
+		for name, f := range frames {
+			name, f := name, f
+
+			It("round-trips a frame: "+name, func() {
+				b, err := f.Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(HaveLen(int(f.Length(protocol.Version1))))
+				// append some data, which must be ignored if the data length is present
+				if f.DataLenPresent {
+					b = append(b, []byte("trailer")...)
+				}
+				r := bytes.NewReader(b)
+				frame, err := parsePRStreamFrame(r, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.StreamID).To(Equal(f.StreamID))
+				Expect(frame.Offset).To(Equal(f.Offset))
+				Expect(frame.Fin).To(Equal(f.Fin))
+				Expect(frame.DataLenPresent).To(Equal(f.DataLenPresent))
+				Expect(frame.PTDA).To(Equal(f.PTDA))
+				Expect(frame.PtdaC).To(Equal(f.PtdaC))
+				Expect(frame.Data).To(HaveLen(len(f.Data)))
+				if len(f.Data) > 0 {
+					Expect(frame.Data).To(Equal(f.Data))
+				}
+				if f.DataLenPresent {
+					Expect(r.Len()).To(Equal(len("trailer")))
+				} else {
+					Expect(r.Len()).To(BeZero())
+				}
+			})
+		}
+
+		It("refuses to write an empty frame without FIN", func() {
+			f := &PRStreamFrame{StreamID: 0x42, Offset: 0x1337, PTDA: 0x80}
+			_, err := f.Append(nil, protocol.Version1)
+			Expect(err).To(MatchError("StreamFrame: attempting to write empty frame without FIN"))
+		})
+
+		It("refuses to write a PtdaC that doesn't fit into a varint", func() {
+			f := &PRStreamFrame{StreamID: 0x42, Data: []byte("foobar"), PTDA: 0x80, PtdaC: quicvarint.Max + 1}
+			_, err := f.Append(nil, protocol.Version1)
+			Expect(err).To(MatchError(quicvarint.ErrTooLarge))
+		})
+
+		It("is at most MaxPRStreamFrameOverhead longer than the STREAM frame", func() {
+			f := &StreamFrame{StreamID: 0x1337, Offset: 0xdeadbeef, Data: []byte("foobar"), DataLenPresent: true}
+			prf := &PRStreamFrame{StreamID: 0x1337, Offset: 0xdeadbeef, Data: []byte("foobar"), DataLenPresent: true, PTDA: 0x80, PtdaC: quicvarint.Max}
+			Expect(prf.Length(protocol.Version1) - f.Length(protocol.Version1)).To(Equal(MaxPRStreamFrameOverhead))
+		})
+	})
+
+	Context("max data length", func() {
+		const maxSize = 3000
+
+		It("always returns a data length such that the resulting frame has the right size, if data length is not present", func() {
+			data := make([]byte, maxSize)
+			f := &PRStreamFrame{
+				StreamID: 0x1337,
+				Offset:   0xdeadbeef,
+				PTDA:     0x20,
+				PtdaC:    1000,
+			}
+			for i := 1; i < 3000; i++ {
+				f.Data = nil
+				maxDataLen := f.MaxDataLen(protocol.ByteCount(i), protocol.Version1)
+				if maxDataLen == 0 { // 0 means that no valid PRSTREAM frame can be written
+					// check that writing a minimal size PRSTREAM frame (i.e. with 1 byte data) is actually larger than the desired size
+					f.Data = []byte{0}
+					b, err := f.Append(nil, protocol.Version1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(len(b)).To(BeNumerically(">", i))
+					continue
+				}
+				f.Data = data[:int(maxDataLen)]
+				b, err := f.Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(b)).To(Equal(i))
+			}
+		})
+
+		It("always returns a data length such that the resulting frame has the right size, if data length is present", func() {
+			data := make([]byte, maxSize)
+			f := &PRStreamFrame{
+				StreamID:       0x1337,
+				Offset:         0xdeadbeef,
+				DataLenPresent: true,
+				PTDA:           0x20,
+				PtdaC:          1000,
+			}
+			var frameOneByteTooSmallCounter int
+			for i := 1; i < 3000; i++ {
+				f.Data = nil
+				maxDataLen := f.MaxDataLen(protocol.ByteCount(i), protocol.Version1)
+				if maxDataLen == 0 { // 0 means that no valid PRSTREAM frame can be written
+					f.Data = []byte{0}
+					b, err := f.Append(nil, protocol.Version1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(len(b)).To(BeNumerically(">", i))
+					continue
+				}
+				f.Data = data[:int(maxDataLen)]
+				b, err := f.Append(nil, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				// There's *one* pathological case, where a data length of x can be encoded into 1 byte
+				// but a data lengths of x+1 needs 2 bytes
+				if len(b) == i-1 {
+					frameOneByteTooSmallCounter++
+					continue
+				}
+				Expect(len(b)).To(Equal(i))
+			}
+			Expect(frameOneByteTooSmallCounter).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// A PRStreamPolicyFrame is a PR_STREAM_POLICY frame.
+// It announces that the sender changed the PR policy of a stream while data was already flowing.
+// The new policy applies to the data starting at Offset. Data below Offset keeps the policy it was sent with.
+//
+//	PR_STREAM_POLICY Frame {
+//	  Type (i) = PRStreamPolicyFrameType,
+//	  Stream ID (i),
+//	  Offset (i),
+//	  PTDA (8),
+//	  Value (i),
+//	}
+type PRStreamPolicyFrame struct {
+	StreamID protocol.StreamID
+	Offset   protocol.ByteCount
+	PTDA     byte
+	Value    uint64
+}
+
+func parsePRStreamPolicyFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PRStreamPolicyFrame, error) {
+	if _, err := quicvarint.Read(r); err != nil {
+		return nil, err
+	}
+
+	sid, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	ptda, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	value, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PRStreamPolicyFrame{
+		StreamID: protocol.StreamID(sid),
+		Offset:   protocol.ByteCount(offset),
+		PTDA:     ptda,
+		Value:    value,
+	}, nil
+}
+
+func (f *PRStreamPolicyFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	b = quicvarint.Append(b, PRStreamPolicyFrameType)
+	b = quicvarint.Append(b, uint64(f.StreamID))
+	b = quicvarint.Append(b, uint64(f.Offset))
+	b = append(b, f.PTDA)
+	b = quicvarint.Append(b, f.Value)
+	return b, nil
+}
+
+// Length of a written frame
+func (f *PRStreamPolicyFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return prFrameTypeLen + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.Offset)) + 1 + quicvarint.Len(f.Value)
+}
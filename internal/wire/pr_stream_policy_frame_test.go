@@ -0,0 +1,64 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR_STREAM_POLICY frame", func() {
+	Context("parsing", func() {
+		It("accepts sample frame", func() {
+			data := encodeVarInt(PRStreamPolicyFrameType)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // Stream ID
+			data = append(data, encodeVarInt(0x12345678)...) // Offset
+			data = append(data, 0x20)                        // PTDA
+			data = append(data, encodeVarInt(150)...)        // Value
+			b := bytes.NewReader(data)
+			frame, err := parsePRStreamPolicyFrame(b, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
+			Expect(frame.Offset).To(Equal(protocol.ByteCount(0x12345678)))
+			Expect(frame.PTDA).To(Equal(byte(0x20)))
+			Expect(frame.Value).To(Equal(uint64(150)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(PRStreamPolicyFrameType)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // Stream ID
+			data = append(data, encodeVarInt(0x12345678)...) // Offset
+			data = append(data, 0x20)                        // PTDA
+			data = append(data, encodeVarInt(150)...)        // Value
+			_, err := parsePRStreamPolicyFrame(bytes.NewReader(data), protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parsePRStreamPolicyFrame(bytes.NewReader(data[0:i]), protocol.Version1)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("writing", func() {
+		It("has proper length", func() {
+			f := &PRStreamPolicyFrame{StreamID: 0x1337, Offset: 0xdeadbeef, PTDA: 0x40, Value: 1000}
+			Expect(f.Length(protocol.VersionWhatever)).To(Equal(2 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.Offset)) + 1 + quicvarint.Len(f.Value)))
+		})
+
+		It("writes a sample frame", func() {
+			f := &PRStreamPolicyFrame{StreamID: 0xdecafbad, Offset: 0xdeadbeefcafe42, PTDA: 0x80, Value: 5000}
+			expected := encodeVarInt(PRStreamPolicyFrameType)
+			expected = append(expected, encodeVarInt(0xdecafbad)...)
+			expected = append(expected, encodeVarInt(0xdeadbeefcafe42)...)
+			expected = append(expected, 0x80)
+			expected = append(expected, encodeVarInt(5000)...)
+			b, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(expected))
+		})
+	})
+})
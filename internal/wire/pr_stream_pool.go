@@ -6,19 +6,33 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
-var prStreamFramepool sync.Pool
+// PRSTREAM frames use the same buffer sizes as STREAM frames.
+var prStreamFramepool [len(frameBufferSizes)]sync.Pool
 
 func init() {
-	prStreamFramepool.New = func() interface{} {
-		return &PRStreamFrame{
-			Data:     make([]byte, 0, protocol.MaxPacketBufferSize),
-			fromPool: true,
+	for i := range prStreamFramepool {
+		size := frameBufferSizes[i]
+		prStreamFramepool[i].New = func() interface{} {
+			return &PRStreamFrame{
+				Data:     make([]byte, 0, size),
+				fromPool: true,
+			}
 		}
 	}
 }
 
+// GetPRStreamFrame returns a PRSTREAM frame that can hold data of the maximum packet size.
 func GetPRStreamFrame() *PRStreamFrame {
-	f := prStreamFramepool.Get().(*PRStreamFrame)
+	f := prStreamFramepool[len(prStreamFramepool)-1].Get().(*PRStreamFrame)
+	auditGet(f)
+	return f
+}
+
+// GetPRStreamFrameWithSize returns a PRSTREAM frame that can hold at least l bytes of data,
+// unless l is larger than the maximum packet size.
+func GetPRStreamFrameWithSize(l protocol.ByteCount) *PRStreamFrame {
+	f := prStreamFramepool[sizeClass(l)].Get().(*PRStreamFrame)
+	auditGet(f)
 	return f
 }
 
@@ -26,8 +40,6 @@ func putPRStreamFrame(f *PRStreamFrame) {
 	if !f.fromPool {
 		return
 	}
-	if protocol.ByteCount(cap(f.Data)) != protocol.MaxPacketBufferSize {
-		panic("wire.PutStreamFrame called with packet of wrong size!")
-	}
-	prStreamFramepool.Put(f)
+	auditPut(f)
+	prStreamFramepool[sizeClassOfBuffer(cap(f.Data))].Put(f)
 }
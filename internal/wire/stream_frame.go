@@ -58,9 +58,9 @@ func parseStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*StreamFrame,
 	if dataLen < protocol.MinStreamFrameBufferSize {
 		frame = &StreamFrame{Data: make([]byte, dataLen)}
 	} else {
-		frame = GetStreamFrame()
+		frame = GetStreamFrameWithSize(protocol.ByteCount(dataLen))
 		// The STREAM frame can't be larger than the StreamFrame we obtained from the buffer,
-		// since those StreamFrames have a buffer length of the maximum packet size.
+		// since the largest StreamFrames have a buffer length of the maximum packet size.
 		if dataLen > uint64(cap(frame.Data)) {
 			return nil, io.EOF
 		}
@@ -166,7 +166,8 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 		return nil, true
 	}
 
-	new := GetStreamFrame()
+	// f keeps the buffer of the new frame, and only needs to hold the remaining data
+	new := GetStreamFrameWithSize(f.DataLen() - n)
 	new.StreamID = f.StreamID
 	new.Offset = f.Offset
 	new.Fin = false
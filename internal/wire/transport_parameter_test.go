@@ -102,6 +102,8 @@ var _ = Describe("Transport Parameters", func() {
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         getRandomValue(),
 			MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+			PartialReliability:              true,
+			MaxSkipRatio:                    uint64(getRandomValueUpTo(protocol.SkipRatioScale)),
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -123,6 +125,30 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
 		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
+		Expect(p.PartialReliability).To(BeTrue())
+		Expect(p.MaxSkipRatio).To(Equal(params.MaxSkipRatio))
+	})
+
+	It("doesn't marshal the partial_reliability, if partial reliability is not supported", func() {
+		data := (&TransportParameters{MaxDatagramFrameSize: protocol.InvalidByteCount}).Marshal(protocol.PerspectiveClient)
+		Expect(bytes.Contains(data, quicvarint.Append(nil, uint64(partialReliabilityParameterID)))).To(BeFalse())
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+		Expect(p.PartialReliability).To(BeFalse())
+		Expect(bytes.Contains(data, quicvarint.Append(nil, uint64(maxSkipRatioParameterID)))).To(BeFalse())
+		Expect(p.MaxSkipRatio).To(BeZero())
+	})
+
+	It("errors when the max_skip_ratio is too large", func() {
+		data := (&TransportParameters{
+			MaxSkipRatio:        protocol.SkipRatioScale + 1,
+			StatelessResetToken: &protocol.StatelessResetToken{},
+		}).Marshal(protocol.PerspectiveServer)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "invalid value for max_skip_ratio: 10001 (maximum 10000)",
+		}))
 	})
 
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
@@ -179,6 +205,17 @@ var _ = Describe("Transport Parameters", func() {
 		}))
 	})
 
+	It("errors when partial_reliability has content", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, uint64(partialReliabilityParameterID))
+		quicvarint.Write(b, 6)
+		b.Write([]byte("foobar"))
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "wrong length for partial_reliability: 6 (expected empty)",
+		}))
+	})
+
 	It("errors when the server doesn't set the original_destination_connection_id", func() {
 		b := &bytes.Buffer{}
 		quicvarint.Write(b, uint64(statelessResetTokenParameterID))
@@ -484,6 +521,8 @@ var _ = Describe("Transport Parameters", func() {
 				MaxBidiStreamNum:               protocol.StreamNum(getRandomValueUpTo(int64(protocol.MaxStreamCount))),
 				MaxUniStreamNum:                protocol.StreamNum(getRandomValueUpTo(int64(protocol.MaxStreamCount))),
 				ActiveConnectionIDLimit:        getRandomValue(),
+				PartialReliability:             true,
+				MaxSkipRatio:                   1234,
 			}
 			Expect(params.ValidFor0RTT(params)).To(BeTrue())
 			b := params.MarshalForSessionTicket(nil)
@@ -496,6 +535,8 @@ var _ = Describe("Transport Parameters", func() {
 			Expect(tp.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
 			Expect(tp.MaxUniStreamNum).To(Equal(params.MaxUniStreamNum))
 			Expect(tp.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+			Expect(tp.PartialReliability).To(BeTrue())
+			Expect(tp.MaxSkipRatio).To(Equal(params.MaxSkipRatio))
 		})
 
 		It("rejects the parameters if it can't parse them", func() {
@@ -522,6 +563,8 @@ var _ = Describe("Transport Parameters", func() {
 				MaxBidiStreamNum:               5,
 				MaxUniStreamNum:                6,
 				ActiveConnectionIDLimit:        7,
+				PartialReliability:             true,
+				MaxSkipRatio:                   8,
 			}
 
 			BeforeEach(func() {
@@ -593,6 +636,35 @@ var _ = Describe("Transport Parameters", func() {
 				p.ActiveConnectionIDLimit = 0
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
 			})
+
+			It("rejects the parameters if partial reliability was disabled", func() {
+				p.PartialReliability = false
+				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+			})
+
+			It("accepts the parameters if partial reliability was enabled", func() {
+				saved := *saved
+				saved.PartialReliability = false
+				Expect(p.ValidFor0RTT(&saved)).To(BeTrue())
+			})
+
+			It("rejects the parameters if the MaxSkipRatio was reduced", func() {
+				p.MaxSkipRatio = saved.MaxSkipRatio - 1
+				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+			})
+
+			It("rejects the parameters if the skip ratio was limited", func() {
+				saved := *saved
+				saved.MaxSkipRatio = 0
+				Expect(p.ValidFor0RTT(&saved)).To(BeFalse())
+			})
+
+			It("accepts the parameters if the MaxSkipRatio was increased", func() {
+				p.MaxSkipRatio = saved.MaxSkipRatio + 1
+				Expect(p.ValidFor0RTT(saved)).To(BeTrue())
+				p.MaxSkipRatio = 0
+				Expect(p.ValidFor0RTT(saved)).To(BeTrue())
+			})
 		})
 	})
 })
@@ -45,6 +45,9 @@ const (
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
 	// RFC 9221
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// partial reliability, see PRConfig
+	partialReliabilityParameterID transportParameterID = 0x7072
+	maxSkipRatioParameterID       transportParameterID = 0x7073
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -86,6 +89,12 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	// PartialReliability says if the endpoint accepts the frames used for partially reliable streams.
+	PartialReliability bool
+	// MaxSkipRatio is the maximum fraction of the data of a stream that the endpoint accepts to be skipped,
+	// in units of 1/protocol.SkipRatioScale. 0 means that the fraction is not limited.
+	MaxSkipRatio uint64
 }
 
 // Unmarshal the transport parameters
@@ -138,6 +147,7 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			maxAckDelayParameterID,
 			activeConnectionIDLimitParameterID,
 			maxDatagramFrameSizeParameterID,
+			maxSkipRatioParameterID,
 			ackDelayExponentParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
@@ -154,6 +164,11 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 				return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 			}
 			p.DisableActiveMigration = true
+		case partialReliabilityParameterID:
+			if paramLen != 0 {
+				return fmt.Errorf("wrong length for partial_reliability: %d (expected empty)", paramLen)
+			}
+			p.PartialReliability = true
 		case statelessResetTokenParameterID:
 			if sentBy == protocol.PerspectiveClient {
 				return errors.New("client sent a stateless_reset_token")
@@ -306,6 +321,11 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.ActiveConnectionIDLimit = val
 	case maxDatagramFrameSizeParameterID:
 		p.MaxDatagramFrameSize = protocol.ByteCount(val)
+	case maxSkipRatioParameterID:
+		if val > protocol.SkipRatioScale {
+			return fmt.Errorf("invalid value for max_skip_ratio: %d (maximum %d)", val, protocol.SkipRatioScale)
+		}
+		p.MaxSkipRatio = val
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -399,6 +419,15 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		b = p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
+	// partial_reliability
+	if p.PartialReliability {
+		b = quicvarint.Append(b, uint64(partialReliabilityParameterID))
+		b = quicvarint.Append(b, 0)
+	}
+	// max_skip_ratio
+	if p.MaxSkipRatio > 0 {
+		b = p.marshalVarintParam(b, maxSkipRatioParameterID, p.MaxSkipRatio)
+	}
 	return b
 }
 
@@ -432,7 +461,17 @@ func (p *TransportParameters) MarshalForSessionTicket(b []byte) []byte {
 	// initial_max_uni_streams
 	b = p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
 	// active_connection_id_limit
-	return p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	b = p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	// partial_reliability
+	if p.PartialReliability {
+		b = quicvarint.Append(b, uint64(partialReliabilityParameterID))
+		b = quicvarint.Append(b, 0)
+	}
+	// max_skip_ratio
+	if p.MaxSkipRatio > 0 {
+		b = p.marshalVarintParam(b, maxSkipRatioParameterID, p.MaxSkipRatio)
+	}
+	return b
 }
 
 // UnmarshalFromSessionTicket unmarshals transport parameters from a session ticket.
@@ -455,7 +494,9 @@ func (p *TransportParameters) ValidFor0RTT(saved *TransportParameters) bool {
 		p.InitialMaxData >= saved.InitialMaxData &&
 		p.MaxBidiStreamNum >= saved.MaxBidiStreamNum &&
 		p.MaxUniStreamNum >= saved.MaxUniStreamNum &&
-		p.ActiveConnectionIDLimit == saved.ActiveConnectionIDLimit
+		p.ActiveConnectionIDLimit == saved.ActiveConnectionIDLimit &&
+		(p.PartialReliability || !saved.PartialReliability) &&
+		(p.MaxSkipRatio == 0 || (saved.MaxSkipRatio > 0 && p.MaxSkipRatio >= saved.MaxSkipRatio))
 }
 
 // String returns a string representation, intended for logging.
@@ -476,6 +517,13 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.PartialReliability {
+		logString += ", PartialReliability: true"
+	}
+	if p.MaxSkipRatio > 0 {
+		logString += ", MaxSkipRatio: %d"
+		logParams = append(logParams, p.MaxSkipRatio)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
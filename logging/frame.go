@@ -60,6 +60,36 @@ type StreamFrame struct {
 	Fin      bool
 }
 
+// A PRStreamFrame is a PR_STREAM frame, carrying the data of a partially reliable stream.
+type PRStreamFrame struct {
+	StreamID StreamID
+	Offset   ByteCount
+	Length   ByteCount
+	Fin      bool
+	// PTDA and Value describe the PR policy of the data.
+	PTDA  byte
+	Value uint64
+}
+
+// A PRAckNotifyFrame is a PR_ACK_NOTIFY frame.
+// It tells the peer to skip stream data that won't be retransmitted.
+type PRAckNotifyFrame = wire.PRAckNotifyFrame
+
+// A PRDatagramFrame is a PR_DATAGRAM frame.
+type PRDatagramFrame struct {
+	Length ByteCount
+	// PTDA and Value describe the PR policy of the datagram.
+	PTDA  byte
+	Value uint64
+}
+
+type (
+	// A PRMessageBoundaryFrame is a PR_MESSAGE_BOUNDARY frame.
+	PRMessageBoundaryFrame = wire.PRMessageBoundaryFrame
+	// A PRStreamPolicyFrame is a PR_STREAM_POLICY frame.
+	PRStreamPolicyFrame = wire.PRStreamPolicyFrame
+)
+
 // A DatagramFrame is a DATAGRAM frame.
 type DatagramFrame struct {
 	Length ByteCount
@@ -140,6 +140,23 @@ type ConnectionTracer interface {
 	SetLossTimer(TimerType, EncryptionLevel, time.Time)
 	LossTimerExpired(TimerType, EncryptionLevel)
 	LossTimerCanceled()
+	// CanceledIdleStream is called when a PR stream is canceled because it was idle for too long.
+	CanceledIdleStream(StreamID)
+	// SpuriousPRConversion is called when a PR STREAM frame that was skipped after being declared lost is acknowledged after all.
+	// notifyCanceled says if the PRAckNotify frame for the data was canceled before it was sent.
+	SpuriousPRConversion(id StreamID, offset, length ByteCount, notifyCanceled bool)
+	// UpdatedPRPolicy is called when the PR policy of a stream changes after data was sent on the stream.
+	// The new policy (given by its PTDA and value) applies to the data starting at offset.
+	// remote says if the change was announced by the peer.
+	UpdatedPRPolicy(id StreamID, offset ByteCount, ptda uint8, value uint64, remote bool)
+	// DisabledPR is called when partial reliability is disabled for the connection, although it is enabled in the config.
+	DisabledPR(PRDisabledReason)
+	// SetPRRandSeed is called when the connection starts, with the seed of the random number generator
+	// that decides if lost data sent with the probability policy is retransmitted.
+	SetPRRandSeed(seed int64)
+	// AmplificationLimited is called when the server stops sending, because it reached the anti-amplification limit.
+	// It can only resume sending once it receives more data from the client, or the client's address is validated.
+	AmplificationLimited(bytesSent, bytesReceived ByteCount)
 	// Close is called when the connection is closed.
 	Close()
 	Debug(name, msg string)
@@ -50,6 +50,18 @@ func (mr *MockConnectionTracerMockRecorder) AcknowledgedPacket(arg0, arg1 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcknowledgedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).AcknowledgedPacket), arg0, arg1)
 }
 
+// AmplificationLimited mocks base method.
+func (m *MockConnectionTracer) AmplificationLimited(arg0, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AmplificationLimited", arg0, arg1)
+}
+
+// AmplificationLimited indicates an expected call of AmplificationLimited.
+func (mr *MockConnectionTracerMockRecorder) AmplificationLimited(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AmplificationLimited", reflect.TypeOf((*MockConnectionTracer)(nil).AmplificationLimited), arg0, arg1)
+}
+
 // BufferedPacket mocks base method.
 func (m *MockConnectionTracer) BufferedPacket(arg0 PacketType) {
 	m.ctrl.T.Helper()
@@ -62,6 +74,18 @@ func (mr *MockConnectionTracerMockRecorder) BufferedPacket(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).BufferedPacket), arg0)
 }
 
+// CanceledIdleStream mocks base method.
+func (m *MockConnectionTracer) CanceledIdleStream(arg0 protocol.StreamID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CanceledIdleStream", arg0)
+}
+
+// CanceledIdleStream indicates an expected call of CanceledIdleStream.
+func (mr *MockConnectionTracerMockRecorder) CanceledIdleStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CanceledIdleStream", reflect.TypeOf((*MockConnectionTracer)(nil).CanceledIdleStream), arg0)
+}
+
 // Close mocks base method.
 func (m *MockConnectionTracer) Close() {
 	m.ctrl.T.Helper()
@@ -98,6 +122,18 @@ func (mr *MockConnectionTracerMockRecorder) Debug(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Debug", reflect.TypeOf((*MockConnectionTracer)(nil).Debug), arg0, arg1)
 }
 
+// DisabledPR mocks base method.
+func (m *MockConnectionTracer) DisabledPR(arg0 PRDisabledReason) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DisabledPR", arg0)
+}
+
+// DisabledPR indicates an expected call of DisabledPR.
+func (mr *MockConnectionTracerMockRecorder) DisabledPR(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisabledPR", reflect.TypeOf((*MockConnectionTracer)(nil).DisabledPR), arg0)
+}
+
 // DroppedEncryptionLevel mocks base method.
 func (m *MockConnectionTracer) DroppedEncryptionLevel(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -290,6 +326,30 @@ func (mr *MockConnectionTracerMockRecorder) SetLossTimer(arg0, arg1, arg2 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLossTimer", reflect.TypeOf((*MockConnectionTracer)(nil).SetLossTimer), arg0, arg1, arg2)
 }
 
+// SetPRRandSeed mocks base method.
+func (m *MockConnectionTracer) SetPRRandSeed(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPRRandSeed", arg0)
+}
+
+// SetPRRandSeed indicates an expected call of SetPRRandSeed.
+func (mr *MockConnectionTracerMockRecorder) SetPRRandSeed(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPRRandSeed", reflect.TypeOf((*MockConnectionTracer)(nil).SetPRRandSeed), arg0)
+}
+
+// SpuriousPRConversion mocks base method.
+func (m *MockConnectionTracer) SpuriousPRConversion(arg0 protocol.StreamID, arg1 protocol.ByteCount, arg2 protocol.ByteCount, arg3 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SpuriousPRConversion", arg0, arg1, arg2, arg3)
+}
+
+// SpuriousPRConversion indicates an expected call of SpuriousPRConversion.
+func (mr *MockConnectionTracerMockRecorder) SpuriousPRConversion(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpuriousPRConversion", reflect.TypeOf((*MockConnectionTracer)(nil).SpuriousPRConversion), arg0, arg1, arg2, arg3)
+}
+
 // StartedConnection mocks base method.
 func (m *MockConnectionTracer) StartedConnection(arg0, arg1 net.Addr, arg2, arg3 protocol.ConnectionID) {
 	m.ctrl.T.Helper()
@@ -350,6 +410,18 @@ func (mr *MockConnectionTracerMockRecorder) UpdatedMetrics(arg0, arg1, arg2, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedMetrics", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedMetrics), arg0, arg1, arg2, arg3)
 }
 
+// UpdatedPRPolicy mocks base method.
+func (m *MockConnectionTracer) UpdatedPRPolicy(arg0 protocol.StreamID, arg1 protocol.ByteCount, arg2 uint8, arg3 uint64, arg4 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatedPRPolicy", arg0, arg1, arg2, arg3, arg4)
+}
+
+// UpdatedPRPolicy indicates an expected call of UpdatedPRPolicy.
+func (mr *MockConnectionTracerMockRecorder) UpdatedPRPolicy(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatedPRPolicy", reflect.TypeOf((*MockConnectionTracer)(nil).UpdatedPRPolicy), arg0, arg1, arg2, arg3, arg4)
+}
+
 // UpdatedPTOCount mocks base method.
 func (m *MockConnectionTracer) UpdatedPTOCount(arg0 uint32) {
 	m.ctrl.T.Helper()
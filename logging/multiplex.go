@@ -218,6 +218,42 @@ func (m *connTracerMultiplexer) LossTimerCanceled() {
 	}
 }
 
+func (m *connTracerMultiplexer) CanceledIdleStream(id StreamID) {
+	for _, t := range m.tracers {
+		t.CanceledIdleStream(id)
+	}
+}
+
+func (m *connTracerMultiplexer) SpuriousPRConversion(id StreamID, offset, length ByteCount, notifyCanceled bool) {
+	for _, t := range m.tracers {
+		t.SpuriousPRConversion(id, offset, length, notifyCanceled)
+	}
+}
+
+func (m *connTracerMultiplexer) UpdatedPRPolicy(id StreamID, offset ByteCount, ptda uint8, value uint64, remote bool) {
+	for _, t := range m.tracers {
+		t.UpdatedPRPolicy(id, offset, ptda, value, remote)
+	}
+}
+
+func (m *connTracerMultiplexer) DisabledPR(reason PRDisabledReason) {
+	for _, t := range m.tracers {
+		t.DisabledPR(reason)
+	}
+}
+
+func (m *connTracerMultiplexer) SetPRRandSeed(seed int64) {
+	for _, t := range m.tracers {
+		t.SetPRRandSeed(seed)
+	}
+}
+
+func (m *connTracerMultiplexer) AmplificationLimited(bytesSent, bytesReceived ByteCount) {
+	for _, t := range m.tracers {
+		t.AmplificationLimited(bytesSent, bytesReceived)
+	}
+}
+
 func (m *connTracerMultiplexer) Debug(name, msg string) {
 	for _, t := range m.tracers {
 		t.Debug(name, msg)
@@ -284,6 +284,42 @@ var _ = Describe("Tracing", func() {
 			tracer.LossTimerCanceled()
 		})
 
+		It("traces the CanceledIdleStream event", func() {
+			tr1.EXPECT().CanceledIdleStream(protocol.StreamID(4))
+			tr2.EXPECT().CanceledIdleStream(protocol.StreamID(4))
+			tracer.CanceledIdleStream(4)
+		})
+
+		It("traces the SpuriousPRConversion event", func() {
+			tr1.EXPECT().SpuriousPRConversion(protocol.StreamID(4), protocol.ByteCount(100), protocol.ByteCount(200), true)
+			tr2.EXPECT().SpuriousPRConversion(protocol.StreamID(4), protocol.ByteCount(100), protocol.ByteCount(200), true)
+			tracer.SpuriousPRConversion(4, 100, 200, true)
+		})
+
+		It("traces the UpdatedPRPolicy event", func() {
+			tr1.EXPECT().UpdatedPRPolicy(protocol.StreamID(4), protocol.ByteCount(100), uint8(0x20), uint64(150), true)
+			tr2.EXPECT().UpdatedPRPolicy(protocol.StreamID(4), protocol.ByteCount(100), uint8(0x20), uint64(150), true)
+			tracer.UpdatedPRPolicy(4, 100, 0x20, 150, true)
+		})
+
+		It("traces the DisabledPR event", func() {
+			tr1.EXPECT().DisabledPR(PRDisabledFrameRejected)
+			tr2.EXPECT().DisabledPR(PRDisabledFrameRejected)
+			tracer.DisabledPR(PRDisabledFrameRejected)
+		})
+
+		It("traces the SetPRRandSeed event", func() {
+			tr1.EXPECT().SetPRRandSeed(int64(1337))
+			tr2.EXPECT().SetPRRandSeed(int64(1337))
+			tracer.SetPRRandSeed(1337)
+		})
+
+		It("traces the AmplificationLimited event", func() {
+			tr1.EXPECT().AmplificationLimited(protocol.ByteCount(3600), protocol.ByteCount(1200))
+			tr2.EXPECT().AmplificationLimited(protocol.ByteCount(3600), protocol.ByteCount(1200))
+			tracer.AmplificationLimited(3600, 1200)
+		})
+
 		It("traces the Close event", func() {
 			tr1.EXPECT().Close()
 			tr2.EXPECT().Close()
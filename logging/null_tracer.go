@@ -57,5 +57,11 @@ func (n NullConnectionTracer) DroppedKey(KeyPhase)
 func (n NullConnectionTracer) SetLossTimer(TimerType, EncryptionLevel, time.Time)          {}
 func (n NullConnectionTracer) LossTimerExpired(timerType TimerType, level EncryptionLevel) {}
 func (n NullConnectionTracer) LossTimerCanceled()                                          {}
+func (n NullConnectionTracer) CanceledIdleStream(StreamID)                                 {}
+func (n NullConnectionTracer) SpuriousPRConversion(StreamID, ByteCount, ByteCount, bool)   {}
+func (n NullConnectionTracer) UpdatedPRPolicy(StreamID, ByteCount, uint8, uint64, bool)    {}
+func (n NullConnectionTracer) DisabledPR(PRDisabledReason)                                 {}
+func (n NullConnectionTracer) SetPRRandSeed(int64)                                         {}
+func (n NullConnectionTracer) AmplificationLimited(bytesSent, bytesReceived ByteCount)     {}
 func (n NullConnectionTracer) Close()                                                      {}
 func (n NullConnectionTracer) Debug(name, msg string)                                      {}
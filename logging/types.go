@@ -92,3 +92,15 @@ const (
 	// CongestionStateApplicationLimited means that the congestion controller is application limited
 	CongestionStateApplicationLimited
 )
+
+// PRDisabledReason is the reason why partial reliability is disabled for a connection
+type PRDisabledReason uint8
+
+const (
+	// PRDisabledNotNegotiated is used when the peer didn't send the partial_reliability transport parameter
+	PRDisabledNotNegotiated PRDisabledReason = iota
+	// PRDisabledFrameRejected is used when the peer closed the connection because it didn't understand a frame used for partial reliability
+	PRDisabledFrameRejected
+	// PRDisabledHostCache is used when the server rejected a frame used for partial reliability on an earlier connection
+	PRDisabledHostCache
+)
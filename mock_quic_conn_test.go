@@ -108,6 +108,20 @@ func (mr *MockQuicConnMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicConn)(nil).Context))
 }
 
+// Events mocks base method.
+func (m *MockQuicConn) Events() <-chan TransportEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Events")
+	ret0, _ := ret[0].(<-chan TransportEvent)
+	return ret0
+}
+
+// Events indicates an expected call of Events.
+func (mr *MockQuicConnMockRecorder) Events() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Events", reflect.TypeOf((*MockQuicConn)(nil).Events))
+}
+
 // GetVersion mocks base method.
 func (m *MockQuicConn) GetVersion() protocol.VersionNumber {
 	m.ctrl.T.Helper()
@@ -122,6 +136,21 @@ func (mr *MockQuicConnMockRecorder) GetVersion() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersion", reflect.TypeOf((*MockQuicConn)(nil).GetVersion))
 }
 
+// Handoff mocks base method.
+func (m *MockQuicConn) Handoff() (*ConnectionHandoff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Handoff")
+	ret0, _ := ret[0].(*ConnectionHandoff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Handoff indicates an expected call of Handoff.
+func (mr *MockQuicConnMockRecorder) Handoff() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handoff", reflect.TypeOf((*MockQuicConn)(nil).Handoff))
+}
+
 // HandshakeComplete mocks base method.
 func (m *MockQuicConn) HandshakeComplete() context.Context {
 	m.ctrl.T.Helper()
@@ -136,6 +165,20 @@ func (mr *MockQuicConnMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicConn)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method.
+func (m *MockQuicConn) HandshakeConfirmed() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed.
+func (mr *MockQuicConnMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockQuicConn)(nil).HandshakeConfirmed))
+}
+
 // LocalAddr mocks base method.
 func (m *MockQuicConn) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -150,6 +193,20 @@ func (mr *MockQuicConnMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicConn)(nil).LocalAddr))
 }
 
+// MaxStreamFrameDataSize mocks base method.
+func (m *MockQuicConn) MaxStreamFrameDataSize(arg0 protocol.StreamID) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxStreamFrameDataSize", arg0)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// MaxStreamFrameDataSize indicates an expected call of MaxStreamFrameDataSize.
+func (mr *MockQuicConnMockRecorder) MaxStreamFrameDataSize(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxStreamFrameDataSize", reflect.TypeOf((*MockQuicConn)(nil).MaxStreamFrameDataSize), arg0)
+}
+
 // NextConnection mocks base method.
 func (m *MockQuicConn) NextConnection() Connection {
 	m.ctrl.T.Helper()
@@ -164,6 +221,30 @@ func (mr *MockQuicConnMockRecorder) NextConnection() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextConnection", reflect.TypeOf((*MockQuicConn)(nil).NextConnection))
 }
 
+// OnStream mocks base method.
+func (m *MockQuicConn) OnStream(arg0 func(Stream)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStream", arg0)
+}
+
+// OnStream indicates an expected call of OnStream.
+func (mr *MockQuicConnMockRecorder) OnStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStream", reflect.TypeOf((*MockQuicConn)(nil).OnStream), arg0)
+}
+
+// OnUniStream mocks base method.
+func (m *MockQuicConn) OnUniStream(arg0 func(ReceiveStream)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnUniStream", arg0)
+}
+
+// OnUniStream indicates an expected call of OnUniStream.
+func (mr *MockQuicConnMockRecorder) OnUniStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnUniStream", reflect.TypeOf((*MockQuicConn)(nil).OnUniStream), arg0)
+}
+
 // OpenStream mocks base method.
 func (m *MockQuicConn) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -224,6 +305,20 @@ func (mr *MockQuicConnMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicConn)(nil).OpenUniStreamSync), arg0)
 }
 
+// PRStats mocks base method.
+func (m *MockQuicConn) PRStats() PRStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PRStats")
+	ret0, _ := ret[0].(PRStats)
+	return ret0
+}
+
+// PRStats indicates an expected call of PRStats.
+func (mr *MockQuicConnMockRecorder) PRStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PRStats", reflect.TypeOf((*MockQuicConn)(nil).PRStats))
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockQuicConn) ReceiveMessage() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -267,6 +362,71 @@ func (mr *MockQuicConnMockRecorder) SendMessage(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockQuicConn)(nil).SendMessage), arg0)
 }
 
+// SendMessageTracked mocks base method.
+func (m *MockQuicConn) SendMessageTracked(arg0 []byte, arg1 PRPolicy) (*DatagramHandle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageTracked", arg0, arg1)
+	ret0, _ := ret[0].(*DatagramHandle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendMessageTracked indicates an expected call of SendMessageTracked.
+func (mr *MockQuicConnMockRecorder) SendMessageTracked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageTracked", reflect.TypeOf((*MockQuicConn)(nil).SendMessageTracked), arg0, arg1)
+}
+
+// SendMessageWithPolicy mocks base method.
+func (m *MockQuicConn) SendMessageWithPolicy(arg0 []byte, arg1 PRPolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMessageWithPolicy", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMessageWithPolicy indicates an expected call of SendMessageWithPolicy.
+func (mr *MockQuicConnMockRecorder) SendMessageWithPolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessageWithPolicy", reflect.TypeOf((*MockQuicConn)(nil).SendMessageWithPolicy), arg0, arg1)
+}
+
+// SetMaxIncomingStreams mocks base method.
+func (m *MockQuicConn) SetMaxIncomingStreams(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxIncomingStreams", arg0)
+}
+
+// SetMaxIncomingStreams indicates an expected call of SetMaxIncomingStreams.
+func (mr *MockQuicConnMockRecorder) SetMaxIncomingStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingStreams", reflect.TypeOf((*MockQuicConn)(nil).SetMaxIncomingStreams), arg0)
+}
+
+// SetMaxIncomingUniStreams mocks base method.
+func (m *MockQuicConn) SetMaxIncomingUniStreams(arg0 int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxIncomingUniStreams", arg0)
+}
+
+// SetMaxIncomingUniStreams indicates an expected call of SetMaxIncomingUniStreams.
+func (mr *MockQuicConnMockRecorder) SetMaxIncomingUniStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingUniStreams", reflect.TypeOf((*MockQuicConn)(nil).SetMaxIncomingUniStreams), arg0)
+}
+
+// SetPRPolicy mocks base method.
+func (m *MockQuicConn) SetPRPolicy(arg0 *PRPolicy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPRPolicy", arg0)
+}
+
+// SetPRPolicy indicates an expected call of SetPRPolicy.
+func (mr *MockQuicConnMockRecorder) SetPRPolicy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPRPolicy", reflect.TypeOf((*MockQuicConn)(nil).SetPRPolicy), arg0)
+}
+
 // destroy mocks base method.
 func (m *MockQuicConn) destroy(arg0 error) {
 	m.ctrl.T.Helper()
@@ -63,6 +63,21 @@ func (mr *MockReceiveStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReceiveStreamI)(nil).Read), p)
 }
 
+// ReadMessageBoundary mocks base method.
+func (m *MockReceiveStreamI) ReadMessageBoundary() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadMessageBoundary")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadMessageBoundary indicates an expected call of ReadMessageBoundary.
+func (mr *MockReceiveStreamIMockRecorder) ReadMessageBoundary() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadMessageBoundary", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadMessageBoundary))
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockReceiveStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -77,6 +92,18 @@ func (mr *MockReceiveStreamIMockRecorder) SetReadDeadline(t interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadDeadline), t)
 }
 
+// SetReadSkippedAsError mocks base method.
+func (m *MockReceiveStreamI) SetReadSkippedAsError(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadSkippedAsError", arg0)
+}
+
+// SetReadSkippedAsError indicates an expected call of SetReadSkippedAsError.
+func (mr *MockReceiveStreamIMockRecorder) SetReadSkippedAsError(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadSkippedAsError", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadSkippedAsError), arg0)
+}
+
 // StreamID mocks base method.
 func (m *MockReceiveStreamI) StreamID() StreamID {
 	m.ctrl.T.Helper()
@@ -91,6 +118,21 @@ func (mr *MockReceiveStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockReceiveStreamI)(nil).StreamID))
 }
 
+// TryRead mocks base method.
+func (m *MockReceiveStreamI) TryRead(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockReceiveStreamIMockRecorder) TryRead(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockReceiveStreamI)(nil).TryRead), arg0)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockReceiveStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -103,6 +145,20 @@ func (mr *MockReceiveStreamIMockRecorder) closeForShutdown(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockReceiveStreamI)(nil).closeForShutdown), arg0)
 }
 
+// evictExpiredData mocks base method.
+func (m *MockReceiveStreamI) evictExpiredData(arg0 time.Time) time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "evictExpiredData", arg0)
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// evictExpiredData indicates an expected call of evictExpiredData.
+func (mr *MockReceiveStreamIMockRecorder) evictExpiredData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "evictExpiredData", reflect.TypeOf((*MockReceiveStreamI)(nil).evictExpiredData), arg0)
+}
+
 // getWindowUpdate mocks base method.
 func (m *MockReceiveStreamI) getWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -117,6 +173,34 @@ func (mr *MockReceiveStreamIMockRecorder) getWindowUpdate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getWindowUpdate", reflect.TypeOf((*MockReceiveStreamI)(nil).getWindowUpdate))
 }
 
+// handleExpiringStreamFrame mocks base method.
+func (m *MockReceiveStreamI) handleExpiringStreamFrame(arg0 *wire.StreamFrame, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleExpiringStreamFrame", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleExpiringStreamFrame indicates an expected call of handleExpiringStreamFrame.
+func (mr *MockReceiveStreamIMockRecorder) handleExpiringStreamFrame(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleExpiringStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleExpiringStreamFrame), arg0, arg1)
+}
+
+// handleMessageBoundaryFrame mocks base method.
+func (m *MockReceiveStreamI) handleMessageBoundaryFrame(arg0 *wire.PRMessageBoundaryFrame) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleMessageBoundaryFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleMessageBoundaryFrame indicates an expected call of handleMessageBoundaryFrame.
+func (mr *MockReceiveStreamIMockRecorder) handleMessageBoundaryFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleMessageBoundaryFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleMessageBoundaryFrame), arg0)
+}
+
 // handleResetStreamFrame mocks base method.
 func (m *MockReceiveStreamI) handleResetStreamFrame(arg0 *wire.ResetStreamFrame) error {
 	m.ctrl.T.Helper()
@@ -131,6 +215,20 @@ func (mr *MockReceiveStreamIMockRecorder) handleResetStreamFrame(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleResetStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleResetStreamFrame), arg0)
 }
 
+// handleSkippedStreamFrame mocks base method.
+func (m *MockReceiveStreamI) handleSkippedStreamFrame(arg0 *wire.StreamFrame) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleSkippedStreamFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleSkippedStreamFrame indicates an expected call of handleSkippedStreamFrame.
+func (mr *MockReceiveStreamIMockRecorder) handleSkippedStreamFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleSkippedStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleSkippedStreamFrame), arg0)
+}
+
 // handleStreamFrame mocks base method.
 func (m *MockReceiveStreamI) handleStreamFrame(arg0 *wire.StreamFrame) error {
 	m.ctrl.T.Helper()
@@ -144,3 +242,15 @@ func (mr *MockReceiveStreamIMockRecorder) handleStreamFrame(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleStreamFrame), arg0)
 }
+
+// handoffState mocks base method.
+func (m *MockReceiveStreamI) handoffState(arg0 *HandoffStream) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handoffState", arg0)
+}
+
+// handoffState indicates an expected call of handoffState.
+func (mr *MockReceiveStreamIMockRecorder) handoffState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handoffState", reflect.TypeOf((*MockReceiveStreamI)(nil).handoffState), arg0)
+}
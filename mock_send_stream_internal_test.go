@@ -6,6 +6,7 @@ package quic
 
 import (
 	context "context"
+	net "net"
 	reflect "reflect"
 	time "time"
 
@@ -38,6 +39,20 @@ func (m *MockSendStreamI) EXPECT() *MockSendStreamIMockRecorder {
 	return m.recorder
 }
 
+// AckedRanges mocks base method.
+func (m *MockSendStreamI) AckedRanges() []ByteRange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckedRanges")
+	ret0, _ := ret[0].([]ByteRange)
+	return ret0
+}
+
+// AckedRanges indicates an expected call of AckedRanges.
+func (mr *MockSendStreamIMockRecorder) AckedRanges() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckedRanges", reflect.TypeOf((*MockSendStreamI)(nil).AckedRanges))
+}
+
 // CancelWrite mocks base method.
 func (m *MockSendStreamI) CancelWrite(arg0 StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -78,6 +93,107 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// DisablePR mocks base method.
+func (m *MockSendStreamI) DisablePR() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DisablePR")
+}
+
+// DisablePR indicates an expected call of DisablePR.
+func (mr *MockSendStreamIMockRecorder) DisablePR() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisablePR", reflect.TypeOf((*MockSendStreamI)(nil).DisablePR))
+}
+
+// EffectivePRPolicy mocks base method.
+func (m *MockSendStreamI) EffectivePRPolicy() (PRPolicy, PRPolicySource) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EffectivePRPolicy")
+	ret0, _ := ret[0].(PRPolicy)
+	ret1, _ := ret[1].(PRPolicySource)
+	return ret0, ret1
+}
+
+// EffectivePRPolicy indicates an expected call of EffectivePRPolicy.
+func (mr *MockSendStreamIMockRecorder) EffectivePRPolicy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EffectivePRPolicy", reflect.TypeOf((*MockSendStreamI)(nil).EffectivePRPolicy))
+}
+
+// EndMessage mocks base method.
+func (m *MockSendStreamI) EndMessage() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EndMessage")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EndMessage indicates an expected call of EndMessage.
+func (mr *MockSendStreamIMockRecorder) EndMessage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndMessage", reflect.TypeOf((*MockSendStreamI)(nil).EndMessage))
+}
+
+// ExpireDataBefore mocks base method.
+func (m *MockSendStreamI) ExpireDataBefore(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExpireDataBefore", arg0)
+}
+
+// ExpireDataBefore indicates an expected call of ExpireDataBefore.
+func (mr *MockSendStreamIMockRecorder) ExpireDataBefore(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireDataBefore", reflect.TypeOf((*MockSendStreamI)(nil).ExpireDataBefore), arg0)
+}
+
+// SetIdleTimeout mocks base method.
+func (m *MockSendStreamI) SetIdleTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetIdleTimeout", arg0)
+}
+
+// SetIdleTimeout indicates an expected call of SetIdleTimeout.
+func (mr *MockSendStreamIMockRecorder) SetIdleTimeout(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdleTimeout", reflect.TypeOf((*MockSendStreamI)(nil).SetIdleTimeout), arg0)
+}
+
+// SetPRPolicy mocks base method.
+func (m *MockSendStreamI) SetPRPolicy(arg0 *PRPolicy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPRPolicy", arg0)
+}
+
+// SetPRPolicy indicates an expected call of SetPRPolicy.
+func (mr *MockSendStreamIMockRecorder) SetPRPolicy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPRPolicy", reflect.TypeOf((*MockSendStreamI)(nil).SetPRPolicy), arg0)
+}
+
+// SetRateLimit mocks base method.
+func (m *MockSendStreamI) SetRateLimit(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetRateLimit", arg0)
+}
+
+// SetRateLimit indicates an expected call of SetRateLimit.
+func (mr *MockSendStreamIMockRecorder) SetRateLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRateLimit", reflect.TypeOf((*MockSendStreamI)(nil).SetRateLimit), arg0)
+}
+
+// SetWriteBufferWatermarks mocks base method.
+func (m *MockSendStreamI) SetWriteBufferWatermarks(arg0, arg1 uint64, arg2 func(bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWriteBufferWatermarks", arg0, arg1, arg2)
+}
+
+// SetWriteBufferWatermarks indicates an expected call of SetWriteBufferWatermarks.
+func (mr *MockSendStreamIMockRecorder) SetWriteBufferWatermarks(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteBufferWatermarks", reflect.TypeOf((*MockSendStreamI)(nil).SetWriteBufferWatermarks), arg0, arg1, arg2)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockSendStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -106,6 +222,21 @@ func (mr *MockSendStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockSendStreamI)(nil).StreamID))
 }
 
+// TryWrite mocks base method.
+func (m *MockSendStreamI) TryWrite(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockSendStreamIMockRecorder) TryWrite(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockSendStreamI)(nil).TryWrite), arg0)
+}
+
 // Write mocks base method.
 func (m *MockSendStreamI) Write(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -121,6 +252,36 @@ func (mr *MockSendStreamIMockRecorder) Write(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendStreamI)(nil).Write), p)
 }
 
+// WriteBuffers mocks base method.
+func (m *MockSendStreamI) WriteBuffers(arg0 net.Buffers) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockSendStreamIMockRecorder) WriteBuffers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockSendStreamI)(nil).WriteBuffers), arg0)
+}
+
+// WriteWithPolicy mocks base method.
+func (m *MockSendStreamI) WriteWithPolicy(p []byte, policy PRPolicy) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteWithPolicy", p, policy)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteWithPolicy indicates an expected call of WriteWithPolicy.
+func (mr *MockSendStreamIMockRecorder) WriteWithPolicy(p, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteWithPolicy", reflect.TypeOf((*MockSendStreamI)(nil).WriteWithPolicy), p, policy)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockSendStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -145,6 +306,18 @@ func (mr *MockSendStreamIMockRecorder) handleStopSendingFrame(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStopSendingFrame", reflect.TypeOf((*MockSendStreamI)(nil).handleStopSendingFrame), arg0)
 }
 
+// handoffState mocks base method.
+func (m *MockSendStreamI) handoffState(arg0 *HandoffStream) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handoffState", arg0)
+}
+
+// handoffState indicates an expected call of handoffState.
+func (mr *MockSendStreamIMockRecorder) handoffState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handoffState", reflect.TypeOf((*MockSendStreamI)(nil).handoffState), arg0)
+}
+
 // hasData mocks base method.
 func (m *MockSendStreamI) hasData() bool {
 	m.ctrl.T.Helper()
@@ -159,6 +332,20 @@ func (mr *MockSendStreamIMockRecorder) hasData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasData", reflect.TypeOf((*MockSendStreamI)(nil).hasData))
 }
 
+// nextDeadline mocks base method.
+func (m *MockSendStreamI) nextDeadline() time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "nextDeadline")
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// nextDeadline indicates an expected call of nextDeadline.
+func (mr *MockSendStreamIMockRecorder) nextDeadline() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "nextDeadline", reflect.TypeOf((*MockSendStreamI)(nil).nextDeadline))
+}
+
 // popStreamFrame mocks base method.
 func (m *MockSendStreamI) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool) {
 	m.ctrl.T.Helper()
@@ -174,6 +361,20 @@ func (mr *MockSendStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockSendStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// rateLimiter mocks base method.
+func (m *MockSendStreamI) rateLimiter() *tokenBucket {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "rateLimiter")
+	ret0, _ := ret[0].(*tokenBucket)
+	return ret0
+}
+
+// rateLimiter indicates an expected call of rateLimiter.
+func (mr *MockSendStreamIMockRecorder) rateLimiter() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "rateLimiter", reflect.TypeOf((*MockSendStreamI)(nil).rateLimiter))
+}
+
 // updateSendWindow mocks base method.
 func (m *MockSendStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
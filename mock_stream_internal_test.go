@@ -6,6 +6,7 @@ package quic
 
 import (
 	context "context"
+	net "net"
 	reflect "reflect"
 	time "time"
 
@@ -38,6 +39,20 @@ func (m *MockStreamI) EXPECT() *MockStreamIMockRecorder {
 	return m.recorder
 }
 
+// AckedRanges mocks base method.
+func (m *MockStreamI) AckedRanges() []ByteRange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AckedRanges")
+	ret0, _ := ret[0].([]ByteRange)
+	return ret0
+}
+
+// AckedRanges indicates an expected call of AckedRanges.
+func (mr *MockStreamIMockRecorder) AckedRanges() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckedRanges", reflect.TypeOf((*MockStreamI)(nil).AckedRanges))
+}
+
 // CancelRead mocks base method.
 func (m *MockStreamI) CancelRead(arg0 StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -90,6 +105,59 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// DisablePR mocks base method.
+func (m *MockStreamI) DisablePR() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DisablePR")
+}
+
+// DisablePR indicates an expected call of DisablePR.
+func (mr *MockStreamIMockRecorder) DisablePR() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisablePR", reflect.TypeOf((*MockStreamI)(nil).DisablePR))
+}
+
+// EffectivePRPolicy mocks base method.
+func (m *MockStreamI) EffectivePRPolicy() (PRPolicy, PRPolicySource) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EffectivePRPolicy")
+	ret0, _ := ret[0].(PRPolicy)
+	ret1, _ := ret[1].(PRPolicySource)
+	return ret0, ret1
+}
+
+// EffectivePRPolicy indicates an expected call of EffectivePRPolicy.
+func (mr *MockStreamIMockRecorder) EffectivePRPolicy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EffectivePRPolicy", reflect.TypeOf((*MockStreamI)(nil).EffectivePRPolicy))
+}
+
+// EndMessage mocks base method.
+func (m *MockStreamI) EndMessage() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EndMessage")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EndMessage indicates an expected call of EndMessage.
+func (mr *MockStreamIMockRecorder) EndMessage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndMessage", reflect.TypeOf((*MockStreamI)(nil).EndMessage))
+}
+
+// ExpireDataBefore mocks base method.
+func (m *MockStreamI) ExpireDataBefore(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ExpireDataBefore", arg0)
+}
+
+// ExpireDataBefore indicates an expected call of ExpireDataBefore.
+func (mr *MockStreamIMockRecorder) ExpireDataBefore(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireDataBefore", reflect.TypeOf((*MockStreamI)(nil).ExpireDataBefore), arg0)
+}
+
 // Read mocks base method.
 func (m *MockStreamI) Read(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -105,6 +173,21 @@ func (mr *MockStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), p)
 }
 
+// ReadMessageBoundary mocks base method.
+func (m *MockStreamI) ReadMessageBoundary() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadMessageBoundary")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadMessageBoundary indicates an expected call of ReadMessageBoundary.
+func (mr *MockStreamIMockRecorder) ReadMessageBoundary() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadMessageBoundary", reflect.TypeOf((*MockStreamI)(nil).ReadMessageBoundary))
+}
+
 // SetDeadline mocks base method.
 func (m *MockStreamI) SetDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -119,6 +202,42 @@ func (mr *MockStreamIMockRecorder) SetDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), t)
 }
 
+// SetIdleTimeout mocks base method.
+func (m *MockStreamI) SetIdleTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetIdleTimeout", arg0)
+}
+
+// SetIdleTimeout indicates an expected call of SetIdleTimeout.
+func (mr *MockStreamIMockRecorder) SetIdleTimeout(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdleTimeout", reflect.TypeOf((*MockStreamI)(nil).SetIdleTimeout), arg0)
+}
+
+// SetPRPolicy mocks base method.
+func (m *MockStreamI) SetPRPolicy(arg0 *PRPolicy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPRPolicy", arg0)
+}
+
+// SetPRPolicy indicates an expected call of SetPRPolicy.
+func (mr *MockStreamIMockRecorder) SetPRPolicy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPRPolicy", reflect.TypeOf((*MockStreamI)(nil).SetPRPolicy), arg0)
+}
+
+// SetRateLimit mocks base method.
+func (m *MockStreamI) SetRateLimit(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetRateLimit", arg0)
+}
+
+// SetRateLimit indicates an expected call of SetRateLimit.
+func (mr *MockStreamIMockRecorder) SetRateLimit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRateLimit", reflect.TypeOf((*MockStreamI)(nil).SetRateLimit), arg0)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -133,6 +252,30 @@ func (mr *MockStreamIMockRecorder) SetReadDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStreamI)(nil).SetReadDeadline), t)
 }
 
+// SetReadSkippedAsError mocks base method.
+func (m *MockStreamI) SetReadSkippedAsError(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReadSkippedAsError", arg0)
+}
+
+// SetReadSkippedAsError indicates an expected call of SetReadSkippedAsError.
+func (mr *MockStreamIMockRecorder) SetReadSkippedAsError(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadSkippedAsError", reflect.TypeOf((*MockStreamI)(nil).SetReadSkippedAsError), arg0)
+}
+
+// SetWriteBufferWatermarks mocks base method.
+func (m *MockStreamI) SetWriteBufferWatermarks(arg0, arg1 uint64, arg2 func(bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetWriteBufferWatermarks", arg0, arg1, arg2)
+}
+
+// SetWriteBufferWatermarks indicates an expected call of SetWriteBufferWatermarks.
+func (mr *MockStreamIMockRecorder) SetWriteBufferWatermarks(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWriteBufferWatermarks", reflect.TypeOf((*MockStreamI)(nil).SetWriteBufferWatermarks), arg0, arg1, arg2)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -161,6 +304,36 @@ func (mr *MockStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStreamI)(nil).StreamID))
 }
 
+// TryRead mocks base method.
+func (m *MockStreamI) TryRead(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockStreamIMockRecorder) TryRead(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockStreamI)(nil).TryRead), arg0)
+}
+
+// TryWrite mocks base method.
+func (m *MockStreamI) TryWrite(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockStreamIMockRecorder) TryWrite(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockStreamI)(nil).TryWrite), arg0)
+}
+
 // Write mocks base method.
 func (m *MockStreamI) Write(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -176,6 +349,36 @@ func (mr *MockStreamIMockRecorder) Write(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStreamI)(nil).Write), p)
 }
 
+// WriteBuffers mocks base method.
+func (m *MockStreamI) WriteBuffers(arg0 net.Buffers) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockStreamIMockRecorder) WriteBuffers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockStreamI)(nil).WriteBuffers), arg0)
+}
+
+// WriteWithPolicy mocks base method.
+func (m *MockStreamI) WriteWithPolicy(p []byte, policy PRPolicy) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteWithPolicy", p, policy)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteWithPolicy indicates an expected call of WriteWithPolicy.
+func (mr *MockStreamIMockRecorder) WriteWithPolicy(p, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteWithPolicy", reflect.TypeOf((*MockStreamI)(nil).WriteWithPolicy), p, policy)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -188,6 +391,20 @@ func (mr *MockStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockStreamI)(nil).closeForShutdown), arg0)
 }
 
+// evictExpiredData mocks base method.
+func (m *MockStreamI) evictExpiredData(arg0 time.Time) time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "evictExpiredData", arg0)
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// evictExpiredData indicates an expected call of evictExpiredData.
+func (mr *MockStreamIMockRecorder) evictExpiredData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "evictExpiredData", reflect.TypeOf((*MockStreamI)(nil).evictExpiredData), arg0)
+}
+
 // getWindowUpdate mocks base method.
 func (m *MockStreamI) getWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -202,6 +419,34 @@ func (mr *MockStreamIMockRecorder) getWindowUpdate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getWindowUpdate", reflect.TypeOf((*MockStreamI)(nil).getWindowUpdate))
 }
 
+// handleExpiringStreamFrame mocks base method.
+func (m *MockStreamI) handleExpiringStreamFrame(arg0 *wire.StreamFrame, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleExpiringStreamFrame", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleExpiringStreamFrame indicates an expected call of handleExpiringStreamFrame.
+func (mr *MockStreamIMockRecorder) handleExpiringStreamFrame(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleExpiringStreamFrame", reflect.TypeOf((*MockStreamI)(nil).handleExpiringStreamFrame), arg0, arg1)
+}
+
+// handleMessageBoundaryFrame mocks base method.
+func (m *MockStreamI) handleMessageBoundaryFrame(arg0 *wire.PRMessageBoundaryFrame) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleMessageBoundaryFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleMessageBoundaryFrame indicates an expected call of handleMessageBoundaryFrame.
+func (mr *MockStreamIMockRecorder) handleMessageBoundaryFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleMessageBoundaryFrame", reflect.TypeOf((*MockStreamI)(nil).handleMessageBoundaryFrame), arg0)
+}
+
 // handleResetStreamFrame mocks base method.
 func (m *MockStreamI) handleResetStreamFrame(arg0 *wire.ResetStreamFrame) error {
 	m.ctrl.T.Helper()
@@ -216,6 +461,20 @@ func (mr *MockStreamIMockRecorder) handleResetStreamFrame(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleResetStreamFrame", reflect.TypeOf((*MockStreamI)(nil).handleResetStreamFrame), arg0)
 }
 
+// handleSkippedStreamFrame mocks base method.
+func (m *MockStreamI) handleSkippedStreamFrame(arg0 *wire.StreamFrame) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "handleSkippedStreamFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// handleSkippedStreamFrame indicates an expected call of handleSkippedStreamFrame.
+func (mr *MockStreamIMockRecorder) handleSkippedStreamFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleSkippedStreamFrame", reflect.TypeOf((*MockStreamI)(nil).handleSkippedStreamFrame), arg0)
+}
+
 // handleStopSendingFrame mocks base method.
 func (m *MockStreamI) handleStopSendingFrame(arg0 *wire.StopSendingFrame) {
 	m.ctrl.T.Helper()
@@ -242,6 +501,18 @@ func (mr *MockStreamIMockRecorder) handleStreamFrame(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamFrame", reflect.TypeOf((*MockStreamI)(nil).handleStreamFrame), arg0)
 }
 
+// handoffState mocks base method.
+func (m *MockStreamI) handoffState(arg0 *HandoffStream) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handoffState", arg0)
+}
+
+// handoffState indicates an expected call of handoffState.
+func (mr *MockStreamIMockRecorder) handoffState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handoffState", reflect.TypeOf((*MockStreamI)(nil).handoffState), arg0)
+}
+
 // hasData mocks base method.
 func (m *MockStreamI) hasData() bool {
 	m.ctrl.T.Helper()
@@ -256,6 +527,20 @@ func (mr *MockStreamIMockRecorder) hasData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasData", reflect.TypeOf((*MockStreamI)(nil).hasData))
 }
 
+// nextDeadline mocks base method.
+func (m *MockStreamI) nextDeadline() time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "nextDeadline")
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// nextDeadline indicates an expected call of nextDeadline.
+func (mr *MockStreamIMockRecorder) nextDeadline() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "nextDeadline", reflect.TypeOf((*MockStreamI)(nil).nextDeadline))
+}
+
 // popStreamFrame mocks base method.
 func (m *MockStreamI) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool) {
 	m.ctrl.T.Helper()
@@ -271,6 +556,20 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// rateLimiter mocks base method.
+func (m *MockStreamI) rateLimiter() *tokenBucket {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "rateLimiter")
+	ret0, _ := ret[0].(*tokenBucket)
+	return ret0
+}
+
+// rateLimiter indicates an expected call of rateLimiter.
+func (mr *MockStreamIMockRecorder) rateLimiter() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "rateLimiter", reflect.TypeOf((*MockStreamI)(nil).rateLimiter))
+}
+
 // updateSendWindow mocks base method.
 func (m *MockStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -7,6 +7,7 @@ package quic
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
@@ -92,6 +93,20 @@ func (mr *MockStreamManagerMockRecorder) DeleteStream(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStream", reflect.TypeOf((*MockStreamManager)(nil).DeleteStream), arg0)
 }
 
+// EvictExpiredData mocks base method.
+func (m *MockStreamManager) EvictExpiredData(arg0 time.Time) time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EvictExpiredData", arg0)
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// EvictExpiredData indicates an expected call of EvictExpiredData.
+func (mr *MockStreamManagerMockRecorder) EvictExpiredData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EvictExpiredData", reflect.TypeOf((*MockStreamManager)(nil).EvictExpiredData), arg0)
+}
+
 // GetOrOpenReceiveStream mocks base method.
 func (m *MockStreamManager) GetOrOpenReceiveStream(arg0 protocol.StreamID) (receiveStreamI, error) {
 	m.ctrl.T.Helper()
@@ -134,6 +149,18 @@ func (mr *MockStreamManagerMockRecorder) HandleMaxStreamsFrame(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMaxStreamsFrame", reflect.TypeOf((*MockStreamManager)(nil).HandleMaxStreamsFrame), arg0)
 }
 
+// HandoffState mocks base method.
+func (m *MockStreamManager) HandoffState(arg0 *ConnectionHandoff) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "HandoffState", arg0)
+}
+
+// HandoffState indicates an expected call of HandoffState.
+func (mr *MockStreamManagerMockRecorder) HandoffState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandoffState", reflect.TypeOf((*MockStreamManager)(nil).HandoffState), arg0)
+}
+
 // OpenStream mocks base method.
 func (m *MockStreamManager) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -194,6 +221,20 @@ func (mr *MockStreamManagerMockRecorder) OpenUniStreamSync(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockStreamManager)(nil).OpenUniStreamSync), arg0)
 }
 
+// RejectIncomingStream mocks base method.
+func (m *MockStreamManager) RejectIncomingStream(arg0 protocol.StreamID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectIncomingStream", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RejectIncomingStream indicates an expected call of RejectIncomingStream.
+func (mr *MockStreamManagerMockRecorder) RejectIncomingStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectIncomingStream", reflect.TypeOf((*MockStreamManager)(nil).RejectIncomingStream), arg0)
+}
+
 // ResetFor0RTT mocks base method.
 func (m *MockStreamManager) ResetFor0RTT() {
 	m.ctrl.T.Helper()
@@ -206,6 +247,42 @@ func (mr *MockStreamManagerMockRecorder) ResetFor0RTT() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetFor0RTT", reflect.TypeOf((*MockStreamManager)(nil).ResetFor0RTT))
 }
 
+// Resume mocks base method.
+func (m *MockStreamManager) Resume(arg0 *ConnectionHandoff) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Resume", arg0)
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockStreamManagerMockRecorder) Resume(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockStreamManager)(nil).Resume), arg0)
+}
+
+// SetMaxIncomingStreams mocks base method.
+func (m *MockStreamManager) SetMaxIncomingStreams(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxIncomingStreams", arg0)
+}
+
+// SetMaxIncomingStreams indicates an expected call of SetMaxIncomingStreams.
+func (mr *MockStreamManagerMockRecorder) SetMaxIncomingStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingStreams", reflect.TypeOf((*MockStreamManager)(nil).SetMaxIncomingStreams), arg0)
+}
+
+// SetMaxIncomingUniStreams mocks base method.
+func (m *MockStreamManager) SetMaxIncomingUniStreams(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxIncomingUniStreams", arg0)
+}
+
+// SetMaxIncomingUniStreams indicates an expected call of SetMaxIncomingUniStreams.
+func (mr *MockStreamManagerMockRecorder) SetMaxIncomingUniStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingUniStreams", reflect.TypeOf((*MockStreamManager)(nil).SetMaxIncomingUniStreams), arg0)
+}
+
 // UpdateLimits mocks base method.
 func (m *MockStreamManager) UpdateLimits(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
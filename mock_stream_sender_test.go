@@ -47,6 +47,42 @@ func (mr *MockStreamSenderMockRecorder) onHasStreamData(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onHasStreamData", reflect.TypeOf((*MockStreamSender)(nil).onHasStreamData), arg0)
 }
 
+// onIdleStreamCanceled mocks base method.
+func (m *MockStreamSender) onIdleStreamCanceled(arg0 protocol.StreamID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onIdleStreamCanceled", arg0)
+}
+
+// onIdleStreamCanceled indicates an expected call of onIdleStreamCanceled.
+func (mr *MockStreamSenderMockRecorder) onIdleStreamCanceled(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onIdleStreamCanceled", reflect.TypeOf((*MockStreamSender)(nil).onIdleStreamCanceled), arg0)
+}
+
+// onPRPolicyChanged mocks base method.
+func (m *MockStreamSender) onPRPolicyChanged(arg0 protocol.StreamID, arg1 protocol.ByteCount, arg2 PRPolicy) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onPRPolicyChanged", arg0, arg1, arg2)
+}
+
+// onPRPolicyChanged indicates an expected call of onPRPolicyChanged.
+func (mr *MockStreamSenderMockRecorder) onPRPolicyChanged(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onPRPolicyChanged", reflect.TypeOf((*MockStreamSender)(nil).onPRPolicyChanged), arg0, arg1, arg2)
+}
+
+// onSpuriousPRConversion mocks base method.
+func (m *MockStreamSender) onSpuriousPRConversion(arg0 protocol.StreamID, arg1 protocol.ByteCount, arg2 protocol.ByteCount, arg3 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onSpuriousPRConversion", arg0, arg1, arg2, arg3)
+}
+
+// onSpuriousPRConversion indicates an expected call of onSpuriousPRConversion.
+func (mr *MockStreamSenderMockRecorder) onSpuriousPRConversion(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onSpuriousPRConversion", reflect.TypeOf((*MockStreamSender)(nil).onSpuriousPRConversion), arg0, arg1, arg2, arg3)
+}
+
 // onStreamCompleted mocks base method.
 func (m *MockStreamSender) onStreamCompleted(arg0 protocol.StreamID) {
 	m.ctrl.T.Helper()
@@ -27,6 +27,7 @@ type rawConn interface {
 	ReadPacket() (*receivedPacket, error)
 	WritePacket(b []byte, addr net.Addr, oob []byte) (int, error)
 	LocalAddr() net.Addr
+	capabilities() connCapabilities
 	io.Closer
 }
 
@@ -56,6 +57,9 @@ type packetHandlerMap struct {
 	listening chan struct{} // is closed when listen returns
 	closed    bool
 
+	// the maps of all sockets of a listener using SO_REUSEPORT, see Config.ReusePortSockets
+	shards []*packetHandlerMap
+
 	deleteRetiredConnsAfter time.Duration
 	zeroRTTQueueDuration    time.Duration
 
@@ -172,6 +176,14 @@ func (h *packetHandlerMap) logUsage() {
 	}
 }
 
+// setShards sets the maps of all sockets of a listener using SO_REUSEPORT.
+// Packets are passed to the map of the socket that handles the connection ID, see connIDShard.
+func (h *packetHandlerMap) setShards(shards []*packetHandlerMap) {
+	h.mutex.Lock()
+	h.shards = shards
+	h.mutex.Unlock()
+}
+
 func (h *packetHandlerMap) Add(id protocol.ConnectionID, handler packetHandler) bool /* was added */ {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -321,6 +333,10 @@ func (h *packetHandlerMap) CloseServer() {
 	wg.Wait()
 }
 
+func (h *packetHandlerMap) Capabilities() connCapabilities {
+	return h.conn.capabilities()
+}
+
 // Destroy closes the underlying connection and waits until listen() has returned.
 // It does not close active connections.
 func (h *packetHandlerMap) Destroy() error {
@@ -351,8 +367,12 @@ func (h *packetHandlerMap) close(e error) error {
 		h.server.setCloseError(e)
 	}
 	h.closed = true
+	sharded := h.shards != nil
 	h.mutex.Unlock()
 	wg.Wait()
+	if sharded { // not registered with the multiplexer
+		return nil
+	}
 	return getMultiplexer().RemoveConn(h.conn)
 }
 
@@ -388,6 +408,15 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 	}
 
 	h.mutex.Lock()
+	if h.shards != nil {
+		// The kernel might deliver the packet to a different socket than the one that handles the connection,
+		// e.g. after a NAT rebinding.
+		if shard := h.shards[connIDShard(connID, len(h.shards))]; shard != h {
+			h.mutex.Unlock()
+			shard.handlePacket(p)
+			return
+		}
+	}
 	defer h.mutex.Unlock()
 
 	if isStatelessReset := h.maybeHandleStatelessReset(p.data); isStatelessReset {
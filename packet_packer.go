@@ -168,10 +168,16 @@ type packetPacker struct {
 	framer              frameSource
 	acks                ackFrameSource
 	datagramQueue       *datagramQueue
+	prAckNotifies       *prAckNotifyQueue
 	retransmissionQueue *retransmissionQueue
 
 	maxPacketSize          protocol.ByteCount
 	numNonAckElicitingAcks int
+
+	// leave the header protection of 1-RTT packets to the send queue, see Config.BatchHeaderProtection
+	batchHeaderProtection bool
+	// the maximum size of the CRYPTO frames in an Initial packet sent by the client, see Config.ClientHelloFragmentSize
+	clientHelloFragmentSize protocol.ByteCount
 }
 
 var _ packer = &packetPacker{}
@@ -188,6 +194,9 @@ func newPacketPacker(
 	framer frameSource,
 	acks ackFrameSource,
 	datagramQueue *datagramQueue,
+	prAckNotifies *prAckNotifyQueue,
+	batchHeaderProtection bool,
+	clientHelloFragmentSize protocol.ByteCount,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) *packetPacker {
@@ -199,12 +208,16 @@ func newPacketPacker(
 		handshakeStream:     handshakeStream,
 		retransmissionQueue: retransmissionQueue,
 		datagramQueue:       datagramQueue,
+		prAckNotifies:       prAckNotifies,
 		perspective:         perspective,
 		version:             version,
 		framer:              framer,
 		acks:                acks,
 		pnManager:           packetNumberManager,
 		maxPacketSize:       getMaxPacketSize(remoteAddr),
+
+		batchHeaderProtection:   batchHeaderProtection,
+		clientHelloFragmentSize: clientHelloFragmentSize,
 	}
 }
 
@@ -451,6 +464,9 @@ func (p *packetPacker) PackPacket(onlyAck bool) (*packedPacket, error) {
 		return nil, nil
 	}
 	buffer := getPacketBuffer()
+	if p.batchHeaderProtection {
+		buffer.headerSealer = sealer
+	}
 	cont, err := p.appendPacket(buffer, hdr, payload, 0, protocol.Encryption1RTT, sealer, false)
 	if err != nil {
 		return nil, err
@@ -502,6 +518,11 @@ func (p *packetPacker) maybeGetCryptoPacket(maxPacketSize protocol.ByteCount, en
 	}
 	hdr := p.getLongHeader(encLevel)
 	maxPacketSize -= hdr.GetLength(p.version)
+	if encLevel == protocol.EncryptionInitial && p.perspective == protocol.PerspectiveClient && p.clientHelloFragmentSize > 0 {
+		// Split the ClientHello into multiple Initial packets.
+		// Every packet is padded to the full size, see initialPaddingLen.
+		maxPacketSize = utils.Min(maxPacketSize, p.clientHelloFragmentSize)
+	}
 	if hasRetransmission {
 		for {
 			var f wire.Frame
@@ -576,26 +597,21 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAc
 			payload := &payload{}
 			payload.ack = ack
 			payload.length += ack.Length(p.version)
+			// PRAckNotify frames are ack-eliciting, so they're not sent in ACK-only packets.
+			// These are sent when the congestion controller doesn't allow sending ack-eliciting packets.
 			return payload
 		}
 		return &payload{}
 	}
 
-	// 把PRAckNotify Frame从PRAckNotifyFrames中放到retransmissionQueue中
-	// 因为sendStream中的重传队列只能存Stream帧
-	if len(PRAckNotifyFrames) > 0 {
-		for _, f := range PRAckNotifyFrames {
-			p.retransmissionQueue.AddAppData(f)
-			PRAckNotifyFrames = PRAckNotifyFrames[1:]
-		}
-	}
 	payload := &payload{frames: make([]ackhandler.Frame, 0, 1)}
 	hasData := p.framer.HasData()
 	hasRetransmission := p.retransmissionQueue.HasAppData()
+	hasPRAckNotifies := p.prAckNotifies.HasData()
 
 	var hasAck bool
 	if ackAllowed {
-		if ack := p.acks.GetAckFrame(protocol.Encryption1RTT, !hasRetransmission && !hasData); ack != nil {
+		if ack := p.acks.GetAckFrame(protocol.Encryption1RTT, !hasRetransmission && !hasData && !hasPRAckNotifies); ack != nil {
 			payload.ack = ack
 			payload.length += ack.Length(p.version)
 			hasAck = true
@@ -603,21 +619,25 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAc
 	}
 
 	if p.datagramQueue != nil {
-		size := p.datagramQueue.NextFrameSize()
-		if size > 0 && size <= maxFrameSize-payload.length {
-			datagram := p.datagramQueue.Get()
-			if datagram == nil || datagram.Length(p.version) != size {
-				panic("packet packer BUG: inconsistent DATAGRAM frame length")
-			}
-			payload.frames = append(payload.frames, ackhandler.Frame{
+		if datagram, handle := p.datagramQueue.Get(maxFrameSize - payload.length); datagram != nil {
+			frame := ackhandler.Frame{
 				Frame: datagram,
 				// set it to a no-op. Then we won't set the default callback, which would retransmit the frame.
 				OnLost: func(wire.Frame) {},
-			})
+			}
+			if handle != nil {
+				frame.OnLost = handle.onLost
+				frame.OnAcked = handle.onAcked
+			}
+			payload.frames = append(payload.frames, frame)
 			payload.length += datagram.Length(p.version)
 		}
 	}
 
+	if hasPRAckNotifies {
+		p.appendPRAckNotifyFrames(payload, maxFrameSize)
+	}
+
 	if hasAck && !hasData && !hasRetransmission {
 		return payload
 	}
@@ -649,6 +669,19 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAc
 	return payload
 }
 
+// appendPRAckNotifyFrames packs the queued PRAckNotify frames that fit into the payload.
+// They are retransmitted by their queue, which keeps track of the acknowledgements.
+func (p *packetPacker) appendPRAckNotifyFrames(payload *payload, maxFrameSize protocol.ByteCount) {
+	for {
+		f := p.prAckNotifies.Pop(maxFrameSize-payload.length, p.version)
+		if f == nil {
+			return
+		}
+		payload.frames = append(payload.frames, ackhandler.Frame{Frame: f, OnLost: p.prAckNotifies.OnLost, OnAcked: p.prAckNotifies.OnAcked})
+		payload.length += f.Length(p.version)
+	}
+}
+
 func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (*packedPacket, error) {
 	var hdr *wire.ExtendedHeader
 	var payload *payload
@@ -805,9 +838,14 @@ func (p *packetPacker) appendPacket(buffer *packetBuffer, header *wire.ExtendedH
 	// encrypt the packet
 	_ = sealer.Seal(raw[payloadOffset:payloadOffset], raw[payloadOffset:], header.PacketNumber, raw[hdrOffset:payloadOffset])
 	raw = raw[0 : len(raw)+sealer.Overhead()]
-	// apply header protection
+	// apply header protection, unless the send queue applies it
 	pnOffset := payloadOffset - int(header.PacketNumberLen)
-	sealer.EncryptHeader(raw[pnOffset+4:pnOffset+4+16], &raw[hdrOffset], raw[pnOffset:payloadOffset])
+	sample, pnBytes := raw[pnOffset+4:pnOffset+4+16], raw[pnOffset:payloadOffset]
+	if buffer.headerSealer != nil {
+		buffer.headerProtection = handshake.HeaderProtection{Sample: sample, FirstByte: &raw[hdrOffset], PNBytes: pnBytes}
+	} else {
+		sealer.EncryptHeader(sample, &raw[hdrOffset], pnBytes)
+	}
 	buffer.Data = raw
 
 	num := p.pnManager.PopPacketNumber(encLevel)
@@ -30,6 +30,7 @@ var _ = Describe("Packet packer", func() {
 	var (
 		packer              *packetPacker
 		retransmissionQueue *retransmissionQueue
+		prAckNotifies       *prAckNotifyQueue
 		datagramQueue       *datagramQueue
 		framer              *MockFrameSource
 		ackFramer           *MockAckFrameSource
@@ -83,6 +84,7 @@ var _ = Describe("Packet packer", func() {
 	BeforeEach(func() {
 		rand.Seed(GinkgoRandomSeed())
 		retransmissionQueue = newRetransmissionQueue(version)
+		prAckNotifies = newPRAckNotifyQueue()
 		mockSender := NewMockStreamSender(mockCtrl)
 		mockSender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
 		initialStream = NewMockCryptoStream(mockCtrl)
@@ -91,7 +93,7 @@ var _ = Describe("Packet packer", func() {
 		ackFramer = NewMockAckFrameSource(mockCtrl)
 		sealingManager = NewMockSealingManager(mockCtrl)
 		pnManager = mockackhandler.NewMockSentPacketHandler(mockCtrl)
-		datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger, version)
+		datagramQueue = newDatagramQueue(func() {}, func(int, bool, bool) {}, 1, DatagramOverflowBlock, utils.DefaultLogger, version)
 
 		packer = newPacketPacker(
 			protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}),
@@ -105,6 +107,9 @@ var _ = Describe("Packet packer", func() {
 			framer,
 			ackFramer,
 			datagramQueue,
+			prAckNotifies,
+			false,
+			0,
 			protocol.PerspectiveServer,
 			version,
 		)
@@ -299,6 +304,35 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.frames).To(BeEmpty())
 				parsePacket(p.buffer.Data)
 			})
+
+			It("doesn't pack PR_ACK_NOTIFY frames into 1-RTT ACK-only packets", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(ack)
+				prAckNotifies.Add(&wire.PRAckNotifyFrame{StreamID: 5, Offset: 100, PRDataLen: 42, DataLenPresent: true, PTDA: PTDAAbandon})
+				// the connection only packs ACK-only packets when the send mode is SendAck
+				p, err := packer.PackPacket(true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.ack).To(Equal(ack))
+				Expect(p.frames).To(BeEmpty())
+				Expect(p.IsAckEliciting()).To(BeFalse())
+				Expect(prAckNotifies.HasData()).To(BeTrue())
+				parsePacket(p.buffer.Data)
+			})
+
+			It("doesn't send PR_ACK_NOTIFY frames in ACK-only packets if there's no ACK to send", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				prAckNotifies.Add(&wire.PRAckNotifyFrame{StreamID: 5, Offset: 100, PRDataLen: 42, DataLenPresent: true, PTDA: PTDAAbandon})
+				p, err := packer.PackPacket(true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(BeNil())
+				Expect(prAckNotifies.HasData()).To(BeTrue())
+			})
 		})
 
 		Context("packing 0-RTT packets", func() {
@@ -533,6 +567,31 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Data).To(ContainSubstring(string(b)))
 			})
 
+			It("leaves the header protection to the send queue", func() {
+				packer.batchHeaderProtection = true
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealer := mocks.NewMockShortHeaderSealer(mockCtrl)
+				sealer.EXPECT().KeyPhase().Return(protocol.KeyPhaseOne).AnyTimes()
+				sealer.EXPECT().Overhead().Return(16).AnyTimes()
+				sealer.EXPECT().Seal(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, src []byte, _ protocol.PacketNumber, _ []byte) []byte {
+					return append(src, bytes.Repeat([]byte{'s'}, 16)...)
+				})
+				// don't expect any calls to EncryptHeader
+				sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				expectAppendControlFrames()
+				expectAppendStreamFrames(ackhandler.Frame{Frame: &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}})
+				p, err := packer.PackPacket(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.buffer.headerSealer).To(Equal(sealer))
+				hp := p.buffer.headerProtection
+				Expect(hp.FirstByte).To(Equal(&p.buffer.Data[0]))
+				Expect(hp.PNBytes).To(HaveLen(2))
+				Expect(hp.Sample).To(HaveLen(16))
+			})
+
 			It("stores the encryption level a packet was sealed with", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -581,6 +640,50 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Len()).ToNot(BeZero())
 			})
 
+			It("packs PR_ACK_NOTIFY frames", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				expectAppendControlFrames()
+				expectAppendStreamFrames()
+				f := &wire.PRAckNotifyFrame{StreamID: 5, Offset: 100, PRDataLen: 42, DataLenPresent: true, PTDA: PTDAAbandon}
+				prAckNotifies.Add(f)
+				p, err := packer.PackPacket(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				Expect(prAckNotifies.HasData()).To(BeFalse())
+				// the queue retransmits the frame, and keeps track of the acknowledgement
+				Expect(prAckNotifies.WaitForStream(5, func() {})).To(BeTrue())
+				p.frames[0].OnLost(p.frames[0].Frame)
+				Expect(prAckNotifies.HasData()).To(BeTrue())
+				prAckNotifies.PopAll()
+				p.frames[0].OnAcked(p.frames[0].Frame)
+				Expect(prAckNotifies.WaitForStream(5, func() {})).To(BeFalse())
+			})
+
+			It("packs PR_ACK_NOTIFY frames with an ACK, if there's no other data to send", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData()
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false).Return(ack)
+				f := &wire.PRAckNotifyFrame{StreamID: 5, Offset: 100, PRDataLen: 42, DataLenPresent: true, PTDA: PTDAAbandon}
+				prAckNotifies.Add(f)
+				p, err := packer.PackPacket(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.ack).To(Equal(ack))
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				Expect(prAckNotifies.HasData()).To(BeFalse())
+				parsePacket(p.buffer.Data)
+			})
+
 			It("packs DATAGRAM frames", func() {
 				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
@@ -594,7 +697,7 @@ var _ = Describe("Packet packer", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(done)
-					datagramQueue.AddAndWait(f)
+					datagramQueue.Add(f, PRPolicy{}, nil)
 				}()
 				// make sure the DATAGRAM has actually been queued
 				time.Sleep(scaleDuration(20 * time.Millisecond))
@@ -609,6 +712,35 @@ var _ = Describe("Packet packer", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("resolves the handle of tracked DATAGRAM frames", func() {
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				f := &wire.DatagramFrame{
+					DataLenPresent: true,
+					Data:           []byte("foobar"),
+				}
+				handle := newDatagramHandle()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.Add(f, PRPolicy{}, handle)
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				framer.EXPECT().HasData()
+				p, err := packer.PackPacket(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Eventually(done).Should(BeClosed())
+				Expect(handle.State()).To(Equal(DatagramPending))
+				p.frames[0].OnLost(p.frames[0].Frame)
+				Expect(handle.State()).To(Equal(DatagramLost))
+			})
+
 			It("doesn't pack a DATAGRAM frame if the ACK frame is too large", func() {
 				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 100}}})
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
@@ -622,7 +754,7 @@ var _ = Describe("Packet packer", func() {
 				go func() {
 					defer GinkgoRecover()
 					defer close(done)
-					datagramQueue.AddAndWait(f)
+					datagramQueue.Add(f, PRPolicy{}, nil)
 				}()
 				// make sure the DATAGRAM has actually been queued
 				time.Sleep(scaleDuration(20 * time.Millisecond))
@@ -691,7 +823,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, true, packer.version)
 				l, frame, err := frameParser.ParseNext(data[len(data)-r.Len():], protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -729,7 +861,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(firstPayloadByte).To(Equal(byte(0)))
 				// ... followed by the STREAM frame
-				frameParser := wire.NewFrameParser(true, packer.version)
+				frameParser := wire.NewFrameParser(true, true, packer.version)
 				l, frame, err := frameParser.ParseNext(packet.buffer.Data[len(data)-r.Len():], protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
@@ -1283,7 +1415,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, true, packer.version)
 				l, frame, err := frameParser.ParseNext(data[len(data)-r.Len():], protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -1352,6 +1484,55 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.packets[0].ack).To(Equal(ack))
 			})
 
+			It("splits the ClientHello, if a fragment size is set", func() {
+				packer.perspective = protocol.PerspectiveClient
+				packer.clientHelloFragmentSize = 50
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen1)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get0RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				initialStream.EXPECT().HasData().Return(true).Times(2)
+				initialStream.EXPECT().PopCryptoFrame(protocol.ByteCount(50)).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					f := &wire.CryptoFrame{}
+					f.Data = make([]byte, f.MaxDataLen(size))
+					return f
+				})
+				p, err := packer.PackCoalescedPacket(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.buffer.Len()).To(BeEquivalentTo(maxPacketSize))
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].frames).To(HaveLen(1))
+				Expect(p.packets[0].frames[0].Frame.Length(packer.version)).To(BeEquivalentTo(50))
+			})
+
+			It("splits retransmissions of the ClientHello, if a fragment size is set", func() {
+				packer.perspective = protocol.PerspectiveClient
+				packer.clientHelloFragmentSize = 50
+				packer.retransmissionQueue.AddInitial(&wire.CryptoFrame{Data: make([]byte, 30)})
+				packer.retransmissionQueue.AddInitial(&wire.CryptoFrame{Offset: 30, Data: make([]byte, 30)})
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen1)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get0RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				initialStream.EXPECT().HasData()
+				p, err := packer.PackCoalescedPacket(false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.buffer.Len()).To(BeEquivalentTo(maxPacketSize))
+				Expect(p.packets).To(HaveLen(1))
+				var length protocol.ByteCount
+				for _, f := range p.packets[0].frames {
+					length += f.Frame.Length(packer.version)
+				}
+				Expect(length).To(BeNumerically("<=", 50))
+				Expect(packer.retransmissionQueue.HasInitialData()).To(BeTrue())
+			})
+
 			for _, pers := range []protocol.Perspective{protocol.PerspectiveServer, protocol.PerspectiveClient} {
 				perspective := pers
 
@@ -0,0 +1,147 @@
+package quic
+
+import (
+	"crypto/rand"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// The pathManager decides when the server migrates a connection to a new client address,
+// e.g. after a NAT rebinding changed the client's address or port (RFC 9000, section 9.3).
+// The connection only migrates after receiving Config.PathValidationPacketThreshold non-probing packets from the new address,
+// each of them with a packet number larger than any packet received before.
+// The new address is then validated using a PATH_CHALLENGE.
+type pathManager struct {
+	threshold int
+
+	largestRcvdPacketNumber protocol.PacketNumber
+
+	// the new address packets were received from, and the number of packets received from it
+	candidate           net.Addr
+	numCandidatePackets int
+
+	// the last address that was validated, and the PATH_CHALLENGE sent to validate the current address
+	validatedAddr      net.Addr
+	challenge          *[8]byte
+	validationDeadline time.Time
+	sentChallenge      bool
+}
+
+func newPathManager(threshold int) *pathManager {
+	return &pathManager{
+		threshold:               threshold,
+		largestRcvdPacketNumber: protocol.InvalidPacketNumber,
+	}
+}
+
+// ReceivedPacket is called for every 1-RTT packet received after the handshake was confirmed.
+// It returns true if the connection should migrate to the address the packet was received from.
+func (m *pathManager) ReceivedPacket(remote, from net.Addr, pn protocol.PacketNumber, isNonProbing bool) (migrate bool) {
+	if !isNonProbing || pn <= m.largestRcvdPacketNumber {
+		return false
+	}
+	m.largestRcvdPacketNumber = pn
+	if equalAddr(remote, from) {
+		m.candidate = nil
+		m.numCandidatePackets = 0
+		return false
+	}
+	if !equalAddr(m.candidate, from) {
+		m.candidate = from
+		m.numCandidatePackets = 0
+	}
+	m.numCandidatePackets++
+	if m.numCandidatePackets < m.threshold {
+		return false
+	}
+	m.candidate = nil
+	m.numCandidatePackets = 0
+	return true
+}
+
+// Migrated is called when the connection migrated away from the previous address.
+// It returns the PATH_CHALLENGE that needs to be sent to validate the new address.
+func (m *pathManager) Migrated(previous net.Addr, deadline time.Time) *wire.PathChallengeFrame {
+	// If the previous address wasn't validated yet, return to the last validated address if validation fails.
+	if m.challenge == nil {
+		m.validatedAddr = previous
+	}
+	var data [8]byte
+	rand.Read(data[:])
+	m.challenge = &data
+	m.validationDeadline = deadline
+	m.sentChallenge = true
+	return &wire.PathChallengeFrame{Data: data}
+}
+
+// ReceivedPathResponse is called when a PATH_RESPONSE is received.
+// A PATH_RESPONSE is only expected if we sent a PATH_CHALLENGE before.
+func (m *pathManager) ReceivedPathResponse(f *wire.PathResponseFrame) (expected, validated bool) {
+	if !m.sentChallenge {
+		return false, false
+	}
+	// Ignore responses to PATH_CHALLENGEs sent on a previous path.
+	if m.challenge == nil || f.Data != *m.challenge {
+		return true, false
+	}
+	m.challenge = nil
+	m.validatedAddr = nil
+	m.validationDeadline = time.Time{}
+	return true, true
+}
+
+// ValidationDeadline is the time when path validation fails.
+// It is zero if no path validation is in progress.
+func (m *pathManager) ValidationDeadline() time.Time {
+	return m.validationDeadline
+}
+
+// OnValidationTimeout is called when path validation failed.
+// It returns the address the connection should return to.
+func (m *pathManager) OnValidationTimeout() net.Addr {
+	addr := m.validatedAddr
+	m.challenge = nil
+	m.validatedAddr = nil
+	m.validationDeadline = time.Time{}
+	return addr
+}
+
+// isProbingFrame says if a frame is a probing frame (RFC 9000, section 9.1).
+// Receiving a packet that only contains probing frames from a new address doesn't cause a migration.
+func isProbingFrame(f wire.Frame) bool {
+	switch f.(type) {
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame, *wire.NewConnectionIDFrame:
+		return true
+	default:
+		return false
+	}
+}
+
+func equalAddr(a, b net.Addr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if ua, ok := a.(*net.UDPAddr); ok {
+		if ub, ok := b.(*net.UDPAddr); ok {
+			return ua.Port == ub.Port && ua.IP.Equal(ub.IP) && ua.Zone == ub.Zone
+		}
+	}
+	return a.Network() == b.Network() && a.String() == b.String()
+}
+
+// isPortOnlyChange says if the peer only changed its port, as it's common for NAT rebindings.
+// In that case, the path most likely still has the same characteristics (RFC 9000, section 9.4).
+func isPortOnlyChange(a, b net.Addr) bool {
+	ua, ok := a.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	ub, ok := b.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	return ua.IP.Equal(ub.IP)
+}
@@ -0,0 +1,127 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path Manager", func() {
+	var (
+		m                    *pathManager
+		addr, rebound, other *net.UDPAddr
+	)
+
+	BeforeEach(func() {
+		m = newPathManager(3)
+		addr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}
+		rebound = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4321}
+		other = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}
+	})
+
+	It("doesn't migrate if packets are received from the current address", func() {
+		for pn := 1; pn <= 10; pn++ {
+			Expect(m.ReceivedPacket(addr, &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1234}, protocol.PacketNumber(pn), true)).To(BeFalse())
+		}
+	})
+
+	It("migrates after receiving enough packets from a new address", func() {
+		Expect(m.ReceivedPacket(addr, rebound, 1, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 2, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 3, true)).To(BeTrue())
+	})
+
+	It("migrates immediately", func() {
+		m = newPathManager(1)
+		Expect(m.ReceivedPacket(addr, rebound, 1, true)).To(BeTrue())
+	})
+
+	It("only counts non-probing packets", func() {
+		m = newPathManager(1)
+		Expect(m.ReceivedPacket(addr, rebound, 1, false)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 2, true)).To(BeTrue())
+	})
+
+	It("only counts packets with the largest packet number received so far", func() {
+		Expect(m.ReceivedPacket(addr, addr, 10, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 7, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 8, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 9, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 11, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 12, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 13, true)).To(BeTrue())
+	})
+
+	It("starts counting again when a packet is received from the current address", func() {
+		Expect(m.ReceivedPacket(addr, rebound, 1, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 2, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, addr, 3, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 4, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 5, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 6, true)).To(BeTrue())
+	})
+
+	It("starts counting again when a packet is received from a different address", func() {
+		Expect(m.ReceivedPacket(addr, rebound, 1, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, rebound, 2, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, other, 3, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, other, 4, true)).To(BeFalse())
+		Expect(m.ReceivedPacket(addr, other, 5, true)).To(BeTrue())
+	})
+
+	Context("validating the new address", func() {
+		It("validates the address", func() {
+			deadline := time.Now().Add(time.Second)
+			f := m.Migrated(addr, deadline)
+			Expect(m.ValidationDeadline()).To(Equal(deadline))
+			expected, validated := m.ReceivedPathResponse(&wire.PathResponseFrame{Data: f.Data})
+			Expect(expected).To(BeTrue())
+			Expect(validated).To(BeTrue())
+			Expect(m.ValidationDeadline()).To(BeZero())
+		})
+
+		It("uses different data for every PATH_CHALLENGE", func() {
+			f1 := m.Migrated(addr, time.Now())
+			f2 := m.Migrated(rebound, time.Now())
+			Expect(f1.Data).ToNot(Equal(f2.Data))
+		})
+
+		It("rejects PATH_RESPONSEs if it never sent a PATH_CHALLENGE", func() {
+			expected, _ := m.ReceivedPathResponse(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}})
+			Expect(expected).To(BeFalse())
+		})
+
+		It("ignores PATH_RESPONSEs that don't match the PATH_CHALLENGE", func() {
+			f := m.Migrated(addr, time.Now().Add(time.Second))
+			data := f.Data
+			data[0]++
+			expected, validated := m.ReceivedPathResponse(&wire.PathResponseFrame{Data: data})
+			Expect(expected).To(BeTrue())
+			Expect(validated).To(BeFalse())
+			Expect(m.ValidationDeadline()).ToNot(BeZero())
+		})
+
+		It("returns to the previous address if validation fails", func() {
+			m.Migrated(addr, time.Now())
+			Expect(m.OnValidationTimeout()).To(Equal(addr))
+			Expect(m.ValidationDeadline()).To(BeZero())
+		})
+
+		It("returns to the last validated address if the client migrates again during validation", func() {
+			m.Migrated(addr, time.Now())
+			m.Migrated(rebound, time.Now())
+			Expect(m.OnValidationTimeout()).To(Equal(addr))
+		})
+	})
+
+	It("tells if only the port changed", func() {
+		Expect(isPortOnlyChange(addr, rebound)).To(BeTrue())
+		Expect(isPortOnlyChange(addr, other)).To(BeFalse())
+		Expect(isPortOnlyChange(addr, &net.TCPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4321})).To(BeFalse())
+	})
+})
@@ -0,0 +1,262 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// The prAckNotifyQueue holds the PRAckNotify frames of a connection until they are acknowledged.
+// The send streams queue a frame when they skip lost data. Since frames can be declared lost and acknowledged
+// while the application is writing, it is safe for concurrent use.
+// A send stream is only completed once all its PRAckNotify frames were acknowledged, see WaitForStream.
+type prAckNotifyQueue struct {
+	mutex  sync.Mutex
+	frames []*wire.PRAckNotifyFrame
+	// the number of frames of every stream that were dequeued, but not acknowledged yet
+	inFlight map[protocol.StreamID]int
+	// called once all frames of the stream were acknowledged
+	waiters map[protocol.StreamID]func()
+}
+
+func newPRAckNotifyQueue() *prAckNotifyQueue {
+	return &prAckNotifyQueue{
+		inFlight: make(map[protocol.StreamID]int),
+		waiters:  make(map[protocol.StreamID]func()),
+	}
+}
+
+// Add queues a PRAckNotify frame.
+// Frames for the same stream that overlap or are adjacent to the new frame are merged into it,
+// such that a burst of skipped frames only results in a single PRAckNotify frame.
+func (q *prAckNotifyQueue) Add(f *wire.PRAckNotifyFrame) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.addLocked(f)
+}
+
+func (q *prAckNotifyQueue) addLocked(f *wire.PRAckNotifyFrame) {
+	var j int
+	for _, queued := range q.frames {
+		if queued.StreamID != f.StreamID || !mergePRAckNotifyFrames(f, queued) {
+			q.frames[j] = queued
+			j++
+		}
+	}
+	q.frames = append(q.frames[:j], f)
+}
+
+// Cancel removes the range [offset, offset+length) of a stream from the queued frames.
+// It is used when data that was skipped turns out to have been delivered.
+// Frames only partially covered by the range are trimmed or split, a FIN is never removed.
+// It returns true if any queued frame overlapped with the range.
+func (q *prAckNotifyQueue) Cancel(id protocol.StreamID, offset, length protocol.ByteCount) bool {
+	q.mutex.Lock()
+	end := offset + length
+	var canceled bool
+	frames := make([]*wire.PRAckNotifyFrame, 0, len(q.frames))
+	for _, f := range q.frames {
+		if f.StreamID != id || length == 0 || f.Offset >= end || f.Offset+f.DataLen() <= offset {
+			frames = append(frames, f)
+			continue
+		}
+		canceled = true
+		fEnd := f.Offset + f.DataLen()
+		if f.Offset < offset {
+			left := *f
+			left.PRDataLen = uint64(offset - f.Offset)
+			left.Fin = false
+			frames = append(frames, &left)
+		}
+		if fEnd > end || f.Fin {
+			// If the FIN is covered by the range, keep it in an empty frame.
+			right := *f
+			right.Offset = utils.Min(end, fEnd)
+			right.PRDataLen = uint64(fEnd - right.Offset)
+			frames = append(frames, &right)
+		}
+	}
+	q.frames = frames
+	var waiter func()
+	if canceled {
+		waiter = q.maybeDoneLocked(id)
+	}
+	q.mutex.Unlock()
+
+	if waiter != nil {
+		waiter()
+	}
+	return canceled
+}
+
+// RemoveStream removes all queued frames of a stream.
+// It is used when the stream is reset, since the peer doesn't need to skip data of a reset stream.
+func (q *prAckNotifyQueue) RemoveStream(id protocol.StreamID) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var j int
+	for _, f := range q.frames {
+		if f.StreamID != id {
+			q.frames[j] = f
+			j++
+		}
+	}
+	for i := j; i < len(q.frames); i++ {
+		q.frames[i] = nil
+	}
+	q.frames = q.frames[:j]
+	delete(q.inFlight, id)
+	delete(q.waiters, id)
+}
+
+// HasData says if any frames are queued.
+// It may be called on a nil prAckNotifyQueue.
+func (q *prAckNotifyQueue) HasData() bool {
+	if q == nil {
+		return false
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.frames) > 0
+}
+
+// PopAll dequeues all frames, in the order they were queued.
+// It may be called on a nil prAckNotifyQueue.
+func (q *prAckNotifyQueue) PopAll() []*wire.PRAckNotifyFrame {
+	if q == nil {
+		return nil
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	frames := q.frames
+	q.frames = nil
+	for _, f := range frames {
+		q.inFlight[f.StreamID]++
+	}
+	return frames
+}
+
+// Pop dequeues the first frame that is not longer than maxLen.
+// It returns nil if no frame fits. It may be called on a nil prAckNotifyQueue.
+func (q *prAckNotifyQueue) Pop(maxLen protocol.ByteCount, v protocol.VersionNumber) *wire.PRAckNotifyFrame {
+	if q == nil {
+		return nil
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, f := range q.frames {
+		if f.Length(v) > maxLen {
+			continue
+		}
+		copy(q.frames[i:], q.frames[i+1:])
+		q.frames[len(q.frames)-1] = nil
+		q.frames = q.frames[:len(q.frames)-1]
+		q.inFlight[f.StreamID]++
+		return f
+	}
+	return nil
+}
+
+// OnAcked is called when a dequeued frame is acknowledged.
+func (q *prAckNotifyQueue) OnAcked(f wire.Frame) {
+	id := f.(*wire.PRAckNotifyFrame).StreamID
+	q.mutex.Lock()
+	q.frameDoneLocked(id)
+	waiter := q.maybeDoneLocked(id)
+	q.mutex.Unlock()
+
+	if waiter != nil {
+		waiter()
+	}
+}
+
+// OnLost is called when a dequeued frame is declared lost.
+// The frame is queued again, unless the stream was removed in the meantime.
+func (q *prAckNotifyQueue) OnLost(f wire.Frame) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	nf := f.(*wire.PRAckNotifyFrame)
+	if q.frameDoneLocked(nf.StreamID) {
+		q.addLocked(nf)
+	}
+}
+
+// frameDoneLocked stops tracking a dequeued frame of the stream.
+// It returns false if the stream was removed after the frame was dequeued.
+func (q *prAckNotifyQueue) frameDoneLocked(id protocol.StreamID) bool {
+	n, ok := q.inFlight[id]
+	if !ok {
+		return false
+	}
+	if n <= 1 {
+		delete(q.inFlight, id)
+	} else {
+		q.inFlight[id]--
+	}
+	return true
+}
+
+// WaitForStream says if the stream has PRAckNotify frames that weren't acknowledged yet.
+// If it does, done is called once all of them were acknowledged (or canceled).
+// It may be called on a nil prAckNotifyQueue.
+func (q *prAckNotifyQueue) WaitForStream(id protocol.StreamID, done func()) bool {
+	if q == nil {
+		return false
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if !q.hasPendingLocked(id) {
+		return false
+	}
+	q.waiters[id] = done
+	return true
+}
+
+func (q *prAckNotifyQueue) hasPendingLocked(id protocol.StreamID) bool {
+	if q.inFlight[id] > 0 {
+		return true
+	}
+	for _, f := range q.frames {
+		if f.StreamID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// maybeDoneLocked returns the function waiting for the frames of the stream, if none are pending any more.
+// The caller must call it after releasing the mutex.
+func (q *prAckNotifyQueue) maybeDoneLocked(id protocol.StreamID) func() {
+	waiter, ok := q.waiters[id]
+	if !ok || q.hasPendingLocked(id) {
+		return nil
+	}
+	delete(q.waiters, id)
+	return waiter
+}
+
+// mergePRAckNotifyFrames extends f to also cover the range of q.
+// It returns false if the ranges are neither overlapping nor adjacent.
+func mergePRAckNotifyFrames(f, q *wire.PRAckNotifyFrame) bool {
+	start, end := f.Offset, f.Offset+f.DataLen()
+	qStart, qEnd := q.Offset, q.Offset+q.DataLen()
+	if qEnd < start || end < qStart {
+		return false
+	}
+	switch {
+	case qEnd > end:
+		f.Fin = q.Fin
+	case qEnd == end:
+		f.Fin = f.Fin || q.Fin
+	}
+	f.Offset = utils.Min(start, qStart)
+	f.PRDataLen = uint64(utils.Max(end, qEnd) - f.Offset)
+	return true
+}
@@ -0,0 +1,200 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PRAckNotify queue", func() {
+	var q *prAckNotifyQueue
+
+	BeforeEach(func() {
+		q = newPRAckNotifyQueue()
+	})
+
+	notifyFrame := func(id protocol.StreamID, offset protocol.ByteCount, dataLen uint64) *wire.PRAckNotifyFrame {
+		return &wire.PRAckNotifyFrame{
+			StreamID:       id,
+			Offset:         offset,
+			PRDataLen:      dataLen,
+			DataLenPresent: true,
+			PTDA:           PTDAProbability,
+			P:              true,
+		}
+	}
+
+	It("queues frames for ranges that are not adjacent", func() {
+		q.Add(notifyFrame(4, 0, 10))
+		q.Add(notifyFrame(4, 20, 10))
+		Expect(q.frames).To(HaveLen(2))
+	})
+
+	It("doesn't merge frames for different streams", func() {
+		q.Add(notifyFrame(4, 0, 10))
+		q.Add(notifyFrame(8, 10, 10))
+		Expect(q.frames).To(HaveLen(2))
+	})
+
+	It("merges adjacent ranges", func() {
+		q.Add(notifyFrame(4, 10, 10))
+		q.Add(notifyFrame(4, 20, 5))
+		q.Add(notifyFrame(4, 0, 10))
+		Expect(q.frames).To(HaveLen(1))
+		f := q.frames[0]
+		Expect(f.Offset).To(BeZero())
+		Expect(f.DataLen()).To(Equal(protocol.ByteCount(25)))
+	})
+
+	It("merges overlapping ranges", func() {
+		q.Add(notifyFrame(4, 10, 10))
+		q.Add(notifyFrame(4, 5, 10))
+		Expect(q.frames).To(HaveLen(1))
+		f := q.frames[0]
+		Expect(f.Offset).To(Equal(protocol.ByteCount(5)))
+		Expect(f.DataLen()).To(Equal(protocol.ByteCount(15)))
+	})
+
+	It("merges a range that closes a gap between two queued ranges", func() {
+		q.Add(notifyFrame(4, 0, 10))
+		q.Add(notifyFrame(4, 20, 10))
+		q.Add(notifyFrame(4, 10, 10))
+		Expect(q.frames).To(HaveLen(1))
+		f := q.frames[0]
+		Expect(f.Offset).To(BeZero())
+		Expect(f.DataLen()).To(Equal(protocol.ByteCount(30)))
+	})
+
+	It("keeps the FIN of the last range", func() {
+		last := notifyFrame(4, 10, 10)
+		last.Fin = true
+		q.Add(last)
+		q.Add(notifyFrame(4, 0, 10))
+		Expect(q.frames).To(HaveLen(1))
+		Expect(q.frames[0].Fin).To(BeTrue())
+	})
+
+	Context("canceling", func() {
+		It("cancels a frame covered by the range", func() {
+			q.Add(notifyFrame(4, 10, 10))
+			q.Add(notifyFrame(8, 10, 10))
+			Expect(q.Cancel(4, 10, 10)).To(BeTrue())
+			Expect(q.frames).To(Equal([]*wire.PRAckNotifyFrame{notifyFrame(8, 10, 10)}))
+		})
+
+		It("doesn't cancel anything if no frame overlaps", func() {
+			q.Add(notifyFrame(4, 10, 10))
+			Expect(q.Cancel(4, 20, 10)).To(BeFalse())
+			Expect(q.Cancel(8, 10, 10)).To(BeFalse())
+			Expect(q.frames).To(Equal([]*wire.PRAckNotifyFrame{notifyFrame(4, 10, 10)}))
+		})
+
+		It("splits a merged frame", func() {
+			q.Add(notifyFrame(4, 0, 10))
+			q.Add(notifyFrame(4, 10, 10))
+			q.Add(notifyFrame(4, 20, 10))
+			Expect(q.Cancel(4, 10, 10)).To(BeTrue())
+			Expect(q.frames).To(Equal([]*wire.PRAckNotifyFrame{notifyFrame(4, 0, 10), notifyFrame(4, 20, 10)}))
+		})
+
+		It("keeps the FIN", func() {
+			last := notifyFrame(4, 10, 10)
+			last.Fin = true
+			q.Add(notifyFrame(4, 0, 10))
+			q.Add(last)
+			Expect(q.Cancel(4, 5, 15)).To(BeTrue())
+			fin := notifyFrame(4, 20, 0)
+			fin.Fin = true
+			Expect(q.frames).To(Equal([]*wire.PRAckNotifyFrame{notifyFrame(4, 0, 5), fin}))
+		})
+	})
+
+	It("removes the frames of a stream", func() {
+		q.Add(notifyFrame(4, 0, 10))
+		q.Add(notifyFrame(8, 0, 10))
+		q.Add(notifyFrame(4, 20, 10))
+		q.RemoveStream(4)
+		Expect(q.frames).To(Equal([]*wire.PRAckNotifyFrame{notifyFrame(8, 0, 10)}))
+	})
+
+	Context("waiting for the frames of a stream", func() {
+		It("doesn't wait if no frames are pending", func() {
+			q.Add(notifyFrame(8, 0, 10))
+			Expect(q.WaitForStream(4, func() { Fail("unexpected call") })).To(BeFalse())
+		})
+
+		It("waits until all frames were acknowledged", func() {
+			q.Add(notifyFrame(4, 0, 10))
+			q.Add(notifyFrame(4, 20, 10))
+			var done bool
+			Expect(q.WaitForStream(4, func() { done = true })).To(BeTrue())
+			frames := q.PopAll()
+			Expect(frames).To(HaveLen(2))
+			q.OnAcked(frames[0])
+			Expect(done).To(BeFalse())
+			q.OnAcked(frames[1])
+			Expect(done).To(BeTrue())
+		})
+
+		It("queues lost frames again", func() {
+			q.Add(notifyFrame(4, 0, 10))
+			var done bool
+			Expect(q.WaitForStream(4, func() { done = true })).To(BeTrue())
+			frames := q.PopAll()
+			q.OnLost(frames[0])
+			Expect(done).To(BeFalse())
+			Expect(q.frames).To(Equal(frames))
+			q.OnAcked(q.PopAll()[0])
+			Expect(done).To(BeTrue())
+		})
+
+		It("stops waiting when the last frame is canceled", func() {
+			q.Add(notifyFrame(4, 0, 10))
+			var done bool
+			Expect(q.WaitForStream(4, func() { done = true })).To(BeTrue())
+			Expect(q.Cancel(4, 0, 10)).To(BeTrue())
+			Expect(done).To(BeTrue())
+		})
+
+		It("doesn't call the function for a stream that was removed", func() {
+			q.Add(notifyFrame(4, 0, 10))
+			Expect(q.WaitForStream(4, func() { Fail("unexpected call") })).To(BeTrue())
+			frames := q.PopAll()
+			q.RemoveStream(4)
+			q.OnAcked(frames[0])
+		})
+
+		It("doesn't queue lost frames of a stream that was removed", func() {
+			q.Add(notifyFrame(4, 0, 10))
+			frames := q.PopAll()
+			q.RemoveStream(4)
+			q.OnLost(frames[0])
+			Expect(q.HasData()).To(BeFalse())
+		})
+	})
+
+	It("dequeues all frames", func() {
+		q.Add(notifyFrame(4, 0, 10))
+		q.Add(notifyFrame(8, 0, 10))
+		Expect(q.HasData()).To(BeTrue())
+		Expect(q.PopAll()).To(Equal([]*wire.PRAckNotifyFrame{notifyFrame(4, 0, 10), notifyFrame(8, 0, 10)}))
+		Expect(q.HasData()).To(BeFalse())
+		Expect(q.PopAll()).To(BeEmpty())
+	})
+
+	It("dequeues the first frame that fits", func() {
+		small := notifyFrame(4, 0, 10)
+		large := notifyFrame(0x1337, 0x1337, 10)
+		q.Add(large)
+		q.Add(small)
+		Expect(q.Pop(small.Length(protocol.Version1)-1, protocol.Version1)).To(BeNil())
+		Expect(q.Pop(small.Length(protocol.Version1), protocol.Version1)).To(Equal(small))
+		Expect(q.frames).To(Equal([]*wire.PRAckNotifyFrame{large}))
+		// the frame is in flight until it is acknowledged
+		Expect(q.WaitForStream(4, func() {})).To(BeTrue())
+		Expect(q.Pop(protocol.MaxByteCount, protocol.Version1)).To(Equal(large))
+		Expect(q.HasData()).To(BeFalse())
+	})
+})
@@ -0,0 +1,62 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// prFrameMetaMaxChunkSize is the maximum number of prFrameMetas a prFrameMetaSlab allocates at once.
+const prFrameMetaMaxChunkSize = 64
+
+// A prFrameMeta holds the state of a sent PRSTREAM frame that is needed after the frame was declared lost.
+// The frame is returned to the pool when its data is skipped, so its range is remembered for a late acknowledgement.
+type prFrameMeta struct {
+	stream         *sendStream
+	offset, length protocol.ByteCount
+	skipped        bool
+	// notified is set if a PRAckNotify frame was queued when the frame was sent, see PTDAAbandon
+	notified bool
+}
+
+func (m *prFrameMeta) onLost(f wire.Frame) {
+	if m.notified {
+		// The peer is already told to skip the data.
+		m.skipped = true
+		m.stream.prStreamFrameDone(f.(*wire.PRStreamFrame), false)
+		return
+	}
+	m.skipped = m.stream.prQueueRetransmission(f)
+}
+
+func (m *prFrameMeta) onAcked(f wire.Frame) {
+	m.stream.prNotifiedFrameAcked(f.(*wire.PRStreamFrame))
+}
+
+func (m *prFrameMeta) onAckedAfterLoss() {
+	if m.skipped {
+		m.stream.prSkipWasSpurious(m.offset, m.length)
+	}
+}
+
+// A prFrameMetaSlab allocates prFrameMetas in chunks,
+// such that sending a PRSTREAM frame doesn't need a separate allocation for its metadata.
+// prFrameMetas are never reused. A chunk is garbage collected once none of its prFrameMetas is referenced by a sent packet.
+// The chunk size grows with the number of frames, such that streams that only send a few frames don't allocate a full chunk.
+type prFrameMetaSlab struct {
+	chunk     []prFrameMeta
+	chunkSize int
+}
+
+func (s *prFrameMetaSlab) get() *prFrameMeta {
+	if len(s.chunk) == 0 {
+		if s.chunkSize == 0 {
+			s.chunkSize = 4
+		} else if s.chunkSize < prFrameMetaMaxChunkSize {
+			s.chunkSize *= 2
+		}
+		s.chunk = make([]prFrameMeta, s.chunkSize)
+	}
+	m := &s.chunk[0]
+	s.chunk = s.chunk[1:]
+	return m
+}
@@ -0,0 +1,39 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR frame metadata slab", func() {
+	It("allocates chunks of increasing size", func() {
+		var slab prFrameMetaSlab
+		seen := make(map[*prFrameMeta]struct{})
+		get := func() {
+			m := slab.get()
+			Expect(*m).To(BeZero())
+			seen[m] = struct{}{}
+		}
+		get()
+		Expect(slab.chunkSize).To(Equal(4))
+		Expect(slab.chunk).To(HaveLen(3))
+		for i := 0; i < 3; i++ {
+			get()
+		}
+		Expect(slab.chunk).To(BeEmpty())
+		get()
+		Expect(slab.chunkSize).To(Equal(8))
+		Expect(slab.chunk).To(HaveLen(7))
+		for i := 0; i < 7+16+32+64+64; i++ {
+			get()
+		}
+		Expect(slab.chunkSize).To(Equal(prFrameMetaMaxChunkSize))
+		Expect(seen).To(HaveLen(4 + 8 + 16 + 32 + 64 + 64))
+	})
+
+	It("only reports late acknowledgements of skipped frames", func() {
+		m := &prFrameMeta{offset: 100, length: 10}
+		// prSkipWasSpurious would dereference the nil stream
+		Expect(m.onAckedAfterLoss).ToNot(Panic())
+	})
+})
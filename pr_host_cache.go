@@ -0,0 +1,60 @@
+package quic
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// prHostCacheTTL is the time a server is remembered as not supporting partial reliability.
+// After that, partial reliability is tried again, in case the server was updated.
+const prHostCacheTTL = 24 * time.Hour
+
+// The prHostCache remembers the servers that closed a connection because they didn't understand a frame used for partial reliability.
+// This happens if a server sends the partial_reliability transport parameter, but doesn't actually support partial reliability.
+// Connections to these servers are established without partial reliability, see PRConfig.Disabled.
+type prHostCache struct {
+	mutex sync.Mutex
+	hosts map[string]time.Time // the time the entry expires
+}
+
+// prIncapableHosts is shared by all connections of the process.
+// Servers are identified by their address.
+var prIncapableHosts = newPRHostCache()
+
+func newPRHostCache() *prHostCache {
+	return &prHostCache{hosts: make(map[string]time.Time)}
+}
+
+func (c *prHostCache) Add(host string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for h, expiry := range c.hosts {
+		if !now.Before(expiry) {
+			delete(c.hosts, h)
+		}
+	}
+	c.hosts[host] = now.Add(prHostCacheTTL)
+}
+
+func (c *prHostCache) Contains(host string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expiry, ok := c.hosts[host]
+	return ok && time.Now().Before(expiry)
+}
+
+// isPRFrameRejection says if the peer closed the connection because it didn't understand a frame used for partial reliability.
+func isPRFrameRejection(err error) bool {
+	var transportErr *qerr.TransportError
+	return errors.As(err, &transportErr) &&
+		transportErr.Remote &&
+		transportErr.ErrorCode == qerr.FrameEncodingError &&
+		wire.IsPRFrameType(transportErr.FrameType)
+}
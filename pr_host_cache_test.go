@@ -0,0 +1,43 @@
+package quic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR host cache", func() {
+	It("remembers hosts", func() {
+		c := newPRHostCache()
+		Expect(c.Contains("192.0.2.1:443")).To(BeFalse())
+		c.Add("192.0.2.1:443")
+		Expect(c.Contains("192.0.2.1:443")).To(BeTrue())
+		Expect(c.Contains("192.0.2.2:443")).To(BeFalse())
+	})
+
+	It("forgets hosts after the TTL", func() {
+		c := newPRHostCache()
+		c.Add("192.0.2.1:443")
+		c.hosts["192.0.2.1:443"] = time.Now().Add(-time.Second)
+		Expect(c.Contains("192.0.2.1:443")).To(BeFalse())
+		c.Add("192.0.2.2:443")
+		Expect(c.hosts).To(HaveLen(1))
+		Expect(c.hosts).To(HaveKey("192.0.2.2:443"))
+	})
+
+	It("detects PR frame rejections", func() {
+		Expect(isPRFrameRejection(&qerr.TransportError{Remote: true, ErrorCode: qerr.FrameEncodingError, FrameType: 0x48})).To(BeTrue())
+		Expect(isPRFrameRejection(&qerr.TransportError{Remote: true, ErrorCode: qerr.FrameEncodingError, FrameType: 0x55})).To(BeTrue())
+		// not a PR frame
+		Expect(isPRFrameRejection(&qerr.TransportError{Remote: true, ErrorCode: qerr.FrameEncodingError, FrameType: 0x8})).To(BeFalse())
+		// closed by us
+		Expect(isPRFrameRejection(&qerr.TransportError{ErrorCode: qerr.FrameEncodingError, FrameType: 0x48})).To(BeFalse())
+		// a different error code
+		Expect(isPRFrameRejection(&qerr.TransportError{Remote: true, ErrorCode: qerr.ProtocolViolation, FrameType: 0x48})).To(BeFalse())
+		Expect(isPRFrameRejection(errors.New("foobar"))).To(BeFalse())
+	})
+})
@@ -1,12 +1,381 @@
 package quic
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
-	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// The PTDA values select the partial reliability policy of a PRPolicy.
+// They are carried in the high nibble of the PTDA byte of PR frames.
+const (
+	PTDAProbability byte = 0x80 // P: 概率重传, Value is the retransmission probability in 1/10000
+	PTDATimes       byte = 0x40 // T: 次数重传, Value is the maximum number of retransmissions
+	PTDADeadline    byte = 0x20 // D: 时限重传, Value is the deadline in milliseconds
+	PTDAPriority    byte = 0x10 // A: 优先级重传, Value is the priority of the content
+)
+
+// maxPRProbability is the Value of a PTDAProbability policy that always retransmits.
+const maxPRProbability = 10000
+
+// PTDAAbandon selects the immediate-abandon mode: 立即放弃, lost data is never retransmitted.
+// A PRAckNotify frame is queued for every frame as soon as it is sent, such that the peer skips lost data
+// without waiting for loss detection. If the frame is acknowledged before the PRAckNotify frame was sent, it is not sent.
+// If skipping the data would exceed the peer's maximum skip ratio, the PRAckNotify frame is only sent once the frame is declared lost.
+// This approximates datagram semantics while keeping the stream ordered,
+// and is useful when loss detection takes longer than the data stays useful anyway.
+// Value is ignored. The mode only affects the sender, it uses the low nibble of the PTDA byte.
+const PTDAAbandon byte = 0x08
+
+// A PRPolicy determines how lost stream data is treated.
+// The zero value is the fully reliable policy: lost data is always retransmitted.
+type PRPolicy struct {
+	// PTDA is one of the PTDA values, or 0 for reliable delivery.
+	PTDA byte
+	// Value is the parameter of the policy (the PtdaC field on the wire).
+	// It is encoded as a varint, and must not be larger than quicvarint.Max (2^62-1).
+	Value uint64
+}
+
+// IsReliable says if data written with this policy is always retransmitted.
+func (p PRPolicy) IsReliable() bool {
+	return p.PTDA == 0
+}
+
+func (p PRPolicy) String() string {
+	switch p.PTDA {
+	case 0:
+		return "reliable"
+	case PTDAProbability:
+		return fmt.Sprintf("probability(%d)", p.Value)
+	case PTDATimes:
+		return fmt.Sprintf("times(%d)", p.Value)
+	case PTDADeadline:
+		return fmt.Sprintf("deadline(%dms)", p.Value)
+	case PTDAPriority:
+		return fmt.Sprintf("priority(%d)", p.Value)
+	case PTDAAbandon:
+		return "abandon"
+	default:
+		return fmt.Sprintf("unknown PTDA %#x (%d)", p.PTDA, p.Value)
+	}
+}
+
+func (p PRPolicy) validate() error {
+	switch p.PTDA {
+	case 0, PTDAProbability, PTDATimes, PTDADeadline, PTDAPriority, PTDAAbandon:
+	default:
+		return fmt.Errorf("invalid PR policy: PTDA %#x", p.PTDA)
+	}
+	// Value is sent as a varint. Reject values that can't be encoded, instead of failing when packing a packet.
+	if !quicvarint.Fits(p.Value) {
+		return fmt.Errorf("invalid PR policy: value %#x larger than %#x", p.Value, uint64(quicvarint.Max))
+	}
+	switch p.PTDA {
+	case PTDADeadline:
+		// All data would expire right away.
+		if p.Value == 0 {
+			return errors.New("invalid PR policy: deadline of 0ms")
+		}
+	case PTDAProbability:
+		if p.Value > maxPRProbability {
+			return fmt.Errorf("invalid PR policy: probability %d larger than %d", p.Value, maxPRProbability)
+		}
+	}
+	return nil
+}
+
+// defaultPRPolicy is the policy used by Write if no other policy was configured.
+// It is derived from the deprecated package-level variables.
+func defaultPRPolicy() PRPolicy {
+	if !PR_ENABLED {
+		return PRPolicy{}
+	}
+	return PRPolicy{PTDA: PTDA, Value: PtadC}
+}
+
+// A PRPolicySource says at which level of the resolution chain the policy used by Write was configured.
+// From the highest to the lowest precedence, the policy used for writing data is:
+//  1. the policy passed to SendStream.WriteWithPolicy, for that data only
+//  2. the stream policy, set by SendStream.SetPRPolicy or IncomingStreamDecision.Policy
+//  3. the connection policy, set by Connection.SetPRPolicy
+//  4. PRConfig.DefaultPolicy
+//  5. the deprecated package-level variables PR_ENABLED, PTDA and PtadC
+type PRPolicySource uint8
+
+const (
+	// PRPolicySourceGlobal means the policy is derived from the deprecated package-level variables.
+	PRPolicySourceGlobal PRPolicySource = iota
+	// PRPolicySourceConfig means the policy was set by PRConfig.DefaultPolicy.
+	PRPolicySourceConfig
+	// PRPolicySourceConnection means the policy was set by Connection.SetPRPolicy.
+	PRPolicySourceConnection
+	// PRPolicySourceStream means the policy was set for the stream.
+	PRPolicySourceStream
 )
 
+func (s PRPolicySource) String() string {
+	switch s {
+	case PRPolicySourceGlobal:
+		return "global"
+	case PRPolicySourceConfig:
+		return "config"
+	case PRPolicySourceConnection:
+		return "connection"
+	case PRPolicySourceStream:
+		return "stream"
+	default:
+		return "unknown PR policy source"
+	}
+}
+
+// The prPolicyChain resolves the policy of the streams of a connection that don't have a stream policy.
+type prPolicyChain struct {
+	config PRConfig
+
+	mutex         sync.RWMutex
+	connPolicy    *PRPolicy
+	numStreams    int  // the number of PR streams, see PRConfig.MaxPRStreams
+	peerRefusedPR bool // the peer didn't send the partial_reliability transport parameter
+	// the max_skip_ratio transport parameter of the peer, in units of 1/protocol.SkipRatioScale, 0 if not limited
+	peerMaxSkipRatio uint64
+
+	// the random number generator of the probability policy, see PRConfig.RandSeed
+	randSeed int64
+	rand     *rand.Rand
+
+	// the PRAckNotify frames of the connection that weren't packed yet
+	ackNotifies *prAckNotifyQueue
+
+	// the RTT and ACK statistics of the connection, see SetAckMetrics
+	smoothedRTT time.Duration
+	ackMetrics  ackhandler.AckMetrics
+}
+
+// PRStats are the statistics of a connection used when evaluating the deadline policy, see Connection.PRStats.
+// They also contain the bandwidth estimate of the congestion controller,
+// which applications can use to adapt the rate of the data they send, e.g. the bitrate of a video.
+type PRStats struct {
+	SmoothedRTT time.Duration
+	// AckDelay is the moving average of the ack delay reported by the peer, MaxAckDelay the largest one.
+	AckDelay    time.Duration
+	MaxAckDelay time.Duration
+	// ReorderingDistance is the largest number of packets a packet was reordered by, ReorderingTime the largest time.
+	ReorderingDistance uint64
+	ReorderingTime     time.Duration
+	// SmoothedReorderingTime is the moving average of the reordering time.
+	// Unlike ReorderingTime, it decays once packets aren't reordered any more.
+	SmoothedReorderingTime time.Duration
+	// DeadlineMargin is subtracted from the deadline when deciding if lost data written with the deadline policy is retransmitted:
+	// a retransmission arrives after half an RTT, and is acknowledged after the ack delay,
+	// or even later if packets are reordered (by SmoothedReorderingTime).
+	DeadlineMargin time.Duration
+	// BandwidthEstimate is the bandwidth estimated by the congestion controller, in bits per second.
+	// It is 0 until the RTT was measured.
+	BandwidthEstimate uint64
+}
+
+// A lockedRandSource is a rand.Source that is safe for concurrent use.
+// It allows all streams of a connection to share a single seeded random number generator.
+type lockedRandSource struct {
+	mutex sync.Mutex
+	src   rand.Source
+}
+
+func (s *lockedRandSource) Int63() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedRandSource) Seed(seed int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.src.Seed(seed)
+}
+
+func newPRPolicyChain(config PRConfig) *prPolicyChain {
+	seed := config.RandSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &prPolicyChain{
+		config:      config,
+		randSeed:    seed,
+		rand:        rand.New(&lockedRandSource{src: rand.NewSource(seed)}),
+		ackNotifies: newPRAckNotifyQueue(),
+	}
+}
+
+// RandSeed returns the seed of the random number generator, see PRConfig.RandSeed.
+func (c *prPolicyChain) RandSeed() int64 {
+	return c.randSeed
+}
+
+// Rand returns the random number generator that decides if lost data sent with the probability policy is retransmitted.
+// It is shared by all streams of the connection, and is safe for concurrent use.
+// It may be called on a nil prPolicyChain, in that case, it returns a new random number generator.
+func (c *prPolicyChain) Rand() *rand.Rand {
+	if c == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return c.rand
+}
+
+func (c *prPolicyChain) SetConnectionPolicy(p *PRPolicy) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if p == nil {
+		c.connPolicy = nil
+		return
+	}
+	policy := *p
+	c.connPolicy = &policy
+}
+
+// Get returns the effective policy.
+// It may be called on a nil prPolicyChain, in that case, it returns the global policy.
+func (c *prPolicyChain) Get() (PRPolicy, PRPolicySource) {
+	if c == nil {
+		return defaultPRPolicy(), PRPolicySourceGlobal
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.connPolicy != nil {
+		return *c.connPolicy, PRPolicySourceConnection
+	}
+	if c.config.DefaultPolicy != nil {
+		return *c.config.DefaultPolicy, PRPolicySourceConfig
+	}
+	return defaultPRPolicy(), PRPolicySourceGlobal
+}
+
+// SetPeerSupportsPR records if the peer sent the partial_reliability transport parameter.
+// Until the peer's transport parameters are known, partial reliability is assumed to be supported.
+func (c *prPolicyChain) SetPeerSupportsPR(supported bool) {
+	c.mutex.Lock()
+	c.peerRefusedPR = !supported
+	c.mutex.Unlock()
+}
+
+// SetPeerMaxSkipRatio records the max_skip_ratio transport parameter of the peer, see PRConfig.MaxSkipRatio.
+func (c *prPolicyChain) SetPeerMaxSkipRatio(ratio uint64) {
+	c.mutex.Lock()
+	c.peerMaxSkipRatio = ratio
+	c.mutex.Unlock()
+}
+
+// SkipAllowed says if a stream may have skipped bytes of the sent bytes, without exceeding the peer's max_skip_ratio.
+// It may be called on a nil prPolicyChain, in that case, the skip ratio is not limited.
+func (c *prPolicyChain) SkipAllowed(skipped, sent protocol.ByteCount) bool {
+	if c == nil {
+		return true
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.peerMaxSkipRatio == 0 {
+		return true
+	}
+	return float64(skipped)*protocol.SkipRatioScale <= float64(sent)*float64(c.peerMaxSkipRatio)
+}
+
+// Enabled says if partially reliable data can be sent, see PRConfig.Disabled.
+// It may be called on a nil prPolicyChain, in that case, partial reliability is enabled.
+func (c *prPolicyChain) Enabled() bool {
+	if c == nil {
+		return true
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return !c.config.Disabled && !c.peerRefusedPR
+}
+
+// acquireStream counts a stream towards the PR stream limits.
+// It returns false if a limit is reached.
+// It may be called on a nil prPolicyChain, in that case, the number of PR streams is not limited.
+func (c *prPolicyChain) acquireStream() bool {
+	if c == nil {
+		return true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.config.MaxPRStreams > 0 && c.numStreams >= c.config.MaxPRStreams {
+		return false
+	}
+	if c.config.StreamLimiter != nil && !c.config.StreamLimiter.acquire() {
+		return false
+	}
+	c.numStreams++
+	return true
+}
+
+// releaseStream must be called for every successful call to acquireStream, once the stream is completed.
+func (c *prPolicyChain) releaseStream() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.numStreams--
+	if c.config.StreamLimiter != nil {
+		c.config.StreamLimiter.release()
+	}
+}
+
+// maxSkipRatioParam returns the value of the max_skip_ratio transport parameter that we send.
+func maxSkipRatioParam(config PRConfig) uint64 {
+	if config.Disabled || config.MaxSkipRatio <= 0 || config.MaxSkipRatio >= 1 {
+		return 0
+	}
+	return uint64(math.Ceil(config.MaxSkipRatio * protocol.SkipRatioScale))
+}
+
+// A PRStreamLimiter limits the number of PR streams across connections, see PRConfig.StreamLimiter.
+type PRStreamLimiter struct {
+	mutex      sync.Mutex
+	max        int
+	numStreams int
+}
+
+// NewPRStreamLimiter creates a limiter that allows max PR streams at the same time.
+func NewPRStreamLimiter(max int) *PRStreamLimiter {
+	return &PRStreamLimiter{max: max}
+}
+
+// NumStreams returns the number of PR streams currently counted by the limiter.
+func (l *PRStreamLimiter) NumStreams() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.numStreams
+}
+
+func (l *PRStreamLimiter) acquire() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.numStreams >= l.max {
+		return false
+	}
+	l.numStreams++
+	return true
+}
+
+func (l *PRStreamLimiter) release() {
+	l.mutex.Lock()
+	l.numStreams--
+	l.mutex.Unlock()
+}
+
 // 1
 // 是否启用PR行为
+//
+// Deprecated: use PRConfig.DefaultPolicy, Connection.SetPRPolicy or SendStream.SetPRPolicy.
 var PR_ENABLED bool = true
 
 // PR策略选项
@@ -14,8 +383,8 @@ var P bool = true    // 概率重传
 var T bool           // 次数重传
 var D bool           // 时限重传
 var A bool           // 优先级重传（流、内容）
-var PTDA byte = 0x80 // PTDA的字节存储
-var PtadC uint64 = 0 // 存放PR策略选项对应的内容/值
+var PTDA byte = 0x80 // PTDA的字节存储, Deprecated: use PRConfig.DefaultPolicy
+var PtadC uint64 = 0 // 存放PR策略选项对应的内容/值, Deprecated: use PRConfig.DefaultPolicy
 var PR_ERROR error
 
 // ----------------------2----------------------------
@@ -57,9 +426,51 @@ var PR_ERROR error
 // var PtadC uint64   // 存放PR策略选项对应的内容/值
 // var PR_ERROR error
 
-// 存sendStream.prAckNotifyRetransmissionQueue中的PRAckNotify Frame
-// 供packetContents.retransmissionQueue获取
-var PRAckNotifyFrames []wire.Frame
-var pr_version protocol.VersionNumber
+// Frames_recv_num counts the frames received by all connections of the process.
+// It must be read using atomic.LoadInt64.
+var Frames_recv_num int64
+
+// SetAckMetrics records the RTT and ACK statistics of the connection.
+// They are updated whenever an ACK for 1-RTT packets is received.
+func (c *prPolicyChain) SetAckMetrics(m ackhandler.AckMetrics, smoothedRTT time.Duration) {
+	c.mutex.Lock()
+	c.ackMetrics = m
+	c.smoothedRTT = smoothedRTT
+	c.mutex.Unlock()
+}
+
+// Stats returns the statistics used when evaluating the deadline policy.
+// It may be called on a nil prPolicyChain, in that case, it returns zero statistics.
+func (c *prPolicyChain) Stats() PRStats {
+	if c == nil {
+		return PRStats{}
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return PRStats{
+		SmoothedRTT:            c.smoothedRTT,
+		AckDelay:               c.ackMetrics.SmoothedAckDelay,
+		MaxAckDelay:            c.ackMetrics.MaxAckDelay,
+		ReorderingDistance:     uint64(c.ackMetrics.ReorderingDistance),
+		ReorderingTime:         c.ackMetrics.ReorderingTime,
+		SmoothedReorderingTime: c.ackMetrics.SmoothedReorderingTime,
+		DeadlineMargin:         c.deadlineMargin(),
+		BandwidthEstimate:      uint64(c.ackMetrics.BandwidthEstimate),
+	}
+}
+
+// DeadlineMargin returns how long before the deadline lost data written with the deadline policy is skipped instead of retransmitted.
+// It may be called on a nil prPolicyChain, in that case, there's no margin.
+func (c *prPolicyChain) DeadlineMargin() time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.deadlineMargin()
+}
 
-var Frames_recv_num int
+// must be called after locking the mutex
+func (c *prPolicyChain) deadlineMargin() time.Duration {
+	return c.smoothedRTT/2 + c.ackMetrics.SmoothedAckDelay + c.ackMetrics.SmoothedReorderingTime
+}
@@ -0,0 +1,57 @@
+package quic
+
+import (
+	"fmt"
+)
+
+// ChunkMetadata describes the content of a chunk written by a ChunkWriter.
+// A PRPolicyPreset uses it to choose the policy of the chunk.
+type ChunkMetadata struct {
+	// Keyframe says that the chunk contains a keyframe, i.e. that the following chunks can't be decoded without it.
+	Keyframe bool
+}
+
+// A PRPolicyPreset is a composite policy: it chooses the policy of every chunk depending on its content.
+// Presets are used by ChunkWriter.WriteChunkWithMetadata.
+type PRPolicyPreset interface {
+	// Name returns the name of the preset, e.g. for logging.
+	Name() string
+	// Policy returns the policy of a chunk.
+	Policy(ChunkMetadata) PRPolicy
+}
+
+// PRPresetProtectKeyframes is the name of the preset returned by ProtectKeyframes.
+const PRPresetProtectKeyframes = "protect-keyframes"
+
+// ProtectKeyframes returns the "protect keyframes" preset: keyframes are always retransmitted,
+// since losing them breaks the decoding of all chunks that depend on them,
+// while all other chunks are sent with the policy other.
+// other must be a deadline (PTDADeadline) or a probability (PTDAProbability) policy.
+func ProtectKeyframes(other PRPolicy) (PRPolicyPreset, error) {
+	if other.PTDA != PTDADeadline && other.PTDA != PTDAProbability {
+		return nil, fmt.Errorf("%s: unsupported policy for non-keyframes: %s", PRPresetProtectKeyframes, other)
+	}
+	if err := other.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", PRPresetProtectKeyframes, err)
+	}
+	return &protectKeyframesPreset{other: other}, nil
+}
+
+type protectKeyframesPreset struct {
+	other PRPolicy
+}
+
+var _ PRPolicyPreset = &protectKeyframesPreset{}
+
+func (p *protectKeyframesPreset) Name() string { return PRPresetProtectKeyframes }
+
+func (p *protectKeyframesPreset) Policy(m ChunkMetadata) PRPolicy {
+	if m.Keyframe {
+		return PRPolicy{}
+	}
+	return p.other
+}
+
+func (p *protectKeyframesPreset) String() string {
+	return fmt.Sprintf("%s(%s)", PRPresetProtectKeyframes, p.other)
+}
@@ -0,0 +1,41 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR policy presets", func() {
+	Context("protecting keyframes", func() {
+		It("retransmits keyframes, and uses the policy for other chunks", func() {
+			policy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+			preset, err := ProtectKeyframes(policy)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(preset.Name()).To(Equal("protect-keyframes"))
+			Expect(preset.Policy(ChunkMetadata{Keyframe: true})).To(Equal(PRPolicy{}))
+			Expect(preset.Policy(ChunkMetadata{})).To(Equal(policy))
+			Expect(preset).To(BeAssignableToTypeOf(&protectKeyframesPreset{}))
+			Expect(preset.(*protectKeyframesPreset).String()).To(Equal("protect-keyframes(deadline(100ms))"))
+		})
+
+		It("accepts probability policies", func() {
+			preset, err := ProtectKeyframes(PRPolicy{PTDA: PTDAProbability, Value: 5000})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(preset.Policy(ChunkMetadata{})).To(Equal(PRPolicy{PTDA: PTDAProbability, Value: 5000}))
+		})
+
+		It("rejects other policies", func() {
+			_, err := ProtectKeyframes(PRPolicy{})
+			Expect(err).To(MatchError("protect-keyframes: unsupported policy for non-keyframes: reliable"))
+			_, err = ProtectKeyframes(PRPolicy{PTDA: PTDATimes, Value: 1})
+			Expect(err).To(MatchError("protect-keyframes: unsupported policy for non-keyframes: times(1)"))
+		})
+
+		It("rejects invalid policies", func() {
+			_, err := ProtectKeyframes(PRPolicy{PTDA: PTDADeadline})
+			Expect(err).To(MatchError("protect-keyframes: invalid PR policy: deadline of 0ms"))
+			_, err = ProtectKeyframes(PRPolicy{PTDA: PTDAProbability, Value: 10001})
+			Expect(err).To(MatchError("protect-keyframes: invalid PR policy: probability 10001 larger than 10000"))
+		})
+	})
+})
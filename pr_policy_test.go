@@ -0,0 +1,182 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PR policies", func() {
+	It("validates policies", func() {
+		Expect(PRPolicy{}.validate()).To(Succeed())
+		Expect(PRPolicy{PTDA: PTDADeadline, Value: 100}.validate()).To(Succeed())
+		Expect(PRPolicy{PTDA: PTDAAbandon}.validate()).To(Succeed())
+		Expect(PRPolicy{PTDA: 0x81}.validate()).To(MatchError("invalid PR policy: PTDA 0x81"))
+		Expect(PRPolicy{PTDA: PTDADeadline}.validate()).To(MatchError("invalid PR policy: deadline of 0ms"))
+		Expect(PRPolicy{PTDA: PTDAProbability, Value: 10000}.validate()).To(Succeed())
+		Expect(PRPolicy{PTDA: PTDAProbability, Value: 10001}.validate()).To(MatchError("invalid PR policy: probability 10001 larger than 10000"))
+		Expect(PRPolicy{PTDA: PTDADeadline, Value: quicvarint.Max}.validate()).To(Succeed())
+		Expect(PRPolicy{PTDA: PTDADeadline, Value: quicvarint.Max + 1}.validate()).To(MatchError("invalid PR policy: value 0x4000000000000000 larger than 0x3fffffffffffffff"))
+	})
+
+	It("has a string representation", func() {
+		Expect(PRPolicy{}.String()).To(Equal("reliable"))
+		Expect(PRPolicy{PTDA: PTDAProbability, Value: 5000}.String()).To(Equal("probability(5000)"))
+		Expect(PRPolicy{PTDA: PTDATimes, Value: 3}.String()).To(Equal("times(3)"))
+		Expect(PRPolicy{PTDA: PTDADeadline, Value: 100}.String()).To(Equal("deadline(100ms)"))
+		Expect(PRPolicy{PTDA: PTDAPriority, Value: 2}.String()).To(Equal("priority(2)"))
+		Expect(PRPolicy{PTDA: PTDAAbandon}.String()).To(Equal("abandon"))
+		Expect(PRPolicy{PTDA: 0x81, Value: 1}.String()).To(Equal("unknown PTDA 0x81 (1)"))
+	})
+
+	Context("resolving the policy", func() {
+		configPolicy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+		connPolicy := PRPolicy{PTDA: PTDATimes, Value: 3}
+
+		It("uses the global policy", func() {
+			var c *prPolicyChain
+			policy, source := c.Get()
+			Expect(policy).To(Equal(defaultPRPolicy()))
+			Expect(source).To(Equal(PRPolicySourceGlobal))
+			policy, source = newPRPolicyChain(PRConfig{}).Get()
+			Expect(policy).To(Equal(defaultPRPolicy()))
+			Expect(source).To(Equal(PRPolicySourceGlobal))
+		})
+
+		It("prefers the config policy over the global policy", func() {
+			policy, source := newPRPolicyChain(PRConfig{DefaultPolicy: &configPolicy}).Get()
+			Expect(policy).To(Equal(configPolicy))
+			Expect(source).To(Equal(PRPolicySourceConfig))
+		})
+
+		It("prefers the connection policy over the config policy", func() {
+			c := newPRPolicyChain(PRConfig{DefaultPolicy: &configPolicy})
+			c.SetConnectionPolicy(&connPolicy)
+			policy, source := c.Get()
+			Expect(policy).To(Equal(connPolicy))
+			Expect(source).To(Equal(PRPolicySourceConnection))
+			c.SetConnectionPolicy(nil)
+			policy, source = c.Get()
+			Expect(policy).To(Equal(configPolicy))
+			Expect(source).To(Equal(PRPolicySourceConfig))
+		})
+
+		It("copies the connection policy", func() {
+			c := newPRPolicyChain(PRConfig{})
+			p := connPolicy
+			c.SetConnectionPolicy(&p)
+			p.Value = 42
+			policy, _ := c.Get()
+			Expect(policy).To(Equal(connPolicy))
+		})
+	})
+
+	Context("disabling partial reliability", func() {
+		It("is enabled by default", func() {
+			var c *prPolicyChain
+			Expect(c.Enabled()).To(BeTrue())
+			Expect(newPRPolicyChain(PRConfig{}).Enabled()).To(BeTrue())
+		})
+
+		It("is disabled by the config", func() {
+			c := newPRPolicyChain(PRConfig{Disabled: true})
+			Expect(c.Enabled()).To(BeFalse())
+			c.SetPeerSupportsPR(true)
+			Expect(c.Enabled()).To(BeFalse())
+		})
+
+		It("is disabled if the peer doesn't support it", func() {
+			c := newPRPolicyChain(PRConfig{})
+			c.SetPeerSupportsPR(false)
+			Expect(c.Enabled()).To(BeFalse())
+			c.SetPeerSupportsPR(true)
+			Expect(c.Enabled()).To(BeTrue())
+		})
+	})
+
+	Context("random number generator", func() {
+		It("uses the seed from the config", func() {
+			c1 := newPRPolicyChain(PRConfig{RandSeed: 1337})
+			c2 := newPRPolicyChain(PRConfig{RandSeed: 1337})
+			Expect(c1.RandSeed()).To(BeEquivalentTo(1337))
+			for i := 0; i < 10; i++ {
+				Expect(c1.Rand().Intn(maxPRProbability)).To(Equal(c2.Rand().Intn(maxPRProbability)))
+			}
+		})
+
+		It("chooses a seed", func() {
+			c := newPRPolicyChain(PRConfig{})
+			Expect(c.RandSeed()).ToNot(BeZero())
+			Expect(c.Rand()).ToNot(BeNil())
+			var nilChain *prPolicyChain
+			Expect(nilChain.Rand()).ToNot(BeNil())
+		})
+	})
+
+	Context("limiting the skip ratio", func() {
+		It("doesn't limit the skip ratio by default", func() {
+			var c *prPolicyChain
+			Expect(c.SkipAllowed(100, 100)).To(BeTrue())
+			Expect(newPRPolicyChain(PRConfig{}).SkipAllowed(100, 100)).To(BeTrue())
+		})
+
+		It("limits the skip ratio to the peer's max_skip_ratio", func() {
+			c := newPRPolicyChain(PRConfig{})
+			c.SetPeerMaxSkipRatio(protocol.SkipRatioScale / 10)
+			Expect(c.SkipAllowed(10, 100)).To(BeTrue())
+			Expect(c.SkipAllowed(11, 100)).To(BeFalse())
+			Expect(c.SkipAllowed(1, 0)).To(BeFalse())
+		})
+
+		It("sends the max_skip_ratio", func() {
+			Expect(maxSkipRatioParam(PRConfig{})).To(BeZero())
+			Expect(maxSkipRatioParam(PRConfig{MaxSkipRatio: 1})).To(BeZero())
+			Expect(maxSkipRatioParam(PRConfig{MaxSkipRatio: 0.1, Disabled: true})).To(BeZero())
+			Expect(maxSkipRatioParam(PRConfig{MaxSkipRatio: 0.1})).To(BeEquivalentTo(1000))
+			// rounds up, such that a small ratio doesn't disable the limit
+			Expect(maxSkipRatioParam(PRConfig{MaxSkipRatio: 0.00001})).To(BeEquivalentTo(1))
+		})
+	})
+
+	Context("limiting the number of PR streams", func() {
+		It("doesn't limit the number of streams by default", func() {
+			chain := newPRPolicyChain(PRConfig{})
+			for i := 0; i < 1000; i++ {
+				Expect(chain.acquireStream()).To(BeTrue())
+			}
+		})
+
+		It("limits the number of streams per connection", func() {
+			chain := newPRPolicyChain(PRConfig{MaxPRStreams: 2})
+			Expect(chain.acquireStream()).To(BeTrue())
+			Expect(chain.acquireStream()).To(BeTrue())
+			Expect(chain.acquireStream()).To(BeFalse())
+			chain.releaseStream()
+			Expect(chain.acquireStream()).To(BeTrue())
+		})
+
+		It("limits the number of streams across connections", func() {
+			limiter := NewPRStreamLimiter(2)
+			chain1 := newPRPolicyChain(PRConfig{StreamLimiter: limiter})
+			chain2 := newPRPolicyChain(PRConfig{StreamLimiter: limiter})
+			Expect(chain1.acquireStream()).To(BeTrue())
+			Expect(chain2.acquireStream()).To(BeTrue())
+			Expect(limiter.NumStreams()).To(Equal(2))
+			Expect(chain1.acquireStream()).To(BeFalse())
+			Expect(chain2.acquireStream()).To(BeFalse())
+			chain2.releaseStream()
+			Expect(limiter.NumStreams()).To(Equal(1))
+			Expect(chain1.acquireStream()).To(BeTrue())
+		})
+
+		It("doesn't count streams that exceed the connection limit towards the global limit", func() {
+			limiter := NewPRStreamLimiter(10)
+			chain := newPRPolicyChain(PRConfig{MaxPRStreams: 1, StreamLimiter: limiter})
+			Expect(chain.acquireStream()).To(BeTrue())
+			Expect(chain.acquireStream()).To(BeFalse())
+			Expect(limiter.NumStreams()).To(Equal(1))
+		})
+	})
+})
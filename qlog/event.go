@@ -150,16 +150,20 @@ func (e eventConnectionClosed) MarshalJSONObject(enc *gojay.Encoder) {
 	case errors.As(e.e, &versionNegotiationErr):
 		enc.StringKey("owner", ownerRemote.String())
 		enc.StringKey("trigger", "version_negotiation")
+	case errors.Is(e.e, quic.ErrHandedOff):
+		enc.StringKey("owner", ownerLocal.String())
+		enc.StringKey("trigger", "handed_off")
 	}
 }
 
 type eventPacketSent struct {
-	Header        packetHeader
-	Length        logging.ByteCount
-	PayloadLength logging.ByteCount
-	Frames        frames
-	IsCoalesced   bool
-	Trigger       string
+	Header            packetHeader
+	Length            logging.ByteCount
+	PayloadLength     logging.ByteCount
+	Frames            frames
+	StreamComposition streamComposition
+	IsCoalesced       bool
+	Trigger           string
 }
 
 var _ eventDetails = eventPacketSent{}
@@ -172,6 +176,7 @@ func (e eventPacketSent) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.ObjectKey("header", e.Header)
 	enc.ObjectKey("raw", rawInfo{Length: e.Length, PayloadLength: e.PayloadLength})
 	enc.ArrayKeyOmitEmpty("frames", e.Frames)
+	enc.StringKeyOmitEmpty("stream_composition", e.StreamComposition.String())
 	enc.BoolKeyOmitEmpty("is_coalesced", e.IsCoalesced)
 	enc.StringKeyOmitEmpty("trigger", e.Trigger)
 }
@@ -503,6 +508,98 @@ func (e eventLossTimerCanceled) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("event_type", "cancelled")
 }
 
+type eventIdleStreamCanceled struct {
+	StreamID protocol.StreamID
+}
+
+func (e eventIdleStreamCanceled) Category() category { return categoryTransport }
+func (e eventIdleStreamCanceled) Name() string       { return "idle_stream_canceled" }
+func (e eventIdleStreamCanceled) IsNil() bool        { return false }
+
+func (e eventIdleStreamCanceled) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("stream_id", int64(e.StreamID))
+}
+
+type eventSpuriousPRConversion struct {
+	StreamID       protocol.StreamID
+	Offset         protocol.ByteCount
+	Length         protocol.ByteCount
+	NotifyCanceled bool
+}
+
+func (e eventSpuriousPRConversion) Category() category { return categoryRecovery }
+func (e eventSpuriousPRConversion) Name() string       { return "spurious_pr_conversion" }
+func (e eventSpuriousPRConversion) IsNil() bool        { return false }
+
+func (e eventSpuriousPRConversion) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("stream_id", int64(e.StreamID))
+	enc.Int64Key("offset", int64(e.Offset))
+	enc.Int64Key("length", int64(e.Length))
+	enc.BoolKey("notify_canceled", e.NotifyCanceled)
+}
+
+type eventPRPolicyUpdated struct {
+	StreamID protocol.StreamID
+	Offset   protocol.ByteCount
+	PTDA     uint8
+	Value    uint64
+	Remote   bool
+}
+
+func (e eventPRPolicyUpdated) Category() category { return categoryTransport }
+func (e eventPRPolicyUpdated) Name() string       { return "pr_policy_updated" }
+func (e eventPRPolicyUpdated) IsNil() bool        { return false }
+
+func (e eventPRPolicyUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	owner := ownerLocal
+	if e.Remote {
+		owner = ownerRemote
+	}
+	enc.StringKey("owner", owner.String())
+	enc.Int64Key("stream_id", int64(e.StreamID))
+	enc.Int64Key("offset", int64(e.Offset))
+	enc.Uint8Key("ptda", e.PTDA)
+	enc.Uint64Key("value", e.Value)
+}
+
+type eventAmplificationLimited struct {
+	BytesSent     protocol.ByteCount
+	BytesReceived protocol.ByteCount
+}
+
+func (e eventAmplificationLimited) Category() category { return categoryTransport }
+func (e eventAmplificationLimited) Name() string       { return "amplification_limited" }
+func (e eventAmplificationLimited) IsNil() bool        { return false }
+
+func (e eventAmplificationLimited) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("bytes_sent", int64(e.BytesSent))
+	enc.Int64Key("bytes_received", int64(e.BytesReceived))
+}
+
+type eventPRDisabled struct {
+	Trigger prDisabledReason
+}
+
+func (e eventPRDisabled) Category() category { return categoryTransport }
+func (e eventPRDisabled) Name() string       { return "pr_disabled" }
+func (e eventPRDisabled) IsNil() bool        { return false }
+
+func (e eventPRDisabled) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("trigger", e.Trigger.String())
+}
+
+type eventPRRandSeedSet struct {
+	Seed int64
+}
+
+func (e eventPRRandSeedSet) Category() category { return categoryTransport }
+func (e eventPRRandSeedSet) Name() string       { return "pr_rand_seed_set" }
+func (e eventPRRandSeedSet) IsNil() bool        { return false }
+
+func (e eventPRRandSeedSet) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("seed", e.Seed)
+}
+
 type eventCongestionStateUpdated struct {
 	state congestionState
 }
@@ -527,3 +624,51 @@ func (e eventGeneric) IsNil() bool        { return false }
 func (e eventGeneric) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("details", e.msg)
 }
+
+type eventSummary struct {
+	Interval       time.Duration
+	Sent           [numPacketNumberSpaces]int
+	Acked          [numPacketNumberSpaces]int
+	Lost           [numPacketNumberSpaces]int
+	PRBytesSent    protocol.ByteCount
+	PRBytesSkipped protocol.ByteCount
+	Retransmitted  streamBytesList
+	Metrics        *metrics
+}
+
+func (e eventSummary) Category() category { return categoryTransport }
+func (e eventSummary) Name() string       { return "summary" }
+func (e eventSummary) IsNil() bool        { return false }
+
+func (e eventSummary) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.FloatKey("interval", milliseconds(e.Interval))
+	var spaces packetNumberSpaceStatsList
+	for s := packetNumberSpace(0); s < numPacketNumberSpaces; s++ {
+		if e.Sent[s] == 0 && e.Acked[s] == 0 && e.Lost[s] == 0 {
+			continue
+		}
+		spaces = append(spaces, packetNumberSpaceStats{
+			Space:             s,
+			Sent:              e.Sent[s],
+			Acked:             e.Acked[s],
+			Lost:              e.Lost[s],
+			IntervalInSeconds: e.Interval.Seconds(),
+		})
+	}
+	enc.ArrayKeyOmitEmpty("packet_number_spaces", spaces)
+	if e.PRBytesSent > 0 || e.PRBytesSkipped > 0 {
+		enc.Int64Key("pr_bytes_sent", int64(e.PRBytesSent))
+		enc.Int64Key("pr_bytes_skipped", int64(e.PRBytesSkipped))
+	}
+	if e.PRBytesSent > 0 {
+		enc.FloatKey("pr_skip_ratio", float64(e.PRBytesSkipped)/float64(e.PRBytesSent))
+	}
+	enc.ArrayKeyOmitEmpty("retransmitted_bytes", e.Retransmitted)
+	if e.Metrics != nil {
+		enc.FloatKey("min_rtt", milliseconds(e.Metrics.MinRTT))
+		enc.FloatKey("smoothed_rtt", milliseconds(e.Metrics.SmoothedRTT))
+		enc.FloatKey("latest_rtt", milliseconds(e.Metrics.LatestRTT))
+		enc.Uint64Key("congestion_window", uint64(e.Metrics.CongestionWindow))
+		enc.Uint64Key("bytes_in_flight", uint64(e.Metrics.BytesInFlight))
+	}
+}
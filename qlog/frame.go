@@ -59,6 +59,16 @@ func (f frame) MarshalJSONObject(enc *gojay.Encoder) {
 		marshalHandshakeDoneFrame(enc, frame)
 	case *logging.DatagramFrame:
 		marshalDatagramFrame(enc, frame)
+	case *logging.PRStreamFrame:
+		marshalPRStreamFrame(enc, frame)
+	case *logging.PRAckNotifyFrame:
+		marshalPRAckNotifyFrame(enc, frame)
+	case *logging.PRDatagramFrame:
+		marshalPRDatagramFrame(enc, frame)
+	case *logging.PRMessageBoundaryFrame:
+		marshalPRMessageBoundaryFrame(enc, frame)
+	case *logging.PRStreamPolicyFrame:
+		marshalPRStreamPolicyFrame(enc, frame)
 	default:
 		panic("unknown frame type")
 	}
@@ -225,3 +235,44 @@ func marshalDatagramFrame(enc *gojay.Encoder, f *logging.DatagramFrame) {
 	enc.StringKey("frame_type", "datagram")
 	enc.Int64Key("length", int64(f.Length))
 }
+
+func marshalPRStreamFrame(enc *gojay.Encoder, f *logging.PRStreamFrame) {
+	enc.StringKey("frame_type", wire.PRFrameTypeName(wire.PRStreamFrameType))
+	enc.Int64Key("stream_id", int64(f.StreamID))
+	enc.Int64Key("offset", int64(f.Offset))
+	enc.IntKey("length", int(f.Length))
+	enc.BoolKeyOmitEmpty("fin", f.Fin)
+	enc.Uint64Key("ptda", uint64(f.PTDA))
+	enc.Uint64Key("value", f.Value)
+}
+
+func marshalPRAckNotifyFrame(enc *gojay.Encoder, f *logging.PRAckNotifyFrame) {
+	enc.StringKey("frame_type", wire.PRFrameTypeName(wire.PRAckNotifyFrameType))
+	enc.Int64Key("stream_id", int64(f.StreamID))
+	enc.Int64Key("offset", int64(f.Offset))
+	enc.Uint64Key("length", f.PRDataLen)
+	enc.BoolKeyOmitEmpty("fin", f.Fin)
+	enc.Uint64Key("ptda", uint64(f.PTDA))
+	enc.Uint64Key("value", f.PtdaC)
+}
+
+func marshalPRDatagramFrame(enc *gojay.Encoder, f *logging.PRDatagramFrame) {
+	enc.StringKey("frame_type", wire.PRFrameTypeName(wire.PRDatagramFrameType))
+	enc.Int64Key("length", int64(f.Length))
+	enc.Uint64Key("ptda", uint64(f.PTDA))
+	enc.Uint64Key("value", f.Value)
+}
+
+func marshalPRMessageBoundaryFrame(enc *gojay.Encoder, f *logging.PRMessageBoundaryFrame) {
+	enc.StringKey("frame_type", wire.PRFrameTypeName(wire.PRMessageBoundaryFrameType))
+	enc.Int64Key("stream_id", int64(f.StreamID))
+	enc.Int64Key("offset", int64(f.Offset))
+}
+
+func marshalPRStreamPolicyFrame(enc *gojay.Encoder, f *logging.PRStreamPolicyFrame) {
+	enc.StringKey("frame_type", wire.PRFrameTypeName(wire.PRStreamPolicyFrameType))
+	enc.Int64Key("stream_id", int64(f.StreamID))
+	enc.Int64Key("offset", int64(f.Offset))
+	enc.Uint64Key("ptda", uint64(f.PTDA))
+	enc.Uint64Key("value", f.Value)
+}
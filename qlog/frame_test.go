@@ -188,6 +188,95 @@ var _ = Describe("Frames", func() {
 		)
 	})
 
+	It("marshals PR_STREAM frames", func() {
+		check(
+			&logging.PRStreamFrame{
+				StreamID: 42,
+				Offset:   1337,
+				Length:   3,
+				PTDA:     0x20,
+				Value:    100,
+			},
+			map[string]interface{}{
+				"frame_type": "pr_stream",
+				"stream_id":  42,
+				"offset":     1337,
+				"length":     3,
+				"ptda":       0x20,
+				"value":      100,
+			},
+		)
+	})
+
+	It("marshals PR_ACK_NOTIFY frames", func() {
+		check(
+			&logging.PRAckNotifyFrame{
+				StreamID:  42,
+				Offset:    1337,
+				PRDataLen: 100,
+				Fin:       true,
+				PTDA:      0x08,
+			},
+			map[string]interface{}{
+				"frame_type": "pr_ack_notify",
+				"stream_id":  42,
+				"offset":     1337,
+				"length":     100,
+				"fin":        true,
+				"ptda":       0x08,
+				"value":      0,
+			},
+		)
+	})
+
+	It("marshals PR_DATAGRAM frames", func() {
+		check(
+			&logging.PRDatagramFrame{
+				Length: 1337,
+				PTDA:   0x20,
+				Value:  100,
+			},
+			map[string]interface{}{
+				"frame_type": "pr_datagram",
+				"length":     1337,
+				"ptda":       0x20,
+				"value":      100,
+			},
+		)
+	})
+
+	It("marshals PR_MESSAGE_BOUNDARY frames", func() {
+		check(
+			&logging.PRMessageBoundaryFrame{
+				StreamID: 42,
+				Offset:   1337,
+			},
+			map[string]interface{}{
+				"frame_type": "pr_message_boundary",
+				"stream_id":  42,
+				"offset":     1337,
+			},
+		)
+	})
+
+	It("marshals PR_STREAM_POLICY frames", func() {
+		check(
+			&logging.PRStreamPolicyFrame{
+				StreamID: 42,
+				Offset:   1337,
+				PTDA:     0x20,
+				Value:    100,
+			},
+			map[string]interface{}{
+				"frame_type": "pr_stream_policy",
+				"stream_id":  42,
+				"offset":     1337,
+				"ptda":       0x20,
+				"value":      100,
+			},
+		)
+	})
+
 	It("marshals MAX_DATA frames", func() {
 		check(
 			&logging.MaxDataFrame{
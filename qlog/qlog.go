@@ -53,18 +53,24 @@ type tracer struct {
 	logging.NullTracer
 
 	getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser
+	opts         *Options
 }
 
 var _ logging.Tracer = &tracer{}
 
 // NewTracer creates a new qlog tracer.
 func NewTracer(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser) logging.Tracer {
-	return &tracer{getLogWriter: getLogWriter}
+	return NewTracerWithOptions(getLogWriter, nil)
+}
+
+// NewTracerWithOptions creates a new qlog tracer, configured by opts.
+func NewTracerWithOptions(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser, opts *Options) logging.Tracer {
+	return &tracer{getLogWriter: getLogWriter, opts: opts}
 }
 
 func (t *tracer) TracerForConnection(_ context.Context, p logging.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
 	if w := t.getLogWriter(p, odcid.Bytes()); w != nil {
-		return NewConnectionTracer(w, p, odcid)
+		return NewConnectionTracerWithOptions(w, p, odcid, t.opts)
 	}
 	return nil
 }
@@ -82,12 +88,20 @@ type connectionTracer struct {
 	runStopped chan struct{}
 
 	lastMetrics *metrics
+
+	summarizer    *summarizer // nil if summary events are disabled
+	onlySummaries bool
 }
 
 var _ logging.ConnectionTracer = &connectionTracer{}
 
 // NewConnectionTracer creates a new tracer to record a qlog for a connection.
 func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+	return NewConnectionTracerWithOptions(w, p, odcid, nil)
+}
+
+// NewConnectionTracerWithOptions creates a new tracer to record a qlog for a connection, configured by opts.
+func NewConnectionTracerWithOptions(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID, opts *Options) logging.ConnectionTracer {
 	t := &connectionTracer{
 		w:             w,
 		perspective:   p,
@@ -96,6 +110,10 @@ func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protoco
 		events:        make(chan event, eventChanSize),
 		referenceTime: time.Now(),
 	}
+	if opts != nil && opts.SummaryInterval > 0 {
+		t.summarizer = newSummarizer(opts.SummaryInterval, t.referenceTime)
+		t.onlySummaries = opts.OnlySummaries
+	}
 	go t.run()
 	return t
 }
@@ -139,6 +157,11 @@ func (t *connectionTracer) run() {
 }
 
 func (t *connectionTracer) Close() {
+	if t.summarizer != nil {
+		t.mutex.Lock()
+		t.recordSummary(time.Now(), true)
+		t.mutex.Unlock()
+	}
 	if err := t.export(); err != nil {
 		log.Printf("exporting qlog failed: %s\n", err)
 	}
@@ -155,12 +178,36 @@ func (t *connectionTracer) export() error {
 }
 
 func (t *connectionTracer) recordEvent(eventTime time.Time, details eventDetails) {
+	t.recordSummary(eventTime, false)
 	t.events <- event{
 		RelativeTime: eventTime.Sub(t.referenceTime),
 		eventDetails: details,
 	}
 }
 
+// recordPacketEvent records an event that is recorded for every packet.
+// These events are omitted if only summary events are recorded.
+func (t *connectionTracer) recordPacketEvent(eventTime time.Time, details eventDetails) {
+	if t.onlySummaries {
+		t.recordSummary(eventTime, false)
+		return
+	}
+	t.recordEvent(eventTime, details)
+}
+
+// recordSummary records a summary event, if summary events are enabled and the interval elapsed.
+func (t *connectionTracer) recordSummary(eventTime time.Time, force bool) {
+	if t.summarizer == nil {
+		return
+	}
+	if ev := t.summarizer.Summarize(eventTime, force); ev != nil {
+		t.events <- event{
+			RelativeTime: eventTime.Sub(t.referenceTime),
+			eventDetails: ev,
+		}
+	}
+}
+
 func (t *connectionTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID protocol.ConnectionID) {
 	// ignore this event if we're not dealing with UDP addresses here
 	localAddr, ok := local.(*net.UDPAddr)
@@ -288,12 +335,16 @@ func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize loggi
 	}
 	header := *transformLongHeader(hdr)
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketSent{
-		Header:        header,
-		Length:        packetSize,
-		PayloadLength: hdr.Length,
-		Frames:        fs,
+	t.recordPacketEvent(time.Now(), &eventPacketSent{
+		Header:            header,
+		Length:            packetSize,
+		PayloadLength:     hdr.Length,
+		Frames:            fs,
+		StreamComposition: getStreamComposition(frames),
 	})
+	if t.summarizer != nil {
+		t.summarizer.SentPacket(getPacketNumberSpaceFromHeader(hdr), frames)
+	}
 	t.mutex.Unlock()
 }
 
@@ -304,7 +355,7 @@ func (t *connectionTracer) ReceivedLongHeaderPacket(hdr *logging.ExtendedHeader,
 	}
 	header := *transformLongHeader(hdr)
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketReceived{
+	t.recordPacketEvent(time.Now(), &eventPacketReceived{
 		Header:        header,
 		Length:        packetSize,
 		PayloadLength: hdr.Length,
@@ -321,7 +372,7 @@ func (t *connectionTracer) ReceivedShortHeaderPacket(hdr *logging.ShortHeader, p
 	header := *transformShortHeader(hdr)
 	hdrLen := 1 + hdr.DestConnectionID.Len() + int(hdr.PacketNumberLen)
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketReceived{
+	t.recordPacketEvent(time.Now(), &eventPacketReceived{
 		Header:        header,
 		Length:        packetSize,
 		PayloadLength: packetSize - protocol.ByteCount(hdrLen),
@@ -356,13 +407,13 @@ func (t *connectionTracer) ReceivedVersionNegotiationPacket(dest, src logging.Ar
 
 func (t *connectionTracer) BufferedPacket(pt logging.PacketType) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketBuffered{PacketType: pt})
+	t.recordPacketEvent(time.Now(), &eventPacketBuffered{PacketType: pt})
 	t.mutex.Unlock()
 }
 
 func (t *connectionTracer) DroppedPacket(pt logging.PacketType, size protocol.ByteCount, reason logging.PacketDropReason) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketDropped{
+	t.recordPacketEvent(time.Now(), &eventPacketDropped{
 		PacketType: pt,
 		PacketSize: size,
 		Trigger:    packetDropReason(reason),
@@ -381,23 +432,37 @@ func (t *connectionTracer) UpdatedMetrics(rttStats *utils.RTTStats, cwnd, bytesI
 		PacketsInFlight:  packetsInFlight,
 	}
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventMetricsUpdated{
+	t.recordPacketEvent(time.Now(), &eventMetricsUpdated{
 		Last:    t.lastMetrics,
 		Current: m,
 	})
 	t.lastMetrics = m
+	if t.summarizer != nil {
+		t.summarizer.UpdatedMetrics(m)
+	}
 	t.mutex.Unlock()
 }
 
-func (t *connectionTracer) AcknowledgedPacket(protocol.EncryptionLevel, protocol.PacketNumber) {}
+func (t *connectionTracer) AcknowledgedPacket(encLevel protocol.EncryptionLevel, _ protocol.PacketNumber) {
+	if t.summarizer == nil {
+		return
+	}
+	t.mutex.Lock()
+	t.recordSummary(time.Now(), false)
+	t.summarizer.AcknowledgedPacket(encLevel)
+	t.mutex.Unlock()
+}
 
 func (t *connectionTracer) LostPacket(encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, lossReason logging.PacketLossReason) {
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPacketLost{
+	t.recordPacketEvent(time.Now(), &eventPacketLost{
 		PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
 		PacketNumber: pn,
 		Trigger:      packetLossReason(lossReason),
 	})
+	if t.summarizer != nil {
+		t.summarizer.LostPacket(encLevel)
+	}
 	t.mutex.Unlock()
 }
 
@@ -494,6 +559,53 @@ func (t *connectionTracer) LossTimerCanceled() {
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) CanceledIdleStream(id protocol.StreamID) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventIdleStreamCanceled{StreamID: id})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) UpdatedPRPolicy(id protocol.StreamID, offset protocol.ByteCount, ptda uint8, value uint64, remote bool) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPRPolicyUpdated{
+		StreamID: id,
+		Offset:   offset,
+		PTDA:     ptda,
+		Value:    value,
+		Remote:   remote,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) SpuriousPRConversion(id protocol.StreamID, offset, length protocol.ByteCount, notifyCanceled bool) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventSpuriousPRConversion{
+		StreamID:       id,
+		Offset:         offset,
+		Length:         length,
+		NotifyCanceled: notifyCanceled,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) DisabledPR(reason logging.PRDisabledReason) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPRDisabled{Trigger: prDisabledReason(reason)})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) SetPRRandSeed(seed int64) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPRRandSeedSet{Seed: seed})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) AmplificationLimited(bytesSent, bytesReceived protocol.ByteCount) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventAmplificationLimited{BytesSent: bytesSent, BytesReceived: bytesReceived})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) Debug(name, msg string) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventGeneric{
@@ -225,6 +225,16 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("trigger", "handshake_timeout"))
 			})
 
+			It("records connections that were handed off", func() {
+				tracer.ClosedConnection(quic.ErrHandedOff)
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:connection_closed"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(2))
+				Expect(ev).To(HaveKeyWithValue("owner", "local"))
+				Expect(ev).To(HaveKeyWithValue("trigger", "handed_off"))
+			})
+
 			It("records a received stateless reset packet", func() {
 				tracer.ClosedConnection(&quic.StatelessResetError{
 					Token: protocol.StatelessResetToken{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
@@ -484,6 +494,31 @@ var _ = Describe("Tracing", func() {
 				Expect(frames).To(HaveLen(2))
 				Expect(frames[0].(map[string]interface{})).To(HaveKeyWithValue("frame_type", "ack"))
 				Expect(frames[1].(map[string]interface{})).To(HaveKeyWithValue("frame_type", "max_data"))
+				Expect(ev).ToNot(HaveKey("stream_composition"))
+			})
+
+			It("records the composition of the stream data in a packet", func() {
+				sendPacket := func(frames ...logging.Frame) {
+					tracer.SentPacket(
+						&logging.ExtendedHeader{
+							Header:       logging.Header{DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4})},
+							PacketNumber: 1337,
+						},
+						123,
+						nil,
+						frames,
+					)
+				}
+				streamFrame := &logging.StreamFrame{StreamID: 4, Length: 10}
+				prStreamFrame := &logging.PRStreamFrame{StreamID: 8, Length: 10, PTDA: 0x20, Value: 100}
+				sendPacket(streamFrame)
+				sendPacket(prStreamFrame, &logging.MaxDataFrame{MaximumData: 987})
+				sendPacket(streamFrame, prStreamFrame)
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(3))
+				Expect(entries[0].Event).To(HaveKeyWithValue("stream_composition", "reliable"))
+				Expect(entries[1].Event).To(HaveKeyWithValue("stream_composition", "partially_reliable"))
+				Expect(entries[2].Event).To(HaveKeyWithValue("stream_composition", "mixed"))
 			})
 
 			It("records a received Long Header packet", func() {
@@ -872,6 +907,74 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("event_type", "cancelled"))
 			})
 
+			It("records when an idle stream is canceled", func() {
+				tracer.CanceledIdleStream(42)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:idle_stream_canceled"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(1))
+				Expect(ev).To(HaveKeyWithValue("stream_id", float64(42)))
+			})
+
+			It("records spurious PR conversions", func() {
+				tracer.SpuriousPRConversion(42, 1000, 1337, true)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:spurious_pr_conversion"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(4))
+				Expect(ev).To(HaveKeyWithValue("stream_id", float64(42)))
+				Expect(ev).To(HaveKeyWithValue("offset", float64(1000)))
+				Expect(ev).To(HaveKeyWithValue("length", float64(1337)))
+				Expect(ev).To(HaveKeyWithValue("notify_canceled", true))
+			})
+
+			It("records PR policy updates", func() {
+				tracer.UpdatedPRPolicy(42, 1000, 0x20, 150, true)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:pr_policy_updated"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(5))
+				Expect(ev).To(HaveKeyWithValue("owner", "remote"))
+				Expect(ev).To(HaveKeyWithValue("stream_id", float64(42)))
+				Expect(ev).To(HaveKeyWithValue("offset", float64(1000)))
+				Expect(ev).To(HaveKeyWithValue("ptda", float64(0x20)))
+				Expect(ev).To(HaveKeyWithValue("value", float64(150)))
+			})
+
+			It("records when the server is amplification limited", func() {
+				tracer.AmplificationLimited(3600, 1200)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:amplification_limited"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(2))
+				Expect(ev).To(HaveKeyWithValue("bytes_sent", float64(3600)))
+				Expect(ev).To(HaveKeyWithValue("bytes_received", float64(1200)))
+			})
+
+			It("records disabling of partial reliability", func() {
+				tracer.DisabledPR(logging.PRDisabledFrameRejected)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:pr_disabled"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(1))
+				Expect(ev).To(HaveKeyWithValue("trigger", "frame_rejected"))
+			})
+
+			It("records the seed of the PR random number generator", func() {
+				tracer.SetPRRandSeed(1337)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:pr_rand_seed_set"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(1))
+				Expect(ev).To(HaveKeyWithValue("seed", float64(1337)))
+			})
+
 			It("records a generic event", func() {
 				tracer.Debug("foo", "bar")
 				entry := exportAndParseSingle()
@@ -883,4 +986,125 @@ var _ = Describe("Tracing", func() {
 			})
 		})
 	})
+
+	Context("summary events", func() {
+		var buf *bytes.Buffer
+
+		newTracer := func(opts *Options) logging.ConnectionTracer {
+			buf = &bytes.Buffer{}
+			t := NewTracerWithOptions(func(logging.Perspective, []byte) io.WriteCloser { return nopWriteCloser(buf) }, opts)
+			return t.TracerForConnection(
+				context.Background(),
+				logging.PerspectiveClient,
+				protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			)
+		}
+
+		parseEvents := func() []entry {
+			_, err := buf.ReadBytes('\n') // skip the header
+			Expect(err).ToNot(HaveOccurred())
+			var entries []entry
+			for buf.Len() > 0 {
+				line, err := buf.ReadBytes('\n')
+				Expect(err).ToNot(HaveOccurred())
+				ev := make(map[string]interface{})
+				Expect(json.Unmarshal(line, &ev)).To(Succeed())
+				entries = append(entries, entry{
+					Name:  ev["name"].(string),
+					Event: ev["data"].(map[string]interface{}),
+				})
+			}
+			return entries
+		}
+
+		sendPacket := func(tracer logging.ConnectionTracer, frames ...logging.Frame) {
+			tracer.SentPacket(
+				&logging.ExtendedHeader{
+					Header:       logging.Header{DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4})},
+					PacketNumber: 1337,
+				},
+				123,
+				nil,
+				frames,
+			)
+		}
+
+		It("doesn't record summary events by default", func() {
+			tracer := newTracer(nil)
+			sendPacket(tracer, &logging.PingFrame{})
+			tracer.Close()
+			entries := parseEvents()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name).To(Equal("transport:packet_sent"))
+		})
+
+		It("records a summary event when the tracer is closed", func() {
+			tracer := newTracer(&Options{SummaryInterval: time.Hour})
+			sendPacket(tracer, &logging.StreamFrame{StreamID: 4, Offset: 0, Length: 100})
+			sendPacket(tracer, &logging.PRStreamFrame{StreamID: 8, Offset: 0, Length: 100, PTDA: 0x20, Value: 100})
+			sendPacket(tracer, &logging.PRAckNotifyFrame{StreamID: 8, Offset: 0, PRDataLen: 25})
+			sendPacket(tracer, &logging.StreamFrame{StreamID: 4, Offset: 50, Length: 100})
+			tracer.LostPacket(protocol.Encryption1RTT, 42, logging.PacketLossReorderingThreshold)
+			tracer.AcknowledgedPacket(protocol.Encryption1RTT, 43)
+			tracer.AcknowledgedPacket(protocol.EncryptionInitial, 1)
+			rttStats := &utils.RTTStats{}
+			rttStats.UpdateRTT(15*time.Millisecond, 0, time.Now())
+			tracer.UpdatedMetrics(rttStats, 4321, 1234, 42)
+			tracer.Close()
+			entries := parseEvents()
+			Expect(entries).To(HaveLen(7))
+			Expect(entries[5].Name).To(Equal("recovery:metrics_updated"))
+			Expect(entries[6].Name).To(Equal("transport:summary"))
+			ev := entries[6].Event
+			Expect(ev).To(HaveKey("interval"))
+			Expect(ev).To(HaveKeyWithValue("pr_bytes_sent", float64(100)))
+			Expect(ev).To(HaveKeyWithValue("pr_bytes_skipped", float64(25)))
+			Expect(ev).To(HaveKeyWithValue("pr_skip_ratio", 0.25))
+			Expect(ev).To(HaveKeyWithValue("smoothed_rtt", float64(15)))
+			Expect(ev).To(HaveKeyWithValue("congestion_window", float64(4321)))
+			Expect(ev).To(HaveKeyWithValue("bytes_in_flight", float64(1234)))
+			Expect(ev).To(HaveKey("retransmitted_bytes"))
+			retransmitted := ev["retransmitted_bytes"].([]interface{})
+			Expect(retransmitted).To(HaveLen(1))
+			Expect(retransmitted[0]).To(HaveKeyWithValue("stream_id", float64(4)))
+			Expect(retransmitted[0]).To(HaveKeyWithValue("bytes", float64(50)))
+			Expect(ev).To(HaveKey("packet_number_spaces"))
+			spaces := ev["packet_number_spaces"].([]interface{})
+			Expect(spaces).To(HaveLen(2))
+			Expect(spaces[0]).To(HaveKeyWithValue("space", "initial"))
+			Expect(spaces[0]).To(HaveKeyWithValue("packets_acked", float64(1)))
+			Expect(spaces[1]).To(HaveKeyWithValue("space", "application_data"))
+			Expect(spaces[1]).To(HaveKeyWithValue("packets_sent", float64(4)))
+			Expect(spaces[1]).To(HaveKeyWithValue("packets_acked", float64(1)))
+			Expect(spaces[1]).To(HaveKeyWithValue("packets_lost", float64(1)))
+			Expect(spaces[1]).To(HaveKey("packets_sent_per_second"))
+		})
+
+		It("records summary events at intervals", func() {
+			tracer := newTracer(&Options{SummaryInterval: scaleDuration(10 * time.Millisecond)})
+			sendPacket(tracer, &logging.PingFrame{})
+			time.Sleep(scaleDuration(15 * time.Millisecond))
+			sendPacket(tracer, &logging.PingFrame{})
+			sendPacket(tracer, &logging.PingFrame{})
+			tracer.Close()
+			entries := parseEvents()
+			Expect(entries).To(HaveLen(5))
+			Expect(entries[1].Name).To(Equal("transport:summary"))
+			Expect(entries[1].Event["packet_number_spaces"].([]interface{})[0]).To(HaveKeyWithValue("packets_sent", float64(1)))
+			Expect(entries[4].Name).To(Equal("transport:summary"))
+			Expect(entries[4].Event["packet_number_spaces"].([]interface{})[0]).To(HaveKeyWithValue("packets_sent", float64(2)))
+		})
+
+		It("only records summary events", func() {
+			tracer := newTracer(&Options{SummaryInterval: time.Hour, OnlySummaries: true})
+			sendPacket(tracer, &logging.PingFrame{})
+			tracer.LostPacket(protocol.Encryption1RTT, 42, logging.PacketLossReorderingThreshold)
+			tracer.UpdatedPTOCount(1)
+			tracer.Close()
+			entries := parseEvents()
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Name).To(Equal("recovery:metrics_updated"))
+			Expect(entries[1].Name).To(Equal("transport:summary"))
+		})
+	})
 })
@@ -0,0 +1,210 @@
+package qlog
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	"github.com/francoispqt/gojay"
+)
+
+// Options configure a qlog tracer.
+type Options struct {
+	// SummaryInterval enables summary events, describing the interval since the last summary event:
+	// the number of packets sent, acknowledged and lost in every packet number space,
+	// the PR data sent and skipped, the data retransmitted on every stream, and the latest RTT and congestion window.
+	// This produces a lightweight time series, e.g. to plot the skip ratio of PR streams over time.
+	// A summary event is recorded with the first event after the interval elapsed, and when the tracer is closed.
+	// If zero, no summary events are recorded.
+	SummaryInterval time.Duration
+	// OnlySummaries disables the events recorded for every packet:
+	// packet_sent, packet_received, packet_lost, packet_buffered, packet_dropped and metrics_updated.
+	OnlySummaries bool
+}
+
+type packetNumberSpace uint8
+
+const (
+	packetNumberSpaceInitial packetNumberSpace = iota
+	packetNumberSpaceHandshake
+	packetNumberSpaceApplicationData
+	numPacketNumberSpaces
+)
+
+func getPacketNumberSpace(encLevel protocol.EncryptionLevel) packetNumberSpace {
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		return packetNumberSpaceInitial
+	case protocol.EncryptionHandshake:
+		return packetNumberSpaceHandshake
+	default:
+		return packetNumberSpaceApplicationData
+	}
+}
+
+func getPacketNumberSpaceFromHeader(hdr *logging.ExtendedHeader) packetNumberSpace {
+	if !hdr.IsLongHeader {
+		return packetNumberSpaceApplicationData
+	}
+	switch hdr.Type {
+	case protocol.PacketTypeInitial:
+		return packetNumberSpaceInitial
+	case protocol.PacketTypeHandshake:
+		return packetNumberSpaceHandshake
+	default:
+		return packetNumberSpaceApplicationData
+	}
+}
+
+func (s packetNumberSpace) String() string {
+	switch s {
+	case packetNumberSpaceInitial:
+		return "initial"
+	case packetNumberSpaceHandshake:
+		return "handshake"
+	case packetNumberSpaceApplicationData:
+		return "application_data"
+	default:
+		return "unknown packet number space"
+	}
+}
+
+// A summarizer collects the statistics of summary events.
+type summarizer struct {
+	interval time.Duration
+	start    time.Time
+
+	sent, acked, lost [numPacketNumberSpaces]int
+	prBytesSent       protocol.ByteCount
+	prBytesSkipped    protocol.ByteCount
+	retransmitted     map[protocol.StreamID]protocol.ByteCount
+	metrics           *metrics
+
+	// the highest offset sent on every stream, used to detect retransmissions
+	highestOffsets map[protocol.StreamID]protocol.ByteCount
+}
+
+func newSummarizer(interval time.Duration, now time.Time) *summarizer {
+	return &summarizer{
+		interval:       interval,
+		start:          now,
+		retransmitted:  make(map[protocol.StreamID]protocol.ByteCount),
+		highestOffsets: make(map[protocol.StreamID]protocol.ByteCount),
+	}
+}
+
+// Summarize returns the summary of the interval, if it elapsed, and starts a new interval.
+func (s *summarizer) Summarize(now time.Time, force bool) *eventSummary {
+	if !force && now.Sub(s.start) < s.interval {
+		return nil
+	}
+	ev := &eventSummary{
+		Interval:       now.Sub(s.start),
+		Sent:           s.sent,
+		Acked:          s.acked,
+		Lost:           s.lost,
+		PRBytesSent:    s.prBytesSent,
+		PRBytesSkipped: s.prBytesSkipped,
+		Metrics:        s.metrics,
+	}
+	for id, n := range s.retransmitted {
+		ev.Retransmitted = append(ev.Retransmitted, streamBytes{StreamID: id, Bytes: n})
+		delete(s.retransmitted, id)
+	}
+	sort.Slice(ev.Retransmitted, func(i, j int) bool { return ev.Retransmitted[i].StreamID < ev.Retransmitted[j].StreamID })
+	s.start = now
+	s.sent = [numPacketNumberSpaces]int{}
+	s.acked = [numPacketNumberSpaces]int{}
+	s.lost = [numPacketNumberSpaces]int{}
+	s.prBytesSent = 0
+	s.prBytesSkipped = 0
+	return ev
+}
+
+func (s *summarizer) SentPacket(space packetNumberSpace, frames []logging.Frame) {
+	s.sent[space]++
+	for _, f := range frames {
+		switch f := f.(type) {
+		case *logging.StreamFrame:
+			s.sentStreamData(f.StreamID, f.Offset, f.Length)
+		case *logging.PRStreamFrame:
+			s.prBytesSent += f.Length
+			s.sentStreamData(f.StreamID, f.Offset, f.Length)
+		case *logging.PRAckNotifyFrame:
+			s.prBytesSkipped += protocol.ByteCount(f.PRDataLen)
+		}
+	}
+}
+
+func (s *summarizer) sentStreamData(id protocol.StreamID, offset, length protocol.ByteCount) {
+	highest := s.highestOffsets[id]
+	if offset < highest {
+		s.retransmitted[id] += utils.Min(offset+length, highest) - offset
+	}
+	if offset+length > highest {
+		s.highestOffsets[id] = offset + length
+	}
+}
+
+func (s *summarizer) AcknowledgedPacket(encLevel protocol.EncryptionLevel) {
+	s.acked[getPacketNumberSpace(encLevel)]++
+}
+
+func (s *summarizer) LostPacket(encLevel protocol.EncryptionLevel) {
+	s.lost[getPacketNumberSpace(encLevel)]++
+}
+
+func (s *summarizer) UpdatedMetrics(m *metrics) {
+	s.metrics = m
+}
+
+type streamBytes struct {
+	StreamID protocol.StreamID
+	Bytes    protocol.ByteCount
+}
+
+func (b streamBytes) IsNil() bool { return false }
+func (b streamBytes) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("stream_id", int64(b.StreamID))
+	enc.Int64Key("bytes", int64(b.Bytes))
+}
+
+type streamBytesList []streamBytes
+
+func (l streamBytesList) IsNil() bool { return len(l) == 0 }
+func (l streamBytesList) MarshalJSONArray(enc *gojay.Encoder) {
+	for _, b := range l {
+		enc.Object(b)
+	}
+}
+
+type packetNumberSpaceStats struct {
+	Space             packetNumberSpace
+	Sent, Acked, Lost int
+	IntervalInSeconds float64
+}
+
+func (s packetNumberSpaceStats) IsNil() bool { return false }
+func (s packetNumberSpaceStats) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("space", s.Space.String())
+	enc.IntKey("packets_sent", s.Sent)
+	enc.IntKey("packets_acked", s.Acked)
+	enc.IntKey("packets_lost", s.Lost)
+	if s.IntervalInSeconds > 0 {
+		enc.FloatKey("packets_sent_per_second", float64(s.Sent)/s.IntervalInSeconds)
+		enc.FloatKey("packets_acked_per_second", float64(s.Acked)/s.IntervalInSeconds)
+		enc.FloatKey("packets_lost_per_second", float64(s.Lost)/s.IntervalInSeconds)
+	}
+}
+
+type packetNumberSpaceStatsList []packetNumberSpaceStats
+
+func (l packetNumberSpaceStatsList) IsNil() bool { return len(l) == 0 }
+func (l packetNumberSpaceStatsList) MarshalJSONArray(enc *gojay.Encoder) {
+	for _, s := range l {
+		enc.Object(s)
+	}
+}
@@ -283,6 +283,21 @@ func (r packetDropReason) String() string {
 	}
 }
 
+type prDisabledReason logging.PRDisabledReason
+
+func (r prDisabledReason) String() string {
+	switch logging.PRDisabledReason(r) {
+	case logging.PRDisabledNotNegotiated:
+		return "not_negotiated"
+	case logging.PRDisabledFrameRejected:
+		return "frame_rejected"
+	case logging.PRDisabledHostCache:
+		return "host_cache"
+	default:
+		return "unknown reason"
+	}
+}
+
 type timerType logging.TimerType
 
 func (t timerType) String() string {
@@ -312,3 +327,50 @@ func (s congestionState) String() string {
 		return "unknown congestion state"
 	}
 }
+
+// streamComposition describes which kinds of stream data a packet carries.
+type streamComposition uint8
+
+const (
+	streamCompositionNone streamComposition = iota
+	streamCompositionReliable
+	streamCompositionPR
+	streamCompositionMixed
+)
+
+func getStreamComposition(frames []logging.Frame) streamComposition {
+	var reliable, pr bool
+	for _, f := range frames {
+		switch f.(type) {
+		case *logging.StreamFrame:
+			reliable = true
+		case *logging.PRStreamFrame:
+			pr = true
+		}
+	}
+	switch {
+	case reliable && pr:
+		return streamCompositionMixed
+	case reliable:
+		return streamCompositionReliable
+	case pr:
+		return streamCompositionPR
+	default:
+		return streamCompositionNone
+	}
+}
+
+func (c streamComposition) String() string {
+	switch c {
+	case streamCompositionNone:
+		return ""
+	case streamCompositionReliable:
+		return "reliable"
+	case streamCompositionPR:
+		return "partially_reliable"
+	case streamCompositionMixed:
+		return "mixed"
+	default:
+		return "unknown stream composition"
+	}
+}
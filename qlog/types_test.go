@@ -53,6 +53,12 @@ var _ = Describe("Types", func() {
 		Expect(packetDropReason(logging.PacketDropUnexpectedVersion).String()).To(Equal("unexpected_version"))
 	})
 
+	It("has a string representation for the reason partial reliability is disabled", func() {
+		Expect(prDisabledReason(logging.PRDisabledNotNegotiated).String()).To(Equal("not_negotiated"))
+		Expect(prDisabledReason(logging.PRDisabledFrameRejected).String()).To(Equal("frame_rejected"))
+		Expect(prDisabledReason(logging.PRDisabledHostCache).String()).To(Equal("host_cache"))
+	})
+
 	It("has a string representation for the timer type", func() {
 		Expect(timerType(logging.TimerTypeACK).String()).To(Equal("ack"))
 		Expect(timerType(logging.TimerTypePTO).String()).To(Equal("pto"))
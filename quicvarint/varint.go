@@ -1,6 +1,7 @@
 package quicvarint
 
 import (
+	"errors"
 	"fmt"
 	"io"
 
@@ -21,6 +22,9 @@ const (
 	maxVarInt8 = 4611686018427387903
 )
 
+// ErrTooLarge is returned when encoding a number larger than Max.
+var ErrTooLarge = errors.New("value doesn't fit into 62 bits")
+
 // Read reads a number in the QUIC varint format from r.
 func Read(r io.ByteReader) (uint64, error) {
 	firstByte, err := r.ReadByte()
@@ -88,6 +92,9 @@ func Write(w Writer, i uint64) {
 	}
 }
 
+// Append appends i in the QUIC varint format to b.
+// It panics if i is larger than Max.
+// Use AppendChecked when encoding values that weren't validated before.
 func Append(b []byte, i uint64) []byte {
 	if i <= maxVarInt1 {
 		return append(b, uint8(i))
@@ -107,6 +114,44 @@ func Append(b []byte, i uint64) []byte {
 	panic(fmt.Sprintf("%#x doesn't fit into 62 bits", i))
 }
 
+// AppendChecked appends i in the QUIC varint format to b.
+// Unlike Append, it returns ErrTooLarge if i is larger than Max.
+func AppendChecked(b []byte, i uint64) ([]byte, error) {
+	if !Fits(i) {
+		return b, fmt.Errorf("%w: %#x", ErrTooLarge, i)
+	}
+	return Append(b, i), nil
+}
+
+// AppendWithLen appends i in the QUIC varint format with the desired length to b.
+// It panics if length is not 1, 2, 4 or 8, or if i can't be encoded in length bytes.
+func AppendWithLen(b []byte, i uint64, length protocol.ByteCount) []byte {
+	if length != 1 && length != 2 && length != 4 && length != 8 {
+		panic("invalid varint length")
+	}
+	l := Len(i)
+	if l == length {
+		return Append(b, i)
+	}
+	if l > length {
+		panic(fmt.Sprintf("cannot encode %d in %d bytes", i, length))
+	}
+	if length == 2 {
+		b = append(b, 0b01000000)
+	} else if length == 4 {
+		b = append(b, 0b10000000)
+	} else if length == 8 {
+		b = append(b, 0b11000000)
+	}
+	for j := protocol.ByteCount(1); j < length-l; j++ {
+		b = append(b, 0)
+	}
+	for j := protocol.ByteCount(0); j < l; j++ {
+		b = append(b, uint8(i>>(8*(l-1-j))))
+	}
+	return b
+}
+
 // WriteWithLen writes i in the QUIC varint format with the desired length to w.
 func WriteWithLen(w Writer, i uint64, length protocol.ByteCount) {
 	if length != 1 && length != 2 && length != 4 && length != 8 {
@@ -135,7 +180,13 @@ func WriteWithLen(w Writer, i uint64, length protocol.ByteCount) {
 	}
 }
 
+// Fits says if i can be encoded in the QUIC varint format, i.e. if it is not larger than Max.
+func Fits(i uint64) bool {
+	return i <= maxVarInt8
+}
+
 // Len determines the number of bytes that will be needed to write the number i.
+// It panics if i is larger than Max.
 func Len(i uint64) protocol.ByteCount {
 	if i <= maxVarInt1 {
 		return 1
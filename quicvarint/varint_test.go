@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"math/rand"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,6 +19,12 @@ var _ = Describe("Varint encoding / decoding", func() {
 		Specify("Max == 2^62-1", func() {
 			Expect(uint64(Max)).To(Equal(uint64(1<<62 - 1)))
 		})
+
+		It("says if a number fits", func() {
+			Expect(Fits(0)).To(BeTrue())
+			Expect(Fits(Max)).To(BeTrue())
+			Expect(Fits(Max + 1)).To(BeFalse())
+		})
 	})
 
 	Context("decoding", func() {
@@ -219,6 +227,34 @@ var _ = Describe("Varint encoding / decoding", func() {
 					Expect(b).To(Equal(buf.Bytes()))
 				}
 			})
+
+			It("returns an error when appending a too large number (> 62 bit)", func() {
+				b, err := AppendChecked([]byte{0x42}, maxVarInt8+1)
+				Expect(err).To(MatchError(ErrTooLarge))
+				Expect(b).To(Equal([]byte{0x42}))
+			})
+
+			It("appends numbers that fit", func() {
+				b, err := AppendChecked([]byte{0x42}, maxVarInt8)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal(Append([]byte{0x42}, maxVarInt8)))
+			})
+
+			It("panics when appending with an invalid or too short length", func() {
+				Expect(func() { AppendWithLen(nil, 25, 3) }).Should(Panic())
+				Expect(func() { AppendWithLen(nil, maxVarInt2+1, 2) }).Should(Panic())
+			})
+
+			It("appends with fixed length", func() {
+				for _, l := range []protocol.ByteCount{1, 2, 4, 8} {
+					buf := &bytes.Buffer{}
+					WriteWithLen(buf, 37, l)
+					b := AppendWithLen([]byte{0x42}, 37, l)
+					Expect(b).To(Equal(append([]byte{0x42}, buf.Bytes()...)))
+				}
+				b := AppendWithLen(nil, 494878333, 8)
+				Expect(b).To(Equal([]byte{0b11000000, 0, 0, 0, 0x1d, 0x7f, 0x3e, 0x7d}))
+			})
 		})
 	})
 
@@ -3,6 +3,7 @@ package quic
 import (
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -17,11 +18,19 @@ type receiveStreamI interface {
 	ReceiveStream
 
 	handleStreamFrame(*wire.StreamFrame) error
+	handleExpiringStreamFrame(*wire.StreamFrame, time.Time) error
+	handleSkippedStreamFrame(*wire.StreamFrame) error
+	handleMessageBoundaryFrame(*wire.PRMessageBoundaryFrame) error
+	evictExpiredData(now time.Time) time.Time
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	closeForShutdown(error)
 	getWindowUpdate() protocol.ByteCount
+	handoffState(*HandoffStream)
 }
 
+// zeroBuffer is used to read evicted data. It must never be written to.
+var zeroBuffer [protocol.MaxPacketBufferSize]byte
+
 type receiveStream struct {
 	mutex sync.Mutex
 
@@ -36,6 +45,19 @@ type receiveStream struct {
 	currentFrameDone   func()
 	currentFrameIsLast bool // is the currentFrame the last frame on this stream
 	readPosInFrame     int
+	readOffset         protocol.ByteCount // the number of bytes read by the application
+
+	// the message boundaries at or above readOffset, sorted, see ReadMessageBoundary
+	messageBoundaries []protocol.ByteCount
+
+	// Data that was evicted from the frame queue, but not read by the application yet.
+	// It was already counted as read by the flow controller, and is read as zeros.
+	evictedBytes        protocol.ByteCount
+	currentFrameEvicted bool
+
+	// If set, Read returns a DataSkippedError instead of reading zeros for skipped or evicted data.
+	readSkippedAsError  bool
+	currentFrameSkipped bool // is the currentFrame skipped or evicted data
 
 	closeForShutdownErr error
 	cancelReadErr       error
@@ -51,6 +73,7 @@ type receiveStream struct {
 	deadline time.Time
 
 	flowController flowcontrol.StreamFlowController
+	logger         utils.Logger
 	version        protocol.VersionNumber
 }
 
@@ -63,6 +86,7 @@ func newReceiveStream(
 	streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receiveStream {
 	return &receiveStream{
@@ -73,6 +97,7 @@ func newReceiveStream(
 		readChan:       make(chan struct{}, 1),
 		readOnce:       make(chan struct{}, 1),
 		finalOffset:    protocol.MaxByteCount,
+		logger:         logger.With("stream_id", streamID),
 		version:        version,
 	}
 }
@@ -90,7 +115,25 @@ func (s *receiveStream) Read(p []byte) (int, error) {
 	defer func() { <-s.readOnce }()
 
 	s.mutex.Lock()
-	completed, n, err := s.readImpl(p)
+	completed, n, err := s.readImpl(p, true)
+	s.mutex.Unlock()
+
+	if completed {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return n, err
+}
+
+func (s *receiveStream) TryRead(p []byte) (int, error) {
+	select {
+	case s.readOnce <- struct{}{}:
+	default:
+		return 0, ErrWouldBlock
+	}
+	defer func() { <-s.readOnce }()
+
+	s.mutex.Lock()
+	completed, n, err := s.readImpl(p, false)
 	s.mutex.Unlock()
 
 	if completed {
@@ -99,7 +142,7 @@ func (s *receiveStream) Read(p []byte) (int, error) {
 	return n, err
 }
 
-func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
+func (s *receiveStream) readImpl(p []byte, block bool) (bool /*stream completed */, int, error) {
 	if s.finRead {
 		return false, 0, io.EOF
 	}
@@ -150,6 +193,9 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			if s.currentFrame != nil || s.currentFrameIsLast {
 				break
 			}
+			if !block {
+				return false, bytesRead, ErrWouldBlock
+			}
 
 			s.mutex.Unlock()
 			if deadline.IsZero() {
@@ -174,29 +220,171 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			return false, bytesRead, fmt.Errorf("BUG: readPosInFrame (%d) > frame.DataLen (%d) in stream.Read", s.readPosInFrame, len(s.currentFrame))
 		}
 
-		m := copy(p[bytesRead:], s.currentFrame[s.readPosInFrame:])
+		var (
+			m       int
+			skipErr *DataSkippedError
+		)
+		if s.readSkippedAsError && s.currentFrameSkipped {
+			// Don't mix the skipped data with the data read so far.
+			if bytesRead > 0 {
+				return false, bytesRead, nil
+			}
+			m = utils.Min(len(p), len(s.currentFrame)-s.readPosInFrame)
+			skipErr = &DataSkippedError{StreamID: s.streamID, Offset: uint64(s.readOffset), Length: uint64(m)}
+		} else {
+			m = copy(p[bytesRead:], s.currentFrame[s.readPosInFrame:])
+			bytesRead += m
+		}
 		s.readPosInFrame += m
-		bytesRead += m
+		s.readOffset += protocol.ByteCount(m)
 
 		// when a RESET_STREAM was received, the was already informed about the final byteOffset for this stream
-		if !s.resetRemotely {
+		if !s.resetRemotely && !s.currentFrameEvicted {
 			s.flowController.AddBytesRead(protocol.ByteCount(m))
 		}
 
 		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
 			s.finRead = true
+			if skipErr != nil {
+				return true, bytesRead, skipErr
+			}
 			return true, bytesRead, io.EOF
 		}
+		if skipErr != nil {
+			return false, bytesRead, skipErr
+		}
 	}
 	return false, bytesRead, nil
 }
 
+func (s *receiveStream) SetReadSkippedAsError(enable bool) {
+	s.mutex.Lock()
+	s.readSkippedAsError = enable
+	s.mutex.Unlock()
+}
+
+// ReadMessageBoundary discards the data up to the next message boundary.
+func (s *receiveStream) ReadMessageBoundary() (int, error) {
+	s.readOnce <- struct{}{}
+	defer func() { <-s.readOnce }()
+
+	s.mutex.Lock()
+	completed, n, err := s.readMessageBoundaryImpl()
+	s.mutex.Unlock()
+
+	if completed {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return n, err
+}
+
+func (s *receiveStream) readMessageBoundaryImpl() (bool /* stream completed */, int, error) {
+	var (
+		discarded     int
+		buf           []byte
+		deadlineTimer *utils.Timer
+	)
+	for {
+		if s.finRead {
+			return false, discarded, io.EOF
+		}
+		// the beginning of the stream is the beginning of the first message
+		if s.readOffset == 0 || (len(s.messageBoundaries) > 0 && s.messageBoundaries[0] == s.readOffset) {
+			return false, discarded, nil
+		}
+		// The end of the stream is a message boundary as well.
+		target := s.finalOffset
+		if len(s.messageBoundaries) > 0 {
+			target = utils.Min(target, s.messageBoundaries[0])
+		}
+		if target != protocol.MaxByteCount {
+			// Discard the data in small chunks, since a lower boundary might be received while waiting for data.
+			// If the FIN is the only thing left to read, read it by discarding 1 byte.
+			if buf == nil {
+				buf = make([]byte, protocol.MaxPacketBufferSize)
+			}
+			n := utils.Min(utils.Max(target-s.readOffset, 1), protocol.ByteCount(len(buf)))
+			completed, m, err := s.readImpl(buf[:n], true)
+			discarded += m
+			if skipErr, ok := err.(*DataSkippedError); ok {
+				discarded += int(skipErr.Length)
+				err = nil
+				if completed {
+					err = io.EOF
+				}
+			}
+			s.dropMessageBoundaries()
+			if completed || err != nil {
+				return completed, discarded, err
+			}
+			continue
+		}
+
+		// We don't know where the next message starts yet.
+		// Wait for the next message boundary, or the end of the stream.
+		if s.closedForShutdown {
+			return false, discarded, s.closeForShutdownErr
+		}
+		if s.canceledRead {
+			return false, discarded, s.cancelReadErr
+		}
+		if s.resetRemotely {
+			return false, discarded, s.resetRemotelyErr
+		}
+		deadline := s.deadline
+		if !deadline.IsZero() {
+			if !time.Now().Before(deadline) {
+				return false, discarded, errDeadline
+			}
+			if deadlineTimer == nil {
+				deadlineTimer = utils.NewTimer()
+				defer deadlineTimer.Stop()
+			}
+			deadlineTimer.Reset(deadline)
+		}
+		s.mutex.Unlock()
+		if deadline.IsZero() {
+			<-s.readChan
+		} else {
+			select {
+			case <-s.readChan:
+			case <-deadlineTimer.Chan():
+				deadlineTimer.SetRead()
+			}
+		}
+		s.mutex.Lock()
+	}
+}
+
+// dropMessageBoundaries removes the message boundaries below the read position.
+func (s *receiveStream) dropMessageBoundaries() {
+	var i int
+	for i < len(s.messageBoundaries) && s.messageBoundaries[i] < s.readOffset {
+		i++
+	}
+	s.messageBoundaries = s.messageBoundaries[i:]
+}
+
 func (s *receiveStream) dequeueNextFrame() {
 	var offset protocol.ByteCount
 	// We're done with the last frame. Release the buffer.
 	if s.currentFrameDone != nil {
 		s.currentFrameDone()
 	}
+	if s.evictedBytes > 0 {
+		l := utils.Min(s.evictedBytes, protocol.ByteCount(len(zeroBuffer)))
+		offset = s.frameQueue.readPos - s.evictedBytes
+		s.evictedBytes -= l
+		s.currentFrame = zeroBuffer[:l]
+		s.currentFrameDone = nil
+		s.currentFrameEvicted = true
+		s.currentFrameSkipped = true
+		s.currentFrameIsLast = offset+l >= s.finalOffset
+		s.readPosInFrame = 0
+		return
+	}
+	s.currentFrameEvicted = false
+	s.currentFrameSkipped = s.frameQueue.IsSkipped()
 	offset, s.currentFrame, s.currentFrameDone = s.frameQueue.Pop()
 	s.currentFrameIsLast = offset+protocol.ByteCount(len(s.currentFrame)) >= s.finalOffset
 	s.readPosInFrame = 0
@@ -219,6 +407,7 @@ func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* c
 	}
 	s.canceledRead = true
 	s.cancelReadErr = fmt.Errorf("Read on stream %d canceled with error code %d", s.streamID, errorCode)
+	s.discardQueuedData()
 	s.signalRead()
 	s.sender.queueControlFrame(&wire.StopSendingFrame{
 		StreamID:  s.streamID,
@@ -229,8 +418,24 @@ func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* c
 }
 
 func (s *receiveStream) handleStreamFrame(frame *wire.StreamFrame) error {
+	return s.handleExpiringStreamFrame(frame, time.Time{})
+}
+
+// handleExpiringStreamFrame handles a STREAM frame whose data may be evicted from the receive buffer after expiry,
+// if the application doesn't read it in time.
+func (s *receiveStream) handleExpiringStreamFrame(frame *wire.StreamFrame, expiry time.Time) error {
+	return s.handleFrame(frame, expiry, false)
+}
+
+// handleSkippedStreamFrame handles the zero-filled STREAM frame created for a PRAckNotify frame.
+// If the skipped data was actually delivered, the data is read instead of the zeros.
+func (s *receiveStream) handleSkippedStreamFrame(frame *wire.StreamFrame) error {
+	return s.handleFrame(frame, time.Time{}, true)
+}
+
+func (s *receiveStream) handleFrame(frame *wire.StreamFrame, expiry time.Time, skipped bool) error {
 	s.mutex.Lock()
-	completed, err := s.handleStreamFrameImpl(frame)
+	completed, err := s.handleStreamFrameImpl(frame, expiry, skipped)
 	s.mutex.Unlock()
 
 	if completed {
@@ -240,7 +445,7 @@ func (s *receiveStream) handleStreamFrame(frame *wire.StreamFrame) error {
 	return err
 }
 
-func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /* completed */, error) {
+func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame, expiry time.Time, skipped bool) (bool /* completed */, error) {
 	maxOffset := frame.Offset + frame.DataLen()
 	if err := s.flowController.UpdateHighestReceived(maxOffset, frame.Fin); err != nil {
 		return false, err
@@ -250,16 +455,92 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 		newlyRcvdFinalOffset = s.finalOffset == protocol.MaxByteCount
 		s.finalOffset = maxOffset
 	}
-	if s.canceledRead {
+	// The data of a canceled or reset stream is never read, and skipped data doesn't need to be filled in.
+	if s.canceledRead || s.resetRemotely {
 		return newlyRcvdFinalOffset, nil
 	}
-	if err := s.frameQueue.Push(frame.Data, frame.Offset, frame.PutBack); err != nil {
+	var err error
+	if skipped {
+		var n protocol.ByteCount
+		n, err = s.frameQueue.PushSkipped(frame.Data, frame.Offset, frame.PutBack)
+		if n > 0 {
+			s.flowController.AddBytesSkipped(n)
+		}
+	} else {
+		err = s.frameQueue.PushWithExpiry(frame.Data, frame.Offset, frame.PutBack, expiry)
+	}
+	if err != nil {
 		return false, err
 	}
 	s.signalRead()
 	return false, nil
 }
 
+// handleMessageBoundaryFrame records the message boundary, such that ReadMessageBoundary can skip to it.
+func (s *receiveStream) handleMessageBoundaryFrame(frame *wire.PRMessageBoundaryFrame) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if frame.Offset > s.finalOffset {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.FinalSizeError,
+			ErrorMessage: fmt.Sprintf("message boundary at %d beyond final offset %d", frame.Offset, s.finalOffset),
+		}
+	}
+	s.dropMessageBoundaries()
+	if frame.Offset < s.readOffset || frame.Offset == 0 {
+		return nil
+	}
+	i := sort.Search(len(s.messageBoundaries), func(i int) bool { return s.messageBoundaries[i] >= frame.Offset })
+	if i < len(s.messageBoundaries) && s.messageBoundaries[i] == frame.Offset {
+		return nil
+	}
+	if len(s.messageBoundaries) >= protocol.MaxQueuedMessageBoundaries {
+		if i == len(s.messageBoundaries) {
+			return nil
+		}
+		s.messageBoundaries = s.messageBoundaries[:len(s.messageBoundaries)-1]
+	}
+	s.messageBoundaries = append(s.messageBoundaries, 0)
+	copy(s.messageBoundaries[i+1:], s.messageBoundaries[i:])
+	s.messageBoundaries[i] = frame.Offset
+	s.signalRead()
+	return nil
+}
+
+// evictExpiredData evicts expired data at the read position from the receive buffer,
+// such that a slow reader doesn't block the peer for data that isn't useful any more.
+// Evicted data is counted as read by the flow controller, and is read as zeros by the application.
+// It returns the time when the data at the read position expires, or the zero value if it never expires.
+func (s *receiveStream) evictExpiredData(now time.Time) time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.finRead || s.canceledRead || s.resetRemotely || s.closedForShutdown {
+		return time.Time{}
+	}
+	var evicted protocol.ByteCount
+	var nextExpiry time.Time
+	for {
+		data, doneCb, expiry := s.frameQueue.PopExpired(now)
+		if data == nil {
+			nextExpiry = expiry
+			break
+		}
+		evicted += protocol.ByteCount(len(data))
+		if doneCb != nil {
+			doneCb()
+		}
+	}
+	if evicted > 0 {
+		s.logger.Debugf("Evicted %d bytes of expired data", evicted)
+		s.evictedBytes += evicted
+		s.flowController.AddBytesRead(evicted)
+		s.signalRead()
+	}
+	return nextExpiry
+}
+
 func (s *receiveStream) handleResetStreamFrame(frame *wire.ResetStreamFrame) error {
 	s.mutex.Lock()
 	completed, err := s.handleResetStreamFrameImpl(frame)
@@ -291,10 +572,26 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 		StreamID:  s.streamID,
 		ErrorCode: frame.ErrorCode,
 	}
+	s.discardQueuedData()
 	s.signalRead()
 	return newlyRcvdFinalOffset, nil
 }
 
+// discardQueuedData releases the data and the fillers for skipped data that were queued, but not read yet.
+// It also drops the evicted bytes and the message boundaries, since nothing will be read from the stream any more.
+// must be called after locking the mutex
+func (s *receiveStream) discardQueuedData() {
+	if s.currentFrameDone != nil {
+		s.currentFrameDone()
+	}
+	s.currentFrame = nil
+	s.currentFrameDone = nil
+	s.readPosInFrame = 0
+	s.evictedBytes = 0
+	s.messageBoundaries = nil
+	s.frameQueue.Discard()
+}
+
 func (s *receiveStream) CloseRemote(offset protocol.ByteCount) {
 	s.handleStreamFrame(&wire.StreamFrame{Fin: true, Offset: offset})
 }
@@ -307,13 +604,23 @@ func (s *receiveStream) SetReadDeadline(t time.Time) error {
 	return nil
 }
 
+// handoffState records the state of the receive side, see Connection.Handoff.
+func (s *receiveStream) handoffState(h *HandoffStream) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h.StreamID = s.streamID
+	h.BytesRead = uint64(s.readOffset)
+	h.ReceiveFinished = s.finRead || s.resetRemotely
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Read unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RESET.
 func (s *receiveStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.closedForShutdown = true
-	s.closeForShutdownErr = err
+	s.closeForShutdownErr = newConnectionClosedError(s.streamID, err)
 	s.mutex.Unlock()
 	s.signalRead()
 }
@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 	"io"
+	"net"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -11,6 +12,8 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -31,7 +34,7 @@ var _ = Describe("Receive Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newReceiveStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		str = newReceiveStream(streamID, mockSender, mockFC, utils.DefaultLogger, protocol.VersionWhatever)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutReader(str, timeout)
@@ -218,6 +221,53 @@ var _ = Describe("Receive Stream", func() {
 			Expect(b).To(Equal([]byte("foobar")))
 		})
 
+		Context("non-blocking reads", func() {
+			It("reads the data that is available", func() {
+				b := make([]byte, 6)
+				n, err := str.TryRead(b)
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foob")})).To(Succeed())
+				n, err = str.TryRead(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(4))
+				Expect(b[:n]).To(Equal([]byte("foob")))
+				n, err = str.TryRead(b)
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+			})
+
+			It("returns io.EOF", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo"), Fin: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 6)
+				n, err := str.TryRead(b)
+				Expect(err).To(MatchError(io.EOF))
+				Expect(n).To(Equal(3))
+			})
+
+			It("doesn't read while Read is blocked", func() {
+				testErr := errors.New("shutdown")
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					_, err := str.Read(make([]byte, 6))
+					Expect(err).To(MatchError(testErr))
+				}()
+				Eventually(func() int { return len(str.readOnce) }).Should(Equal(1))
+				n, err := str.TryRead(make([]byte, 6))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+				str.closeForShutdown(testErr)
+				Eventually(done).Should(BeClosed())
+			})
+		})
+
 		Context("deadlines", func() {
 			It("the deadline error has the right net.Error properties", func() {
 				Expect(errDeadline.Timeout()).To(BeTrue())
@@ -298,6 +348,7 @@ var _ = Describe("Receive Stream", func() {
 			})
 
 			It("doesn't unblock if the deadline is removed", func() {
+				testErr := errors.New("test done")
 				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
 				str.SetReadDeadline(deadline)
 				deadlineUnset := make(chan struct{})
@@ -313,14 +364,14 @@ var _ = Describe("Receive Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Read(make([]byte, 1))
-					Expect(err).To(MatchError("test done"))
+					Expect(err).To(MatchError(testErr))
 					close(done)
 				}()
 				runtime.Gosched()
 				Eventually(deadlineUnset).Should(BeClosed())
 				Consistently(done, scaleDuration(100*time.Millisecond)).ShouldNot(BeClosed())
 				// make the go routine return
-				str.closeForShutdown(errors.New("test done"))
+				str.closeForShutdown(testErr)
 				Eventually(done).Should(BeClosed())
 			})
 		})
@@ -481,6 +532,308 @@ var _ = Describe("Receive Stream", func() {
 				Expect(n).To(BeZero())
 				Expect(err).To(MatchError(testErr))
 			})
+
+			It("says who closed the connection, and why", func() {
+				str.closeForShutdown(&qerr.TransportError{
+					ErrorCode:    qerr.FlowControlError,
+					ErrorMessage: "flow control violated",
+				})
+				_, err := strWithTimeout.Read(make([]byte, 1))
+				var closeErr *ConnectionClosedError
+				Expect(errors.As(err, &closeErr)).To(BeTrue())
+				Expect(closeErr.StreamID).To(Equal(streamID))
+				Expect(closeErr.Remote).To(BeFalse())
+				Expect(closeErr.IsApplicationError).To(BeFalse())
+				Expect(closeErr.ErrorCode).To(BeEquivalentTo(qerr.FlowControlError))
+				Expect(closeErr.ReasonPhrase).To(Equal("flow control violated"))
+			})
+
+			It("marks stateless resets as remote", func() {
+				str.closeForShutdown(&qerr.StatelessResetError{})
+				_, err := strWithTimeout.Read(make([]byte, 1))
+				var closeErr *ConnectionClosedError
+				Expect(errors.As(err, &closeErr)).To(BeTrue())
+				Expect(closeErr.Remote).To(BeTrue())
+				var resetErr *StatelessResetError
+				Expect(errors.As(err, &resetErr)).To(BeTrue())
+			})
+
+			It("matches the connection error", func() {
+				str.closeForShutdown(&qerr.IdleTimeoutError{})
+				_, err := strWithTimeout.Read(make([]byte, 1))
+				Expect(err).To(MatchError(&IdleTimeoutError{}))
+				Expect(errors.Is(err, &HandshakeTimeoutError{})).To(BeFalse())
+				Expect(errors.Is(err, net.ErrClosed)).To(BeTrue())
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+			})
+		})
+	})
+
+	Context("skipped data", func() {
+		It("reads data that arrives after it was skipped", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true).Times(2)
+			mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(6))
+			Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6), Fin: true})).To(Succeed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar"), Fin: true})).To(Succeed())
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			b := make([]byte, 10)
+			n, err := strWithTimeout.Read(b)
+			Expect(err).To(MatchError(io.EOF))
+			Expect(b[:n]).To(Equal([]byte{0, 0, 0, 'b', 'a', 'r'}))
+		})
+
+		It("credits the connection for skipped bytes once", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false).Times(3)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(9), false)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar")})).To(Succeed())
+			mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(3))
+			Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6)})).To(Succeed())
+			// duplicate PRAckNotify frame
+			Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6)})).To(Succeed())
+			mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(3))
+			Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 9)})).To(Succeed())
+		})
+
+		It("unblocks a Read with a deadline when the data is skipped", func() {
+			str.SetReadDeadline(time.Now().Add(time.Hour))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				b := make([]byte, 6)
+				n, err := str.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal(make([]byte, 6)))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+			mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(6))
+			Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6)})).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+
+		Context("reading skipped data as an error", func() {
+			BeforeEach(func() {
+				str.SetReadSkippedAsError(true)
+			})
+
+			It("returns the data received before the skipped data, and then an error", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(8), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(11), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo")})).To(Succeed())
+				mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(5))
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Offset: 3, Data: make([]byte, 5)})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 8, Data: []byte("bar")})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				b := make([]byte, 10)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foo")))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				n, err = strWithTimeout.Read(b[:4])
+				Expect(n).To(BeZero())
+				Expect(err).To(MatchError(&DataSkippedError{StreamID: streamID, Offset: 3, Length: 4}))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1))
+				n, err = strWithTimeout.Read(b)
+				Expect(n).To(BeZero())
+				Expect(err).To(MatchError(&DataSkippedError{StreamID: streamID, Offset: 7, Length: 1}))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				n, err = strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("bar")))
+			})
+
+			It("returns io.EOF after skipped data at the end of the stream", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(6))
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6), Fin: true})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 10)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).To(MatchError(&DataSkippedError{StreamID: streamID, Offset: 0, Length: 6}))
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(io.EOF))
+			})
+
+			It("reads skipped data that was received nonetheless", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false).Times(2)
+				mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(6))
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6)})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar")})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				b := make([]byte, 6)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte{0, 0, 0, 'b', 'a', 'r'}))
+			})
+
+			It("returns an error for evicted data", func() {
+				now := time.Now()
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleExpiringStreamFrame(&wire.StreamFrame{Data: []byte("foob")}, now)).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				str.evictExpiredData(now)
+				_, err := strWithTimeout.Read(make([]byte, 10))
+				Expect(err).To(MatchError(&DataSkippedError{StreamID: streamID, Offset: 0, Length: 4}))
+			})
+
+			It("discards skipped data when skipping to a message boundary", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(3), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(8), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo")})).To(Succeed())
+				mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(5))
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Offset: 3, Data: make([]byte, 5)})).To(Succeed())
+				Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 6})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1))
+				_, err := strWithTimeout.Read(make([]byte, 1))
+				Expect(err).ToNot(HaveOccurred())
+				mockFC.EXPECT().AddBytesRead(gomock.Any()).Times(2)
+				n, err := str.ReadMessageBoundary()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(5))
+			})
+		})
+	})
+
+	Context("message boundaries", func() {
+		It("is at a message boundary at the beginning of the stream", func() {
+			n, err := str.ReadMessageBoundary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeZero())
+		})
+
+		It("discards data up to the next message boundary", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+			Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 3})).To(Succeed())
+			b := make([]byte, 1)
+			_, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			n, err := str.ReadMessageBoundary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+			// we're at the boundary now
+			n, err = str.ReadMessageBoundary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeZero())
+			b = make([]byte, 3)
+			_, err = strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("bar")))
+		})
+
+		It("blocks until the next message boundary is received", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+			b := make([]byte, 1)
+			_, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				n, err := str.ReadMessageBoundary()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(3))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 4})).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("treats the end of the stream as a message boundary", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), Fin: true})).To(Succeed())
+			b := make([]byte, 1)
+			_, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			n, err := str.ReadMessageBoundary()
+			Expect(err).To(MatchError(io.EOF))
+			Expect(n).To(Equal(5))
+		})
+
+		It("ignores message boundaries that were already read", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+			b := make([]byte, 3)
+			_, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 2})).To(Succeed())
+			Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 5})).To(Succeed())
+			Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 5})).To(Succeed())
+			Expect(str.messageBoundaries).To(Equal([]protocol.ByteCount{5}))
+			n, err := str.ReadMessageBoundary()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(2))
+		})
+
+		It("errors when a message boundary is beyond the final offset", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), Fin: true})).To(Succeed())
+			Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 7})).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.FinalSizeError,
+				ErrorMessage: "message boundary at 7 beyond final offset 6",
+			}))
+		})
+
+		It("limits the number of message boundaries", func() {
+			for i := 1; i <= protocol.MaxQueuedMessageBoundaries+1; i++ {
+				Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: protocol.ByteCount(2 * i)})).To(Succeed())
+			}
+			Expect(str.messageBoundaries).To(HaveLen(protocol.MaxQueuedMessageBoundaries))
+			// a lower boundary replaces the highest one
+			Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 1})).To(Succeed())
+			Expect(str.messageBoundaries).To(HaveLen(protocol.MaxQueuedMessageBoundaries))
+			Expect(str.messageBoundaries[0]).To(Equal(protocol.ByteCount(1)))
+			Expect(str.messageBoundaries[protocol.MaxQueuedMessageBoundaries-1]).To(Equal(protocol.ByteCount(2 * (protocol.MaxQueuedMessageBoundaries - 1))))
+		})
+	})
+
+	Context("evicting expired data", func() {
+		It("evicts expired data, and reads it as zeros", func() {
+			now := time.Now()
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+			Expect(str.handleExpiringStreamFrame(&wire.StreamFrame{Data: []byte("foob")}, now)).To(Succeed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("ar"), Fin: true})).To(Succeed())
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+			Expect(str.evictExpiredData(now)).To(BeZero())
+			// the evicted data was already counted as read
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			b := make([]byte, 10)
+			n, err := strWithTimeout.Read(b)
+			Expect(err).To(MatchError(io.EOF))
+			Expect(b[:n]).To(Equal([]byte{0, 0, 0, 0, 'a', 'r'}))
+		})
+
+		It("doesn't evict data that didn't expire yet", func() {
+			expiry := time.Now().Add(time.Hour)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			Expect(str.handleExpiringStreamFrame(&wire.StreamFrame{Data: []byte("foobar")}, expiry)).To(Succeed())
+			Expect(str.evictExpiredData(time.Now())).To(Equal(expiry))
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+			b := make([]byte, 6)
+			n, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't evict data that doesn't expire", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+			Expect(str.evictExpiredData(time.Now())).To(BeZero())
 		})
 	})
 
@@ -551,6 +904,22 @@ var _ = Describe("Receive Stream", func() {
 				str.CancelRead(1234)
 			})
 
+			It("discards queued data, skipped data and message boundaries", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false).Times(2)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar")})).To(Succeed())
+				mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(3))
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6)})).To(Succeed())
+				Expect(str.handleMessageBoundaryFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 3})).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(1234)
+				Expect(str.frameQueue.HasMoreData()).To(BeFalse())
+				Expect(str.messageBoundaries).To(BeEmpty())
+				// skipped data received after canceling is ignored
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(9), false)
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Offset: 6, Data: make([]byte, 3)})).To(Succeed())
+				Expect(str.frameQueue.HasMoreData()).To(BeFalse())
+			})
+
 			It("sends a STOP_SENDING and completes the stream after receiving the final offset", func() {
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(1000), true)
 				Expect(str.handleStreamFrame(&wire.StreamFrame{
@@ -668,6 +1037,26 @@ var _ = Describe("Receive Stream", func() {
 				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
 			})
 
+			It("discards queued data and skipped data", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false).Times(2)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar")})).To(Succeed())
+				mockFC.EXPECT().AddBytesSkipped(protocol.ByteCount(3))
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Data: make([]byte, 6)})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true),
+					mockFC.EXPECT().Abandon(),
+				)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				Expect(str.frameQueue.HasMoreData()).To(BeFalse())
+				// Data received after the reset is ignored.
+				// In particular, skipped data is not credited to the connection again after the flow controller was abandoned.
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false).Times(2)
+				Expect(str.handleSkippedStreamFrame(&wire.StreamFrame{Offset: 6, Data: make([]byte, 4)})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("data")})).To(Succeed())
+				Expect(str.frameQueue.HasMoreData()).To(BeFalse())
+			})
+
 			It("doesn't do anyting when it was closed for shutdown", func() {
 				str.closeForShutdown(nil)
 				err := str.handleResetStreamFrame(rst)
@@ -0,0 +1,97 @@
+// Package replay records the packet-level trace of a connection, such that it can be replayed in tests.
+//
+// The trace contains the packets sent and received, and the packets that were acknowledged or declared lost,
+// along with the time at which that happened. For frames carrying stream data, the stream ID,
+// the offset and length of the data, and its PR policy are recorded, but not the data itself.
+// This allows reproducing bugs in the loss handling of partially reliable streams that were observed in the wild:
+// replaying the trace makes the state machine see the same sequence of losses and acknowledgements as the original connection.
+//
+// A trace is stored as a sequence of JSON objects, one per line, see Event.
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// An EventType is the type of an Event.
+type EventType string
+
+const (
+	// EventPacketSent is recorded when a packet is sent.
+	EventPacketSent EventType = "packet_sent"
+	// EventPacketReceived is recorded when a packet is received.
+	EventPacketReceived EventType = "packet_received"
+	// EventPacketAcked is recorded when a packet sent is acknowledged.
+	EventPacketAcked EventType = "packet_acked"
+	// EventPacketLost is recorded when a packet sent is declared lost.
+	EventPacketLost EventType = "packet_lost"
+)
+
+// A FrameType is the type of a recorded Frame.
+type FrameType string
+
+const (
+	// FrameTypeAck is an ACK frame.
+	FrameTypeAck FrameType = "ack"
+	// FrameTypeStream is a STREAM frame.
+	FrameTypeStream FrameType = "stream"
+	// FrameTypePRStream is a PR_STREAM frame.
+	FrameTypePRStream FrameType = "pr_stream"
+	// FrameTypePRAckNotify is a PR_ACK_NOTIFY frame.
+	FrameTypePRAckNotify FrameType = "pr_ack_notify"
+	// FrameTypeOther is used for all frames that are not relevant for the replay.
+	FrameTypeOther FrameType = "other"
+)
+
+// An Event is an event recorded in a trace.
+type Event struct {
+	// Time is the time since the start of the recording.
+	Time            time.Duration           `json:"time"`
+	Type            EventType               `json:"type"`
+	EncryptionLevel logging.EncryptionLevel `json:"encryption_level"`
+	PacketNumber    logging.PacketNumber    `json:"packet_number"`
+	// Frames are the frames contained in a packet sent or received.
+	Frames []Frame `json:"frames,omitempty"`
+}
+
+// A Frame is a frame contained in a recorded packet.
+// Depending on the Type, not all fields are used.
+type Frame struct {
+	Type FrameType `json:"type"`
+	// StreamID, Offset, Length and Fin describe the data of STREAM, PR_STREAM and PR_ACK_NOTIFY frames.
+	StreamID logging.StreamID  `json:"stream_id,omitempty"`
+	Offset   logging.ByteCount `json:"offset,omitempty"`
+	Length   logging.ByteCount `json:"length,omitempty"`
+	Fin      bool              `json:"fin,omitempty"`
+	// PTDA and Value are the PR policy of PR_STREAM and PR_ACK_NOTIFY frames.
+	PTDA  uint8  `json:"ptda,omitempty"`
+	Value uint64 `json:"value,omitempty"`
+	// AckRanges are the ranges acknowledged by an ACK frame.
+	AckRanges []logging.AckRange `json:"ack_ranges,omitempty"`
+}
+
+// A Trace is a recorded trace.
+type Trace struct {
+	Events []Event
+}
+
+// Read reads a trace written by a tracer created by NewConnectionTracer.
+func Read(r io.Reader) (*Trace, error) {
+	t := &Trace{}
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var ev Event
+		if err := dec.Decode(&ev); err != nil {
+			if err == io.EOF {
+				return t, nil
+			}
+			return nil, err
+		}
+		t.Events = append(t.Events, ev)
+	}
+}
@@ -0,0 +1,184 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+type tracer struct {
+	logging.NullTracer
+
+	getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser
+}
+
+var _ logging.Tracer = &tracer{}
+
+// NewTracer creates a new tracer recording the trace of every connection.
+// If getLogWriter returns nil, the connection is not recorded.
+func NewTracer(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser) logging.Tracer {
+	return &tracer{getLogWriter: getLogWriter}
+}
+
+func (t *tracer) TracerForConnection(_ context.Context, p logging.Perspective, odcid logging.ConnectionID) logging.ConnectionTracer {
+	if w := t.getLogWriter(p, odcid.Bytes()); w != nil {
+		return NewConnectionTracer(w)
+	}
+	return nil
+}
+
+type connectionTracer struct {
+	logging.NullConnectionTracer
+
+	mutex         sync.Mutex
+	w             io.WriteCloser
+	buf           *bufio.Writer
+	enc           *json.Encoder
+	encodeErr     error
+	referenceTime time.Time
+}
+
+var _ logging.ConnectionTracer = &connectionTracer{}
+
+// NewConnectionTracer creates a new tracer to record the trace of a connection.
+// The trace is written to w, which is closed when the connection is closed.
+func NewConnectionTracer(w io.WriteCloser) logging.ConnectionTracer {
+	buf := bufio.NewWriter(w)
+	return &connectionTracer{
+		w:             w,
+		buf:           buf,
+		enc:           json.NewEncoder(buf),
+		referenceTime: time.Now(),
+	}
+}
+
+func (t *connectionTracer) record(ev *Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.encodeErr != nil {
+		return
+	}
+	ev.Time = time.Since(t.referenceTime)
+	t.encodeErr = t.enc.Encode(ev)
+}
+
+func (t *connectionTracer) SentPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
+	fs := make([]Frame, 0, len(frames)+1)
+	if ack != nil {
+		fs = append(fs, convertFrame(ack))
+	}
+	for _, f := range frames {
+		fs = append(fs, convertFrame(f))
+	}
+	t.record(&Event{
+		Type:            EventPacketSent,
+		EncryptionLevel: encryptionLevel(hdr),
+		PacketNumber:    hdr.PacketNumber,
+		Frames:          fs,
+	})
+}
+
+func (t *connectionTracer) ReceivedLongHeaderPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, frames []logging.Frame) {
+	t.receivedPacket(encryptionLevel(hdr), hdr.PacketNumber, frames)
+}
+
+func (t *connectionTracer) ReceivedShortHeaderPacket(hdr *logging.ShortHeader, _ logging.ByteCount, frames []logging.Frame) {
+	t.receivedPacket(logging.Encryption1RTT, hdr.PacketNumber, frames)
+}
+
+func (t *connectionTracer) receivedPacket(encLevel logging.EncryptionLevel, pn logging.PacketNumber, frames []logging.Frame) {
+	fs := make([]Frame, 0, len(frames))
+	for _, f := range frames {
+		fs = append(fs, convertFrame(f))
+	}
+	t.record(&Event{
+		Type:            EventPacketReceived,
+		EncryptionLevel: encLevel,
+		PacketNumber:    pn,
+		Frames:          fs,
+	})
+}
+
+func (t *connectionTracer) AcknowledgedPacket(encLevel logging.EncryptionLevel, pn logging.PacketNumber) {
+	t.record(&Event{
+		Type:            EventPacketAcked,
+		EncryptionLevel: encLevel,
+		PacketNumber:    pn,
+	})
+}
+
+func (t *connectionTracer) LostPacket(encLevel logging.EncryptionLevel, pn logging.PacketNumber, _ logging.PacketLossReason) {
+	t.record(&Event{
+		Type:            EventPacketLost,
+		EncryptionLevel: encLevel,
+		PacketNumber:    pn,
+	})
+}
+
+func (t *connectionTracer) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.encodeErr == nil {
+		t.encodeErr = t.buf.Flush()
+	}
+	t.w.Close()
+}
+
+func encryptionLevel(hdr *logging.ExtendedHeader) logging.EncryptionLevel {
+	if !hdr.IsLongHeader {
+		return logging.Encryption1RTT
+	}
+	switch hdr.Type {
+	case protocol.PacketTypeInitial:
+		return logging.EncryptionInitial
+	case protocol.PacketTypeHandshake:
+		return logging.EncryptionHandshake
+	default:
+		return logging.Encryption0RTT
+	}
+}
+
+func convertFrame(f logging.Frame) Frame {
+	switch f := f.(type) {
+	case *logging.AckFrame:
+		ranges := make([]logging.AckRange, len(f.AckRanges))
+		copy(ranges, f.AckRanges)
+		return Frame{Type: FrameTypeAck, AckRanges: ranges}
+	case *logging.StreamFrame:
+		return Frame{
+			Type:     FrameTypeStream,
+			StreamID: f.StreamID,
+			Offset:   f.Offset,
+			Length:   f.Length,
+			Fin:      f.Fin,
+		}
+	case *logging.PRStreamFrame:
+		return Frame{
+			Type:     FrameTypePRStream,
+			StreamID: f.StreamID,
+			Offset:   f.Offset,
+			Length:   f.Length,
+			Fin:      f.Fin,
+			PTDA:     f.PTDA,
+			Value:    f.Value,
+		}
+	case *logging.PRAckNotifyFrame:
+		return Frame{
+			Type:     FrameTypePRAckNotify,
+			StreamID: f.StreamID,
+			Offset:   f.Offset,
+			Length:   logging.ByteCount(f.PRDataLen),
+			Fin:      f.Fin,
+			PTDA:     f.PTDA,
+			Value:    f.PtdaC,
+		}
+	default:
+		return Frame{Type: FrameTypeOther}
+	}
+}
@@ -0,0 +1,93 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type nopWriteCloserImpl struct{ io.Writer }
+
+func (nopWriteCloserImpl) Close() error { return nil }
+
+func nopWriteCloser(w io.Writer) io.WriteCloser {
+	return &nopWriteCloserImpl{Writer: w}
+}
+
+var _ = Describe("Tracing", func() {
+	It("returns no connection tracer if the writer is nil", func() {
+		t := NewTracer(func(logging.Perspective, []byte) io.WriteCloser { return nil })
+		Expect(t.TracerForConnection(context.Background(), logging.PerspectiveClient, protocol.ParseConnectionID([]byte{1, 2, 3, 4}))).To(BeNil())
+	})
+
+	It("records and reads a trace", func() {
+		buf := &bytes.Buffer{}
+		t := NewTracer(func(logging.Perspective, []byte) io.WriteCloser { return nopWriteCloser(buf) })
+		tracer := t.TracerForConnection(context.Background(), logging.PerspectiveClient, protocol.ParseConnectionID([]byte{1, 2, 3, 4}))
+		tracer.SentPacket(
+			&logging.ExtendedHeader{PacketNumber: 3},
+			1234,
+			&logging.AckFrame{AckRanges: []logging.AckRange{{Smallest: 1, Largest: 10}}},
+			[]logging.Frame{
+				&logging.StreamFrame{StreamID: 4, Offset: 10, Length: 100, Fin: true},
+				&logging.PRStreamFrame{StreamID: 8, Offset: 20, Length: 6, PTDA: 0x20, Value: 100},
+				&logging.PingFrame{},
+			},
+		)
+		tracer.LostPacket(logging.Encryption1RTT, 3, logging.PacketLossReorderingThreshold)
+		tracer.ReceivedShortHeaderPacket(
+			&logging.ShortHeader{PacketNumber: 7},
+			1234,
+			[]logging.Frame{&logging.PRAckNotifyFrame{StreamID: 8, Offset: 20, PRDataLen: 6, PTDA: 0x20, PtdaC: 100}},
+		)
+		tracer.AcknowledgedPacket(logging.Encryption1RTT, 3)
+		tracer.Close()
+
+		trace, err := Read(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(trace.Events).To(HaveLen(4))
+		for i := 1; i < len(trace.Events); i++ {
+			Expect(trace.Events[i].Time).To(BeNumerically(">=", trace.Events[i-1].Time))
+		}
+		sent := trace.Events[0]
+		Expect(sent.Type).To(Equal(EventPacketSent))
+		Expect(sent.EncryptionLevel).To(Equal(logging.Encryption1RTT))
+		Expect(sent.PacketNumber).To(Equal(logging.PacketNumber(3)))
+		Expect(sent.Frames).To(Equal([]Frame{
+			{Type: FrameTypeAck, AckRanges: []logging.AckRange{{Smallest: 1, Largest: 10}}},
+			{Type: FrameTypeStream, StreamID: 4, Offset: 10, Length: 100, Fin: true},
+			{Type: FrameTypePRStream, StreamID: 8, Offset: 20, Length: 6, PTDA: 0x20, Value: 100},
+			{Type: FrameTypeOther},
+		}))
+		Expect(trace.Events[1]).To(Equal(Event{Time: trace.Events[1].Time, Type: EventPacketLost, EncryptionLevel: logging.Encryption1RTT, PacketNumber: 3}))
+		received := trace.Events[2]
+		Expect(received.Type).To(Equal(EventPacketReceived))
+		Expect(received.PacketNumber).To(Equal(logging.PacketNumber(7)))
+		Expect(received.Frames).To(Equal([]Frame{{Type: FrameTypePRAckNotify, StreamID: 8, Offset: 20, Length: 6, PTDA: 0x20, Value: 100}}))
+		Expect(trace.Events[3].Type).To(Equal(EventPacketAcked))
+	})
+
+	It("records the encryption level of long header packets", func() {
+		buf := &bytes.Buffer{}
+		tracer := NewConnectionTracer(nopWriteCloser(buf))
+		tracer.SentPacket(&logging.ExtendedHeader{Header: logging.Header{IsLongHeader: true, Type: protocol.PacketTypeHandshake}, PacketNumber: 1}, 1234, nil, nil)
+		tracer.ReceivedLongHeaderPacket(&logging.ExtendedHeader{Header: logging.Header{IsLongHeader: true, Type: protocol.PacketTypeInitial}, PacketNumber: 2}, 1234, nil)
+		tracer.Close()
+		trace, err := Read(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(trace.Events).To(HaveLen(2))
+		Expect(trace.Events[0].EncryptionLevel).To(Equal(logging.EncryptionHandshake))
+		Expect(trace.Events[1].EncryptionLevel).To(Equal(logging.EncryptionInitial))
+	})
+
+	It("errors on invalid traces", func() {
+		_, err := Read(bytes.NewReader([]byte("foobar")))
+		Expect(err).To(HaveOccurred())
+	})
+})
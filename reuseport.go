@@ -0,0 +1,325 @@
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	"golang.org/x/net/bpf"
+)
+
+// maxReusePortSockets is the maximum value of Config.ReusePortSockets.
+// The socket is encoded in the first byte of the connection ID.
+const maxReusePortSockets = 256
+
+// connIDShard returns the index of the socket that handles the connection ID, see Config.ReusePortSockets.
+func connIDShard(connID protocol.ConnectionID, numShards int) int {
+	if connID.Len() == 0 {
+		return 0
+	}
+	return int(connID.Bytes()[0]) % numShards
+}
+
+// ReusePortSocket returns the index of the socket that handles a connection ID
+// issued by a listener using numSockets sockets, see Config.ReusePortSockets.
+// The sockets are numbered in the order they were bound to the address, which is also their index in the SO_REUSEPORT group.
+// The connection IDs are encoded as follows: the first byte, modulo the number of sockets, is the index of the socket.
+// All other bytes are generated by the ConnectionIDGenerator of the config.
+func ReusePortSocket(connID ConnectionID, numSockets int) int {
+	if numSockets <= 1 {
+		return 0
+	}
+	return connIDShard(connID, numSockets)
+}
+
+// ReusePortSteeringKey returns the steering key of a QUIC packet, which is the first byte of its Destination Connection ID.
+// The packet is the UDP payload. It is handled by the socket with the index key % numSockets.
+// Short header packets are expected to carry a connection ID issued by the listener.
+// Long header packets with a zero-length Destination Connection ID have the key 0.
+// It can be used to steer packets in XDP programs and load balancers.
+func ReusePortSteeringKey(packet []byte) (byte, error) {
+	if len(packet) < 2 {
+		return 0, errors.New("quic: packet too short")
+	}
+	if packet[0]&0x80 == 0 {
+		return packet[1], nil
+	}
+	// Long header: the Destination Connection ID follows the first byte, the version and the length.
+	if len(packet) < 6 {
+		return 0, errors.New("quic: packet too short")
+	}
+	if packet[5] == 0 {
+		return 0, nil
+	}
+	if len(packet) < 7 {
+		return 0, errors.New("quic: packet too short")
+	}
+	return packet[6], nil
+}
+
+// ReusePortSteeringProgram generates a classic BPF program that steers packets to the socket that handles their connection ID,
+// for a listener using numSockets sockets. It computes the steering key (see ReusePortSteeringKey) modulo the number of sockets.
+// On Linux, it can be attached to the SO_REUSEPORT group using the SO_ATTACH_REUSEPORT_CBPF socket option,
+// such that packets don't need to be passed between the sockets.
+func ReusePortSteeringProgram(numSockets int) ([]bpf.RawInstruction, error) {
+	if numSockets < 1 || numSockets > maxReusePortSockets {
+		return nil, fmt.Errorf("quic: invalid number of sockets: %d", numSockets)
+	}
+	return bpf.Assemble([]bpf.Instruction{
+		bpf.LoadAbsolute{Off: 0, Size: 1},
+		bpf.JumpIf{Cond: bpf.JumpBitsSet, Val: 0x80, SkipTrue: 2},
+		// short header
+		bpf.LoadAbsolute{Off: 1, Size: 1},
+		bpf.Jump{Skip: 3},
+		// long header
+		bpf.LoadAbsolute{Off: 5, Size: 1},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0, SkipTrue: 2},
+		bpf.LoadAbsolute{Off: 6, Size: 1},
+		bpf.ALUOpConstant{Op: bpf.ALUOpMod, Val: uint32(numSockets)},
+		bpf.RetA{},
+	})
+}
+
+// The shardedConnIDGenerator generates connection IDs that are handled by a socket of a listener using SO_REUSEPORT.
+// The first byte, modulo the number of sockets, is the index of the socket.
+// The other bits are generated by the ConnectionIDGenerator of the config.
+type shardedConnIDGenerator struct {
+	ConnectionIDGenerator
+	shard, numShards int
+}
+
+func (g *shardedConnIDGenerator) GenerateConnectionID() (protocol.ConnectionID, error) {
+	connID, err := g.ConnectionIDGenerator.GenerateConnectionID()
+	if err != nil || connID.Len() == 0 {
+		return connID, err
+	}
+	b := connID.Bytes()
+	v := int(b[0]) - int(b[0])%g.numShards + g.shard
+	if v > 0xff {
+		v -= g.numShards
+	}
+	b[0] = byte(v)
+	return protocol.ParseConnectionID(b), nil
+}
+
+// listenAddrReusePort opens Config.ReusePortSockets sockets on the address, and starts a server on each of them.
+func listenAddrReusePort(addr string, tlsConf *tls.Config, config *Config, acceptEarly bool) (*shardedServer, error) {
+	if tlsConf == nil {
+		return nil, errors.New("quic: tls.Config not set")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = populateServerConfig(config)
+	if config.ConnectionIDGenerator.ConnectionIDLen() == 0 {
+		return nil, errors.New("quic: Config.ReusePortSockets requires connection IDs")
+	}
+	tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	lc := net.ListenConfig{Control: setReusePort}
+	conns := make([]net.PacketConn, 0, config.ReusePortSockets)
+	closeConns := func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}
+	for i := 0; i < config.ReusePortSockets; i++ {
+		// If the port is 0, the first socket chooses the port, and the other ones bind to the same port.
+		if i == 1 {
+			addr = conns[0].LocalAddr().String()
+		}
+		conn, err := lc.ListenPacket(context.Background(), "udp", addr)
+		if err != nil {
+			closeConns()
+			return nil, err
+		}
+		if config.DisableOOB {
+			conn = newPortableConn(conn)
+		}
+		conns = append(conns, conn)
+	}
+
+	// Each socket has its own packet handler map, and packets are passed to the map of the socket that handles the connection ID.
+	// The sockets share the local address, so the maps can't be registered with the multiplexer.
+	maps := make([]*packetHandlerMap, 0, len(conns))
+	for _, conn := range conns {
+		m, err := newPacketHandlerMap(conn, config.ConnectionIDGenerator.ConnectionIDLen(), config.StatelessResetKey, config.Tracer, utils.DefaultLogger.WithPrefix("muxer"))
+		if err != nil {
+			for _, m := range maps {
+				m.Destroy()
+			}
+			closeConns()
+			return nil, err
+		}
+		maps = append(maps, m.(*packetHandlerMap))
+	}
+	for _, m := range maps {
+		m.setShards(maps)
+	}
+
+	s := &shardedServer{
+		shards:  make([]*baseServer, 0, len(conns)),
+		conns:   make(chan quicConn),
+		stopped: make(chan struct{}),
+	}
+	for i, conn := range conns {
+		conf := config.Clone()
+		conf.ConnectionIDGenerator = &shardedConnIDGenerator{
+			ConnectionIDGenerator: config.ConnectionIDGenerator,
+			shard:                 i,
+			numShards:             len(conns),
+		}
+		// The tokens are validated by the socket that handles the connection ID the client chose, so they must be shared.
+		serv, err := newServer(conn, maps[i], tlsConf, conf, tokenGenerator, acceptEarly)
+		if err != nil {
+			s.Close()
+			for _, m := range maps[i:] {
+				m.Destroy()
+			}
+			return nil, err
+		}
+		serv.createdPacketConn = true
+		s.shards = append(s.shards, serv)
+	}
+	for _, serv := range s.shards {
+		go s.runAccept(serv)
+	}
+	return s, nil
+}
+
+// The shardedServer is a Listener that uses multiple sockets, see Config.ReusePortSockets.
+type shardedServer struct {
+	shards []*baseServer
+
+	conns chan quicConn
+
+	stopOnce sync.Once
+	stopped  chan struct{} // closed when accepting on one of the sockets fails
+	err      error
+}
+
+var _ Listener = &shardedServer{}
+
+// runAccept passes the connections accepted by a socket to Accept.
+func (s *shardedServer) runAccept(serv *baseServer) {
+	for {
+		conn, err := serv.accept(context.Background())
+		if err != nil {
+			s.stopOnce.Do(func() {
+				s.err = err
+				close(s.stopped)
+			})
+			return
+		}
+		select {
+		case s.conns <- conn:
+		case <-s.stopped:
+			return
+		}
+	}
+}
+
+func (s *shardedServer) accept(ctx context.Context) (quicConn, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case conn := <-s.conns:
+		return conn, nil
+	case <-s.stopped:
+		return nil, s.err
+	}
+}
+
+func (s *shardedServer) Accept(ctx context.Context) (Connection, error) {
+	return s.accept(ctx)
+}
+
+func (s *shardedServer) Serve(handler func(Connection)) error {
+	for {
+		conn, err := s.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go handler(conn)
+	}
+}
+
+// Import resumes a connection that was handed off by another process.
+// All source connection IDs of the connection must be handled by the same socket,
+// i.e. the connection must have been handed off by a listener using the same number of sockets.
+func (s *shardedServer) Import(h *ConnectionHandoff, code StreamErrorCode) (Connection, error) {
+	if len(h.SourceConnectionIDs) == 0 {
+		return nil, errors.New("quic: handoff without connection IDs")
+	}
+	shard := -1
+	for _, c := range h.SourceConnectionIDs {
+		connID, err := parseHandoffConnectionID(c.ConnectionID)
+		if err != nil {
+			return nil, err
+		}
+		i := connIDShard(connID, len(s.shards))
+		if shard >= 0 && i != shard {
+			return nil, fmt.Errorf("quic: connection IDs of the handoff are handled by different sockets (%d and %d)", shard, i)
+		}
+		shard = i
+	}
+	return s.shards[shard].Import(h, code)
+}
+
+func (s *shardedServer) CloseGracefully(ctx context.Context) error {
+	errs := make(chan error, len(s.shards))
+	for _, serv := range s.shards {
+		go func(serv *baseServer) { errs <- serv.CloseGracefully(ctx) }(serv)
+	}
+	var err error
+	for range s.shards {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *shardedServer) Close() error {
+	var err error
+	for _, serv := range s.shards {
+		if e := serv.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Addr returns the local address. It is the same for all sockets.
+func (s *shardedServer) Addr() net.Addr {
+	return s.shards[0].Addr()
+}
+
+type earlyShardedServer struct{ *shardedServer }
+
+var _ EarlyListener = &earlyShardedServer{}
+
+func (s *earlyShardedServer) Accept(ctx context.Context) (EarlyConnection, error) {
+	return s.shardedServer.accept(ctx)
+}
+
+func (s *earlyShardedServer) Serve(handler func(EarlyConnection)) error {
+	for {
+		conn, err := s.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go handler(conn)
+	}
+}
@@ -0,0 +1,172 @@
+package quic
+
+import (
+	"bytes"
+	"math/rand"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/bpf"
+)
+
+var _ = Describe("SO_REUSEPORT sockets", func() {
+	It("generates connection IDs that are handled by the socket", func() {
+		for _, numShards := range []int{1, 3, 4, 7, 256} {
+			for shard := 0; shard < numShards; shard++ {
+				g := &shardedConnIDGenerator{
+					ConnectionIDGenerator: &protocol.DefaultConnectionIDGenerator{ConnLen: 8},
+					shard:                 shard,
+					numShards:             numShards,
+				}
+				for i := 0; i < 20; i++ {
+					connID, err := g.GenerateConnectionID()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(connID.Len()).To(Equal(8))
+					Expect(connIDShard(connID, numShards)).To(Equal(shard))
+				}
+			}
+		}
+	})
+
+	It("doesn't change zero-length connection IDs", func() {
+		g := &shardedConnIDGenerator{
+			ConnectionIDGenerator: &protocol.DefaultConnectionIDGenerator{},
+			shard:                 1,
+			numShards:             2,
+		}
+		connID, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID.Len()).To(BeZero())
+	})
+
+	It("passes packets to the map of the socket that handles the connection ID", func() {
+		newMap := func() *packetHandlerMap {
+			return &packetHandlerMap{
+				connIDLen:   4,
+				handlers:    make(map[protocol.ConnectionID]packetHandler),
+				resetTokens: make(map[protocol.StatelessResetToken]packetHandler),
+				logger:      utils.DefaultLogger,
+			}
+		}
+		m1, m2 := newMap(), newMap()
+		m1.setShards([]*packetHandlerMap{m1, m2})
+		m2.setShards([]*packetHandlerMap{m1, m2})
+		connID := protocol.ParseConnectionID([]byte{0x13, 0x37, 0xca, 0xfe}) // handled by the second socket
+		handler := NewMockPacketHandler(mockCtrl)
+		m2.Add(connID, handler)
+
+		buf := &bytes.Buffer{}
+		Expect((&wire.ExtendedHeader{
+			Header:          wire.Header{DestConnectionID: connID},
+			PacketNumber:    42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}).Write(buf, protocol.Version1)).To(Succeed())
+		buf.Write(make([]byte, 50))
+		handler.EXPECT().handlePacket(gomock.Any()).Times(2)
+		m1.handlePacket(&receivedPacket{data: buf.Bytes(), buffer: getPacketBuffer()})
+		m2.handlePacket(&receivedPacket{data: buf.Bytes(), buffer: getPacketBuffer()})
+	})
+
+	Context("steering packets", func() {
+		getPacket := func(hdr *wire.ExtendedHeader) []byte {
+			buf := &bytes.Buffer{}
+			Expect(hdr.Write(buf, protocol.Version1)).To(Succeed())
+			buf.Write(make([]byte, 50))
+			return buf.Bytes()
+		}
+
+		getShortHeaderPacket := func(connID protocol.ConnectionID) []byte {
+			return getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: connID},
+				PacketNumber:    42,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			})
+		}
+
+		getLongHeaderPacket := func(connID protocol.ConnectionID) []byte {
+			return getPacket(&wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					DestConnectionID: connID,
+					SrcConnectionID:  protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+					Version:          protocol.Version1,
+					Length:           52,
+				},
+				PacketNumber:    42,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			})
+		}
+
+		It("returns the socket of a connection ID", func() {
+			connID := protocol.ParseConnectionID([]byte{0x13, 0x37, 0xca, 0xfe})
+			Expect(ReusePortSocket(connID, 0)).To(BeZero())
+			Expect(ReusePortSocket(connID, 1)).To(BeZero())
+			Expect(ReusePortSocket(connID, 4)).To(Equal(3))
+			Expect(ReusePortSocket(protocol.ConnectionID{}, 4)).To(BeZero())
+		})
+
+		It("returns the steering key of packets", func() {
+			connID := protocol.ParseConnectionID([]byte{0x13, 0x37, 0xca, 0xfe})
+			key, err := ReusePortSteeringKey(getShortHeaderPacket(connID))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(BeEquivalentTo(0x13))
+			key, err = ReusePortSteeringKey(getLongHeaderPacket(connID))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(BeEquivalentTo(0x13))
+			key, err = ReusePortSteeringKey(getLongHeaderPacket(protocol.ConnectionID{}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(key).To(BeZero())
+		})
+
+		It("errors when getting the steering key of packets that are too short", func() {
+			_, err := ReusePortSteeringKey([]byte{0x40})
+			Expect(err).To(MatchError("quic: packet too short"))
+			_, err = ReusePortSteeringKey([]byte{0xc0, 0, 0, 0, 1})
+			Expect(err).To(MatchError("quic: packet too short"))
+			_, err = ReusePortSteeringKey([]byte{0xc0, 0, 0, 0, 1, 8})
+			Expect(err).To(MatchError("quic: packet too short"))
+		})
+
+		It("generates a program that steers packets to the socket that handles the connection ID", func() {
+			for _, numSockets := range []int{1, 3, 4, 7, 256} {
+				raw, err := ReusePortSteeringProgram(numSockets)
+				Expect(err).ToNot(HaveOccurred())
+				prog, ok := bpf.Disassemble(raw)
+				Expect(ok).To(BeTrue())
+				vm, err := bpf.NewVM(prog)
+				Expect(err).ToNot(HaveOccurred())
+				for i := 0; i < 20; i++ {
+					b := make([]byte, 8)
+					rand.Read(b)
+					connID := protocol.ParseConnectionID(b)
+					for _, packet := range [][]byte{getShortHeaderPacket(connID), getLongHeaderPacket(connID)} {
+						socket, err := vm.Run(packet)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(socket).To(Equal(ReusePortSocket(connID, numSockets)))
+						key, err := ReusePortSteeringKey(packet)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(int(key) % numSockets).To(Equal(socket))
+					}
+				}
+				socket, err := vm.Run(getLongHeaderPacket(protocol.ConnectionID{}))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(socket).To(BeZero())
+			}
+		})
+
+		It("refuses to generate a program for an invalid number of sockets", func() {
+			_, err := ReusePortSteeringProgram(0)
+			Expect(err).To(MatchError("quic: invalid number of sockets: 0"))
+			_, err = ReusePortSteeringProgram(257)
+			Expect(err).To(MatchError("quic: invalid number of sockets: 257"))
+		})
+	})
+})
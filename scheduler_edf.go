@@ -0,0 +1,71 @@
+package quic
+
+import "time"
+
+// edfMaxDeadlineFrames is the number of STREAM frames that streams with a deadline send in a row,
+// before a waiting stream without a deadline sends a STREAM frame.
+const edfMaxDeadlineFrames = 3
+
+// SchedulerEDF creates an Earliest-Deadline-First scheduler, to be used as Config.Scheduler.
+// Streams with data written with the PTDADeadline policy send data in the order in which that data expires.
+// Streams without such data, e.g. reliable streams, are served round-robin.
+// To prevent them from starving, they send at least every fourth STREAM frame while they have data to send.
+func SchedulerEDF() Scheduler {
+	return &edfScheduler{deadlines: make(map[StreamID]time.Time)}
+}
+
+type edfScheduler struct {
+	deadlines map[StreamID]time.Time
+	// the streams that have data to send, in the order they were added
+	streams []StreamID
+	// the number of STREAM frames sent in a row by streams with a deadline, while streams without a deadline were waiting
+	numDeadlineFrames int
+}
+
+var _ Scheduler = &edfScheduler{}
+
+func (s *edfScheduler) AddStream(id StreamID) {
+	s.streams = append(s.streams, id)
+}
+
+func (s *edfScheduler) NextStream(SchedulerContext) (StreamID, bool) {
+	if len(s.streams) == 0 {
+		return 0, false
+	}
+	earliest, firstWithoutDeadline := -1, -1
+	for i, id := range s.streams {
+		deadline, ok := s.deadlines[id]
+		if !ok {
+			if firstWithoutDeadline == -1 {
+				firstWithoutDeadline = i
+			}
+			continue
+		}
+		if earliest == -1 || deadline.Before(s.deadlines[s.streams[earliest]]) {
+			earliest = i
+		}
+	}
+	i := earliest
+	switch {
+	case firstWithoutDeadline == -1:
+		s.numDeadlineFrames = 0
+	case earliest == -1 || s.numDeadlineFrames >= edfMaxDeadlineFrames:
+		i = firstWithoutDeadline
+		s.numDeadlineFrames = 0
+	default:
+		s.numDeadlineFrames++
+	}
+	id := s.streams[i]
+	s.streams = append(s.streams[:i], s.streams[i+1:]...)
+	return id, true
+}
+
+func (s *edfScheduler) OnStreamBlocked(StreamID) {}
+
+func (s *edfScheduler) OnDeadlineApproaching(id StreamID, deadline time.Time) {
+	if deadline.IsZero() {
+		delete(s.deadlines, id)
+		return
+	}
+	s.deadlines[id] = deadline
+}
@@ -0,0 +1,95 @@
+package quic
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EDF Scheduler", func() {
+	var scheduler Scheduler
+
+	BeforeEach(func() {
+		scheduler = SchedulerEDF()
+	})
+
+	next := func() StreamID {
+		id, ok := scheduler.NextStream(SchedulerContext{Now: time.Now()})
+		ExpectWithOffset(1, ok).To(BeTrue())
+		return id
+	}
+
+	It("doesn't return a stream if no stream has data", func() {
+		_, ok := scheduler.NextStream(SchedulerContext{Now: time.Now()})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("serves streams without deadlines round-robin", func() {
+		scheduler.AddStream(4)
+		scheduler.AddStream(8)
+		Expect(next()).To(Equal(StreamID(4)))
+		scheduler.AddStream(4)
+		Expect(next()).To(Equal(StreamID(8)))
+		Expect(next()).To(Equal(StreamID(4)))
+		_, ok := scheduler.NextStream(SchedulerContext{Now: time.Now()})
+		Expect(ok).To(BeFalse())
+	})
+
+	It("serves the stream with the earliest deadline first", func() {
+		now := time.Now()
+		scheduler.OnDeadlineApproaching(4, now.Add(300*time.Millisecond))
+		scheduler.AddStream(4)
+		scheduler.OnDeadlineApproaching(8, now.Add(100*time.Millisecond))
+		scheduler.AddStream(8)
+		scheduler.OnDeadlineApproaching(12, now.Add(200*time.Millisecond))
+		scheduler.AddStream(12)
+		Expect(next()).To(Equal(StreamID(8)))
+		Expect(next()).To(Equal(StreamID(12)))
+		Expect(next()).To(Equal(StreamID(4)))
+	})
+
+	It("serves streams with a deadline before streams without a deadline", func() {
+		scheduler.AddStream(4)
+		scheduler.OnDeadlineApproaching(8, time.Now().Add(time.Second))
+		scheduler.AddStream(8)
+		Expect(next()).To(Equal(StreamID(8)))
+		Expect(next()).To(Equal(StreamID(4)))
+	})
+
+	It("forgets deadlines", func() {
+		now := time.Now()
+		scheduler.OnDeadlineApproaching(4, now.Add(100*time.Millisecond))
+		scheduler.AddStream(4)
+		scheduler.OnDeadlineApproaching(8, now.Add(200*time.Millisecond))
+		scheduler.AddStream(8)
+		scheduler.OnDeadlineApproaching(4, time.Time{})
+		Expect(next()).To(Equal(StreamID(8)))
+		Expect(next()).To(Equal(StreamID(4)))
+	})
+
+	It("doesn't starve streams without a deadline", func() {
+		scheduler.AddStream(4)
+		scheduler.OnDeadlineApproaching(8, time.Now().Add(time.Second))
+		scheduler.AddStream(8)
+		for i := 0; i < edfMaxDeadlineFrames; i++ {
+			Expect(next()).To(Equal(StreamID(8)))
+			scheduler.AddStream(8)
+		}
+		Expect(next()).To(Equal(StreamID(4)))
+		Expect(next()).To(Equal(StreamID(8)))
+	})
+
+	It("only counts frames sent while streams without a deadline are waiting", func() {
+		scheduler.OnDeadlineApproaching(8, time.Now().Add(time.Second))
+		scheduler.AddStream(8)
+		for i := 0; i < 2*edfMaxDeadlineFrames; i++ {
+			Expect(next()).To(Equal(StreamID(8)))
+			scheduler.AddStream(8)
+		}
+		scheduler.AddStream(4)
+		Expect(next()).To(Equal(StreamID(8)))
+		scheduler.AddStream(8)
+		Expect(next()).To(Equal(StreamID(8)))
+	})
+})
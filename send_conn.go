@@ -2,44 +2,101 @@ package quic
 
 import (
 	"net"
+	"sync"
 )
 
+const (
+	maxDSCP          = 0x3f
+	maxIPv6FlowLabel = 0xfffff
+)
+
+// PacketMarking is the marking of the IP header of outgoing packets, see Config.PacketMarking.
+// Fields with the zero value are not set, i.e. the defaults of the socket are used.
+type PacketMarking struct {
+	// DSCP is the Differentiated Services Code Point (RFC 2474), e.g. 46 (EF) or 34 (AF41) for interactive video.
+	// It is set in the TOS field of IPv4 packets, and the Traffic Class of IPv6 packets.
+	// Values larger than 63 are invalid.
+	DSCP uint8
+	// IPv6FlowLabel is the flow label (RFC 6437) of IPv6 packets.
+	// Values larger than 0xfffff are invalid.
+	IPv6FlowLabel uint32
+}
+
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
 type sendConn interface {
 	Write([]byte) error
+	// WritePR writes a packet that carries partially reliable data, see Config.PRPacketMarking.
+	WritePR([]byte) error
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
+	// SetRemoteAddr changes the address packets are sent to, when the peer migrated to a new address.
+	// It is called from the connection's run loop, concurrently with Write and WritePR.
+	SetRemoteAddr(net.Addr)
 }
 
 type sconn struct {
 	rawConn
 
+	info               *packetInfo
+	marking, prMarking PacketMarking
+
+	mutex      sync.RWMutex
 	remoteAddr net.Addr
-	info       *packetInfo
 	oob        []byte
+	prOOB      []byte
 }
 
 var _ sendConn = &sconn{}
 
-func newSendConn(c rawConn, remote net.Addr, info *packetInfo) sendConn {
-	return &sconn{
-		rawConn:    c,
-		remoteAddr: remote,
-		info:       info,
-		oob:        info.OOB(),
+func newSendConn(c rawConn, remote net.Addr, info *packetInfo, marking, prMarking PacketMarking) sendConn {
+	conn := &sconn{
+		rawConn:   c,
+		info:      info,
+		marking:   marking,
+		prMarking: prMarking,
 	}
+	conn.SetRemoteAddr(remote)
+	return conn
 }
 
 func (c *sconn) Write(p []byte) error {
-	_, err := c.WritePacket(p, c.remoteAddr, c.oob)
+	c.mutex.RLock()
+	remote, oob := c.remoteAddr, c.oob
+	c.mutex.RUnlock()
+	_, err := c.WritePacket(p, remote, oob)
+	return err
+}
+
+func (c *sconn) WritePR(p []byte) error {
+	c.mutex.RLock()
+	remote, oob := c.remoteAddr, c.prOOB
+	c.mutex.RUnlock()
+	_, err := c.WritePacket(p, remote, oob)
 	return err
 }
 
 func (c *sconn) RemoteAddr() net.Addr {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	return c.remoteAddr
 }
 
+// SetRemoteAddr sets the remote address.
+// The marking depends on the address family, so the OOB data is regenerated.
+func (c *sconn) SetRemoteAddr(remote net.Addr) {
+	oob := appendMarking(c.info.OOB(), remote, c.marking)
+	prOOB := oob
+	if c.prMarking != (PacketMarking{}) {
+		prOOB = appendMarking(c.info.OOB(), remote, c.prMarking)
+	}
+	c.mutex.Lock()
+	c.remoteAddr = remote
+	c.oob = oob
+	c.prOOB = prOOB
+	c.mutex.Unlock()
+}
+
 func (c *sconn) LocalAddr() net.Addr {
 	addr := c.rawConn.LocalAddr()
 	if c.info != nil {
@@ -55,20 +112,71 @@ func (c *sconn) LocalAddr() net.Addr {
 type spconn struct {
 	net.PacketConn
 
+	marking, prMarking PacketMarking
+
+	mutex      sync.RWMutex
 	remoteAddr net.Addr
+	// Only set if packets are marked, and the net.PacketConn supports writing OOB data.
+	oobConn    OOBCapablePacketConn
+	oob, prOOB []byte
 }
 
 var _ sendConn = &spconn{}
 
-func newSendPconn(c net.PacketConn, remote net.Addr) sendConn {
-	return &spconn{PacketConn: c, remoteAddr: remote}
+func newSendPconn(c net.PacketConn, remote net.Addr, marking, prMarking PacketMarking) sendConn {
+	conn := &spconn{PacketConn: c, marking: marking, prMarking: prMarking}
+	conn.SetRemoteAddr(remote)
+	return conn
 }
 
 func (c *spconn) Write(p []byte) error {
-	_, err := c.WriteTo(p, c.remoteAddr)
+	c.mutex.RLock()
+	remote, oobConn, oob := c.remoteAddr, c.oobConn, c.oob
+	c.mutex.RUnlock()
+	return c.write(p, remote, oobConn, oob)
+}
+
+func (c *spconn) WritePR(p []byte) error {
+	c.mutex.RLock()
+	remote, oobConn, oob := c.remoteAddr, c.oobConn, c.prOOB
+	c.mutex.RUnlock()
+	return c.write(p, remote, oobConn, oob)
+}
+
+func (c *spconn) write(p []byte, remote net.Addr, oobConn OOBCapablePacketConn, oob []byte) error {
+	if oobConn != nil {
+		_, _, err := oobConn.WriteMsgUDP(p, oob, remote.(*net.UDPAddr))
+		return err
+	}
+	_, err := c.WriteTo(p, remote)
 	return err
 }
 
 func (c *spconn) RemoteAddr() net.Addr {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	return c.remoteAddr
 }
+
+func (c *spconn) SetRemoteAddr(remote net.Addr) {
+	var oobConn OOBCapablePacketConn
+	var oob, prOOB []byte
+	if _, ok := remote.(*net.UDPAddr); ok && (c.marking != (PacketMarking{}) || c.prMarking != (PacketMarking{})) {
+		if conn, ok := c.PacketConn.(OOBCapablePacketConn); ok {
+			oob = appendMarking(nil, remote, c.marking)
+			prOOB = oob
+			if c.prMarking != (PacketMarking{}) {
+				prOOB = appendMarking(nil, remote, c.prMarking)
+			}
+			if len(oob) > 0 || len(prOOB) > 0 {
+				oobConn = conn
+			}
+		}
+	}
+	c.mutex.Lock()
+	c.remoteAddr = remote
+	c.oobConn = oobConn
+	c.oob = oob
+	c.prOOB = prOOB
+	c.mutex.Unlock()
+}
@@ -17,7 +17,7 @@ var _ = Describe("Connection (for sending packets)", func() {
 	BeforeEach(func() {
 		addr = &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
 		packetConn = NewMockPacketConn(mockCtrl)
-		c = newSendPconn(packetConn, addr)
+		c = newSendPconn(packetConn, addr, PacketMarking{}, PacketMarking{})
 	})
 
 	It("writes", func() {
@@ -25,10 +25,31 @@ var _ = Describe("Connection (for sending packets)", func() {
 		Expect(c.Write([]byte("foobar"))).To(Succeed())
 	})
 
+	It("writes packets that carry partially reliable data", func() {
+		packetConn.EXPECT().WriteTo([]byte("foobar"), addr)
+		Expect(c.WritePR([]byte("foobar"))).To(Succeed())
+	})
+
+	It("doesn't mark packets if the net.PacketConn doesn't support writing OOB data", func() {
+		c = newSendPconn(packetConn, addr, PacketMarking{DSCP: 46}, PacketMarking{DSCP: 34})
+		packetConn.EXPECT().WriteTo([]byte("foo"), addr)
+		packetConn.EXPECT().WriteTo([]byte("bar"), addr)
+		Expect(c.Write([]byte("foo"))).To(Succeed())
+		Expect(c.WritePR([]byte("bar"))).To(Succeed())
+	})
+
 	It("gets the remote address", func() {
 		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
 	})
 
+	It("changes the remote address", func() {
+		newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 4242}
+		c.SetRemoteAddr(newAddr)
+		Expect(c.RemoteAddr()).To(Equal(newAddr))
+		packetConn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+		Expect(c.Write([]byte("foobar"))).To(Succeed())
+	})
+
 	It("gets the local address", func() {
 		addr := &net.UDPAddr{
 			IP:   net.IPv4(192, 168, 0, 1),
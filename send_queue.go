@@ -1,5 +1,7 @@
 package quic
 
+import "github.com/lucas-clemente/quic-go/internal/handshake"
+
 type sender interface {
 	Send(p *packetBuffer)
 	Run() error
@@ -53,6 +55,10 @@ func (h *sendQueue) Available() <-chan struct{} {
 func (h *sendQueue) Run() error {
 	defer close(h.runStopped)
 	var shouldClose bool
+	var (
+		batch []*packetBuffer
+		hps   []handshake.HeaderProtection
+	)
 	for {
 		if shouldClose && len(h.queue) == 0 {
 			return nil
@@ -63,24 +69,83 @@ func (h *sendQueue) Run() error {
 			// make sure that all queued packets are actually sent out
 			shouldClose = true
 		case p := <-h.queue:
-			if err := h.conn.Write(p.Data); err != nil {
-				// This additional check enables:
-				// 1. Checking for "datagram too large" message from the kernel, as such,
-				// 2. Path MTU discovery,and
-				// 3. Eventual detection of loss PingFrame.
-				if !isMsgSizeErr(err) {
+			if p.headerSealer == nil {
+				if err := h.write(p); err != nil {
 					return err
 				}
+				continue
+			}
+			// Take all packets that are queued already, such that their header protection is applied at once.
+			batch = append(batch[:0], p)
+		loop:
+			for len(batch) < sendQueueCapacity {
+				select {
+				case p := <-h.queue:
+					batch = append(batch, p)
+				default:
+					break loop
+				}
 			}
-			p.Release()
-			select {
-			case h.available <- struct{}{}:
-			default:
+			hps = applyHeaderProtection(batch, hps)
+			for i, p := range batch {
+				batch[i] = nil
+				if err := h.write(p); err != nil {
+					return err
+				}
 			}
 		}
 	}
 }
 
+func (h *sendQueue) write(p *packetBuffer) error {
+	var err error
+	if p.partiallyReliable {
+		err = h.conn.WritePR(p.Data)
+	} else {
+		err = h.conn.Write(p.Data)
+	}
+	if err != nil {
+		// This additional check enables:
+		// 1. Checking for "datagram too large" message from the kernel, as such,
+		// 2. Path MTU discovery,and
+		// 3. Eventual detection of loss PingFrame.
+		if !isMsgSizeErr(err) {
+			return err
+		}
+	}
+	p.Release()
+	select {
+	case h.available <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// applyHeaderProtection applies the header protection of the packets that still need it.
+// Packets protected by the same sealer are passed to the sealer at once.
+// hps is only used as a buffer, and returned for reuse.
+func applyHeaderProtection(packets []*packetBuffer, hps []handshake.HeaderProtection) []handshake.HeaderProtection {
+	var sealer handshake.ShortHeaderSealer
+	hps = hps[:0]
+	for _, p := range packets {
+		if p.headerSealer == nil {
+			continue
+		}
+		if sealer != nil && p.headerSealer != sealer {
+			sealer.EncryptHeaders(hps)
+			hps = hps[:0]
+		}
+		sealer = p.headerSealer
+		hps = append(hps, p.headerProtection)
+		p.headerSealer = nil
+		p.headerProtection = handshake.HeaderProtection{}
+	}
+	if len(hps) > 0 {
+		sealer.EncryptHeaders(hps)
+	}
+	return hps[:0]
+}
+
 func (h *sendQueue) Close() {
 	close(h.closeCalled)
 	// wait until the run loop returned
@@ -4,6 +4,8 @@ import (
 	"errors"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -42,6 +44,54 @@ var _ = Describe("Send Queue", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("sends packets that carry partially reliable data", func() {
+		p := getPacket([]byte("foobar"))
+		p.partiallyReliable = true
+		q.Send(p)
+
+		written := make(chan struct{})
+		c.EXPECT().WritePR([]byte("foobar")).Do(func([]byte) { close(written) })
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+
+		Eventually(written).Should(BeClosed())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("applies the header protection of the queued packets at once", func() {
+		sealer := mocks.NewMockShortHeaderSealer(mockCtrl)
+		for _, b := range []string{"foo", "bar", "baz"} {
+			p := getPacket([]byte(b))
+			p.headerSealer = sealer
+			p.headerProtection = handshake.HeaderProtection{FirstByte: &p.Data[0], PNBytes: p.Data[1:]}
+			q.Send(p)
+		}
+		sealer.EXPECT().EncryptHeaders(gomock.Any()).Do(func(hps []handshake.HeaderProtection) {
+			Expect(hps).To(HaveLen(3))
+			for _, hp := range hps {
+				*hp.FirstByte -= 'a' - 'A'
+			}
+		})
+		gomock.InOrder(
+			c.EXPECT().Write([]byte("Foo")),
+			c.EXPECT().Write([]byte("Bar")),
+			c.EXPECT().Write([]byte("Baz")),
+		)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
 	It("panics when Send() is called although there's no space in the queue", func() {
 		for i := 0; i < sendQueueCapacity; i++ {
 			Expect(q.WouldBlock()).To(BeFalse())
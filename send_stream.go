@@ -4,14 +4,18 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"net"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/utils/ringbuffer"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -20,15 +24,20 @@ type sendStreamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	hasData() bool
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
+	rateLimiter() *tokenBucket
+	nextDeadline() time.Time
 	closeForShutdown(error)
 	updateSendWindow(protocol.ByteCount)
+	handoffState(*HandoffStream)
 }
 
 type sendStream struct {
 	mutex sync.Mutex
 
 	numOutstandingFrames int64
-	retransmissionQueue  []*wire.StreamFrame
+	retransmissionQueue  ringbuffer.RingBuffer[*wire.StreamFrame]
+	// data below this offset is not retransmitted, see ExpireDataBefore
+	expiredOffset protocol.ByteCount
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -50,15 +59,101 @@ type sendStream struct {
 	dataForWriting []byte // during a Write() call, this slice is the part of p that still needs to be sent out
 	nextFrame      *wire.StreamFrame
 
+	// the offset of the last message boundary, see EndMessage
+	lastMessageBoundary protocol.ByteCount
+
+	// the PR policies of the data written so far, ordered by offset.
+	// A STREAM frame never contains data of more than one policy.
+	// The ranges of data that was acknowledged or skipped are dropped, see addDoneRange.
+	policyRanges []prPolicyRange
+	// the number of policyRanges with a partially reliable policy, see usesPR
+	numPRPolicyRanges int
+	// set once data was written with a partially reliable policy
+	wrotePR bool
+	// the stream policy used by Write. If nil, the policy is resolved by the policyChain.
+	writePolicy *PRPolicy
+	policyChain *prPolicyChain
+	// the PRAckNotify frames for skipped data are queued here, until the packet packer picks them up.
+	// Shared by all streams of the connection, see setPRPolicyChain.
+	prAckNotifies *prAckNotifyQueue
+	// set by DisablePR: all data is sent reliably, regardless of the policies
+	prDisabled bool
+	// set when the stream is counted towards the PR stream limits, see PRConfig.MaxPRStreams
+	countedAsPRStream bool
+	// the expiry of the data written with the deadline policy that wasn't acknowledged or skipped yet, ordered by offset,
+	// see nextDeadline and deadlineAt
+	deadlines []dataDeadline
+
+	// the ranges of acknowledged data, sorted and merged, see AckedRanges
+	ackedRanges []byteInterval
+	// the ranges of data that was acknowledged or skipped, sorted and merged, and their total length
+	doneRanges []byteInterval
+	doneBytes  protocol.ByteCount
+	// the number of bytes skipped, limited by the peer's max_skip_ratio, see PRConfig.MaxSkipRatio
+	skippedBytes protocol.ByteCount
+	// how often the PR data that is still in flight was retransmitted, sorted by offset.
+	// It is tracked by range instead of by frame, such that all parts of a split retransmission share the count.
+	retransmissions []retransmittedRange
+
+	// backpressure signals, see SetWriteBufferWatermarks
+	lowWatermark, highWatermark protocol.ByteCount
+	watermarkCallback           func(pause bool)
+	aboveHighWatermark          bool
+	// held while calling the watermarkCallback, such that the callbacks are called in order
+	watermarkMutex sync.Mutex
+
+	// decides if lost data sent with the probability policy is retransmitted, see PRConfig.RandSeed.
+	// Set when it's first needed.
+	rand *rand.Rand
+
+	// for canceling the stream when the producer stalls, see PRConfig.IdleStreamTimeout
+	idleTimeout   time.Duration
+	idleErrorCode StreamErrorCode
+	idleTimer     *wheelTimer
+	lastWrite     time.Time
+
+	// clock is used for the deadlines of the deadline policy, and for the idle timeout.
+	// Tests replaying a trace replace it, see replayTrace.
+	clock congestion.Clock
+
+	// The callbacks of the frames returned by popStreamFrame.
+	// Creating a method value allocates, so they're only created once, when the first frame is popped.
+	onFrameLost, onFrameAcked, onPRFrameAcked func(wire.Frame)
+	// allocates the metadata of the PRSTREAM frames. Only used by popStreamFrame, so it's not protected by the mutex.
+	prFrameMetas prFrameMetaSlab
+
+	// paces the stream, see SetRateLimit. nil if the stream is not rate-limited.
+	limiter *tokenBucket
+
 	writeChan chan struct{}
 	writeOnce chan struct{}
 	deadline  time.Time
 
 	flowController flowcontrol.StreamFlowController
 
+	logger utils.Logger
+
 	version protocol.VersionNumber
 }
 
+// A prPolicyRange marks the offset at which data written with a certain policy starts.
+type prPolicyRange struct {
+	offset protocol.ByteCount
+	policy PRPolicy
+}
+
+// A retransmittedRange is a range of PR data that was retransmitted count times.
+type retransmittedRange struct {
+	start, end protocol.ByteCount
+	count      uint64
+}
+
+// A dataDeadline is the time the data before offset expires.
+type dataDeadline struct {
+	offset   protocol.ByteCount
+	deadline time.Time
+}
+
 var (
 	_ SendStream  = &sendStream{}
 	_ sendStreamI = &sendStream{}
@@ -68,6 +163,7 @@ func newSendStream(
 	streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *sendStream {
 	s := &sendStream{
@@ -76,6 +172,9 @@ func newSendStream(
 		flowController: flowController,
 		writeChan:      make(chan struct{}, 1),
 		writeOnce:      make(chan struct{}, 1), // cap: 1, to protect against concurrent use of Write
+		prAckNotifies:  newPRAckNotifyQueue(),
+		logger:         logger.With("stream_id", streamID),
+		clock:          congestion.DefaultClock{},
 		version:        version,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
@@ -87,6 +186,37 @@ func (s *sendStream) StreamID() protocol.StreamID {
 }
 
 func (s *sendStream) Write(p []byte) (int, error) {
+	policy, _ := s.EffectivePRPolicy()
+	return s.WriteWithPolicy(p, policy)
+}
+
+func (s *sendStream) SetPRPolicy(policy *PRPolicy) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if policy == nil {
+		s.writePolicy = nil
+		return
+	}
+	p := *policy
+	s.writePolicy = &p
+}
+
+func (s *sendStream) EffectivePRPolicy() (PRPolicy, PRPolicySource) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.prDisabled {
+		return PRPolicy{}, PRPolicySourceStream
+	}
+	if s.writePolicy != nil {
+		return *s.writePolicy, PRPolicySourceStream
+	}
+	return s.policyChain.Get()
+}
+
+func (s *sendStream) WriteWithPolicy(p []byte, policy PRPolicy) (int, error) {
+	if err := policy.validate(); err != nil {
+		return 0, err
+	}
 
 	// Concurrent use of Write is not permitted (and doesn't make any sense),
 	// but sometimes people do it anyway.
@@ -94,26 +224,61 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	s.writeOnce <- struct{}{}
 	defer func() { <-s.writeOnce }()
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	return s.writeImpl(p, policy)
+}
 
-	if s.finishedWriting {
-		return 0, fmt.Errorf("write on closed stream %d", s.streamID)
-	}
-	if s.canceledWrite {
-		return 0, s.cancelWriteErr
+func (s *sendStream) WriteBuffers(bufs net.Buffers) (int64, error) {
+	policy, _ := s.EffectivePRPolicy()
+	if err := policy.validate(); err != nil {
+		return 0, err
 	}
-	if s.closeForShutdownErr != nil {
-		return 0, s.closeForShutdownErr
+
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	// The slices are written one after the other, without concatenating them.
+	// A slice is only bundled with the data of the previous slices if they fit into a packet together,
+	// so STREAM frames usually start at the beginning of a slice.
+	var written int64
+	for _, b := range bufs {
+		n, err := s.writeImpl(b, policy)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
 	}
-	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
-		return 0, errDeadline
+	return written, nil
+}
+
+// writeImpl writes p to the stream.
+// It must only be called by one go routine at a time, see writeOnce.
+func (s *sendStream) writeImpl(p []byte, policy PRPolicy) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.writeError(); err != nil {
+		return 0, err
 	}
 	if len(p) == 0 {
 		return 0, nil
 	}
+	policy, err := s.sendPolicy(policy)
+	if err != nil {
+		return 0, err
+	}
 
+	var bufferedLen protocol.ByteCount
+	if s.nextFrame != nil {
+		bufferedLen = s.nextFrame.DataLen()
+	}
+	policyOffset := s.writeOffset + bufferedLen
+	policyChanged := s.setWritePolicy(policyOffset, protocol.ByteCount(len(p)), policy)
 	s.dataForWriting = p
+	if signal := s.watermarkSignal(); signal != nil {
+		s.mutex.Unlock()
+		signal()
+		s.mutex.Lock()
+	}
 
 	var (
 		deadlineTimer  *utils.Timer
@@ -129,21 +294,9 @@ func (s *sendStream) Write(p []byte) (int, error) {
 		// When the user now calls Close(), this is much more likely to happen before we popped that last STREAM frame,
 		// allowing us to set the FIN bit on that frame (instead of sending an empty STREAM frame with FIN).
 		// FIN bit在Stream Frame的首字节（Type字节）的第二bit位，置为1时表示发送结束
-		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 {
-			// 空的话就直接添加，不空就加载nextFrame.Data中
-			if s.nextFrame == nil {
-				f := wire.GetStreamFrame() //只是生成一个空的StreamFrame
-				f.Offset = s.writeOffset
-				f.StreamID = s.streamID
-				f.DataLenPresent = true
-				f.Data = f.Data[:len(s.dataForWriting)]
-				copy(f.Data, s.dataForWriting)
-				s.nextFrame = f
-			} else {
-				l := len(s.nextFrame.Data)
-				s.nextFrame.Data = s.nextFrame.Data[:l+len(s.dataForWriting)]
-				copy(s.nextFrame.Data[l:], s.dataForWriting)
-			}
+		// Data written with a different policy is never appended to the buffered frame.
+		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 && (s.nextFrame == nil || s.policyAt(s.nextFrame.Offset) == policy) {
+			s.bufferStreamFrameData(s.dataForWriting)
 			s.dataForWriting = nil
 			bytesWritten = len(p)
 			copied = true
@@ -168,6 +321,9 @@ func (s *sendStream) Write(p []byte) (int, error) {
 
 		s.mutex.Unlock()
 		if !notifiedSender {
+			if policyChanged {
+				s.sender.onPRPolicyChanged(s.streamID, policyOffset, policy) // must be called without holding the mutex
+			}
 			s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
 			notifiedSender = true
 		}
@@ -198,6 +354,140 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	return bytesWritten, nil
 }
 
+func (s *sendStream) TryWrite(p []byte) (int, error) {
+	policy, _ := s.EffectivePRPolicy()
+	if err := policy.validate(); err != nil {
+		return 0, err
+	}
+	select {
+	case s.writeOnce <- struct{}{}:
+	default:
+		return 0, ErrWouldBlock
+	}
+	defer func() { <-s.writeOnce }()
+
+	s.mutex.Lock()
+	if err := s.writeError(); err != nil || len(p) == 0 {
+		s.mutex.Unlock()
+		return 0, err
+	}
+	policy, err := s.sendPolicy(policy)
+	if err != nil {
+		s.mutex.Unlock()
+		return 0, err
+	}
+	// Only the data that fits into the buffered STREAM frame can be written without blocking.
+	// Data written with a different policy is never appended to the buffered frame.
+	var bufferedLen protocol.ByteCount
+	if s.nextFrame != nil {
+		if s.policyAt(s.nextFrame.Offset) != policy {
+			s.mutex.Unlock()
+			return 0, ErrWouldBlock
+		}
+		bufferedLen = s.nextFrame.DataLen()
+	}
+	n := utils.Min(len(p), int(protocol.MaxPacketBufferSize-bufferedLen))
+	if n <= 0 {
+		s.mutex.Unlock()
+		return 0, ErrWouldBlock
+	}
+	policyOffset := s.writeOffset + bufferedLen
+	policyChanged := s.setWritePolicy(policyOffset, protocol.ByteCount(n), policy)
+	s.bufferStreamFrameData(p[:n])
+	signal := s.watermarkSignal()
+	s.mutex.Unlock()
+
+	if signal != nil {
+		signal()
+	}
+	if policyChanged {
+		s.sender.onPRPolicyChanged(s.streamID, policyOffset, policy) // must be called without holding the mutex
+	}
+	s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	if n < len(p) {
+		return n, ErrWouldBlock
+	}
+	return n, nil
+}
+
+// writeError returns the error that a write would fail with.
+// It must be called with the mutex held.
+func (s *sendStream) writeError() error {
+	if s.finishedWriting {
+		return fmt.Errorf("write on closed stream %d", s.streamID)
+	}
+	if s.canceledWrite {
+		return s.cancelWriteErr
+	}
+	if s.closeForShutdownErr != nil {
+		return s.closeForShutdownErr
+	}
+	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+		return errDeadline
+	}
+	return nil
+}
+
+// sendPolicy returns the policy that data written with policy is sent with.
+// It must be called with the mutex held.
+func (s *sendStream) sendPolicy(policy PRPolicy) (PRPolicy, error) {
+	if s.prDisabled && !policy.IsReliable() {
+		return policy, ErrPRDisabled
+	}
+	if !policy.IsReliable() && !s.policyChain.Enabled() {
+		policy = PRPolicy{}
+	}
+	if !policy.IsReliable() && !s.countedAsPRStream {
+		if s.policyChain.acquireStream() {
+			s.countedAsPRStream = true
+		} else {
+			if s.policyChain.config.RejectExcessPRStreams {
+				return policy, ErrTooManyPRStreams
+			}
+			if s.logger.Debug() {
+				s.logger.With("pr_policy", policy).Debugf("Too many PR streams. Sending data reliably.")
+			}
+			policy = PRPolicy{}
+		}
+	}
+	return policy, nil
+}
+
+// setWritePolicy sets the policy for length bytes written at offset.
+// It returns if the policy changed.
+func (s *sendStream) setWritePolicy(offset, length protocol.ByteCount, policy PRPolicy) bool {
+	policyChanged := s.setPolicy(offset, policy)
+	s.lastWrite = s.clock.Now()
+	if policy.PTDA == PTDADeadline {
+		s.deadlines = append(s.deadlines, dataDeadline{
+			offset:   offset + length,
+			deadline: s.lastWrite.Add(time.Duration(policy.Value) * time.Millisecond),
+		})
+	}
+	if !policy.IsReliable() {
+		s.maybeStartIdleTimer()
+	}
+	return policyChanged
+}
+
+// bufferStreamFrameData appends data to the buffered STREAM frame (s.nextFrame), creating it if necessary.
+func (s *sendStream) bufferStreamFrameData(data []byte) {
+	// 空的话就直接添加，不空就加载nextFrame.Data中
+	if s.nextFrame == nil {
+		f := wire.GetStreamFrame() //只是生成一个空的StreamFrame
+		f.Offset = s.writeOffset
+		f.StreamID = s.streamID
+		f.DataLenPresent = true
+		f.Data = f.Data[:len(data)]
+		copy(f.Data, data)
+		s.nextFrame = f
+		return
+	}
+	l := len(s.nextFrame.Data)
+	s.nextFrame.Data = s.nextFrame.Data[:l+len(data)]
+	copy(s.nextFrame.Data[l:], data)
+}
+
 // 检查待写入的帧能否存下要写入的数据，
 // 检查方式为比较帧中已有数据的大小加上要写入数据的大小是否小于QUIC报文允许的最大数据大小，
 // 如果返回True，则代表能装下。
@@ -209,22 +499,150 @@ func (s *sendStream) canBufferStreamFrame() bool {
 	return l+protocol.ByteCount(len(s.dataForWriting)) <= protocol.MaxPacketBufferSize
 }
 
+// setPolicy sets the policy for all data starting at offset.
+// It returns true if the policy differs from the policy of the data before offset.
+// Such a mid-stream change is announced to the peer in a PR_STREAM_POLICY frame.
+// must be called after locking the mutex
+func (s *sendStream) setPolicy(offset protocol.ByteCount, policy PRPolicy) bool {
+	if l := len(s.policyRanges); l > 0 {
+		last := s.policyRanges[l-1]
+		if last.policy == policy {
+			// If no data has been written with this policy yet, the Write that changed it didn't get to announce the change.
+			return offset > 0 && last.offset == offset
+		}
+		// no data has been written with the last policy
+		if last.offset == offset {
+			if !last.policy.IsReliable() {
+				s.numPRPolicyRanges--
+			}
+			s.policyRanges = s.policyRanges[:l-1]
+			return s.setPolicy(offset, policy)
+		}
+	} else if policy.IsReliable() {
+		return false
+	}
+	if !policy.IsReliable() {
+		s.numPRPolicyRanges++
+		s.wrotePR = true
+	}
+	s.policyRanges = append(s.policyRanges, prPolicyRange{offset: offset, policy: policy})
+	return offset > 0
+}
+
+// prunePolicyRanges drops the policy ranges that only contain data below offset,
+// i.e. data that was acknowledged or skipped.
+// must be called after locking the mutex
+func (s *sendStream) prunePolicyRanges(offset protocol.ByteCount) {
+	var n int
+	for n+1 < len(s.policyRanges) && s.policyRanges[n+1].offset <= offset {
+		if !s.policyRanges[n].policy.IsReliable() {
+			s.numPRPolicyRanges--
+		}
+		n++
+	}
+	s.policyRanges = s.policyRanges[n:]
+}
+
+// policyAt returns the policy of the data at offset.
+// must be called after locking the mutex
+func (s *sendStream) policyAt(offset protocol.ByteCount) PRPolicy {
+	i := sort.Search(len(s.policyRanges), func(i int) bool { return s.policyRanges[i].offset > offset })
+	if i == 0 {
+		return PRPolicy{}
+	}
+	return s.policyRanges[i-1].policy
+}
+
+// usesPR says if any data on this stream that wasn't acknowledged or skipped yet was written with a partially reliable policy,
+// or if the data written next uses a partially reliable policy.
+// must be called after locking the mutex
+func (s *sendStream) usesPR() bool {
+	return s.numPRPolicyRanges > 0
+}
+
+func (s *sendStream) SetRateLimit(bytesPerSecond uint64) {
+	s.mutex.Lock()
+	if bytesPerSecond > 0 {
+		if s.limiter != nil {
+			s.limiter.SetRate(bytesPerSecond)
+		} else {
+			s.limiter = newTokenBucket(bytesPerSecond, func() { s.sender.onHasStreamData(s.streamID) })
+		}
+		s.mutex.Unlock()
+		return
+	}
+	if s.limiter == nil {
+		s.mutex.Unlock()
+		return
+	}
+	s.limiter.Stop()
+	s.limiter = nil
+	s.mutex.Unlock()
+
+	// The framer might have stopped sending data on this stream while waiting for the limiter.
+	s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+}
+
+// nextDeadline returns the time the oldest data that wasn't sent yet expires,
+// if it was written with the deadline policy, see Scheduler.OnDeadlineApproaching.
+func (s *sendStream) nextDeadline() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := sort.Search(len(s.deadlines), func(i int) bool { return s.deadlines[i].offset > s.writeOffset })
+	if i == len(s.deadlines) {
+		return time.Time{}
+	}
+	return s.deadlines[i].deadline
+}
+
+// deadlineAt returns the time the data at offset expires, if it was written with the deadline policy.
+// must be called after locking the mutex
+func (s *sendStream) deadlineAt(offset protocol.ByteCount) (time.Time, bool) {
+	i := sort.Search(len(s.deadlines), func(i int) bool { return s.deadlines[i].offset > offset })
+	if i == len(s.deadlines) {
+		return time.Time{}, false
+	}
+	return s.deadlines[i].deadline, true
+}
+
+func (s *sendStream) rateLimiter() *tokenBucket {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.limiter
+}
+
 // popStreamFrame returns the next STREAM frame that is supposed to be sent on this stream
 // maxBytes is the maximum length this frame (including frame header) will have.
 // 如果队列中有PRAckNotify帧的话，先取出来
 func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool /* has more data to send */) {
 	s.mutex.Lock()
 
+	// Data written before partial reliability was disabled by the peer's transport parameters is sent reliably.
+	prEnabled := s.policyChain.Enabled()
 	pr_maxBytes := maxBytes
-
-	if PR_ENABLED {
-		pr_maxBytes = maxBytes - (1 + 8)
+	// reserve space for the flags byte, the PTDA byte and PtdaC
+	if prEnabled && s.usesPR() {
+		pr_maxBytes = maxBytes - wire.MaxPRStreamFrameOverhead
 	}
 
+	if s.onFrameLost == nil {
+		s.onFrameLost = s.queueRetransmission
+		s.onFrameAcked = s.frameAcked
+		s.onPRFrameAcked = s.prStreamframeAcked
+	}
 	f, hasMoreData := s.popNewOrRetransmittedStreamFrame(pr_maxBytes)
 
+	var policy PRPolicy
+	var notified bool
 	if f != nil {
 		s.numOutstandingFrames++
+		if prEnabled {
+			policy = s.policyAt(f.Offset)
+			if policy.PTDA == PTDAAbandon {
+				notified = s.notifyAbandonedLocked(f, policy)
+			}
+		}
 	}
 	s.mutex.Unlock()
 
@@ -232,36 +650,53 @@ func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Fr
 		return nil, hasMoreData
 	}
 
-	// 假如采用PR策略：
-	if PR_ENABLED {
-		// 将Stream帧转为PRStream帧
-		prf := &wire.PRStreamFrame{
-			StreamID:       f.StreamID,
-			Offset:         f.Offset,
-			Data:           f.Data,
-			Fin:            f.Fin,
-			DataLenPresent: f.DataLenPresent,
-			PTDA:           PTDA,
-			PtdaC:          PtadC,
-			// fromPool: f.fromPool,  // 首字母小写的结构变量不能在外面用
-		}
-		switch PTDA {
-		case 0x80:
-			prf.P = true
-		case 0x40:
-			prf.T = true
-		case 0x20:
-			prf.D = true
-		case 0x10:
-			prf.A = true
-		default:
-			fmt.Println("PR Policy wrong!")
-		}
-		// 改变返回的帧，以及OnLost()与OnAcked()方法
-		return &ackhandler.Frame{Frame: prf, OnLost: s.prQueueRetransmission, OnAcked: s.prStreamframeAcked}, hasMoreData
+	frame := ackhandler.GetFrame()
+	if policy.IsReliable() {
+		frame.Frame = f
+		frame.OnLost = s.onFrameLost
+		frame.OnAcked = s.onFrameAcked
+		return frame, hasMoreData
+	}
+	// 将Stream帧转为PRStream帧，并改变OnLost()与OnAcked()方法
+	prf := f.ToPRStreamFrame()
+	prf.PTDA = policy.PTDA
+	prf.PtdaC = policy.Value
+	switch policy.PTDA {
+	case PTDAProbability:
+		prf.P = true
+	case PTDATimes:
+		prf.T = true
+	case PTDADeadline:
+		prf.D = true
+	case PTDAPriority:
+		prf.A = true
+	}
+	meta := s.prFrameMetas.get()
+	meta.stream = s
+	meta.offset, meta.length = prf.Offset, prf.DataLen()
+	meta.notified = notified
+	frame.Frame = prf
+	frame.OnLost = meta.onLost
+	frame.OnAcked = s.onPRFrameAcked
+	if notified {
+		frame.OnAcked = meta.onAcked
 	}
+	frame.OnAckedAfterLoss = meta.onAckedAfterLoss
+	return frame, hasMoreData
+}
 
-	return &ackhandler.Frame{Frame: f, OnLost: s.queueRetransmission, OnAcked: s.frameAcked}, hasMoreData
+// notifyAbandonedLocked queues the PRAckNotify frame for data sent in immediate-abandon mode, see PTDAAbandon.
+// The frame is queued when the data is sent, so the peer doesn't have to wait for loss detection to skip it.
+// The data counts as skipped until it is acknowledged. If that would exceed the peer's maximum skip ratio,
+// no PRAckNotify frame is queued, and it returns false.
+// must be called after locking the mutex
+func (s *sendStream) notifyAbandonedLocked(f *wire.StreamFrame, policy PRPolicy) bool {
+	if !s.policyChain.SkipAllowed(s.skippedBytes+f.DataLen(), s.writeOffset) {
+		return false
+	}
+	s.skippedBytes += f.DataLen()
+	s.prAckNotifies.Add(newPRAckNotifyFrame(s.streamID, f.Offset, f.DataLen(), f.Fin, policy))
+	return true
 }
 
 func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more data to send */) {
@@ -269,7 +704,7 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		return nil, false
 	}
 
-	if len(s.retransmissionQueue) > 0 {
+	if !s.retransmissionQueue.Empty() {
 		f, hasMoreRetransmissions := s.maybeGetRetransmission(maxBytes)
 		if f != nil || hasMoreRetransmissions {
 			if f == nil {
@@ -363,14 +798,18 @@ func (s *sendStream) popNewStreamFrameWithoutBuffer(f *wire.StreamFrame, maxByte
 	return s.dataForWriting != nil || s.nextFrame != nil || s.finishedWriting
 }
 
+// maybeGetRetransmission returns the next retransmission, split such that it fits into maxBytes.
+// Retransmissions are queued as STREAM frames. popStreamFrame sends them with the policy of their data,
+// so every part of a split is a PRSTREAM frame with the policy of the lost frame,
+// and the parts share its retransmission count, see retransmissionsAt.
 func (s *sendStream) maybeGetRetransmission(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more retransmissions */) {
-	f := s.retransmissionQueue[0]
+	f := s.retransmissionQueue.PeekFront()
 	newFrame, needsSplit := f.MaybeSplitOffFrame(maxBytes, s.version)
 	if needsSplit {
 		return newFrame, true
 	}
-	s.retransmissionQueue = s.retransmissionQueue[1:]
-	return f, len(s.retransmissionQueue) > 0
+	s.retransmissionQueue.PopFront()
+	return f, !s.retransmissionQueue.Empty()
 }
 
 func (s *sendStream) hasData() bool {
@@ -397,19 +836,23 @@ func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.By
 }
 
 func (s *sendStream) frameAcked(f wire.Frame) {
-	f.(*wire.StreamFrame).PutBack()
+	sf := f.(*wire.StreamFrame)
+	offset, length := sf.Offset, sf.DataLen()
+	sf.PutBack()
 
 	s.mutex.Lock()
 	if s.canceledWrite {
 		s.mutex.Unlock()
 		return
 	}
+	s.addAckedRange(offset, offset+length)
+	s.addDoneRange(offset, offset+length)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
 	newlyCompleted := s.isNewlyCompleted()
-	s.mutex.Unlock()
+	s.unlockAndSignalWatermark()
 
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
@@ -418,34 +861,78 @@ func (s *sendStream) frameAcked(f wire.Frame) {
 
 // frameAcked()方法的PR化
 func (s *sendStream) prStreamframeAcked(f wire.Frame) {
-	f.(*wire.PRStreamFrame).PutBack()
+	s.prStreamFrameDone(f.(*wire.PRStreamFrame), true)
+}
+
+// prNotifiedFrameAcked is called when a PR STREAM frame is acknowledged,
+// for which a PRAckNotify frame was queued when it was sent, see notifyAbandonedLocked.
+// The data was delivered, so the PRAckNotify frame isn't needed any more, if it wasn't sent yet.
+func (s *sendStream) prNotifiedFrameAcked(f *wire.PRStreamFrame) {
+	s.mutex.Lock()
+	if !s.canceledWrite {
+		s.skippedBytes -= f.DataLen()
+	}
+	prAckNotifies := s.prAckNotifies
+	s.mutex.Unlock()
+	prAckNotifies.Cancel(s.streamID, f.Offset, f.DataLen())
+	s.prStreamFrameDone(f, true)
+}
+
+// prStreamFrameDone is called when a PR STREAM frame is acknowledged, or when its data is skipped.
+func (s *sendStream) prStreamFrameDone(f *wire.PRStreamFrame, acked bool) {
+	offset, length := f.Offset, f.DataLen()
+	f.PutBack()
 
 	s.mutex.Lock()
 	if s.canceledWrite {
 		s.mutex.Unlock()
 		return
 	}
+	if acked {
+		s.addAckedRange(offset, offset+length)
+	}
+	s.addDoneRange(offset, offset+length)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
 	newlyCompleted := s.isNewlyCompleted()
-	s.mutex.Unlock()
+	s.unlockAndSignalWatermark()
 
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
 }
 
+// isNewlyCompleted says if the stream just completed.
+// Skipped data only counts as delivered once the PRAckNotify frames were acknowledged:
+// the stream is deleted from the streams map when it completes, and left out of a handoff.
+// must be called after locking the mutex
 func (s *sendStream) isNewlyCompleted() bool {
-	completed := (s.finSent || s.canceledWrite) && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0
+	completed := (s.finSent || s.canceledWrite) && s.numOutstandingFrames == 0 && s.retransmissionQueue.Empty()
+	// After a reset, the peer doesn't need to be told to skip any data.
+	if completed && !s.completed && !s.canceledWrite && s.prAckNotifies.WaitForStream(s.streamID, s.prAckNotifiesAcked) {
+		return false
+	}
 	if completed && !s.completed {
 		s.completed = true
+		s.releasePRStream()
 		return true
 	}
 	return false
 }
 
+// prAckNotifiesAcked is called when all PRAckNotify frames of the stream were acknowledged.
+func (s *sendStream) prAckNotifiesAcked() {
+	s.mutex.Lock()
+	newlyCompleted := s.isNewlyCompleted()
+	s.mutex.Unlock()
+
+	if newlyCompleted {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+}
+
 func (s *sendStream) queueRetransmission(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
 	sf.DataLenPresent = true
@@ -454,7 +941,7 @@ func (s *sendStream) queueRetransmission(f wire.Frame) {
 		s.mutex.Unlock()
 		return
 	}
-	s.retransmissionQueue = append(s.retransmissionQueue, sf)
+	s.retransmissionQueue.PushBack(sf)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
@@ -469,25 +956,55 @@ func (s *sendStream) queueRetransmission(f wire.Frame) {
 // 如果不重传，则放一个PR_Ack_Notify帧到重传队列。
 // 由于用sendStream重传PRAckNotify帧比较麻烦，所以如果丢了先存到PRAckNotifyFrames中，
 // 随后给另一个packethandler的重传队列读取
-func (s *sendStream) prQueueRetransmission(f wire.Frame) {
+// prQueueRetransmission applies the PR policy to a lost PR STREAM frame.
+// It returns true if the data was skipped, i.e. if a PRAckNotify frame was queued instead of a retransmission.
+func (s *sendStream) prQueueRetransmission(f wire.Frame) bool {
 	frame := f.(*wire.PRStreamFrame)
 
-	pr_retran_enabled := false
+	s.mutex.Lock()
+	if s.canceledWrite {
+		s.mutex.Unlock()
+		frame.PutBack()
+		return false
+	}
+	// data that was expired by ExpireDataBefore is never retransmitted
+	expired := frame.Offset+frame.DataLen() <= s.expiredOffset
+	pr_retran_enabled := expired
+	retransmissions := s.retransmissionsAt(frame.Offset)
 
 	switch frame.PTDA {
 	case 0x80: // 概率重传策略,生成0-10000的随机值，ptdaC>它则PR重传，小于则正常重传
-		pC := int(frame.PtdaC)
-		rand.Seed(time.Now().Unix())
-		retran_num := rand.Intn(10000)
-		if pC < int(retran_num) {
+		if s.rand == nil {
+			s.rand = s.policyChain.Rand()
+		}
+		if int(frame.PtdaC) < s.rand.Intn(maxPRProbability) {
+			pr_retran_enabled = true
+		}
+	case 0x40: // 次数重传: the data is retransmitted at most PtdaC times, no matter how the retransmissions were split
+		if retransmissions >= frame.PtdaC {
+			pr_retran_enabled = true
+		}
+	case 0x20: // 时限重传: the retransmission is skipped if it wouldn't arrive before the deadline, see prPolicyChain.DeadlineMargin
+		if deadline, ok := s.deadlineAt(frame.Offset); ok && !s.clock.Now().Add(s.policyChain.DeadlineMargin()).Before(deadline) {
 			pr_retran_enabled = true
 		}
-	case 0x40:
-	case 0x20:
 	case 0x10:
+	case PTDAAbandon: // 立即放弃：从不重传. Only reached if no PRAckNotify frame was queued when the frame was sent.
+		pr_retran_enabled = true
 	}
-	if pr_retran_enabled { // pr retransmision
-		prAckNf := wire.PRAckNotifyFrame{
+	// The skipped bytes are counted while holding the mutex,
+	// such that frames lost concurrently can't exceed the peer's maximum skip ratio.
+	if pr_retran_enabled && !s.policyChain.SkipAllowed(s.skippedBytes+frame.DataLen(), s.writeOffset) {
+		if s.logger.Debug() {
+			s.logger.Debugf("Retransmitting lost data (offset %d, length %d), since skipping it would exceed the peer's maximum skip ratio", frame.Offset, frame.DataLen())
+		}
+		pr_retran_enabled = false
+	}
+	if pr_retran_enabled {
+		s.skippedBytes += frame.DataLen()
+		// The PRAckNotify frame is queued while holding the mutex,
+		// such that a concurrent reset (e.g. triggered by a STOP_SENDING frame) removes it, see cancelWriteImpl.
+		s.prAckNotifies.Add(&wire.PRAckNotifyFrame{
 			StreamID:       frame.StreamID,
 			Offset:         frame.Offset,
 			PRDataLen:      uint64(frame.DataLen()),
@@ -499,19 +1016,151 @@ func (s *sendStream) prQueueRetransmission(f wire.Frame) {
 			D:              frame.D,
 			A:              frame.A,
 			PtdaC:          frame.PtdaC,
+		})
+	} else {
+		s.setRetransmissions(frame.Offset, frame.Offset+frame.DataLen(), retransmissions+1)
+	}
+	s.mutex.Unlock()
+
+	if pr_retran_enabled { // pr retransmision
+		if s.logger.Debug() {
+			s.logger.With("pr_policy", PRPolicy{PTDA: frame.PTDA, Value: frame.PtdaC}).Debugf("Skipping lost data (offset %d, length %d)", frame.Offset, frame.DataLen())
 		}
-		PRAckNotifyFrames = append(PRAckNotifyFrames, &prAckNf)
-		s.prStreamframeAcked(frame)
-	} else { // 正常重传
-		sf := wire.StreamFrame{
-			StreamID:       frame.StreamID,
-			Offset:         frame.Offset,
-			Data:           frame.Data,
-			Fin:            frame.Fin,
-			DataLenPresent: frame.DataLenPresent,
+		// the frame is returned to the pool by prStreamFrameDone
+		abandoned := frame.PTDA == PTDAAbandon
+		s.prStreamFrameDone(frame, false)
+		if abandoned || expired {
+			// make sure that the PRAckNotify frame is sent right away
+			s.sender.onHasStreamData(s.streamID)
 		}
-		s.queueRetransmission(&sf)
+		return true
+	}
+	// 正常重传
+	s.queueRetransmission(frame.ToStreamFrame())
+	return false
+}
+
+// prSkipWasSpurious is called when a PR STREAM frame that was skipped after being declared lost is acknowledged after all.
+// If the PRAckNotify frame for its data wasn't sent yet, it's not needed any more.
+func (s *sendStream) prSkipWasSpurious(offset, length protocol.ByteCount) {
+	s.mutex.Lock()
+	if !s.canceledWrite {
+		s.addAckedRange(offset, offset+length)
+	}
+	prAckNotifies := s.prAckNotifies
+	s.mutex.Unlock()
+	canceled := prAckNotifies.Cancel(s.streamID, offset, length)
+	if canceled {
+		// the peer won't skip the data
+		s.mutex.Lock()
+		s.skippedBytes -= length
+		s.mutex.Unlock()
 	}
+	s.sender.onSpuriousPRConversion(s.streamID, offset, length, canceled)
+}
+
+// ExpireDataBefore abandons the data below offset that was written with a partially reliable policy.
+// Lost data in this range is not retransmitted, instead the peer is told to skip it.
+// Data that was written reliably is not affected.
+func (s *sendStream) ExpireDataBefore(offset uint64) {
+	s.mutex.Lock()
+	if s.canceledWrite || protocol.ByteCount(offset) <= s.expiredOffset {
+		s.mutex.Unlock()
+		return
+	}
+	s.expiredOffset = protocol.ByteCount(offset)
+	var abandoned bool
+	s.retransmissionQueue.Filter(func(f *wire.StreamFrame) bool {
+		if f.Offset >= s.expiredOffset {
+			return true
+		}
+		policy := s.policyAt(f.Offset)
+		if policy.IsReliable() {
+			return true
+		}
+		n := utils.Min(f.DataLen(), s.expiredOffset-f.Offset)
+		if !s.policyChain.SkipAllowed(s.skippedBytes+n, s.writeOffset) {
+			return true
+		}
+		abandoned = true
+		s.skippedBytes += n
+		if n == f.DataLen() {
+			s.prAckNotifies.Add(newPRAckNotifyFrame(s.streamID, f.Offset, f.DataLen(), f.Fin, policy))
+			s.addDoneRange(f.Offset, f.Offset+f.DataLen())
+			f.PutBack()
+			return false
+		}
+		// Only the beginning of the frame expired.
+		// Move the rest of the data to the front, as frames from the pool must keep their capacity.
+		s.prAckNotifies.Add(newPRAckNotifyFrame(s.streamID, f.Offset, n, false, policy))
+		s.addDoneRange(f.Offset, s.expiredOffset)
+		copy(f.Data, f.Data[n:])
+		f.Data = f.Data[:f.DataLen()-n]
+		f.Offset = s.expiredOffset
+		return true
+	})
+	newlyCompleted := s.isNewlyCompleted()
+	s.unlockAndSignalWatermark()
+
+	if abandoned {
+		s.logger.Debugf("Abandoned data below offset %d", offset)
+		// make sure that the PRAckNotify frames are sent right away
+		s.sender.onHasStreamData(s.streamID)
+	}
+	if newlyCompleted {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+}
+
+// newPRAckNotifyFrame creates the PRAckNotify frame telling the peer to skip data sent with policy.
+func newPRAckNotifyFrame(streamID protocol.StreamID, offset, dataLen protocol.ByteCount, fin bool, policy PRPolicy) *wire.PRAckNotifyFrame {
+	return &wire.PRAckNotifyFrame{
+		StreamID:       streamID,
+		Offset:         offset,
+		PRDataLen:      uint64(dataLen),
+		Fin:            fin,
+		DataLenPresent: true,
+		PTDA:           policy.PTDA,
+		P:              policy.PTDA == PTDAProbability,
+		T:              policy.PTDA == PTDATimes,
+		D:              policy.PTDA == PTDADeadline,
+		A:              policy.PTDA == PTDAPriority,
+		PtdaC:          policy.Value,
+	}
+}
+
+func (s *sendStream) EndMessage() error {
+	s.mutex.Lock()
+	if s.finishedWriting {
+		s.mutex.Unlock()
+		return fmt.Errorf("end of message on closed stream %d", s.streamID)
+	}
+	if s.canceledWrite {
+		s.mutex.Unlock()
+		return s.cancelWriteErr
+	}
+	if s.closeForShutdownErr != nil {
+		s.mutex.Unlock()
+		return s.closeForShutdownErr
+	}
+	offset := s.writeOffset
+	if s.nextFrame != nil {
+		offset += s.nextFrame.DataLen()
+	}
+	// Nothing was written since the last boundary.
+	// The beginning of the stream is always the beginning of a message.
+	if offset == s.lastMessageBoundary {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.lastMessageBoundary = offset
+	s.mutex.Unlock()
+
+	if !s.policyChain.Enabled() {
+		return nil
+	}
+	s.sender.queueControlFrame(&wire.PRMessageBoundaryFrame{StreamID: s.streamID, Offset: offset})
+	return nil
 }
 
 func (s *sendStream) Close() error {
@@ -526,6 +1175,7 @@ func (s *sendStream) Close() error {
 	}
 	s.ctxCancel()
 	s.finishedWriting = true
+	s.stopIdleTimer()
 	s.mutex.Unlock()
 
 	s.sender.onHasStreamData(s.streamID) // need to send the FIN, must be called without holding the mutex
@@ -546,8 +1196,14 @@ func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr er
 	s.ctxCancel()
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
+	s.stopIdleTimer()
 	s.numOutstandingFrames = 0
-	s.retransmissionQueue = nil
+	// Drop all state kept for retransmitting and skipping data.
+	// The RESET_STREAM frame ends the stream, the peer doesn't need to be told to skip data any more.
+	s.retransmissionQueue.Clear()
+	s.retransmissions = nil
+	s.deadlines = nil
+	s.prAckNotifies.RemoveStream(s.streamID)
 	newlyCompleted := s.isNewlyCompleted()
 	s.mutex.Unlock()
 
@@ -592,6 +1248,84 @@ func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+func (s *sendStream) DisablePR() {
+	s.mutex.Lock()
+	s.prDisabled = true
+	s.mutex.Unlock()
+}
+
+// setPRPolicyChain sets the chain used to resolve the policy,
+// and applies the idle timeout of the PRConfig of the connection.
+func (s *sendStream) setPRPolicyChain(c *prPolicyChain) {
+	s.mutex.Lock()
+	s.policyChain = c
+	s.prAckNotifies = c.ackNotifies
+	s.idleTimeout = c.config.IdleStreamTimeout
+	s.idleErrorCode = c.config.IdleStreamErrorCode
+	s.mutex.Unlock()
+}
+
+func (s *sendStream) SetIdleTimeout(d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.idleTimeout = d
+	if s.idleTimer != nil {
+		s.idleTimer.Reset(0) // reevaluate using the new timeout
+		return
+	}
+	s.maybeStartIdleTimer()
+}
+
+// must be called after locking the mutex
+func (s *sendStream) maybeStartIdleTimer() {
+	if s.idleTimeout == 0 || s.idleTimer != nil || !s.wrotePR {
+		return
+	}
+	if s.finishedWriting || s.canceledWrite || s.closedForShutdown {
+		return
+	}
+	s.idleTimer = sharedTimerWheel.AfterFunc(s.idleTimeout, s.checkIdle)
+}
+
+// must be called after locking the mutex
+func (s *sendStream) stopIdleTimer() {
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+}
+
+// checkIdle is called when the idle timer fires.
+// It cancels the stream if no data was written for the idle timeout.
+func (s *sendStream) checkIdle() {
+	s.mutex.Lock()
+	if s.idleTimer == nil {
+		s.mutex.Unlock()
+		return
+	}
+	if s.idleTimeout == 0 {
+		s.idleTimer = nil
+		s.mutex.Unlock()
+		return
+	}
+	// A Write call that is blocked (e.g. by flow control) doesn't mean that the producer stalled.
+	if s.dataForWriting != nil {
+		s.lastWrite = s.clock.Now()
+	}
+	if remaining := s.idleTimeout - s.clock.Now().Sub(s.lastWrite); remaining > 0 {
+		s.idleTimer.Reset(remaining)
+		s.mutex.Unlock()
+		return
+	}
+	s.idleTimer = nil
+	errorCode := s.idleErrorCode
+	s.mutex.Unlock()
+
+	s.cancelWriteImpl(errorCode, fmt.Errorf("stream %d: %w", s.streamID, ErrIdleStreamTimeout))
+	s.sender.onIdleStreamCanceled(s.streamID)
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Write unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
@@ -599,11 +1333,206 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.ctxCancel()
 	s.closedForShutdown = true
-	s.closeForShutdownErr = err
+	s.closeForShutdownErr = newConnectionClosedError(s.streamID, err)
+	s.stopIdleTimer()
+	s.releasePRStream()
 	s.mutex.Unlock()
 	s.signalWrite()
 }
 
+func (s *sendStream) AckedRanges() []ByteRange {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ranges := make([]ByteRange, 0, len(s.ackedRanges))
+	for _, r := range s.ackedRanges {
+		ranges = append(ranges, ByteRange{Start: uint64(r.Start), End: uint64(r.End)})
+	}
+	return ranges
+}
+
+// handoffState records the state of the send side, see Connection.Handoff.
+func (s *sendStream) handoffState(h *HandoffStream) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h.StreamID = s.streamID
+	h.BytesSent = uint64(s.writeOffset)
+	if len(s.doneRanges) > 0 && s.doneRanges[0].Start == 0 {
+		h.BytesDelivered = uint64(s.doneRanges[0].End)
+	}
+	h.SendFinished = s.completed && !s.canceledWrite
+}
+
+// addAckedRange records that the data from start to end was acknowledged.
+// must be called after locking the mutex
+func (s *sendStream) addAckedRange(start, end protocol.ByteCount) {
+	s.ackedRanges, _ = addByteInterval(s.ackedRanges, start, end)
+}
+
+// addDoneRange records that the data from start to end was acknowledged or skipped,
+// i.e. that it doesn't count towards the buffered data any more.
+// must be called after locking the mutex
+func (s *sendStream) addDoneRange(start, end protocol.ByteCount) {
+	var added protocol.ByteCount
+	s.doneRanges, added = addByteInterval(s.doneRanges, start, end)
+	s.doneBytes += added
+	s.forgetRetransmissions(start, end)
+	if len(s.doneRanges) > 0 && s.doneRanges[0].Start == 0 {
+		for len(s.deadlines) > 0 && s.deadlines[0].offset <= s.doneRanges[0].End {
+			s.deadlines = s.deadlines[1:]
+		}
+		s.prunePolicyRanges(s.doneRanges[0].End)
+	}
+}
+
+// retransmissionsAt returns how often the PR data at offset was retransmitted.
+// must be called after locking the mutex
+func (s *sendStream) retransmissionsAt(offset protocol.ByteCount) uint64 {
+	i := sort.Search(len(s.retransmissions), func(i int) bool { return s.retransmissions[i].end > offset })
+	if i < len(s.retransmissions) && s.retransmissions[i].start <= offset {
+		return s.retransmissions[i].count
+	}
+	return 0
+}
+
+// setRetransmissions records that the data from start to end was retransmitted count times.
+// must be called after locking the mutex
+func (s *sendStream) setRetransmissions(start, end protocol.ByteCount, count uint64) {
+	if start >= end { // a frame that only carries the FIN
+		return
+	}
+	s.forgetRetransmissions(start, end)
+	i := sort.Search(len(s.retransmissions), func(i int) bool { return s.retransmissions[i].start >= end })
+	s.retransmissions = append(s.retransmissions, retransmittedRange{})
+	copy(s.retransmissions[i+1:], s.retransmissions[i:])
+	s.retransmissions[i] = retransmittedRange{start: start, end: end, count: count}
+}
+
+// forgetRetransmissions removes the retransmission counts of the data from start to end.
+// must be called after locking the mutex
+func (s *sendStream) forgetRetransmissions(start, end protocol.ByteCount) {
+	r := s.retransmissions
+	// the first range that ends after start
+	i := sort.Search(len(r), func(i int) bool { return r[i].end > start })
+	j := i
+	for j < len(r) && r[j].start < end {
+		j++
+	}
+	if start >= end || i == j {
+		return
+	}
+	// keep the parts of the overlapping ranges that lie outside of start and end
+	var keep [2]retransmittedRange
+	var n int
+	if r[i].start < start {
+		keep[n] = retransmittedRange{start: r[i].start, end: start, count: r[i].count}
+		n++
+	}
+	if r[j-1].end > end {
+		keep[n] = retransmittedRange{start: end, end: r[j-1].end, count: r[j-1].count}
+		n++
+	}
+	if n > j-i { // a range was split in two
+		r = append(r, retransmittedRange{})
+		copy(r[i+n:], r[j:len(r)-1])
+	} else {
+		copy(r[i+n:], r[j:])
+		r = r[:len(r)-(j-i)+n]
+	}
+	copy(r[i:], keep[:n])
+	s.retransmissions = r
+}
+
+// addByteInterval adds the interval from start to end to the sorted and merged intervals.
+// It returns the number of bytes that weren't covered by the intervals before.
+func addByteInterval(intervals []byteInterval, start, end protocol.ByteCount) ([]byteInterval, protocol.ByteCount) {
+	if start >= end { // e.g. a frame that only carries the FIN
+		return intervals, 0
+	}
+	// the first range that ends at or after start
+	i := sort.Search(len(intervals), func(i int) bool { return intervals[i].End >= start })
+	j := i
+	added := end - start
+	for j < len(intervals) && intervals[j].Start <= end {
+		added -= utils.Min(end, intervals[j].End) - utils.Max(start, intervals[j].Start)
+		start = utils.Min(start, intervals[j].Start)
+		end = utils.Max(end, intervals[j].End)
+		j++
+	}
+	if i == j {
+		intervals = append(intervals, byteInterval{})
+		copy(intervals[i+1:], intervals[i:])
+		intervals[i] = byteInterval{Start: start, End: end}
+		return intervals, added
+	}
+	intervals[i] = byteInterval{Start: start, End: end}
+	return append(intervals[:i+1], intervals[j:]...), added
+}
+
+func (s *sendStream) SetWriteBufferWatermarks(low, high uint64, callback func(pause bool)) {
+	s.mutex.Lock()
+	s.lowWatermark = protocol.ByteCount(low)
+	s.highWatermark = protocol.ByteCount(high)
+	s.watermarkCallback = callback
+	s.aboveHighWatermark = false
+	s.unlockAndSignalWatermark()
+}
+
+// bufferedBytes returns the number of bytes written that weren't acknowledged or skipped yet.
+// must be called after locking the mutex
+func (s *sendStream) bufferedBytes() protocol.ByteCount {
+	written := s.writeOffset + protocol.ByteCount(len(s.dataForWriting))
+	if s.nextFrame != nil {
+		written += s.nextFrame.DataLen()
+	}
+	return written - s.doneBytes
+}
+
+// watermarkSignal returns a function that calls the watermark callback, if the buffered data crossed a watermark.
+// The function must be called after unlocking the mutex.
+// must be called after locking the mutex
+func (s *sendStream) watermarkSignal() func() {
+	callback := s.watermarkCallback
+	if callback == nil || s.canceledWrite || s.closedForShutdown {
+		return nil
+	}
+	var pause bool
+	switch buffered := s.bufferedBytes(); {
+	case !s.aboveHighWatermark && buffered >= s.highWatermark:
+		s.aboveHighWatermark = true
+		pause = true
+	case s.aboveHighWatermark && buffered <= s.lowWatermark:
+		s.aboveHighWatermark = false
+	default:
+		return nil
+	}
+	// Make sure that the callbacks are called in the order the watermarks were crossed.
+	s.watermarkMutex.Lock()
+	return func() {
+		callback(pause)
+		s.watermarkMutex.Unlock()
+	}
+}
+
+// unlockAndSignalWatermark unlocks the mutex, and calls the watermark callback if the buffered data crossed a watermark.
+// must be called after locking the mutex
+func (s *sendStream) unlockAndSignalWatermark() {
+	signal := s.watermarkSignal()
+	s.mutex.Unlock()
+	if signal != nil {
+		signal()
+	}
+}
+
+// releasePRStream stops counting the stream towards the PR stream limits.
+// must be called after locking the mutex
+func (s *sendStream) releasePRStream() {
+	if s.countedAsPRStream {
+		s.countedAsPRStream = false
+		s.policyChain.releaseStream()
+	}
+}
+
 // signalWrite performs a non-blocking send on the writeChan
 func (s *sendStream) signalWrite() {
 	select {
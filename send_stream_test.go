@@ -5,16 +5,22 @@ import (
 	"errors"
 	"io"
 	mrand "math/rand"
+	"net"
 	"runtime"
+	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 )
@@ -32,7 +38,9 @@ var _ = Describe("Send Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newSendStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		str = newSendStream(streamID, mockSender, mockFC, utils.DefaultLogger, protocol.VersionWhatever)
+		// Don't depend on the deprecated package-level policy variables: Write sends data reliably.
+		str.setPRPolicyChain(newPRPolicyChain(PRConfig{DefaultPolicy: &PRPolicy{}}))
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutWriter(str, timeout)
@@ -148,6 +156,38 @@ var _ = Describe("Send Stream", func() {
 			Expect(f.Data).To(Equal([]byte("foobar")))
 		})
 
+		It("writes multiple buffers", func() {
+			str.SetPRPolicy(&PRPolicy{})
+			large := getData(2000)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				mockSender.EXPECT().onHasStreamData(streamID).Times(3)
+				n, err := str.WriteBuffers(net.Buffers{[]byte("foo"), []byte("bar"), large})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(2006))
+			}()
+			Eventually(func() bool {
+				str.mutex.Lock()
+				defer str.mutex.Unlock()
+				return str.dataForWriting != nil
+			}).Should(BeTrue())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(3)
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(3)
+			// the small buffers are bundled, and the large buffer starts a new frame
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			frame, _ = str.popStreamFrame(1000)
+			f := frame.Frame.(*wire.StreamFrame)
+			Expect(f.Offset).To(BeEquivalentTo(6))
+			Expect(f.Data).To(Equal(large[:f.DataLen()]))
+			offset := f.DataLen()
+			Eventually(done).Should(BeClosed())
+			frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal(large[offset:]))
+		})
+
 		It("writes and gets data in multiple turns, for large writes", func() {
 			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(5)
 			var totalBytesSent protocol.ByteCount
@@ -306,6 +346,804 @@ var _ = Describe("Send Stream", func() {
 			Expect(str.Context().Done()).To(BeClosed())
 		})
 
+		Context("writing with a PR policy", func() {
+			prPolicy := PRPolicy{PTDA: PTDAProbability, Value: 5000}
+
+			It("uses the write policy of the stream for Write", func() {
+				str.SetPRPolicy(&prPolicy)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(done).Should(BeClosed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				Expect(frame.Frame.(*wire.PRStreamFrame).PtdaC).To(BeEquivalentTo(5000))
+			})
+
+			It("resolves the effective policy", func() {
+				configPolicy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+				chain := newPRPolicyChain(PRConfig{DefaultPolicy: &configPolicy})
+				str.setPRPolicyChain(chain)
+				policy, source := str.EffectivePRPolicy()
+				Expect(policy).To(Equal(configPolicy))
+				Expect(source).To(Equal(PRPolicySourceConfig))
+				connPolicy := PRPolicy{PTDA: PTDATimes, Value: 2}
+				chain.SetConnectionPolicy(&connPolicy)
+				policy, source = str.EffectivePRPolicy()
+				Expect(policy).To(Equal(connPolicy))
+				Expect(source).To(Equal(PRPolicySourceConnection))
+				str.SetPRPolicy(&prPolicy)
+				policy, source = str.EffectivePRPolicy()
+				Expect(policy).To(Equal(prPolicy))
+				Expect(source).To(Equal(PRPolicySourceStream))
+				str.SetPRPolicy(nil)
+				policy, source = str.EffectivePRPolicy()
+				Expect(policy).To(Equal(connPolicy))
+				Expect(source).To(Equal(PRPolicySourceConnection))
+			})
+
+			It("uses the global policy if no policy chain is set", func() {
+				str.policyChain = nil
+				policy, source := str.EffectivePRPolicy()
+				Expect(policy).To(Equal(defaultPRPolicy()))
+				Expect(source).To(Equal(PRPolicySourceGlobal))
+			})
+
+			It("rejects invalid policies", func() {
+				_, err := str.WriteWithPolicy([]byte("foobar"), PRPolicy{PTDA: 0x3})
+				Expect(err).To(MatchError("invalid PR policy: PTDA 0x3"))
+			})
+
+			It("sends data written with a PR policy in PRSTREAM frames", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					n, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(6))
+				}()
+				Eventually(done).Should(BeClosed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				f := frame.Frame.(*wire.PRStreamFrame)
+				Expect(f.Data).To(Equal([]byte("foobar")))
+				Expect(f.PTDA).To(Equal(PTDAProbability))
+				Expect(f.P).To(BeTrue())
+				Expect(f.PtdaC).To(BeEquivalentTo(5000))
+			})
+
+			It("announces policy changes after data was written", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(3)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+				_, err := str.WriteWithPolicy([]byte("foo"), PRPolicy{})
+				Expect(err).ToNot(HaveOccurred())
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				// the policy is unchanged
+				_, err = str.WriteWithPolicy([]byte("bar"), PRPolicy{})
+				Expect(err).ToNot(HaveOccurred())
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				str.SetPRPolicy(&prPolicy)
+				mockSender.EXPECT().onPRPolicyChanged(streamID, protocol.ByteCount(6), prPolicy)
+				_, err = str.Write([]byte("baz"))
+				Expect(err).ToNot(HaveOccurred())
+				// data written before the change keeps its policy
+				str.mutex.Lock()
+				Expect(str.policyAt(5)).To(Equal(PRPolicy{}))
+				Expect(str.policyAt(6)).To(Equal(prPolicy))
+				str.mutex.Unlock()
+			})
+
+			It("doesn't announce the policy of the first data written", func() {
+				str.SetPRPolicy(&prPolicy)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("sends data reliably if partial reliability is disabled", func() {
+				str.setPRPolicyChain(newPRPolicyChain(PRConfig{Disabled: true}))
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+				Expect(err).ToNot(HaveOccurred())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				// no PR_MESSAGE_BOUNDARY frame is queued
+				Expect(str.EndMessage()).To(Succeed())
+			})
+
+			It("sends data reliably on streams with partial reliability disabled", func() {
+				connPolicy := PRPolicy{PTDA: PTDATimes, Value: 2}
+				chain := newPRPolicyChain(PRConfig{DefaultPolicy: &prPolicy})
+				chain.SetConnectionPolicy(&connPolicy)
+				str.setPRPolicyChain(chain)
+				str.SetPRPolicy(&prPolicy)
+				str.DisablePR()
+				policy, source := str.EffectivePRPolicy()
+				Expect(policy.IsReliable()).To(BeTrue())
+				Expect(source).To(Equal(PRPolicySourceStream))
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			})
+
+			It("refuses to write data with a PR policy on streams with partial reliability disabled", func() {
+				str.DisablePR()
+				_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+				Expect(err).To(MatchError(ErrPRDisabled))
+				Expect(str.writeOffset).To(BeZero())
+			})
+
+			It("sends data written before the peer refused partial reliability reliably", func() {
+				chain := newPRPolicyChain(PRConfig{})
+				str.setPRPolicyChain(chain)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+				Expect(err).ToNot(HaveOccurred())
+				chain.SetPeerSupportsPR(false)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			})
+
+			Context("limiting the number of PR streams", func() {
+				var chain *prPolicyChain
+
+				BeforeEach(func() {
+					chain = newPRPolicyChain(PRConfig{MaxPRStreams: 1})
+					str.setPRPolicyChain(chain)
+				})
+
+				It("sends data reliably when the limit is reached", func() {
+					Expect(chain.acquireStream()).To(BeTrue()) // another stream
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+					Expect(err).ToNot(HaveOccurred())
+					mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+					mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+					frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				})
+
+				It("rejects writes when the limit is reached, if configured", func() {
+					chain = newPRPolicyChain(PRConfig{MaxPRStreams: 1, RejectExcessPRStreams: true})
+					str.setPRPolicyChain(chain)
+					Expect(chain.acquireStream()).To(BeTrue()) // another stream
+					_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+					Expect(err).To(MatchError(ErrTooManyPRStreams))
+					// reliable data can still be written
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err = str.WriteWithPolicy([]byte("foobar"), PRPolicy{})
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("counts the stream only once", func() {
+					mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+					mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+					mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+					_, err := str.WriteWithPolicy([]byte("foo"), prPolicy)
+					Expect(err).ToNot(HaveOccurred())
+					frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+					_, err = str.WriteWithPolicy([]byte("bar"), prPolicy)
+					Expect(err).ToNot(HaveOccurred())
+					frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+					Expect(chain.acquireStream()).To(BeFalse())
+				})
+
+				It("stops counting the stream when it is closed for shutdown", func() {
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(chain.acquireStream()).To(BeFalse())
+					str.closeForShutdown(errors.New("shutdown"))
+					Expect(chain.acquireStream()).To(BeTrue())
+				})
+			})
+
+			It("doesn't bundle writes with different policies", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+					mockSender.EXPECT().onPRPolicyChanged(streamID, protocol.ByteCount(3), prPolicy)
+					n, err := str.WriteWithPolicy([]byte("foo"), PRPolicy{})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(3))
+					n, err = str.WriteWithPolicy([]byte("bar"), prPolicy)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(3))
+				}()
+				waitForWrite()
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+				frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(hasMoreData).To(BeTrue())
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+				Eventually(func() []byte {
+					str.mutex.Lock()
+					defer str.mutex.Unlock()
+					return str.dataForWriting
+				}).ShouldNot(BeNil())
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				f := frame.Frame.(*wire.PRStreamFrame)
+				Expect(f.Offset).To(Equal(protocol.ByteCount(3)))
+				Expect(f.Data).To(Equal([]byte("bar")))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("keeps the policy of retransmitted data", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.WriteWithPolicy([]byte("foobar"), PRPolicy{})
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(done).Should(BeClosed())
+				str.mutex.Lock()
+				str.setPolicy(6, prPolicy)
+				str.mutex.Unlock()
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				mockSender.EXPECT().onHasStreamData(streamID)
+				frame.OnLost(frame.Frame)
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			})
+
+			It("never retransmits data written in immediate-abandon mode", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.WriteWithPolicy([]byte("foobar"), PRPolicy{PTDA: PTDAAbandon})
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(done).Should(BeClosed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				Expect(frame.Frame.(*wire.PRStreamFrame).PTDA).To(Equal(PTDAAbandon))
+				// the PRAckNotify frame is queued when the data is sent, not when it is lost
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				nf := str.prAckNotifies.frames[0]
+				Expect(nf.StreamID).To(Equal(streamID))
+				Expect(nf.PRDataLen).To(BeEquivalentTo(6))
+				frame.OnLost(frame.Frame)
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				Expect(str.hasData()).To(BeFalse())
+				f, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(f).To(BeNil())
+			})
+
+			Context("rate limiting", func() {
+				It("sets a rate limit", func() {
+					Expect(str.rateLimiter()).To(BeNil())
+					str.SetRateLimit(1e6)
+					limiter := str.rateLimiter()
+					Expect(limiter).ToNot(BeNil())
+					str.SetRateLimit(2e6)
+					Expect(str.rateLimiter()).To(BeIdenticalTo(limiter))
+					Expect(limiter.rate).To(BeEquivalentTo(2e6))
+				})
+
+				It("removes the rate limit", func() {
+					str.SetRateLimit(0)
+					str.SetRateLimit(1e6)
+					// the framer might be waiting for the limiter, so the stream needs to be queued again
+					mockSender.EXPECT().onHasStreamData(streamID)
+					str.SetRateLimit(0)
+					Expect(str.rateLimiter()).To(BeNil())
+				})
+			})
+
+			writeAndPop := func(policy PRPolicy) *ackhandler.Frame {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.WriteWithPolicy([]byte("foobar"), policy)
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(done).Should(BeClosed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				return frame
+			}
+
+			It("returns the deadline of the data that wasn't sent yet", func() {
+				Expect(str.nextDeadline()).To(BeZero())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.WriteWithPolicy([]byte("foobar"), PRPolicy{PTDA: PTDADeadline, Value: 100})
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(func() time.Time { return str.nextDeadline() }).Should(BeTemporally("~", time.Now().Add(100*time.Millisecond), scaleDuration(20*time.Millisecond)))
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				Eventually(done).Should(BeClosed())
+				Expect(str.nextDeadline()).To(BeZero())
+			})
+
+			Context("expiring data", func() {
+				timesPolicy := PRPolicy{PTDA: PTDATimes, Value: 3}
+
+				It("removes expired data from the retransmission queue", func() {
+					frame := writeAndPop(timesPolicy)
+					mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+					frame.OnLost(frame.Frame)
+					str.ExpireDataBefore(3)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					nf := str.prAckNotifies.frames[0]
+					Expect(nf.Offset).To(BeZero())
+					Expect(nf.PRDataLen).To(BeEquivalentTo(3))
+					Expect(nf.PTDA).To(Equal(PTDATimes))
+					Expect(nf.T).To(BeTrue())
+					frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).ToNot(BeNil())
+					Expect(frame.Frame.(*wire.PRStreamFrame).Offset).To(BeEquivalentTo(3))
+					Expect(frame.Frame.(*wire.PRStreamFrame).Data).To(Equal([]byte("bar")))
+				})
+
+				It("completes the stream when all queued retransmissions expired", func() {
+					frame := writeAndPop(timesPolicy)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					Expect(str.Close()).To(Succeed())
+					fin, _ := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(fin).ToNot(BeNil())
+					Expect(fin.Frame.(*wire.PRStreamFrame).Fin).To(BeTrue())
+					fin.OnAcked(fin.Frame)
+					mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+					frame.OnLost(frame.Frame)
+					str.ExpireDataBefore(6)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					// the stream is completed once the peer acknowledged the PRAckNotify frame
+					nf := str.prAckNotifies.PopAll()[0]
+					mockSender.EXPECT().onStreamCompleted(streamID)
+					str.prAckNotifies.OnAcked(nf)
+				})
+
+				It("doesn't retransmit data that expired while in flight", func() {
+					frame := writeAndPop(timesPolicy)
+					str.ExpireDataBefore(6)
+					Expect(str.prAckNotifies.frames).To(BeEmpty())
+					mockSender.EXPECT().onHasStreamData(streamID)
+					frame.OnLost(frame.Frame)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					Expect(str.prAckNotifies.frames[0].PRDataLen).To(BeEquivalentTo(6))
+					f, _ := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(f).To(BeNil())
+				})
+
+				It("doesn't expire data written reliably", func() {
+					frame := writeAndPop(PRPolicy{})
+					mockSender.EXPECT().onHasStreamData(streamID)
+					frame.OnLost(frame.Frame)
+					str.ExpireDataBefore(6)
+					Expect(str.prAckNotifies.frames).To(BeEmpty())
+					frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).ToNot(BeNil())
+					Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+				})
+			})
+
+			Context("acknowledgements after a loss", func() {
+				It("cancels the PRAckNotify frame when skipped data is acknowledged after all", func() {
+					frame := writeAndPop(PRPolicy{PTDA: PTDATimes}) // never retransmitted
+					frame.OnLost(frame.Frame)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					mockSender.EXPECT().onSpuriousPRConversion(streamID, protocol.ByteCount(0), protocol.ByteCount(6), true)
+					frame.OnAckedAfterLoss()
+					Expect(str.prAckNotifies.frames).To(BeEmpty())
+				})
+
+				It("reports when the PRAckNotify frame was already sent", func() {
+					frame := writeAndPop(PRPolicy{PTDA: PTDATimes}) // never retransmitted
+					frame.OnLost(frame.Frame)
+					str.prAckNotifies.PopAll() // the packet packer dequeued the frame
+					mockSender.EXPECT().onSpuriousPRConversion(streamID, protocol.ByteCount(0), protocol.ByteCount(6), false)
+					frame.OnAckedAfterLoss()
+				})
+
+				It("doesn't report data that was retransmitted", func() {
+					frame := writeAndPop(PRPolicy{PTDA: PTDATimes, Value: 3})
+					mockSender.EXPECT().onHasStreamData(streamID)
+					frame.OnLost(frame.Frame)
+					frame.OnAckedAfterLoss()
+					Expect(str.prAckNotifies.frames).To(BeEmpty())
+				})
+			})
+
+			Context("maximum skip ratio", func() {
+				BeforeEach(func() {
+					str.policyChain = newPRPolicyChain(PRConfig{})
+					str.policyChain.SetPeerMaxSkipRatio(protocol.SkipRatioScale / 2)
+				})
+
+				It("retransmits lost data if skipping it would exceed the peer's maximum skip ratio", func() {
+					first := writeAndPop(PRPolicy{PTDA: PTDATimes}) // never retransmitted
+					second := writeAndPop(PRPolicy{PTDA: PTDATimes})
+					mockSender.EXPECT().onHasStreamData(streamID)
+					first.OnLost(first.Frame)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					second.OnLost(second.Frame)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).ToNot(BeNil())
+					Expect(frame.Frame.(*wire.PRStreamFrame).Offset).To(Equal(protocol.ByteCount(6)))
+				})
+
+				It("only queues a PRAckNotify frame when sending data in immediate-abandon mode, if the skip ratio allows it", func() {
+					// Skipping the first 6 bytes sent would exceed the skip ratio, skipping the second 6 bytes doesn't.
+					first := writeAndPop(PRPolicy{PTDA: PTDAAbandon})
+					Expect(str.prAckNotifies.frames).To(BeEmpty())
+					second := writeAndPop(PRPolicy{PTDA: PTDAAbandon})
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					Expect(str.prAckNotifies.frames[0].Offset).To(Equal(protocol.ByteCount(6)))
+					Expect(str.skippedBytes).To(BeEquivalentTo(6))
+					second.OnLost(second.Frame)
+					// the first frame is retransmitted, since skipping it would exceed the skip ratio
+					mockSender.EXPECT().onHasStreamData(streamID)
+					first.OnLost(first.Frame)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).ToNot(BeNil())
+					Expect(frame.Frame.(*wire.PRStreamFrame).Offset).To(BeZero())
+				})
+
+				It("doesn't expire data if that would exceed the peer's maximum skip ratio", func() {
+					first := writeAndPop(PRPolicy{PTDA: PTDATimes, Value: 3})
+					second := writeAndPop(PRPolicy{PTDA: PTDATimes, Value: 3})
+					mockSender.EXPECT().onHasStreamData(streamID).Times(3)
+					first.OnLost(first.Frame)
+					second.OnLost(second.Frame)
+					str.ExpireDataBefore(12)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					Expect(str.prAckNotifies.frames[0].Offset).To(BeZero())
+					frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).ToNot(BeNil())
+					Expect(frame.Frame.(*wire.PRStreamFrame).Offset).To(Equal(protocol.ByteCount(6)))
+				})
+			})
+
+			Context("retransmission probability", func() {
+				It("retransmits lost data with the probability of the policy", func() {
+					const num = 1000
+					str.rand = mrand.New(mrand.NewSource(1))
+					str.mutex.Lock()
+					str.numOutstandingFrames = num
+					str.mutex.Unlock()
+					var retransmitted int
+					mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { retransmitted++ }).AnyTimes()
+					for i := 0; i < num; i++ {
+						f := &wire.PRStreamFrame{
+							StreamID: streamID,
+							Offset:   protocol.ByteCount(10 * i),
+							Data:     make([]byte, 5),
+							PTDA:     PTDAProbability,
+							PtdaC:    2500,
+						}
+						str.prQueueRetransmission(f)
+					}
+					Expect(retransmitted).To(And(BeNumerically(">", 200), BeNumerically("<", 300)))
+					Expect(str.prAckNotifies.frames).To(HaveLen(num - retransmitted))
+				})
+			})
+
+			Context("concurrency", func() {
+				It("handles frames that are lost and acknowledged on multiple goroutines", func() {
+					const (
+						numWorkers = 4
+						numWrites  = 200
+						chunkSize  = 100
+					)
+					// Both streams share the random number generator and the PRAckNotify queue of the connection.
+					chain := newPRPolicyChain(PRConfig{RandSeed: 1})
+					chain.SetPeerMaxSkipRatio(protocol.SkipRatioScale / 4)
+					str2 := newSendStream(streamID+4, mockSender, mockFC, utils.DefaultLogger, protocol.VersionWhatever)
+					streams := []*sendStream{str, str2}
+					mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+					mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+					mockSender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
+					streamCompleted := make(chan struct{}, len(streams))
+					for _, s := range streams {
+						s.setPRPolicyChain(chain)
+						mockSender.EXPECT().onStreamCompleted(s.streamID).Do(func(protocol.StreamID) { streamCompleted <- struct{}{} })
+						go func(s *sendStream) {
+							defer GinkgoRecover()
+							for i := 0; i < numWrites; i++ {
+								_, err := s.WriteWithPolicy(make([]byte, chunkSize), PRPolicy{PTDA: PTDAProbability, Value: 5000})
+								Expect(err).ToNot(HaveOccurred())
+							}
+							Expect(s.Close()).To(Succeed())
+						}(s)
+					}
+
+					done := make(chan struct{})
+					defer close(done)
+					frames := make(chan *ackhandler.Frame, 100)
+					// the packet packer
+					go func() {
+						defer GinkgoRecover()
+						for i := 0; ; i++ {
+							for _, nf := range chain.ackNotifies.PopAll() {
+								select {
+								case <-done:
+									return
+								case frames <- &ackhandler.Frame{Frame: nf, OnLost: chain.ackNotifies.OnLost, OnAcked: chain.ackNotifies.OnAcked}:
+								}
+							}
+							f, _ := streams[i%len(streams)].popStreamFrame(500)
+							if f == nil {
+								runtime.Gosched()
+							}
+							select {
+							case <-done:
+								return
+							case frames <- f:
+							}
+						}
+					}()
+					// the loss detection
+					for i := 0; i < numWorkers; i++ {
+						go func(seed int64) {
+							defer GinkgoRecover()
+							r := mrand.New(mrand.NewSource(seed))
+							for {
+								var f *ackhandler.Frame
+								select {
+								case <-done:
+									return
+								case f = <-frames:
+								}
+								if f == nil {
+									continue
+								}
+								if r.Intn(2) == 0 {
+									f.OnLost(f.Frame)
+								} else {
+									f.OnAcked(f.Frame)
+								}
+							}
+						}(int64(i))
+					}
+
+					for range streams {
+						Eventually(streamCompleted, scaleDuration(5*time.Second)).Should(Receive())
+					}
+					for _, s := range streams {
+						s.mutex.Lock()
+						Expect(s.doneBytes).To(BeEquivalentTo(numWrites * chunkSize))
+						Expect(s.skippedBytes).To(BeNumerically("<=", numWrites*chunkSize/4))
+						s.mutex.Unlock()
+					}
+				})
+			})
+
+			Context("acknowledged ranges", func() {
+				It("merges acknowledged ranges", func() {
+					str.mutex.Lock()
+					str.addAckedRange(10, 20)
+					str.addAckedRange(30, 40)
+					str.mutex.Unlock()
+					Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 10, End: 20}, {Start: 30, End: 40}}))
+					str.mutex.Lock()
+					str.addAckedRange(0, 5)
+					str.mutex.Unlock()
+					Expect(str.AckedRanges()).To(HaveLen(3))
+					str.mutex.Lock()
+					str.addAckedRange(20, 30)
+					str.mutex.Unlock()
+					Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 5}, {Start: 10, End: 40}}))
+					str.mutex.Lock()
+					str.addAckedRange(3, 12)
+					str.addAckedRange(50, 50)
+					str.mutex.Unlock()
+					Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 40}}))
+				})
+
+				It("reports acknowledged data, but not skipped data", func() {
+					Expect(str.AckedRanges()).To(BeEmpty())
+					first := writeAndPop(PRPolicy{PTDA: PTDATimes}) // never retransmitted
+					second := writeAndPop(PRPolicy{PTDA: PTDATimes})
+					first.OnAcked(first.Frame)
+					Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 6}}))
+					second.OnLost(second.Frame)
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 6}}))
+					// the skipped data is acknowledged after all
+					mockSender.EXPECT().onSpuriousPRConversion(streamID, protocol.ByteCount(6), protocol.ByteCount(6), true)
+					second.OnAckedAfterLoss()
+					Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 12}}))
+				})
+			})
+
+			Context("write buffer watermarks", func() {
+				var signals []bool
+
+				BeforeEach(func() {
+					signals = nil
+				})
+
+				It("counts the bytes that weren't acknowledged or skipped before", func() {
+					intervals, added := addByteInterval(nil, 10, 20)
+					Expect(added).To(BeEquivalentTo(10))
+					intervals, added = addByteInterval(intervals, 15, 25)
+					Expect(added).To(BeEquivalentTo(5))
+					intervals, added = addByteInterval(intervals, 30, 40)
+					Expect(added).To(BeEquivalentTo(10))
+					intervals, added = addByteInterval(intervals, 0, 50)
+					Expect(added).To(BeEquivalentTo(25))
+					Expect(intervals).To(Equal([]byteInterval{{Start: 0, End: 50}}))
+					_, added = addByteInterval(intervals, 10, 20)
+					Expect(added).To(BeZero())
+				})
+
+				It("signals when the buffered data crosses the watermarks", func() {
+					str.SetWriteBufferWatermarks(6, 12, func(pause bool) { signals = append(signals, pause) })
+					first := writeAndPop(PRPolicy{})
+					Expect(signals).To(BeEmpty())
+					second := writeAndPop(PRPolicy{})
+					Expect(signals).To(Equal([]bool{true}))
+					second.OnAcked(second.Frame)
+					Expect(signals).To(Equal([]bool{true, false}))
+					first.OnAcked(first.Frame)
+					Expect(signals).To(Equal([]bool{true, false}))
+				})
+
+				It("doesn't count skipped data", func() {
+					str.SetWriteBufferWatermarks(6, 12, func(pause bool) { signals = append(signals, pause) })
+					writeAndPop(PRPolicy{PTDA: PTDATimes}) // never retransmitted
+					second := writeAndPop(PRPolicy{PTDA: PTDATimes})
+					Expect(signals).To(Equal([]bool{true}))
+					second.OnLost(second.Frame)
+					Expect(signals).To(Equal([]bool{true, false}))
+					// the skipped data is acknowledged after all
+					mockSender.EXPECT().onSpuriousPRConversion(streamID, protocol.ByteCount(6), protocol.ByteCount(6), true)
+					second.OnAckedAfterLoss()
+					Expect(signals).To(Equal([]bool{true, false}))
+				})
+
+				It("signals right away, if the buffered data already reached the high watermark", func() {
+					writeAndPop(PRPolicy{})
+					str.SetWriteBufferWatermarks(3, 6, func(pause bool) { signals = append(signals, pause) })
+					Expect(signals).To(Equal([]bool{true}))
+				})
+
+				It("removes the watermarks", func() {
+					str.SetWriteBufferWatermarks(6, 12, func(pause bool) { signals = append(signals, pause) })
+					str.SetWriteBufferWatermarks(0, 0, nil)
+					writeAndPop(PRPolicy{})
+					writeAndPop(PRPolicy{})
+					Expect(signals).To(BeEmpty())
+				})
+			})
+
+			It("returns all pooled frames when PR frames are retransmitted and acknowledged", func() {
+				audit := wire.StartPoolAudit()
+				defer audit.Stop()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.WriteWithPolicy([]byte("foobar"), PRPolicy{PTDA: PTDATimes, Value: 3})
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(done).Should(BeClosed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				mockSender.EXPECT().onHasStreamData(streamID)
+				frame.OnLost(frame.Frame)
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				Expect(frame.Frame.(*wire.PRStreamFrame).Data).To(Equal([]byte("foobar")))
+				frame.OnAcked(frame.Frame)
+				Expect(audit.Err()).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("non-blocking writes", func() {
+			It("writes the data that fits into a packet", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				n, err := str.TryWrite(getData(protocol.MaxPacketBufferSize + 10))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+				n, err = str.TryWrite([]byte("foobar"))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(gomock.Any())
+				frame, _ := str.popStreamFrame(protocol.MaxPacketBufferSize / 2)
+				Expect(frame).ToNot(BeNil())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				n, err = str.TryWrite([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(6))
+				Expect(str.nextFrame.Data[len(str.nextFrame.Data)-6:]).To(Equal([]byte("foobar")))
+			})
+
+			It("doesn't write while Write is blocked", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.Write(getData(protocol.MaxPacketBufferSize + 3))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				waitForWrite()
+				n, err := str.TryWrite([]byte("foobar"))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+				// make the Write go routine return
+				str.closeForShutdown(nil)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("doesn't append data written with a different policy to the buffered frame", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.TryWrite([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				str.SetPRPolicy(&PRPolicy{PTDA: PTDAAbandon})
+				n, err := str.TryWrite([]byte("bar"))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+				Expect(str.nextFrame.Data).To(Equal([]byte("foo")))
+			})
+
+			It("returns write errors", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				_, err := str.TryWrite([]byte("foobar"))
+				Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+			})
+		})
+
 		Context("flow control blocking", func() {
 			It("queues a BLOCKED frame if the stream is flow control blocked", func() {
 				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0))
@@ -490,6 +1328,7 @@ var _ = Describe("Send Stream", func() {
 			})
 
 			It("doesn't unblock if the deadline is removed", func() {
+				testErr := errors.New("test done")
 				mockSender.EXPECT().onHasStreamData(streamID)
 				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
 				str.SetWriteDeadline(deadline)
@@ -506,18 +1345,45 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Write(getData(5000))
-					Expect(err).To(MatchError("test done"))
+					Expect(err).To(MatchError(testErr))
 					close(done)
 				}()
 				runtime.Gosched()
 				Eventually(deadlineUnset).Should(BeClosed())
 				Consistently(done, scaleDuration(100*time.Millisecond)).ShouldNot(BeClosed())
 				// make the go routine return
-				str.closeForShutdown(errors.New("test done"))
+				str.closeForShutdown(testErr)
 				Eventually(done).Should(BeClosed())
 			})
 		})
 
+		Context("message boundaries", func() {
+			It("queues a PR_MESSAGE_BOUNDARY frame", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().queueControlFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 6})
+				Expect(str.EndMessage()).To(Succeed())
+			})
+
+			It("doesn't queue a frame if nothing was written since the last boundary", func() {
+				// the beginning of the stream is a message boundary
+				Expect(str.EndMessage()).To(Succeed())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().queueControlFrame(&wire.PRMessageBoundaryFrame{StreamID: streamID, Offset: 6})
+				Expect(str.EndMessage()).To(Succeed())
+				Expect(str.EndMessage()).To(Succeed())
+			})
+
+			It("doesn't allow ending a message after the stream has been closed", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				str.Close()
+				Expect(str.EndMessage()).To(MatchError("end of message on closed stream 1337"))
+			})
+		})
+
 		Context("closing", func() {
 			It("doesn't allow writes after it has been closed", func() {
 				mockSender.EXPECT().onHasStreamData(streamID)
@@ -641,6 +1507,36 @@ var _ = Describe("Send Stream", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("says who closed the connection, and why", func() {
+				str.closeForShutdown(&qerr.ApplicationError{
+					Remote:       true,
+					ErrorCode:    0x42,
+					ErrorMessage: "restarting",
+				})
+				_, err := strWithTimeout.Write([]byte("foo"))
+				var closeErr *ConnectionClosedError
+				Expect(errors.As(err, &closeErr)).To(BeTrue())
+				Expect(closeErr.StreamID).To(Equal(streamID))
+				Expect(closeErr.Remote).To(BeTrue())
+				Expect(closeErr.IsApplicationError).To(BeTrue())
+				Expect(closeErr.ErrorCode).To(BeEquivalentTo(0x42))
+				Expect(closeErr.ReasonPhrase).To(Equal("restarting"))
+				var appErr *ApplicationError
+				Expect(errors.As(err, &appErr)).To(BeTrue())
+			})
+
+			It("returns a net.Error when the connection timed out", func() {
+				str.closeForShutdown(&qerr.IdleTimeoutError{})
+				_, err := strWithTimeout.Write([]byte("foo"))
+				var closeErr *ConnectionClosedError
+				Expect(errors.As(err, &closeErr)).To(BeTrue())
+				Expect(closeErr.Remote).To(BeFalse())
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+				Expect(errors.Is(err, net.ErrClosed)).To(BeTrue())
+			})
+
 			It("cancels the context", func() {
 				Expect(str.Context().Done()).ToNot(BeClosed())
 				str.closeForShutdown(testErr)
@@ -892,6 +1788,66 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("idle timeout", func() {
+		prPolicy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+
+		BeforeEach(func() {
+			str.setPRPolicyChain(newPRPolicyChain(PRConfig{IdleStreamTimeout: scaleDuration(50 * time.Millisecond), IdleStreamErrorCode: 1337}))
+		})
+
+		// stop the idle timer, such that it doesn't fire after the test
+		AfterEach(func() { str.closeForShutdown(nil) })
+
+		It("cancels a PR stream if no data is written", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+			Expect(err).ToNot(HaveOccurred())
+			canceled := make(chan struct{})
+			gomock.InOrder(
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: streamID, ErrorCode: 1337}),
+				mockSender.EXPECT().onStreamCompleted(streamID),
+				mockSender.EXPECT().onIdleStreamCanceled(streamID).Do(func(protocol.StreamID) { close(canceled) }),
+			)
+			Eventually(canceled, scaleDuration(500*time.Millisecond)).Should(BeClosed())
+			_, err = str.Write([]byte("foo"))
+			Expect(err).To(MatchError(ErrIdleStreamTimeout))
+			Expect(str.Context().Done()).To(BeClosed())
+		})
+
+		It("doesn't cancel streams that are written to", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			for i := 0; i < 5; i++ {
+				_, err := str.WriteWithPolicy([]byte("foo"), prPolicy)
+				Expect(err).ToNot(HaveOccurred())
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+			}
+			Expect(str.Context().Done()).ToNot(BeClosed())
+		})
+
+		It("doesn't cancel reliable streams", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.WriteWithPolicy([]byte("foobar"), PRPolicy{})
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(str.Context().Done(), scaleDuration(100*time.Millisecond)).ShouldNot(BeClosed())
+		})
+
+		It("disables the timeout for a single stream", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+			Expect(err).ToNot(HaveOccurred())
+			str.SetIdleTimeout(0)
+			Consistently(str.Context().Done(), scaleDuration(100*time.Millisecond)).ShouldNot(BeClosed())
+		})
+
+		It("doesn't cancel the stream after it was closed", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := str.WriteWithPolicy([]byte("foobar"), prPolicy)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			time.Sleep(scaleDuration(100 * time.Millisecond))
+		})
+	})
+
 	Context("retransmissions", func() {
 		It("queues and retrieves frames", func() {
 			str.numOutstandingFrames = 1
@@ -994,7 +1950,7 @@ var _ = Describe("Send Stream", func() {
 			str.CancelWrite(9876)
 			// don't EXPECT any calls to onHasStreamData
 			f.OnLost(f.Frame)
-			Expect(str.retransmissionQueue).To(BeEmpty())
+			Expect(str.retransmissionQueue.Empty()).To(BeTrue())
 		})
 	})
 
@@ -1156,4 +2112,507 @@ var _ = Describe("Send Stream", func() {
 			Expect(received).To(Equal(data))
 		})
 	})
+
+	Context("partial reliability", func() {
+		BeforeEach(func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			mockSender.EXPECT().onPRPolicyChanged(streamID, gomock.Any(), gomock.Any()).AnyTimes()
+		})
+
+		writeAndPop := func(data string, policy PRPolicy) *ackhandler.Frame {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.WriteWithPolicy([]byte(data), policy)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			ExpectWithOffset(1, frame).ToNot(BeNil())
+			return frame
+		}
+
+		// ackPRAckNotifies simulates the packing and the acknowledgement of all queued PRAckNotify frames
+		ackPRAckNotifies := func() {
+			for _, f := range str.prAckNotifies.PopAll() {
+				str.prAckNotifies.OnAcked(f)
+			}
+		}
+
+		closeAndPop := func() *ackhandler.Frame {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			ExpectWithOffset(1, str.Close()).To(Succeed())
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			ExpectWithOffset(1, frame).ToNot(BeNil())
+			return frame
+		}
+
+		Context("handling lost frames", func() {
+			It("retransmits lost STREAM frames", func() {
+				frame := writeAndPop("foobar", PRPolicy{})
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
+				mockSender.EXPECT().onHasStreamData(streamID)
+				frame.OnLost(frame.Frame)
+				Expect(str.prAckNotifies.HasData()).To(BeFalse())
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			})
+
+			DescribeTable("retransmitting lost PRSTREAM frames",
+				func(policy PRPolicy) {
+					frame := writeAndPop("foobar", policy)
+					Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+					mockSender.EXPECT().onHasStreamData(streamID)
+					frame.OnLost(frame.Frame)
+					Expect(str.prAckNotifies.HasData()).To(BeFalse())
+					Expect(str.skippedBytes).To(BeZero())
+					// the retransmission is sent with the policy of the data
+					frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).ToNot(BeNil())
+					Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+					f := frame.Frame.(*wire.PRStreamFrame)
+					Expect(f.Offset).To(BeZero())
+					Expect(f.Data).To(Equal([]byte("foobar")))
+					Expect(f.PTDA).To(Equal(policy.PTDA))
+					Expect(f.PtdaC).To(Equal(policy.Value))
+				},
+				Entry("probability, if the retransmission probability is 100%", PRPolicy{PTDA: PTDAProbability, Value: maxPRProbability}),
+				Entry("times", PRPolicy{PTDA: PTDATimes, Value: 3}),
+				Entry("deadline", PRPolicy{PTDA: PTDADeadline, Value: 100}),
+				Entry("priority", PRPolicy{PTDA: PTDAPriority, Value: 1}),
+			)
+
+			It("skips lost PRSTREAM frames with the probability policy", func() {
+				str.rand = mrand.New(mrand.NewSource(1))
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDAProbability, Value: 0})
+				// The PRAckNotify frame is sent with the next packet, it doesn't need to be scheduled.
+				frame.OnLost(frame.Frame)
+				Expect(str.skippedBytes).To(BeEquivalentTo(6))
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				f := str.prAckNotifies.frames[0]
+				Expect(f.StreamID).To(Equal(streamID))
+				Expect(f.Offset).To(BeZero())
+				Expect(f.PRDataLen).To(BeEquivalentTo(6))
+				Expect(f.PTDA).To(Equal(PTDAProbability))
+				Expect(f.P).To(BeTrue())
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+			})
+
+			It("tells the peer to skip data with the abandon policy when sending it", func() {
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDAAbandon})
+				Expect(str.skippedBytes).To(BeEquivalentTo(6))
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				Expect(str.prAckNotifies.frames[0].PTDA).To(Equal(PTDAAbandon))
+				Expect(str.prAckNotifies.frames[0].PRDataLen).To(BeEquivalentTo(6))
+				// the data is already skipped, losing it doesn't queue another PRAckNotify frame
+				frame.OnLost(frame.Frame)
+				Expect(str.skippedBytes).To(BeEquivalentTo(6))
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+			})
+
+			It("cancels the PRAckNotify frame when data with the abandon policy is acknowledged", func() {
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDAAbandon})
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				frame.OnAcked(frame.Frame)
+				Expect(str.prAckNotifies.frames).To(BeEmpty())
+				Expect(str.skippedBytes).To(BeZero())
+				Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 6}}))
+			})
+
+			It("doesn't count data with the abandon policy as skipped when it is acknowledged after the PRAckNotify frame was sent", func() {
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDAAbandon})
+				Expect(str.prAckNotifies.PopAll()).To(HaveLen(1)) // the packet packer dequeued the frame
+				frame.OnAcked(frame.Frame)
+				Expect(str.skippedBytes).To(BeZero())
+				Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 6}}))
+			})
+
+			It("skips lost PRSTREAM frames with the deadline policy after the deadline", func() {
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDADeadline, Value: 100})
+				Expect(str.deadlines).To(HaveLen(1))
+				str.deadlines[0].deadline = time.Now().Add(-time.Millisecond)
+				frame.OnLost(frame.Frame)
+				Expect(str.skippedBytes).To(BeEquivalentTo(6))
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				Expect(str.prAckNotifies.frames[0].D).To(BeTrue())
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+			})
+
+			It("skips lost PRSTREAM frames with the deadline policy if the retransmission would arrive too late", func() {
+				str.policyChain.SetAckMetrics(ackhandler.AckMetrics{SmoothedAckDelay: 20 * time.Millisecond}, 200*time.Millisecond)
+				Expect(str.policyChain.DeadlineMargin()).To(Equal(120 * time.Millisecond))
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDADeadline, Value: 100})
+				frame.OnLost(frame.Frame)
+				Expect(str.skippedBytes).To(BeEquivalentTo(6))
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+			})
+
+			It("forgets the deadline of data once it is acknowledged", func() {
+				frame := writeAndPop("foo", PRPolicy{PTDA: PTDADeadline, Value: 100})
+				writeAndPop("bar", PRPolicy{PTDA: PTDADeadline, Value: 200})
+				Expect(str.deadlines).To(HaveLen(2))
+				frame.OnAcked(frame.Frame)
+				Expect(str.deadlines).To(HaveLen(1))
+				Expect(str.deadlines[0].offset).To(BeEquivalentTo(6))
+			})
+
+			It("skips PR data declared lost by the loss detection, with an aggressive packet threshold", func() {
+				sph, _ := ackhandler.NewAckHandler(0, protocol.InitialPacketSizeIPv4, &utils.RTTStats{}, true, protocol.MaxAmplificationFactor, 1, protocol.DefaultTimeThreshold, false, protocol.PerspectiveServer, nil, utils.DefaultLogger, protocol.Version1)
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				Expect(frame.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+				now := time.Now()
+				sph.SentPacket(&ackhandler.Packet{
+					PacketNumber:    1,
+					Frames:          []ackhandler.Frame{*frame},
+					Length:          100,
+					EncryptionLevel: protocol.Encryption1RTT,
+					SendTime:        now,
+				})
+				sph.SentPacket(&ackhandler.Packet{
+					PacketNumber:    2,
+					Frames:          []ackhandler.Frame{{Frame: &wire.PingFrame{}}},
+					Length:          100,
+					EncryptionLevel: protocol.Encryption1RTT,
+					SendTime:        now,
+				})
+				_, err := sph.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}, protocol.Encryption1RTT, now)
+				Expect(err).ToNot(HaveOccurred())
+				// the frame was declared lost, and the PR policy skipped the data instead of queueing a retransmission
+				Expect(str.skippedBytes).To(BeEquivalentTo(6))
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				Expect(str.retransmissionQueue.Empty()).To(BeTrue())
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+			})
+
+			It("forgets the policies of data once it is acknowledged", func() {
+				first := writeAndPop("foo", PRPolicy{PTDA: PTDADeadline, Value: 100})
+				second := writeAndPop("bar", PRPolicy{PTDA: PTDAAbandon})
+				writeAndPop("baz", PRPolicy{})
+				Expect(str.policyRanges).To(HaveLen(3))
+				Expect(str.usesPR()).To(BeTrue())
+				first.OnAcked(first.Frame)
+				Expect(str.policyRanges).To(HaveLen(2))
+				Expect(str.usesPR()).To(BeTrue())
+				Expect(str.policyAt(3)).To(Equal(PRPolicy{PTDA: PTDAAbandon}))
+				second.OnAcked(second.Frame)
+				// the policy of the data written next is kept
+				Expect(str.policyRanges).To(Equal([]prPolicyRange{{offset: 6, policy: PRPolicy{}}}))
+				Expect(str.usesPR()).To(BeFalse())
+			})
+
+			It("skips the FIN along with the data", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				_, err := str.WriteWithPolicy([]byte("foobar"), PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame.(*wire.PRStreamFrame).Fin).To(BeTrue())
+				frame.OnLost(frame.Frame)
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				Expect(str.prAckNotifies.frames[0].Fin).To(BeTrue())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				ackPRAckNotifies()
+			})
+
+			Context("splitting retransmissions", func() {
+				// the size of a packet that fits the first 3 bytes of the retransmission of "foobar"
+				maxBytes := wire.MaxPRStreamFrameOverhead + (&wire.StreamFrame{
+					StreamID:       streamID,
+					Data:           []byte("foo"),
+					DataLenPresent: true,
+				}).Length(protocol.VersionWhatever)
+
+				loseAndSplit := func(frame *ackhandler.Frame) (*ackhandler.Frame, *ackhandler.Frame) {
+					mockSender.EXPECT().onHasStreamData(streamID)
+					frame.OnLost(frame.Frame)
+					first, hasMoreData := str.popStreamFrame(maxBytes)
+					ExpectWithOffset(1, hasMoreData).To(BeTrue())
+					ExpectWithOffset(1, first).ToNot(BeNil())
+					second, _ := str.popStreamFrame(protocol.MaxByteCount)
+					ExpectWithOffset(1, second).ToNot(BeNil())
+					return first, second
+				}
+
+				It("sends all parts with the policy of the data", func() {
+					policy := PRPolicy{PTDA: PTDATimes, Value: 3}
+					first, second := loseAndSplit(writeAndPop("foobar", policy))
+					Expect(first.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+					Expect(second.Frame).To(BeAssignableToTypeOf(&wire.PRStreamFrame{}))
+					for i, f := range []*wire.PRStreamFrame{first.Frame.(*wire.PRStreamFrame), second.Frame.(*wire.PRStreamFrame)} {
+						Expect(f.Offset).To(BeEquivalentTo(3 * i))
+						Expect(f.PTDA).To(Equal(PTDATimes))
+						Expect(f.T).To(BeTrue())
+						Expect(f.PtdaC).To(BeEquivalentTo(3))
+					}
+					Expect(first.Frame.(*wire.PRStreamFrame).Data).To(Equal([]byte("foo")))
+					Expect(second.Frame.(*wire.PRStreamFrame).Data).To(Equal([]byte("bar")))
+				})
+
+				It("retransmits data with the times policy at most as often as the policy allows", func() {
+					first, second := loseAndSplit(writeAndPop("foobar", PRPolicy{PTDA: PTDATimes, Value: 1}))
+					first.OnLost(first.Frame)
+					second.OnLost(second.Frame)
+					Expect(str.skippedBytes).To(BeEquivalentTo(6))
+					Expect(str.prAckNotifies.frames).To(HaveLen(1))
+					Expect(str.prAckNotifies.frames[0].Offset).To(BeZero())
+					Expect(str.prAckNotifies.frames[0].PRDataLen).To(BeEquivalentTo(6))
+					Expect(str.retransmissions).To(BeEmpty())
+				})
+
+				It("counts the retransmissions of every part", func() {
+					first, second := loseAndSplit(writeAndPop("foobar", PRPolicy{PTDA: PTDATimes, Value: 2}))
+					second.OnAcked(second.Frame)
+					Expect(str.retransmissions).To(Equal([]retransmittedRange{{start: 0, end: 3, count: 1}}))
+					mockSender.EXPECT().onHasStreamData(streamID)
+					first.OnLost(first.Frame)
+					Expect(str.retransmissions).To(Equal([]retransmittedRange{{start: 0, end: 3, count: 2}}))
+					first, _ = str.popStreamFrame(protocol.MaxByteCount)
+					Expect(first).ToNot(BeNil())
+					first.OnLost(first.Frame)
+					Expect(str.skippedBytes).To(BeEquivalentTo(3))
+					Expect(str.retransmissions).To(BeEmpty())
+				})
+
+				It("splits the retransmission counts", func() {
+					str.mutex.Lock()
+					defer str.mutex.Unlock()
+					str.setRetransmissions(0, 10, 1)
+					str.setRetransmissions(10, 20, 2)
+					str.forgetRetransmissions(5, 15)
+					Expect(str.retransmissions).To(Equal([]retransmittedRange{{start: 0, end: 5, count: 1}, {start: 15, end: 20, count: 2}}))
+					str.forgetRetransmissions(16, 18)
+					Expect(str.retransmissions).To(Equal([]retransmittedRange{
+						{start: 0, end: 5, count: 1},
+						{start: 15, end: 16, count: 2},
+						{start: 18, end: 20, count: 2},
+					}))
+					Expect(str.retransmissionsAt(4)).To(BeEquivalentTo(1))
+					Expect(str.retransmissionsAt(5)).To(BeZero())
+					Expect(str.retransmissionsAt(15)).To(BeEquivalentTo(2))
+					Expect(str.retransmissionsAt(17)).To(BeZero())
+					str.setRetransmissions(3, 19, 3)
+					Expect(str.retransmissions).To(Equal([]retransmittedRange{
+						{start: 0, end: 3, count: 1},
+						{start: 3, end: 19, count: 3},
+						{start: 19, end: 20, count: 2},
+					}))
+				})
+			})
+		})
+
+		Context("determining when a stream is completed", func() {
+			It("completes a stream with STREAM and PRSTREAM frames once all data was acknowledged or skipped", func() {
+				reliable := writeAndPop("foo", PRPolicy{})
+				abandoned := writeAndPop("bar", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				retransmitted := writeAndPop("baz", PRPolicy{PTDA: PTDATimes, Value: 3})
+				fin := closeAndPop()
+				Expect(fin.Frame.(*wire.PRStreamFrame).Fin).To(BeTrue())
+
+				reliable.OnAcked(reliable.Frame)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				abandoned.OnLost(abandoned.Frame)
+				retransmitted.OnLost(retransmitted.Frame)
+				fin.OnAcked(fin.Frame)
+				// the retransmission is still outstanding
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				Expect(frame.Frame.(*wire.PRStreamFrame).Data).To(Equal([]byte("baz")))
+				frame.OnAcked(frame.Frame)
+				// the PRAckNotify frame for the skipped data is still outstanding
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				ackPRAckNotifies()
+				Expect(str.numOutstandingFrames).To(BeZero())
+				Expect(str.skippedBytes).To(BeEquivalentTo(3))
+				Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 3}, {Start: 6, End: 9}}))
+			})
+
+			It("completes a stream when the last outstanding frame is skipped", func() {
+				reliable := writeAndPop("foo", PRPolicy{})
+				abandoned := writeAndPop("bar", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				fin := closeAndPop()
+				reliable.OnAcked(reliable.Frame)
+				fin.OnAcked(fin.Frame)
+				abandoned.OnLost(abandoned.Frame)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				ackPRAckNotifies()
+			})
+
+			It("waits for lost PRAckNotify frames to be retransmitted", func() {
+				abandoned := writeAndPop("foobar", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				fin := closeAndPop()
+				fin.OnAcked(fin.Frame)
+				abandoned.OnLost(abandoned.Frame)
+				nf := str.prAckNotifies.PopAll()
+				Expect(nf).To(HaveLen(1))
+				str.prAckNotifies.OnLost(nf[0])
+				Expect(str.prAckNotifies.frames).To(HaveLen(1))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				ackPRAckNotifies()
+			})
+
+			It("completes a stream when its queued PRAckNotify frame is canceled", func() {
+				abandoned := writeAndPop("foobar", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				fin := closeAndPop()
+				fin.OnAcked(fin.Frame)
+				abandoned.OnLost(abandoned.Frame)
+				mockSender.EXPECT().onSpuriousPRConversion(streamID, protocol.ByteCount(0), protocol.ByteCount(6), true)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				abandoned.OnAckedAfterLoss()
+			})
+
+			It("doesn't complete a stream twice when skipped data is acknowledged after all", func() {
+				abandoned := writeAndPop("foobar", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				fin := closeAndPop()
+				fin.OnAcked(fin.Frame)
+				abandoned.OnLost(abandoned.Frame)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				ackPRAckNotifies()
+				mockSender.EXPECT().onSpuriousPRConversion(streamID, protocol.ByteCount(0), protocol.ByteCount(6), false)
+				abandoned.OnAckedAfterLoss()
+			})
+		})
+
+		Context("canceling writing", func() {
+			It("drops the stream's queued PRAckNotify frames", func() {
+				other := newPRAckNotifyFrame(streamID+4, 0, 10, false, PRPolicy{PTDA: PTDAAbandon})
+				str.prAckNotifies.Add(other)
+				// the PRAckNotify frame is queued when the data is sent
+				writeAndPop("foobar", PRPolicy{PTDA: PTDAAbandon})
+				Expect(str.prAckNotifies.frames).To(HaveLen(2))
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				// the PRAckNotify frames of other streams sharing the queue are kept
+				Expect(str.prAckNotifies.frames).To(Equal([]*wire.PRAckNotifyFrame{other}))
+			})
+
+			It("drops all PR state when receiving a STOP_SENDING frame", func() {
+				skipped1 := writeAndPop("foo", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				retransmitted := writeAndPop("bar", PRPolicy{PTDA: PTDATimes, Value: 3})
+				skipped2 := writeAndPop("baz", PRPolicy{PTDA: PTDATimes})
+				mockSender.EXPECT().onHasStreamData(streamID)
+				skipped1.OnLost(skipped1.Frame)
+				// the PRAckNotify frame is sent, but not acknowledged yet
+				inFlight := str.prAckNotifies.PopAll()
+				Expect(inFlight).To(HaveLen(1))
+				skipped2.OnLost(skipped2.Frame)
+				retransmitted.OnLost(retransmitted.Frame)
+				Expect(str.prAckNotifies.HasData()).To(BeTrue())
+				Expect(str.retransmissions).ToNot(BeEmpty())
+
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 9,
+					ErrorCode: 101,
+				})
+				// the stream completes without waiting for the PRAckNotify frame in flight
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 101})
+				Expect(str.prAckNotifies.HasData()).To(BeFalse())
+				Expect(str.retransmissionQueue.Empty()).To(BeTrue())
+				Expect(str.retransmissions).To(BeEmpty())
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+				// the PRAckNotify frame in flight is not retransmitted
+				str.prAckNotifies.OnLost(inFlight[0])
+				Expect(str.prAckNotifies.HasData()).To(BeFalse())
+			})
+
+			It("doesn't skip frames lost after the stream was canceled", func() {
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDATimes}) // never retransmitted
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				frame.OnLost(frame.Frame)
+				Expect(str.prAckNotifies.HasData()).To(BeFalse())
+				Expect(str.skippedBytes).To(BeZero())
+				// a late acknowledgement is ignored
+				frame.OnAckedAfterLoss()
+			})
+
+			It("doesn't retransmit frames lost after the stream was canceled", func() {
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDATimes, Value: 3})
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				frame.OnLost(frame.Frame)
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+			})
+
+			It("ignores PRSTREAM frames acknowledged after the stream was canceled", func() {
+				frame := writeAndPop("foobar", PRPolicy{PTDA: PTDAAbandon})
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				frame.OnAcked(frame.Frame)
+				Expect(str.numOutstandingFrames).To(BeZero())
+				Expect(str.AckedRanges()).To(BeEmpty())
+			})
+		})
+	})
 })
+
+func BenchmarkSendStreamOnLost(b *testing.B) {
+	mockCtrl := gomock.NewController(b)
+	sender := NewMockStreamSender(mockCtrl)
+	sender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
+	str := newSendStream(42, sender, nil, utils.DefaultLogger, protocol.VersionWhatever)
+	str.numOutstandingFrames = int64(b.N)
+	data := make([]byte, 500)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Heavy loss: every frame is lost, half of them are retransmitted.
+		str.prQueueRetransmission(&wire.PRStreamFrame{
+			StreamID: 42,
+			Offset:   protocol.ByteCount(i) * 1000,
+			Data:     data,
+			PTDA:     PTDAProbability,
+			PtdaC:    5000,
+		})
+		if i%100 == 0 {
+			str.prAckNotifies.PopAll()
+		}
+		str.retransmissionQueue.Clear()
+	}
+}
+
+// BenchmarkSendStreamPRFrames sends a video stream at a high frame rate:
+// Every frame is a small PRSTREAM frame, which is acknowledged right away.
+// Besides the allocations, it reports the GC pause time, since the metadata of the frames is allocated for every packet.
+func BenchmarkSendStreamPRFrames(b *testing.B) {
+	mockCtrl := gomock.NewController(b)
+	sender := NewMockStreamSender(mockCtrl)
+	sender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
+	rttStats := &utils.RTTStats{}
+	cfc := flowcontrol.NewConnectionFlowController(protocol.MaxByteCount, protocol.MaxByteCount, nil, func(protocol.ByteCount) bool { return false }, nil, rttStats, utils.DefaultLogger)
+	cfc.UpdateSendWindow(protocol.MaxByteCount)
+	fc := flowcontrol.NewStreamFlowController(42, cfc, protocol.MaxByteCount, protocol.MaxByteCount, protocol.MaxByteCount, func(protocol.StreamID) {}, nil, rttStats, utils.DefaultLogger)
+	str := newSendStream(42, sender, fc, utils.DefaultLogger, protocol.VersionWhatever)
+	str.setPolicy(0, PRPolicy{PTDA: PTDAAbandon})
+	data := make([]byte, 200)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		str.mutex.Lock()
+		str.dataForWriting = data
+		str.mutex.Unlock()
+		f, _ := str.popStreamFrame(protocol.MaxPacketBufferSize)
+		frame := *f
+		ackhandler.PutFrame(f)
+		frame.OnAcked(frame.Frame)
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+	b.ReportMetric(float64(after.NumGC-before.NumGC), "gcs")
+}
@@ -38,6 +38,8 @@ type unknownPacketHandler interface {
 
 type packetHandlerManager interface {
 	AddWithConnID(protocol.ConnectionID, protocol.ConnectionID, func() packetHandler) bool
+	// Capabilities returns the capabilities of the underlying connection.
+	Capabilities() connCapabilities
 	Destroy() error
 	connRunner
 	SetServer(unknownPacketHandler)
@@ -104,6 +106,16 @@ type baseServer struct {
 	connQueue    chan quicConn
 	connQueueLen int32 // to be used as an atomic
 
+	// the number of handshakes in progress with clients whose address wasn't validated, see Config.MaxUnvalidatedHandshakes
+	unvalidatedHandshakes int32 // to be used as an atomic
+
+	draining     chan struct{} // closed as soon as CloseGracefully is called
+	drainingOnce sync.Once
+
+	connsMutex   sync.Mutex
+	numConns     int           // the number of connections that are not closed yet
+	connsChanged chan struct{} // signaled every time a connection is closed
+
 	logger utils.Logger
 }
 
@@ -120,15 +132,35 @@ func (s *earlyServer) Accept(ctx context.Context) (EarlyConnection, error) {
 	return s.baseServer.accept(ctx)
 }
 
+func (s *earlyServer) Serve(handler func(EarlyConnection)) error {
+	for {
+		conn, err := s.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go handler(conn)
+	}
+}
+
 // ListenAddr creates a QUIC server listening on a given address.
 // The tls.Config must not be nil and must contain a certificate configuration.
 // The quic.Config may be nil, in that case the default values will be used.
 func ListenAddr(addr string, tlsConf *tls.Config, config *Config) (Listener, error) {
+	if config != nil && config.ReusePortSockets > 1 {
+		return listenAddrReusePort(addr, tlsConf, config, false)
+	}
 	return listenAddr(addr, tlsConf, config, false)
 }
 
 // ListenAddrEarly works like ListenAddr, but it returns connections before the handshake completes.
 func ListenAddrEarly(addr string, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
+	if config != nil && config.ReusePortSockets > 1 {
+		s, err := listenAddrReusePort(addr, tlsConf, config, true)
+		if err != nil {
+			return nil, err
+		}
+		return &earlyShardedServer{s}, nil
+	}
 	s, err := listenAddr(addr, tlsConf, config, true)
 	if err != nil {
 		return nil, err
@@ -181,15 +213,13 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
-	if err := validateConfig(config); err != nil {
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 	config = populateServerConfig(config)  //如果config为空（第一次就是空），则初始化为其中的各个默认值
 	
-	for _, v := range config.Versions {
-		if !protocol.IsValidVersion(v) {
-			return nil, fmt.Errorf("%s is not a valid QUIC version", v)
-		}
+	if config.DisableOOB {
+		conn = newPortableConn(conn)
 	}
 	connHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDGenerator.ConnectionIDLen(), config.StatelessResetKey, config.Tracer)
 	if err != nil {
@@ -199,10 +229,25 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 	if err != nil {
 		return nil, err
 	}
+	return newServer(conn, connHandler, tlsConf, config, tokenGenerator, acceptEarly)
+}
+
+// newServer creates a server for a populated config.
+func newServer(
+	conn net.PacketConn,
+	connHandler packetHandlerManager,
+	tlsConf *tls.Config,
+	config *Config,
+	tokenGenerator *handshake.TokenGenerator,
+	acceptEarly bool,
+) (*baseServer, error) {
 	c, err := wrapConn(conn)
 	if err != nil {
 		return nil, err
 	}
+	if !c.capabilities().DF {
+		config.DisablePathMTUDiscovery = true
+	}
 	s := &baseServer{
 		conn:             c,
 		tlsConf:          tlsConf,
@@ -212,6 +257,8 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		connQueue:        make(chan quicConn),
 		errorChan:        make(chan struct{}),
 		running:          make(chan struct{}),
+		draining:         make(chan struct{}),
+		connsChanged:     make(chan struct{}, 1),
 		receivedPackets:  make(chan *receivedPacket, protocol.MaxServerUnprocessedPackets),
 		newConn:          newConnection,  //将baseServer s的newConn函数定义为newConnection函数，即客户端用于创建quicConn的函数
 		logger:           utils.DefaultLogger.WithPrefix("server"),
@@ -248,6 +295,67 @@ func (s *baseServer) Accept(ctx context.Context) (Connection, error) {
 	return s.accept(ctx)
 }
 
+func (s *baseServer) Serve(handler func(Connection)) error {
+	for {
+		conn, err := s.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go handler(conn)
+	}
+}
+
+// Import resumes a connection that was handed off by another process, see Connection.Handoff.
+func (s *baseServer) Import(h *ConnectionHandoff, code StreamErrorCode) (Connection, error) {
+	s.mutex.Lock()
+	closed := s.closed
+	s.mutex.Unlock()
+	if closed || s.isDraining() {
+		return nil, ErrServerClosed
+	}
+	if len(h.SourceConnectionIDs) == 0 {
+		return nil, errors.New("quic: handoff without connection IDs")
+	}
+	connID, err := parseHandoffConnectionID(h.SourceConnectionIDs[0].ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	remoteAddr, err := net.ResolveUDPAddr("udp", h.RemoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	tracingID := nextConnTracingID()
+	var tracer logging.ConnectionTracer
+	if s.config.Tracer != nil {
+		tracer = s.config.Tracer.TracerForConnection(
+			context.WithValue(context.Background(), ConnectionTracingKey, tracingID),
+			protocol.PerspectiveServer,
+			connID,
+		)
+	}
+	conn, err := newImportedConnection(
+		newSendConn(s.conn, remoteAddr, nil, s.config.PacketMarking, s.config.PRPacketMarking),
+		s.connHandler,
+		h,
+		code,
+		s.config,
+		tracer,
+		tracingID,
+		s.logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+	go conn.run()
+	s.addConn()
+	go func() {
+		// keep track of the connection until it is closed, see CloseGracefully
+		<-conn.Context().Done()
+		s.removeConn()
+	}()
+	return conn, nil
+}
+
 func (s *baseServer) accept(ctx context.Context) (quicConn, error) {
 	select {
 	case <-ctx.Done():
@@ -257,6 +365,63 @@ func (s *baseServer) accept(ctx context.Context) (quicConn, error) {
 		return conn, nil
 	case <-s.errorChan:
 		return nil, s.serverError
+	case <-s.draining:
+		return nil, ErrServerClosed
+	}
+}
+
+// CloseGracefully stops accepting new connections, and waits until all existing connections are closed.
+// New connection attempts are refused, and connections that didn't complete the handshake
+// before CloseGracefully was called are closed once they complete it.
+// If ctx is done before all connections are closed, the remaining connections are closed,
+// and ctx.Err() is returned.
+// In both cases, the server is closed as if Close was called.
+func (s *baseServer) CloseGracefully(ctx context.Context) error {
+	s.drainingOnce.Do(func() { close(s.draining) })
+	s.logger.Debugf("Closing server gracefully. Waiting for %d connections to close.", s.numOpenConns())
+	for s.numOpenConns() > 0 {
+		select {
+		case <-s.connsChanged:
+		case <-s.errorChan:
+			return s.Close()
+		case <-ctx.Done():
+			if err := s.Close(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		}
+	}
+	return s.Close()
+}
+
+func (s *baseServer) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *baseServer) numOpenConns() int {
+	s.connsMutex.Lock()
+	defer s.connsMutex.Unlock()
+	return s.numConns
+}
+
+func (s *baseServer) addConn() {
+	s.connsMutex.Lock()
+	s.numConns++
+	s.connsMutex.Unlock()
+}
+
+func (s *baseServer) removeConn() {
+	s.connsMutex.Lock()
+	s.numConns--
+	s.connsMutex.Unlock()
+	select {
+	case s.connsChanged <- struct{}{}:
+	default:
 	}
 }
 
@@ -396,7 +561,11 @@ func (s *baseServer) validateToken(token *handshake.Token, addr net.Addr) bool {
 	if token == nil {
 		return false
 	}
-	if !token.ValidateRemoteAddr(addr) {
+	if token.IsRetryToken {
+		if !token.ValidateRemoteAddr(addr) {
+			return false
+		}
+	} else if !token.ValidateRemoteAddrPrefix(addr, s.config.TokenIPv4PrefixLen, s.config.TokenIPv6PrefixLen) {
 		return false
 	}
 	if !token.IsRetryToken && time.Since(token.SentTime) > s.config.MaxTokenAge {
@@ -455,7 +624,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			return nil
 		}
 	}
-	if token == nil && s.config.RequireAddressValidation(p.remoteAddr) {
+	if token == nil && (s.config.RequireAddressValidation(p.remoteAddr) || s.tooManyUnvalidatedHandshakes()) {
 		go func() {
 			defer p.buffer.Release()
 			if err := s.sendRetry(p.remoteAddr, hdr, p.info); err != nil {
@@ -465,6 +634,17 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		return nil
 	}
 
+	if s.isDraining() {
+		s.logger.Debugf("Rejecting new connection. Server is closing.")
+		go func() {
+			defer p.buffer.Release()
+			if err := s.sendConnectionRefused(p.remoteAddr, hdr, p.info); err != nil {
+				s.logger.Debugf("Error rejecting connection: %s", err)
+			}
+		}()
+		return nil
+	}
+
 	//服务器连接数满后拒绝连接接入
 	if queueLen := atomic.LoadInt32(&s.connQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
 		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, protocol.MaxAcceptQueueSize)
@@ -499,7 +679,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			)
 		}
 		conn = s.newConn(  //实际是调用了newConnection函数创建客户端的quicConn
-			newSendConn(s.conn, p.remoteAddr, p.info),
+			newSendConn(s.conn, p.remoteAddr, p.info, s.config.PacketMarking, s.config.PRPacketMarking),
 			s.connHandler,
 			origDestConnID,  
 			retrySrcConnID,  
@@ -523,7 +703,12 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		return nil
 	}
 	go conn.run()
+	s.addConn()
 	go s.handleNewConn(conn)
+	if !clientAddrIsValid && s.config.MaxUnvalidatedHandshakes > 0 {
+		atomic.AddInt32(&s.unvalidatedHandshakes, 1)
+		go s.trackUnvalidatedHandshake(conn)
+	}
 	if conn == nil {
 		p.buffer.Release()
 		return nil
@@ -531,8 +716,35 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 	return nil
 }
 
+func (s *baseServer) tooManyUnvalidatedHandshakes() bool {
+	if s.config.MaxUnvalidatedHandshakes <= 0 {
+		return false
+	}
+	if n := atomic.LoadInt32(&s.unvalidatedHandshakes); n >= int32(s.config.MaxUnvalidatedHandshakes) {
+		s.logger.Debugf("Sending a Retry. %d handshakes with unvalidated clients in progress (max %d)", n, s.config.MaxUnvalidatedHandshakes)
+		return true
+	}
+	return false
+}
+
+// trackUnvalidatedHandshake decrements the number of unvalidated handshakes
+// when the handshake of conn completes (or fails).
+func (s *baseServer) trackUnvalidatedHandshake(conn quicConn) {
+	select {
+	case <-conn.HandshakeComplete().Done():
+	case <-conn.Context().Done():
+	}
+	atomic.AddInt32(&s.unvalidatedHandshakes, -1)
+}
+
 func (s *baseServer) handleNewConn(conn quicConn) {
 	connCtx := conn.Context()
+	defer func() {
+		// keep track of the connection until it is closed, see CloseGracefully
+		<-connCtx.Done()
+		s.removeConn()
+	}()
+
 	if s.acceptEarlyConns {
 		// wait until the early connection is ready (or the handshake fails)
 		select {
@@ -553,6 +765,10 @@ func (s *baseServer) handleNewConn(conn quicConn) {
 	select {
 	case s.connQueue <- conn:
 		// blocks until the connection is accepted
+	case <-s.draining:
+		atomic.AddInt32(&s.connQueueLen, -1)
+		// the connection won't be accepted anymore
+		conn.shutdown()
 	case <-connCtx.Done():
 		atomic.AddInt32(&s.connQueueLen, -1)
 		// don't pass connections that were already closed to Accept()
@@ -166,6 +166,15 @@ var _ = Describe("Server", func() {
 		Expect(ln.Close()).To(Succeed())
 	})
 
+	It("disables Path MTU Discovery if the DF bit can't be set", func() {
+		// the DF bit is only set on UDP sockets
+		ln, err := Listen(conn, tlsConf, &Config{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ln.(*baseServer).config.DisablePathMTUDiscovery).To(BeTrue())
+		// stop the listener
+		Expect(ln.Close()).To(Succeed())
+	})
+
 	It("errors if given an invalid address", func() {
 		addr := "127.0.0.1"
 		_, err := ListenAddr(addr, tlsConf, &Config{})
@@ -451,6 +460,52 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("replies with a Retry packet, if there are too many unvalidated handshakes", func() {
+				serv.config.MaxUnvalidatedHandshakes = 2
+				atomic.StoreInt32(&serv.unvalidatedHandshakes, 2)
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ParseConnectionID([]byte{5, 4, 3, 2, 1}),
+					DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}),
+					Version:          protocol.VersionTLS,
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				raddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				packet.remoteAddr = raddr
+				tracer.EXPECT().SentPacket(packet.remoteAddr, gomock.Any(), gomock.Any(), nil)
+				done := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), raddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(done)
+					replyHdr := parseHeader(b)
+					Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+					Expect(replyHdr.Token).ToNot(BeEmpty())
+					return len(b), nil
+				})
+				serv.handlePacket(packet)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("tracks the number of unvalidated handshakes", func() {
+				serv.config.MaxUnvalidatedHandshakes = 2
+				atomic.StoreInt32(&serv.unvalidatedHandshakes, 2)
+				Expect(serv.tooManyUnvalidatedHandshakes()).To(BeTrue())
+				c := NewMockQuicConn(mockCtrl)
+				ctx, cancel := context.WithCancel(context.Background())
+				c.EXPECT().HandshakeComplete().Return(ctx)
+				c.EXPECT().Context().Return(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					serv.trackUnvalidatedHandshake(c)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				cancel()
+				Eventually(done).Should(BeClosed())
+				Expect(atomic.LoadInt32(&serv.unvalidatedHandshakes)).To(BeEquivalentTo(1))
+				Expect(serv.tooManyUnvalidatedHandshakes()).To(BeFalse())
+			})
+
 			It("creates a connection, if no token is required", func() {
 				hdr := &wire.Header{
 					IsLongHeader:     true,
@@ -762,7 +817,7 @@ var _ = Describe("Server", func() {
 				Expect(err).ToNot(HaveOccurred())
 				data, err := opener.Open(nil, b[extHdr.ParsedLen():], extHdr.PacketNumber, b[:extHdr.ParsedLen()])
 				Expect(err).ToNot(HaveOccurred())
-				_, f, err := wire.NewFrameParser(false, origHdr.Version).ParseNext(data, protocol.EncryptionInitial)
+				_, f, err := wire.NewFrameParser(false, true, origHdr.Version).ParseNext(data, protocol.EncryptionInitial)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(f).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
 				ccf := f.(*wire.ConnectionCloseFrame)
@@ -892,6 +947,25 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("accepts non-retry tokens from addresses in the same network, if a prefix length is set", func() {
+				serv.config.TokenIPv4PrefixLen = 24
+				raddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+				newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 4242}
+				tokenEnc, err := serv.tokenGenerator.NewToken(raddr)
+				Expect(err).ToNot(HaveOccurred())
+				token, err := serv.tokenGenerator.DecodeToken(tokenEnc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(serv.validateToken(token, newAddr)).To(BeTrue())
+				Expect(serv.validateToken(token, &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 1337})).To(BeFalse())
+				// retry tokens are always bound to the exact IP address
+				tokenEnc, err = serv.tokenGenerator.NewRetryToken(raddr, protocol.ConnectionID{}, protocol.ConnectionID{})
+				Expect(err).ToNot(HaveOccurred())
+				token, err = serv.tokenGenerator.DecodeToken(tokenEnc)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(serv.validateToken(token, raddr)).To(BeTrue())
+				Expect(serv.validateToken(token, newAddr)).To(BeFalse())
+			})
+
 			It("sends an INVALID_TOKEN error, if an expired non-retry token is received", func() {
 				serv.config.RequireAddressValidation = func(net.Addr) bool { return true }
 				serv.config.MaxTokenAge = time.Millisecond
@@ -970,6 +1044,22 @@ var _ = Describe("Server", func() {
 				}
 			})
 
+			It("serves connections", func() {
+				conn := NewMockQuicConn(mockCtrl)
+				conns := make(chan Connection, 1)
+				errChan := make(chan error, 1)
+				go func() {
+					defer GinkgoRecover()
+					errChan <- serv.Serve(func(c Connection) { conns <- c })
+				}()
+				atomic.AddInt32(&serv.connQueueLen, 1)
+				serv.connQueue <- conn
+				Eventually(conns).Should(Receive(Equal(conn)))
+				testErr := errors.New("test err")
+				serv.setCloseError(testErr)
+				Eventually(errChan).Should(Receive(MatchError(testErr)))
+			})
+
 			It("returns when the context is canceled", func() {
 				ctx, cancel := context.WithCancel(context.Background())
 				done := make(chan struct{})
@@ -1038,6 +1128,140 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 		})
+
+		Context("closing gracefully", func() {
+			// newConn creates a new connection using the handshake context and the connection context
+			newConn := func(handshakeCtx, connCtx context.Context) *MockQuicConn {
+				conn := NewMockQuicConn(mockCtrl)
+				serv.newConn = func(
+					_ sendConn,
+					runner connRunner,
+					_ protocol.ConnectionID,
+					_ *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ uint64,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicConn {
+					conn.EXPECT().handlePacket(gomock.Any())
+					conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+					conn.EXPECT().run()
+					conn.EXPECT().Context().Return(connCtx)
+					return conn
+				}
+				phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				})
+				tracer.EXPECT().TracerForConnection(gomock.Any(), protocol.PerspectiveServer, gomock.Any())
+				serv.handleInitialImpl(
+					&receivedPacket{buffer: getPacketBuffer()},
+					&wire.Header{DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8})},
+				)
+				return conn
+			}
+
+			It("closes the server right away, if there are no connections", func() {
+				phm.EXPECT().CloseServer()
+				Expect(serv.CloseGracefully(context.Background())).To(Succeed())
+				_, err := serv.Accept(context.Background())
+				Expect(err).To(MatchError(ErrServerClosed))
+			})
+
+			It("waits until all connections are closed", func() {
+				handshakeCtx, completeHandshake := context.WithCancel(context.Background())
+				completeHandshake()
+				connCtx, closeConn := context.WithCancel(context.Background())
+				conn := newConn(handshakeCtx, connCtx)
+				c, err := serv.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(c).To(Equal(conn))
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(serv.CloseGracefully(context.Background())).To(Succeed())
+				}()
+				Eventually(serv.draining).Should(BeClosed())
+				_, err = serv.Accept(context.Background())
+				Expect(err).To(MatchError(ErrServerClosed))
+				Consistently(done).ShouldNot(BeClosed())
+				phm.EXPECT().CloseServer()
+				closeConn()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("closes connections that complete the handshake after it was called", func() {
+				handshakeCtx, completeHandshake := context.WithCancel(context.Background())
+				connCtx, closeConn := context.WithCancel(context.Background())
+				conn := newConn(handshakeCtx, connCtx)
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(serv.CloseGracefully(context.Background())).To(Succeed())
+				}()
+				Eventually(serv.draining).Should(BeClosed())
+				Consistently(done).ShouldNot(BeClosed())
+				phm.EXPECT().CloseServer()
+				conn.EXPECT().shutdown().Do(closeConn)
+				completeHandshake()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("refuses new connections", func() {
+				serv.addConn()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(serv.CloseGracefully(context.Background())).To(Succeed())
+				}()
+				Eventually(serv.draining).Should(BeClosed())
+
+				p := getInitialWithRandomDestConnID()
+				hdr, _, _, err := wire.ParsePacket(p.data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				written := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(written)
+					rejectHdr := parseHeader(b)
+					Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+					Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+					Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+					return len(b), nil
+				})
+				serv.handlePacket(p)
+				Eventually(written).Should(BeClosed())
+
+				phm.EXPECT().CloseServer()
+				serv.removeConn()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("closes the server when the context is done", func() {
+				serv.addConn()
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+				defer cancel()
+				phm.EXPECT().CloseServer()
+				Expect(serv.CloseGracefully(ctx)).To(MatchError(context.DeadlineExceeded))
+				_, err := serv.Accept(context.Background())
+				Expect(err).To(MatchError(ErrServerClosed))
+			})
+		})
 	})
 
 	Context("server accepting connections that haven't completed the handshake", func() {
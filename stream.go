@@ -9,6 +9,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -27,6 +28,12 @@ type streamSender interface {
 	onHasStreamData(protocol.StreamID)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
+	// called when a stream is canceled because no data was written for the idle timeout
+	onIdleStreamCanceled(protocol.StreamID)
+	// called when a PR STREAM frame that was skipped after being declared lost is acknowledged after all
+	onSpuriousPRConversion(id protocol.StreamID, offset, length protocol.ByteCount, notifyCanceled bool)
+	// called when the PR policy changes after data was written on the stream, see wire.PRStreamPolicyFrame
+	onPRPolicyChanged(id protocol.StreamID, offset protocol.ByteCount, policy PRPolicy)
 }
 
 // Each of the both stream halves gets its own uniStreamSender.
@@ -55,13 +62,20 @@ type streamI interface {
 	closeForShutdown(error)
 	// for receiving
 	handleStreamFrame(*wire.StreamFrame) error
+	handleExpiringStreamFrame(*wire.StreamFrame, time.Time) error
+	handleSkippedStreamFrame(*wire.StreamFrame) error
+	handleMessageBoundaryFrame(*wire.PRMessageBoundaryFrame) error
+	evictExpiredData(now time.Time) time.Time
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	getWindowUpdate() protocol.ByteCount
 	// for sending
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
+	rateLimiter() *tokenBucket
+	nextDeadline() time.Time
 	updateSendWindow(protocol.ByteCount)
+	handoffState(*HandoffStream)
 }
 
 var (
@@ -90,6 +104,7 @@ var _ Stream = &stream{}
 func newStream(streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *stream {
 	s := &stream{sender: sender, version: version}
@@ -102,7 +117,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, version)
+	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, logger, version)
 	senderForReceiveStream := &uniStreamSender{
 		streamSender: sender,
 		onStreamCompletedImpl: func() {
@@ -112,7 +127,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController, version)
+	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController, logger, version)
 	return s
 }
 
@@ -140,6 +155,11 @@ func (s *stream) closeForShutdown(err error) {
 	s.receiveStream.closeForShutdown(err)
 }
 
+func (s *stream) handoffState(h *HandoffStream) {
+	s.sendStream.handoffState(h)
+	s.receiveStream.handoffState(h)
+}
+
 // checkIfCompleted is called from the uniStreamSender, when one of the stream halves is completed.
 // It makes sure that the onStreamCompleted callback is only called if both receive and send side have completed.
 func (s *stream) checkIfCompleted() {
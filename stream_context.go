@@ -0,0 +1,99 @@
+package quic
+
+import (
+	"context"
+	"time"
+)
+
+// OpenStreamWithContext opens a new bidirectional stream, blocking until it can be opened (see Connection.OpenStreamSync),
+// and binds the stream to ctx, see BindStreamContext.
+func OpenStreamWithContext(ctx context.Context, conn Connection, code StreamErrorCode) (Stream, error) {
+	str, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BindStreamContext(ctx, str, code), nil
+}
+
+// OpenUniStreamWithContext opens a new unidirectional stream, blocking until it can be opened (see Connection.OpenUniStreamSync),
+// and binds the stream to ctx, see BindSendStreamContext.
+func OpenUniStreamWithContext(ctx context.Context, conn Connection, code StreamErrorCode) (SendStream, error) {
+	str, err := conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BindSendStreamContext(ctx, str, code), nil
+}
+
+// BindStreamContext binds the lifetime of a stream to ctx.
+// When ctx is done, both directions of the stream are canceled using code (see Stream.CancelWrite and Stream.CancelRead).
+// To release the resources associated with the binding, ctx must be canceled once the stream is not used any more.
+//
+// If ctx has a deadline, it is propagated into the PR policy:
+// Write on the returned stream uses the deadline policy (PTDADeadline), such that lost data is not retransmitted
+// once the deadline has passed. If the policy of the stream is a deadline policy that expires earlier, it is used instead.
+// Data written using WriteWithPolicy uses the policy passed in.
+func BindStreamContext(ctx context.Context, str Stream, code StreamErrorCode) Stream {
+	if ctx.Done() != nil {
+		go func() {
+			<-ctx.Done()
+			str.CancelWrite(code)
+			str.CancelRead(code)
+		}()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		return &contextStream{Stream: str, deadline: deadline}
+	}
+	return str
+}
+
+// BindSendStreamContext binds the lifetime of a unidirectional stream to ctx.
+// When ctx is done, the stream is canceled using code (see SendStream.CancelWrite).
+// The deadline of ctx is propagated into the PR policy, see BindStreamContext.
+func BindSendStreamContext(ctx context.Context, str SendStream, code StreamErrorCode) SendStream {
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				str.CancelWrite(code)
+			case <-str.Context().Done():
+			}
+		}()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		return &contextSendStream{SendStream: str, deadline: deadline}
+	}
+	return str
+}
+
+type contextStream struct {
+	Stream
+	deadline time.Time
+}
+
+func (s *contextStream) Write(p []byte) (int, error) {
+	return s.Stream.WriteWithPolicy(p, deadlinePolicy(s.Stream, s.deadline))
+}
+
+type contextSendStream struct {
+	SendStream
+	deadline time.Time
+}
+
+func (s *contextSendStream) Write(p []byte) (int, error) {
+	return s.SendStream.WriteWithPolicy(p, deadlinePolicy(s.SendStream, s.deadline))
+}
+
+// deadlinePolicy returns the deadline policy for data written now, such that it expires at the deadline.
+// If the stream uses a deadline policy that expires earlier, that policy is returned.
+func deadlinePolicy(str SendStream, deadline time.Time) PRPolicy {
+	// A deadline of 0ms is invalid. Once the deadline has passed, the stream is canceled anyway.
+	ms := uint64(1)
+	if remaining := time.Until(deadline).Milliseconds(); remaining > 1 {
+		ms = uint64(remaining)
+	}
+	if policy, _ := str.EffectivePRPolicy(); policy.PTDA == PTDADeadline && policy.Value < ms {
+		return policy
+	}
+	return PRPolicy{PTDA: PTDADeadline, Value: ms}
+}
@@ -0,0 +1,97 @@
+package quic
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Context", func() {
+	It("cancels a stream when the context is canceled", func() {
+		str := NewMockStreamI(mockCtrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		Expect(BindStreamContext(ctx, str, 1337)).To(Equal(str))
+		canceled := make(chan struct{})
+		str.EXPECT().CancelWrite(StreamErrorCode(1337))
+		str.EXPECT().CancelRead(StreamErrorCode(1337)).Do(func(StreamErrorCode) { close(canceled) })
+		cancel()
+		Eventually(canceled).Should(BeClosed())
+	})
+
+	It("cancels a unidirectional stream when the context is canceled", func() {
+		str := NewMockSendStreamI(mockCtrl)
+		str.EXPECT().Context().Return(context.Background())
+		ctx, cancel := context.WithCancel(context.Background())
+		BindSendStreamContext(ctx, str, 1337)
+		canceled := make(chan struct{})
+		str.EXPECT().CancelWrite(StreamErrorCode(1337)).Do(func(StreamErrorCode) { close(canceled) })
+		cancel()
+		Eventually(canceled).Should(BeClosed())
+	})
+
+	It("doesn't cancel a unidirectional stream that was closed", func() {
+		str := NewMockSendStreamI(mockCtrl)
+		strCtx, strCancel := context.WithCancel(context.Background())
+		str.EXPECT().Context().Return(strCtx)
+		ctx, cancel := context.WithCancel(context.Background())
+		BindSendStreamContext(ctx, str, 1337)
+		strCancel()
+		time.Sleep(scaleDuration(10 * time.Millisecond)) // wait for the go routine to return
+		cancel()
+		time.Sleep(scaleDuration(10 * time.Millisecond)) // make sure CancelWrite is not called
+	})
+
+	It("uses the deadline of the context for Write", func() {
+		str := NewMockStreamI(mockCtrl)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		s := BindStreamContext(ctx, str, 1337)
+		str.EXPECT().EffectivePRPolicy().Return(PRPolicy{PTDA: PTDATimes, Value: 3}, PRPolicySourceStream)
+		str.EXPECT().WriteWithPolicy([]byte("foobar"), gomock.Any()).DoAndReturn(func(_ []byte, policy PRPolicy) (int, error) {
+			Expect(policy.PTDA).To(Equal(PTDADeadline))
+			Expect(policy.Value).To(BeNumerically("~", time.Hour.Milliseconds(), 1000))
+			return 6, nil
+		})
+		n, err := s.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(6))
+		// WriteWithPolicy is not changed
+		str.EXPECT().WriteWithPolicy([]byte("foo"), PRPolicy{}).Return(3, nil)
+		_, err = s.WriteWithPolicy([]byte("foo"), PRPolicy{})
+		Expect(err).ToNot(HaveOccurred())
+		str.EXPECT().CancelWrite(gomock.Any()).AnyTimes()
+		str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+	})
+
+	It("uses the deadline policy of the stream if it expires earlier", func() {
+		str := NewMockSendStreamI(mockCtrl)
+		str.EXPECT().Context().Return(context.Background()).AnyTimes()
+		str.EXPECT().CancelWrite(gomock.Any()).AnyTimes()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		s := BindSendStreamContext(ctx, str, 1337)
+		policy := PRPolicy{PTDA: PTDADeadline, Value: 100}
+		str.EXPECT().EffectivePRPolicy().Return(policy, PRPolicySourceConnection)
+		str.EXPECT().WriteWithPolicy([]byte("foobar"), policy).Return(6, nil)
+		_, err := s.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("opens streams", func() {
+		conn := NewMockQuicConn(mockCtrl)
+		str := NewMockStreamI(mockCtrl)
+		conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+		s, err := OpenStreamWithContext(context.Background(), conn, 1337)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+		testErr := errors.New("test err")
+		conn.EXPECT().OpenUniStreamSync(context.Background()).Return(nil, testErr)
+		_, err = OpenUniStreamWithContext(context.Background(), conn, 1337)
+		Expect(err).To(MatchError(testErr))
+	})
+})
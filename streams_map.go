@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -50,9 +53,11 @@ type streamsMap struct {
 
 	maxIncomingBidiStreams uint64
 	maxIncomingUniStreams  uint64
+	prPolicies             *prPolicyChain
 
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
+	logger            utils.Logger
 
 	mutex               sync.Mutex
 	outgoingBidiStreams *outgoingStreamsMap[streamI]
@@ -69,7 +74,9 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	prPolicies *prPolicyChain,
 	perspective protocol.Perspective,
+	logger utils.Logger,
 	version protocol.VersionNumber,
 ) streamManager {
 	m := &streamsMap{
@@ -77,7 +84,9 @@ func newStreamsMap(
 		newFlowController:      newFlowController,
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
+		prPolicies:             prPolicies,
 		sender:                 sender,
+		logger:                 logger,
 		version:                version,
 	}
 	m.initMaps()
@@ -89,7 +98,9 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
+			str := newStream(id, m.sender, m.newFlowController(id), m.logger, m.version)
+			str.setPRPolicyChain(m.prPolicies)
+			return str
 		},
 		m.sender.queueControlFrame,
 	)
@@ -97,7 +108,9 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite())
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
+			str := newStream(id, m.sender, m.newFlowController(id), m.logger, m.version)
+			str.setPRPolicyChain(m.prPolicies)
+			return str
 		},
 		m.maxIncomingBidiStreams,
 		m.sender.queueControlFrame,
@@ -106,7 +119,9 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeUni,
 		func(num protocol.StreamNum) sendStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
-			return newSendStream(id, m.sender, m.newFlowController(id), m.version)
+			str := newSendStream(id, m.sender, m.newFlowController(id), m.logger, m.version)
+			str.setPRPolicyChain(m.prPolicies)
+			return str
 		},
 		m.sender.queueControlFrame,
 	)
@@ -114,7 +129,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeUni,
 		func(num protocol.StreamNum) receiveStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective.Opposite())
-			return newReceiveStream(id, m.sender, m.newFlowController(id), m.version)
+			return newReceiveStream(id, m.sender, m.newFlowController(id), m.logger, m.version)
 		},
 		m.maxIncomingUniStreams,
 		m.sender.queueControlFrame,
@@ -287,6 +302,36 @@ func (m *streamsMap) HandleMaxStreamsFrame(f *wire.MaxStreamsFrame) {
 	}
 }
 
+func (m *streamsMap) RejectIncomingStream(id protocol.StreamID) error {
+	if id.InitiatedBy() == m.perspective {
+		return fmt.Errorf("tried to reject outgoing stream %d", id)
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	num := id.StreamNum()
+	switch id.Type() {
+	case protocol.StreamTypeUni:
+		return convertStreamError(m.incomingUniStreams.RejectStream(num), protocol.StreamTypeUni, id.InitiatedBy())
+	case protocol.StreamTypeBidi:
+		return convertStreamError(m.incomingBidiStreams.RejectStream(num), protocol.StreamTypeBidi, id.InitiatedBy())
+	}
+	panic("")
+}
+
+func (m *streamsMap) SetMaxIncomingStreams(n uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.maxIncomingBidiStreams = n
+	m.incomingBidiStreams.SetMaxNumStreams(n)
+}
+
+func (m *streamsMap) SetMaxIncomingUniStreams(n uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.maxIncomingUniStreams = n
+	m.incomingUniStreams.SetMaxNumStreams(n)
+}
+
 func (m *streamsMap) UpdateLimits(p *wire.TransportParameters) {
 	m.outgoingBidiStreams.UpdateSendWindow(p.InitialMaxStreamDataBidiRemote)
 	m.outgoingBidiStreams.SetMaxStream(p.MaxBidiStreamNum)
@@ -294,6 +339,46 @@ func (m *streamsMap) UpdateLimits(p *wire.TransportParameters) {
 	m.outgoingUniStreams.SetMaxStream(p.MaxUniStreamNum)
 }
 
+func (m *streamsMap) EvictExpiredData(now time.Time) time.Time {
+	m.mutex.Lock()
+	outgoingBidi := m.outgoingBidiStreams
+	incomingBidi := m.incomingBidiStreams
+	incomingUni := m.incomingUniStreams
+	m.mutex.Unlock()
+
+	next := outgoingBidi.EvictExpiredData(now)
+	next = utils.MinNonZeroTime(next, incomingBidi.EvictExpiredData(now))
+	return utils.MinNonZeroTime(next, incomingUni.EvictExpiredData(now))
+}
+
+// HandoffState records the stream limits and the state of the open streams, see Connection.Handoff.
+func (m *streamsMap) HandoffState(h *ConnectionHandoff) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var opened, maxStream protocol.StreamNum
+	h.Streams, opened, maxStream = m.outgoingBidiStreams.HandoffState(h.Streams)
+	h.OpenedBidiStreams, h.MaxBidiStreams = uint64(opened), uint64(maxStream)
+	h.Streams, opened, maxStream = m.outgoingUniStreams.HandoffState(h.Streams)
+	h.OpenedUniStreams, h.MaxUniStreams = uint64(opened), uint64(maxStream)
+	h.Streams, opened, maxStream = m.incomingBidiStreams.HandoffState(h.Streams)
+	h.PeerOpenedBidiStreams, h.PeerMaxBidiStreams = uint64(opened), uint64(maxStream)
+	h.Streams, opened, maxStream = m.incomingUniStreams.HandoffState(h.Streams)
+	h.PeerOpenedUniStreams, h.PeerMaxUniStreams = uint64(opened), uint64(maxStream)
+	sort.Slice(h.Streams, func(i, j int) bool { return h.Streams[i].StreamID < h.Streams[j].StreamID })
+}
+
+// Resume restores the stream limits of a connection that was handed off by another process.
+func (m *streamsMap) Resume(h *ConnectionHandoff) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.outgoingBidiStreams.Resume(protocol.StreamNum(h.OpenedBidiStreams), protocol.StreamNum(h.MaxBidiStreams))
+	m.outgoingUniStreams.Resume(protocol.StreamNum(h.OpenedUniStreams), protocol.StreamNum(h.MaxUniStreams))
+	m.incomingBidiStreams.Resume(protocol.StreamNum(h.PeerOpenedBidiStreams), protocol.StreamNum(h.PeerMaxBidiStreams))
+	m.incomingUniStreams.Resume(protocol.StreamNum(h.PeerOpenedUniStreams), protocol.StreamNum(h.PeerMaxUniStreams))
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
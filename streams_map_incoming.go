@@ -3,13 +3,16 @@ package quic
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
 type incomingStream interface {
 	closeForShutdown(error)
+	handoffState(*HandoffStream)
 }
 
 // When a stream is deleted before it was accepted, we can't delete it from the map immediately.
@@ -17,6 +20,7 @@ type incomingStream interface {
 type incomingStreamEntry[T incomingStream] struct {
 	stream       T
 	shouldDelete bool
+	rejected     bool // rejected streams are skipped by AcceptStream
 }
 
 type incomingStreamsMap[T incomingStream] struct {
@@ -75,6 +79,16 @@ func (m *incomingStreamsMap[T]) AcceptStream(ctx context.Context) (T, error) {
 		}
 		var ok bool
 		entry, ok = m.streams[num]
+		if ok && entry.rejected {
+			m.nextStreamToAccept++
+			if entry.shouldDelete {
+				if err := m.deleteStream(num); err != nil {
+					m.mutex.Unlock()
+					return *new(T), err
+				}
+			}
+			continue
+		}
 		if ok {
 			break
 		}
@@ -138,6 +152,28 @@ func (m *incomingStreamsMap[T]) GetOrOpenStream(num protocol.StreamNum) (T, erro
 	return entry.stream, nil
 }
 
+// RejectStream marks a stream as rejected. It won't be returned by AcceptStream.
+func (m *incomingStreamsMap[T]) RejectStream(num protocol.StreamNum) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok := m.streams[num]
+	if !ok || num < m.nextStreamToAccept {
+		return streamError{
+			message: "tried to reject unknown or already accepted incoming stream %d",
+			nums:    []protocol.StreamNum{num},
+		}
+	}
+	entry.rejected = true
+	m.streams[num] = entry
+	// wake up AcceptStream, so that it skips this stream
+	select {
+	case m.newStreamChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
 func (m *incomingStreamsMap[T]) DeleteStream(num protocol.StreamNum) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -170,18 +206,80 @@ func (m *incomingStreamsMap[T]) deleteStream(num protocol.StreamNum) error {
 
 	delete(m.streams, num)
 	// queue a MAX_STREAM_ID frame, giving the peer the option to open a new stream
-	if m.maxNumStreams > uint64(len(m.streams)) {
-		maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
-		// Never send a value larger than protocol.MaxStreamCount.
-		if maxStream <= protocol.MaxStreamCount {
-			m.maxStream = maxStream
-			m.queueMaxStreamID(&wire.MaxStreamsFrame{
-				Type:         m.streamType,
-				MaxStreamNum: m.maxStream,
-			})
+	m.maybeQueueMaxStreams()
+	return nil
+}
+
+// SetMaxNumStreams sets the maximum number of concurrent streams.
+// The stream limit granted to the peer can't be reduced.
+// When the maximum is lowered, it only takes effect as streams are closed.
+func (m *incomingStreamsMap[T]) SetMaxNumStreams(n uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.maxNumStreams = n
+	m.maybeQueueMaxStreams()
+}
+
+// must be called after locking the mutex
+func (m *incomingStreamsMap[T]) maybeQueueMaxStreams() {
+	if m.maxNumStreams <= uint64(len(m.streams)) {
+		return
+	}
+	maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
+	// Never send a value larger than protocol.MaxStreamCount.
+	// The limit can't be reduced, which can happen if the maximum number of streams was lowered.
+	if maxStream <= m.maxStream || maxStream > protocol.MaxStreamCount {
+		return
+	}
+	m.maxStream = maxStream
+	m.queueMaxStreamID(&wire.MaxStreamsFrame{
+		Type:         m.streamType,
+		MaxStreamNum: m.maxStream,
+	})
+}
+
+// EvictExpiredData evicts expired data from the receive buffers of the streams.
+// It returns the time when the next data expires.
+func (m *incomingStreamsMap[T]) EvictExpiredData(now time.Time) time.Time {
+	var next time.Time
+	m.mutex.RLock()
+	for _, entry := range m.streams {
+		if str, ok := any(entry.stream).(receiveStreamI); ok {
+			next = utils.MinNonZeroTime(next, str.evictExpiredData(now))
 		}
 	}
-	return nil
+	m.mutex.RUnlock()
+	return next
+}
+
+// HandoffState appends the state of the open streams to streams, see Connection.Handoff.
+// It also returns the number of streams the peer opened, and the number of streams the peer is allowed to open.
+func (m *incomingStreamsMap[T]) HandoffState(streams []HandoffStream) ([]HandoffStream, protocol.StreamNum, protocol.StreamNum) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, entry := range m.streams {
+		if entry.shouldDelete {
+			continue
+		}
+		var h HandoffStream
+		entry.stream.handoffState(&h)
+		streams = append(streams, h)
+	}
+	return streams, m.nextStreamToOpen - 1, m.maxStream
+}
+
+// Resume restores the stream limits of a connection that was handed off by another process.
+// The streams themselves aren't resumed, so the peer is allowed to open new streams in their place.
+func (m *incomingStreamsMap[T]) Resume(opened, maxStream protocol.StreamNum) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.nextStreamToOpen = opened + 1
+	m.nextStreamToAccept = opened + 1
+	m.maxStream = utils.Max(m.maxStream, maxStream)
+	m.maybeQueueMaxStreams()
 }
 
 func (m *incomingStreamsMap[T]) CloseWithError(err error) {
@@ -31,6 +31,10 @@ func (s *mockGenericStream) updateSendWindow(limit protocol.ByteCount) {
 	s.sendWindow = limit
 }
 
+func (s *mockGenericStream) handoffState(h *HandoffStream) {
+	h.StreamID = StreamID(s.num)
+}
+
 var _ = Describe("Streams Map (incoming)", func() {
 	var (
 		m              *incomingStreamsMap[*mockGenericStream]
@@ -44,7 +48,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 	checkFrameSerialization := func(f wire.Frame) {
 		b, err := f.Append(nil, protocol.VersionTLS)
 		ExpectWithOffset(1, err).ToNot(HaveOccurred())
-		_, frame, err := wire.NewFrameParser(false, protocol.VersionTLS).ParseNext(b, protocol.Encryption1RTT)
+		_, frame, err := wire.NewFrameParser(false, true, protocol.VersionTLS).ParseNext(b, protocol.Encryption1RTT)
 		ExpectWithOffset(1, err).ToNot(HaveOccurred())
 		Expect(f).To(Equal(frame))
 	}
@@ -211,6 +215,38 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(str).ToNot(BeNil())
 	})
 
+	It("skips rejected streams in AcceptStream", func() {
+		_, err := m.GetOrOpenStream(3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.RejectStream(1)).To(Succeed())
+		Expect(m.RejectStream(2)).To(Succeed())
+		str, err := m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.num).To(Equal(protocol.StreamNum(3)))
+	})
+
+	It("deletes rejected streams when skipping them", func() {
+		_, err := m.GetOrOpenStream(2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.RejectStream(1)).To(Succeed())
+		Expect(m.DeleteStream(1)).To(Succeed())
+		mockSender.EXPECT().queueControlFrame(gomock.Any())
+		str, err := m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.num).To(Equal(protocol.StreamNum(2)))
+		Expect(m.streams).ToNot(HaveKey(protocol.StreamNum(1)))
+	})
+
+	It("errors when rejecting a stream that was already accepted", func() {
+		_, err := m.GetOrOpenStream(1)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		err = m.RejectStream(1)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(streamError).TestError()).To(MatchError("tried to reject unknown or already accepted incoming stream 1"))
+	})
+
 	It("errors when deleting a non-existing stream", func() {
 		err := m.DeleteStream(1337)
 		Expect(err).To(HaveOccurred())
@@ -240,6 +276,50 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(m.DeleteStream(4)).To(Succeed())
 	})
 
+	Context("changing the maximum number of streams", func() {
+		It("sends a MAX_STREAMS frame when the maximum is raised", func() {
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				msf := f.(*wire.MaxStreamsFrame)
+				Expect(msf.Type).To(BeEquivalentTo(streamType))
+				Expect(msf.MaxStreamNum).To(Equal(protocol.StreamNum(10)))
+			})
+			m.SetMaxNumStreams(10)
+			_, err = m.GetOrOpenStream(10)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("doesn't reduce the limit granted to the peer when the maximum is lowered", func() {
+			m.SetMaxNumStreams(2)
+			_, err := m.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 5; i++ {
+				_, err := m.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			// 5 streams are open, so deleting streams doesn't allow the peer to open new streams
+			for i := 1; i <= 3; i++ {
+				Expect(m.DeleteStream(protocol.StreamNum(i))).To(Succeed())
+			}
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(6)))
+			})
+			Expect(m.DeleteStream(4)).To(Succeed())
+		})
+
+		It("doesn't allow any new streams when the maximum is set to 0", func() {
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			m.SetMaxNumStreams(0)
+			Expect(m.DeleteStream(1)).To(Succeed())
+			_, err = m.GetOrOpenStream(6)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Context("using high stream limits", func() {
 		BeforeEach(func() { maxNumStreams = uint64(protocol.MaxStreamCount) - 2 })
 
@@ -3,14 +3,17 @@ package quic
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
 type outgoingStream interface {
 	updateSendWindow(protocol.ByteCount)
 	closeForShutdown(error)
+	handoffState(*HandoffStream)
 }
 
 type outgoingStreamsMap[T outgoingStream] struct {
@@ -195,6 +198,44 @@ func (m *outgoingStreamsMap[T]) UpdateSendWindow(limit protocol.ByteCount) {
 	m.mutex.Unlock()
 }
 
+// EvictExpiredData evicts expired data from the receive buffers of bidirectional streams.
+// It returns the time when the next data expires.
+func (m *outgoingStreamsMap[T]) EvictExpiredData(now time.Time) time.Time {
+	var next time.Time
+	m.mutex.RLock()
+	for _, str := range m.streams {
+		if rstr, ok := any(str).(receiveStreamI); ok {
+			next = utils.MinNonZeroTime(next, rstr.evictExpiredData(now))
+		}
+	}
+	m.mutex.RUnlock()
+	return next
+}
+
+// HandoffState appends the state of the open streams to streams, see Connection.Handoff.
+// It also returns the number of streams opened, and the number of streams the peer allows us to open.
+func (m *outgoingStreamsMap[T]) HandoffState(streams []HandoffStream) ([]HandoffStream, protocol.StreamNum, protocol.StreamNum) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, str := range m.streams {
+		var h HandoffStream
+		str.handoffState(&h)
+		streams = append(streams, h)
+	}
+	return streams, m.nextStream - 1, m.maxStream
+}
+
+// Resume restores the stream limits of a connection that was handed off by another process.
+// The streams themselves aren't resumed.
+func (m *outgoingStreamsMap[T]) Resume(opened, maxStream protocol.StreamNum) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.nextStream = opened + 1
+	m.maxStream = utils.Max(m.maxStream, maxStream)
+}
+
 // unblockOpenSync unblocks the next OpenStreamSync go-routine to open a new stream
 func (m *outgoingStreamsMap[T]) unblockOpenSync() {
 	if len(m.openQueue) == 0 {
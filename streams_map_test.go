@@ -12,6 +12,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -88,7 +89,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, newPRPolicyChain(PRConfig{}), perspective, utils.DefaultLogger, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -430,6 +431,39 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			Context("handing off", func() {
+				It("resumes the stream limits", func() {
+					mockSender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
+					m.UpdateLimits(&wire.TransportParameters{MaxBidiStreamNum: 10, MaxUniStreamNum: 20})
+					outgoing, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					incoming, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					h := &ConnectionHandoff{}
+					m.HandoffState(h)
+					Expect(h.Streams).To(HaveLen(2))
+					Expect(h.Streams[0].StreamID).To(Equal(utils.Min(outgoing.StreamID(), incoming.StreamID())))
+					Expect(h.OpenedBidiStreams).To(BeEquivalentTo(1))
+					Expect(h.MaxBidiStreams).To(BeEquivalentTo(10))
+					Expect(h.OpenedUniStreams).To(BeZero())
+					Expect(h.MaxUniStreams).To(BeEquivalentTo(20))
+					Expect(h.PeerOpenedBidiStreams).To(BeZero())
+					Expect(h.PeerMaxBidiStreams).To(BeEquivalentTo(MaxBidiStreamNum))
+					Expect(h.PeerOpenedUniStreams).To(BeEquivalentTo(1))
+					Expect(h.PeerMaxUniStreams).To(BeEquivalentTo(MaxUniStreamNum))
+
+					resumed := newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, newPRPolicyChain(PRConfig{}), perspective, utils.DefaultLogger, protocol.VersionWhatever).(*streamsMap)
+					resumed.Resume(h)
+					str, err := resumed.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream + 4))
+					// the streams opened by the peer before the handoff are not resumed
+					rstr, err := resumed.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rstr).To(BeNil())
+				})
+			})
+
 			Context("sending MAX_STREAMS frames", func() {
 				It("sends a MAX_STREAMS frame for bidirectional streams", func() {
 					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
@@ -454,6 +488,28 @@ var _ = Describe("Streams Map", func() {
 					})
 					Expect(m.DeleteStream(ids.firstIncomingUniStream)).To(Succeed())
 				})
+
+				It("sends a MAX_STREAMS frame when the maximum number of bidirectional streams is raised", func() {
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{
+						Type:         protocol.StreamTypeBidi,
+						MaxStreamNum: MaxBidiStreamNum + 10,
+					})
+					m.SetMaxIncomingStreams(MaxBidiStreamNum + 10)
+				})
+
+				It("sends a MAX_STREAMS frame when the maximum number of unidirectional streams is raised", func() {
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{
+						Type:         protocol.StreamTypeUni,
+						MaxStreamNum: MaxUniStreamNum + 10,
+					})
+					m.SetMaxIncomingUniStreams(MaxUniStreamNum + 10)
+				})
+
+				It("uses the new maximum after resetting the maps for 0-RTT", func() {
+					m.SetMaxIncomingStreams(MaxBidiStreamNum - 10)
+					m.ResetFor0RTT()
+					Expect(m.incomingBidiStreams.maxNumStreams).To(BeEquivalentTo(MaxBidiStreamNum - 10))
+				})
 			})
 
 			It("closes", func() {
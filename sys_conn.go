@@ -21,7 +21,15 @@ type OOBCapablePacketConn interface {
 
 var _ OOBCapablePacketConn = &net.UDPConn{}
 
+// connCapabilities are the capabilities of a rawConn.
+type connCapabilities struct {
+	// DF is true if the Don't Fragment (DF) bit is set on the packets sent.
+	// Path MTU Discovery only works if packets are not fragmented.
+	DF bool
+}
+
 func wrapConn(pc net.PacketConn) (rawConn, error) {
+	var supportsDF bool
 	conn, ok := pc.(interface {
 		SyscallConn() (syscall.RawConn, error)
 	})
@@ -33,31 +41,72 @@ func wrapConn(pc net.PacketConn) (rawConn, error) {
 
 		if _, ok := pc.LocalAddr().(*net.UDPAddr); ok {
 			// Only set DF on sockets that we expect to be able to handle that configuration.
-			err = setDF(rawConn)
-			if err != nil {
-				return nil, err
+			if err := setDF(rawConn); err != nil {
+				// Packets might get fragmented, but the connection still works.
+				utils.DefaultLogger.Infof("%s. Disabling Path MTU Discovery.", err)
+			} else {
+				supportsDF = true
 			}
 		}
 	}
 	c, ok := pc.(OOBCapablePacketConn)
 	if !ok {
 		utils.DefaultLogger.Infof("PacketConn is not a net.UDPConn. Disabling optimizations possible on UDP connections.")
-		return &basicConn{PacketConn: pc}, nil
+		return &basicConn{PacketConn: pc, supportsDF: supportsDF}, nil
 	}
-	return newConn(c)
+	rc, err := newConn(c, supportsDF)
+	if err != nil {
+		// Not all platforms (and not all sockets) support the socket options needed for reading OOB data.
+		// The basicConn works everywhere, it just doesn't read ECN bits and packet info.
+		utils.DefaultLogger.Infof("Failed to enable reading of OOB data (%s). Disabling optimizations possible on UDP connections.", err)
+		return &basicConn{PacketConn: pc, supportsDF: supportsDF}, nil
+	}
+	return rc, nil
+}
+
+// A portableConn hides the ReadMsgUDP and WriteMsgUDP methods of a net.PacketConn,
+// so that it is used as a basicConn on all platforms, see Config.DisableOOB.
+type portableConn struct {
+	net.PacketConn
+}
+
+// A portableSyscallConn is a portableConn that keeps the SyscallConn method,
+// which is used to set the DF bit and the receive buffer size.
+type portableSyscallConn struct {
+	net.PacketConn
+	sc interface {
+		SyscallConn() (syscall.RawConn, error)
+	}
+}
+
+func (c *portableSyscallConn) SyscallConn() (syscall.RawConn, error) { return c.sc.SyscallConn() }
+
+func newPortableConn(pc net.PacketConn) net.PacketConn {
+	if sc, ok := pc.(interface {
+		SyscallConn() (syscall.RawConn, error)
+	}); ok {
+		return &portableSyscallConn{PacketConn: pc, sc: sc}
+	}
+	return &portableConn{PacketConn: pc}
 }
 
 // The basicConn is the most trivial implementation of a connection.
 // It reads a single packet from the underlying net.PacketConn.
 // It is used when
-// * the net.PacketConn is not a OOBCapablePacketConn, and
-// * when the OS doesn't support OOB.
+// * the net.PacketConn is not a OOBCapablePacketConn,
+// * when the OS doesn't support OOB, or enabling OOB failed, and
+// * when OOB is disabled by Config.DisableOOB.
 type basicConn struct {
 	net.PacketConn
+	supportsDF bool
 }
 
 var _ rawConn = &basicConn{}
 
+func (c *basicConn) capabilities() connCapabilities {
+	return connCapabilities{DF: c.supportsDF}
+}
+
 func (c *basicConn) ReadPacket() (*receivedPacket, error) {
 	buffer := getPacketBuffer()
 	// The packet size should not exceed protocol.MaxPacketBufferSize bytes
@@ -2,11 +2,14 @@
 
 package quic
 
-import "syscall"
+import (
+	"errors"
+	"syscall"
+)
 
 func setDF(rawConn syscall.RawConn) error {
-	// no-op on unsupported platforms
-	return nil
+	// Setting DF isn't supported on this platform.
+	return errors.New("setting DF is not supported")
 }
 
 func isMsgSizeErr(err error) bool {
@@ -19,3 +19,9 @@ const (
 // ReadBatch only returns a single packet on OSX,
 // see https://godoc.org/golang.org/x/net/ipv4#PacketConn.ReadBatch.
 const batchSize = 1
+
+// The size of the data of the IP_TOS control message.
+const ipv4TOSDataLen = 4
+
+// Setting the flow label of outgoing packets isn't supported.
+const ipv6FLOWINFO = 0
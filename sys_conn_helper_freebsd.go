@@ -19,3 +19,9 @@ const (
 )
 
 const batchSize = 8
+
+// The size of the data of the IP_TOS control message.
+const ipv4TOSDataLen = 1
+
+// Setting the flow label of outgoing packets isn't supported.
+const ipv6FLOWINFO = 0
@@ -17,3 +17,10 @@ const (
 )
 
 const batchSize = 8 // needs to smaller than MaxUint8 (otherwise the type of oobConn.readPos has to be changed)
+
+// The size of the data of the IP_TOS control message.
+const ipv4TOSDataLen = 1
+
+// IPV6_FLOWINFO, not defined in x/sys/unix.
+// On Linux, a flow label set in a control message doesn't need to be leased, unless exclusive leases are used.
+const ipv6FLOWINFO = 0xb
@@ -4,8 +4,8 @@ package quic
 
 import "net"
 
-func newConn(c net.PacketConn) (rawConn, error) {
-	return &basicConn{PacketConn: c}, nil
+func newConn(c net.PacketConn, supportsDF bool) (rawConn, error) {
+	return &basicConn{PacketConn: c, supportsDF: supportsDF}, nil
 }
 
 func inspectReadBuffer(interface{}) (int, error) {
@@ -13,3 +13,5 @@ func inspectReadBuffer(interface{}) (int, error) {
 }
 
 func (i *packetInfo) OOB() []byte { return nil }
+
+func appendMarking(oob []byte, _ net.Addr, _ PacketMarking) []byte { return oob }
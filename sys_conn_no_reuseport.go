@@ -0,0 +1,12 @@
+//go:build !darwin && !linux && !freebsd
+
+package quic
+
+import (
+	"errors"
+	"syscall"
+)
+
+func setReusePort(_, _ string, _ syscall.RawConn) error {
+	return errors.New("SO_REUSEPORT is not supported on this platform")
+}
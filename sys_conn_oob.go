@@ -9,6 +9,7 @@ import (
 	"net"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
@@ -61,11 +62,13 @@ type oobConn struct {
 	// Packets received from the kernel, but not yet returned by ReadPacket().
 	messages []ipv4.Message
 	buffers  [batchSize]*packetBuffer
+
+	supportsDF bool
 }
 
 var _ rawConn = &oobConn{}
 
-func newConn(c OOBCapablePacketConn) (*oobConn, error) {
+func newConn(c OOBCapablePacketConn, supportsDF bool) (*oobConn, error) {
 	rawConn, err := c.SyscallConn()
 	if err != nil {
 		return nil, err
@@ -108,7 +111,9 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 		case errPIIPv4 != nil && errPIIPv6 == nil:
 			utils.DefaultLogger.Debugf("Activating reading of packet info bits for IPv6.")
 		case errPIIPv4 != nil && errPIIPv6 != nil:
-			return nil, errors.New("activating packet info failed for both IPv4 and IPv6")
+			// Without packet info, packets are sent from the address chosen by the kernel.
+			// This is also what happens when using a basicConn.
+			utils.DefaultLogger.Infof("Activating reading of packet info failed for both IPv4 and IPv6.")
 		}
 	}
 
@@ -132,6 +137,7 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 		batchConn:            bc,
 		messages:             msgs,
 		readPos:              batchSize,
+		supportsDF:           supportsDF,
 	}
 	for i := 0; i < batchSize; i++ {
 		oobConn.messages[i].OOB = make([]byte, oobBufferSize)
@@ -139,6 +145,10 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 	return oobConn, nil
 }
 
+func (c *oobConn) capabilities() connCapabilities {
+	return connCapabilities{DF: c.supportsDF}
+}
+
 func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 	if len(c.messages) == int(c.readPos) { // all messages read. Read the next batch of messages.
 		c.messages = c.messages[:batchSize]
@@ -259,3 +269,48 @@ func (info *packetInfo) OOB() []byte {
 	}
 	return nil
 }
+
+// appendMarking appends the control messages that mark the IP header of a packet sent to the remote address,
+// see Config.PacketMarking.
+func appendMarking(oob []byte, remote net.Addr, m PacketMarking) []byte {
+	addr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return oob
+	}
+	if addr.IP.To4() != nil {
+		// Also used for IPv4-mapped IPv6 addresses on dual-stack sockets.
+		if m.DSCP != 0 {
+			tos := make([]byte, ipv4TOSDataLen)
+			if ipv4TOSDataLen == 1 {
+				tos[0] = m.DSCP << 2
+			} else {
+				*(*int32)(unsafe.Pointer(&tos[0])) = int32(m.DSCP) << 2
+			}
+			oob = appendControlMessage(oob, unix.IPPROTO_IP, unix.IP_TOS, tos)
+		}
+		return oob
+	}
+	if m.DSCP != 0 {
+		tclass := make([]byte, 4)
+		*(*int32)(unsafe.Pointer(&tclass[0])) = int32(m.DSCP) << 2
+		oob = appendControlMessage(oob, unix.IPPROTO_IPV6, unix.IPV6_TCLASS, tclass)
+	}
+	if m.IPv6FlowLabel != 0 && ipv6FLOWINFO != 0 {
+		// The flow info is in network byte order.
+		flowInfo := make([]byte, 4)
+		binary.BigEndian.PutUint32(flowInfo, m.IPv6FlowLabel)
+		oob = appendControlMessage(oob, unix.IPPROTO_IPV6, ipv6FLOWINFO, flowInfo)
+	}
+	return oob
+}
+
+func appendControlMessage(oob []byte, level, typ int32, data []byte) []byte {
+	start := len(oob)
+	oob = append(oob, make([]byte, unix.CmsgSpace(len(data)))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[start]))
+	h.Level = level
+	h.Type = typ
+	h.SetLen(unix.CmsgLen(len(data)))
+	copy(oob[start+unix.CmsgLen(0):], data)
+	return oob
+}
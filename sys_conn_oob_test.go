@@ -3,8 +3,10 @@
 package quic
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
+	"runtime"
 	"time"
 
 	"golang.org/x/net/ipv4"
@@ -24,7 +26,7 @@ var _ = Describe("OOB Conn Test", func() {
 		Expect(err).ToNot(HaveOccurred())
 		udpConn, err := net.ListenUDP(network, addr)
 		Expect(err).ToNot(HaveOccurred())
-		oobConn, err := newConn(udpConn)
+		oobConn, err := newConn(udpConn, true)
 		Expect(err).ToNot(HaveOccurred())
 
 		packetChan := make(chan *receivedPacket)
@@ -228,7 +230,7 @@ var _ = Describe("OOB Conn Test", func() {
 			Expect(err).ToNot(HaveOccurred())
 			udpConn, err := net.ListenUDP("udp", addr)
 			Expect(err).ToNot(HaveOccurred())
-			oobConn, err := newConn(udpConn)
+			oobConn, err := newConn(udpConn, true)
 			Expect(err).ToNot(HaveOccurred())
 			oobConn.batchConn = batchConn
 
@@ -239,4 +241,130 @@ var _ = Describe("OOB Conn Test", func() {
 			}
 		})
 	})
+
+	Context("marking packets", func() {
+		// runReceiver runs a socket that reads the DSCP and flow label of the packets it receives.
+		runReceiver := func(network, address string) *net.UDPConn {
+			addr, err := net.ResolveUDPAddr(network, address)
+			Expect(err).ToNot(HaveOccurred())
+			conn, err := net.ListenUDP(network, addr)
+			Expect(err).ToNot(HaveOccurred())
+			rawConn, err := conn.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawConn.Control(func(fd uintptr) {
+				if network == "udp4" {
+					Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_RECVTOS, 1)).To(Succeed())
+					return
+				}
+				Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_RECVTCLASS, 1)).To(Succeed())
+				if ipv6FLOWINFO != 0 {
+					Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, ipv6FLOWINFO, 1)).To(Succeed())
+				}
+			})).To(Succeed())
+			return conn
+		}
+
+		receive := func(conn *net.UDPConn) (data []byte, dscp uint8, flowLabel uint32) {
+			b := make([]byte, 100)
+			oob := make([]byte, oobBufferSize)
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, oobn, _, _, err := conn.ReadMsgUDP(b, oob)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			for _, msg := range msgs {
+				switch {
+				case msg.Header.Level == unix.IPPROTO_IP && msg.Header.Type == msgTypeIPTOS,
+					msg.Header.Level == unix.IPPROTO_IPV6 && msg.Header.Type == unix.IPV6_TCLASS:
+					dscp = msg.Data[0] >> 2
+				case msg.Header.Level == unix.IPPROTO_IPV6 && ipv6FLOWINFO != 0 && msg.Header.Type == ipv6FLOWINFO:
+					flowLabel = binary.BigEndian.Uint32(msg.Data) & maxIPv6FlowLabel
+				}
+			}
+			return b[:n], dscp, flowLabel
+		}
+
+		newSender := func(network string, remote net.Addr, marking, prMarking PacketMarking) sendConn {
+			conn, err := net.ListenUDP(network, nil)
+			Expect(err).ToNot(HaveOccurred())
+			c, err := newConn(conn, true)
+			Expect(err).ToNot(HaveOccurred())
+			return newSendConn(c, remote, nil, marking, prMarking)
+		}
+
+		It("marks packets on IPv4", func() {
+			conn := runReceiver("udp4", "localhost:0")
+			defer conn.Close()
+			c := newSender("udp4", conn.LocalAddr(), PacketMarking{DSCP: 46}, PacketMarking{DSCP: 34})
+			defer c.Close()
+
+			Expect(c.Write([]byte("foo"))).To(Succeed())
+			data, dscp, _ := receive(conn)
+			Expect(data).To(Equal([]byte("foo")))
+			Expect(dscp).To(BeEquivalentTo(46))
+			Expect(c.WritePR([]byte("bar"))).To(Succeed())
+			data, dscp, _ = receive(conn)
+			Expect(data).To(Equal([]byte("bar")))
+			Expect(dscp).To(BeEquivalentTo(34))
+		})
+
+		It("marks packets on IPv6", func() {
+			conn := runReceiver("udp6", "[::1]:0")
+			defer conn.Close()
+			c := newSender("udp6", conn.LocalAddr(), PacketMarking{DSCP: 46, IPv6FlowLabel: 0x12345}, PacketMarking{})
+			defer c.Close()
+
+			Expect(c.Write([]byte("foo"))).To(Succeed())
+			_, dscp, flowLabel := receive(conn)
+			Expect(dscp).To(BeEquivalentTo(46))
+			if runtime.GOOS == "linux" {
+				Expect(flowLabel).To(BeEquivalentTo(0x12345))
+			}
+			// If no marking is configured for partially reliable data, all packets are marked the same way.
+			Expect(c.WritePR([]byte("bar"))).To(Succeed())
+			_, dscp, flowLabel = receive(conn)
+			Expect(dscp).To(BeEquivalentTo(46))
+			if runtime.GOOS == "linux" {
+				Expect(flowLabel).To(BeEquivalentTo(0x12345))
+			}
+		})
+
+		It("marks IPv4 packets sent on a connection that supports both IPv4 and IPv6", func() {
+			conn := runReceiver("udp4", "localhost:0")
+			defer conn.Close()
+			c := newSender("udp", conn.LocalAddr(), PacketMarking{DSCP: 46}, PacketMarking{})
+			defer c.Close()
+
+			Expect(c.Write([]byte("foo"))).To(Succeed())
+			_, dscp, _ := receive(conn)
+			Expect(dscp).To(BeEquivalentTo(46))
+		})
+
+		It("marks packets sent on a net.PacketConn", func() {
+			conn := runReceiver("udp4", "localhost:0")
+			defer conn.Close()
+			udpConn, err := net.ListenUDP("udp4", nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			c := newSendPconn(udpConn, conn.LocalAddr(), PacketMarking{DSCP: 46}, PacketMarking{DSCP: 34})
+
+			Expect(c.Write([]byte("foo"))).To(Succeed())
+			_, dscp, _ := receive(conn)
+			Expect(dscp).To(BeEquivalentTo(46))
+			Expect(c.WritePR([]byte("bar"))).To(Succeed())
+			_, dscp, _ = receive(conn)
+			Expect(dscp).To(BeEquivalentTo(34))
+		})
+
+		It("doesn't mark packets by default", func() {
+			conn := runReceiver("udp4", "localhost:0")
+			defer conn.Close()
+			c := newSender("udp4", conn.LocalAddr(), PacketMarking{}, PacketMarking{})
+			defer c.Close()
+
+			Expect(c.WritePR([]byte("foo"))).To(Succeed())
+			_, dscp, _ := receive(conn)
+			Expect(dscp).To(BeZero())
+		})
+	})
 })
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"net"
+	"syscall"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -30,4 +31,38 @@ var _ = Describe("Basic Conn Test", func() {
 		Expect(p.rcvTime).To(BeTemporally("~", time.Now(), scaleDuration(100*time.Millisecond)))
 		Expect(p.remoteAddr).To(Equal(addr))
 	})
+
+	It("reports that the DF bit isn't set, if the conn doesn't have a SyscallConn", func() {
+		conn, err := wrapConn(NewMockPacketConn(mockCtrl))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.capabilities().DF).To(BeFalse())
+	})
+
+	It("uses a basicConn for portable conns", func() {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		udpConn, err := net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+
+		pc := newPortableConn(udpConn)
+		_, isOOBCapable := pc.(OOBCapablePacketConn)
+		Expect(isOOBCapable).To(BeFalse())
+		// the SyscallConn is still needed to set the DF bit and the receive buffer size
+		_, hasSyscallConn := pc.(interface {
+			SyscallConn() (syscall.RawConn, error)
+		})
+		Expect(hasSyscallConn).To(BeTrue())
+		Expect(pc.LocalAddr()).To(Equal(udpConn.LocalAddr()))
+		conn, err := wrapConn(pc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn).To(BeAssignableToTypeOf(&basicConn{}))
+	})
+
+	It("doesn't add a SyscallConn for portable conns", func() {
+		_, hasSyscallConn := newPortableConn(NewMockPacketConn(mockCtrl)).(interface {
+			SyscallConn() (syscall.RawConn, error)
+		})
+		Expect(hasSyscallConn).To(BeFalse())
+	})
 })
@@ -15,7 +15,7 @@ var _ = Describe("Windows Conn Test", func() {
 		Expect(err).ToNot(HaveOccurred())
 		udpConn, err := net.ListenUDP("udp4", addr)
 		Expect(err).ToNot(HaveOccurred())
-		conn, err := newConn(udpConn)
+		conn, err := newConn(udpConn, true)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(conn.Close()).To(Succeed())
 	})
@@ -25,7 +25,7 @@ var _ = Describe("Windows Conn Test", func() {
 		Expect(err).ToNot(HaveOccurred())
 		udpConn, err := net.ListenUDP("udp6", addr)
 		Expect(err).ToNot(HaveOccurred())
-		conn, err := newConn(udpConn)
+		conn, err := newConn(udpConn, true)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(conn.Close()).To(Succeed())
 	})
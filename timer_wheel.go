@@ -0,0 +1,210 @@
+package quic
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// timerWheelTick is the resolution of the timer wheel.
+	timerWheelTick = time.Millisecond
+	// timerWheelSlots is the number of slots of the timer wheel.
+	// Timers that expire more than one revolution (timerWheelSlots ticks) in the future stay in their slot for multiple revolutions.
+	timerWheelSlots = 1024
+)
+
+// sharedTimerWheel runs the timers of the streams of all connections,
+// such that servers with many PR streams don't need one runtime timer (and goroutine) per idle timer and rate limit.
+//
+// The timers of a connection (loss detection and PTO, ACK, idle timeout and pacing) don't run on the wheel:
+// the connection already combines them into a single timer, which is served by its run loop,
+// and pacing needs a finer resolution than timerWheelTick.
+// The deadline policy doesn't use any timers, deadlines are only evaluated when data is declared lost.
+var sharedTimerWheel = newTimerWheel()
+
+// A wheelTimer is a timer run by a timerWheel.
+// Its methods behave like those of a time.Timer created by time.AfterFunc.
+type wheelTimer struct {
+	wheel *timerWheel
+	f     func()
+
+	// only accessed while holding the mutex of the wheel
+	scheduled  bool
+	tick       int64 // the tick at which the timer expires
+	prev, next *wheelTimer
+}
+
+// A timerWheel is a hashed timing wheel.
+// A single goroutine runs the timers. It only wakes up for ticks that have timers in their slot,
+// and it exits when no timers are scheduled.
+type timerWheel struct {
+	mutex sync.Mutex
+
+	epoch time.Time // the time of tick 0
+	// all timers up to this tick have expired
+	current   int64
+	slots     [timerWheelSlots]*wheelTimer // linked lists of the timers
+	numTimers int
+
+	running  bool
+	nextTick int64 // the tick the goroutine wakes up at
+	wakeup   chan struct{}
+}
+
+func newTimerWheel() *timerWheel {
+	return &timerWheel{
+		epoch:  time.Now(),
+		wakeup: make(chan struct{}, 1),
+	}
+}
+
+// AfterFunc waits for the duration to elapse and then calls f.
+// f is called on the goroutine of the timer wheel, so it must not block.
+func (w *timerWheel) AfterFunc(d time.Duration, f func()) *wheelTimer {
+	t := &wheelTimer{wheel: w, f: f}
+	t.Reset(d)
+	return t
+}
+
+// Reset changes the timer to expire after duration d.
+// It returns true if the timer had been active, false if the timer had expired or been stopped.
+func (t *wheelTimer) Reset(d time.Duration) bool {
+	w := t.wheel
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	active := w.remove(t)
+	// round up, such that the timer never expires early
+	w.add(t, int64((time.Since(w.epoch)+d+timerWheelTick-1)/timerWheelTick))
+	return active
+}
+
+// Stop prevents the timer from firing.
+// It returns true if the call stops the timer, false if the timer has already expired or been stopped.
+func (t *wheelTimer) Stop() bool {
+	w := t.wheel
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.remove(t)
+}
+
+// must be called after locking the mutex
+func (w *timerWheel) add(t *wheelTimer, tick int64) {
+	if tick <= w.current {
+		tick = w.current + 1
+	}
+	t.tick = tick
+	t.scheduled = true
+	slot := &w.slots[tick%timerWheelSlots]
+	t.prev = nil
+	t.next = *slot
+	if t.next != nil {
+		t.next.prev = t
+	}
+	*slot = t
+	w.numTimers++
+
+	if !w.running {
+		w.running = true
+		w.nextTick = tick
+		go w.run()
+		return
+	}
+	if tick < w.nextTick {
+		w.nextTick = tick
+		select {
+		case w.wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// remove removes a timer from the wheel.
+// It returns false if the timer wasn't scheduled.
+// must be called after locking the mutex
+func (w *timerWheel) remove(t *wheelTimer) bool {
+	if !t.scheduled {
+		return false
+	}
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		w.slots[t.tick%timerWheelSlots] = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.prev = nil
+	t.next = nil
+	t.scheduled = false
+	w.numTimers--
+	return true
+}
+
+func (w *timerWheel) run() {
+	timer := time.NewTimer(0)
+	var expired []*wheelTimer
+	for {
+		select {
+		case <-timer.C:
+		case <-w.wakeup:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		w.mutex.Lock()
+		expired = w.expire(int64(time.Since(w.epoch)/timerWheelTick), expired)
+		done := w.numTimers == 0
+		if done {
+			w.running = false
+		} else {
+			w.nextTick = w.nextOccupiedTick()
+		}
+		wakeAt := w.epoch.Add(time.Duration(w.nextTick) * timerWheelTick)
+		w.mutex.Unlock()
+
+		// call the functions without holding the mutex, since they might reset timers
+		for i, t := range expired {
+			t.f()
+			expired[i] = nil
+		}
+		expired = expired[:0]
+		if done {
+			return
+		}
+		timer.Reset(time.Until(wakeAt))
+	}
+}
+
+// expire removes all timers that expire up to tick now, and appends them to expired.
+// must be called after locking the mutex
+func (w *timerWheel) expire(now int64, expired []*wheelTimer) []*wheelTimer {
+	for tick := w.current + 1; tick <= now && tick <= w.current+timerWheelSlots; tick++ {
+		for t := w.slots[tick%timerWheelSlots]; t != nil; {
+			next := t.next
+			if t.tick <= now {
+				w.remove(t)
+				expired = append(expired, t)
+			}
+			t = next
+		}
+	}
+	if now > w.current {
+		w.current = now
+	}
+	return expired
+}
+
+// nextOccupiedTick returns the next tick that has timers in its slot.
+// These timers don't necessarily expire at this tick, they might only expire after more revolutions of the wheel.
+// must be called after locking the mutex
+func (w *timerWheel) nextOccupiedTick() int64 {
+	for i := int64(1); i <= timerWheelSlots; i++ {
+		if w.slots[(w.current+i)%timerWheelSlots] != nil {
+			return w.current + i
+		}
+	}
+	return w.current + timerWheelSlots
+}
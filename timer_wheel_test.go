@@ -0,0 +1,111 @@
+package quic
+
+import (
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Timer Wheel", func() {
+	var w *timerWheel
+
+	BeforeEach(func() {
+		w = newTimerWheel()
+	})
+
+	isRunning := func() bool {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		return w.running
+	}
+
+	It("calls the function after the duration", func() {
+		fired := make(chan time.Time, 1)
+		start := time.Now()
+		w.AfterFunc(20*time.Millisecond, func() { fired <- time.Now() })
+		var t time.Time
+		Eventually(fired).Should(Receive(&t))
+		Expect(t.Sub(start)).To(BeNumerically(">=", 20*time.Millisecond))
+		Expect(t.Sub(start)).To(BeNumerically("<", 100*time.Millisecond))
+	})
+
+	It("calls the functions of multiple timers in order", func() {
+		fired := make(chan int, 3)
+		w.AfterFunc(30*time.Millisecond, func() { fired <- 3 })
+		w.AfterFunc(10*time.Millisecond, func() { fired <- 1 })
+		w.AfterFunc(20*time.Millisecond, func() { fired <- 2 })
+		for i := 1; i <= 3; i++ {
+			Eventually(fired).Should(Receive(Equal(i)))
+		}
+	})
+
+	It("stops timers", func() {
+		var fired int32
+		t := w.AfterFunc(20*time.Millisecond, func() { atomic.AddInt32(&fired, 1) })
+		Expect(t.Stop()).To(BeTrue())
+		Expect(t.Stop()).To(BeFalse())
+		Consistently(func() int32 { return atomic.LoadInt32(&fired) }, 50*time.Millisecond).Should(BeZero())
+	})
+
+	It("resets timers", func() {
+		fired := make(chan time.Time, 2)
+		t := w.AfterFunc(time.Hour, func() { fired <- time.Now() })
+		start := time.Now()
+		Expect(t.Reset(20 * time.Millisecond)).To(BeTrue())
+		var at time.Time
+		Eventually(fired).Should(Receive(&at))
+		Expect(at.Sub(start)).To(BeNumerically(">=", 20*time.Millisecond))
+		// reset a timer that already expired
+		Expect(t.Reset(0)).To(BeFalse())
+		Eventually(fired).Should(Receive())
+	})
+
+	It("allows the function to reset its timer", func() {
+		var count int32
+		var t *wheelTimer
+		t = w.AfterFunc(time.Hour, func() {
+			if atomic.AddInt32(&count, 1) < 3 {
+				t.Reset(time.Millisecond)
+			}
+		})
+		t.Reset(time.Millisecond)
+		Eventually(func() int32 { return atomic.LoadInt32(&count) }).Should(BeEquivalentTo(3))
+		Consistently(func() int32 { return atomic.LoadInt32(&count) }, 20*time.Millisecond).Should(BeEquivalentTo(3))
+	})
+
+	It("stops the goroutine when no timers are scheduled", func() {
+		t := w.AfterFunc(time.Hour, func() {})
+		Expect(isRunning()).To(BeTrue())
+		t.Stop()
+		// The goroutine exits once it wakes up. This happens when the next timer's slot comes around.
+		w.AfterFunc(0, func() {})
+		Eventually(isRunning).Should(BeFalse())
+	})
+
+	It("keeps timers that expire after more than one revolution", func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		w.running = true // don't start the goroutine
+		t := &wheelTimer{wheel: w, f: func() {}}
+		w.add(t, w.current+timerWheelSlots+5)
+		Expect(w.nextOccupiedTick()).To(Equal(w.current + 5))
+		Expect(w.expire(w.current+5, nil)).To(BeEmpty())
+		Expect(w.nextOccupiedTick()).To(Equal(w.current + timerWheelSlots))
+		Expect(w.expire(w.current+timerWheelSlots, nil)).To(Equal([]*wheelTimer{t}))
+		Expect(w.numTimers).To(BeZero())
+	})
+
+	It("expires all timers if it falls behind by more than one revolution", func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		w.running = true // don't start the goroutine
+		t1 := &wheelTimer{wheel: w, f: func() {}}
+		t2 := &wheelTimer{wheel: w, f: func() {}}
+		w.add(t1, w.current+10)
+		w.add(t2, w.current+2*timerWheelSlots)
+		Expect(w.expire(w.current+3*timerWheelSlots, nil)).To(ConsistOf(t1, t2))
+		Expect(w.numTimers).To(BeZero())
+	})
+})
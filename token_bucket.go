@@ -0,0 +1,138 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The time it takes to fill the bucket of a rate-limited stream.
+// It determines the burst size, which is never smaller than a full packet.
+const rateLimitBurstInterval = 20 * time.Millisecond
+
+// A tokenBucket paces the data sent on a stream, see SendStream.SetRateLimit.
+// Tokens are measured in bytes.
+// It is used by the framer, which only pops STREAM frames from a stream if enough tokens are available.
+type tokenBucket struct {
+	mutex sync.Mutex
+
+	rate       uint64 // in bytes per second
+	burst      protocol.ByteCount
+	tokens     protocol.ByteCount
+	lastUpdate time.Time
+
+	// called when the tokens awaited by WaitFor are available
+	onAvailable func()
+	timer       *wheelTimer
+}
+
+func newTokenBucket(bytesPerSecond uint64, onAvailable func()) *tokenBucket {
+	b := &tokenBucket{
+		onAvailable: onAvailable,
+		lastUpdate:  time.Now(),
+	}
+	b.setRate(bytesPerSecond)
+	b.tokens = b.burst
+	return b
+}
+
+// SetRate changes the rate.
+// If WaitFor was called, the time when tokens become available is reevaluated.
+func (b *tokenBucket) SetRate(bytesPerSecond uint64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.update(time.Now())
+	b.setRate(bytesPerSecond)
+	if b.timer != nil {
+		b.timer.Reset(0)
+	}
+}
+
+// must be called after locking the mutex
+func (b *tokenBucket) setRate(bytesPerSecond uint64) {
+	b.rate = bytesPerSecond
+	b.burst = utils.Max(
+		protocol.ByteCount(bytesPerSecond*uint64(rateLimitBurstInterval)/uint64(time.Second)),
+		protocol.MaxPacketBufferSize,
+	)
+	b.tokens = utils.Min(b.tokens, b.burst)
+}
+
+// must be called after locking the mutex
+func (b *tokenBucket) update(now time.Time) {
+	if !now.After(b.lastUpdate) {
+		return
+	}
+	added := protocol.ByteCount(float64(b.rate) * now.Sub(b.lastUpdate).Seconds())
+	if added == 0 && b.tokens < b.burst {
+		// Don't advance lastUpdate, otherwise frequent calls would never add any tokens.
+		return
+	}
+	b.tokens = utils.Min(b.tokens+added, b.burst)
+	b.lastUpdate = now
+}
+
+// Available returns the number of bytes that can be sent now.
+func (b *tokenBucket) Available(now time.Time) protocol.ByteCount {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.update(now)
+	return b.tokens
+}
+
+// Consume removes the tokens for n bytes that were sent.
+func (b *tokenBucket) Consume(n protocol.ByteCount, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.update(now)
+	if n > b.tokens {
+		b.tokens = 0
+		return
+	}
+	b.tokens -= n
+}
+
+// WaitFor calls onAvailable as soon as n tokens are available.
+func (b *tokenBucket) WaitFor(n protocol.ByteCount, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.update(now)
+	n = utils.Min(n, b.burst)
+	var d time.Duration
+	if n > b.tokens {
+		d = time.Duration(float64(n-b.tokens)/float64(b.rate)*float64(time.Second)) + time.Millisecond
+	}
+	if b.timer != nil {
+		b.timer.Reset(d)
+		return
+	}
+	b.timer = sharedTimerWheel.AfterFunc(d, b.fire)
+}
+
+func (b *tokenBucket) fire() {
+	b.mutex.Lock()
+	if b.timer == nil {
+		b.mutex.Unlock()
+		return
+	}
+	b.timer = nil
+	b.mutex.Unlock()
+	b.onAvailable() // must be called without holding the mutex
+}
+
+// Stop stops the timer started by WaitFor.
+func (b *tokenBucket) Stop() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
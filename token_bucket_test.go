@@ -0,0 +1,87 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Bucket", func() {
+	It("starts with a full bucket", func() {
+		b := newTokenBucket(1e6, nil)
+		Expect(b.Available(time.Now())).To(BeEquivalentTo(1e6 * rateLimitBurstInterval / time.Second))
+	})
+
+	It("allows bursts of at least one packet", func() {
+		b := newTokenBucket(100, nil)
+		Expect(b.Available(time.Now())).To(Equal(protocol.MaxPacketBufferSize))
+	})
+
+	It("refills the bucket", func() {
+		b := newTokenBucket(1e6, nil)
+		now := time.Now()
+		burst := b.Available(now)
+		b.Consume(burst, now)
+		Expect(b.Available(now)).To(BeZero())
+		Expect(b.Available(now.Add(5 * time.Millisecond))).To(BeEquivalentTo(5000))
+		// never exceeds the burst size
+		Expect(b.Available(now.Add(time.Second))).To(Equal(burst))
+	})
+
+	It("accumulates tokens when called frequently", func() {
+		b := newTokenBucket(1000, nil)
+		now := time.Now()
+		b.Consume(b.Available(now), now)
+		for i := 0; i < 100; i++ {
+			now = now.Add(100 * time.Microsecond)
+			b.Available(now)
+		}
+		Expect(b.Available(now)).To(BeNumerically("~", 10, 1))
+	})
+
+	It("doesn't go below zero", func() {
+		b := newTokenBucket(1e6, nil)
+		now := time.Now()
+		b.Consume(1e6, now)
+		Expect(b.Available(now)).To(BeZero())
+	})
+
+	It("reduces the tokens when the rate is lowered", func() {
+		b := newTokenBucket(1e6, nil)
+		b.SetRate(100)
+		Expect(b.Available(time.Now())).To(Equal(protocol.MaxPacketBufferSize))
+	})
+
+	It("calls the callback when tokens are available", func() {
+		called := make(chan struct{})
+		b := newTokenBucket(1e4, func() { close(called) })
+		now := time.Now()
+		b.Consume(b.Available(now), now)
+		b.WaitFor(500, now) // takes 50ms
+		// The wait time is determined by the rate, so it's not scaled.
+		Consistently(called, 25*time.Millisecond).ShouldNot(BeClosed())
+		Eventually(called).Should(BeClosed())
+	})
+
+	It("reevaluates the wait time when the rate is changed", func() {
+		called := make(chan struct{})
+		b := newTokenBucket(10, func() { close(called) })
+		now := time.Now()
+		b.Consume(b.Available(now), now)
+		b.WaitFor(500, now) // takes 50s
+		b.SetRate(1e9)
+		Eventually(called).Should(BeClosed())
+	})
+
+	It("stops the timer", func() {
+		b := newTokenBucket(1e4, func() { Fail("didn't expect the callback to be called") })
+		now := time.Now()
+		b.Consume(b.Available(now), now)
+		b.WaitFor(100, now)
+		b.Stop()
+		time.Sleep(scaleDuration(20 * time.Millisecond))
+	})
+})
@@ -0,0 +1,211 @@
+package quic
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/replay"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// replayClock is the clock of a stream replaying a trace.
+// It is set to the time of the event that is replayed.
+type replayClock time.Time
+
+func (c *replayClock) Now() time.Time { return time.Time(*c) }
+
+// replayTrace replays the transmission of the data of a stream, recorded in a trace, on a new send stream.
+// For every packet that carried data of the stream, a frame is popped from the stream,
+// after writing the data using the recorded PR policy if it is new data. The popped frame must match the recorded frame.
+// PR_ACK_NOTIFY frames sent for the stream must be queued by the stream, and are dequeued.
+// Packets that were declared lost or acknowledged are reported to the stream, the same way the sent packet handler does.
+// The stream's clock follows the time of the events, so that the deadline policy is evaluated as it was during the recording.
+// Only 1-RTT packets are replayed.
+func replayTrace(trace *replay.Trace, id protocol.StreamID, sender streamSender) *sendStream {
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+	mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+	str := newSendStream(id, sender, mockFC, utils.DefaultLogger, protocol.VersionWhatever)
+	start := time.Now()
+	clock := replayClock(start)
+	str.clock = &clock
+
+	type replayedPacket struct {
+		frames []*ackhandler.Frame
+		lost   bool
+	}
+	packets := make(map[protocol.PacketNumber]*replayedPacket)
+	var written protocol.ByteCount
+	for _, ev := range trace.Events {
+		if ev.EncryptionLevel != protocol.Encryption1RTT {
+			continue
+		}
+		clock = replayClock(start.Add(ev.Time))
+		switch ev.Type {
+		case replay.EventPacketSent:
+			p := &replayedPacket{}
+			for _, f := range ev.Frames {
+				if f.StreamID != id {
+					continue
+				}
+				switch f.Type {
+				case replay.FrameTypeStream, replay.FrameTypePRStream:
+					var frame *ackhandler.Frame
+					if end := f.Offset + f.Length; end > written {
+						ExpectWithOffset(1, f.Offset).To(Equal(written), "new data must be sent in order")
+						frame = replayWrite(str, int(end-written), PRPolicy{PTDA: f.PTDA, Value: f.Value}, f.Fin)
+						written = end
+					} else {
+						maxBytes := (&wire.StreamFrame{StreamID: id, Offset: f.Offset, DataLenPresent: true, Data: make([]byte, f.Length)}).Length(protocol.VersionWhatever)
+						if f.Type == replay.FrameTypePRStream {
+							maxBytes += wire.MaxPRStreamFrameOverhead
+						}
+						frame, _ = str.popStreamFrame(maxBytes)
+					}
+					ExpectWithOffset(1, frame).ToNot(BeNil(), "no frame for packet %d", ev.PacketNumber)
+					offset, length := replayFrameRange(frame.Frame)
+					ExpectWithOffset(1, offset).To(Equal(f.Offset), "unexpected offset in packet %d", ev.PacketNumber)
+					ExpectWithOffset(1, length).To(Equal(f.Length), "unexpected length in packet %d", ev.PacketNumber)
+					p.frames = append(p.frames, frame)
+				case replay.FrameTypePRAckNotify:
+					var found bool
+					queued := str.prAckNotifies.frames
+					for i, nf := range queued {
+						if nf.StreamID == id && nf.Offset == f.Offset && nf.DataLen() == f.Length {
+							str.prAckNotifies.frames = append(queued[:i], queued[i+1:]...)
+							found = true
+							break
+						}
+					}
+					ExpectWithOffset(1, found).To(BeTrue(), "PR_ACK_NOTIFY frame (offset %d, length %d) in packet %d was not queued", f.Offset, f.Length, ev.PacketNumber)
+				}
+			}
+			if len(p.frames) > 0 {
+				packets[ev.PacketNumber] = p
+			}
+		case replay.EventPacketLost:
+			p, ok := packets[ev.PacketNumber]
+			if !ok || p.lost {
+				continue
+			}
+			p.lost = true
+			for _, f := range p.frames {
+				f.OnLost(f.Frame)
+			}
+		case replay.EventPacketAcked:
+			p, ok := packets[ev.PacketNumber]
+			if !ok {
+				continue
+			}
+			delete(packets, ev.PacketNumber)
+			for _, f := range p.frames {
+				if !p.lost {
+					f.OnAcked(f.Frame)
+				} else if f.OnAckedAfterLoss != nil {
+					f.OnAckedAfterLoss()
+				}
+			}
+		}
+	}
+	return str
+}
+
+// replayWrite writes n bytes to the stream, and pops them in a single frame.
+func replayWrite(str *sendStream, n int, policy PRPolicy, fin bool) *ackhandler.Frame {
+	done := make(chan struct{})
+	go func() {
+		defer GinkgoRecover()
+		defer close(done)
+		_, err := str.WriteWithPolicy(make([]byte, n), policy)
+		Expect(err).ToNot(HaveOccurred())
+		if fin {
+			Expect(str.Close()).To(Succeed())
+		}
+	}()
+	var frame *ackhandler.Frame
+	Eventually(func() *ackhandler.Frame {
+		frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+		return frame
+	}).ShouldNot(BeNil())
+	Eventually(done).Should(BeClosed())
+	return frame
+}
+
+func replayFrameRange(f wire.Frame) (protocol.ByteCount, protocol.ByteCount) {
+	switch f := f.(type) {
+	case *wire.StreamFrame:
+		return f.Offset, f.DataLen()
+	case *wire.PRStreamFrame:
+		return f.Offset, f.DataLen()
+	default:
+		Fail("unexpected frame type")
+		return 0, 0
+	}
+}
+
+var _ = Describe("Replaying traces", func() {
+	readTrace := func(s string) *replay.Trace {
+		trace, err := replay.Read(strings.NewReader(s))
+		Expect(err).ToNot(HaveOccurred())
+		return trace
+	}
+
+	It("retransmits lost data, and skips abandoned data", func() {
+		// stream 4 sends 10 bytes using the times policy, and 10 bytes that are abandoned when lost
+		trace := readTrace(`
+{"time":1000,"type":"packet_sent","encryption_level":4,"packet_number":10,"frames":[{"type":"pr_stream","stream_id":4,"length":10,"ptda":64,"value":3}]}
+{"time":2000,"type":"packet_sent","encryption_level":4,"packet_number":11,"frames":[{"type":"pr_stream","stream_id":4,"offset":10,"length":10,"fin":true,"ptda":8}]}
+{"time":3000,"type":"packet_lost","encryption_level":4,"packet_number":10}
+{"time":3000,"type":"packet_lost","encryption_level":4,"packet_number":11}
+{"time":4000,"type":"packet_sent","encryption_level":4,"packet_number":12,"frames":[{"type":"pr_stream","stream_id":4,"length":10,"ptda":64,"value":3},{"type":"pr_ack_notify","stream_id":4,"offset":10,"length":10,"fin":true,"ptda":8}]}
+{"time":5000,"type":"packet_acked","encryption_level":4,"packet_number":12}
+`)
+		sender := NewMockStreamSender(mockCtrl)
+		sender.EXPECT().onHasStreamData(protocol.StreamID(4)).AnyTimes()
+		sender.EXPECT().onPRPolicyChanged(protocol.StreamID(4), protocol.ByteCount(10), PRPolicy{PTDA: PTDAAbandon})
+		sender.EXPECT().onStreamCompleted(protocol.StreamID(4))
+		str := replayTrace(trace, 4, sender)
+		Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 10}}))
+		Expect(str.prAckNotifies.frames).To(BeEmpty())
+	})
+
+	It("evaluates the deadline policy at the time of the events", func() {
+		// The data expires after 100ms. It is retransmitted when it's first declared lost (after 50ms),
+		// but skipped when the retransmission is declared lost (after 150ms).
+		trace := readTrace(`
+{"time":1000000,"type":"packet_sent","encryption_level":4,"packet_number":1,"frames":[{"type":"pr_stream","stream_id":4,"length":10,"ptda":32,"value":100}]}
+{"time":50000000,"type":"packet_lost","encryption_level":4,"packet_number":1}
+{"time":60000000,"type":"packet_sent","encryption_level":4,"packet_number":2,"frames":[{"type":"pr_stream","stream_id":4,"length":10,"ptda":32,"value":100}]}
+{"time":150000000,"type":"packet_lost","encryption_level":4,"packet_number":2}
+{"time":160000000,"type":"packet_sent","encryption_level":4,"packet_number":3,"frames":[{"type":"pr_ack_notify","stream_id":4,"length":10,"ptda":32,"value":100}]}
+`)
+		sender := NewMockStreamSender(mockCtrl)
+		sender.EXPECT().onHasStreamData(protocol.StreamID(4)).AnyTimes()
+		str := replayTrace(trace, 4, sender)
+		Expect(str.skippedBytes).To(BeEquivalentTo(10))
+		Expect(str.retransmissionQueue.Empty()).To(BeTrue())
+	})
+
+	It("handles skipped data that is acknowledged after all", func() {
+		trace := readTrace(`
+{"time":1000,"type":"packet_sent","encryption_level":4,"packet_number":1,"frames":[{"type":"pr_stream","stream_id":4,"length":10,"ptda":8}]}
+{"time":2000,"type":"packet_lost","encryption_level":4,"packet_number":1}
+{"time":3000,"type":"packet_acked","encryption_level":4,"packet_number":1}
+`)
+		sender := NewMockStreamSender(mockCtrl)
+		sender.EXPECT().onHasStreamData(protocol.StreamID(4)).AnyTimes()
+		sender.EXPECT().onSpuriousPRConversion(protocol.StreamID(4), protocol.ByteCount(0), protocol.ByteCount(10), true)
+		str := replayTrace(trace, 4, sender)
+		Expect(str.AckedRanges()).To(Equal([]ByteRange{{Start: 0, End: 10}}))
+		// the PRAckNotify frame was never sent
+		Expect(str.prAckNotifies.frames).To(BeEmpty())
+	})
+})
@@ -0,0 +1,127 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// A TransportEvent is a change of the transport state of a connection, see Connection.Events.
+// It is one of KeyUpdateEvent, PeerTransportParametersEvent, PRNegotiatedEvent, DatagramDroppedEvent and StreamResetEvent.
+type TransportEvent interface {
+	transportEvent()
+}
+
+// A KeyUpdateEvent is delivered when the 1-RTT keys are updated.
+type KeyUpdateEvent struct {
+	// KeyPhase is the key phase of the new keys.
+	KeyPhase uint64
+	// Remote says if the key update was initiated by the peer.
+	Remote bool
+}
+
+// A PeerTransportParametersEvent is delivered when the transport parameters of the peer are received.
+type PeerTransportParametersEvent struct {
+	MaxIdleTimeout time.Duration
+	InitialMaxData uint64
+	// MaxBidiStreams and MaxUniStreams are the initial number of streams that the peer allows us to open.
+	MaxBidiStreams int64
+	MaxUniStreams  int64
+	// MaxDatagramFrameSize is 0 if the peer doesn't support DATAGRAMs.
+	MaxDatagramFrameSize uint64
+	PartialReliability   bool
+}
+
+// A PRNegotiatedEvent is delivered once the use of partial reliability was negotiated with the peer.
+// If it is disabled, all data is sent reliably, see PRConfig.Disabled.
+type PRNegotiatedEvent struct {
+	Enabled bool
+}
+
+// A DatagramDroppedEvent is delivered when a DATAGRAM is dropped without being sent,
+// because the send queue overflowed (see DatagramOverflowPolicy) or its deadline passed,
+// or when a received DATAGRAM is dropped, because the application didn't call ReceiveMessage fast enough.
+type DatagramDroppedEvent struct {
+	// Length is the length of the payload.
+	Length   int
+	Expired  bool
+	Received bool
+}
+
+// A StreamResetEvent is delivered when the peer resets a stream, i.e. cancels writing on it.
+type StreamResetEvent struct {
+	StreamID  StreamID
+	ErrorCode StreamErrorCode
+	// FinalSize is the number of bytes sent on the stream before it was reset.
+	FinalSize uint64
+}
+
+func (KeyUpdateEvent) transportEvent()               {}
+func (PeerTransportParametersEvent) transportEvent() {}
+func (PRNegotiatedEvent) transportEvent()            {}
+func (DatagramDroppedEvent) transportEvent()         {}
+func (StreamResetEvent) transportEvent()             {}
+
+// The eventBus delivers the transport events of a connection.
+// Events are never blocked on: if the application doesn't keep up, events are dropped.
+type eventBus struct {
+	mutex  sync.Mutex
+	events chan TransportEvent
+	closed bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{events: make(chan TransportEvent, protocol.TransportEventQueueLen)}
+}
+
+func (b *eventBus) Events() <-chan TransportEvent {
+	return b.events
+}
+
+func (b *eventBus) Publish(ev TransportEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+	select {
+	case b.events <- ev:
+	default:
+	}
+}
+
+// Close closes the events channel. Events published afterwards are dropped.
+func (b *eventBus) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.events)
+}
+
+// The eventTracer publishes the events that are only reported to the tracer, e.g. key updates.
+type eventTracer struct {
+	logging.NullConnectionTracer
+	bus *eventBus
+}
+
+var _ logging.ConnectionTracer = &eventTracer{}
+
+// tracerWithEvents returns a tracer that publishes the events reported to the tracer, in addition to passing them to tracer.
+func tracerWithEvents(tracer logging.ConnectionTracer, bus *eventBus) logging.ConnectionTracer {
+	t := &eventTracer{bus: bus}
+	if tracer == nil {
+		return t
+	}
+	return logging.NewMultiplexedConnectionTracer(tracer, t)
+}
+
+func (t *eventTracer) UpdatedKey(generation logging.KeyPhase, remote bool) {
+	t.bus.Publish(KeyUpdateEvent{KeyPhase: uint64(generation), Remote: remote})
+}
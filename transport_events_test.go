@@ -0,0 +1,58 @@
+package quic
+
+import (
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport Events", func() {
+	var bus *eventBus
+
+	BeforeEach(func() {
+		bus = newEventBus()
+	})
+
+	It("delivers events", func() {
+		bus.Publish(PRNegotiatedEvent{Enabled: true})
+		bus.Publish(StreamResetEvent{StreamID: 4, ErrorCode: 42})
+		Expect(bus.Events()).To(Receive(Equal(PRNegotiatedEvent{Enabled: true})))
+		Expect(bus.Events()).To(Receive(Equal(StreamResetEvent{StreamID: 4, ErrorCode: 42})))
+		Expect(bus.Events()).ToNot(Receive())
+	})
+
+	It("drops events if the application doesn't receive them", func() {
+		for i := 0; i < protocol.TransportEventQueueLen; i++ {
+			bus.Publish(KeyUpdateEvent{KeyPhase: uint64(i)})
+		}
+		bus.Publish(KeyUpdateEvent{KeyPhase: 1337})
+		Expect(bus.Events()).To(HaveLen(protocol.TransportEventQueueLen))
+		Expect(bus.Events()).To(Receive(Equal(KeyUpdateEvent{KeyPhase: 0})))
+	})
+
+	It("closes the channel", func() {
+		bus.Publish(PRNegotiatedEvent{})
+		bus.Close()
+		bus.Close()
+		bus.Publish(PRNegotiatedEvent{})
+		Expect(bus.Events()).To(Receive())
+		Expect(bus.Events()).To(BeClosed())
+	})
+
+	It("delivers key updates reported to the tracer", func() {
+		tracer := tracerWithEvents(nil, bus)
+		tracer.UpdatedKey(3, true)
+		Expect(bus.Events()).To(Receive(Equal(KeyUpdateEvent{KeyPhase: 3, Remote: true})))
+	})
+
+	It("passes events to the tracer", func() {
+		tr := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer := tracerWithEvents(tr, bus)
+		tr.EXPECT().UpdatedKey(logging.KeyPhase(3), false)
+		tracer.UpdatedKey(3, false)
+		Expect(bus.Events()).To(Receive(Equal(KeyUpdateEvent{KeyPhase: 3})))
+	})
+})
@@ -0,0 +1,39 @@
+package quic
+
+import "github.com/lucas-clemente/quic-go/internal/protocol"
+
+func newWindowState(bytesRead, receiveWindow, windowSize protocol.ByteCount) WindowState {
+	return WindowState{
+		BytesRead:  uint64(bytesRead),
+		Window:     uint64(receiveWindow),
+		WindowSize: uint64(windowSize),
+	}
+}
+
+// A ThresholdWindowUpdateStrategy increases a receive window once the peer can send less than Threshold bytes,
+// by advertising Increment bytes beyond the data read. The window size determined by auto-tuning is ignored.
+// This allows receivers of data sent at a known bitrate to use a large static window, and to avoid frequent window updates.
+// It uses the same values for all streams and for the connection.
+type ThresholdWindowUpdateStrategy struct {
+	Threshold uint64
+	Increment uint64
+}
+
+var _ WindowUpdateStrategy = &ThresholdWindowUpdateStrategy{}
+
+// StreamWindowUpdate returns the new offset for a stream.
+func (s *ThresholdWindowUpdateStrategy) StreamWindowUpdate(_ StreamID, state WindowState) uint64 {
+	return s.windowUpdate(state)
+}
+
+// ConnectionWindowUpdate returns the new offset for the connection.
+func (s *ThresholdWindowUpdateStrategy) ConnectionWindowUpdate(state WindowState) uint64 {
+	return s.windowUpdate(state)
+}
+
+func (s *ThresholdWindowUpdateStrategy) windowUpdate(state WindowState) uint64 {
+	if state.Window-state.BytesRead >= s.Threshold {
+		return state.Window
+	}
+	return state.BytesRead + s.Increment
+}
@@ -0,0 +1,24 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Threshold Window Update Strategy", func() {
+	strategy := &ThresholdWindowUpdateStrategy{Threshold: 1000, Increment: 5000}
+
+	It("doesn't update the window before reaching the threshold", func() {
+		Expect(strategy.StreamWindowUpdate(4, WindowState{BytesRead: 4000, Window: 5000, WindowSize: 5000})).To(BeEquivalentTo(5000))
+		Expect(strategy.ConnectionWindowUpdate(WindowState{BytesRead: 4000, Window: 5000, WindowSize: 5000})).To(BeEquivalentTo(5000))
+	})
+
+	It("updates the window", func() {
+		Expect(strategy.StreamWindowUpdate(4, WindowState{BytesRead: 4001, Window: 5000, WindowSize: 5000})).To(BeEquivalentTo(9001))
+		Expect(strategy.ConnectionWindowUpdate(WindowState{BytesRead: 4001, Window: 5000, WindowSize: 5000})).To(BeEquivalentTo(9001))
+	})
+
+	It("ignores the window size", func() {
+		Expect(strategy.StreamWindowUpdate(4, WindowState{BytesRead: 4500, Window: 5000, WindowSize: 20000})).To(BeEquivalentTo(9500))
+	})
+})
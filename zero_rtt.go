@@ -0,0 +1,89 @@
+package quic
+
+import (
+	"crypto/sha256"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+)
+
+// ZeroRTTInfo contains information about a client that attempts to send 0-RTT data, see Config.Allow0RTT.
+type ZeroRTTInfo struct {
+	// RemoteAddr is the address of the client.
+	// Note that the address is not validated yet when the decision about 0-RTT is made.
+	RemoteAddr net.Addr
+	// ServerName is the server name indicated by the client (SNI).
+	ServerName string
+	// TicketAge is the time since the session ticket used for 0-RTT was issued.
+	TicketAge time.Duration
+}
+
+// A ZeroRTTReplayFilter protects a server against replays of 0-RTT data, see RFC 8446, section 8.
+// It remembers the ClientHellos of the connections that 0-RTT was accepted for during the replay window,
+// and rejects 0-RTT for ClientHellos that it has seen before.
+// Since older ClientHellos are forgotten, 0-RTT is also rejected for session tickets that were issued before the replay window.
+// A server usually shares a single filter between all its connections.
+// Replays to other servers, e.g. to other instances behind the same load balancer, are not detected.
+type ZeroRTTReplayFilter struct {
+	mutex  sync.Mutex
+	window time.Duration
+	seen   map[[sha256.Size]byte]struct{}
+	queue  []zeroRTTReplayFilterEntry // ordered by time
+}
+
+type zeroRTTReplayFilterEntry struct {
+	hash [sha256.Size]byte
+	time time.Time
+}
+
+// NewZeroRTTReplayFilter creates a filter with the given replay window.
+// Clients can only use 0-RTT with session tickets issued less than window ago.
+func NewZeroRTTReplayFilter(window time.Duration) *ZeroRTTReplayFilter {
+	return &ZeroRTTReplayFilter{
+		window: window,
+		seen:   make(map[[sha256.Size]byte]struct{}),
+	}
+}
+
+// accept decides if 0-RTT is accepted for a ClientHello, and remembers it if it is.
+func (f *ZeroRTTReplayFilter) accept(clientHello []byte, ticketIssued, now time.Time) bool {
+	if now.Sub(ticketIssued) >= f.window {
+		return false
+	}
+	hash := sha256.Sum256(clientHello)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for len(f.queue) > 0 && now.Sub(f.queue[0].time) >= f.window {
+		delete(f.seen, f.queue[0].hash)
+		f.queue = f.queue[1:]
+	}
+	if _, ok := f.seen[hash]; ok {
+		return false
+	}
+	f.seen[hash] = struct{}{}
+	f.queue = append(f.queue, zeroRTTReplayFilterEntry{hash: hash, time: now})
+	return true
+}
+
+// newZeroRTTPolicy returns the 0-RTT policy of the server, see Config.Allow0RTT and Config.ZeroRTTReplayFilter.
+// It returns nil if 0-RTT is accepted whenever the session ticket is valid for 0-RTT.
+func newZeroRTTPolicy(conf *Config, remoteAddr net.Addr) func(*handshake.ZeroRTTAttempt) bool {
+	if conf.Allow0RTT == nil && conf.ZeroRTTReplayFilter == nil {
+		return nil
+	}
+	return func(a *handshake.ZeroRTTAttempt) bool {
+		now := time.Now()
+		if conf.Allow0RTT != nil && !conf.Allow0RTT(&ZeroRTTInfo{
+			RemoteAddr: remoteAddr,
+			ServerName: a.ServerName,
+			TicketAge:  now.Sub(a.TicketIssued),
+		}) {
+			return false
+		}
+		return conf.ZeroRTTReplayFilter == nil || conf.ZeroRTTReplayFilter.accept(a.ClientHello, a.TicketIssued, now)
+	}
+}
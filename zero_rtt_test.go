@@ -0,0 +1,87 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("0-RTT", func() {
+	Context("replay filter", func() {
+		It("rejects replayed ClientHellos", func() {
+			f := NewZeroRTTReplayFilter(time.Minute)
+			now := time.Now()
+			Expect(f.accept([]byte("foo"), now.Add(-time.Second), now)).To(BeTrue())
+			Expect(f.accept([]byte("foo"), now.Add(-time.Second), now.Add(time.Second))).To(BeFalse())
+			Expect(f.accept([]byte("bar"), now.Add(-time.Second), now.Add(time.Second))).To(BeTrue())
+		})
+
+		It("rejects session tickets issued before the replay window", func() {
+			f := NewZeroRTTReplayFilter(time.Minute)
+			now := time.Now()
+			Expect(f.accept([]byte("foo"), now.Add(-time.Minute), now)).To(BeFalse())
+			Expect(f.accept([]byte("foo"), now.Add(-time.Minute+time.Second), now)).To(BeTrue())
+		})
+
+		It("forgets ClientHellos after the replay window", func() {
+			f := NewZeroRTTReplayFilter(time.Minute)
+			now := time.Now()
+			Expect(f.accept([]byte("foo"), now, now)).To(BeTrue())
+			Expect(f.accept([]byte("bar"), now.Add(time.Second), now.Add(time.Second))).To(BeTrue())
+			Expect(f.seen).To(HaveLen(2))
+			now = now.Add(time.Minute)
+			Expect(f.accept([]byte("baz"), now, now)).To(BeTrue())
+			Expect(f.seen).To(HaveLen(2))
+			Expect(f.queue).To(HaveLen(2))
+		})
+	})
+
+	Context("policy", func() {
+		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+
+		It("doesn't use a policy, if 0-RTT is always accepted", func() {
+			Expect(newZeroRTTPolicy(&Config{}, remoteAddr)).To(BeNil())
+		})
+
+		It("calls Allow0RTT", func() {
+			var info *ZeroRTTInfo
+			policy := newZeroRTTPolicy(&Config{
+				Allow0RTT: func(i *ZeroRTTInfo) bool {
+					info = i
+					return i.ServerName == "media.example.com"
+				},
+			}, remoteAddr)
+			Expect(policy).ToNot(BeNil())
+			Expect(policy(&handshake.ZeroRTTAttempt{ServerName: "media.example.com", TicketIssued: time.Now().Add(-time.Hour)})).To(BeTrue())
+			Expect(info.RemoteAddr).To(Equal(remoteAddr))
+			Expect(info.ServerName).To(Equal("media.example.com"))
+			Expect(info.TicketAge).To(BeNumerically("~", time.Hour, time.Second))
+			Expect(policy(&handshake.ZeroRTTAttempt{ServerName: "api.example.com", TicketIssued: time.Now()})).To(BeFalse())
+		})
+
+		It("applies the replay filter", func() {
+			policy := newZeroRTTPolicy(&Config{ZeroRTTReplayFilter: NewZeroRTTReplayFilter(time.Minute)}, remoteAddr)
+			Expect(policy).ToNot(BeNil())
+			Expect(policy(&handshake.ZeroRTTAttempt{ClientHello: []byte("foo"), TicketIssued: time.Now()})).To(BeTrue())
+			Expect(policy(&handshake.ZeroRTTAttempt{ClientHello: []byte("foo"), TicketIssued: time.Now()})).To(BeFalse())
+			Expect(policy(&handshake.ZeroRTTAttempt{ClientHello: []byte("bar"), TicketIssued: time.Now().Add(-time.Hour)})).To(BeFalse())
+		})
+
+		It("doesn't record ClientHellos that Allow0RTT rejected", func() {
+			filter := NewZeroRTTReplayFilter(time.Minute)
+			var allow bool
+			policy := newZeroRTTPolicy(&Config{
+				Allow0RTT:           func(*ZeroRTTInfo) bool { return allow },
+				ZeroRTTReplayFilter: filter,
+			}, remoteAddr)
+			Expect(policy(&handshake.ZeroRTTAttempt{ClientHello: []byte("foo"), TicketIssued: time.Now()})).To(BeFalse())
+			Expect(filter.seen).To(BeEmpty())
+			allow = true
+			Expect(policy(&handshake.ZeroRTTAttempt{ClientHello: []byte("foo"), TicketIssued: time.Now()})).To(BeTrue())
+		})
+	})
+})